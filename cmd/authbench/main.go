@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"runtime"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/auth"
+)
+
+func main() {
+	var (
+		useEnv       = flag.Bool("env", false, "Benchmark the configuration read from AUTH_* environment variables instead of an in-memory instance")
+		profile      = flag.String("profile", "", "Configuration profile to apply with -env (development, staging, production)")
+		mixFlag      = flag.String("mix", "register=1,login=10,validate=80,refresh=9", "Relative weights of the operations")
+		users        = flag.Int("users", 100, "Users registered and logged in before the run")
+		concurrency  = flag.Int("concurrency", 4*runtime.GOMAXPROCS(0), "Number of concurrent workers")
+		duration     = flag.Duration("duration", 10*time.Second, "Duration of the run, or of each capacity step")
+		rps          = flag.Float64("rps", 0, "Target requests per second (0 runs flat out)")
+		capacity     = flag.Bool("capacity", false, "Search for the maximum sustainable RPS")
+		startRPS     = flag.Float64("start-rps", 50, "Target rate of the first capacity step")
+		growth       = flag.Float64("growth", 1.5, "Factor by which each capacity step raises the target rate")
+		maxSteps     = flag.Int("max-steps", 12, "Maximum number of capacity steps")
+		maxP99       = flag.Duration("max-p99", 100*time.Millisecond, "Highest acceptable p99 latency")
+		maxErrorRate = flag.Float64("max-error-rate", 0.01, "Highest acceptable error rate")
+		fastHash     = flag.Bool("fast-hash", false, "Use cheap password hashing to measure everything but the hash")
+		jsonOut      = flag.Bool("json", false, "Print the report as JSON")
+		showHelp     = flag.Bool("help", false, "Show help information")
+	)
+	flag.Parse()
+
+	if *showHelp {
+		showUsage()
+		return
+	}
+
+	workload, err := parseMix(*mixFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid -mix: %v\n", err)
+		os.Exit(2)
+	}
+	if *users < 1 || *concurrency < 1 || *growth <= 1 {
+		fmt.Fprintln(os.Stderr, "-users and -concurrency must be positive and -growth greater than 1")
+		os.Exit(2)
+	}
+
+	a, err := newTarget(*useEnv, *profile, *fastHash)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create auth instance: %v\n", err)
+		os.Exit(1)
+	}
+	defer a.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	logf := func(format string, args ...interface{}) {
+		if !*jsonOut {
+			fmt.Printf(format, args...)
+		}
+	}
+	logf("Seeding %d users...\n", *users)
+	t, err := seed(a, *users)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to seed users: %v\n", err)
+		os.Exit(1)
+	}
+
+	config := runConfig{mix: workload, concurrency: *concurrency, duration: *duration, rps: *rps}
+	var report interface{}
+	if *capacity {
+		logf("Searching capacity from %.0f rps (p99 <= %v, errors <= %.2f%%)...\n", *startRPS, *maxP99, 100**maxErrorRate)
+		result := findCapacity(ctx, t, capacityConfig{
+			run:          config,
+			startRPS:     *startRPS,
+			growth:       *growth,
+			maxSteps:     *maxSteps,
+			maxP99:       *maxP99,
+			maxErrorRate: *maxErrorRate,
+		}, func(step *runReport) {
+			logf("  target %8.0f rps: achieved %8.0f rps, p99 %v, errors %d\n", step.TargetRPS, step.RPS, step.P99, step.Errors)
+		})
+		report = result
+		if !*jsonOut {
+			printCapacity(result)
+		}
+	} else {
+		logf("Running %s with %d workers...\n", *duration, *concurrency)
+		result := run(ctx, t, config)
+		report = result
+		if !*jsonOut {
+			printRun(result)
+		}
+	}
+
+	if *jsonOut {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode report: %v\n", err)
+			os.Exit(2)
+		}
+		fmt.Println(string(data))
+	}
+}
+
+// newTarget creates the Auth instance under test.
+func newTarget(useEnv bool, profile string, fastHash bool) (*auth.Auth, error) {
+	config := &auth.AuthConfig{
+		JWTSecret:        "authbench-secret-0123456789abcdef",
+		JWTRefreshSecret: "authbench-refresh-secret-0123456789",
+		AppName:          "authbench",
+	}
+	if useEnv {
+		var (
+			envConfig *auth.EnhancedConfig
+			err       error
+		)
+		if profile != "" {
+			envConfig, err = auth.LoadConfigWithProfile(profile)
+		} else {
+			envConfig, err = auth.LoadConfigFromEnv()
+		}
+		if err != nil {
+			return nil, err
+		}
+		config = envConfig.ToAuthConfig()
+	}
+	config.LogLevel = "error"
+	if fastHash {
+		config.PasswordHashParams = auth.TestHashParams
+	}
+	return auth.NewWithConfig(config)
+}
+
+func printRun(r *runReport) {
+	fmt.Println()
+	fmt.Printf("Requests: %d in %v (%.0f rps), errors: %d (%.2f%%)\n",
+		r.Requests, r.Duration.Round(time.Millisecond), r.RPS, r.Errors, 100*r.errorRate())
+	fmt.Println()
+	fmt.Printf("%-10s %8s %7s %10s %10s %10s %10s\n", "operation", "count", "errors", "p50", "p90", "p99", "max")
+	for _, op := range r.Operations {
+		fmt.Printf("%-10s %8d %7d %10v %10v %10v %10v\n", op.Operation, op.Count, op.Errors,
+			op.P50.Round(time.Microsecond), op.P90.Round(time.Microsecond),
+			op.P99.Round(time.Microsecond), op.Max.Round(time.Microsecond))
+	}
+}
+
+func printCapacity(r *capacityReport) {
+	fmt.Println()
+	fmt.Printf("Max sustainable RPS: %.0f\n", r.MaxSustainableRPS)
+	fmt.Printf("Limited by: %s\n", r.Limit)
+	if len(r.Steps) > 0 {
+		printRun(r.Steps[len(r.Steps)-1])
+	}
+}
+
+func showUsage() {
+	fmt.Println("Go-Auth Load Test")
+	fmt.Println("=================")
+	fmt.Println()
+	fmt.Println("Runs a mixed register/login/validate/refresh workload against an Auth")
+	fmt.Println("configuration and reports latency percentiles per operation. With")
+	fmt.Println("-capacity it raises the request rate step by step and reports the highest")
+	fmt.Println("rate that kept p99 latency and errors within their limits.")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  authbench [options]")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  -env")
+	fmt.Println("        Benchmark the configuration from AUTH_* environment variables")
+	fmt.Println("  -profile string")
+	fmt.Println("        Configuration profile to apply with -env")
+	fmt.Println("  -mix string")
+	fmt.Println("        Relative operation weights (default \"register=1,login=10,validate=80,refresh=9\")")
+	fmt.Println("  -users int")
+	fmt.Println("        Users registered before the run (default 100)")
+	fmt.Println("  -concurrency int")
+	fmt.Println("        Number of concurrent workers (default 4 x GOMAXPROCS)")
+	fmt.Println("  -duration duration")
+	fmt.Println("        Duration of the run or of each capacity step (default 10s)")
+	fmt.Println("  -rps float")
+	fmt.Println("        Target requests per second; 0 runs flat out")
+	fmt.Println("  -capacity")
+	fmt.Println("        Search for the maximum sustainable RPS")
+	fmt.Println("  -start-rps, -growth, -max-steps")
+	fmt.Println("        Rate of the first step (50), factor per step (1.5), step limit (12)")
+	fmt.Println("  -max-p99 duration, -max-error-rate float")
+	fmt.Println("        Limits of a passing step (default 100ms and 0.01)")
+	fmt.Println("  -fast-hash")
+	fmt.Println("        Use cheap password hashing to measure everything but the hash")
+	fmt.Println("  -json")
+	fmt.Println("        Print the report as JSON")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  # Validation-heavy workload against the in-memory storage")
+	fmt.Println("  authbench -mix validate=95,refresh=5 -duration 30s")
+	fmt.Println()
+	fmt.Println("  # Capacity of the production PostgreSQL configuration")
+	fmt.Println("  AUTH_PROFILE=production authbench -env -capacity -max-p99 50ms")
+}
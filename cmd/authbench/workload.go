@@ -0,0 +1,363 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/auth"
+)
+
+// Operations of a workload, in report order.
+var operations = []string{"register", "login", "validate", "refresh"}
+
+// benchPassword is the password of every user created by the benchmark.
+const benchPassword = "authbench-password-1"
+
+// mix holds the relative weights of the operations.
+type mix map[string]int
+
+// parseMix parses "register=1,login=10,validate=80,refresh=9".
+func parseMix(s string) (mix, error) {
+	m := mix{}
+	total := 0
+	for _, part := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid mix entry %q, expected operation=weight", part)
+		}
+		if !isOperation(name) {
+			return nil, fmt.Errorf("unknown operation %q, expected one of %s", name, strings.Join(operations, ", "))
+		}
+		weight, err := strconv.Atoi(value)
+		if err != nil || weight < 0 {
+			return nil, fmt.Errorf("invalid weight %q for %s", value, name)
+		}
+		m[name] = weight
+		total += weight
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("the mix has no operations")
+	}
+	return m, nil
+}
+
+func isOperation(name string) bool {
+	for _, op := range operations {
+		if op == name {
+			return true
+		}
+	}
+	return false
+}
+
+// pick returns an operation with probability proportional to its weight.
+func (m mix) pick(rng *rand.Rand) string {
+	total := 0
+	for _, op := range operations {
+		total += m[op]
+	}
+	n := rng.Intn(total)
+	for _, op := range operations {
+		if n < m[op] {
+			return op
+		}
+		n -= m[op]
+	}
+	return operations[len(operations)-1]
+}
+
+// session is a token pair that validate and refresh operations use.
+type session struct {
+	mu           sync.Mutex
+	accessToken  string
+	refreshToken string
+}
+
+// target is an Auth instance seeded with users and sessions.
+type target struct {
+	auth      *auth.Auth
+	usernames []string
+	sessions  []*session
+	prefix    string
+	counter   atomic.Int64
+}
+
+// seed registers users and logs each of them in once.
+func seed(a *auth.Auth, users int) (*target, error) {
+	t := &target{auth: a, prefix: fmt.Sprintf("bench%d", time.Now().UnixNano())}
+	for i := 0; i < users; i++ {
+		username := t.nextUsername()
+		if _, err := a.Register(auth.RegisterRequest{
+			Username: username,
+			Email:    username + "@authbench.invalid",
+			Password: benchPassword,
+		}); err != nil {
+			return nil, fmt.Errorf("failed to register user %s: %w", username, err)
+		}
+		result, err := a.Login(username, benchPassword, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to log in user %s: %w", username, err)
+		}
+		t.usernames = append(t.usernames, username)
+		t.sessions = append(t.sessions, &session{accessToken: result.AccessToken, refreshToken: result.RefreshToken})
+	}
+	return t, nil
+}
+
+func (t *target) nextUsername() string {
+	return fmt.Sprintf("%s_%d", t.prefix, t.counter.Add(1))
+}
+
+// do runs one operation.
+func (t *target) do(op string, rng *rand.Rand) error {
+	switch op {
+	case "register":
+		username := t.nextUsername()
+		_, err := t.auth.Register(auth.RegisterRequest{
+			Username: username,
+			Email:    username + "@authbench.invalid",
+			Password: benchPassword,
+		})
+		return err
+	case "login":
+		_, err := t.auth.Login(t.usernames[rng.Intn(len(t.usernames))], benchPassword, nil)
+		return err
+	case "validate":
+		s := t.sessions[rng.Intn(len(t.sessions))]
+		s.mu.Lock()
+		token := s.accessToken
+		s.mu.Unlock()
+		_, err := t.auth.ValidateAccessToken(token)
+		return err
+	case "refresh":
+		// Refresh tokens rotate, so a session is refreshed by one worker at a time
+		s := t.sessions[rng.Intn(len(t.sessions))]
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		result, err := t.auth.RefreshToken(s.refreshToken)
+		if err != nil {
+			return err
+		}
+		s.accessToken, s.refreshToken = result.AccessToken, result.RefreshToken
+		return nil
+	}
+	return fmt.Errorf("unknown operation %q", op)
+}
+
+// runConfig configures one load test run.
+type runConfig struct {
+	mix         mix
+	concurrency int
+	duration    time.Duration
+	// rps paces the requests; the workers run flat out when zero.
+	rps float64
+}
+
+// opStats summarizes the latencies of one operation.
+type opStats struct {
+	Operation string        `json:"operation"`
+	Count     int           `json:"count"`
+	Errors    int           `json:"errors"`
+	P50       time.Duration `json:"p50"`
+	P90       time.Duration `json:"p90"`
+	P99       time.Duration `json:"p99"`
+	Max       time.Duration `json:"max"`
+}
+
+// runReport is the result of one run.
+type runReport struct {
+	TargetRPS  float64       `json:"target_rps,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	Requests   int           `json:"requests"`
+	Errors     int           `json:"errors"`
+	RPS        float64       `json:"rps"`
+	P99        time.Duration `json:"p99"`
+	Operations []opStats     `json:"operations"`
+}
+
+// errorRate returns the share of failed requests.
+func (r *runReport) errorRate() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Errors) / float64(r.Requests)
+}
+
+// sample is the outcome of one request.
+type sample struct {
+	op      string
+	latency time.Duration
+	failed  bool
+}
+
+// run drives the workload against t for config.duration.
+func run(ctx context.Context, t *target, config runConfig) *runReport {
+	ctx, cancel := context.WithTimeout(ctx, config.duration)
+	defer cancel()
+
+	// With a target rate a pacer hands out tickets; workers that cannot keep
+	// up leave tickets unused, which shows as an RPS below the target.
+	var tickets chan struct{}
+	if config.rps > 0 {
+		tickets = make(chan struct{}, config.concurrency)
+		go pace(ctx, tickets, config.rps)
+	}
+
+	results := make([][]sample, config.concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for w := 0; w < config.concurrency; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			rng := rand.New(rand.NewSource(time.Now().UnixNano() + int64(w)))
+			for {
+				if tickets != nil {
+					select {
+					case <-tickets:
+					case <-ctx.Done():
+						return
+					}
+				} else if ctx.Err() != nil {
+					return
+				}
+				op := config.mix.pick(rng)
+				began := time.Now()
+				err := t.do(op, rng)
+				results[w] = append(results[w], sample{op: op, latency: time.Since(began), failed: err != nil})
+			}
+		}(w)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	return summarize(results, elapsed, config.rps)
+}
+
+// pace sends rps tickets per second until ctx is done.
+func pace(ctx context.Context, tickets chan<- struct{}, rps float64) {
+	interval := time.Duration(float64(time.Second) / rps)
+	next := time.Now()
+	for {
+		next = next.Add(interval)
+		if wait := time.Until(next); wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return
+			}
+		}
+		select {
+		case tickets <- struct{}{}:
+		case <-ctx.Done():
+			return
+		default:
+			// Saturated: the ticket is lost
+		}
+	}
+}
+
+// summarize computes the report of a run.
+func summarize(results [][]sample, elapsed time.Duration, targetRPS float64) *runReport {
+	report := &runReport{TargetRPS: targetRPS, Duration: elapsed}
+	latencies := map[string][]time.Duration{}
+	errors := map[string]int{}
+	var all []time.Duration
+	for _, samples := range results {
+		for _, s := range samples {
+			latencies[s.op] = append(latencies[s.op], s.latency)
+			all = append(all, s.latency)
+			if s.failed {
+				errors[s.op]++
+				report.Errors++
+			}
+		}
+	}
+	report.Requests = len(all)
+	report.RPS = float64(report.Requests) / elapsed.Seconds()
+	report.P99 = percentile(all, 0.99)
+
+	for _, op := range operations {
+		values := latencies[op]
+		if len(values) == 0 {
+			continue
+		}
+		report.Operations = append(report.Operations, opStats{
+			Operation: op,
+			Count:     len(values),
+			Errors:    errors[op],
+			P50:       percentile(values, 0.50),
+			P90:       percentile(values, 0.90),
+			P99:       percentile(values, 0.99),
+			Max:       percentile(values, 1),
+		})
+	}
+	return report
+}
+
+// percentile returns the p-th percentile of values, sorting them in place.
+func percentile(values []time.Duration, p float64) time.Duration {
+	if len(values) == 0 {
+		return 0
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	index := int(p*float64(len(values))+0.5) - 1
+	return values[min(max(index, 0), len(values)-1)]
+}
+
+// capacityConfig configures the search for the maximum sustainable rate.
+type capacityConfig struct {
+	run          runConfig
+	startRPS     float64
+	growth       float64
+	maxSteps     int
+	maxP99       time.Duration
+	maxErrorRate float64
+}
+
+// capacityReport is the result of a capacity search.
+type capacityReport struct {
+	MaxSustainableRPS float64      `json:"max_sustainable_rps"`
+	Limit             string       `json:"limit"`
+	Steps             []*runReport `json:"steps"`
+}
+
+// findCapacity raises the target rate step by step until the p99 latency or
+// error rate exceeds its limit, or the target is no longer reached. The rate
+// of the last passing step is the maximum sustainable RPS.
+func findCapacity(ctx context.Context, t *target, config capacityConfig, progress func(*runReport)) *capacityReport {
+	report := &capacityReport{Limit: "step limit reached"}
+	rps := config.startRPS
+	for step := 0; step < config.maxSteps && ctx.Err() == nil; step++ {
+		stepConfig := config.run
+		stepConfig.rps = rps
+		result := run(ctx, t, stepConfig)
+		report.Steps = append(report.Steps, result)
+		if progress != nil {
+			progress(result)
+		}
+
+		switch {
+		case result.P99 > config.maxP99:
+			report.Limit = fmt.Sprintf("p99 %v exceeded %v at %.0f rps", result.P99, config.maxP99, rps)
+		case result.errorRate() > config.maxErrorRate:
+			report.Limit = fmt.Sprintf("error rate %.2f%% exceeded %.2f%% at %.0f rps",
+				100*result.errorRate(), 100*config.maxErrorRate, rps)
+		case result.RPS < 0.9*rps:
+			report.Limit = fmt.Sprintf("reached only %.0f of %.0f rps", result.RPS, rps)
+		default:
+			report.MaxSustainableRPS = result.RPS
+			rps *= config.growth
+			continue
+		}
+		break
+	}
+	return report
+}
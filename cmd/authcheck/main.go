@@ -0,0 +1,140 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/auth"
+)
+
+func main() {
+	var (
+		profile  = flag.String("profile", "", "Configuration profile to apply (development, staging, production)")
+		timeURL  = flag.String("time-url", "", "URL whose Date header is used as the clock skew reference (optional)")
+		maxSkew  = flag.Duration("max-skew", 30*time.Second, "Maximum tolerated clock skew")
+		jsonOut  = flag.Bool("json", false, "Print the report as JSON")
+		strict   = flag.Bool("strict", false, "Treat warnings as failures")
+		showHelp = flag.Bool("help", false, "Show help information")
+	)
+	flag.Parse()
+
+	if *showHelp {
+		showUsage()
+		return
+	}
+
+	report := run(*profile, *timeURL, *maxSkew)
+	report.Checks = append(report.Checks, checkEnv())
+
+	if *jsonOut {
+		data, err := report.JSON()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode report: %v\n", err)
+			os.Exit(2)
+		}
+		fmt.Println(string(data))
+	} else {
+		report.Print()
+	}
+
+	if !report.Passed() || (*strict && report.HasWarnings()) {
+		os.Exit(1)
+	}
+}
+
+// run loads the configuration from the environment and runs the self-check.
+// Checks that need a running Auth instance are skipped if it cannot be created.
+func run(profile, timeURL string, maxSkew time.Duration) *auth.SelfCheckReport {
+	var (
+		envConfig *auth.EnhancedConfig
+		err       error
+	)
+	if profile != "" {
+		envConfig, err = auth.LoadConfigWithProfile(profile)
+	} else {
+		envConfig, err = auth.LoadConfigFromEnv()
+	}
+	if err != nil {
+		return &auth.SelfCheckReport{
+			CheckedAt: time.Now(),
+			Checks: []auth.CheckResult{
+				{Name: "config", Status: auth.CheckFail, Message: err.Error()},
+			},
+		}
+	}
+
+	config := envConfig.ToAuthConfig()
+	config.LogLevel = "error"
+
+	a, err := auth.NewWithConfig(config)
+	if err != nil {
+		report := auth.CheckConfig(config)
+		report.Checks = append(report.Checks, auth.CheckResult{
+			Name:    "startup",
+			Status:  auth.CheckFail,
+			Message: err.Error(),
+		})
+		return report
+	}
+
+	opts := auth.SelfCheckOptions{MaxClockSkew: maxSkew}
+	if timeURL != "" {
+		opts.TimeReference = auth.HTTPTimeReference(timeURL)
+	}
+	return a.SelfCheckWithOptions(opts)
+}
+
+// checkEnv warns about AUTH_* variables the loader ignores, e.g. typos.
+func checkEnv() auth.CheckResult {
+	unknown := auth.UnknownEnvVars(os.Environ())
+	if len(unknown) == 0 {
+		return auth.CheckResult{Name: "environment", Status: auth.CheckPass, Message: "all AUTH_* variables are recognized"}
+	}
+	names := make([]string, len(unknown))
+	for i, u := range unknown {
+		names[i] = u.String()
+	}
+	return auth.CheckResult{
+		Name:    "environment",
+		Status:  auth.CheckWarn,
+		Message: "unknown variables are ignored: " + strings.Join(names, ", "),
+	}
+}
+
+func showUsage() {
+	fmt.Println("Go-Auth Preflight Check")
+	fmt.Println("=======================")
+	fmt.Println()
+	fmt.Println("Validates the go-auth configuration read from AUTH_* environment variables")
+	fmt.Println("and checks the runtime environment before a deploy. Exits with status 1")
+	fmt.Println("if any check fails.")
+	fmt.Println()
+	fmt.Println("Checks:")
+	fmt.Println("  secret entropy, token TTL sanity, database connectivity,")
+	fmt.Println("  migration status, clock skew, signing key health and unknown")
+	fmt.Println("  AUTH_* variables")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  authcheck [options]")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  -profile string")
+	fmt.Println("        Configuration profile to apply (development, staging, production)")
+	fmt.Println("  -time-url string")
+	fmt.Println("        URL whose Date header is used as the clock skew reference")
+	fmt.Println("  -max-skew duration")
+	fmt.Println("        Maximum tolerated clock skew (default 30s)")
+	fmt.Println("  -json")
+	fmt.Println("        Print the report as JSON")
+	fmt.Println("  -strict")
+	fmt.Println("        Treat warnings as failures")
+	fmt.Println("  -help")
+	fmt.Println("        Show this help information")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  # Preflight gate in a deploy pipeline")
+	fmt.Println("  AUTH_PROFILE=production authcheck -strict -time-url https://www.google.com")
+}
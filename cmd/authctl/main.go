@@ -0,0 +1,202 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/auth"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		showUsage()
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "purge-users":
+		purgeUsers(os.Args[2:])
+	case "purge-sessions":
+		purgeSessions(os.Args[2:])
+	case "audit-verify":
+		auditVerify(os.Args[2:])
+	case "help", "-help", "--help", "-h":
+		showUsage()
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", os.Args[1])
+		showUsage()
+		os.Exit(2)
+	}
+}
+
+// report is implemented by the purge reports.
+type report interface {
+	Fprint(w io.Writer)
+	JSON() ([]byte, error)
+}
+
+func purgeUsers(args []string) {
+	flags := flag.NewFlagSet("purge-users", flag.ExitOnError)
+	profile := flags.String("profile", "", "Configuration profile to apply (development, staging, production)")
+	olderThan := flags.Duration("older-than", 0, "Purge users inactive for longer than this duration (required)")
+	dryRun := flags.Bool("dry-run", false, "List the users that would be purged without deleting them")
+	jsonOut := flags.Bool("json", false, "Print the report as JSON")
+	flags.Parse(args)
+
+	if *olderThan <= 0 {
+		fmt.Fprintln(os.Stderr, "purge-users: -older-than is required")
+		os.Exit(2)
+	}
+
+	a := open(*profile)
+	result, err := a.Users().PurgeInactive(*olderThan, *dryRun)
+	if err != nil {
+		fatal(err)
+	}
+	printReport(result, *jsonOut)
+	if len(result.Errors) > 0 {
+		os.Exit(1)
+	}
+}
+
+func purgeSessions(args []string) {
+	flags := flag.NewFlagSet("purge-sessions", flag.ExitOnError)
+	profile := flags.String("profile", "", "Configuration profile to apply (development, staging, production)")
+	olderThan := flags.Duration("older-than", 30*24*time.Hour, "Purge sessions that ended longer ago than this duration")
+	jsonOut := flags.Bool("json", false, "Print the report as JSON")
+	flags.Parse(args)
+
+	a := open(*profile)
+	result, err := a.Tokens().PurgeSessions(*olderThan)
+	if result != nil {
+		printReport(result, *jsonOut)
+	}
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func auditVerify(args []string) {
+	flags := flag.NewFlagSet("audit-verify", flag.ExitOnError)
+	publicKey := flags.String("public-key", "", "Base64 Ed25519 public key of the audit log (required)")
+	jsonOut := flags.Bool("json", false, "Print the report as JSON")
+	flags.Parse(args)
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(*publicKey))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		fmt.Fprintln(os.Stderr, "audit-verify: -public-key must be a base64 Ed25519 public key")
+		os.Exit(2)
+	}
+	input := os.Stdin
+	if flags.NArg() > 0 {
+		file, err := os.Open(flags.Arg(0))
+		if err != nil {
+			fatal(err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	result, verifyErr := auth.VerifyAuditLog(input, ed25519.PublicKey(key))
+	if *jsonOut {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			fatal(err)
+		}
+		fmt.Println(string(data))
+	} else if result != nil {
+		fmt.Printf("Records:        %d\n", result.Records)
+		fmt.Printf("Checkpoints:    %d\n", result.Checkpoints)
+		fmt.Printf("Signed through: %d (%d unsigned)\n", result.SignedThrough, result.Unsigned())
+	}
+	if verifyErr != nil {
+		fatal(verifyErr)
+	}
+}
+
+// open creates an Auth instance from the AUTH_* environment variables.
+func open(profile string) *auth.Auth {
+	var (
+		envConfig *auth.EnhancedConfig
+		err       error
+	)
+	if profile != "" {
+		envConfig, err = auth.LoadConfigWithProfile(profile)
+	} else {
+		envConfig, err = auth.LoadConfigFromEnv()
+	}
+	if err != nil {
+		fatal(err)
+	}
+
+	config := envConfig.ToAuthConfig()
+	config.LogLevel = "error"
+	a, err := auth.NewWithConfig(config)
+	if err != nil {
+		fatal(err)
+	}
+	return a
+}
+
+// printReport writes r to stdout as text or JSON.
+func printReport(r report, jsonOut bool) {
+	if !jsonOut {
+		r.Fprint(os.Stdout)
+		return
+	}
+	data, err := r.JSON()
+	if err != nil {
+		fatal(err)
+	}
+	fmt.Println(string(data))
+}
+
+func fatal(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	os.Exit(1)
+}
+
+func showUsage() {
+	fmt.Println("Go-Auth Maintenance CLI")
+	fmt.Println("=======================")
+	fmt.Println()
+	fmt.Println("Enforces data-retention policies on the go-auth database configured with")
+	fmt.Println("AUTH_* environment variables.")
+	fmt.Println()
+	fmt.Println("Usage:")
+	fmt.Println("  authctl <command> [options]")
+	fmt.Println()
+	fmt.Println("Commands:")
+	fmt.Println("  purge-users     Delete users who have not logged in for a given duration")
+	fmt.Println("  purge-sessions  Remove expired blacklist entries and ended delegation grants")
+	fmt.Println("  audit-verify    Verify an audit log export against its signing public key")
+	fmt.Println()
+	fmt.Println("Options:")
+	fmt.Println("  -profile string")
+	fmt.Println("        Configuration profile to apply (development, staging, production)")
+	fmt.Println("  -older-than duration")
+	fmt.Println("        Retention period, e.g. 8760h for one year")
+	fmt.Println("  -dry-run")
+	fmt.Println("        purge-users only: report without deleting")
+	fmt.Println("  -public-key string")
+	fmt.Println("        audit-verify only: base64 Ed25519 public key of the checkpoints")
+	fmt.Println("  -json")
+	fmt.Println("        Print the report as JSON")
+	fmt.Println()
+	fmt.Println("Examples:")
+	fmt.Println("  # Preview users inactive for two years")
+	fmt.Println("  authctl purge-users -older-than 17520h -dry-run")
+	fmt.Println()
+	fmt.Println("  # Drop session state that ended more than 30 days ago")
+	fmt.Println("  authctl purge-sessions -older-than 720h")
+	fmt.Println()
+	fmt.Println("  # Verify an export written by Auth.Audit().Export")
+	fmt.Println("  authctl audit-verify -public-key \"$AUDIT_PUBLIC_KEY\" audit.jsonl")
+}
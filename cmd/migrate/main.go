@@ -14,6 +14,9 @@ func main() {
 		outputFile  = flag.String("output", "", "Output file for the migration report (optional)")
 		scriptPath  = flag.String("script", "", "Generate migration script at the specified path")
 		fileToCheck = flag.String("file", "", "Analyze a specific Go file")
+		apply       = flag.Bool("apply", false, "Rewrite mechanical v1 API changes in place")
+		preview     = flag.Bool("preview", false, "Show the diff of the mechanical rewrites without changing files")
+		noBackup    = flag.Bool("no-backup", false, "Do not keep .bak copies of files changed by -apply")
 		showHelp    = flag.Bool("help", false, "Show help information")
 	)
 	flag.Parse()
@@ -36,6 +39,12 @@ func main() {
 		return
 	}
 
+	// Rewrite mechanical changes if requested
+	if *apply || *preview {
+		rewrite(tool, *projectPath, *fileToCheck, *apply, !*noBackup)
+		return
+	}
+
 	// Analyze a specific file if requested
 	if *fileToCheck != "" {
 		fmt.Printf("Analyzing file: %s\n\n", *fileToCheck)
@@ -80,6 +89,44 @@ func main() {
 	}
 }
 
+// rewrite previews or applies the mechanical v1 to v2 rewrites of a file,
+// or of the whole project if no file is given.
+func rewrite(tool *auth.CodeMigrationTool, projectPath, filePath string, apply, backup bool) {
+	var results []*auth.RewriteResult
+	if filePath != "" {
+		fmt.Printf("Rewriting file: %s\n\n", filePath)
+		result, err := tool.RewriteFile(filePath, apply, backup)
+		if err != nil {
+			log.Fatalf("Failed to rewrite file: %v", err)
+		}
+		if result != nil {
+			results = append(results, result)
+		}
+	} else {
+		fmt.Printf("Rewriting project at: %s\n\n", projectPath)
+		var err error
+		results, err = tool.RewriteProject(projectPath, apply, backup)
+		if err != nil {
+			log.Fatalf("Failed to rewrite project: %v", err)
+		}
+	}
+
+	if len(results) == 0 {
+		fmt.Println("✅ No mechanical changes needed!")
+		return
+	}
+	for _, result := range results {
+		result.Print()
+	}
+
+	if apply {
+		fmt.Printf("✅ Rewrote %d file(s)\n", len(results))
+		fmt.Println("Run the analysis again to review the remaining suggestions.")
+	} else {
+		fmt.Printf("%d file(s) would be rewritten. Run with -apply to write the changes.\n", len(results))
+	}
+}
+
 func showUsage() {
 	fmt.Println("Go-Auth v2 Migration Tool")
 	fmt.Println("========================")
@@ -99,6 +146,13 @@ func showUsage() {
 	fmt.Println("        Generate migration script at the specified path")
 	fmt.Println("  -file string")
 	fmt.Println("        Analyze a specific Go file")
+	fmt.Println("  -preview")
+	fmt.Println("        Show the diff of the mechanical rewrites without changing files")
+	fmt.Println("  -apply")
+	fmt.Println("        Rewrite mechanical v1 API changes in place (RegisterPayload,")
+	fmt.Println("        LoginResponse, NewAuthService), keeping .bak backups")
+	fmt.Println("  -no-backup")
+	fmt.Println("        Do not keep .bak copies of files changed by -apply")
 	fmt.Println("  -help")
 	fmt.Println("        Show this help information")
 	fmt.Println()
@@ -112,6 +166,10 @@ func showUsage() {
 	fmt.Println("  # Analyze a specific file")
 	fmt.Println("  migrate -file main.go")
 	fmt.Println()
+	fmt.Println("  # Preview, then apply, the mechanical rewrites")
+	fmt.Println("  migrate -preview")
+	fmt.Println("  migrate -apply")
+	fmt.Println()
 	fmt.Println("  # Generate migration script")
 	fmt.Println("  migrate -script migrate.sh")
 	fmt.Println()
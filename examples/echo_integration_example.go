@@ -70,8 +70,8 @@ func main() {
 
 	// Health and monitoring
 	e.GET("/health", healthHandler(authService))
-	e.GET("/health/ready", readinessHandler(authService))
-	e.GET("/health/live", livenessHandler(authService))
+	e.GET("/health/ready", echo.WrapHandler(authService.Monitor().ReadinessHandler()))
+	e.GET("/health/live", echo.WrapHandler(authService.Monitor().LivenessHandler()))
 
 	// Start server
 	e.Logger.Fatal(e.Start(":8081"))
@@ -103,21 +103,17 @@ func registerHandler(authService *auth.Auth) echo.HandlerFunc {
 
 func loginHandler(authService *auth.Auth) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		var req struct {
-			Username string                 `json:"username"`
-			Password string                 `json:"password"`
-			Claims   map[string]interface{} `json:"claims,omitempty"`
-		}
+		var req auth.LoginRequest
 
 		if err := c.Bind(&req); err != nil {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request format"})
 		}
 
-		if req.Username == "" || req.Password == "" {
+		if err := req.Validate(); err != nil {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Username and password are required"})
 		}
 
-		result, err := authService.Login(req.Username, req.Password, req.Claims)
+		result, err := authService.Login(req.Username, req.Password, nil)
 		if err != nil {
 			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid credentials"})
 		}
@@ -137,15 +133,13 @@ func loginHandler(authService *auth.Auth) echo.HandlerFunc {
 
 func refreshHandler(authService *auth.Auth) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		var req struct {
-			RefreshToken string `json:"refresh_token"`
-		}
+		var req auth.RefreshRequest
 
 		if err := c.Bind(&req); err != nil {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request format"})
 		}
 
-		if req.RefreshToken == "" {
+		if err := req.Validate(); err != nil {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Refresh token is required"})
 		}
 
@@ -197,17 +191,14 @@ func forgotPasswordHandler(authService *auth.Auth) echo.HandlerFunc {
 
 func resetPasswordHandler(authService *auth.Auth) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		var req struct {
-			Token       string `json:"token"`
-			NewPassword string `json:"new_password"`
-		}
+		var req auth.ResetPasswordRequest
 
 		if err := c.Bind(&req); err != nil {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request format"})
 		}
 
-		if req.Token == "" || req.NewPassword == "" {
-			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Token and new password are required"})
+		if err := req.Validate(); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 		}
 
 		users := authService.Users()
@@ -476,28 +467,6 @@ func healthHandler(authService *auth.Auth) echo.HandlerFunc {
 	}
 }
 
-func readinessHandler(authService *auth.Auth) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		if err := authService.Health(); err != nil {
-			return c.JSON(http.StatusServiceUnavailable, map[string]string{
-				"status": "not ready",
-			})
-		}
-
-		return c.JSON(http.StatusOK, map[string]string{
-			"status": "ready",
-		})
-	}
-}
-
-func livenessHandler(authService *auth.Auth) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		return c.JSON(http.StatusOK, map[string]string{
-			"status": "alive",
-		})
-	}
-}
-
 // Middleware helpers
 func requireRole(role string) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
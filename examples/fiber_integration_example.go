@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
@@ -76,8 +77,8 @@ func main() {
 
 	// Health and monitoring
 	app.Get("/health", healthHandler(authService))
-	app.Get("/health/ready", readinessHandler(authService))
-	app.Get("/health/live", livenessHandler())
+	app.Get("/health/ready", adaptor.HTTPHandlerFunc(authService.Monitor().ReadinessHandler()))
+	app.Get("/health/live", adaptor.HTTPHandlerFunc(authService.Monitor().LivenessHandler()))
 	app.Get("/metrics", metricsHandler(authService))
 
 	// Start server
@@ -132,11 +133,7 @@ func registerHandler(authService *auth.Auth) fiber.Handler {
 
 func loginHandler(authService *auth.Auth) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		var req struct {
-			Username string                 `json:"username"`
-			Password string                 `json:"password"`
-			Claims   map[string]interface{} `json:"claims,omitempty"`
-		}
+		var req auth.LoginRequest
 
 		if err := c.BodyParser(&req); err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -144,25 +141,19 @@ func loginHandler(authService *auth.Auth) fiber.Handler {
 			})
 		}
 
-		if req.Username == "" || req.Password == "" {
+		if err := req.Validate(); err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"error": "Username and password are required",
 			})
 		}
 
-		// Add default role if not specified
-		if req.Claims == nil {
-			req.Claims = make(map[string]interface{})
-		}
-		if _, exists := req.Claims["role"]; !exists {
-			if req.Username == "admin" {
-				req.Claims["role"] = "admin"
-			} else {
-				req.Claims["role"] = "user"
-			}
+		// The role is decided by the server, never taken from the request
+		role := "user"
+		if req.Username == "admin" {
+			role = "admin"
 		}
 
-		result, err := authService.Login(req.Username, req.Password, req.Claims)
+		result, err := authService.Login(req.Username, req.Password, map[string]interface{}{"role": role})
 		if err != nil {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Invalid credentials",
@@ -184,9 +175,7 @@ func loginHandler(authService *auth.Auth) fiber.Handler {
 
 func refreshHandler(authService *auth.Auth) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		var req struct {
-			RefreshToken string `json:"refresh_token"`
-		}
+		var req auth.RefreshRequest
 
 		if err := c.BodyParser(&req); err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -194,7 +183,7 @@ func refreshHandler(authService *auth.Auth) fiber.Handler {
 			})
 		}
 
-		if req.RefreshToken == "" {
+		if err := req.Validate(); err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"error": "Refresh token is required",
 			})
@@ -253,10 +242,7 @@ func forgotPasswordHandler(authService *auth.Auth) fiber.Handler {
 
 func resetPasswordHandler(authService *auth.Auth) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		var req struct {
-			Token       string `json:"token"`
-			NewPassword string `json:"new_password"`
-		}
+		var req auth.ResetPasswordRequest
 
 		if err := c.BodyParser(&req); err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -264,9 +250,9 @@ func resetPasswordHandler(authService *auth.Auth) fiber.Handler {
 			})
 		}
 
-		if req.Token == "" || req.NewPassword == "" {
+		if err := req.Validate(); err != nil {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Token and new password are required",
+				"error": err.Error(),
 			})
 		}
 
@@ -639,28 +625,6 @@ func healthHandler(authService *auth.Auth) fiber.Handler {
 	}
 }
 
-func readinessHandler(authService *auth.Auth) fiber.Handler {
-	return func(c *fiber.Ctx) error {
-		if err := authService.Health(); err != nil {
-			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
-				"status": "not ready",
-			})
-		}
-
-		return c.JSON(fiber.Map{
-			"status": "ready",
-		})
-	}
-}
-
-func livenessHandler() fiber.Handler {
-	return func(c *fiber.Ctx) error {
-		return c.JSON(fiber.Map{
-			"status": "alive",
-		})
-	}
-}
-
 func metricsHandler(authService *auth.Auth) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		metrics := authService.GetMetrics()
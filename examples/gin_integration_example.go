@@ -61,6 +61,8 @@ func main() {
 
 	// Health and monitoring
 	r.GET("/health", healthHandler(authService))
+	r.GET("/health/ready", gin.WrapF(authService.Monitor().ReadinessHandler()))
+	r.GET("/health/live", gin.WrapF(authService.Monitor().LivenessHandler()))
 	r.GET("/metrics", metricsHandler(authService))
 
 	// Start server
@@ -95,18 +97,14 @@ func registerHandler(authService *auth.Auth) gin.HandlerFunc {
 
 func loginHandler(authService *auth.Auth) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var req struct {
-			Username string                 `json:"username" binding:"required"`
-			Password string                 `json:"password" binding:"required"`
-			Claims   map[string]interface{} `json:"claims,omitempty"`
-		}
+		var req auth.LoginRequest
 
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
 			return
 		}
 
-		result, err := authService.Login(req.Username, req.Password, req.Claims)
+		result, err := authService.Login(req.Username, req.Password, nil)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 			return
@@ -127,9 +125,7 @@ func loginHandler(authService *auth.Auth) gin.HandlerFunc {
 
 func refreshHandler(authService *auth.Auth) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var req struct {
-			RefreshToken string `json:"refresh_token" binding:"required"`
-		}
+		var req auth.RefreshRequest
 
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
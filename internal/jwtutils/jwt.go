@@ -1,6 +1,7 @@
 package jwtutils
 
 import (
+	"crypto"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -8,11 +9,48 @@ import (
 
 type JWTConfig struct {
 	AccessSecret    []byte
-	RefreshSecret   []byte        // used for HS256 signing
-	Issuer          string        // e.g. "neighborhood-app"
-	AccessTokenTTL  time.Duration // e.g. 15 * time.Minute
-	RefreshTokenTTL time.Duration // e.g. 7 * 24 * time.Hour
-	SigningMethod   string        // jwt.SigningMethodHS256 or RS256
+	RefreshSecret   []byte           // used for HS256 signing
+	Issuer          string           // e.g. "neighborhood-app"
+	AccessTokenTTL  time.Duration    // e.g. 15 * time.Minute
+	RefreshTokenTTL time.Duration    // e.g. 7 * 24 * time.Hour
+	SigningMethod   string           // jwt.SigningMethodHS256 or RS256
+	Now             func() time.Time // clock used for issuance and validation; defaults to time.Now
+
+	Audience          []string // "aud" claim set on issued tokens; optional
+	ExpectedIssuers   []string // accepted "iss" values; defaults to Issuer
+	ExpectedAudiences []string // accepted "aud" values; defaults to Audience, no check when both are empty
+	RefreshAudience   []string // "aud" claim set on and accepted from refresh tokens; defaults to Audience and ExpectedAudiences
+
+	Leeway time.Duration // tolerated clock skew when checking "exp", "nbf" and "iat"; defaults to none
+
+	// Keys of the asymmetric RS*, PS* and ES* methods, which sign access and
+	// refresh tokens alike; the secrets are only used by HS* methods.
+	SigningKey      crypto.Signer    // RSA or ECDSA key, in memory or e.g. in a KMS; optional for managers that only validate
+	VerificationKey crypto.PublicKey // defaults to the public key of SigningKey
+}
+
+// IssueOptions schedules the validity of an issued token and adds claims to it.
+type IssueOptions struct {
+	IssuedAt  time.Time      // "iat"; defaults to now
+	NotBefore time.Time      // "nbf"; defaults to IssuedAt. The TTL counts from it.
+	Claims    map[string]any // extra claims; standard claims of refresh tokens are kept
+	TTL       time.Duration  // overrides the configured TTL when positive
+}
+
+// times returns the issue, activation and expiry times for a token with ttl.
+func (o IssueOptions) times(now time.Time, ttl time.Duration) (iat, nbf, exp time.Time) {
+	iat = now
+	if !o.IssuedAt.IsZero() {
+		iat = o.IssuedAt
+	}
+	nbf = iat
+	if !o.NotBefore.IsZero() {
+		nbf = o.NotBefore
+	}
+	if o.TTL > 0 {
+		ttl = o.TTL
+	}
+	return iat, nbf, nbf.Add(ttl)
 }
 
 var signingMethods = map[string]jwt.SigningMethod{
@@ -20,4 +58,23 @@ var signingMethods = map[string]jwt.SigningMethod{
 	"HS384": jwt.SigningMethodHS384,
 	"HS512": jwt.SigningMethodHS512,
 	"RS256": jwt.SigningMethodRS256,
+	"RS384": jwt.SigningMethodRS384,
+	"RS512": jwt.SigningMethodRS512,
+	"PS256": jwt.SigningMethodPS256,
+	"PS384": jwt.SigningMethodPS384,
+	"PS512": jwt.SigningMethodPS512,
+	"ES256": jwt.SigningMethodES256,
+	"ES384": jwt.SigningMethodES384,
+	"ES512": jwt.SigningMethodES512,
+}
+
+// symmetric reports whether method signs with a shared secret.
+func symmetric(method jwt.SigningMethod) bool {
+	_, ok := method.(*jwt.SigningMethodHMAC)
+	return ok
+}
+
+// SupportedSigningMethods returns the names of the supported signing methods.
+func SupportedSigningMethods() []string {
+	return []string{"HS256", "HS384", "HS512", "RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
 }
@@ -5,7 +5,9 @@ import "github.com/golang-jwt/jwt/v5"
 
 type TokenManager interface {
 	GenerateAccessToken(userID string, customClaims map[string]any) (string, error)
+	GenerateAccessTokenWithOptions(userID string, customClaims map[string]any, opts IssueOptions) (string, error)
 	GenerateRefreshToken(userID string) (string, error)
+	GenerateRefreshTokenWithOptions(userID string, opts IssueOptions) (string, error)
 	RefreshAccessToken(refreshToken string) (string, error)
 	ValidateAccessToken(accessToken string) (jwt.MapClaims, error)
 	ValidateRefreshToken(refreshToken string) (jwt.MapClaims, error)
@@ -1,6 +1,11 @@
 package jwtutils
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
 	"testing"
 	"time"
 
@@ -134,3 +139,232 @@ func TestSecurityChecks(t *testing.T) {
 	_, err = tm.ValidateAccessToken(refreshToken)
 	assert.Error(t, err, "Refresh token should not be valid when validated as an access token")
 }
+
+// TestCustomClock ensures issuance and validation use the configured clock.
+func TestCustomClock(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cfg := JWTConfig{
+		AccessSecret:   []byte("clock-secret"),
+		Issuer:         "test-clock",
+		AccessTokenTTL: 10 * time.Minute,
+		SigningMethod:  jwt.SigningMethodHS256.Alg(),
+		Now:            func() time.Time { return now },
+	}
+	tm := NewJWTManager(cfg)
+
+	accessToken, err := tm.GenerateAccessToken("user-clock", nil)
+	require.NoError(t, err)
+
+	claims, err := tm.ValidateAccessToken(accessToken)
+	require.NoError(t, err, "Token should be valid at issuance time")
+	assert.Equal(t, float64(now.Unix()), claims["iat"], "iat should come from the configured clock")
+
+	// Move the clock past the TTL
+	now = now.Add(11 * time.Minute)
+	_, err = tm.ValidateAccessToken(accessToken)
+	assert.Error(t, err, "Token should be expired once the clock passes its TTL")
+}
+
+// TestIssuerAndAudienceValidation ensures tokens from another environment are
+// rejected even when the secrets are shared.
+func TestIssuerAndAudienceValidation(t *testing.T) {
+	newManager := func(issuer string, audience ...string) TokenManager {
+		return NewJWTManager(JWTConfig{
+			AccessSecret:    []byte("shared-secret"),
+			RefreshSecret:   []byte("shared-refresh-secret"),
+			Issuer:          issuer,
+			AccessTokenTTL:  5 * time.Minute,
+			RefreshTokenTTL: time.Hour,
+			SigningMethod:   jwt.SigningMethodHS256.Alg(),
+			Audience:        audience,
+		})
+	}
+	production := newManager("auth.example.com", "app:production")
+
+	token, err := production.GenerateAccessToken("user-1", nil)
+	require.NoError(t, err)
+	claims, err := production.ValidateAccessToken(token)
+	require.NoError(t, err, "Token should validate in its own environment")
+	assert.Equal(t, []interface{}{"app:production"}, claims["aud"])
+
+	stagingToken, _ := newManager("auth.example.com", "app:staging").GenerateAccessToken("user-1", nil)
+	_, err = production.ValidateAccessToken(stagingToken)
+	assert.Error(t, err, "Token for another audience should be rejected")
+
+	otherIssuerToken, _ := newManager("staging-auth.example.com", "app:production").GenerateAccessToken("user-1", nil)
+	_, err = production.ValidateAccessToken(otherIssuerToken)
+	assert.Error(t, err, "Token from another issuer should be rejected")
+
+	stagingRefresh, _ := newManager("auth.example.com", "app:staging").GenerateRefreshToken("user-1")
+	_, err = production.RefreshAccessToken(stagingRefresh)
+	assert.Error(t, err, "Refresh token for another audience should be rejected")
+
+	// Explicit expectations allow accepting several issuers during a migration
+	migrating := NewJWTManager(JWTConfig{
+		AccessSecret:      []byte("shared-secret"),
+		Issuer:            "auth.example.com",
+		AccessTokenTTL:    5 * time.Minute,
+		SigningMethod:     jwt.SigningMethodHS256.Alg(),
+		ExpectedIssuers:   []string{"auth.example.com", "staging-auth.example.com"},
+		ExpectedAudiences: []string{"app:production"},
+	})
+	_, err = migrating.ValidateAccessToken(otherIssuerToken)
+	assert.NoError(t, err, "Token from an expected issuer should validate")
+}
+
+// TestRefreshAudience ensures refresh tokens carry their own audience.
+func TestRefreshAudience(t *testing.T) {
+	manager := NewJWTManager(JWTConfig{
+		AccessSecret:    []byte("shared-secret"),
+		RefreshSecret:   []byte("shared-secret"),
+		Issuer:          "auth.example.com",
+		AccessTokenTTL:  5 * time.Minute,
+		RefreshTokenTTL: time.Hour,
+		SigningMethod:   jwt.SigningMethodHS256.Alg(),
+		Audience:        []string{"api"},
+		RefreshAudience: []string{"auth.example.com/refresh"},
+	})
+
+	refreshToken, err := manager.GenerateRefreshToken("user-1")
+	require.NoError(t, err)
+	claims, err := manager.ValidateRefreshToken(refreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"auth.example.com/refresh"}, claims["aud"])
+	_, err = manager.RefreshAccessToken(refreshToken)
+	assert.NoError(t, err)
+
+	// Even with a shared secret, the audiences keep the classes apart
+	accessToken, err := manager.GenerateAccessToken("user-1", nil)
+	require.NoError(t, err)
+	_, err = manager.ValidateRefreshToken(accessToken)
+	assert.Error(t, err, "Access token should not validate as a refresh token")
+	_, err = manager.ValidateAccessToken(refreshToken)
+	assert.Error(t, err, "Refresh token should not validate as an access token")
+}
+
+// TestLeeway ensures tokens from an instance whose clock runs slightly ahead
+// validate once a leeway is configured.
+func TestLeeway(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	newManager := func(skew, leeway time.Duration) TokenManager {
+		return NewJWTManager(JWTConfig{
+			AccessSecret:   []byte("leeway-secret"),
+			Issuer:         "test-leeway",
+			AccessTokenTTL: time.Minute,
+			SigningMethod:  jwt.SigningMethodHS256.Alg(),
+			Now:            func() time.Time { return now.Add(skew) },
+			Leeway:         leeway,
+		})
+	}
+
+	// Issued by an instance 3 seconds ahead, so "iat" lies in the future
+	token, err := newManager(3*time.Second, 0).GenerateAccessToken("user-1", nil)
+	require.NoError(t, err)
+
+	_, err = newManager(0, 0).ValidateAccessToken(token)
+	assert.Error(t, err, "Token issued in the future should be rejected without leeway")
+
+	tolerant := newManager(0, 5*time.Second)
+	_, err = tolerant.ValidateAccessToken(token)
+	assert.NoError(t, err, "Token within the leeway should validate")
+
+	// Leeway also extends expiry by the same amount
+	now = now.Add(time.Minute + 5*time.Second)
+	_, err = tolerant.ValidateAccessToken(token)
+	assert.NoError(t, err, "Token expired within the leeway should validate")
+	now = now.Add(5 * time.Second)
+	_, err = tolerant.ValidateAccessToken(token)
+	assert.Error(t, err, "Token expired beyond the leeway should be rejected")
+}
+
+// TestScheduledActivation ensures tokens issued with a future "nbf" only
+// validate from then on and keep their full lifetime.
+func TestScheduledActivation(t *testing.T) {
+	now := time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC)
+	tm := NewJWTManager(JWTConfig{
+		AccessSecret:    []byte("schedule-secret"),
+		RefreshSecret:   []byte("schedule-refresh-secret"),
+		Issuer:          "test-schedule",
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+		SigningMethod:   jwt.SigningMethodHS256.Alg(),
+		Now:             func() time.Time { return now },
+	})
+	opening := now.Add(3 * time.Hour)
+
+	accessToken, err := tm.GenerateAccessTokenWithOptions("kiosk", nil, IssueOptions{NotBefore: opening})
+	require.NoError(t, err)
+	refreshToken, err := tm.GenerateRefreshTokenWithOptions("kiosk", IssueOptions{NotBefore: opening})
+	require.NoError(t, err)
+
+	_, err = tm.ValidateAccessToken(accessToken)
+	assert.Error(t, err, "Access token should not be valid before activation")
+	_, err = tm.ValidateRefreshToken(refreshToken)
+	assert.Error(t, err, "Refresh token should not be valid before activation")
+
+	now = opening.Add(59 * time.Second)
+	claims, err := tm.ValidateAccessToken(accessToken)
+	require.NoError(t, err, "Access token should be valid after activation")
+	assert.Equal(t, float64(opening.Unix()), claims["nbf"])
+	_, err = tm.ValidateRefreshToken(refreshToken)
+	assert.NoError(t, err, "Refresh token should be valid after activation")
+}
+
+// TestTTLOverride ensures IssueOptions.TTL replaces the configured TTL.
+func TestTTLOverride(t *testing.T) {
+	now := time.Date(2024, 1, 1, 6, 0, 0, 0, time.UTC)
+	tm := NewJWTManager(JWTConfig{
+		AccessSecret:    []byte("ttl-secret"),
+		RefreshSecret:   []byte("ttl-refresh-secret"),
+		Issuer:          "test-ttl",
+		AccessTokenTTL:  time.Minute,
+		RefreshTokenTTL: time.Hour,
+		SigningMethod:   jwt.SigningMethodHS256.Alg(),
+		Now:             func() time.Time { return now },
+	})
+
+	accessToken, err := tm.GenerateAccessTokenWithOptions("user-ttl", nil, IssueOptions{TTL: 5 * time.Minute})
+	require.NoError(t, err)
+	refreshToken, err := tm.GenerateRefreshTokenWithOptions("user-ttl", IssueOptions{TTL: 2 * time.Hour})
+	require.NoError(t, err)
+
+	claims, err := tm.ValidateAccessToken(accessToken)
+	require.NoError(t, err)
+	assert.Equal(t, float64(now.Add(5*time.Minute).Unix()), claims["exp"])
+	claims, err = tm.ValidateRefreshToken(refreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, float64(now.Add(2*time.Hour).Unix()), claims["exp"])
+}
+
+// opaqueSigner hides the private key type, like a KMS or HSM key.
+type opaqueSigner struct {
+	crypto.Signer
+}
+
+// TestCryptoSigner checks that tokens are signed by any crypto.Signer.
+func TestCryptoSigner(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	for method, key := range map[string]crypto.Signer{"ES384": ecKey, "PS256": rsaKey, "RS256": rsaKey} {
+		tm := NewJWTManager(JWTConfig{
+			Issuer:          "test-issuer",
+			AccessTokenTTL:  5 * time.Minute,
+			RefreshTokenTTL: time.Hour,
+			SigningMethod:   method,
+			SigningKey:      opaqueSigner{key},
+		})
+		accessToken, err := tm.GenerateAccessToken("user-123", nil)
+		require.NoError(t, err, method)
+		claims, err := tm.ValidateAccessToken(accessToken)
+		require.NoError(t, err, method)
+		assert.Equal(t, "user-123", claims["sub"], method)
+
+		refreshToken, err := tm.GenerateRefreshToken("user-123")
+		require.NoError(t, err, method)
+		_, err = tm.ValidateAccessToken(refreshToken)
+		assert.Error(t, err, "%s: refresh tokens must not validate as access tokens", method)
+	}
+}
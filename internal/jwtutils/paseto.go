@@ -0,0 +1,302 @@
+package jwtutils
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/hkdf"
+)
+
+// pasetoLocalHeader is the header of PASETO v4 tokens for local use.
+const pasetoLocalHeader = "v4.local."
+
+// pasetoTimeClaims are encoded as RFC 3339 strings, as PASETO requires.
+var pasetoTimeClaims = []string{"exp", "iat", "nbf"}
+
+// PASETOManager is a TokenManager that issues PASETO v4.local tokens:
+// claims are encrypted and authenticated with keys derived from the access
+// and refresh secrets. The claims returned by validation are the same as
+// those of JWTManager, with timestamps as Unix seconds.
+type PASETOManager struct {
+	jwt        *JWTManager // builds the claims and checks issuer and audience
+	accessKey  []byte
+	refreshKey []byte
+}
+
+// NewPASETOManager creates a TokenManager issuing PASETO v4.local tokens.
+// SigningMethod is ignored.
+func NewPASETOManager(cfg JWTConfig) TokenManager {
+	return &PASETOManager{
+		jwt:        &JWTManager{cfg: cfg},
+		accessKey:  pasetoKey(cfg.AccessSecret, "access"),
+		refreshKey: pasetoKey(cfg.RefreshSecret, "refresh"),
+	}
+}
+
+// pasetoKey derives a 256-bit key from secret, or returns nil without one.
+func pasetoKey(secret []byte, purpose string) []byte {
+	if len(secret) == 0 {
+		return nil
+	}
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha512.New, secret, nil, []byte("go-auth paseto v4.local "+purpose)), key); err != nil {
+		panic(err)
+	}
+	return key
+}
+
+// GenerateAccessToken creates a new access token with the specified custom claims.
+func (m *PASETOManager) GenerateAccessToken(userID string, customClaims map[string]any) (string, error) {
+	return m.GenerateAccessTokenWithOptions(userID, customClaims, IssueOptions{})
+}
+
+// GenerateAccessTokenWithOptions creates an access token whose validity is
+// scheduled by opts.
+func (m *PASETOManager) GenerateAccessTokenWithOptions(userID string, customClaims map[string]any, opts IssueOptions) (string, error) {
+	if m.accessKey == nil {
+		return "", errors.New("access secret key cannot be empty in config")
+	}
+	token, err := pasetoEncrypt(m.accessKey, m.jwt.accessClaims(userID, customClaims, opts))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt access token: %w", err)
+	}
+	return token, nil
+}
+
+// GenerateRefreshToken creates a long-lived refresh token.
+func (m *PASETOManager) GenerateRefreshToken(userID string) (string, error) {
+	return m.GenerateRefreshTokenWithOptions(userID, IssueOptions{})
+}
+
+// GenerateRefreshTokenWithOptions creates a refresh token whose validity is
+// scheduled by opts.
+func (m *PASETOManager) GenerateRefreshTokenWithOptions(userID string, opts IssueOptions) (string, error) {
+	if m.refreshKey == nil {
+		return "", errors.New("refresh secret key cannot be empty in config")
+	}
+	token, err := pasetoEncrypt(m.refreshKey, m.jwt.refreshClaims(userID, opts))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt refresh token: %w", err)
+	}
+	return token, nil
+}
+
+// RefreshAccessToken validates a refresh token and issues a new, clean access token.
+func (m *PASETOManager) RefreshAccessToken(refreshToken string) (string, error) {
+	claims, err := m.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		return "", fmt.Errorf("could not validate refresh token: %w", err)
+	}
+	if tokenType, ok := claims["token_type"].(string); !ok || tokenType != "refresh" {
+		return "", errors.New("token is not a valid refresh token")
+	}
+	userID, ok := claims["sub"].(string)
+	if !ok {
+		return "", errors.New("invalid user ID in refresh token claims")
+	}
+	return m.GenerateAccessToken(userID, nil)
+}
+
+// ValidateAccessToken decrypts and validates an access token.
+func (m *PASETOManager) ValidateAccessToken(accessToken string) (jwt.MapClaims, error) {
+	return m.parseToken(accessToken, m.accessKey, m.jwt.expectedAudiences())
+}
+
+// ValidateRefreshToken decrypts and validates a refresh token.
+func (m *PASETOManager) ValidateRefreshToken(refreshToken string) (jwt.MapClaims, error) {
+	return m.parseToken(refreshToken, m.refreshKey, m.jwt.expectedRefreshAudiences())
+}
+
+// parseToken decrypts token and checks its timestamps, issuer and audience.
+func (m *PASETOManager) parseToken(token string, key []byte, audiences []string) (jwt.MapClaims, error) {
+	if key == nil {
+		return nil, errors.New("token validation failed: secret key cannot be empty in config")
+	}
+	claims, err := pasetoDecrypt(key, token)
+	if err != nil {
+		return nil, fmt.Errorf("token validation failed: %w", err)
+	}
+	if err := m.checkTimes(claims); err != nil {
+		return nil, fmt.Errorf("token validation failed: %w", err)
+	}
+	if err := m.jwt.checkIssuerAndAudience(claims, audiences); err != nil {
+		return nil, fmt.Errorf("token validation failed: %w", err)
+	}
+	return claims, nil
+}
+
+// checkTimes converts the RFC 3339 timestamps of claims to Unix seconds and
+// rejects expired tokens and tokens that are not valid yet.
+func (m *PASETOManager) checkTimes(claims jwt.MapClaims) error {
+	now := m.jwt.now()
+	leeway := m.jwt.cfg.Leeway
+	for _, name := range pasetoTimeClaims {
+		value, ok := claims[name]
+		if !ok {
+			continue
+		}
+		s, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("invalid %s claim", name)
+		}
+		t, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			return fmt.Errorf("invalid %s claim: %w", name, err)
+		}
+		switch name {
+		case "exp":
+			if !now.Before(t.Add(leeway)) {
+				return errors.New("token is expired")
+			}
+		case "nbf":
+			if now.Add(leeway).Before(t) {
+				return errors.New("token is not valid yet")
+			}
+		case "iat":
+			if now.Add(leeway).Before(t) {
+				return errors.New("token used before issued")
+			}
+		}
+		claims[name] = float64(t.Unix())
+	}
+	if _, ok := claims["exp"]; !ok {
+		return errors.New("token has no exp claim")
+	}
+	return nil
+}
+
+// pasetoEncrypt encodes claims as a v4.local token, following
+// https://github.com/paseto-standard/paseto-spec/blob/master/docs/01-Protocol-Versions/Version4.md
+func pasetoEncrypt(key []byte, claims jwt.MapClaims) (string, error) {
+	payload := make(map[string]any, len(claims))
+	for k, v := range claims {
+		payload[k] = v
+	}
+	for _, name := range pasetoTimeClaims {
+		if seconds, ok := payload[name].(int64); ok {
+			payload[name] = time.Unix(seconds, 0).UTC().Format(time.RFC3339)
+		}
+	}
+	message, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return pasetoSeal(key, nonce, message)
+}
+
+// pasetoSeal encrypts message as a v4.local token with nonce.
+func pasetoSeal(key, nonce, message []byte) (string, error) {
+	encryptionKey, counterNonce, authKey, err := pasetoSplitKey(key, nonce)
+	if err != nil {
+		return "", err
+	}
+	cipher, err := chacha20.NewUnauthenticatedCipher(encryptionKey, counterNonce)
+	if err != nil {
+		return "", err
+	}
+	ciphertext := make([]byte, len(message))
+	cipher.XORKeyStream(ciphertext, message)
+
+	tag, err := pasetoTag(authKey, nonce, ciphertext)
+	if err != nil {
+		return "", err
+	}
+	body := append(append(nonce, ciphertext...), tag...)
+	return pasetoLocalHeader + base64.RawURLEncoding.EncodeToString(body), nil
+}
+
+// pasetoDecrypt authenticates and decrypts a v4.local token without footer.
+func pasetoDecrypt(key []byte, token string) (jwt.MapClaims, error) {
+	encoded, ok := strings.CutPrefix(token, pasetoLocalHeader)
+	if !ok || strings.Contains(encoded, ".") {
+		return nil, errors.New("not a PASETO v4.local token")
+	}
+	body, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(body) < 64 {
+		return nil, errors.New("malformed token")
+	}
+	nonce, ciphertext, tag := body[:32], body[32:len(body)-32], body[len(body)-32:]
+
+	encryptionKey, counterNonce, authKey, err := pasetoSplitKey(key, nonce)
+	if err != nil {
+		return nil, err
+	}
+	expected, err := pasetoTag(authKey, nonce, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(tag, expected) != 1 {
+		return nil, errors.New("invalid token authentication tag")
+	}
+
+	cipher, err := chacha20.NewUnauthenticatedCipher(encryptionKey, counterNonce)
+	if err != nil {
+		return nil, err
+	}
+	message := make([]byte, len(ciphertext))
+	cipher.XORKeyStream(message, ciphertext)
+
+	claims := jwt.MapClaims{}
+	if err := json.Unmarshal(message, &claims); err != nil {
+		return nil, fmt.Errorf("invalid claims: %w", err)
+	}
+	return claims, nil
+}
+
+// pasetoSplitKey derives the encryption key, the XChaCha20 nonce and the
+// authentication key of a token from key and its nonce.
+func pasetoSplitKey(key, nonce []byte) (encryptionKey, counterNonce, authKey []byte, err error) {
+	h, err := blake2b.New(56, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	h.Write([]byte("paseto-encryption-key"))
+	h.Write(nonce)
+	tmp := h.Sum(nil)
+
+	h, err = blake2b.New(32, key)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	h.Write([]byte("paseto-auth-key-for-aead"))
+	h.Write(nonce)
+	return tmp[:32], tmp[32:], h.Sum(nil), nil
+}
+
+// pasetoTag returns the BLAKE2b-MAC of the pre-authentication encoding of
+// the token without footer and implicit assertion.
+func pasetoTag(authKey, nonce, ciphertext []byte) ([]byte, error) {
+	h, err := blake2b.New(32, authKey)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(preAuthEncode([]byte(pasetoLocalHeader), nonce, ciphertext, nil, nil))
+	return h.Sum(nil), nil
+}
+
+// preAuthEncode implements PAE, the pre-authentication encoding of PASETO.
+func preAuthEncode(pieces ...[]byte) []byte {
+	out := binary.LittleEndian.AppendUint64(nil, uint64(len(pieces)))
+	for _, piece := range pieces {
+		out = binary.LittleEndian.AppendUint64(out, uint64(len(piece))&^(1<<63))
+		out = append(out, piece...)
+	}
+	return out
+}
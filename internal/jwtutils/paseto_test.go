@@ -0,0 +1,94 @@
+package jwtutils
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pasetoVectors are the v4.local test vectors without footer or implicit
+// assertion from https://github.com/paseto-standard/test-vectors (v4.json).
+var pasetoVectors = []struct {
+	name    string
+	key     string
+	nonce   string
+	payload string
+	token   string
+}{
+	{
+		name:    "4-E-1",
+		key:     "707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f",
+		nonce:   "0000000000000000000000000000000000000000000000000000000000000000",
+		payload: `{"data":"this is a secret message","exp":"2022-01-01T00:00:00+00:00"}`,
+		token:   "v4.local.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQAr68PS4AXe7If_ZgesdkUMvSwscFlAl1pk5HC0e8kApeaqMfGo_7OpBnwJOAbY9V7WU6abu74MmcUE8YWAiaArVI8XJ5hOb_4v9RmDkneN0S92dx0OW4pgy7omxgf3S8c3LlQg",
+	},
+	{
+		name:    "4-E-2",
+		key:     "707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f",
+		nonce:   "0000000000000000000000000000000000000000000000000000000000000000",
+		payload: `{"data":"this is a hidden message","exp":"2022-01-01T00:00:00+00:00"}`,
+		token:   "v4.local.AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAQAr68PS4AXe7If_ZgesdkUMvS2csCgglvpk5HC0e8kApeaqMfGo_7OpBnwJOAbY9V7WU6abu74MmcUE8YWAiaArVI8XIemu9chy3WVKvRBfg6t8wwYHK0ArLxxfZP73W_vfwt5A",
+	},
+	{
+		name:    "4-E-3",
+		key:     "707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f",
+		nonce:   "df654812bac492663825520ba2f6e67cf5ca5bdc13d4e7507a98cc4c2fcc3ad8",
+		payload: `{"data":"this is a secret message","exp":"2022-01-01T00:00:00+00:00"}`,
+		token:   "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WkwMsYXw6FSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t6-tyebyWG6Ov7kKvBdkrrAJ837lKP3iDag2hzUPHuMKA",
+	},
+	{
+		name:    "4-E-4",
+		key:     "707172737475767778797a7b7c7d7e7f808182838485868788898a8b8c8d8e8f",
+		nonce:   "df654812bac492663825520ba2f6e67cf5ca5bdc13d4e7507a98cc4c2fcc3ad8",
+		payload: `{"data":"this is a hidden message","exp":"2022-01-01T00:00:00+00:00"}`,
+		token:   "v4.local.32VIErrEkmY4JVILovbmfPXKW9wT1OdQepjMTC_MOtjA4kiqw7_tcaOM5GNEcnTxl60WiA8rd3wgFSNb_UdJPXjpzm0KW9ojM5f4O2mRvE2IcweP-PRdoHjd5-RHCiExR1IK6t4gt6TiLm55vIH8c_lGxxZpE3AWlH4WTR0v45nsWoU3gQ",
+	},
+}
+
+// TestPASETOVectors checks encryption and decryption against the official
+// test vectors.
+func TestPASETOVectors(t *testing.T) {
+	for _, vector := range pasetoVectors {
+		t.Run(vector.name, func(t *testing.T) {
+			key, err := hex.DecodeString(vector.key)
+			require.NoError(t, err)
+			nonce, err := hex.DecodeString(vector.nonce)
+			require.NoError(t, err)
+
+			token, err := pasetoSeal(key, nonce, []byte(vector.payload))
+			require.NoError(t, err)
+			assert.Equal(t, vector.token, token)
+
+			claims, err := pasetoDecrypt(key, vector.token)
+			require.NoError(t, err)
+			assert.Equal(t, vector.payload[len(`{"data":"`):strings.Index(vector.payload, `","exp"`)], claims["data"])
+			assert.Equal(t, "2022-01-01T00:00:00+00:00", claims["exp"])
+		})
+	}
+}
+
+// TestPASETORejectsTampering checks that tokens only decrypt unmodified and
+// with their key.
+func TestPASETORejectsTampering(t *testing.T) {
+	vector := pasetoVectors[0]
+	key, err := hex.DecodeString(vector.key)
+	require.NoError(t, err)
+
+	otherKey := append([]byte(nil), key...)
+	otherKey[0] ^= 1
+	_, err = pasetoDecrypt(otherKey, vector.token)
+	assert.Error(t, err, "wrong key")
+
+	tampered := []byte(vector.token)
+	tampered[len(tampered)-10] ^= 1
+	_, err = pasetoDecrypt(key, string(tampered))
+	assert.Error(t, err, "modified tag")
+
+	_, err = pasetoDecrypt(key, strings.Replace(vector.token, "v4.local.", "v3.local.", 1))
+	assert.Error(t, err, "other version")
+	_, err = pasetoDecrypt(key, vector.token+".e30")
+	assert.Error(t, err, "unsupported footer")
+}
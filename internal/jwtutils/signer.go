@@ -0,0 +1,89 @@
+package jwtutils
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signerMethod signs with any crypto.Signer, such as a key held by a KMS or
+// an HSM that never leaves it. The methods of the jwt package only sign
+// with in-memory *rsa.PrivateKey and *ecdsa.PrivateKey values; verification
+// is left to them.
+type signerMethod struct {
+	jwt.SigningMethod
+}
+
+// withSigner returns method wrapped to sign with key, unless the jwt package
+// signs with key itself.
+func withSigner(method jwt.SigningMethod, key crypto.Signer) jwt.SigningMethod {
+	switch key.(type) {
+	case *rsa.PrivateKey, *ecdsa.PrivateKey:
+		return method
+	}
+	return signerMethod{SigningMethod: method}
+}
+
+// Sign hashes signingString and delegates the signature to key, a
+// crypto.Signer, converting ECDSA signatures to the JWS format.
+func (m signerMethod) Sign(signingString string, key interface{}) ([]byte, error) {
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, jwt.ErrInvalidKeyType
+	}
+
+	var hash crypto.Hash
+	var opts crypto.SignerOpts
+	switch method := m.SigningMethod.(type) {
+	case *jwt.SigningMethodRSA:
+		hash, opts = method.Hash, method.Hash
+	case *jwt.SigningMethodRSAPSS:
+		hash = method.Hash
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hash}
+	case *jwt.SigningMethodECDSA:
+		hash, opts = method.Hash, method.Hash
+	default:
+		return nil, fmt.Errorf("signing method %s does not support crypto.Signer keys", m.Alg())
+	}
+	if !hash.Available() {
+		return nil, jwt.ErrHashUnavailable
+	}
+	hasher := hash.New()
+	hasher.Write([]byte(signingString))
+
+	signature, err := signer.Sign(rand.Reader, hasher.Sum(nil), opts)
+	if err != nil {
+		return nil, fmt.Errorf("signer failed: %w", err)
+	}
+	if method, ok := m.SigningMethod.(*jwt.SigningMethodECDSA); ok {
+		return ecdsaJWSSignature(signature, method.KeySize)
+	}
+	return signature, nil
+}
+
+// ecdsaJWSSignature converts an ASN.1 DER ECDSA signature, as returned by
+// crypto.Signer, to the fixed-size r || s encoding of JWS (RFC 7518).
+func ecdsaJWSSignature(der []byte, keySize int) ([]byte, error) {
+	var parsed struct {
+		R, S *big.Int
+	}
+	rest, err := asn1.Unmarshal(der, &parsed)
+	if err != nil || len(rest) > 0 {
+		return nil, errors.New("signer returned a malformed ECDSA signature")
+	}
+	if parsed.R.Sign() <= 0 || parsed.S.Sign() <= 0 ||
+		len(parsed.R.Bytes()) > keySize || len(parsed.S.Bytes()) > keySize {
+		return nil, errors.New("signer returned an ECDSA signature of the wrong size")
+	}
+	signature := make([]byte, 2*keySize)
+	parsed.R.FillBytes(signature[:keySize])
+	parsed.S.FillBytes(signature[keySize:])
+	return signature, nil
+}
@@ -23,37 +23,31 @@ func NewJWTManager(cfg JWTConfig) TokenManager {
 	}
 }
 
-// GenerateAccessToken creates a new access token with the specified custom claims.
-func (m *JWTManager) GenerateAccessToken(userID string, customClaims map[string]any) (string, error) {
-	if m.cfg.SigningMethod == "" {
-		return "", errors.New("JWT signing method cannot be empty in config")
-	}
-	if len(m.cfg.AccessSecret) == 0 {
-		return "", errors.New("JWT access secret key cannot be empty in config")
+// now returns the current time from the configured clock.
+func (m *JWTManager) now() time.Time {
+	if m.cfg.Now != nil {
+		return m.cfg.Now()
 	}
+	return time.Now()
+}
 
-	claims := jwt.MapClaims{
-		"iss":        m.cfg.Issuer,
-		"exp":        time.Now().Add(m.cfg.AccessTokenTTL).Unix(),
-		"iat":        time.Now().Unix(),
-		"sub":        userID,
-		"nbf":        time.Now().Unix(),
-		"jti":        uuid.New().String(),
-		"token_type": "access",
-	}
+// GenerateAccessToken creates a new access token with the specified custom claims.
+func (m *JWTManager) GenerateAccessToken(userID string, customClaims map[string]any) (string, error) {
+	return m.GenerateAccessTokenWithOptions(userID, customClaims, IssueOptions{})
+}
 
-	// Securely copy custom claims, ensuring they don't overwrite standard claims.
-	if customClaims != nil {
-		maps.Copy(claims, customClaims)
+// GenerateAccessTokenWithOptions creates an access token whose validity is
+// scheduled by opts, e.g. one that only becomes valid at a future time.
+func (m *JWTManager) GenerateAccessTokenWithOptions(userID string, customClaims map[string]any, opts IssueOptions) (string, error) {
+	method, key, err := m.signing(m.cfg.AccessSecret, "access")
+	if err != nil {
+		return "", err
 	}
 
-	method, ok := signingMethods[m.cfg.SigningMethod]
-	if !ok {
-		return "", fmt.Errorf("unsupported signing method in config: %s", m.cfg.SigningMethod)
-	}
+	claims := m.accessClaims(userID, customClaims, opts)
 
 	token := jwt.NewWithClaims(method, claims)
-	signedToken, err := token.SignedString(m.cfg.AccessSecret)
+	signedToken, err := token.SignedString(key)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign access token: %w", err)
 	}
@@ -61,31 +55,67 @@ func (m *JWTManager) GenerateAccessToken(userID string, customClaims map[string]
 	return signedToken, nil
 }
 
-// GenerateRefreshToken creates a simple, long-lived refresh token.
-func (m *JWTManager) GenerateRefreshToken(userID string) (string, error) {
+// signing returns the signing method and the key signing tokens of the
+// kind secret belongs to: secret for HS* methods, the signing key for the
+// others.
+func (m *JWTManager) signing(secret []byte, kind string) (jwt.SigningMethod, interface{}, error) {
 	if m.cfg.SigningMethod == "" {
-		return "", errors.New("JWT signing method cannot be empty in config")
+		return nil, nil, errors.New("JWT signing method cannot be empty in config")
 	}
-	if len(m.cfg.RefreshSecret) == 0 {
-		return "", errors.New("JWT refresh secret key cannot be empty in config")
+	method, ok := signingMethods[m.cfg.SigningMethod]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported signing method in config: %s", m.cfg.SigningMethod)
 	}
-
-	claims := jwt.MapClaims{
-		"iss":        m.cfg.Issuer,
-		"exp":        time.Now().Add(m.cfg.RefreshTokenTTL).Unix(),
-		"iat":        time.Now().Unix(),
-		"sub":        userID,
-		"jti":        uuid.New().String(),
-		"token_type": "refresh",
+	if symmetric(method) {
+		if len(secret) == 0 {
+			return nil, nil, fmt.Errorf("JWT %s secret key cannot be empty in config", kind)
+		}
+		return method, secret, nil
 	}
+	if m.cfg.SigningKey == nil {
+		return nil, nil, fmt.Errorf("JWT signing key cannot be empty in config for %s", m.cfg.SigningMethod)
+	}
+	return withSigner(method, m.cfg.SigningKey), m.cfg.SigningKey, nil
+}
 
-	method, ok := signingMethods[m.cfg.SigningMethod]
-	if !ok {
-		return "", fmt.Errorf("unsupported signing method in config: %s", m.cfg.SigningMethod)
+// accessClaims returns the claims of an access token. Custom claims are
+// copied first so that the standard claims overwrite them.
+func (m *JWTManager) accessClaims(userID string, customClaims map[string]any, opts IssueOptions) jwt.MapClaims {
+	claims := jwt.MapClaims{}
+	maps.Copy(claims, customClaims)
+	maps.Copy(claims, opts.Claims)
+
+	iat, nbf, exp := opts.times(m.now(), m.cfg.AccessTokenTTL)
+	claims["iss"] = m.cfg.Issuer
+	claims["exp"] = exp.Unix()
+	claims["iat"] = iat.Unix()
+	claims["sub"] = userID
+	claims["nbf"] = nbf.Unix()
+	claims["jti"] = uuid.New().String()
+	claims["token_type"] = "access"
+	if len(m.cfg.Audience) > 0 {
+		claims["aud"] = m.cfg.Audience
+	}
+	return claims
+}
+
+// GenerateRefreshToken creates a simple, long-lived refresh token.
+func (m *JWTManager) GenerateRefreshToken(userID string) (string, error) {
+	return m.GenerateRefreshTokenWithOptions(userID, IssueOptions{})
+}
+
+// GenerateRefreshTokenWithOptions creates a refresh token whose validity is
+// scheduled by opts.
+func (m *JWTManager) GenerateRefreshTokenWithOptions(userID string, opts IssueOptions) (string, error) {
+	method, key, err := m.signing(m.cfg.RefreshSecret, "refresh")
+	if err != nil {
+		return "", err
 	}
 
+	claims := m.refreshClaims(userID, opts)
+
 	token := jwt.NewWithClaims(method, claims)
-	signedToken, err := token.SignedString(m.cfg.RefreshSecret)
+	signedToken, err := token.SignedString(key)
 	if err != nil {
 		return "", fmt.Errorf("failed to sign refresh token: %w", err)
 	}
@@ -93,6 +123,31 @@ func (m *JWTManager) GenerateRefreshToken(userID string) (string, error) {
 	return signedToken, nil
 }
 
+// refreshClaims returns the claims of a refresh token.
+func (m *JWTManager) refreshClaims(userID string, opts IssueOptions) jwt.MapClaims {
+	iat, nbf, exp := opts.times(m.now(), m.cfg.RefreshTokenTTL)
+	claims := jwt.MapClaims{
+		"iss":        m.cfg.Issuer,
+		"exp":        exp.Unix(),
+		"iat":        iat.Unix(),
+		"sub":        userID,
+		"jti":        uuid.New().String(),
+		"token_type": "refresh",
+	}
+	if !opts.IssuedAt.IsZero() || !opts.NotBefore.IsZero() {
+		claims["nbf"] = nbf.Unix()
+	}
+	for k, v := range opts.Claims {
+		if _, ok := claims[k]; !ok {
+			claims[k] = v
+		}
+	}
+	if audience := m.refreshAudience(); len(audience) > 0 {
+		claims["aud"] = audience
+	}
+	return claims
+}
+
 // RefreshAccessToken validates a refresh token and issues a new, clean access token.
 func (m *JWTManager) RefreshAccessToken(refreshTokenStr string) (string, error) {
 	// 1. Validate the refresh token using the simplified validation method.
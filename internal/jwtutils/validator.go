@@ -3,33 +3,40 @@ package jwtutils
 import (
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 // ValidateAccessToken validates an access token string using the secret from the JWTManager config.
 func (m *JWTManager) ValidateAccessToken(accessToken string) (jwt.MapClaims, error) {
-	return m.parseToken(accessToken, m.cfg.AccessSecret)
+	return m.parseToken(accessToken, m.cfg.AccessSecret, m.expectedAudiences(), "access")
 }
 
 // ValidateRefreshToken validates a refresh token string using the secret from the JWTManager config.
 func (m *JWTManager) ValidateRefreshToken(refreshToken string) (jwt.MapClaims, error) {
-	return m.parseToken(refreshToken, m.cfg.RefreshSecret)
+	return m.parseToken(refreshToken, m.cfg.RefreshSecret, m.expectedRefreshAudiences(), "refresh")
 }
 
-// parseToken is an internal helper that parses a token string with a given secret.
-func (m *JWTManager) parseToken(tokenStr string, secret []byte) (jwt.MapClaims, error) {
+// parseToken is an internal helper that parses a token string of the
+// given type with a given secret, or the verification key of asymmetric
+// methods.
+func (m *JWTManager) parseToken(tokenStr string, secret []byte, audiences []string, tokenType string) (jwt.MapClaims, error) {
+	method, ok := signingMethods[m.cfg.SigningMethod]
 	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
 		// Check that the signing method is the one specified in the config.
-		if _, ok := signingMethods[m.cfg.SigningMethod]; !ok {
+		if !ok {
 			return nil, fmt.Errorf("unsupported signing method: %s", m.cfg.SigningMethod)
 		}
-		if token.Method.Alg() != signingMethods[m.cfg.SigningMethod].Alg() {
+		if token.Method.Alg() != method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 
-		return secret, nil
-	})
+		if symmetric(method) {
+			return secret, nil
+		}
+		return m.verificationKey()
+	}, jwt.WithTimeFunc(m.now), jwt.WithLeeway(m.cfg.Leeway), jwt.WithIssuedAt())
 
 	if err != nil {
 		// The library returns a detailed error, e.g., if the token is expired.
@@ -42,5 +49,76 @@ func (m *JWTManager) parseToken(tokenStr string, secret []byte) (jwt.MapClaims,
 		return nil, errors.New("invalid token or claims")
 	}
 
+	if err := m.checkIssuerAndAudience(claims, audiences); err != nil {
+		return nil, fmt.Errorf("token validation failed: %w", err)
+	}
+	// One key signs both types with asymmetric methods
+	if !symmetric(method) && claims["token_type"] != tokenType {
+		return nil, fmt.Errorf("token validation failed: not a %s token", tokenType)
+	}
+
 	return claims, nil
+}
+
+// verificationKey returns the public key verifying tokens of asymmetric
+// methods.
+func (m *JWTManager) verificationKey() (interface{}, error) {
+	if m.cfg.VerificationKey != nil {
+		return m.cfg.VerificationKey, nil
+	}
+	if m.cfg.SigningKey != nil {
+		return m.cfg.SigningKey.Public(), nil
+	}
+	return nil, fmt.Errorf("no verification key configured for %s", m.cfg.SigningMethod)
+}
+
+// expectedAudiences returns the accepted audiences of access tokens.
+func (m *JWTManager) expectedAudiences() []string {
+	if len(m.cfg.ExpectedAudiences) > 0 {
+		return m.cfg.ExpectedAudiences
+	}
+	return m.cfg.Audience
+}
+
+// refreshAudience returns the audience of issued refresh tokens.
+func (m *JWTManager) refreshAudience() []string {
+	if len(m.cfg.RefreshAudience) > 0 {
+		return m.cfg.RefreshAudience
+	}
+	return m.cfg.Audience
+}
+
+// expectedRefreshAudiences returns the accepted audiences of refresh tokens.
+func (m *JWTManager) expectedRefreshAudiences() []string {
+	if len(m.cfg.RefreshAudience) > 0 {
+		return m.cfg.RefreshAudience
+	}
+	return m.expectedAudiences()
+}
+
+// checkIssuerAndAudience rejects tokens minted for another issuer or for
+// none of audiences, e.g. by a staging deployment that shares secrets with
+// production. No audience is required when audiences is empty.
+func (m *JWTManager) checkIssuerAndAudience(claims jwt.MapClaims, audiences []string) error {
+	issuers := m.cfg.ExpectedIssuers
+	if len(issuers) == 0 && m.cfg.Issuer != "" {
+		issuers = []string{m.cfg.Issuer}
+	}
+	if len(issuers) > 0 {
+		issuer, _ := claims.GetIssuer()
+		if !slices.Contains(issuers, issuer) {
+			return fmt.Errorf("unexpected issuer %q", issuer)
+		}
+	}
+
+	if len(audiences) > 0 {
+		tokenAudiences, _ := claims.GetAudience()
+		if !slices.ContainsFunc(tokenAudiences, func(aud string) bool {
+			return slices.Contains(audiences, aud)
+		}) {
+			return fmt.Errorf("unexpected audience %v", []string(tokenAudiences))
+		}
+	}
+
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,189 @@
+package libsql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// client runs statements on a libSQL server with the Hrana over HTTP
+// protocol, see https://github.com/tursodatabase/libsql/blob/main/docs/HRANA_3_SPEC.md.
+type client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+// newClient creates a client for databaseURL, e.g.
+// "libsql://my-db-my-org.turso.io". The libsql:// scheme means HTTPS;
+// http:// and https:// URLs are used as given.
+func newClient(databaseURL, authToken string) (*client, error) {
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid libSQL URL: %w", err)
+	}
+	switch u.Scheme {
+	case "libsql", "wss":
+		u.Scheme = "https"
+	case "ws":
+		u.Scheme = "http"
+	case "http", "https":
+	default:
+		return nil, fmt.Errorf("unsupported libSQL URL scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("libSQL URL %q has no host", databaseURL)
+	}
+	if token := u.Query().Get("authToken"); token != "" && authToken == "" {
+		authToken = token
+	}
+	u.RawQuery = ""
+	return &client{
+		baseURL: strings.TrimSuffix(u.String(), "/"),
+		token:   authToken,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// stream is a server-side connection. Statements of one stream run on the
+// same connection, so a transaction spans the requests of a stream.
+type stream struct {
+	baton   string
+	baseURL string
+}
+
+// hranaValue is a value as encoded by Hrana.
+type hranaValue struct {
+	Type   string          `json:"type"`
+	Value  json.RawMessage `json:"value,omitempty"`
+	Base64 string          `json:"base64,omitempty"`
+}
+
+type hranaStmt struct {
+	SQL      string       `json:"sql"`
+	Args     []hranaValue `json:"args,omitempty"`
+	WantRows bool         `json:"want_rows"`
+}
+
+type hranaRequest struct {
+	Type string     `json:"type"`
+	Stmt *hranaStmt `json:"stmt,omitempty"`
+}
+
+type pipelineRequest struct {
+	Baton    *string        `json:"baton"`
+	Requests []hranaRequest `json:"requests"`
+}
+
+type hranaCol struct {
+	Name     string `json:"name"`
+	Decltype string `json:"decltype"`
+}
+
+// stmtResult is the result of an executed statement.
+type stmtResult struct {
+	Cols             []hranaCol     `json:"cols"`
+	Rows             [][]hranaValue `json:"rows"`
+	AffectedRowCount int64          `json:"affected_row_count"`
+	LastInsertRowID  *string        `json:"last_insert_rowid"`
+}
+
+type pipelineResponse struct {
+	Baton   *string `json:"baton"`
+	BaseURL *string `json:"base_url"`
+	Results []struct {
+		Type     string `json:"type"`
+		Response *struct {
+			Type   string      `json:"type"`
+			Result *stmtResult `json:"result"`
+		} `json:"response"`
+		Error *struct {
+			Message string `json:"message"`
+			Code    string `json:"code"`
+		} `json:"error"`
+	} `json:"results"`
+}
+
+// execute runs stmt. With a nil stream it runs on a fresh server stream
+// that is closed right after; otherwise it runs on s, which is updated to
+// continue with the next request, and last ends s.
+func (c *client) execute(ctx context.Context, s *stream, stmt hranaStmt, last bool) (*stmtResult, error) {
+	requests := []hranaRequest{{Type: "execute", Stmt: &stmt}}
+	if s == nil || last {
+		requests = append(requests, hranaRequest{Type: "close"})
+	}
+	req := pipelineRequest{Requests: requests}
+	baseURL := c.baseURL
+	if s != nil {
+		if s.baton != "" {
+			req.Baton = &s.baton
+		}
+		if s.baseURL != "" {
+			baseURL = s.baseURL
+		}
+	}
+
+	resp, err := c.pipeline(ctx, baseURL, req)
+	if err != nil {
+		return nil, err
+	}
+	if s != nil {
+		s.baton = ""
+		if resp.Baton != nil {
+			s.baton = *resp.Baton
+		}
+		if resp.BaseURL != nil {
+			s.baseURL = strings.TrimSuffix(*resp.BaseURL, "/")
+		}
+	}
+
+	if len(resp.Results) == 0 {
+		return nil, fmt.Errorf("libSQL returned no result")
+	}
+	result := resp.Results[0]
+	if result.Type == "error" && result.Error != nil {
+		return nil, fmt.Errorf("libSQL: %s", result.Error.Message)
+	}
+	if result.Type != "ok" || result.Response == nil || result.Response.Result == nil {
+		return nil, fmt.Errorf("libSQL returned an unexpected %q result", result.Type)
+	}
+	return result.Response.Result, nil
+}
+
+// pipeline posts a pipeline request to baseURL.
+func (c *client) pipeline(ctx context.Context, baseURL string, req pipelineRequest) (*pipelineResponse, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+"/v2/pipeline", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if c.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	httpResp, err := c.http.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("libSQL request failed: %w", err)
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(httpResp.Body, 1024))
+		return nil, fmt.Errorf("libSQL request failed with status %d: %s", httpResp.StatusCode, strings.TrimSpace(string(message)))
+	}
+
+	var resp pipelineResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("invalid libSQL response: %w", err)
+	}
+	return &resp, nil
+}
@@ -0,0 +1,351 @@
+package libsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/internal/storage/sqlite"
+)
+
+// timestampFormat is the format times are stored in, as by the cgo SQLite
+// driver, so that databases can move between the two.
+const timestampFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+// timestampFormats are the formats tried when reading a time column.
+var timestampFormats = []string{
+	timestampFormat,
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// NewLibSQLStorage creates a storage on a remote libSQL database, such as
+// one hosted by Turso, reached over HTTP. It shares the SQLite schema and
+// queries of sqlite.SQLiteStorage.
+func NewLibSQLStorage(databaseURL, authToken string) (*sqlite.SQLiteStorage, error) {
+	db, err := OpenDB(databaseURL, authToken)
+	if err != nil {
+		return nil, err
+	}
+	return sqlite.NewSQLiteStorageWithDB(db)
+}
+
+// OpenDB opens a remote libSQL database as a *sql.DB. Statements outside
+// transactions are sent as independent HTTP requests; a transaction keeps
+// one server stream until it commits or rolls back.
+func OpenDB(databaseURL, authToken string) (*sql.DB, error) {
+	c, err := newClient(databaseURL, authToken)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(&connector{client: c}), nil
+}
+
+// connector opens connections to one libSQL database. It is not registered
+// as a named driver, so that it does not clash with other libSQL drivers.
+type connector struct {
+	client *client
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	return &conn{client: c.client}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return libsqlDriver{}
+}
+
+// libsqlDriver only exists to satisfy driver.Connector.
+type libsqlDriver struct{}
+
+func (libsqlDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("libsql: open databases with OpenDB")
+}
+
+// conn is a connection to a libSQL database. Outside a transaction it holds
+// no server state.
+type conn struct {
+	client *client
+	// tx is the server stream of the open transaction, if any.
+	tx *stream
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	if c.tx != nil {
+		return c.Rollback()
+	}
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.tx != nil {
+		return nil, errors.New("libsql: transaction already open")
+	}
+	tx := &stream{}
+	if _, err := c.client.execute(ctx, tx, hranaStmt{SQL: "BEGIN"}, false); err != nil {
+		return nil, err
+	}
+	c.tx = tx
+	return c, nil
+}
+
+// Commit commits the open transaction and ends its stream.
+func (c *conn) Commit() error {
+	return c.end("COMMIT")
+}
+
+// Rollback rolls back the open transaction and ends its stream.
+func (c *conn) Rollback() error {
+	return c.end("ROLLBACK")
+}
+
+func (c *conn) end(statement string) error {
+	tx := c.tx
+	if tx == nil {
+		return errors.New("libsql: no transaction open")
+	}
+	c.tx = nil
+	_, err := c.client.execute(context.Background(), tx, hranaStmt{SQL: statement}, true)
+	return err
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	_, err := c.run(ctx, "SELECT 1", nil, true)
+	return err
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result, err := c.run(ctx, query, args, false)
+	if err != nil {
+		return nil, err
+	}
+	return execResult{result}, nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result, err := c.run(ctx, query, args, true)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{result: result}, nil
+}
+
+// run executes query on the transaction stream, or a fresh one.
+func (c *conn) run(ctx context.Context, query string, args []driver.NamedValue, wantRows bool) (*stmtResult, error) {
+	values := make([]hranaValue, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("libsql: named argument %q is not supported", arg.Name)
+		}
+		value, err := encodeValue(arg.Value)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+	return c.client.execute(ctx, c.tx, hranaStmt{SQL: query, Args: values, WantRows: wantRows}, false)
+}
+
+// stmt is a statement sent unprepared with each execution.
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.conn.ExecContext(context.Background(), s.query, namedValues(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.conn.QueryContext(context.Background(), s.query, namedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+// namedValues converts positional arguments.
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return named
+}
+
+// execResult is the driver.Result of a statement.
+type execResult struct {
+	result *stmtResult
+}
+
+func (r execResult) LastInsertId() (int64, error) {
+	if r.result.LastInsertRowID == nil {
+		return 0, nil
+	}
+	return strconv.ParseInt(*r.result.LastInsertRowID, 10, 64)
+}
+
+func (r execResult) RowsAffected() (int64, error) {
+	return r.result.AffectedRowCount, nil
+}
+
+// rows iterates over the rows of a query result.
+type rows struct {
+	result *stmtResult
+	next   int
+}
+
+func (r *rows) Columns() []string {
+	names := make([]string, len(r.result.Cols))
+	for i, col := range r.result.Cols {
+		names[i] = col.Name
+	}
+	return names
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.result.Rows) {
+		return io.EOF
+	}
+	row := r.result.Rows[r.next]
+	r.next++
+	for i := range dest {
+		if i >= len(row) {
+			dest[i] = nil
+			continue
+		}
+		decltype := ""
+		if i < len(r.result.Cols) {
+			decltype = r.result.Cols[i].Decltype
+		}
+		value, err := decodeValue(row[i], decltype)
+		if err != nil {
+			return err
+		}
+		dest[i] = value
+	}
+	return nil
+}
+
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	return strings.ToUpper(r.result.Cols[index].Decltype)
+}
+
+// encodeValue encodes an argument as a Hrana value.
+func encodeValue(value driver.Value) (hranaValue, error) {
+	switch v := value.(type) {
+	case nil:
+		return hranaValue{Type: "null"}, nil
+	case int64:
+		return textValue("integer", strconv.FormatInt(v, 10))
+	case float64:
+		raw, err := json.Marshal(v)
+		return hranaValue{Type: "float", Value: raw}, err
+	case bool:
+		if v {
+			return textValue("integer", "1")
+		}
+		return textValue("integer", "0")
+	case string:
+		return textValue("text", v)
+	case []byte:
+		return hranaValue{Type: "blob", Base64: base64.StdEncoding.EncodeToString(v)}, nil
+	case time.Time:
+		return textValue("text", v.Format(timestampFormat))
+	}
+	return hranaValue{}, fmt.Errorf("libsql: unsupported argument type %T", value)
+}
+
+// textValue encodes a value whose Hrana representation is a JSON string.
+func textValue(valueType, value string) (hranaValue, error) {
+	raw, err := json.Marshal(value)
+	return hranaValue{Type: valueType, Value: raw}, err
+}
+
+// decodeValue decodes a Hrana value of a column declared as decltype. Text
+// in date and time columns is returned as time.Time.
+func decodeValue(value hranaValue, decltype string) (driver.Value, error) {
+	switch value.Type {
+	case "null":
+		return nil, nil
+	case "integer":
+		var text string
+		if err := json.Unmarshal(value.Value, &text); err != nil {
+			return nil, fmt.Errorf("libsql: invalid integer: %w", err)
+		}
+		return strconv.ParseInt(text, 10, 64)
+	case "float":
+		var f float64
+		if err := json.Unmarshal(value.Value, &f); err != nil {
+			return nil, fmt.Errorf("libsql: invalid float: %w", err)
+		}
+		return f, nil
+	case "text":
+		var text string
+		if err := json.Unmarshal(value.Value, &text); err != nil {
+			return nil, fmt.Errorf("libsql: invalid text: %w", err)
+		}
+		if isTimeType(decltype) {
+			if t, ok := parseTimestamp(text); ok {
+				return t, nil
+			}
+		}
+		return text, nil
+	case "blob":
+		return base64.StdEncoding.DecodeString(value.Base64)
+	}
+	return nil, fmt.Errorf("libsql: unsupported value type %q", value.Type)
+}
+
+// isTimeType reports whether decltype declares a date or time column.
+func isTimeType(decltype string) bool {
+	switch strings.ToUpper(decltype) {
+	case "DATE", "DATETIME", "TIMESTAMP":
+		return true
+	}
+	return false
+}
+
+// parseTimestamp parses a stored time in one of timestampFormats.
+func parseTimestamp(text string) (time.Time, bool) {
+	text = strings.TrimSuffix(text, "Z")
+	for _, format := range timestampFormats {
+		if t, err := time.ParseInLocation(format, text, time.UTC); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,269 @@
+package libsql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/internal/storage/sqlite"
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+// fakeServer serves the Hrana pipeline endpoint from a local SQLite
+// database, keeping one connection per open stream.
+type fakeServer struct {
+	db      *sql.DB
+	mu      sync.Mutex
+	streams map[string]*sql.Conn
+	next    int
+	token   string
+}
+
+func newFakeServer(t *testing.T) (*httptest.Server, *fakeServer) {
+	t.Helper()
+	db, err := sql.Open(sqlite.DefaultDriver, fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name()))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(2)
+	db.SetConnMaxIdleTime(0)
+	t.Cleanup(func() { db.Close() })
+
+	f := &fakeServer{db: db, streams: map[string]*sql.Conn{}}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	return server, f
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v2/pipeline" {
+		http.NotFound(w, r)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.token = r.Header.Get("Authorization")
+
+	var req pipelineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx := context.Background()
+	var conn *sql.Conn
+	baton := ""
+	if req.Baton != nil {
+		baton = *req.Baton
+		conn = f.streams[baton]
+		delete(f.streams, baton)
+	}
+	if conn == nil {
+		var err error
+		if conn, err = f.db.Conn(ctx); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	results := []map[string]interface{}{}
+	closed := false
+	for _, request := range req.Requests {
+		switch request.Type {
+		case "execute":
+			result, err := f.execute(ctx, conn, request.Stmt)
+			if err != nil {
+				results = append(results, map[string]interface{}{"type": "error", "error": map[string]string{"message": err.Error()}})
+				continue
+			}
+			results = append(results, map[string]interface{}{"type": "ok", "response": map[string]interface{}{"type": "execute", "result": result}})
+		case "close":
+			conn.Close()
+			closed = true
+			results = append(results, map[string]interface{}{"type": "ok", "response": map[string]string{"type": "close"}})
+		}
+	}
+
+	resp := map[string]interface{}{"baton": nil, "base_url": nil, "results": results}
+	if !closed {
+		f.next++
+		baton = strconv.Itoa(f.next)
+		f.streams[baton] = conn
+		resp["baton"] = baton
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (f *fakeServer) execute(ctx context.Context, conn *sql.Conn, stmt *hranaStmt) (*stmtResult, error) {
+	args := make([]interface{}, len(stmt.Args))
+	for i, arg := range stmt.Args {
+		value, err := decodeValue(arg, "")
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	if !stmt.WantRows {
+		res, err := conn.ExecContext(ctx, stmt.SQL, args...)
+		if err != nil {
+			return nil, err
+		}
+		affected, _ := res.RowsAffected()
+		return &stmtResult{AffectedRowCount: affected}, nil
+	}
+
+	rows, err := conn.QueryContext(ctx, stmt.SQL, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	types, _ := rows.ColumnTypes()
+	result := &stmtResult{Rows: [][]hranaValue{}}
+	for _, column := range types {
+		result.Cols = append(result.Cols, hranaCol{Name: column.Name(), Decltype: column.DatabaseTypeName()})
+	}
+	for rows.Next() {
+		values := make([]interface{}, len(types))
+		pointers := make([]interface{}, len(types))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, err
+		}
+		row := make([]hranaValue, len(values))
+		for i, value := range values {
+			if b, ok := value.([]byte); ok && types[i].DatabaseTypeName() != "BLOB" {
+				value = string(b)
+			}
+			if row[i], err = encodeValue(value); err != nil {
+				return nil, err
+			}
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, rows.Err()
+}
+
+func TestLibSQLStorage(t *testing.T) {
+	server, fake := newFakeServer(t)
+
+	s, err := NewLibSQLStorage(server.URL, "secret-token")
+	if err != nil {
+		t.Fatalf("NewLibSQLStorage failed: %v", err)
+	}
+	if err := s.Ping(); err != nil {
+		t.Fatalf("Ping failed: %v", err)
+	}
+	if fake.token != "Bearer secret-token" {
+		t.Errorf("Expected bearer token, got %q", fake.token)
+	}
+
+	createdAt := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	user := models.User{
+		ID:           "remote-id",
+		Username:     "remote",
+		Email:        "remote@example.com",
+		PasswordHash: "hash",
+		IsActive:     true,
+		CreatedAt:    createdAt,
+		UpdatedAt:    createdAt,
+		Metadata:     map[string]interface{}{"role": "admin"},
+	}
+	if err := s.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	got, err := s.GetUserByUsername("remote")
+	if err != nil {
+		t.Fatalf("GetUserByUsername failed: %v", err)
+	}
+	if got.ID != "remote-id" || !got.IsActive || got.Metadata["role"] != "admin" {
+		t.Errorf("Unexpected user: %+v", got)
+	}
+	if !got.CreatedAt.Equal(createdAt) {
+		t.Errorf("Expected created at %v, got %v", createdAt, got.CreatedAt)
+	}
+
+	users, err := s.ListUsers(10, 0)
+	if err != nil || len(users) != 1 {
+		t.Errorf("Expected one user, got %d, %v", len(users), err)
+	}
+	if _, err := s.GetUserByID("missing"); err == nil {
+		t.Error("Expected an error for a missing user")
+	}
+}
+
+func TestOpenDB_Transaction(t *testing.T) {
+	server, _ := newFakeServer(t)
+	db, err := OpenDB(server.URL, "")
+	if err != nil {
+		t.Fatalf("OpenDB failed: %v", err)
+	}
+	if _, err := db.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT, data BLOB)"); err != nil {
+		t.Fatalf("CREATE TABLE failed: %v", err)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	tx.Exec("INSERT INTO items (name) VALUES (?)", "discarded")
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback failed: %v", err)
+	}
+
+	tx, err = db.Begin()
+	if err != nil {
+		t.Fatalf("Begin failed: %v", err)
+	}
+	if _, err := tx.Exec("INSERT INTO items (name, data) VALUES (?, ?)", "kept", []byte{0, 1, 2}); err != nil {
+		t.Fatalf("INSERT failed: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	var name string
+	var data []byte
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM items").Scan(&count); err != nil || count != 1 {
+		t.Fatalf("Expected one committed row, got %d, %v", count, err)
+	}
+	if err := db.QueryRow("SELECT name, data FROM items").Scan(&name, &data); err != nil {
+		t.Fatalf("SELECT failed: %v", err)
+	}
+	if name != "kept" || string(data) != "\x00\x01\x02" {
+		t.Errorf("Unexpected row: %q, %v", name, data)
+	}
+
+	if _, err := db.Exec("INSERT INTO missing_table VALUES (1)"); err == nil {
+		t.Error("Expected the server error to be returned")
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	c, err := newClient("libsql://my-db.turso.io?authToken=from-url", "")
+	if err != nil {
+		t.Fatalf("newClient failed: %v", err)
+	}
+	if c.baseURL != "https://my-db.turso.io" || c.token != "from-url" {
+		t.Errorf("Unexpected client: %s, %s", c.baseURL, c.token)
+	}
+
+	if _, err := newClient("postgres://localhost/db", ""); err == nil {
+		t.Error("Expected an error for an unsupported scheme")
+	}
+
+	v, _ := encodeValue([]byte("blob"))
+	if v.Base64 != base64.StdEncoding.EncodeToString([]byte("blob")) {
+		t.Errorf("Unexpected blob encoding: %+v", v)
+	}
+}
@@ -2,22 +2,37 @@ package memory
 
 import (
 	"errors"
+	"reflect"
 	"sync"
+	"time"
 
 	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
 )
 
 // InMemoryStorage is a simple, thread-safe, in-memory implementation of the storage.Storage interface.
 // It is intended for example and testing purposes.	ype
 type InMemoryStorage struct {
-	mu    sync.RWMutex
-	users map[string]models.User
+	mu         sync.RWMutex
+	users      map[string]models.User
+	extensions map[string]map[string]interface{} // user ID -> column -> value
+	families   map[string]refreshFamily
+	resets     map[string]storage.ResetToken
+}
+
+// refreshFamily is the revocation record of a refresh token family.
+type refreshFamily struct {
+	cutoff    int64
+	expiresAt time.Time
 }
 
 // NewInMemoryStorage creates a new in-memory storage instance.
 func NewInMemoryStorage() *InMemoryStorage {
 	return &InMemoryStorage{
-		users: make(map[string]models.User),
+		users:      make(map[string]models.User),
+		extensions: make(map[string]map[string]interface{}),
+		families:   make(map[string]refreshFamily),
+		resets:     make(map[string]storage.ResetToken),
 	}
 }
 
@@ -44,3 +59,148 @@ func (s *InMemoryStorage) GetUserByUsername(username string) (*models.User, erro
 	}
 	return &user, nil
 }
+
+// hasUserID reports whether a user with userID exists. The caller must hold s.mu.
+func (s *InMemoryStorage) hasUserID(userID string) bool {
+	for _, user := range s.users {
+		if user.ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// ExtendUserSchema accepts any valid columns; values are kept per user.
+func (s *InMemoryStorage) ExtendUserSchema(columns []storage.ExtensionColumn) error {
+	for _, column := range columns {
+		if !storage.ValidColumnName(column.Name) {
+			return errors.New("invalid extension column name " + column.Name)
+		}
+	}
+	return nil
+}
+
+// GetUserExtension copies the stored column values into dest. Columns never
+// written are left at their zero value.
+func (s *InMemoryStorage) GetUserExtension(userID string, columns []string, dest []interface{}) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.hasUserID(userID) {
+		return errors.New("user not found")
+	}
+	for i, column := range columns {
+		target := reflect.ValueOf(dest[i]).Elem()
+		value, ok := s.extensions[userID][column]
+		if !ok || value == nil {
+			target.Set(reflect.Zero(target.Type()))
+			continue
+		}
+		v := reflect.ValueOf(value)
+		if !v.Type().AssignableTo(target.Type()) {
+			return errors.New("cannot assign extension column " + column + " of type " + v.Type().String())
+		}
+		target.Set(v)
+	}
+	return nil
+}
+
+// UpdateUserExtension stores the column values of a user.
+func (s *InMemoryStorage) UpdateUserExtension(userID string, columns []string, values []interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.hasUserID(userID) {
+		return errors.New("user not found")
+	}
+	if s.extensions[userID] == nil {
+		s.extensions[userID] = make(map[string]interface{})
+	}
+	for i, column := range columns {
+		s.extensions[userID][column] = values[i]
+	}
+	return nil
+}
+
+// RevokeRefreshFamily records the cutoff generation of a refresh token family.
+func (s *InMemoryStorage) RevokeRefreshFamily(familyID string, cutoff int64, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if family, ok := s.families[familyID]; ok && family.cutoff > cutoff {
+		return nil
+	}
+	s.families[familyID] = refreshFamily{cutoff: cutoff, expiresAt: expiresAt}
+	return nil
+}
+
+// RefreshFamilyCutoff returns the cutoff generation of a refresh token family.
+func (s *InMemoryStorage) RefreshFamilyCutoff(familyID string) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	family, ok := s.families[familyID]
+	if !ok || !family.expiresAt.After(time.Now()) {
+		return 0, nil
+	}
+	return family.cutoff, nil
+}
+
+// SaveResetToken stores a password reset token.
+func (s *InMemoryStorage) SaveResetToken(token storage.ResetToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.resets[token.ID] = token
+	return nil
+}
+
+// GetResetToken retrieves a password reset token.
+func (s *InMemoryStorage) GetResetToken(id string) (*storage.ResetToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	token, ok := s.resets[id]
+	if !ok {
+		return nil, nil
+	}
+	return &token, nil
+}
+
+// RecordResetTokenAttempt counts a wrong code entered for a reset token.
+func (s *InMemoryStorage) RecordResetTokenAttempt(id string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.resets[id]
+	if !ok {
+		return 0, nil
+	}
+	token.Attempts++
+	s.resets[id] = token
+	return token.Attempts, nil
+}
+
+// DeleteResetToken deletes a password reset token.
+func (s *InMemoryStorage) DeleteResetToken(id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.resets[id]
+	delete(s.resets, id)
+	return ok, nil
+}
+
+// ListResetTokens lists the password reset tokens of a user.
+func (s *InMemoryStorage) ListResetTokens(userID string) ([]storage.ResetToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var tokens []storage.ResetToken
+	for _, token := range s.resets {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
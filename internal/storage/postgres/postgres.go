@@ -16,6 +16,8 @@ import (
 // PostgresStorage is a PostgreSQL implementation of the storage.EnhancedStorage interface.
 type PostgresStorage struct {
 	db *sql.DB
+	// tenant scopes the user queries of a storage returned by ForTenant.
+	tenant string
 }
 
 // NewPostgresStorage creates a new PostgreSQL storage instance and initializes the database schema.
@@ -61,11 +63,22 @@ func (s *PostgresStorage) init() error {
         updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
         last_login_at TIMESTAMP WITH TIME ZONE,
         is_active BOOLEAN NOT NULL DEFAULT TRUE,
-        metadata JSONB
+        metadata JSONB,
+        display_name TEXT NOT NULL DEFAULT '',
+        locale TEXT NOT NULL DEFAULT '',
+        timezone TEXT NOT NULL DEFAULT '',
+        expires_at TIMESTAMP
     );`
 	if _, err := s.db.Exec(usersQuery); err != nil {
 		return fmt.Errorf("failed to create users table: %w", err)
 	}
+	// Tables created before the profile columns existed
+	if err := s.ExtendUserSchema(storage.ProfileColumns); err != nil {
+		return fmt.Errorf("failed to add profile columns: %w", err)
+	}
+	if err := s.ExtendUserSchema(storage.AccountColumns); err != nil {
+		return fmt.Errorf("failed to add account columns: %w", err)
+	}
 
 	// Create blacklisted_tokens table
 	tokensQuery := `
@@ -78,11 +91,41 @@ func (s *PostgresStorage) init() error {
 		return fmt.Errorf("failed to create blacklisted_tokens table: %w", err)
 	}
 
+	// Create refresh_families table
+	familiesQuery := `
+    CREATE TABLE IF NOT EXISTS refresh_families (
+        family_id TEXT PRIMARY KEY,
+        cutoff BIGINT NOT NULL,
+        expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+    );`
+	if _, err := s.db.Exec(familiesQuery); err != nil {
+		return fmt.Errorf("failed to create refresh_families table: %w", err)
+	}
+
+	// Create reset_tokens table
+	resetTokensQuery := `
+    CREATE TABLE IF NOT EXISTS reset_tokens (
+        id TEXT PRIMARY KEY,
+        code_hash TEXT NOT NULL DEFAULT '',
+        user_id TEXT NOT NULL,
+        channel TEXT NOT NULL,
+        attempts INTEGER NOT NULL DEFAULT 0,
+        created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+        expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+    );`
+	if _, err := s.db.Exec(resetTokensQuery); err != nil {
+		return fmt.Errorf("failed to create reset_tokens table: %w", err)
+	}
+
 	// Create indexes for better performance
 	indexes := []string{
 		"CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);",
 		"CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);",
 		"CREATE INDEX IF NOT EXISTS idx_blacklisted_tokens_expires_at ON blacklisted_tokens(expires_at);",
+		"CREATE INDEX IF NOT EXISTS idx_refresh_families_expires_at ON refresh_families(expires_at);",
+		"CREATE INDEX IF NOT EXISTS idx_reset_tokens_user_id ON reset_tokens(user_id);",
+		"CREATE INDEX IF NOT EXISTS idx_reset_tokens_expires_at ON reset_tokens(expires_at);",
+		"CREATE INDEX IF NOT EXISTS idx_users_phone ON users((metadata->>'phone'));",
 		"CREATE INDEX IF NOT EXISTS idx_users_metadata ON users USING GIN(metadata);",
 	}
 
@@ -114,21 +157,25 @@ func (s *PostgresStorage) CreateUser(user models.User) error {
 		}
 	}
 
-	query := `INSERT INTO users (id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata) 
-              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
-	_, err := s.db.Exec(query, user.ID, user.Username, user.Email, user.PasswordHash, 
-		user.CreatedAt, user.UpdatedAt, user.LastLoginAt, user.IsActive, metadataJSON)
-	return err
+	query := `INSERT INTO users (id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata, display_name, locale, timezone, expires_at) 
+              VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+	_, err := s.exec(query, user.ID, user.Username, user.Email, user.PasswordHash, 
+		user.CreatedAt, user.UpdatedAt, user.LastLoginAt, user.IsActive, metadataJSON,
+		user.DisplayName, user.Locale, user.Timezone, user.ExpiresAt)
+	return duplicateError(err)
 }
 
 // GetUserByUsername retrieves a user by their username.
 func (s *PostgresStorage) GetUserByUsername(username string) (*models.User, error) {
 	user := &models.User{}
 	var metadataJSON []byte
-	query := `SELECT id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata 
+	query := `SELECT id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata, display_name, locale, timezone, expires_at 
               FROM users WHERE username = $1`
-	err := s.db.QueryRow(query, username).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash,
-		&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.IsActive, &metadataJSON)
+	err := s.scoped(func(q queryer) error {
+		return q.QueryRow(query, username).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash,
+			&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.IsActive, &metadataJSON,
+			&user.DisplayName, &user.Locale, &user.Timezone, &user.ExpiresAt)
+	})
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user not found")
@@ -170,13 +217,48 @@ func (s *PostgresStorage) UpdateUser(userID string, updates storage.UserUpdates)
 		args = append(args, metadataJSON)
 		argIndex++
 	}
+	if updates.LastLoginAt != nil {
+		setParts = append(setParts, fmt.Sprintf("last_login_at = $%d", argIndex))
+		args = append(args, *updates.LastLoginAt)
+		argIndex++
+	}
+	if updates.DisplayName != nil {
+		setParts = append(setParts, fmt.Sprintf("display_name = $%d", argIndex))
+		args = append(args, *updates.DisplayName)
+		argIndex++
+	}
+	if updates.Locale != nil {
+		setParts = append(setParts, fmt.Sprintf("locale = $%d", argIndex))
+		args = append(args, *updates.Locale)
+		argIndex++
+	}
+	if updates.Timezone != nil {
+		setParts = append(setParts, fmt.Sprintf("timezone = $%d", argIndex))
+		args = append(args, *updates.Timezone)
+		argIndex++
+	}
+	if updates.ExpiresAt != nil {
+		// A zero time removes the expiry
+		var expiresAt interface{}
+		if !updates.ExpiresAt.IsZero() {
+			expiresAt = *updates.ExpiresAt
+		}
+		setParts = append(setParts, fmt.Sprintf("expires_at = $%d", argIndex))
+		args = append(args, expiresAt)
+		argIndex++
+	}
+	if updates.IsActive != nil {
+		setParts = append(setParts, fmt.Sprintf("is_active = $%d", argIndex))
+		args = append(args, *updates.IsActive)
+		argIndex++
+	}
 
 	args = append(args, userID)
 	query := fmt.Sprintf("UPDATE users SET %s WHERE id = $%d", strings.Join(setParts, ", "), argIndex)
 	
-	result, err := s.db.Exec(query, args...)
+	result, err := s.exec(query, args...)
 	if err != nil {
-		return err
+		return duplicateError(err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -192,7 +274,7 @@ func (s *PostgresStorage) UpdateUser(userID string, updates storage.UserUpdates)
 
 // DeleteUser removes a user from the database.
 func (s *PostgresStorage) DeleteUser(userID string) error {
-	result, err := s.db.Exec("DELETE FROM users WHERE id = $1", userID)
+	result, err := s.exec("DELETE FROM users WHERE id = $1", userID)
 	if err != nil {
 		return err
 	}
@@ -212,10 +294,13 @@ func (s *PostgresStorage) DeleteUser(userID string) error {
 func (s *PostgresStorage) GetUserByID(userID string) (*models.User, error) {
 	user := &models.User{}
 	var metadataJSON []byte
-	query := `SELECT id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata 
+	query := `SELECT id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata, display_name, locale, timezone, expires_at 
               FROM users WHERE id = $1`
-	err := s.db.QueryRow(query, userID).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash,
-		&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.IsActive, &metadataJSON)
+	err := s.scoped(func(q queryer) error {
+		return q.QueryRow(query, userID).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash,
+			&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.IsActive, &metadataJSON,
+			&user.DisplayName, &user.Locale, &user.Timezone, &user.ExpiresAt)
+	})
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user not found")
@@ -236,10 +321,13 @@ func (s *PostgresStorage) GetUserByID(userID string) (*models.User, error) {
 func (s *PostgresStorage) GetUserByEmail(email string) (*models.User, error) {
 	user := &models.User{}
 	var metadataJSON []byte
-	query := `SELECT id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata 
+	query := `SELECT id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata, display_name, locale, timezone, expires_at 
               FROM users WHERE email = $1`
-	err := s.db.QueryRow(query, email).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash,
-		&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.IsActive, &metadataJSON)
+	err := s.scoped(func(q queryer) error {
+		return q.QueryRow(query, email).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash,
+			&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.IsActive, &metadataJSON,
+			&user.DisplayName, &user.Locale, &user.Timezone, &user.ExpiresAt)
+	})
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user not found")
@@ -258,20 +346,33 @@ func (s *PostgresStorage) GetUserByEmail(email string) (*models.User, error) {
 
 // ListUsers retrieves a paginated list of users.
 func (s *PostgresStorage) ListUsers(limit, offset int) ([]*models.User, error) {
-	query := `SELECT id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata 
+	query := `SELECT id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata, display_name, locale, timezone, expires_at 
               FROM users ORDER BY created_at DESC LIMIT $1 OFFSET $2`
-	rows, err := s.db.Query(query, limit, offset)
+	var users []*models.User
+	err := s.scoped(func(q queryer) error {
+		rows, err := q.Query(query, limit, offset)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		users, err = scanUsers(rows)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	return users, nil
+}
 
+// scanUsers reads the users of rows selected with the columns of ListUsers.
+func scanUsers(rows *sql.Rows) ([]*models.User, error) {
 	var users []*models.User
 	for rows.Next() {
 		user := &models.User{}
 		var metadataJSON []byte
 		err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash,
-			&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.IsActive, &metadataJSON)
+			&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.IsActive, &metadataJSON,
+			&user.DisplayName, &user.Locale, &user.Timezone, &user.ExpiresAt)
 		if err != nil {
 			return nil, err
 		}
@@ -290,7 +391,7 @@ func (s *PostgresStorage) ListUsers(limit, offset int) ([]*models.User, error) {
 
 // UpdatePassword updates a user's password hash.
 func (s *PostgresStorage) UpdatePassword(userID string, passwordHash string) error {
-	result, err := s.db.Exec("UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2", 
+	result, err := s.exec("UPDATE users SET password_hash = $1, updated_at = NOW() WHERE id = $2", 
 		passwordHash, userID)
 	if err != nil {
 		return err
@@ -327,15 +428,176 @@ func (s *PostgresStorage) IsTokenBlacklisted(tokenID string) (bool, error) {
 
 // CleanupExpiredTokens removes expired tokens from the blacklist.
 func (s *PostgresStorage) CleanupExpiredTokens() error {
-	_, err := s.db.Exec("DELETE FROM blacklisted_tokens WHERE expires_at <= NOW()")
+	if _, err := s.db.Exec("DELETE FROM blacklisted_tokens WHERE expires_at <= NOW()"); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec("DELETE FROM refresh_families WHERE expires_at <= NOW()"); err != nil {
+		return err
+	}
+	_, err := s.db.Exec("DELETE FROM reset_tokens WHERE expires_at <= NOW()")
+	return err
+}
+
+// ListBlacklistedTokens lists the token blacklist, most recently blacklisted first.
+func (s *PostgresStorage) ListBlacklistedTokens(filter storage.BlacklistFilter, limit, offset int) ([]storage.BlacklistedToken, error) {
+	var conditions []string
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if !filter.IncludeExpired {
+		conditions = append(conditions, "expires_at > NOW()")
+	}
+	if filter.TokenIDPrefix != "" {
+		conditions = append(conditions, "starts_with(token_id, "+arg(filter.TokenIDPrefix)+")")
+	}
+	if !filter.CreatedAfter.IsZero() {
+		conditions = append(conditions, "created_at >= "+arg(filter.CreatedAfter))
+	}
+	if !filter.CreatedBefore.IsZero() {
+		conditions = append(conditions, "created_at < "+arg(filter.CreatedBefore))
+	}
+	query := "SELECT token_id, expires_at, created_at FROM blacklisted_tokens"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, token_id LIMIT " + arg(limit) + " OFFSET " + arg(offset)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []storage.BlacklistedToken
+	for rows.Next() {
+		var token storage.BlacklistedToken
+		if err := rows.Scan(&token.TokenID, &token.ExpiresAt, &token.CreatedAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeRefreshFamily records the cutoff generation of a refresh token family.
+func (s *PostgresStorage) RevokeRefreshFamily(familyID string, cutoff int64, expiresAt time.Time) error {
+	query := `
+    INSERT INTO refresh_families (family_id, cutoff, expires_at) VALUES ($1, $2, $3)
+    ON CONFLICT (family_id) DO UPDATE SET
+        expires_at = CASE WHEN excluded.cutoff >= refresh_families.cutoff THEN excluded.expires_at ELSE refresh_families.expires_at END,
+        cutoff = CASE WHEN excluded.cutoff > refresh_families.cutoff THEN excluded.cutoff ELSE refresh_families.cutoff END`
+	_, err := s.db.Exec(query, familyID, cutoff, expiresAt)
+	return err
+}
+
+// RefreshFamilyCutoff returns the cutoff generation of a refresh token family.
+func (s *PostgresStorage) RefreshFamilyCutoff(familyID string) (int64, error) {
+	var cutoff int64
+	err := s.db.QueryRow("SELECT cutoff FROM refresh_families WHERE family_id = $1 AND expires_at > NOW()", familyID).Scan(&cutoff)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return cutoff, err
+}
+
+// SaveResetToken stores a password reset token, replacing one with the same ID.
+func (s *PostgresStorage) SaveResetToken(token storage.ResetToken) error {
+	query := `
+    INSERT INTO reset_tokens (id, code_hash, user_id, channel, attempts, created_at, expires_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7)
+    ON CONFLICT (id) DO UPDATE SET
+        code_hash = excluded.code_hash, user_id = excluded.user_id, channel = excluded.channel,
+        attempts = excluded.attempts, created_at = excluded.created_at, expires_at = excluded.expires_at`
+	_, err := s.db.Exec(query, token.ID, token.CodeHash, token.UserID, token.Channel, token.Attempts, token.CreatedAt, token.ExpiresAt)
 	return err
 }
 
+// GetResetToken retrieves a password reset token, or nil if it does not exist.
+func (s *PostgresStorage) GetResetToken(id string) (*storage.ResetToken, error) {
+	token := &storage.ResetToken{}
+	query := "SELECT id, code_hash, user_id, channel, attempts, created_at, expires_at FROM reset_tokens WHERE id = $1"
+	err := s.db.QueryRow(query, id).Scan(&token.ID, &token.CodeHash, &token.UserID, &token.Channel,
+		&token.Attempts, &token.CreatedAt, &token.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// RecordResetTokenAttempt counts a wrong code entered for a reset token.
+func (s *PostgresStorage) RecordResetTokenAttempt(id string) (int, error) {
+	var attempts int
+	err := s.db.QueryRow("UPDATE reset_tokens SET attempts = attempts + 1 WHERE id = $1 RETURNING attempts", id).Scan(&attempts)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return attempts, err
+}
+
+// DeleteResetToken deletes a password reset token and reports whether it existed.
+func (s *PostgresStorage) DeleteResetToken(id string) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM reset_tokens WHERE id = $1", id)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+// ListResetTokens lists the password reset tokens of a user.
+func (s *PostgresStorage) ListResetTokens(userID string) ([]storage.ResetToken, error) {
+	query := "SELECT id, code_hash, user_id, channel, attempts, created_at, expires_at FROM reset_tokens WHERE user_id = $1"
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []storage.ResetToken
+	for rows.Next() {
+		var token storage.ResetToken
+		if err := rows.Scan(&token.ID, &token.CodeHash, &token.UserID, &token.Channel,
+			&token.Attempts, &token.CreatedAt, &token.ExpiresAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// GetUsersByPhone retrieves the users with a phone number, using the index
+// on the "phone" metadata.
+func (s *PostgresStorage) GetUsersByPhone(phone string) ([]*models.User, error) {
+	query := `SELECT id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata, display_name, locale, timezone, expires_at 
+              FROM users WHERE metadata->>'phone' = $1`
+	var users []*models.User
+	err := s.scoped(func(q queryer) error {
+		rows, err := q.Query(query, phone)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		users, err = scanUsers(rows)
+		return err
+	})
+	return users, err
+}
+
 // Ping checks the database connection.
 func (s *PostgresStorage) Ping() error {
 	return s.db.Ping()
 }
 
+// Stats returns the connection pool statistics of the database.
+func (s *PostgresStorage) Stats() sql.DBStats {
+	return s.db.Stats()
+}
+
 // Migrate runs database migrations.
 func (s *PostgresStorage) Migrate() error {
 	// For now, just ensure the current schema is up to date
@@ -368,6 +630,180 @@ func (s *PostgresStorage) RemoveMigrationRecord(version int) error {
 	return err
 }
 
+// ApplyMigrationTx runs up and records the migration in one transaction.
+func (s *PostgresStorage) ApplyMigrationTx(version int, description string, up func(tx *sql.Tx) error) error {
+	return s.migrateTx(func(tx *sql.Tx) error {
+		if err := up(tx); err != nil {
+			return err
+		}
+		query := "INSERT INTO migrations (version, description, applied_at) VALUES ($1, $2, $3)"
+		_, err := tx.Exec(query, version, description, time.Now())
+		return err
+	})
+}
+
+// RevertMigrationTx runs down and removes the migration record in one transaction.
+func (s *PostgresStorage) RevertMigrationTx(version int, down func(tx *sql.Tx) error) error {
+	return s.migrateTx(func(tx *sql.Tx) error {
+		if err := down(tx); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DELETE FROM migrations WHERE version = $1", version)
+		return err
+	})
+}
+
+// DryRunTx runs fn in a transaction that is always rolled back.
+func (s *PostgresStorage) DryRunTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return fn(tx)
+}
+
+// migrateTx runs fn in a transaction, committing only if it succeeds.
+func (s *PostgresStorage) migrateTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// queryer is implemented by *sql.DB and *sql.Tx.
+type queryer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// scoped runs fn on the database. The storage of a tenant runs it in a
+// transaction with app.tenant_id set locally to the tenant, which the
+// row-level security policy of the users table compares rows against.
+func (s *PostgresStorage) scoped(fn func(q queryer) error) error {
+	if s.tenant == "" {
+		return fn(s.db)
+	}
+	return s.migrateTx(func(tx *sql.Tx) error {
+		// Like SET LOCAL, but with the tenant as a parameter
+		if _, err := tx.Exec("SELECT set_config('app.tenant_id', $1, true)", s.tenant); err != nil {
+			return fmt.Errorf("failed to set tenant: %w", err)
+		}
+		return fn(tx)
+	})
+}
+
+// exec runs a statement with scoped.
+func (s *PostgresStorage) exec(query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := s.scoped(func(q queryer) (err error) {
+		result, err = q.Exec(query, args...)
+		return err
+	})
+	return result, err
+}
+
+// rowLevelSecurityStatements add the tenant of users and the policy that
+// isolates them. New users take the tenant of the transaction; users
+// created before keep an empty tenant that no tenant can see.
+var rowLevelSecurityStatements = []string{
+	"ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE users ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id', true)",
+	"CREATE INDEX IF NOT EXISTS idx_users_tenant_id ON users(tenant_id)",
+	"ALTER TABLE users ENABLE ROW LEVEL SECURITY",
+	"ALTER TABLE users FORCE ROW LEVEL SECURITY",
+	"DROP POLICY IF EXISTS tenant_isolation ON users",
+	`CREATE POLICY tenant_isolation ON users
+        USING (tenant_id = current_setting('app.tenant_id', true))
+        WITH CHECK (tenant_id = current_setting('app.tenant_id', true))`,
+}
+
+// EnableRowLevelSecurity isolates the users of tenants with a row-level
+// security policy, in one transaction. Queries only see and write the
+// users of the tenant set by a storage from ForTenant, even if they lack a
+// tenant condition; without a tenant they see no users. The policy is
+// forced on the table owner, but superusers and roles with BYPASSRLS are
+// not subject to it.
+func (s *PostgresStorage) EnableRowLevelSecurity() error {
+	return s.migrateTx(func(tx *sql.Tx) error {
+		for _, statement := range rowLevelSecurityStatements {
+			if _, err := tx.Exec(statement); err != nil {
+				return fmt.Errorf("failed to enable row-level security: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// ForTenant returns a storage sharing the connection pool of s whose user
+// queries run with tenant set. Token and migration queries are not scoped.
+func (s *PostgresStorage) ForTenant(tenant string) storage.Storage {
+	return &PostgresStorage{db: s.db, tenant: tenant}
+}
+
+// uniquenessStatements replace the unique indexes of usernames and emails
+// with those of a scope. The username constraint of the table definition
+// is global, so the per-tenant scope drops it.
+var uniquenessStatements = map[storage.UniquenessScope][]string{
+	storage.UniqueGlobal: {
+		"DROP INDEX IF EXISTS idx_users_tenant_username",
+		"DROP INDEX IF EXISTS idx_users_tenant_email",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_unique ON users(username)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_unique ON users(email) WHERE email <> ''",
+	},
+	storage.UniquePerTenant: {
+		"ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT ''",
+		"ALTER TABLE users DROP CONSTRAINT IF EXISTS users_username_key",
+		"DROP INDEX IF EXISTS idx_users_username_unique",
+		"DROP INDEX IF EXISTS idx_users_email_unique",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_users_tenant_username ON users(tenant_id, username)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_users_tenant_email ON users(tenant_id, email) WHERE email <> ''",
+	},
+}
+
+// SetUniquenessScope replaces the unique indexes of usernames and emails
+// in one transaction. Per tenant, they are composite indexes on the tenant
+// column of row-level security, see EnableRowLevelSecurity.
+func (s *PostgresStorage) SetUniquenessScope(scope storage.UniquenessScope) error {
+	statements, ok := uniquenessStatements[scope]
+	if !ok {
+		return fmt.Errorf("unknown uniqueness scope %d", scope)
+	}
+	return s.migrateTx(func(tx *sql.Tx) error {
+		for _, statement := range statements {
+			if _, err := tx.Exec(statement); err != nil {
+				return fmt.Errorf("failed to set uniqueness scope: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// duplicateError returns a *storage.DuplicateError for a violation of a
+// unique username or email index, and err otherwise.
+func duplicateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	message := err.Error()
+	if !strings.Contains(message, "duplicate key value violates unique constraint") {
+		return err
+	}
+	switch {
+	case strings.Contains(message, "email"):
+		return &storage.DuplicateError{Field: "email"}
+	case strings.Contains(message, "username"):
+		return &storage.DuplicateError{Field: "username"}
+	}
+	return err
+}
+
 // GetAppliedMigrations returns all applied migrations from the database.
 func (s *PostgresStorage) GetAppliedMigrations() ([]models.Migration, error) {
 	query := "SELECT version, description, applied_at FROM migrations ORDER BY version"
@@ -389,3 +825,80 @@ func (s *PostgresStorage) GetAppliedMigrations() ([]models.Migration, error) {
 
 	return migrations, rows.Err()
 }
+
+// postgresColumnDefinitions maps extension column types to column definitions.
+var postgresColumnDefinitions = map[storage.ColumnType][2]string{
+	storage.ColumnText:    {"TEXT", "''"},
+	storage.ColumnInteger: {"BIGINT", "0"},
+	storage.ColumnFloat:   {"DOUBLE PRECISION", "0"},
+	storage.ColumnBool:    {"BOOLEAN", "FALSE"},
+	storage.ColumnTime:    {"TIMESTAMP WITH TIME ZONE", "'epoch'"},
+}
+
+// ExtendUserSchema adds application-defined columns to the users table.
+func (s *PostgresStorage) ExtendUserSchema(columns []storage.ExtensionColumn) error {
+	for _, column := range columns {
+		if !storage.ValidColumnName(column.Name) {
+			return fmt.Errorf("invalid extension column name %q", column.Name)
+		}
+		definition, ok := postgresColumnDefinitions[column.Type]
+		if !ok {
+			return fmt.Errorf("unsupported type of extension column %q", column.Name)
+		}
+		query := fmt.Sprintf("ALTER TABLE users ADD COLUMN IF NOT EXISTS %s %s", column.Name, definition[0])
+		if !column.Nullable {
+			query += " NOT NULL DEFAULT " + definition[1]
+		}
+		if _, err := s.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to add extension column %q: %w", column.Name, err)
+		}
+	}
+	return nil
+}
+
+// GetUserExtension reads application-defined columns of a user.
+func (s *PostgresStorage) GetUserExtension(userID string, columns []string, dest []interface{}) error {
+	for _, column := range columns {
+		if !storage.ValidColumnName(column) {
+			return fmt.Errorf("invalid extension column name %q", column)
+		}
+	}
+	query := fmt.Sprintf("SELECT %s FROM users WHERE id = $1", strings.Join(columns, ", "))
+	err := s.scoped(func(q queryer) error {
+		return q.QueryRow(query, userID).Scan(dest...)
+	})
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("user not found")
+	}
+	return err
+}
+
+// UpdateUserExtension writes application-defined columns of a user.
+func (s *PostgresStorage) UpdateUserExtension(userID string, columns []string, values []interface{}) error {
+	setParts := []string{"updated_at = $1"}
+	args := []interface{}{time.Now()}
+	for i, column := range columns {
+		if !storage.ValidColumnName(column) {
+			return fmt.Errorf("invalid extension column name %q", column)
+		}
+		args = append(args, values[i])
+		setParts = append(setParts, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+
+	args = append(args, userID)
+	query := fmt.Sprintf("UPDATE users SET %s WHERE id = $%d", strings.Join(setParts, ", "), len(args))
+	result, err := s.exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
@@ -2,11 +2,13 @@ package postgres
 
 import (
 	"database/sql"
+	"errors"
 	"os"
 	"testing"
 	"time"
 
 	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
 	_ "github.com/lib/pq"
 )
 
@@ -340,4 +342,82 @@ func TestPostgresStorage_ErrorCases(t *testing.T) {
 			t.Error("Expected error when creating duplicate user")
 		}
 	})
-}
\ No newline at end of file
+}
+func TestPostgresStorage_RowLevelSecurity(t *testing.T) {
+	base := setupTestDB(t)
+	if err := base.EnableRowLevelSecurity(); err != nil {
+		t.Fatalf("Failed to enable row-level security: %v", err)
+	}
+	t.Cleanup(func() {
+		base.db.Exec("ALTER TABLE users NO FORCE ROW LEVEL SECURITY")
+		base.db.Exec("ALTER TABLE users DISABLE ROW LEVEL SECURITY")
+		base.db.Exec("DELETE FROM users WHERE username LIKE 'testrls%'")
+	})
+
+	acme, globex := base.ForTenant("acme").(*PostgresStorage), base.ForTenant("globex").(*PostgresStorage)
+	user := models.User{
+		ID:           "a1b2c3d4-0000-4000-8000-000000000001",
+		Username:     "testrls_acme",
+		Email:        "testrls_acme@example.com",
+		PasswordHash: "hash",
+		IsActive:     true,
+	}
+	if err := acme.CreateUser(user); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+
+	if _, err := acme.GetUserByID(user.ID); err != nil {
+		t.Errorf("Expected the tenant to see its user: %v", err)
+	}
+	if _, err := globex.GetUserByID(user.ID); err == nil {
+		t.Error("Expected another tenant not to see the user")
+	}
+	if err := globex.UpdatePassword(user.ID, "other"); err == nil {
+		t.Error("Expected another tenant not to update the user")
+	}
+	if users, err := globex.ListUsers(100, 0); err != nil || len(users) != 0 {
+		t.Errorf("Expected another tenant to list no users, got %d, %v", len(users), err)
+	}
+	if _, err := base.GetUserByID(user.ID); err == nil {
+		t.Error("Expected queries without a tenant to see no users")
+	}
+}
+
+func TestPostgresStorage_UniquenessScope(t *testing.T) {
+	base := setupTestDB(t)
+	if err := base.EnableRowLevelSecurity(); err != nil {
+		t.Fatalf("Failed to enable row-level security: %v", err)
+	}
+	if err := base.SetUniquenessScope(storage.UniquePerTenant); err != nil {
+		t.Fatalf("Failed to set per-tenant uniqueness: %v", err)
+	}
+	t.Cleanup(func() {
+		base.db.Exec("ALTER TABLE users NO FORCE ROW LEVEL SECURITY")
+		base.db.Exec("ALTER TABLE users DISABLE ROW LEVEL SECURITY")
+		base.db.Exec("DELETE FROM users WHERE username LIKE 'testunique%'")
+		base.SetUniquenessScope(storage.UniqueGlobal)
+	})
+
+	acme, globex := base.ForTenant("acme").(*PostgresStorage), base.ForTenant("globex").(*PostgresStorage)
+	user := func(id string) models.User {
+		return models.User{
+			ID:           "a1b2c3d4-0000-4000-8000-00000000000" + id,
+			Username:     "testunique_alice",
+			Email:        "testunique_alice@example.com",
+			PasswordHash: "hash",
+			IsActive:     true,
+		}
+	}
+	if err := acme.CreateUser(user("1")); err != nil {
+		t.Fatalf("Failed to create user: %v", err)
+	}
+	if err := globex.CreateUser(user("2")); err != nil {
+		t.Errorf("Expected another tenant to reuse the username: %v", err)
+	}
+	duplicate := user("3")
+	duplicate.Email = "testunique_other@example.com"
+	var dupErr *storage.DuplicateError
+	if err := acme.CreateUser(duplicate); !errors.As(err, &dupErr) || dupErr.Field != "username" {
+		t.Errorf("Expected a duplicate username in the tenant, got %v", err)
+	}
+}
@@ -0,0 +1,7 @@
+//go:build cgo
+
+package sqlite
+
+// The default driver needs cgo. Builds without it register a driver of
+// their own and use NewSQLiteStorageWithDriver or NewSQLiteStorageWithDB.
+import _ "github.com/mattn/go-sqlite3" // Import the sqlite3 driver
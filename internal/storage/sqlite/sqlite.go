@@ -5,12 +5,13 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/pragneshbagary/go-auth/pkg/models"
 	"github.com/pragneshbagary/go-auth/pkg/storage"
-	_ "github.com/mattn/go-sqlite3" // Import the sqlite3 driver
 )
 
 // SQLiteStorage is a SQLite implementation of the storage.EnhancedStorage interface.
@@ -18,19 +19,76 @@ type SQLiteStorage struct {
 	db *sql.DB
 }
 
+// DefaultDriver is the database/sql driver NewSQLiteStorage opens databases
+// with. It is registered by github.com/mattn/go-sqlite3 in cgo builds only.
+const DefaultDriver = "sqlite3"
+
 // NewSQLiteStorage creates a new SQLite storage instance and initializes the database schema.
 func NewSQLiteStorage(dataSourceName string) (*SQLiteStorage, error) {
-	db, err := sql.Open("sqlite3", dataSourceName)
+	return NewSQLiteStorageWithDriver(DefaultDriver, dataSourceName)
+}
+
+// NewSQLiteStorageWithDriver creates a SQLite storage on a database opened
+// with the named database/sql driver, e.g. "sqlite" for the pure-Go
+// modernc.org/sqlite, which the caller imports to register it.
+func NewSQLiteStorageWithDriver(driverName, dataSourceName string) (*SQLiteStorage, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	return newSQLiteStorage(db)
+}
+
+// NewSQLiteStorageWithDB creates a SQLite storage on a database the caller
+// opened, with any driver and connection pool settings. Close closes db.
+func NewSQLiteStorageWithDB(db *sql.DB) (*SQLiteStorage, error) {
+	if db == nil {
+		return nil, fmt.Errorf("database is nil")
+	}
+	return newSQLiteStorage(db)
+}
+
+// memoryDatabases numbers the unnamed in-memory databases.
+var memoryDatabases atomic.Int64
+
+// NewInMemorySQLiteStorage creates a SQLite storage in a shared-cache
+// in-memory database, for tests that exercise the real SQL without the cost
+// of a database file. Storages opened with the same name share one
+// database; an empty name opens a fresh one. Foreign keys are enforced as
+// in a file database, but SQLite keeps in-memory databases in "memory"
+// journal mode, so WAL is not available. It needs DefaultDriver.
+//
+// An in-memory database is dropped when its last connection closes, and
+// shared-cache connections lock whole tables against each other, so every
+// call goes through one connection that stays open until Close.
+func NewInMemorySQLiteStorage(name string) (*SQLiteStorage, error) {
+	if name == "" {
+		name = fmt.Sprintf("go-auth-%d", memoryDatabases.Add(1))
+	}
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared&_foreign_keys=on&_busy_timeout=5000", url.PathEscape(name))
+	db, err := sql.Open(DefaultDriver, dsn)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(0)
+	db.SetConnMaxIdleTime(0)
 
+	return newSQLiteStorage(db)
+}
+
+// newSQLiteStorage pings db and initializes the database schema.
+func newSQLiteStorage(db *sql.DB) (*SQLiteStorage, error) {
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
 	storage := &SQLiteStorage{db: db}
 	if err := storage.init(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to initialize storage: %w", err)
 	}
 
@@ -61,11 +119,22 @@ func (s *SQLiteStorage) init() error {
         updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
         last_login_at DATETIME,
         is_active BOOLEAN NOT NULL DEFAULT 1,
-        metadata TEXT
+        metadata TEXT,
+        display_name TEXT NOT NULL DEFAULT '',
+        locale TEXT NOT NULL DEFAULT '',
+        timezone TEXT NOT NULL DEFAULT '',
+        expires_at DATETIME
     );`
 	if _, err := s.db.Exec(usersQuery); err != nil {
 		return fmt.Errorf("failed to create users table: %w", err)
 	}
+	// Tables created before the profile columns existed
+	if err := s.ExtendUserSchema(storage.ProfileColumns); err != nil {
+		return fmt.Errorf("failed to add profile columns: %w", err)
+	}
+	if err := s.ExtendUserSchema(storage.AccountColumns); err != nil {
+		return fmt.Errorf("failed to add account columns: %w", err)
+	}
 
 	// Create blacklisted_tokens table
 	tokensQuery := `
@@ -78,11 +147,41 @@ func (s *SQLiteStorage) init() error {
 		return fmt.Errorf("failed to create blacklisted_tokens table: %w", err)
 	}
 
+	// Create refresh_families table
+	familiesQuery := `
+    CREATE TABLE IF NOT EXISTS refresh_families (
+        family_id TEXT PRIMARY KEY,
+        cutoff BIGINT NOT NULL,
+        expires_at DATETIME NOT NULL
+    );`
+	if _, err := s.db.Exec(familiesQuery); err != nil {
+		return fmt.Errorf("failed to create refresh_families table: %w", err)
+	}
+
+	// Create reset_tokens table
+	resetTokensQuery := `
+    CREATE TABLE IF NOT EXISTS reset_tokens (
+        id TEXT PRIMARY KEY,
+        code_hash TEXT NOT NULL DEFAULT '',
+        user_id TEXT NOT NULL,
+        channel TEXT NOT NULL,
+        attempts INTEGER NOT NULL DEFAULT 0,
+        created_at DATETIME NOT NULL,
+        expires_at DATETIME NOT NULL
+    );`
+	if _, err := s.db.Exec(resetTokensQuery); err != nil {
+		return fmt.Errorf("failed to create reset_tokens table: %w", err)
+	}
+
 	// Create indexes for better performance
 	indexes := []string{
 		"CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);",
 		"CREATE INDEX IF NOT EXISTS idx_users_username ON users(username);",
 		"CREATE INDEX IF NOT EXISTS idx_blacklisted_tokens_expires_at ON blacklisted_tokens(expires_at);",
+		"CREATE INDEX IF NOT EXISTS idx_refresh_families_expires_at ON refresh_families(expires_at);",
+		"CREATE INDEX IF NOT EXISTS idx_reset_tokens_user_id ON reset_tokens(user_id);",
+		"CREATE INDEX IF NOT EXISTS idx_reset_tokens_expires_at ON reset_tokens(expires_at);",
+		"CREATE INDEX IF NOT EXISTS idx_users_phone ON users(json_extract(metadata, '$.phone'));",
 	}
 
 	for _, indexQuery := range indexes {
@@ -113,21 +212,23 @@ func (s *SQLiteStorage) CreateUser(user models.User) error {
 		}
 	}
 
-	query := `INSERT INTO users (id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata) 
-              VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO users (id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata, display_name, locale, timezone, expires_at) 
+              VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	_, err := s.db.Exec(query, user.ID, user.Username, user.Email, user.PasswordHash, 
-		user.CreatedAt, user.UpdatedAt, user.LastLoginAt, user.IsActive, metadataJSON)
-	return err
+		user.CreatedAt, user.UpdatedAt, user.LastLoginAt, user.IsActive, metadataJSON,
+		user.DisplayName, user.Locale, user.Timezone, user.ExpiresAt)
+	return duplicateError(err)
 }
 
 // GetUserByUsername retrieves a user by their username.
 func (s *SQLiteStorage) GetUserByUsername(username string) (*models.User, error) {
 	user := &models.User{}
 	var metadataJSON []byte
-	query := `SELECT id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata 
+	query := `SELECT id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata, display_name, locale, timezone, expires_at 
               FROM users WHERE username = ?`
 	err := s.db.QueryRow(query, username).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash,
-		&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.IsActive, &metadataJSON)
+		&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.IsActive, &metadataJSON,
+		&user.DisplayName, &user.Locale, &user.Timezone, &user.ExpiresAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user not found")
@@ -165,13 +266,42 @@ func (s *SQLiteStorage) UpdateUser(userID string, updates storage.UserUpdates) e
 		setParts = append(setParts, "metadata = ?")
 		args = append(args, metadataJSON)
 	}
+	if updates.LastLoginAt != nil {
+		setParts = append(setParts, "last_login_at = ?")
+		args = append(args, *updates.LastLoginAt)
+	}
+	if updates.DisplayName != nil {
+		setParts = append(setParts, "display_name = ?")
+		args = append(args, *updates.DisplayName)
+	}
+	if updates.Locale != nil {
+		setParts = append(setParts, "locale = ?")
+		args = append(args, *updates.Locale)
+	}
+	if updates.Timezone != nil {
+		setParts = append(setParts, "timezone = ?")
+		args = append(args, *updates.Timezone)
+	}
+	if updates.ExpiresAt != nil {
+		// A zero time removes the expiry
+		var expiresAt interface{}
+		if !updates.ExpiresAt.IsZero() {
+			expiresAt = *updates.ExpiresAt
+		}
+		setParts = append(setParts, "expires_at = ?")
+		args = append(args, expiresAt)
+	}
+	if updates.IsActive != nil {
+		setParts = append(setParts, "is_active = ?")
+		args = append(args, *updates.IsActive)
+	}
 
 	args = append(args, userID)
 	query := fmt.Sprintf("UPDATE users SET %s WHERE id = ?", strings.Join(setParts, ", "))
 	
 	result, err := s.db.Exec(query, args...)
 	if err != nil {
-		return err
+		return duplicateError(err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -207,10 +337,11 @@ func (s *SQLiteStorage) DeleteUser(userID string) error {
 func (s *SQLiteStorage) GetUserByID(userID string) (*models.User, error) {
 	user := &models.User{}
 	var metadataJSON []byte
-	query := `SELECT id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata 
+	query := `SELECT id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata, display_name, locale, timezone, expires_at 
               FROM users WHERE id = ?`
 	err := s.db.QueryRow(query, userID).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash,
-		&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.IsActive, &metadataJSON)
+		&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.IsActive, &metadataJSON,
+		&user.DisplayName, &user.Locale, &user.Timezone, &user.ExpiresAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user not found")
@@ -231,10 +362,11 @@ func (s *SQLiteStorage) GetUserByID(userID string) (*models.User, error) {
 func (s *SQLiteStorage) GetUserByEmail(email string) (*models.User, error) {
 	user := &models.User{}
 	var metadataJSON []byte
-	query := `SELECT id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata 
+	query := `SELECT id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata, display_name, locale, timezone, expires_at 
               FROM users WHERE email = ?`
 	err := s.db.QueryRow(query, email).Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash,
-		&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.IsActive, &metadataJSON)
+		&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.IsActive, &metadataJSON,
+		&user.DisplayName, &user.Locale, &user.Timezone, &user.ExpiresAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("user not found")
@@ -253,20 +385,25 @@ func (s *SQLiteStorage) GetUserByEmail(email string) (*models.User, error) {
 
 // ListUsers retrieves a paginated list of users.
 func (s *SQLiteStorage) ListUsers(limit, offset int) ([]*models.User, error) {
-	query := `SELECT id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata 
+	query := `SELECT id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata, display_name, locale, timezone, expires_at 
               FROM users ORDER BY created_at DESC LIMIT ? OFFSET ?`
 	rows, err := s.db.Query(query, limit, offset)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
+	return scanUsers(rows)
+}
 
+// scanUsers reads the users of rows selected with the columns of ListUsers.
+func scanUsers(rows *sql.Rows) ([]*models.User, error) {
 	var users []*models.User
 	for rows.Next() {
 		user := &models.User{}
 		var metadataJSON []byte
 		err := rows.Scan(&user.ID, &user.Username, &user.Email, &user.PasswordHash,
-			&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.IsActive, &metadataJSON)
+			&user.CreatedAt, &user.UpdatedAt, &user.LastLoginAt, &user.IsActive, &metadataJSON,
+			&user.DisplayName, &user.Locale, &user.Timezone, &user.ExpiresAt)
 		if err != nil {
 			return nil, err
 		}
@@ -302,6 +439,33 @@ func (s *SQLiteStorage) UpdatePassword(userID string, passwordHash string) error
 	return nil
 }
 
+// duplicateError returns a *storage.DuplicateError for a violation of the
+// unique username or email index, and err otherwise.
+func duplicateError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch message := err.Error(); {
+	case strings.Contains(message, "UNIQUE constraint failed: users.username"):
+		return &storage.DuplicateError{Field: "username"}
+	case strings.Contains(message, "UNIQUE constraint failed: users.email"):
+		return &storage.DuplicateError{Field: "email"}
+	}
+	return err
+}
+
+// SetUniquenessScope makes non-empty emails unique, like usernames. SQLite
+// storages have no tenants, so only storage.UniqueGlobal is supported.
+func (s *SQLiteStorage) SetUniquenessScope(scope storage.UniquenessScope) error {
+	if scope != storage.UniqueGlobal {
+		return fmt.Errorf("SQLite storage only supports global uniqueness")
+	}
+	if _, err := s.db.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_unique ON users(email) WHERE email <> ''"); err != nil {
+		return fmt.Errorf("failed to create unique email index: %w", err)
+	}
+	return nil
+}
+
 // BlacklistToken adds a token to the blacklist.
 func (s *SQLiteStorage) BlacklistToken(tokenID string, expiresAt time.Time) error {
 	query := "INSERT INTO blacklisted_tokens (token_id, expires_at) VALUES (?, ?)"
@@ -322,15 +486,179 @@ func (s *SQLiteStorage) IsTokenBlacklisted(tokenID string) (bool, error) {
 
 // CleanupExpiredTokens removes expired tokens from the blacklist.
 func (s *SQLiteStorage) CleanupExpiredTokens() error {
-	_, err := s.db.Exec("DELETE FROM blacklisted_tokens WHERE expires_at <= ?", time.Now())
+	now := time.Now()
+	if _, err := s.db.Exec("DELETE FROM blacklisted_tokens WHERE expires_at <= ?", now); err != nil {
+		return err
+	}
+	if _, err := s.db.Exec("DELETE FROM refresh_families WHERE expires_at <= ?", now); err != nil {
+		return err
+	}
+	_, err := s.db.Exec("DELETE FROM reset_tokens WHERE expires_at <= ?", now)
+	return err
+}
+
+// ListBlacklistedTokens lists the token blacklist, most recently blacklisted first.
+func (s *SQLiteStorage) ListBlacklistedTokens(filter storage.BlacklistFilter, limit, offset int) ([]storage.BlacklistedToken, error) {
+	var conditions []string
+	var args []interface{}
+	if !filter.IncludeExpired {
+		conditions = append(conditions, "expires_at > ?")
+		args = append(args, time.Now())
+	}
+	if filter.TokenIDPrefix != "" {
+		conditions = append(conditions, "substr(token_id, 1, length(?)) = ?")
+		args = append(args, filter.TokenIDPrefix, filter.TokenIDPrefix)
+	}
+	if !filter.CreatedAfter.IsZero() {
+		conditions = append(conditions, "created_at >= ?")
+		args = append(args, filter.CreatedAfter.UTC())
+	}
+	if !filter.CreatedBefore.IsZero() {
+		conditions = append(conditions, "created_at < ?")
+		args = append(args, filter.CreatedBefore.UTC())
+	}
+	query := "SELECT token_id, expires_at, created_at FROM blacklisted_tokens"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, token_id LIMIT ? OFFSET ?"
+	args = append(args, limit, offset)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []storage.BlacklistedToken
+	for rows.Next() {
+		var token storage.BlacklistedToken
+		if err := rows.Scan(&token.TokenID, &token.ExpiresAt, &token.CreatedAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeRefreshFamily records the cutoff generation of a refresh token family.
+func (s *SQLiteStorage) RevokeRefreshFamily(familyID string, cutoff int64, expiresAt time.Time) error {
+	query := `
+    INSERT INTO refresh_families (family_id, cutoff, expires_at) VALUES (?, ?, ?)
+    ON CONFLICT (family_id) DO UPDATE SET
+        expires_at = CASE WHEN excluded.cutoff >= refresh_families.cutoff THEN excluded.expires_at ELSE refresh_families.expires_at END,
+        cutoff = CASE WHEN excluded.cutoff > refresh_families.cutoff THEN excluded.cutoff ELSE refresh_families.cutoff END`
+	_, err := s.db.Exec(query, familyID, cutoff, expiresAt)
 	return err
 }
 
+// RefreshFamilyCutoff returns the cutoff generation of a refresh token family.
+func (s *SQLiteStorage) RefreshFamilyCutoff(familyID string) (int64, error) {
+	var cutoff int64
+	err := s.db.QueryRow("SELECT cutoff FROM refresh_families WHERE family_id = ? AND expires_at > ?", familyID, time.Now()).Scan(&cutoff)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return cutoff, err
+}
+
+// SaveResetToken stores a password reset token, replacing one with the same ID.
+func (s *SQLiteStorage) SaveResetToken(token storage.ResetToken) error {
+	query := `
+    INSERT INTO reset_tokens (id, code_hash, user_id, channel, attempts, created_at, expires_at)
+    VALUES (?, ?, ?, ?, ?, ?, ?)
+    ON CONFLICT (id) DO UPDATE SET
+        code_hash = excluded.code_hash, user_id = excluded.user_id, channel = excluded.channel,
+        attempts = excluded.attempts, created_at = excluded.created_at, expires_at = excluded.expires_at`
+	_, err := s.db.Exec(query, token.ID, token.CodeHash, token.UserID, token.Channel, token.Attempts, token.CreatedAt, token.ExpiresAt)
+	return err
+}
+
+// GetResetToken retrieves a password reset token, or nil if it does not exist.
+func (s *SQLiteStorage) GetResetToken(id string) (*storage.ResetToken, error) {
+	token := &storage.ResetToken{}
+	query := "SELECT id, code_hash, user_id, channel, attempts, created_at, expires_at FROM reset_tokens WHERE id = ?"
+	err := s.db.QueryRow(query, id).Scan(&token.ID, &token.CodeHash, &token.UserID, &token.Channel,
+		&token.Attempts, &token.CreatedAt, &token.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// RecordResetTokenAttempt counts a wrong code entered for a reset token.
+func (s *SQLiteStorage) RecordResetTokenAttempt(id string) (int, error) {
+	var attempts int
+	err := s.db.QueryRow("UPDATE reset_tokens SET attempts = attempts + 1 WHERE id = ? RETURNING attempts", id).Scan(&attempts)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return attempts, err
+}
+
+// DeleteResetToken deletes a password reset token and reports whether it existed.
+func (s *SQLiteStorage) DeleteResetToken(id string) (bool, error) {
+	result, err := s.db.Exec("DELETE FROM reset_tokens WHERE id = ?", id)
+	if err != nil {
+		return false, err
+	}
+	deleted, err := result.RowsAffected()
+	return deleted > 0, err
+}
+
+// ListResetTokens lists the password reset tokens of a user.
+func (s *SQLiteStorage) ListResetTokens(userID string) ([]storage.ResetToken, error) {
+	query := "SELECT id, code_hash, user_id, channel, attempts, created_at, expires_at FROM reset_tokens WHERE user_id = ?"
+	rows, err := s.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tokens []storage.ResetToken
+	for rows.Next() {
+		var token storage.ResetToken
+		if err := rows.Scan(&token.ID, &token.CodeHash, &token.UserID, &token.Channel,
+			&token.Attempts, &token.CreatedAt, &token.ExpiresAt); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, rows.Err()
+}
+
+// GetUsersByPhone retrieves the users with a phone number, using the index
+// on the "phone" metadata.
+func (s *SQLiteStorage) GetUsersByPhone(phone string) ([]*models.User, error) {
+	query := `SELECT id, username, email, password_hash, created_at, updated_at, last_login_at, is_active, metadata, display_name, locale, timezone, expires_at 
+              FROM users WHERE json_extract(metadata, '$.phone') = ?`
+	rows, err := s.db.Query(query, phone)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanUsers(rows)
+}
+
 // Ping checks the database connection.
 func (s *SQLiteStorage) Ping() error {
 	return s.db.Ping()
 }
 
+// Stats returns the connection pool statistics of the database.
+func (s *SQLiteStorage) Stats() sql.DBStats {
+	return s.db.Stats()
+}
+
+// Close closes the database. An in-memory database is dropped once every
+// storage sharing it is closed.
+func (s *SQLiteStorage) Close() error {
+	return s.db.Close()
+}
+
 // Migrate runs database migrations.
 func (s *SQLiteStorage) Migrate() error {
 	// For now, just ensure the current schema is up to date
@@ -363,6 +691,52 @@ func (s *SQLiteStorage) RemoveMigrationRecord(version int) error {
 	return err
 }
 
+// ApplyMigrationTx runs up and records the migration in one transaction.
+func (s *SQLiteStorage) ApplyMigrationTx(version int, description string, up func(tx *sql.Tx) error) error {
+	return s.migrateTx(func(tx *sql.Tx) error {
+		if err := up(tx); err != nil {
+			return err
+		}
+		query := "INSERT INTO migrations (version, description, applied_at) VALUES (?, ?, ?)"
+		_, err := tx.Exec(query, version, description, time.Now())
+		return err
+	})
+}
+
+// RevertMigrationTx runs down and removes the migration record in one transaction.
+func (s *SQLiteStorage) RevertMigrationTx(version int, down func(tx *sql.Tx) error) error {
+	return s.migrateTx(func(tx *sql.Tx) error {
+		if err := down(tx); err != nil {
+			return err
+		}
+		_, err := tx.Exec("DELETE FROM migrations WHERE version = ?", version)
+		return err
+	})
+}
+
+// DryRunTx runs fn in a transaction that is always rolled back.
+func (s *SQLiteStorage) DryRunTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	return fn(tx)
+}
+
+// migrateTx runs fn in a transaction, committing only if it succeeds.
+func (s *SQLiteStorage) migrateTx(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
 // GetAppliedMigrations returns all applied migrations from the database.
 func (s *SQLiteStorage) GetAppliedMigrations() ([]models.Migration, error) {
 	query := "SELECT version, description, applied_at FROM migrations ORDER BY version"
@@ -384,3 +758,101 @@ func (s *SQLiteStorage) GetAppliedMigrations() ([]models.Migration, error) {
 
 	return migrations, rows.Err()
 }
+
+// sqliteColumnDefinitions maps extension column types to column definitions.
+var sqliteColumnDefinitions = map[storage.ColumnType][2]string{
+	storage.ColumnText:    {"TEXT", "''"},
+	storage.ColumnInteger: {"INTEGER", "0"},
+	storage.ColumnFloat:   {"REAL", "0"},
+	storage.ColumnBool:    {"BOOLEAN", "0"},
+	storage.ColumnTime:    {"DATETIME", "'1970-01-01 00:00:00'"},
+}
+
+// ExtendUserSchema adds application-defined columns to the users table.
+func (s *SQLiteStorage) ExtendUserSchema(columns []storage.ExtensionColumn) error {
+	rows, err := s.db.Query("PRAGMA table_info(users)")
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, columnType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		if !storage.ValidColumnName(column.Name) {
+			return fmt.Errorf("invalid extension column name %q", column.Name)
+		}
+		if existing[column.Name] {
+			continue
+		}
+		definition, ok := sqliteColumnDefinitions[column.Type]
+		if !ok {
+			return fmt.Errorf("unsupported type of extension column %q", column.Name)
+		}
+		query := fmt.Sprintf("ALTER TABLE users ADD COLUMN %s %s", column.Name, definition[0])
+		if !column.Nullable {
+			query += " NOT NULL DEFAULT " + definition[1]
+		}
+		if _, err := s.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to add extension column %q: %w", column.Name, err)
+		}
+	}
+	return nil
+}
+
+// GetUserExtension reads application-defined columns of a user.
+func (s *SQLiteStorage) GetUserExtension(userID string, columns []string, dest []interface{}) error {
+	for _, column := range columns {
+		if !storage.ValidColumnName(column) {
+			return fmt.Errorf("invalid extension column name %q", column)
+		}
+	}
+	query := fmt.Sprintf("SELECT %s FROM users WHERE id = ?", strings.Join(columns, ", "))
+	err := s.db.QueryRow(query, userID).Scan(dest...)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("user not found")
+	}
+	return err
+}
+
+// UpdateUserExtension writes application-defined columns of a user.
+func (s *SQLiteStorage) UpdateUserExtension(userID string, columns []string, values []interface{}) error {
+	setParts := []string{"updated_at = ?"}
+	args := []interface{}{time.Now()}
+	for i, column := range columns {
+		if !storage.ValidColumnName(column) {
+			return fmt.Errorf("invalid extension column name %q", column)
+		}
+		setParts = append(setParts, column+" = ?")
+		args = append(args, values[i])
+	}
+
+	args = append(args, userID)
+	query := fmt.Sprintf("UPDATE users SET %s WHERE id = ?", strings.Join(setParts, ", "))
+	result, err := s.db.Exec(query, args...)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
@@ -1,6 +1,9 @@
 package sqlite
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"os"
 	"testing"
 	"time"
@@ -97,4 +100,246 @@ func TestSQLiteStorage_EnhancedInterface(t *testing.T) {
 	if version < 0 {
 		t.Errorf("Expected non-negative schema version, got %d", version)
 	}
-}
\ No newline at end of file
+}
+func TestNewInMemorySQLiteStorage(t *testing.T) {
+	first, err := NewInMemorySQLiteStorage("shared-test")
+	if err != nil {
+		t.Fatalf("NewInMemorySQLiteStorage failed: %v", err)
+	}
+	defer first.Close()
+	second, err := NewInMemorySQLiteStorage("shared-test")
+	if err != nil {
+		t.Fatalf("NewInMemorySQLiteStorage failed: %v", err)
+	}
+	defer second.Close()
+	other, err := NewInMemorySQLiteStorage("")
+	if err != nil {
+		t.Fatalf("NewInMemorySQLiteStorage failed: %v", err)
+	}
+	defer other.Close()
+
+	user := models.User{ID: "mem-id", Username: "memuser", Email: "mem@example.com", PasswordHash: "hash", IsActive: true}
+	if err := first.CreateUser(user); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+
+	// Storages with the same name share the database
+	if _, err := second.GetUserByID("mem-id"); err != nil {
+		t.Errorf("Expected user in shared database, got %v", err)
+	}
+	if _, err := other.GetUserByID("mem-id"); err == nil {
+		t.Error("Expected unnamed database to be isolated")
+	}
+
+	var foreignKeys int
+	if err := first.db.QueryRow("PRAGMA foreign_keys").Scan(&foreignKeys); err != nil || foreignKeys != 1 {
+		t.Errorf("Expected foreign keys enforced, got %d, %v", foreignKeys, err)
+	}
+
+	// Concurrent calls share the single connection without locking errors
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		go func(i int) {
+			_, err := first.ListUsers(10, 0)
+			if err == nil {
+				err = first.BlacklistToken(fmt.Sprintf("token-%d", i), time.Now().Add(time.Hour))
+			}
+			errs <- err
+		}(i)
+	}
+	for i := 0; i < 20; i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Concurrent call failed: %v", err)
+		}
+	}
+}
+
+func TestNewSQLiteStorageWithDB(t *testing.T) {
+	db, err := sql.Open(DefaultDriver, "file:injected?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("sql.Open failed: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	s, err := NewSQLiteStorageWithDB(db)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorageWithDB failed: %v", err)
+	}
+	defer s.Close()
+	if err := s.CreateUser(models.User{ID: "db-id", Username: "dbuser", PasswordHash: "hash", IsActive: true}); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil || count != 1 {
+		t.Errorf("Expected the user in the injected database, got %d, %v", count, err)
+	}
+
+	if _, err := NewSQLiteStorageWithDB(nil); err == nil {
+		t.Error("Expected an error for a nil database")
+	}
+	if _, err := NewSQLiteStorageWithDriver("no-such-driver", "test.db"); err == nil {
+		t.Error("Expected an error for an unregistered driver")
+	}
+}
+
+func TestSQLiteStorage_RefreshFamilies(t *testing.T) {
+	s, err := NewInMemorySQLiteStorage("")
+	if err != nil {
+		t.Fatalf("NewInMemorySQLiteStorage failed: %v", err)
+	}
+	defer s.Close()
+
+	var _ storage.RefreshFamilyStorage = s
+	expiresAt := time.Now().Add(time.Hour)
+	if cutoff, err := s.RefreshFamilyCutoff("family"); err != nil || cutoff != 0 {
+		t.Fatalf("Expected no cutoff, got %d, %v", cutoff, err)
+	}
+	for _, cutoff := range []int64{1, 3, 2} {
+		if err := s.RevokeRefreshFamily("family", cutoff, expiresAt); err != nil {
+			t.Fatalf("RevokeRefreshFamily failed: %v", err)
+		}
+	}
+	if cutoff, err := s.RefreshFamilyCutoff("family"); err != nil || cutoff != 3 {
+		t.Errorf("Expected the cutoff never to be lowered, got %d, %v", cutoff, err)
+	}
+
+	if err := s.RevokeRefreshFamily("expired", 5, time.Now().Add(-time.Hour)); err != nil {
+		t.Fatalf("RevokeRefreshFamily failed: %v", err)
+	}
+	if err := s.CleanupExpiredTokens(); err != nil {
+		t.Fatalf("CleanupExpiredTokens failed: %v", err)
+	}
+	var count int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM refresh_families").Scan(&count); err != nil || count != 1 {
+		t.Errorf("Expected one family after cleanup, got %d, %v", count, err)
+	}
+}
+
+func TestSQLiteStorage_ResetTokens(t *testing.T) {
+	s, err := NewInMemorySQLiteStorage("")
+	if err != nil {
+		t.Fatalf("NewInMemorySQLiteStorage failed: %v", err)
+	}
+	defer s.Close()
+
+	var _ storage.ResetTokenStorage = s
+	now := time.Now().UTC().Truncate(time.Second)
+	token := storage.ResetToken{ID: "code-id", CodeHash: "hash", UserID: "user-1", Channel: "sms",
+		CreatedAt: now, ExpiresAt: now.Add(10 * time.Minute)}
+	if err := s.SaveResetToken(token); err != nil {
+		t.Fatalf("SaveResetToken failed: %v", err)
+	}
+	if attempts, err := s.RecordResetTokenAttempt("code-id"); err != nil || attempts != 1 {
+		t.Errorf("Expected one attempt, got %d, %v", attempts, err)
+	}
+	stored, err := s.GetResetToken("code-id")
+	if err != nil || stored == nil || stored.CodeHash != "hash" || stored.Attempts != 1 || !stored.ExpiresAt.Equal(token.ExpiresAt) {
+		t.Fatalf("Expected the stored token, got %+v, %v", stored, err)
+	}
+	if tokens, err := s.ListResetTokens("user-1"); err != nil || len(tokens) != 1 {
+		t.Errorf("Expected the token of the user, got %v, %v", tokens, err)
+	}
+
+	// A new code replaces the previous one
+	token.CodeHash = "other"
+	if err := s.SaveResetToken(token); err != nil {
+		t.Fatalf("SaveResetToken failed: %v", err)
+	}
+	if stored, _ := s.GetResetToken("code-id"); stored == nil || stored.CodeHash != "other" || stored.Attempts != 0 {
+		t.Errorf("Expected the token to be replaced, got %+v", stored)
+	}
+	for _, want := range []bool{true, false} {
+		if deleted, err := s.DeleteResetToken("code-id"); err != nil || deleted != want {
+			t.Errorf("Expected DeleteResetToken to report %v, got %v, %v", want, deleted, err)
+		}
+	}
+	if stored, err := s.GetResetToken("code-id"); err != nil || stored != nil {
+		t.Errorf("Expected no token, got %+v, %v", stored, err)
+	}
+
+	var _ storage.PhoneStorage = s
+	for i, phone := range []string{"+15550100", "+15550101"} {
+		user := models.User{ID: fmt.Sprint(i), Username: "user" + fmt.Sprint(i), Email: fmt.Sprintf("user%d@example.com", i),
+			PasswordHash: "hash", CreatedAt: now, UpdatedAt: now, IsActive: true,
+			Metadata: map[string]interface{}{"phone": phone}}
+		if err := s.CreateUser(user); err != nil {
+			t.Fatalf("CreateUser failed: %v", err)
+		}
+	}
+	if users, err := s.GetUsersByPhone("+15550101"); err != nil || len(users) != 1 || users[0].ID != "1" {
+		t.Errorf("Expected the user with the number, got %v, %v", users, err)
+	}
+}
+
+func TestSQLiteStorage_ListBlacklistedTokens(t *testing.T) {
+	s, err := NewInMemorySQLiteStorage("")
+	if err != nil {
+		t.Fatalf("NewInMemorySQLiteStorage failed: %v", err)
+	}
+	defer s.Close()
+
+	var _ storage.BlacklistListingStorage = s
+	now := time.Now().UTC()
+	for i, id := range []string{"access-1", "access-2", "refresh-1", "expired-1"} {
+		expiresAt := now.Add(time.Hour)
+		if id == "expired-1" {
+			expiresAt = now.Add(-time.Hour)
+		}
+		createdAt := now.Add(time.Duration(i-4) * time.Minute)
+		if _, err := s.db.Exec("INSERT INTO blacklisted_tokens (token_id, expires_at, created_at) VALUES (?, ?, ?)",
+			id, expiresAt, createdAt); err != nil {
+			t.Fatalf("Failed to insert token: %v", err)
+		}
+	}
+
+	tokens, err := s.ListBlacklistedTokens(storage.BlacklistFilter{}, 10, 0)
+	if err != nil || len(tokens) != 3 || tokens[0].TokenID != "refresh-1" {
+		t.Fatalf("Expected the unexpired tokens newest first, got %+v, %v", tokens, err)
+	}
+	tokens, _ = s.ListBlacklistedTokens(storage.BlacklistFilter{TokenIDPrefix: "access-"}, 1, 1)
+	if len(tokens) != 1 || tokens[0].TokenID != "access-1" {
+		t.Errorf("Expected the second access token, got %+v", tokens)
+	}
+	tokens, _ = s.ListBlacklistedTokens(storage.BlacklistFilter{IncludeExpired: true, CreatedAfter: now.Add(-150 * time.Second)}, 10, 0)
+	if len(tokens) != 2 || tokens[0].TokenID != "expired-1" {
+		t.Errorf("Expected the tokens blacklisted in the last minutes, got %+v", tokens)
+	}
+}
+
+func TestSQLiteStorage_UniquenessScope(t *testing.T) {
+	s, err := NewInMemorySQLiteStorage("")
+	if err != nil {
+		t.Fatalf("NewInMemorySQLiteStorage failed: %v", err)
+	}
+	defer s.Close()
+
+	var _ storage.UniquenessStorage = s
+	if err := s.SetUniquenessScope(storage.UniquePerTenant); err == nil {
+		t.Error("Expected per-tenant uniqueness to be unsupported")
+	}
+	if err := s.SetUniquenessScope(storage.UniqueGlobal); err != nil {
+		t.Fatalf("SetUniquenessScope failed: %v", err)
+	}
+
+	now := time.Now()
+	user := func(id, username, email string) models.User {
+		return models.User{ID: id, Username: username, Email: email, PasswordHash: "hash",
+			CreatedAt: now, UpdatedAt: now, IsActive: true}
+	}
+	if err := s.CreateUser(user("1", "alice", "alice@example.com")); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	var dupErr *storage.DuplicateError
+	if err := s.CreateUser(user("2", "alice2", "alice@example.com")); !errors.As(err, &dupErr) || dupErr.Field != "email" {
+		t.Errorf("Expected a duplicate email, got %v", err)
+	}
+	if err := s.CreateUser(user("3", "alice", "other@example.com")); !errors.As(err, &dupErr) || dupErr.Field != "username" {
+		t.Errorf("Expected a duplicate username, got %v", err)
+	}
+	if err := s.CreateUser(user("4", "bob", "")); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if err := s.CreateUser(user("5", "carol", "")); err != nil {
+		t.Errorf("Expected users without email to be allowed: %v", err)
+	}
+}
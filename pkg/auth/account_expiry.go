@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pragneshbagary/go-auth/internal/jwtutils"
+	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// accountExpiresClaim is the claim carrying the account expiry of users
+// with one (Unix seconds). Access tokens are rejected once it has passed.
+const accountExpiresClaim = "account_expires_at"
+
+// expiredMetadataKey is the user metadata key marking accounts deactivated
+// by DeactivateExpired, so that ExtendExpiry can reactivate them.
+const expiredMetadataKey = "deactivated_expired_at"
+
+// AccountExpiryConfig configures the background job deactivating expired
+// accounts, see Users.DeactivateExpired.
+type AccountExpiryConfig struct {
+	// Interval between runs of the job. Zero disables it; expired
+	// accounts are still refused logins and tokens.
+	Interval time.Duration
+}
+
+// accountExpired reports whether the account of user has expired at now.
+func accountExpired(user *models.User, now time.Time) bool {
+	return user.ExpiresAt != nil && !now.Before(*user.ExpiresAt)
+}
+
+// checkAccountExpiry returns ErrAccountExpired for expired accounts.
+func checkAccountExpiry(user *models.User, clock Clock) error {
+	if accountExpired(user, nowFrom(clock)) {
+		return ErrAccountExpired()
+	}
+	return nil
+}
+
+// expiringTokenManager rejects access tokens whose account expiry claim
+// has passed.
+type expiringTokenManager struct {
+	jwtutils.TokenManager
+	clock Clock
+}
+
+func (m *expiringTokenManager) ValidateAccessToken(accessToken string) (jwt.MapClaims, error) {
+	claims, err := m.TokenManager.ValidateAccessToken(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt, ok := claims[accountExpiresClaim].(float64); ok && !nowFrom(m.clock).Before(time.Unix(int64(expiresAt), 0)) {
+		return nil, ErrAccountExpired()
+	}
+	return claims, nil
+}
+
+// ExtendExpiry sets the account expiry of userID to expiresAt; a zero
+// time removes it. Accounts deactivated by DeactivateExpired are
+// reactivated if the new expiry lies in the future. Tokens issued before
+// the change keep the old expiry until they are refreshed.
+func (u *Users) ExtendExpiry(userID string, expiresAt time.Time) error {
+	if userID == "" {
+		return ErrValidationError("user ID")
+	}
+	user, err := u.storage.GetUserByID(userID)
+	if err != nil {
+		return ErrUserNotFound()
+	}
+
+	updates := storage.UserUpdates{ExpiresAt: &expiresAt}
+	if _, deactivated := user.Metadata[expiredMetadataKey]; deactivated && !user.IsActive &&
+		(expiresAt.IsZero() || nowFrom(u.clock).Before(expiresAt)) {
+		active := true
+		updates.IsActive = &active
+		updates.Metadata = withoutMetadataKey(user.Metadata, expiredMetadataKey)
+	}
+	if err := u.storage.UpdateUser(userID, updates); err != nil {
+		return WrapDatabaseError(err)
+	}
+
+	if u.eventLogger != nil {
+		u.eventLogger.LogAccountExpiry(userID, expiresAt, updates.IsActive != nil)
+	}
+	return nil
+}
+
+// DeactivateExpired deactivates the active accounts whose expiry has
+// passed and returns how many it deactivated.
+func (u *Users) DeactivateExpired(ctx context.Context) (int, error) {
+	now := nowFrom(u.clock)
+
+	// Collect first; updating while paging could shift the offsets
+	var expired []*models.User
+	const pageSize = 100
+	for offset := 0; ; offset += pageSize {
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		users, err := u.storage.ListUsers(pageSize, offset)
+		if err != nil {
+			return 0, WrapDatabaseError(err)
+		}
+		for _, user := range users {
+			if user.IsActive && accountExpired(user, now) {
+				expired = append(expired, user)
+			}
+		}
+		if len(users) < pageSize {
+			break
+		}
+	}
+
+	deactivated := 0
+	inactive := false
+	for _, user := range expired {
+		metadata := withoutMetadataKey(user.Metadata, expiredMetadataKey)
+		metadata[expiredMetadataKey] = now.UTC().Format(time.RFC3339)
+		if err := u.storage.UpdateUser(user.ID, storage.UserUpdates{IsActive: &inactive, Metadata: metadata}); err != nil {
+			return deactivated, WrapDatabaseError(err)
+		}
+		deactivated++
+		if u.eventLogger != nil {
+			u.eventLogger.LogAccountDeactivated(user.ID, *user.ExpiresAt)
+		}
+	}
+	return deactivated, nil
+}
+
+// withoutMetadataKey returns a copy of metadata without key.
+func withoutMetadataKey(metadata map[string]interface{}, key string) map[string]interface{} {
+	result := make(map[string]interface{}, len(metadata)+1)
+	for k, v := range metadata {
+		if k != key {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// startExpiryJob runs DeactivateExpired every interval until stop is
+// called. Failed runs are logged and retried at the next interval.
+func (a *Auth) startExpiryJob(interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			_, err := a.Users().DeactivateExpired(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			a.jobs.record("account_expiry", a.clock.Now(), err)
+			if err != nil {
+				a.logger.Error("Failed to deactivate expired accounts", map[string]interface{}{
+					"error": err,
+				})
+			}
+		}
+	}()
+	return cancel
+}
@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAccountExpiry(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("alice", "alice-password")
+	users := ta.Users()
+
+	if err := users.ExtendExpiry(user.ID, ta.Clock.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("ExtendExpiry failed: %v", err)
+	}
+	result := ta.LoginAs("alice")
+	claims, err := ta.ValidateAccessToken(result.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected the token to be valid before expiry, got %v", err)
+	}
+	if _, ok := claims[accountExpiresClaim]; !ok {
+		t.Errorf("Expected the %s claim, got %v", accountExpiresClaim, claims)
+	}
+
+	// Past expiry logins, tokens and refreshes fail
+	ta.Clock.Advance(time.Hour)
+	if _, err := ta.ValidateAccessToken(result.AccessToken); !errors.Is(err, ErrAccountExpired()) {
+		t.Errorf("Expected ACCOUNT_EXPIRED for the token, got %v", err)
+	}
+	if _, err := ta.Login("alice", "alice-password", nil); !errors.Is(err, ErrAccountExpired()) {
+		t.Errorf("Expected ACCOUNT_EXPIRED for the login, got %v", err)
+	}
+	if _, err := ta.Tokens().Refresh(result.RefreshToken); !errors.Is(err, ErrAccountExpired()) {
+		t.Errorf("Expected ACCOUNT_EXPIRED for the refresh, got %v", err)
+	}
+
+	// The job deactivates the account once
+	ta.SeedUser("bob", "bob-password")
+	for want := 1; want >= 0; want-- {
+		deactivated, err := users.DeactivateExpired(context.Background())
+		if err != nil || deactivated != want {
+			t.Fatalf("Expected %d accounts deactivated, got %d, %v", want, deactivated, err)
+		}
+	}
+	if stored, _ := ta.GetUser(user.ID); stored.IsActive {
+		t.Error("Expected the expired account to be deactivated")
+	}
+
+	// Renewal reactivates it
+	if err := users.ExtendExpiry(user.ID, ta.Clock.Now().Add(24*time.Hour)); err != nil {
+		t.Fatalf("ExtendExpiry failed: %v", err)
+	}
+	if _, err := ta.Login("alice", "alice-password", nil); err != nil {
+		t.Errorf("Expected login after renewal, got %v", err)
+	}
+
+	// A zero time removes the expiry
+	if err := users.ExtendExpiry(user.ID, time.Time{}); err != nil {
+		t.Fatalf("ExtendExpiry failed: %v", err)
+	}
+	if stored, _ := ta.GetUser(user.ID); stored.ExpiresAt != nil {
+		t.Errorf("Expected no expiry, got %v", stored.ExpiresAt)
+	}
+}
@@ -0,0 +1,180 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// ActionTokenParam is the query parameter used for signed URLs.
+const ActionTokenParam = "token"
+
+// actionAudiencePrefix scopes action tokens so they can never be accepted
+// as access tokens or for a different purpose.
+const actionAudiencePrefix = "action:"
+
+// ActionClaims describes a verified single-purpose action token.
+type ActionClaims struct {
+	ID        string            `json:"id"`
+	Purpose   string            `json:"purpose"`
+	Subject   string            `json:"subject"`
+	Params    map[string]string `json:"params,omitempty"`
+	IssuedAt  time.Time         `json:"issued_at"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// deriveKey derives a purpose-specific key from the access secret so that
+// action and CSRF tokens are cryptographically separate from access tokens.
+func deriveKey(secret, label string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(label))
+	return mac.Sum(nil)
+}
+
+// SignAction issues a single-purpose token, e.g. for email unsubscribe links,
+// file downloads or invite acceptance. The token is only accepted by
+// VerifyAction with the same purpose and is never valid as an access token.
+func (t *Tokens) SignAction(purpose, subject string, params map[string]string, ttl time.Duration) (string, error) {
+	if purpose == "" {
+		return "", ErrValidationError("purpose")
+	}
+	if ttl <= 0 {
+		return "", ErrValidationError("ttl")
+	}
+
+	now := nowFrom(t.clock)
+	tokenID := uuid.New().String()
+	claims := jwt.MapClaims{
+		"aud":        actionAudiencePrefix + purpose,
+		"sub":        subject,
+		"iat":        now.Unix(),
+		"exp":        now.Add(ttl).Unix(),
+		"jti":        tokenID,
+		"token_type": "action",
+	}
+	if len(params) > 0 {
+		claims["params"] = params
+	}
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.actionKey)
+	if t.eventLogger != nil {
+		t.eventLogger.LogActionToken("issue", purpose, subject, tokenID, err == nil, err)
+	}
+	if err != nil {
+		return "", WrapError(err, ErrCodeInternalError, "Failed to sign action token")
+	}
+	t.pending.add(purpose, PendingToken{ID: tokenID, UserID: subject, CreatedAt: now, ExpiresAt: now.Add(ttl)})
+	return signed, nil
+}
+
+// VerifyAction validates an action token issued by SignAction for purpose.
+func (t *Tokens) VerifyAction(tokenString, purpose string) (*ActionClaims, error) {
+	claims, err := t.verifyAction(tokenString, purpose)
+
+	if t.eventLogger != nil {
+		var subject, tokenID string
+		if claims != nil {
+			subject, tokenID = claims.Subject, claims.ID
+		}
+		t.eventLogger.LogActionToken("verify", purpose, subject, tokenID, err == nil, err)
+	}
+	if err == nil && purpose == PurposeEmailVerification {
+		t.metricsCollector.RecordFunnelStep(FunnelEmailVerification, true)
+	}
+	return claims, err
+}
+
+func (t *Tokens) verifyAction(tokenString, purpose string) (*ActionClaims, error) {
+	if tokenString == "" {
+		return nil, ErrMissingToken()
+	}
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return t.actionKey, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithAudience(actionAudiencePrefix+purpose),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(func() time.Time { return nowFrom(t.clock) }),
+	)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, NewAuthError(ErrCodeTokenExpired, "Action token has expired")
+		}
+		return nil, ErrInvalidToken()
+	}
+
+	mapClaims, _ := token.Claims.(jwt.MapClaims)
+	if tokenType, _ := mapClaims["token_type"].(string); tokenType != "action" {
+		return nil, ErrInvalidToken()
+	}
+
+	result := &ActionClaims{Purpose: purpose}
+	result.ID, _ = mapClaims["jti"].(string)
+	if _, tracked := pendingPurposes[purpose]; tracked && t.pending != nil {
+		// Tracked tokens can be revoked with Users().RevokePendingTokens
+		revoked, err := t.storage.IsTokenBlacklisted(result.ID)
+		if err != nil {
+			return nil, WrapDatabaseError(err)
+		}
+		if revoked {
+			return nil, ErrInvalidToken()
+		}
+	}
+	result.Subject, _ = mapClaims["sub"].(string)
+	if iat, err := mapClaims.GetIssuedAt(); err == nil && iat != nil {
+		result.IssuedAt = iat.Time
+	}
+	if exp, err := mapClaims.GetExpirationTime(); err == nil && exp != nil {
+		result.ExpiresAt = exp.Time
+	}
+	if params, ok := mapClaims["params"].(map[string]interface{}); ok {
+		result.Params = make(map[string]string, len(params))
+		for k, v := range params {
+			if s, ok := v.(string); ok {
+				result.Params[k] = s
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// SignURL returns rawURL with an action token appended as the "token" query
+// parameter. The token is bound to the URL path, so it cannot be reused on
+// another endpoint.
+func (t *Tokens) SignURL(rawURL, purpose, subject string, ttl time.Duration) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", ErrValidationError("url")
+	}
+
+	token, err := t.SignAction(purpose, subject, map[string]string{"path": u.EscapedPath()}, ttl)
+	if err != nil {
+		return "", err
+	}
+
+	query := u.Query()
+	query.Set(ActionTokenParam, token)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// VerifyURL validates the action token of a request to a URL produced by SignURL.
+func (t *Tokens) VerifyURL(r *http.Request, purpose string) (*ActionClaims, error) {
+	claims, err := t.VerifyAction(r.URL.Query().Get(ActionTokenParam), purpose)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Params["path"] != r.URL.EscapedPath() {
+		return nil, NewAuthErrorWithDetails(ErrCodeInvalidToken,
+			"Invalid or expired token", "Signed URL does not match the requested path")
+	}
+	return claims, nil
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newActionTestTokens() (*Tokens, *FrozenClock) {
+	clock := NewFrozenClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	return &Tokens{actionKey: deriveKey("test-secret", "go-auth action tokens"), clock: clock}, clock
+}
+
+func TestActionTokens(t *testing.T) {
+	tokens, clock := newActionTestTokens()
+
+	token, err := tokens.SignAction("unsubscribe", "user-1", map[string]string{"list": "news"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to sign action token: %v", err)
+	}
+
+	claims, err := tokens.VerifyAction(token, "unsubscribe")
+	if err != nil {
+		t.Fatalf("Expected action token to verify, got %v", err)
+	}
+	if claims.Subject != "user-1" || claims.Params["list"] != "news" || claims.ID == "" {
+		t.Errorf("Unexpected action claims: %+v", claims)
+	}
+	if !claims.ExpiresAt.Equal(clock.Now().Add(time.Hour)) {
+		t.Errorf("Expected expiry %v, got %v", clock.Now().Add(time.Hour), claims.ExpiresAt)
+	}
+
+	if _, err := tokens.VerifyAction(token, "invite_accept"); !errors.Is(err, ErrInvalidToken()) {
+		t.Errorf("Expected token for another purpose to be rejected, got %v", err)
+	}
+
+	other := &Tokens{actionKey: deriveKey("other-secret", "go-auth action tokens"), clock: clock}
+	if _, err := other.VerifyAction(token, "unsubscribe"); !errors.Is(err, ErrInvalidToken()) {
+		t.Errorf("Expected token signed with another key to be rejected, got %v", err)
+	}
+
+	clock.Advance(2 * time.Hour)
+	if _, err := tokens.VerifyAction(token, "unsubscribe"); !errors.Is(err, NewAuthError(ErrCodeTokenExpired, "")) {
+		t.Errorf("Expected expired token error, got %v", err)
+	}
+}
+
+func TestActionTokenValidation(t *testing.T) {
+	tokens, _ := newActionTestTokens()
+
+	if _, err := tokens.SignAction("", "user-1", nil, time.Hour); err == nil {
+		t.Error("Expected empty purpose to be rejected")
+	}
+	if _, err := tokens.SignAction("download", "user-1", nil, 0); err == nil {
+		t.Error("Expected non-positive TTL to be rejected")
+	}
+	if _, err := tokens.VerifyAction("", "download"); !errors.Is(err, ErrMissingToken()) {
+		t.Errorf("Expected missing token error, got %v", err)
+	}
+}
+
+func TestSignedURL(t *testing.T) {
+	tokens, _ := newActionTestTokens()
+
+	signed, err := tokens.SignURL("https://example.com/files/report.pdf?inline=1", "download", "user-1", 5*time.Minute)
+	if err != nil {
+		t.Fatalf("Failed to sign URL: %v", err)
+	}
+	if !strings.Contains(signed, "inline=1") || !strings.Contains(signed, ActionTokenParam+"=") {
+		t.Errorf("Expected signed URL to keep existing query and add token, got %s", signed)
+	}
+
+	claims, err := tokens.VerifyURL(httptest.NewRequest("GET", signed, nil), "download")
+	if err != nil {
+		t.Fatalf("Expected signed URL to verify, got %v", err)
+	}
+	if claims.Subject != "user-1" {
+		t.Errorf("Expected subject user-1, got %s", claims.Subject)
+	}
+
+	moved := strings.Replace(signed, "/files/report.pdf", "/files/secret.pdf", 1)
+	if _, err := tokens.VerifyURL(httptest.NewRequest("GET", moved, nil), "download"); err == nil {
+		t.Error("Expected token to be rejected on a different path")
+	}
+}
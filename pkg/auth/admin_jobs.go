@@ -0,0 +1,332 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// Kinds of admin jobs.
+const (
+	AdminJobRevokeTokens       = "revoke_tokens"
+	AdminJobForcePasswordReset = "force_password_reset"
+	AdminJobDeactivateDormant  = "deactivate_dormant"
+)
+
+// States of admin jobs.
+const (
+	AdminJobRunning   = "running"
+	AdminJobCompleted = "completed"
+	AdminJobCancelled = "cancelled"
+	AdminJobFailed    = "failed"
+)
+
+// maxAdminJobErrors bounds the errors kept in a job's status.
+const maxAdminJobErrors = 100
+
+// UserFilter selects the users of an admin job. Set fields must all match;
+// the zero filter matches every user.
+type UserFilter struct {
+	// UserIDs restricts the job to these users.
+	UserIDs []string
+	// Role matches users with the role in their "roles" metadata.
+	Role string
+	// CreatedBefore and CreatedAfter bound the account creation time.
+	CreatedBefore time.Time
+	CreatedAfter  time.Time
+	// LastActiveBefore matches users who have not logged in since; users
+	// who never logged in are judged by their creation time.
+	LastActiveBefore time.Time
+	// ActiveOnly skips deactivated accounts.
+	ActiveOnly bool
+	// Match is an additional custom condition.
+	Match func(user *models.User) bool
+}
+
+// matches reports whether user is selected by the filter.
+func (f UserFilter) matches(user *models.User) bool {
+	if len(f.UserIDs) > 0 && !slices.Contains(f.UserIDs, user.ID) {
+		return false
+	}
+	if f.Role != "" && !slices.Contains(stringList(user.Metadata["roles"]), f.Role) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !user.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && !user.CreatedAt.After(f.CreatedAfter) {
+		return false
+	}
+	if !f.LastActiveBefore.IsZero() {
+		lastActive := user.CreatedAt
+		if user.LastLoginAt != nil {
+			lastActive = *user.LastLoginAt
+		}
+		if !lastActive.Before(f.LastActiveBefore) {
+			return false
+		}
+	}
+	if f.ActiveOnly && !user.IsActive {
+		return false
+	}
+	return f.Match == nil || f.Match(user)
+}
+
+// AdminJobStatus reports the progress and result of an admin job.
+type AdminJobStatus struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	State string `json:"state"`
+	// Total is the number of matched users, known once they were selected.
+	Total     int       `json:"total"`
+	Processed int       `json:"processed"`
+	Succeeded int       `json:"succeeded"`
+	Failed    int       `json:"failed"`
+	Errors    []string  `json:"errors,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+	// FinishedAt is zero while the job runs.
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+}
+
+// AdminJob is a bulk action running in the background.
+type AdminJob struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.Mutex
+	status AdminJobStatus
+}
+
+// Status returns a snapshot of the job's progress.
+func (j *AdminJob) Status() AdminJobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	status := j.status
+	status.Errors = slices.Clone(j.status.Errors)
+	return status
+}
+
+// Done returns a channel that is closed when the job has finished.
+func (j *AdminJob) Done() <-chan struct{} {
+	return j.done
+}
+
+// Cancel stops the job. Users already processed stay processed.
+func (j *AdminJob) Cancel() {
+	j.cancel()
+}
+
+// Wait blocks until the job has finished or ctx is done and returns the
+// job's status.
+func (j *AdminJob) Wait(ctx context.Context) (AdminJobStatus, error) {
+	select {
+	case <-j.done:
+		return j.Status(), nil
+	case <-ctx.Done():
+		return j.Status(), ctx.Err()
+	}
+}
+
+// update changes the status under the job's lock.
+func (j *AdminJob) update(fn func(status *AdminJobStatus)) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	fn(&j.status)
+}
+
+// AdminJobs runs bulk administrative actions, such as revoking the tokens
+// of a cohort, in the background. Jobs are kept in memory by this instance
+// until the Auth is closed.
+type AdminJobs struct {
+	auth *Auth
+
+	mu   sync.Mutex
+	jobs map[string]*AdminJob
+}
+
+func newAdminJobs(auth *Auth) *AdminJobs {
+	return &AdminJobs{auth: auth, jobs: make(map[string]*AdminJob)}
+}
+
+// AdminJobs returns the component running bulk administrative actions.
+func (a *Auth) AdminJobs() *AdminJobs {
+	return a.adminJobs
+}
+
+// RevokeTokens logs the users matching filter out everywhere, as
+// Tokens().RevokeAll does.
+func (aj *AdminJobs) RevokeTokens(filter UserFilter) *AdminJob {
+	tokens := aj.auth.Tokens()
+	return aj.start(AdminJobRevokeTokens, filter, func(user *models.User) error {
+		return tokens.RevokeAll(user.ID)
+	})
+}
+
+// ForcePasswordReset requires the users matching filter to change or reset
+// their password before they can log in again, and logs them out.
+func (aj *AdminJobs) ForcePasswordReset(filter UserFilter) *AdminJob {
+	tokens := aj.auth.Tokens()
+	return aj.start(AdminJobForcePasswordReset, filter, func(user *models.User) error {
+		metadata := make(map[string]interface{}, len(user.Metadata)+1)
+		for k, v := range user.Metadata {
+			metadata[k] = v
+		}
+		metadata[passwordResetRequiredMetadataKey] = true
+		if err := aj.auth.storage.UpdateUser(user.ID, storage.UserUpdates{Metadata: metadata}); err != nil {
+			return err
+		}
+		return tokens.RevokeAll(user.ID)
+	})
+}
+
+// DeactivateDormant deactivates the active accounts that have not logged in
+// for inactiveFor. Like the other jobs it can be narrowed with filter.
+func (aj *AdminJobs) DeactivateDormant(inactiveFor time.Duration, filter UserFilter) (*AdminJob, error) {
+	if inactiveFor <= 0 {
+		return nil, ErrValidationError("inactiveFor")
+	}
+	filter.LastActiveBefore = nowFrom(aj.auth.clock).Add(-inactiveFor)
+	filter.ActiveOnly = true
+	inactive := false
+	return aj.start(AdminJobDeactivateDormant, filter, func(user *models.User) error {
+		return aj.auth.storage.UpdateUser(user.ID, storage.UserUpdates{IsActive: &inactive})
+	}), nil
+}
+
+// Get returns the job with id.
+func (aj *AdminJobs) Get(id string) (*AdminJob, bool) {
+	aj.mu.Lock()
+	defer aj.mu.Unlock()
+	job, ok := aj.jobs[id]
+	return job, ok
+}
+
+// List returns the status of every job, most recent first.
+func (aj *AdminJobs) List() []AdminJobStatus {
+	aj.mu.Lock()
+	statuses := make([]AdminJobStatus, 0, len(aj.jobs))
+	for _, job := range aj.jobs {
+		statuses = append(statuses, job.Status())
+	}
+	aj.mu.Unlock()
+	slices.SortFunc(statuses, func(a, b AdminJobStatus) int {
+		return b.StartedAt.Compare(a.StartedAt)
+	})
+	return statuses
+}
+
+// Cancel stops the job with id.
+func (aj *AdminJobs) Cancel(id string) error {
+	job, ok := aj.Get(id)
+	if !ok {
+		return NewAuthError(ErrCodeValidationError, "Admin job not found")
+	}
+	job.Cancel()
+	return nil
+}
+
+// cancelAll stops the running jobs when the Auth is closed.
+func (aj *AdminJobs) cancelAll() {
+	aj.mu.Lock()
+	defer aj.mu.Unlock()
+	for _, job := range aj.jobs {
+		job.Cancel()
+	}
+}
+
+// start runs action for the users matching filter in the background.
+func (aj *AdminJobs) start(kind string, filter UserFilter, action func(user *models.User) error) *AdminJob {
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &AdminJob{
+		cancel: cancel,
+		done:   make(chan struct{}),
+		status: AdminJobStatus{
+			ID:        uuid.New().String(),
+			Kind:      kind,
+			State:     AdminJobRunning,
+			StartedAt: nowFrom(aj.auth.clock),
+		},
+	}
+	aj.mu.Lock()
+	aj.jobs[job.status.ID] = job
+	aj.mu.Unlock()
+	aj.auth.eventLogger.LogAdminJob(job.Status())
+
+	go aj.run(ctx, job, filter, action)
+	return job
+}
+
+// run selects the users and applies action to each until done or cancelled.
+func (aj *AdminJobs) run(ctx context.Context, job *AdminJob, filter UserFilter, action func(user *models.User) error) {
+	defer close(job.done)
+	defer job.cancel()
+
+	state := AdminJobCompleted
+	users, err := aj.selectUsers(ctx, filter)
+	if err != nil {
+		state = AdminJobFailed
+		job.update(func(status *AdminJobStatus) {
+			status.Errors = append(status.Errors, err.Error())
+		})
+	}
+	job.update(func(status *AdminJobStatus) { status.Total = len(users) })
+
+	for _, user := range users {
+		if ctx.Err() != nil {
+			break
+		}
+		err := action(user)
+		job.update(func(status *AdminJobStatus) {
+			status.Processed++
+			if err == nil {
+				status.Succeeded++
+				return
+			}
+			status.Failed++
+			if len(status.Errors) < maxAdminJobErrors {
+				status.Errors = append(status.Errors, fmt.Sprintf("%s: %v", user.ID, err))
+			}
+		})
+	}
+	if ctx.Err() != nil {
+		state = AdminJobCancelled
+	}
+
+	job.update(func(status *AdminJobStatus) {
+		status.State = state
+		status.FinishedAt = nowFrom(aj.auth.clock)
+	})
+	final := job.Status()
+	aj.auth.jobs.record("admin_"+final.Kind, final.FinishedAt, err)
+	aj.auth.eventLogger.LogAdminJob(final)
+}
+
+// selectUsers collects the users matching filter. Collecting first keeps
+// the paging offsets stable while the job changes users.
+func (aj *AdminJobs) selectUsers(ctx context.Context, filter UserFilter) ([]*models.User, error) {
+	var selected []*models.User
+	const pageSize = 100
+	for offset := 0; ; offset += pageSize {
+		if err := ctx.Err(); err != nil {
+			return selected, nil
+		}
+		users, err := aj.auth.storage.ListUsers(pageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			if filter.matches(user) {
+				selected = append(selected, user)
+			}
+		}
+		if len(users) < pageSize {
+			return selected, nil
+		}
+	}
+}
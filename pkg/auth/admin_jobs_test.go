@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// waitJob waits for job to finish and returns its status.
+func waitJob(t *testing.T, job *AdminJob) AdminJobStatus {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	status, err := job.Wait(ctx)
+	if err != nil {
+		t.Fatalf("Admin job did not finish: %v", err)
+	}
+	return status
+}
+
+func TestAdminJobsRevokeTokens(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("alice", "alice-password", "staff")
+	ta.SeedUser("bob", "bob-password")
+	alice := ta.LoginAs("alice")
+	bob := ta.LoginAs("bob")
+	ta.Clock.Advance(time.Second)
+
+	status := waitJob(t, ta.AdminJobs().RevokeTokens(UserFilter{Role: "staff"}))
+	if status.State != AdminJobCompleted || status.Total != 1 || status.Succeeded != 1 {
+		t.Fatalf("Expected one user to be processed, got %+v", status)
+	}
+	tokens := ta.Tokens()
+	if _, err := tokens.Validate(alice.AccessToken); err == nil {
+		t.Error("Expected the staff token to be revoked")
+	}
+	if _, err := tokens.Validate(bob.AccessToken); err != nil {
+		t.Errorf("Expected other users to be unaffected: %v", err)
+	}
+
+	if job, ok := ta.AdminJobs().Get(status.ID); !ok || job.Status().ID != status.ID {
+		t.Error("Expected the job to be listed")
+	}
+	if list := ta.AdminJobs().List(); len(list) != 1 || list[0].Kind != AdminJobRevokeTokens {
+		t.Errorf("Expected one listed job, got %+v", list)
+	}
+}
+
+func TestAdminJobsForcePasswordReset(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("carol", "carol-password")
+
+	status := waitJob(t, ta.AdminJobs().ForcePasswordReset(UserFilter{UserIDs: []string{user.ID}}))
+	if status.Succeeded != 1 {
+		t.Fatalf("Expected the user to be processed, got %+v", status)
+	}
+	if _, err := ta.Login("carol", "carol-password", nil); !errors.Is(err, ErrPasswordExpired()) {
+		t.Fatalf("Expected login to require a new password, got %v", err)
+	}
+
+	if err := ta.Users().ChangePassword(user.ID, "carol-password", "carol-new-password"); err != nil {
+		t.Fatalf("ChangePassword failed: %v", err)
+	}
+	if _, err := ta.Login("carol", "carol-new-password", nil); err != nil {
+		t.Errorf("Expected login after the change: %v", err)
+	}
+}
+
+func TestAdminJobsDeactivateDormant(t *testing.T) {
+	ta := NewTestAuth(t)
+	dormant := ta.SeedUser("dave", "dave-password")
+	ta.Clock.Advance(100 * 24 * time.Hour)
+	active := ta.SeedUser("erin", "erin-password")
+
+	if _, err := ta.AdminJobs().DeactivateDormant(0, UserFilter{}); err == nil {
+		t.Error("Expected a zero period to be rejected")
+	}
+	job, err := ta.AdminJobs().DeactivateDormant(90*24*time.Hour, UserFilter{})
+	if err != nil {
+		t.Fatalf("DeactivateDormant failed: %v", err)
+	}
+	if status := waitJob(t, job); status.Total != 1 {
+		t.Fatalf("Expected one dormant user, got %+v", status)
+	}
+	for _, c := range []struct {
+		user   *models.User
+		active bool
+	}{{dormant, false}, {active, true}} {
+		user, err := ta.storage.GetUserByID(c.user.ID)
+		if err != nil {
+			t.Fatalf("GetUserByID failed: %v", err)
+		}
+		if user.IsActive != c.active {
+			t.Errorf("Expected %s to be active=%v", user.Username, c.active)
+		}
+	}
+}
+
+// blockingStorage holds ListUsers until released.
+type blockingStorage struct {
+	storage.EnhancedStorage
+	release chan struct{}
+}
+
+func (s *blockingStorage) ListUsers(limit, offset int) ([]*models.User, error) {
+	<-s.release
+	return s.EnhancedStorage.ListUsers(limit, offset)
+}
+
+func TestAdminJobsCancel(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("frank", "frank-password")
+	blocking := &blockingStorage{EnhancedStorage: ta.storage, release: make(chan struct{})}
+	ta.storage = blocking
+
+	job := ta.AdminJobs().RevokeTokens(UserFilter{})
+	if status := job.Status(); status.State != AdminJobRunning {
+		t.Fatalf("Expected the job to run, got %+v", status)
+	}
+	if err := ta.AdminJobs().Cancel(job.Status().ID); err != nil {
+		t.Fatalf("Cancel failed: %v", err)
+	}
+	close(blocking.release)
+
+	if status := waitJob(t, job); status.State != AdminJobCancelled || status.Processed != 0 {
+		t.Errorf("Expected the job to be cancelled before processing, got %+v", status)
+	}
+	if err := ta.AdminJobs().Cancel("unknown"); err == nil {
+		t.Error("Expected unknown jobs to be rejected")
+	}
+}
@@ -0,0 +1,156 @@
+package auth
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// Page selects a page of an administrative listing.
+type Page struct {
+	// Limit is the number of entries per page. Defaults to 50, at most 500.
+	Limit int
+	// Offset is the number of entries skipped.
+	Offset int
+}
+
+// normalize applies the defaults and bounds of page.
+func (p Page) normalize() Page {
+	if p.Limit <= 0 {
+		p.Limit = 50
+	}
+	if p.Limit > 500 {
+		p.Limit = 500
+	}
+	if p.Offset < 0 {
+		p.Offset = 0
+	}
+	return p
+}
+
+// BlacklistFilter selects entries of the token blacklist, see
+// Tokens.ListBlacklisted.
+type BlacklistFilter = storage.BlacklistFilter
+
+// BlacklistedToken is an entry of the token blacklist.
+type BlacklistedToken = storage.BlacklistedToken
+
+// ListBlacklisted returns a page of the revoked tokens matching filter,
+// most recently revoked first, so that incident responders can inspect
+// them without querying the database. The blacklist also holds internal
+// markers, such as the token epoch's "go-auth-epoch:" entries.
+func (t *Tokens) ListBlacklisted(filter BlacklistFilter, page Page) ([]BlacklistedToken, error) {
+	lister, ok := baseStorage(t.storage).(storage.BlacklistListingStorage)
+	if !ok {
+		return nil, NewAuthErrorWithDetails(ErrCodeInvalidConfig, "Invalid configuration",
+			"The storage does not support listing blacklisted tokens")
+	}
+	page = page.normalize()
+	tokens, err := lister.ListBlacklistedTokens(filter, page.Limit, page.Offset)
+	if err != nil {
+		return nil, WrapDatabaseError(err)
+	}
+	return tokens, nil
+}
+
+// SessionFilter selects sessions, see Tokens.SearchSessions. Zero fields
+// match all sessions.
+type SessionFilter struct {
+	UserID string
+	// IP matches the client address of the login.
+	IP string
+	// IssuedAfter and IssuedBefore bound when sessions started.
+	IssuedAfter  time.Time
+	IssuedBefore time.Time
+}
+
+// matches reports whether session is selected by f.
+func (f SessionFilter) matches(session *Session) bool {
+	switch {
+	case f.UserID != "" && session.UserID != f.UserID:
+		return false
+	case f.IP != "" && session.IP != f.IP:
+		return false
+	case !f.IssuedAfter.IsZero() && session.CreatedAt.Before(f.IssuedAfter):
+		return false
+	case !f.IssuedBefore.IsZero() && !session.CreatedAt.Before(f.IssuedBefore):
+		return false
+	}
+	return true
+}
+
+// SessionSearchStore is implemented by SessionStores that can search the
+// sessions of all users. Without it, Tokens.SearchSessions requires a user.
+type SessionSearchStore interface {
+	// SearchSessions returns up to limit sessions matching filter, most
+	// recently started first, skipping the first offset.
+	SearchSessions(filter SessionFilter, limit, offset int) ([]*Session, error)
+}
+
+func (s *memorySessionStore) SearchSessions(filter SessionFilter, limit, offset int) ([]*Session, error) {
+	s.mu.RLock()
+	var sessions []*Session
+	for _, session := range s.sessions {
+		if filter.matches(session) {
+			result := *session
+			sessions = append(sessions, &result)
+		}
+	}
+	s.mu.RUnlock()
+	return pageSessions(sessions, limit, offset), nil
+}
+
+// pageSessions sorts sessions most recently started first and returns the
+// page of limit sessions at offset.
+func pageSessions(sessions []*Session, limit, offset int) []*Session {
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].CreatedAt.Equal(sessions[j].CreatedAt) {
+			return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+	if offset >= len(sessions) {
+		return []*Session{}
+	}
+	return sessions[offset:min(offset+limit, len(sessions))]
+}
+
+// SearchSessions returns a page of the sessions matching filter, most
+// recently started first. Sessions that ended but were not purged yet are
+// included. Unless the SessionStore implements SessionSearchStore, the
+// filter must name a user.
+func (t *Tokens) SearchSessions(filter SessionFilter, page Page) ([]*Session, error) {
+	if t.sessions == nil {
+		return nil, errSessionsDisabled()
+	}
+	page = page.normalize()
+
+	var sessions []*Session
+	if searcher, ok := t.sessions.store.(SessionSearchStore); ok {
+		found, err := searcher.SearchSessions(filter, page.Limit, page.Offset)
+		if err != nil {
+			return nil, WrapDatabaseError(err)
+		}
+		sessions = found
+	} else {
+		if filter.UserID == "" {
+			return nil, NewAuthErrorWithDetails(ErrCodeInvalidConfig, "Invalid configuration",
+				"The session store does not support searching the sessions of all users")
+		}
+		all, err := t.sessions.store.ListSessions(filter.UserID)
+		if err != nil {
+			return nil, WrapDatabaseError(err)
+		}
+		for _, session := range all {
+			if filter.matches(session) {
+				sessions = append(sessions, session)
+			}
+		}
+		sessions = pageSessions(sessions, page.Limit, page.Offset)
+	}
+	for _, session := range sessions {
+		t.sessions.withPending(session)
+	}
+	return sessions, nil
+}
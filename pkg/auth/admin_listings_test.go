@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSearchSessions(t *testing.T) {
+	ta, _ := newSessionTestAuth(t, 0)
+	ta.SeedUser("alice", "alice-password")
+	bob := ta.SeedUser("bob", "bob-password")
+	loginFrom := func(username, password, ip string) {
+		ctx := WithClientInfo(context.Background(), ip, "test")
+		if _, err := ta.LoginContext(ctx, username, password, nil); err != nil {
+			t.Fatalf("Login failed: %v", err)
+		}
+		ta.Clock.Advance(time.Minute)
+	}
+	loginFrom("alice", "alice-password", "203.0.113.1")
+	started := ta.Clock.Now()
+	loginFrom("bob", "bob-password", "203.0.113.1")
+	loginFrom("bob", "bob-password", "198.51.100.7")
+
+	sessions, err := ta.Tokens().SearchSessions(SessionFilter{IP: "203.0.113.1"}, Page{})
+	if err != nil || len(sessions) != 2 || sessions[0].UserID != bob.ID {
+		t.Fatalf("Expected both sessions from the address, newest first, got %+v, %v", sessions, err)
+	}
+	sessions, _ = ta.Tokens().SearchSessions(SessionFilter{UserID: bob.ID, IssuedBefore: started.Add(time.Second)}, Page{})
+	if len(sessions) != 1 || sessions[0].IP != "203.0.113.1" {
+		t.Errorf("Expected bob's first session, got %+v", sessions)
+	}
+	sessions, _ = ta.Tokens().SearchSessions(SessionFilter{IssuedAfter: started}, Page{Limit: 1, Offset: 1})
+	if len(sessions) != 1 || sessions[0].IP != "203.0.113.1" {
+		t.Errorf("Expected the second page of one session, got %+v", sessions)
+	}
+}
+
+func TestListBlacklistedUnsupported(t *testing.T) {
+	ta := NewTestAuth(t)
+	if _, err := ta.Tokens().ListBlacklisted(BlacklistFilter{}, Page{}); err == nil {
+		t.Error("Expected an error from a storage that cannot list the blacklist")
+	}
+	if _, err := ta.Tokens().SearchSessions(SessionFilter{}, Page{}); err == nil {
+		t.Error("Expected an error without session tracking")
+	}
+}
@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"embed"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+//go:embed admin_ui/*.html
+var adminUIFiles embed.FS
+
+// adminUITemplates are the pages of the admin UI.
+var adminUITemplates = template.Must(template.New("admin").Funcs(template.FuncMap{
+	"add": func(a, b int) int { return a + b },
+	"sub": func(a, b int) int { return a - b },
+}).ParseFS(adminUIFiles, "admin_ui/*.html"))
+
+// adminUIFlashes are the messages shown after the actions of the admin UI.
+var adminUIFlashes = map[string]string{
+	"deactivated": "User deactivated.",
+	"revoked":     "Sessions revoked.",
+}
+
+// adminUIPageSize is the number of users per page of search results.
+const adminUIPageSize = 50
+
+// AdminUIConfig configures AdminUI.
+type AdminUIConfig struct {
+	// Prefix is the path the handler is mounted at. Defaults to
+	// "/auth-admin".
+	Prefix string
+	// Role is the role required to use the UI. Defaults to "admin".
+	Role string
+}
+
+// adminUI serves the pages of the admin UI.
+type adminUI struct {
+	auth   *Auth
+	config AdminUIConfig
+	csrf   *CSRF
+}
+
+// adminPage is the data of an admin UI page.
+type adminPage struct {
+	Title     string
+	AppName   string
+	Prefix    string
+	Flash     string
+	CSRFField string
+	CSRFToken string
+
+	Query    string
+	Page     int
+	More     bool
+	Users    []*models.UserProfile
+	User     *models.UserProfile
+	Sessions []*SessionInfo
+	Metrics  *Metrics
+	Jobs     map[string]JobStatus
+}
+
+// AdminUI returns an HTTP handler serving a small user management UI: user
+// search, deactivation, session revocation and metrics. Mount it at
+// config.Prefix:
+//
+//	mux.Handle("/auth-admin/", auth.AdminUI(auth.AdminUIConfig{}))
+//
+// Requests must carry an access token with config.Role, as for
+// Middleware.RequireRole; browsers typically reach it through a proxy
+// that adds the token. Forms are protected by CSRF tokens.
+func (a *Auth) AdminUI(config AdminUIConfig) http.Handler {
+	if config.Prefix == "" {
+		config.Prefix = "/auth-admin"
+	}
+	config.Prefix = strings.TrimSuffix(config.Prefix, "/")
+	if config.Role == "" {
+		config.Role = "admin"
+	}
+	ui := &adminUI{auth: a, config: config, csrf: a.CSRF(CSRFConfig{})}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, config.Prefix+"/users", http.StatusFound)
+	})
+	mux.HandleFunc("GET /users", ui.users)
+	mux.HandleFunc("GET /users/{id}", ui.user)
+	mux.HandleFunc("POST /users/{id}/deactivate", ui.deactivate)
+	mux.HandleFunc("POST /users/{id}/revoke", ui.revoke)
+	mux.HandleFunc("GET /metrics", ui.metrics)
+
+	handler := http.StripPrefix(config.Prefix, mux)
+	return a.Middleware().RequireRole(config.Role)(ui.csrf.Protect(handler))
+}
+
+// page returns the data of a page titled title.
+func (ui *adminUI) page(r *http.Request, title string) *adminPage {
+	token, _ := GetCSRFTokenFromContext(r.Context())
+	return &adminPage{
+		Title:     title,
+		AppName:   ui.auth.currentConfig().AppName,
+		Prefix:    ui.config.Prefix,
+		Flash:     adminUIFlashes[r.URL.Query().Get("done")],
+		CSRFField: ui.csrf.FormField(),
+		CSRFToken: token,
+	}
+}
+
+// render writes the template name with page.
+func (ui *adminUI) render(w http.ResponseWriter, name string, page *adminPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := adminUITemplates.ExecuteTemplate(w, name, page); err != nil {
+		ui.auth.logger.Error("Failed to render admin page", map[string]interface{}{
+			"template": name,
+			"error":    err,
+		})
+	}
+}
+
+// users lists the users matching the "q" parameter.
+func (ui *adminUI) users(w http.ResponseWriter, r *http.Request) {
+	page := ui.page(r, "Users")
+	page.Query = strings.TrimSpace(r.URL.Query().Get("q"))
+	page.Page, _ = strconv.Atoi(r.URL.Query().Get("page"))
+	if page.Page < 0 {
+		page.Page = 0
+	}
+	users, more, err := ui.search(r, page.Query, page.Page*adminUIPageSize)
+	if err != nil {
+		ui.auth.writeError(w, r, err)
+		return
+	}
+	page.Users, page.More = users, more
+	ui.render(w, "users.html", page)
+}
+
+// search returns a page of the users whose username, email or ID contains
+// query, starting at the skip-th match, and whether more users match.
+func (ui *adminUI) search(r *http.Request, query string, skip int) ([]*models.UserProfile, bool, error) {
+	query = strings.ToLower(query)
+	var matches []*models.UserProfile
+	const batchSize = 100
+	for offset := 0; ; offset += batchSize {
+		if err := r.Context().Err(); err != nil {
+			return nil, false, err
+		}
+		users, err := ui.auth.storage.ListUsers(batchSize, offset)
+		if err != nil {
+			return nil, false, WrapDatabaseError(err)
+		}
+		for _, user := range users {
+			if query != "" && !strings.Contains(strings.ToLower(user.Username), query) &&
+				!strings.Contains(strings.ToLower(user.Email), query) && user.ID != query {
+				continue
+			}
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if len(matches) == adminUIPageSize {
+				return matches, true, nil
+			}
+			matches = append(matches, toProfile(user))
+		}
+		if len(users) < batchSize {
+			return matches, false, nil
+		}
+	}
+}
+
+// user shows a user and their sessions.
+func (ui *adminUI) user(w http.ResponseWriter, r *http.Request) {
+	user, err := ui.auth.Users().Get(r.PathValue("id"))
+	if err != nil {
+		ui.auth.writeError(w, r, err)
+		return
+	}
+	sessions, err := ui.auth.Tokens().ListActiveSessions(user.ID)
+	if err != nil {
+		ui.auth.writeError(w, r, err)
+		return
+	}
+	page := ui.page(r, user.Username)
+	page.User = user
+	page.Sessions = sessions
+	ui.render(w, "user.html", page)
+}
+
+// deactivate deactivates a user and ends their sessions.
+func (ui *adminUI) deactivate(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if _, err := ui.auth.Users().Get(userID); err != nil {
+		ui.auth.writeError(w, r, err)
+		return
+	}
+	inactive := false
+	if err := ui.auth.storage.UpdateUser(userID, storage.UserUpdates{IsActive: &inactive}); err != nil {
+		ui.auth.writeError(w, r, WrapDatabaseError(err))
+		return
+	}
+	if err := ui.auth.Tokens().RevokeAll(userID); err != nil {
+		ui.auth.writeError(w, r, err)
+		return
+	}
+	ui.logAction(r, "deactivate", userID)
+	ui.redirect(w, r, userID, "deactivated")
+}
+
+// revoke ends the sessions of a user.
+func (ui *adminUI) revoke(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	if _, err := ui.auth.Users().Get(userID); err != nil {
+		ui.auth.writeError(w, r, err)
+		return
+	}
+	if err := ui.auth.Tokens().RevokeAll(userID); err != nil {
+		ui.auth.writeError(w, r, err)
+		return
+	}
+	ui.logAction(r, "revoke_sessions", userID)
+	ui.redirect(w, r, userID, "revoked")
+}
+
+// metrics shows the metrics and background jobs.
+func (ui *adminUI) metrics(w http.ResponseWriter, r *http.Request) {
+	metrics := ui.auth.GetMetrics()
+	page := ui.page(r, "Metrics")
+	page.Metrics = &metrics
+	page.Jobs = ui.auth.jobs.snapshot()
+	ui.render(w, "metrics.html", page)
+}
+
+// redirect sends the browser back to the page of userID after action.
+func (ui *adminUI) redirect(w http.ResponseWriter, r *http.Request, userID, action string) {
+	target := ui.config.Prefix + "/users/" + url.PathEscape(userID) + "?done=" + action
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+// logAction logs an action taken by the administrator of r.
+func (ui *adminUI) logAction(r *http.Request, action, userID string) {
+	fields := map[string]interface{}{
+		"action":  action,
+		"user_id": userID,
+	}
+	if admin, ok := GetUserFromContext(r.Context()); ok {
+		fields["admin_id"] = admin.ID
+	}
+	ui.auth.logger.Info("Admin UI action", fields)
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestAdminUI(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("root", "root-password", "admin")
+	bob := ta.SeedUser("bob", "bob-password")
+	ta.SeedUser("carol", "carol-password")
+	handler := ta.AdminUI(AdminUIConfig{})
+	adminToken := ta.LoginAs("root").AccessToken
+
+	request := func(method, target, token string, form url.Values) *httptest.ResponseRecorder {
+		var r *http.Request
+		if form != nil {
+			r = httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		} else {
+			r = httptest.NewRequest(method, target, nil)
+		}
+		r.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec
+	}
+
+	// Non-administrators are rejected
+	if rec := request("GET", "/auth-admin/users", ta.LoginAs("bob").AccessToken, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a user without the admin role, got %d", rec.Code)
+	}
+
+	rec := request("GET", "/auth-admin/users?q=BO", adminToken, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected search to succeed, got %d: %s", rec.Code, rec.Body)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, "bob@example.test") || strings.Contains(body, "carol") {
+		t.Errorf("Expected only bob to match, got %s", body)
+	}
+
+	// Actions require the CSRF token of the user page
+	target := "/auth-admin/users/" + bob.ID + "/deactivate"
+	if rec := request("POST", target, adminToken, url.Values{}); rec.Code == http.StatusSeeOther {
+		t.Error("Expected an action without CSRF token to be rejected")
+	}
+	rec = request("GET", "/auth-admin/users/"+bob.ID, adminToken, nil)
+	csrfToken := rec.Header().Get("X-CSRF-Token")
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), csrfToken) {
+		t.Fatalf("Expected the user page to embed a CSRF token, got %d: %s", rec.Code, rec.Body)
+	}
+	rec = request("POST", target, adminToken, url.Values{"csrf_token": {csrfToken}})
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("Expected deactivation to redirect, got %d: %s", rec.Code, rec.Body)
+	}
+	if user, _ := ta.Users().Get(bob.ID); user.IsActive {
+		t.Error("Expected bob to be deactivated")
+	}
+
+	rec = request("GET", "/auth-admin/metrics", adminToken, nil)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "Logins") {
+		t.Errorf("Expected the metrics page, got %d: %s", rec.Code, rec.Body)
+	}
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+const (
+	// birthdateMetadataKey is the user metadata key holding the birthdate
+	// as "YYYY-MM-DD". It is left out of UserProfile output.
+	birthdateMetadataKey = "birthdate"
+	// ageReviewMetadataKey marks users whose registration was flagged by
+	// the registration policy.
+	ageReviewMetadataKey = "age_review"
+	// birthdateLayout is the accepted birthdate format.
+	birthdateLayout = "2006-01-02"
+)
+
+// RegistrationDecision is the outcome of a RegistrationPolicy.
+type RegistrationDecision int
+
+const (
+	// RegistrationAllow lets the registration proceed.
+	RegistrationAllow RegistrationDecision = iota
+	// RegistrationFlag lets the registration proceed but marks the user for
+	// review, e.g. until parental consent has been collected.
+	RegistrationFlag
+	// RegistrationReject rejects the registration with UNDER_AGE.
+	RegistrationReject
+)
+
+// RegistrationCheck describes a registration to a RegistrationPolicy.
+type RegistrationCheck struct {
+	Username string
+	Email    string
+	// Birthdate is zero if none was given.
+	Birthdate time.Time
+	// Age in whole years, -1 if no birthdate was given.
+	Age        int
+	MinimumAge int
+	// UnderAge is set if the birthdate is missing while required, or the
+	// user is younger than MinimumAge.
+	UnderAge bool
+}
+
+// RegistrationPolicy decides on registrations subject to age checks. An
+// error aborts the registration and is returned to the caller.
+type RegistrationPolicy func(ctx context.Context, check RegistrationCheck) (RegistrationDecision, error)
+
+// AgeConfig configures age gating of registrations, e.g. for COPPA.
+type AgeConfig struct {
+	// MinimumAge in years. Zero disables the age check.
+	MinimumAge int
+	// RequireBirthdate rejects registrations without a birthdate when
+	// MinimumAge is set. Otherwise such users are not considered under age.
+	RequireBirthdate bool
+	// Policy decides on every registration once MinimumAge is set. The
+	// default rejects under-age registrations and allows the rest.
+	Policy RegistrationPolicy
+}
+
+// parseBirthdate parses a "YYYY-MM-DD" birthdate, rejecting dates in the
+// future or implausibly far in the past.
+func parseBirthdate(value string, now time.Time) (time.Time, error) {
+	birthdate, err := time.Parse(birthdateLayout, value)
+	if err != nil || birthdate.After(now) || ageAt(birthdate, now) > 150 {
+		return time.Time{}, ErrValidationError("birthdate")
+	}
+	return birthdate, nil
+}
+
+// ageAt returns the age in whole years of someone born on birthdate.
+func ageAt(birthdate, now time.Time) int {
+	age := now.Year() - birthdate.Year()
+	if now.Month() < birthdate.Month() || (now.Month() == birthdate.Month() && now.Day() < birthdate.Day()) {
+		age--
+	}
+	return age
+}
+
+// defaultRegistrationPolicy rejects under-age registrations.
+func defaultRegistrationPolicy(_ context.Context, check RegistrationCheck) (RegistrationDecision, error) {
+	if check.UnderAge {
+		return RegistrationReject, nil
+	}
+	return RegistrationAllow, nil
+}
+
+// checkAge runs the registration policy. It returns whether the user is to
+// be flagged for review.
+func (a *Auth) checkAge(ctx context.Context, payload RegisterRequest, birthdate time.Time) (bool, error) {
+	config := a.config.Age
+	if config.MinimumAge <= 0 {
+		return false, nil
+	}
+
+	check := RegistrationCheck{
+		Username:   payload.Username,
+		Email:      payload.Email,
+		Birthdate:  birthdate,
+		Age:        -1,
+		MinimumAge: config.MinimumAge,
+	}
+	if birthdate.IsZero() {
+		check.UnderAge = config.RequireBirthdate
+	} else {
+		check.Age = ageAt(birthdate, nowFrom(a.clock))
+		check.UnderAge = check.Age < config.MinimumAge
+	}
+
+	policy := config.Policy
+	if policy == nil {
+		policy = defaultRegistrationPolicy
+	}
+	decision, err := policy(ctx, check)
+	if err != nil {
+		return false, err
+	}
+	switch decision {
+	case RegistrationReject:
+		return false, ErrUnderAge()
+	case RegistrationFlag:
+		return true, nil
+	}
+	return false, nil
+}
+
+// UserBirthdate returns the birthdate recorded at registration.
+func UserBirthdate(user *models.User) (time.Time, bool) {
+	value, _ := user.Metadata[birthdateMetadataKey].(string)
+	birthdate, err := time.Parse(birthdateLayout, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return birthdate, true
+}
+
+// toProfile converts user to a UserProfile without private metadata such
+// as the birthdate.
+func toProfile(user *models.User) *models.UserProfile {
+	profile := user.ToUserProfile()
+	profile.DisplayName = user.DisplayName
+	profile.Locale = user.Locale
+	profile.Timezone = user.Timezone
+	profile.ExpiresAt = user.ExpiresAt
+	if _, ok := profile.Metadata[birthdateMetadataKey]; ok {
+		metadata := make(map[string]interface{}, len(profile.Metadata))
+		for k, v := range profile.Metadata {
+			if k != birthdateMetadataKey {
+				metadata[k] = v
+			}
+		}
+		profile.Metadata = metadata
+	}
+	return profile
+}
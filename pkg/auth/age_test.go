@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistrationMinimumAge(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.config.Age = AgeConfig{MinimumAge: 13}
+	now := ta.Clock.Now()
+
+	child := now.AddDate(-12, 0, 0).Format("2006-01-02")
+	_, err := ta.Register(RegisterRequest{Username: "kid", Password: "kid-password", Birthdate: child})
+	var authErr *AuthError
+	if !errors.As(err, &authErr) || authErr.Code != ErrCodeUnderAge {
+		t.Fatalf("Expected UNDER_AGE, got %v", err)
+	}
+
+	adult := now.AddDate(-30, 0, 0).Format("2006-01-02")
+	user, err := ta.Register(RegisterRequest{Username: "adult", Password: "adult-password", Birthdate: adult})
+	if err != nil {
+		t.Fatalf("Expected adult to register: %v", err)
+	}
+	if birthdate, ok := UserBirthdate(user); !ok || birthdate.Format("2006-01-02") != adult {
+		t.Errorf("Expected birthdate %s to be recorded, got %v", adult, birthdate)
+	}
+
+	// The birthdate is not part of the default profile
+	profile, err := ta.Users().Get(user.ID)
+	if err != nil {
+		t.Fatalf("Failed to get profile: %v", err)
+	}
+	if _, ok := profile.Metadata[birthdateMetadataKey]; ok {
+		t.Error("Expected birthdate to be excluded from the profile")
+	}
+
+	// Without RequireBirthdate, users may omit it
+	if _, err := ta.Register(RegisterRequest{Username: "anon", Password: "anon-password"}); err != nil {
+		t.Errorf("Expected registration without birthdate to succeed: %v", err)
+	}
+
+	future := now.AddDate(1, 0, 0).Format("2006-01-02")
+	if _, err := ta.Register(RegisterRequest{Username: "future", Password: "future-password", Birthdate: future}); err == nil {
+		t.Error("Expected birthdate in the future to be rejected")
+	}
+}
+
+func TestRegistrationPolicyFlag(t *testing.T) {
+	ta := NewTestAuth(t)
+	var checked RegistrationCheck
+	ta.config.Age = AgeConfig{
+		MinimumAge:       13,
+		RequireBirthdate: true,
+		Policy: func(ctx context.Context, check RegistrationCheck) (RegistrationDecision, error) {
+			checked = check
+			if check.UnderAge {
+				return RegistrationFlag, nil
+			}
+			return RegistrationAllow, nil
+		},
+	}
+
+	birthdate := ta.Clock.Now().AddDate(-10, 0, -1)
+	user, err := ta.Register(RegisterRequest{Username: "kid", Password: "kid-password", Birthdate: birthdate.Format("2006-01-02")})
+	if err != nil {
+		t.Fatalf("Expected flagged registration to succeed: %v", err)
+	}
+	if checked.Age != 10 || !checked.UnderAge {
+		t.Errorf("Expected age 10 and under age, got %+v", checked)
+	}
+	if flagged, _ := user.Metadata[ageReviewMetadataKey].(bool); !flagged {
+		t.Error("Expected user to be flagged for review")
+	}
+
+	if _, err := ta.Register(RegisterRequest{Username: "anon", Password: "anon-password"}); err != nil {
+		t.Fatalf("Expected registration without birthdate to be flagged, got %v", err)
+	}
+	if !checked.UnderAge || checked.Age != -1 {
+		t.Errorf("Expected missing birthdate to count as under age, got %+v", checked)
+	}
+}
+
+func TestAgeAt(t *testing.T) {
+	birthdate := time.Date(2000, time.March, 15, 0, 0, 0, 0, time.UTC)
+	cases := map[string]int{
+		"2010-03-14": 9,
+		"2010-03-15": 10,
+		"2010-12-01": 10,
+	}
+	for date, want := range cases {
+		now, _ := time.Parse("2006-01-02", date)
+		if got := ageAt(birthdate, now); got != want {
+			t.Errorf("ageAt(%s) = %d, want %d", date, got, want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+// AuthAPI, UsersAPI and TokensAPI are the stable public surface of v2,
+// implemented by *Auth, *Users and *Tokens. Depend on them instead of the
+// concrete types to mock the library in tests or to wrap it in decorators
+// such as instrumentation or authorization checks.
+//
+// Within v2 no method is removed from or changed in these interfaces.
+// Methods may be added in minor releases, so implementations outside this
+// package should embed the interface they implement to keep compiling.
+
+// AuthAPI is the stable surface of Auth.
+type AuthAPI interface {
+	Register(payload RegisterRequest) (*models.User, error)
+	RegisterContext(ctx context.Context, payload RegisterRequest) (*models.User, error)
+	Login(username, password string, customClaims map[string]interface{}) (*LoginResult, error)
+	LoginContext(ctx context.Context, username, password string, customClaims map[string]interface{}) (*LoginResult, error)
+	ValidateAccessToken(tokenString string) (jwt.MapClaims, error)
+	ValidateRefreshToken(tokenString string) (jwt.MapClaims, error)
+	RefreshToken(refreshToken string) (*RefreshResult, error)
+	GetUser(userID string) (*models.UserProfile, error)
+	GetUserByUsername(username string) (*models.UserProfile, error)
+	GetUserByEmail(email string) (*models.UserProfile, error)
+	Health() error
+	Close()
+}
+
+// UsersAPI is the stable surface of Users.
+type UsersAPI interface {
+	Get(userID string) (*models.UserProfile, error)
+	GetByEmail(email string) (*models.UserProfile, error)
+	GetByUsername(username string) (*models.UserProfile, error)
+	List(limit, offset int) ([]*models.UserProfile, error)
+	Update(userID string, updates UserUpdate) error
+	Delete(userID string) error
+	ChangePassword(userID, oldPassword, newPassword string) error
+	ChangePasswordContext(ctx context.Context, userID, oldPassword, newPassword string) error
+	CreateResetToken(email string) (*ResetToken, error)
+	ResetPassword(token, newPassword string) error
+	ResetPasswordContext(ctx context.Context, token, newPassword string) error
+}
+
+// TokensAPI is the stable surface of Tokens.
+type TokensAPI interface {
+	Issue(userID string, opts IssueOptions) (*LoginResult, error)
+	Refresh(refreshToken string) (*RefreshResult, error)
+	Validate(tokenString string) (*models.User, error)
+	IsValid(tokenString string) bool
+	ValidateBatch(tokens []string) []ValidationResult
+	Revoke(tokenString string) error
+	RevokeAll(userID string) error
+	GetSessionInfo(tokenString string) (*SessionInfo, error)
+	ListActiveSessions(userID string) ([]*SessionInfo, error)
+	CleanupExpired() error
+}
+
+var (
+	_ AuthAPI   = (*Auth)(nil)
+	_ UsersAPI  = (*Users)(nil)
+	_ TokensAPI = (*Tokens)(nil)
+)
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+// countingUsers decorates a UsersAPI, counting lookups by ID.
+type countingUsers struct {
+	UsersAPI
+	gets int
+}
+
+func (c *countingUsers) Get(userID string) (*models.UserProfile, error) {
+	c.gets++
+	return c.UsersAPI.Get(userID)
+}
+
+// stubTokens is a TokensAPI mock that rejects every token.
+type stubTokens struct {
+	TokensAPI
+}
+
+func (stubTokens) Validate(tokenString string) (*models.User, error) {
+	return nil, ErrInvalidToken()
+}
+
+func TestAPI_Decorator(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("alice", TestPassword)
+
+	users := &countingUsers{UsersAPI: ta.Users()}
+	var api UsersAPI = users
+	profile, err := api.Get(user.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if profile.Username != "alice" {
+		t.Errorf("Expected alice, got %s", profile.Username)
+	}
+	if users.gets != 1 {
+		t.Errorf("Expected 1 counted lookup, got %d", users.gets)
+	}
+}
+
+func TestAPI_Mock(t *testing.T) {
+	var tokens TokensAPI = stubTokens{}
+	if _, err := tokens.Validate("anything"); !errors.Is(err, ErrInvalidToken()) {
+		t.Errorf("Expected invalid token error from mock, got %v", err)
+	}
+
+	var api AuthAPI = NewTestAuth(t).Auth
+	if err := api.Health(); err != nil {
+		t.Errorf("Expected healthy Auth, got %v", err)
+	}
+}
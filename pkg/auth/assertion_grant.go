@@ -0,0 +1,332 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pragneshbagary/go-auth/internal/jwtutils"
+)
+
+// AssertionGrantType is the grant_type of the JWT bearer assertion grant
+// (RFC 7523).
+const AssertionGrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+// AssertionClient is a machine client that authenticates with JWT
+// assertions signed by its own keys instead of a shared secret, e.g. a CI
+// system. Access tokens are issued for the client's machine user.
+type AssertionClient struct {
+	// ID is the "iss" and "sub" of the client's assertions.
+	ID string `json:"id"`
+	// UserID is the machine user the client acts as.
+	UserID string `json:"user_id"`
+	// Keys are the client's public keys as JWKs. Assertions naming a "kid"
+	// are verified with the key of that ID, others with every key.
+	Keys      []map[string]interface{} `json:"keys"`
+	CreatedAt time.Time                `json:"created_at"`
+}
+
+// AssertionClientStore persists assertion clients.
+type AssertionClientStore interface {
+	SaveAssertionClient(client *AssertionClient) error
+	// GetAssertionClient returns the client with id or ErrInvalidToken if
+	// it does not exist.
+	GetAssertionClient(id string) (*AssertionClient, error)
+	DeleteAssertionClient(id string) error
+}
+
+// memoryAssertionClientStore is an in-memory AssertionClientStore.
+type memoryAssertionClientStore struct {
+	mu      sync.RWMutex
+	clients map[string]*AssertionClient
+}
+
+// NewMemoryAssertionClientStore creates an in-memory AssertionClientStore.
+// Clients are lost on restart; use a persistent store in production.
+func NewMemoryAssertionClientStore() AssertionClientStore {
+	return &memoryAssertionClientStore{clients: make(map[string]*AssertionClient)}
+}
+
+func (s *memoryAssertionClientStore) SaveAssertionClient(client *AssertionClient) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *client
+	s.clients[client.ID] = &stored
+	return nil
+}
+
+func (s *memoryAssertionClientStore) GetAssertionClient(id string) (*AssertionClient, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	client, ok := s.clients[id]
+	if !ok {
+		return nil, ErrInvalidToken()
+	}
+	result := *client
+	return &result, nil
+}
+
+func (s *memoryAssertionClientStore) DeleteAssertionClient(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.clients, id)
+	return nil
+}
+
+// AssertionGrantConfig configures the JWT bearer assertion grant.
+type AssertionGrantConfig struct {
+	// Store persists the registered clients. Defaults to an in-memory store.
+	Store AssertionClientStore
+	// Audience lists the accepted "aud" values of assertions, typically
+	// the token endpoint URL. Defaults to JWTIssuer.
+	Audience []string
+	// MaxLifetime bounds the lifetime of assertions, from iat to exp.
+	// Defaults to 5 minutes.
+	MaxLifetime time.Duration
+	// Algorithms lists the accepted signing algorithms. Defaults to ES256,
+	// ES384, ES512, RS256, PS256 and EdDSA.
+	Algorithms []string
+}
+
+// assertionGrants verifies JWT bearer assertions and tracks their
+// identifiers to prevent replay.
+type assertionGrants struct {
+	config AssertionGrantConfig
+	clock  Clock
+	leeway time.Duration
+
+	mu          sync.Mutex
+	seen        map[string]time.Time
+	lastCleanup time.Time
+}
+
+func newAssertionGrants(config AssertionGrantConfig, issuer string, clock Clock, leeway time.Duration) *assertionGrants {
+	if config.Store == nil {
+		config.Store = NewMemoryAssertionClientStore()
+	}
+	if len(config.Audience) == 0 {
+		config.Audience = []string{issuer}
+	}
+	if config.MaxLifetime <= 0 {
+		config.MaxLifetime = 5 * time.Minute
+	}
+	if len(config.Algorithms) == 0 {
+		config.Algorithms = []string{"ES256", "ES384", "ES512", "RS256", "PS256", "EdDSA"}
+	}
+	return &assertionGrants{config: config, clock: clock, leeway: leeway, seen: make(map[string]time.Time)}
+}
+
+// verify validates assertion and returns its client.
+func (g *assertionGrants) verify(assertion string) (*AssertionClient, error) {
+	unverified := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(assertion, unverified); err != nil {
+		return nil, ErrInvalidToken()
+	}
+	clientID, _ := unverified.GetIssuer()
+	client, err := g.config.Store.GetAssertionClient(clientID)
+	if err != nil {
+		return nil, ErrInvalidToken()
+	}
+
+	now := nowFrom(g.clock)
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(g.config.Algorithms),
+		jwt.WithIssuer(client.ID),
+		jwt.WithSubject(client.ID),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+		jwt.WithLeeway(g.leeway),
+		jwt.WithExpirationRequired(),
+	}
+	wantKid := unverifiedKid(assertion)
+	claims := jwt.MapClaims{}
+	var verified bool
+	for _, jwk := range client.Keys {
+		if kid, _ := jwk["kid"].(string); wantKid != "" && kid != "" && kid != wantKid {
+			continue
+		}
+		key, err := parsePublicJWK(jwk)
+		if err != nil {
+			continue
+		}
+		claims = jwt.MapClaims{}
+		token, err := jwt.ParseWithClaims(assertion, claims, func(*jwt.Token) (interface{}, error) {
+			return key, nil
+		}, options...)
+		if err == nil && token.Valid {
+			verified = true
+			break
+		}
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, NewAuthError(ErrCodeTokenExpired, "Assertion has expired")
+		}
+	}
+	if !verified || !audienceMatches(claims, g.config.Audience) {
+		return nil, ErrInvalidToken()
+	}
+
+	// Assertions are short-lived and single-use
+	expiresAt, _ := claims.GetExpirationTime()
+	issuedAt, _ := claims.GetIssuedAt()
+	if issuedAt != nil && expiresAt.Sub(issuedAt.Time) > g.config.MaxLifetime {
+		return nil, NewAuthErrorWithDetails(ErrCodeInvalidToken, "Invalid assertion", "The assertion lifetime is too long")
+	}
+	if issuedAt == nil && expiresAt.Sub(now) > g.config.MaxLifetime {
+		return nil, NewAuthErrorWithDetails(ErrCodeInvalidToken, "Invalid assertion", "The assertion lifetime is too long")
+	}
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return nil, NewAuthErrorWithDetails(ErrCodeInvalidToken, "Invalid assertion", "The assertion has no jti")
+	}
+	if !g.markSeen(client.ID+" "+jti, expiresAt.Add(g.leeway), now) {
+		return nil, NewAuthErrorWithDetails(ErrCodeInvalidToken, "Invalid assertion", "The assertion was already used")
+	}
+	return client, nil
+}
+
+// markSeen records an assertion identifier until expiresAt and reports
+// whether it was new.
+func (g *assertionGrants) markSeen(id string, expiresAt, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if now.Sub(g.lastCleanup) > time.Minute {
+		for seenID, until := range g.seen {
+			if now.After(until) {
+				delete(g.seen, seenID)
+			}
+		}
+		g.lastCleanup = now
+	}
+	if until, ok := g.seen[id]; ok && !now.After(until) {
+		return false
+	}
+	g.seen[id] = expiresAt
+	return true
+}
+
+// unverifiedKid returns the "kid" header of a JWT without verifying it.
+func unverifiedKid(tokenString string) string {
+	token, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		return ""
+	}
+	kid, _ := token.Header["kid"].(string)
+	return kid
+}
+
+// RegisterAssertionClient registers or replaces a machine client allowed
+// to exchange JWT assertions for access tokens of its machine user.
+func (t *Tokens) RegisterAssertionClient(client AssertionClient) error {
+	if client.ID == "" {
+		return ErrValidationError("client ID")
+	}
+	if len(client.Keys) == 0 {
+		return ErrValidationError("keys")
+	}
+	for _, jwk := range client.Keys {
+		if _, err := parsePublicJWK(jwk); err != nil {
+			return NewAuthErrorWithDetails(ErrCodeValidationError, "Invalid client key", err.Error())
+		}
+	}
+	user, err := t.storage.GetUserByID(client.UserID)
+	if err != nil {
+		return ErrUserNotFound()
+	}
+	if !user.IsActive {
+		return ErrUserInactive()
+	}
+	if client.CreatedAt.IsZero() {
+		client.CreatedAt = nowFrom(t.clock)
+	}
+	if err := t.assertions.config.Store.SaveAssertionClient(&client); err != nil {
+		return WrapDatabaseError(err)
+	}
+	return nil
+}
+
+// RemoveAssertionClient removes a machine client. Access tokens it already
+// obtained stay valid until they expire or are revoked.
+func (t *Tokens) RemoveAssertionClient(clientID string) error {
+	if err := t.assertions.config.Store.DeleteAssertionClient(clientID); err != nil {
+		return WrapDatabaseError(err)
+	}
+	return nil
+}
+
+// ExchangeAssertion exchanges a JWT bearer assertion (RFC 7523) for an
+// access token of the client's machine user. The assertion must be issued
+// and signed by a registered client, with the client ID as "iss" and
+// "sub", an accepted "aud", a short lifetime and a unique "jti". No
+// refresh token is issued; clients sign a new assertion instead. The
+// access token carries the client ID in a "client_id" claim.
+func (t *Tokens) ExchangeAssertion(ctx context.Context, assertion string) (*LoginResult, error) {
+	if assertion == "" {
+		return nil, ErrMissingToken()
+	}
+	if err := t.maintenance.check(maintenanceLogin); err != nil {
+		return nil, err
+	}
+	client, err := t.assertions.verify(assertion)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := t.storage.GetUserByID(client.UserID)
+	if err != nil {
+		return nil, ErrUserNotFound()
+	}
+	if !user.IsActive {
+		return nil, ErrUserInactive()
+	}
+	if err := checkAccountExpiry(user, t.clock); err != nil {
+		return nil, err
+	}
+
+	stored := t.claims.stored(user)
+	if err := t.subscriptions.apply(ctx, user, stored); err != nil {
+		return nil, err
+	}
+	claims := t.claims.merge(user.ID, stored, map[string]interface{}{"client_id": client.ID})
+	accessToken, err := t.jwtManager.GenerateAccessTokenWithOptions(user.ID, claims, jwtutils.IssueOptions{})
+	if err != nil {
+		return nil, WrapError(err, ErrCodeInternalError, "Failed to generate access token")
+	}
+	return &LoginResult{AccessToken: accessToken, TokenType: "Bearer"}, nil
+}
+
+// AssertionHandler returns an HTTP handler for a token endpoint accepting
+// the JWT bearer grant. It answers form-encoded POST requests carrying
+// grant_type and assertion with an AccessTokenResponse.
+func (a *Auth) AssertionHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		if r.PostFormValue("grant_type") != AssertionGrantType {
+			a.writeError(w, r, ErrValidationError("grant_type"))
+			return
+		}
+		result, err := a.Tokens().ExchangeAssertion(WithClientRequest(r.Context(), r), r.PostFormValue("assertion"))
+		if err != nil {
+			a.writeError(w, r, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(AccessTokenResponse{
+			AccessToken: result.AccessToken,
+			TokenType:   result.TokenType,
+			ExpiresIn:   int64(a.currentConfig().AccessTokenTTL / time.Second),
+		}); err != nil {
+			a.logger.Error("Failed to encode token response", map[string]interface{}{
+				"error": err,
+			})
+		}
+	})
+}
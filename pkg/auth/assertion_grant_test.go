@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestExchangeAssertion(t *testing.T) {
+	ta := NewTestAuth(t)
+	machine := ta.SeedUser("ci-bot", "ci-bot-password")
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	jwk := map[string]interface{}{
+		"kty": "EC",
+		"crv": "P-256",
+		"kid": "ci-1",
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	}
+	if err := ta.Tokens().RegisterAssertionClient(AssertionClient{ID: "ci", UserID: machine.ID, Keys: []map[string]interface{}{jwk}}); err != nil {
+		t.Fatalf("RegisterAssertionClient failed: %v", err)
+	}
+
+	sign := func(jti string, lifetime time.Duration, signer *ecdsa.PrivateKey) string {
+		now := ta.Clock.Now()
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+			"iss": "ci",
+			"sub": "ci",
+			"aud": "go-auth-test",
+			"iat": now.Unix(),
+			"exp": now.Add(lifetime).Unix(),
+			"jti": jti,
+		})
+		token.Header["kid"] = "ci-1"
+		signed, _ := token.SignedString(signer)
+		return signed
+	}
+
+	handler := ta.AssertionHandler()
+	exchange := func(assertion string) *httptest.ResponseRecorder {
+		form := url.Values{"grant_type": {AssertionGrantType}, "assertion": {assertion}}
+		r := httptest.NewRequest("POST", "/token", strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec
+	}
+
+	assertion := sign("a-1", time.Minute, key)
+	rec := exchange(assertion)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the assertion to be exchanged, got %d: %s", rec.Code, rec.Body)
+	}
+	var response AccessTokenResponse
+	json.NewDecoder(rec.Body).Decode(&response)
+	if user, err := ta.Tokens().Validate(response.AccessToken); err != nil || user.ID != machine.ID {
+		t.Errorf("Expected an access token of the machine user, got %+v, %v", user, err)
+	}
+
+	if rec := exchange(assertion); rec.Code == http.StatusOK {
+		t.Error("Expected a replayed assertion to be rejected")
+	}
+	if _, err := ta.Tokens().ExchangeAssertion(context.Background(), sign("a-2", time.Hour, key)); !isCode(err, ErrCodeInvalidToken) {
+		t.Errorf("Expected a long-lived assertion to be rejected, got %v", err)
+	}
+	other, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if _, err := ta.Tokens().ExchangeAssertion(context.Background(), sign("a-3", time.Minute, other)); !isCode(err, ErrCodeInvalidToken) {
+		t.Errorf("Expected an assertion signed by another key to be rejected, got %v", err)
+	}
+
+	if err := ta.Tokens().RemoveAssertionClient("ci"); err != nil {
+		t.Fatalf("RemoveAssertionClient failed: %v", err)
+	}
+	if _, err := ta.Tokens().ExchangeAssertion(context.Background(), sign("a-4", time.Minute, key)); err == nil {
+		t.Error("Expected assertions of a removed client to be rejected")
+	}
+}
@@ -0,0 +1,411 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// AuditConfig keeps a tamper-evident audit log of authentication events.
+// Each record is chained to the previous one by a SHA-256 hash, and the
+// head of the chain is signed with Ed25519 in periodic checkpoints, so that
+// auditors holding the public key can verify an export with
+// VerifyAuditLog or "authctl audit-verify" without trusting the database.
+type AuditConfig struct {
+	// Store persists the log. Setting it enables the audit log.
+	Store AuditStore
+	// SigningKey signs the checkpoints. Keep it apart from the database.
+	SigningKey ed25519.PrivateKey
+	// Events lists the event types to record; all when empty.
+	Events []string
+	// CheckpointEvery signs a checkpoint after this many records. Defaults
+	// to 1,000.
+	CheckpointEvery int
+	// CheckpointInterval signs a checkpoint at the first record after this
+	// time since the last one. Defaults to 1 hour.
+	CheckpointInterval time.Duration
+}
+
+// AuditRecord is an event in the audit log.
+type AuditRecord struct {
+	// Seq numbers the records from 1 without gaps.
+	Seq int64 `json:"seq"`
+	// Event is the JSON encoding of the AuthEvent, hashed as is.
+	Event json.RawMessage `json:"event"`
+	// PrevHash is the Hash of the previous record, empty for the first.
+	PrevHash string `json:"prev_hash"`
+	// Hash is the hex SHA-256 of "<prev_hash>\n<seq>\n<event>".
+	Hash string `json:"hash"`
+}
+
+// AuditCheckpoint is a signature over the head of the audit log.
+type AuditCheckpoint struct {
+	// Seq and Hash are those of the newest record covered.
+	Seq  int64     `json:"seq"`
+	Hash string    `json:"hash"`
+	Time time.Time `json:"time"`
+	// Signature is the base64 Ed25519 signature of the checkpoint's
+	// signing payload.
+	Signature string `json:"signature"`
+}
+
+// AuditStore persists the audit log. Records are only ever appended.
+type AuditStore interface {
+	AppendAuditRecord(record *AuditRecord) error
+	// LastAuditRecord returns the newest record, nil if the log is empty.
+	LastAuditRecord() (*AuditRecord, error)
+	// ListAuditRecords returns up to limit records with a Seq above after,
+	// oldest first.
+	ListAuditRecords(after int64, limit int) ([]*AuditRecord, error)
+	SaveAuditCheckpoint(checkpoint *AuditCheckpoint) error
+	// ListAuditCheckpoints returns all checkpoints, oldest first.
+	ListAuditCheckpoints() ([]*AuditCheckpoint, error)
+}
+
+// memoryAuditStore is an in-memory AuditStore.
+type memoryAuditStore struct {
+	mu          sync.RWMutex
+	records     []*AuditRecord
+	checkpoints []*AuditCheckpoint
+}
+
+// NewMemoryAuditStore creates an in-memory AuditStore. The log is lost on
+// restart; use a persistent AuditStore in production.
+func NewMemoryAuditStore() AuditStore {
+	return &memoryAuditStore{}
+}
+
+func (s *memoryAuditStore) AppendAuditRecord(record *AuditRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *record
+	s.records = append(s.records, &stored)
+	return nil
+}
+
+func (s *memoryAuditStore) LastAuditRecord() (*AuditRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.records) == 0 {
+		return nil, nil
+	}
+	last := *s.records[len(s.records)-1]
+	return &last, nil
+}
+
+func (s *memoryAuditStore) ListAuditRecords(after int64, limit int) ([]*AuditRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	start := sort.Search(len(s.records), func(i int) bool { return s.records[i].Seq > after })
+	var records []*AuditRecord
+	for _, record := range s.records[start:] {
+		if len(records) == limit {
+			break
+		}
+		copied := *record
+		records = append(records, &copied)
+	}
+	return records, nil
+}
+
+func (s *memoryAuditStore) SaveAuditCheckpoint(checkpoint *AuditCheckpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *checkpoint
+	s.checkpoints = append(s.checkpoints, &stored)
+	return nil
+}
+
+func (s *memoryAuditStore) ListAuditCheckpoints() ([]*AuditCheckpoint, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	checkpoints := make([]*AuditCheckpoint, len(s.checkpoints))
+	for i, checkpoint := range s.checkpoints {
+		copied := *checkpoint
+		checkpoints[i] = &copied
+	}
+	return checkpoints, nil
+}
+
+// auditRecordHash returns the hash of a record chained to prevHash.
+func auditRecordHash(prevHash string, seq int64, event []byte) string {
+	h := sha256.New()
+	h.Write([]byte(prevHash + "\n" + strconv.FormatInt(seq, 10) + "\n"))
+	h.Write(event)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// signingPayload returns the bytes the checkpoint signature covers.
+func (c *AuditCheckpoint) signingPayload() []byte {
+	return []byte("go-auth-audit-checkpoint\n" + strconv.FormatInt(c.Seq, 10) + "\n" + c.Hash + "\n" + c.Time.UTC().Format(time.RFC3339Nano))
+}
+
+// AuditLog appends authentication events to the audit log, see AuditConfig.
+type AuditLog struct {
+	config AuditConfig
+	clock  Clock
+
+	mu              sync.Mutex
+	loaded          bool
+	last            *AuditRecord
+	sinceCheckpoint int
+	checkpointAt    time.Time
+}
+
+// newAuditLog validates config. It returns nil without a store.
+func newAuditLog(config AuditConfig, clock Clock) (*AuditLog, error) {
+	if config.Store == nil {
+		return nil, nil
+	}
+	if len(config.SigningKey) != ed25519.PrivateKeySize {
+		return nil, ErrConfigError("Audit.SigningKey")
+	}
+	if config.CheckpointEvery <= 0 {
+		config.CheckpointEvery = 1000
+	}
+	if config.CheckpointInterval <= 0 {
+		config.CheckpointInterval = time.Hour
+	}
+	return &AuditLog{config: config, clock: clock}, nil
+}
+
+// Audit returns the audit log, or nil if AuthConfig.Audit has no store.
+func (a *Auth) Audit() *AuditLog {
+	return a.audit
+}
+
+// PublicKey returns the key that verifies the checkpoints.
+func (l *AuditLog) PublicKey() ed25519.PublicKey {
+	return l.config.SigningKey.Public().(ed25519.PublicKey)
+}
+
+// load continues the chain of a log written before a restart.
+func (l *AuditLog) load() error {
+	if l.loaded {
+		return nil
+	}
+	last, err := l.config.Store.LastAuditRecord()
+	if err != nil {
+		return err
+	}
+	checkpoints, err := l.config.Store.ListAuditCheckpoints()
+	if err != nil {
+		return err
+	}
+	l.last = last
+	l.checkpointAt = nowFrom(l.clock)
+	if len(checkpoints) > 0 {
+		latest := checkpoints[len(checkpoints)-1]
+		l.checkpointAt = latest.Time
+		if last != nil {
+			l.sinceCheckpoint = int(last.Seq - latest.Seq)
+		}
+	} else if last != nil {
+		l.sinceCheckpoint = int(last.Seq)
+	}
+	l.loaded = true
+	return nil
+}
+
+// append adds event to the chain and signs a checkpoint when one is due.
+func (l *AuditLog) append(event AuthEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit event: %w", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.load(); err != nil {
+		return err
+	}
+	record := &AuditRecord{Seq: 1, Event: data}
+	if l.last != nil {
+		record.Seq = l.last.Seq + 1
+		record.PrevHash = l.last.Hash
+	}
+	record.Hash = auditRecordHash(record.PrevHash, record.Seq, record.Event)
+	if err := l.config.Store.AppendAuditRecord(record); err != nil {
+		return err
+	}
+	l.last = record
+	l.sinceCheckpoint++
+
+	if l.sinceCheckpoint >= l.config.CheckpointEvery || nowFrom(l.clock).Sub(l.checkpointAt) >= l.config.CheckpointInterval {
+		// The record is kept; a failed checkpoint is retried with the next one
+		_, err := l.checkpoint()
+		return err
+	}
+	return nil
+}
+
+// Checkpoint signs the current head of the log, e.g. before an export. It
+// returns nil if the log is empty.
+func (l *AuditLog) Checkpoint() (*AuditCheckpoint, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.load(); err != nil {
+		return nil, WrapDatabaseError(err)
+	}
+	checkpoint, err := l.checkpoint()
+	if err != nil {
+		return nil, WrapDatabaseError(err)
+	}
+	return checkpoint, nil
+}
+
+// checkpoint signs the head of the log. The caller holds l.mu.
+func (l *AuditLog) checkpoint() (*AuditCheckpoint, error) {
+	if l.last == nil {
+		return nil, nil
+	}
+	checkpoint := &AuditCheckpoint{
+		Seq:  l.last.Seq,
+		Hash: l.last.Hash,
+		Time: nowFrom(l.clock).UTC(),
+	}
+	signature := ed25519.Sign(l.config.SigningKey, checkpoint.signingPayload())
+	checkpoint.Signature = base64.StdEncoding.EncodeToString(signature)
+	if err := l.config.Store.SaveAuditCheckpoint(checkpoint); err != nil {
+		return nil, err
+	}
+	l.sinceCheckpoint = 0
+	l.checkpointAt = checkpoint.Time
+	return checkpoint, nil
+}
+
+// auditExportEntry is a line of an audit log export.
+type auditExportEntry struct {
+	Record     *AuditRecord     `json:"record,omitempty"`
+	Checkpoint *AuditCheckpoint `json:"checkpoint,omitempty"`
+}
+
+// Export writes the whole log to w as JSON lines, each holding a "record"
+// or a "checkpoint" following the record it covers. Call Checkpoint first
+// to sign the newest records.
+func (l *AuditLog) Export(w io.Writer) error {
+	checkpoints, err := l.config.Store.ListAuditCheckpoints()
+	if err != nil {
+		return WrapDatabaseError(err)
+	}
+	encoder := json.NewEncoder(w)
+	const pageSize = 1000
+	var after int64
+	for {
+		records, err := l.config.Store.ListAuditRecords(after, pageSize)
+		if err != nil {
+			return WrapDatabaseError(err)
+		}
+		for _, record := range records {
+			if err := encoder.Encode(auditExportEntry{Record: record}); err != nil {
+				return err
+			}
+			for len(checkpoints) > 0 && checkpoints[0].Seq <= record.Seq {
+				if err := encoder.Encode(auditExportEntry{Checkpoint: checkpoints[0]}); err != nil {
+					return err
+				}
+				checkpoints = checkpoints[1:]
+			}
+			after = record.Seq
+		}
+		if len(records) < pageSize {
+			return nil
+		}
+	}
+}
+
+// auditSink records events in the audit log.
+type auditSink struct {
+	log *AuditLog
+}
+
+func (s auditSink) Publish(ctx context.Context, event AuthEvent) error {
+	return s.log.append(event)
+}
+
+// AuditVerification summarizes a verified audit log export.
+type AuditVerification struct {
+	Records     int64 `json:"records"`
+	Checkpoints int   `json:"checkpoints"`
+	// SignedThrough is the Seq of the newest record covered by a valid
+	// checkpoint. Records after it are chained but not yet signed, so
+	// their removal could not be detected.
+	SignedThrough int64 `json:"signed_through"`
+}
+
+// Unsigned returns the number of records after the last checkpoint.
+func (v *AuditVerification) Unsigned() int64 {
+	return v.Records - v.SignedThrough
+}
+
+// VerifyAuditLog checks an export written by AuditLog.Export: that records
+// are numbered without gaps, each hash matches its record and chains to
+// the previous one, and every checkpoint is signed by publicKey and matches
+// the record it covers. It returns the first problem found as an error,
+// with the summary of the part verified until then.
+func VerifyAuditLog(r io.Reader, publicKey ed25519.PublicKey) (*AuditVerification, error) {
+	if len(publicKey) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid Ed25519 public key")
+	}
+	verification := &AuditVerification{}
+	hashes := make(map[int64]string)
+	var prevHash string
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry auditExportEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return verification, fmt.Errorf("line %d: %w", line, err)
+		}
+		switch {
+		case entry.Record != nil:
+			record := entry.Record
+			if record.Seq != verification.Records+1 {
+				return verification, fmt.Errorf("line %d: expected record %d, found %d", line, verification.Records+1, record.Seq)
+			}
+			if record.PrevHash != prevHash {
+				return verification, fmt.Errorf("record %d: does not chain to the previous record", record.Seq)
+			}
+			if auditRecordHash(record.PrevHash, record.Seq, record.Event) != record.Hash {
+				return verification, fmt.Errorf("record %d: hash does not match its contents", record.Seq)
+			}
+			prevHash = record.Hash
+			hashes[record.Seq] = record.Hash
+			verification.Records++
+		case entry.Checkpoint != nil:
+			checkpoint := entry.Checkpoint
+			if hash, ok := hashes[checkpoint.Seq]; !ok || hash != checkpoint.Hash {
+				return verification, fmt.Errorf("line %d: checkpoint of record %d does not match the log", line, checkpoint.Seq)
+			}
+			signature, err := base64.StdEncoding.DecodeString(checkpoint.Signature)
+			if err != nil || !ed25519.Verify(publicKey, checkpoint.signingPayload(), signature) {
+				return verification, fmt.Errorf("line %d: invalid signature of checkpoint %d", line, checkpoint.Seq)
+			}
+			verification.Checkpoints++
+			if checkpoint.Seq > verification.SignedThrough {
+				verification.SignedThrough = checkpoint.Seq
+			}
+		default:
+			return verification, fmt.Errorf("line %d: neither a record nor a checkpoint", line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return verification, err
+	}
+	return verification, nil
+}
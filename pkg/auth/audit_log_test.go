@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestAuditLog returns an audit log checkpointing every three records.
+func newTestAuditLog(t *testing.T, clock Clock) (*AuditLog, AuditStore) {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	store := NewMemoryAuditStore()
+	log, err := newAuditLog(AuditConfig{Store: store, SigningKey: key, CheckpointEvery: 3}, clock)
+	if err != nil {
+		t.Fatalf("Failed to create audit log: %v", err)
+	}
+	return log, store
+}
+
+func TestAuditLogExportVerifies(t *testing.T) {
+	clock := NewFrozenClock(time.Now())
+	log, store := newTestAuditLog(t, clock)
+	for i := 0; i < 5; i++ {
+		if err := log.append(newAuthEvent(LogLevelInfo, "User logged in", map[string]interface{}{
+			"event":   "user_login",
+			"user_id": "user-1",
+		})); err != nil {
+			t.Fatalf("append failed: %v", err)
+		}
+	}
+	if checkpoints, _ := store.ListAuditCheckpoints(); len(checkpoints) != 1 || checkpoints[0].Seq != 3 {
+		t.Fatalf("Expected a checkpoint after three records, got %+v", checkpoints)
+	}
+
+	var export bytes.Buffer
+	if err := log.Export(&export); err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	result, err := VerifyAuditLog(bytes.NewReader(export.Bytes()), log.PublicKey())
+	if err != nil {
+		t.Fatalf("Expected the export to verify: %v", err)
+	}
+	if result.Records != 5 || result.SignedThrough != 3 || result.Unsigned() != 2 {
+		t.Errorf("Unexpected verification %+v", result)
+	}
+
+	// Signing the head covers the remaining records
+	if _, err := log.Checkpoint(); err != nil {
+		t.Fatalf("Checkpoint failed: %v", err)
+	}
+	export.Reset()
+	log.Export(&export)
+	if result, err := VerifyAuditLog(&export, log.PublicKey()); err != nil || result.Unsigned() != 0 {
+		t.Errorf("Expected all records to be signed, got %+v, %v", result, err)
+	}
+}
+
+func TestAuditLogDetectsTampering(t *testing.T) {
+	log, store := newTestAuditLog(t, nil)
+	for _, user := range []string{"alice", "bob", "carol"} {
+		log.append(newAuthEvent(LogLevelInfo, "User logged in", map[string]interface{}{"event": "user_login", "username": user}))
+	}
+	var export bytes.Buffer
+	log.Export(&export)
+	original := export.String()
+
+	// Edited event
+	edited := strings.Replace(original, "bob", "eve", 1)
+	if _, err := VerifyAuditLog(strings.NewReader(edited), log.PublicKey()); err == nil {
+		t.Error("Expected an edited record to be detected")
+	}
+
+	// Removed record
+	lines := strings.SplitAfter(original, "\n")
+	removed := lines[0] + strings.Join(lines[2:], "")
+	if _, err := VerifyAuditLog(strings.NewReader(removed), log.PublicKey()); err == nil {
+		t.Error("Expected a removed record to be detected")
+	}
+
+	// Rewritten chain without the signing key
+	records, _ := store.ListAuditRecords(0, 10)
+	forged := NewMemoryAuditStore()
+	for _, record := range records {
+		record.Event = []byte(strings.Replace(string(record.Event), "bob", "eve", 1))
+		if record.Seq > 1 {
+			record.PrevHash = records[record.Seq-2].Hash
+		}
+		record.Hash = auditRecordHash(record.PrevHash, record.Seq, record.Event)
+		forged.AppendAuditRecord(record)
+	}
+	checkpoints, _ := store.ListAuditCheckpoints()
+	forged.SaveAuditCheckpoint(checkpoints[0])
+	export.Reset()
+	(&AuditLog{config: AuditConfig{Store: forged}}).Export(&export)
+	if _, err := VerifyAuditLog(&export, log.PublicKey()); err == nil {
+		t.Error("Expected a rewritten chain to fail the checkpoint")
+	}
+
+	if _, err := newAuditLog(AuditConfig{Store: store}, nil); err == nil {
+		t.Error("Expected a signing key to be required")
+	}
+}
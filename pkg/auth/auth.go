@@ -1,11 +1,14 @@
 package auth
 
 import (
+	"context"
+	"database/sql"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/pragneshbagary/go-auth/internal/jwtutils"
+	"github.com/pragneshbagary/go-auth/internal/storage/libsql"
 	"github.com/pragneshbagary/go-auth/internal/storage/memory"
 	"github.com/pragneshbagary/go-auth/internal/storage/postgres"
 	"github.com/pragneshbagary/go-auth/internal/storage/sqlite"
@@ -24,6 +27,48 @@ type Auth struct {
 	eventLogger      *AuthEventLogger
 	metricsCollector *MetricsCollector
 	monitor          *Monitor
+	translator       *translatorSwitch
+	clock            Clock
+	hasher           Hasher
+	hooks            *Hooks
+	maintenance      *maintenanceSwitch
+	dpop             *DPoP
+	mtls             *mtlsBinding
+	actionKey        []byte
+	captcha          *captchaGuard
+	risk             *riskEngine
+	grants           GrantStore
+	assertions       *assertionGrants
+	delegationKey    []byte
+	csrfKey          []byte
+	permissions      *PermissionCache
+	refreshLimiter   *refreshLimiter
+	trustedIssuers   *trustedIssuers
+	loginCache       *loginUserCache
+	epoch            *epochTokenManager
+	consent          ConsentConfig
+	usernames        *usernamePolicy
+	emails           *emailRenderer
+	pending          *pendingTokens
+	sessions         *sessionTracker
+	revocations      *revocationBroadcaster
+	watcher          *RevocationWatcher
+	deadLetters      DeadLetterStore
+	webhooks         map[string]*webhookSink
+	claims           *claimPolicy
+	subscriptions    *subscriptionPolicy
+	flags            *featureFlags
+	stopExpiryJob    func()
+	jobs             *jobTracker
+	validations      *validationCaches
+	settings         *runtimeSettings
+	dual             *compatibilityIssuer
+	sms              *smsResets
+	adminJobs        *AdminJobs
+	audit            *AuditLog
+	notifier         *securityNotifier
+	stopDigestJob    func()
+	quotas           *registrationQuotas
 }
 
 // AuthConfig holds the configuration for the Auth service.
@@ -31,6 +76,22 @@ type AuthConfig struct {
 	// Database configuration
 	DatabasePath string // For SQLite
 	DatabaseURL  string // For PostgreSQL
+	// SQLiteDriver is the database/sql driver DatabasePath is opened with,
+	// e.g. "sqlite" for the pure-Go modernc.org/sqlite. Defaults to
+	// "sqlite3", which needs cgo.
+	SQLiteDriver string
+	// SQLiteDB is an open SQLite database used instead of DatabasePath.
+	SQLiteDB *sql.DB
+	// Tenant restricts the instance to the users of one tenant with
+	// row-level security in the database, set up on startup. It requires
+	// PostgreSQL; run one Auth per tenant on the same database.
+	Tenant string
+	// Uniqueness is the scope within which usernames and non-empty emails
+	// are unique, enforced with unique indexes set up on startup:
+	// UniqueGlobal, UniquePerTenant, which requires Tenant, or UniquePerApp,
+	// which isolates the users of each AppName like a tenant. Empty leaves
+	// the indexes unchanged.
+	Uniqueness string
 	
 	// JWT configuration
 	JWTSecret       string
@@ -38,6 +99,19 @@ type AuthConfig struct {
 	JWTIssuer       string
 	AccessTokenTTL  time.Duration
 	RefreshTokenTTL time.Duration
+
+	// Issuer and audience validation. Tokens are issued with JWTAudience and
+	// only accepted if their issuer is in ExpectedIssuers (defaults to
+	// JWTIssuer) and their audience is in ExpectedAudiences (defaults to
+	// JWTAudience). Use distinct values per environment so that tokens minted
+	// by staging never validate in production, even with shared secrets.
+	JWTAudience       []string
+	ExpectedIssuers   []string
+	ExpectedAudiences []string
+
+	// TokenLeeway tolerates clock skew between instances when checking the
+	// exp, nbf and iat claims, e.g. 5 seconds. Defaults to none.
+	TokenLeeway time.Duration
 	
 	// Application configuration
 	AppName string
@@ -45,6 +119,161 @@ type AuthConfig struct {
 	
 	// Logging configuration
 	LogLevel string
+
+	// Testing and tuning
+	Clock              Clock         // Time source; defaults to the system clock
+	PasswordHashParams *Argon2Params // Argon2id parameters; defaults to DefaultParams
+
+	// PasswordHasher hashes new passwords. Defaults to Argon2id with PasswordHashParams.
+	// Existing hashes from other built-in hashers are still accepted on login and
+	// upgraded to this hasher transparently.
+	PasswordHasher Hasher
+
+	// DPoP configures proof-of-possession token binding (RFC 9449).
+	DPoP DPoPConfig
+
+	// MTLS configures certificate-bound tokens (RFC 8705).
+	MTLS MTLSConfig
+
+	// TokenFormat selects the format of issued tokens: TokenFormatJWT
+	// (default) or TokenFormatPASETO for PASETO v4.local tokens, which are
+	// encrypted and authenticated with keys derived from the JWT secrets.
+	TokenFormat string
+
+	// TokenEncryption enables JWE encryption of access tokens when set.
+	TokenEncryption *TokenEncryptionConfig
+
+	// Captcha requires a CAPTCHA after repeated failed logins when a provider is set.
+	Captcha CaptchaConfig
+
+	// Risk scores logins with valid credentials when a scorer is set.
+	Risk RiskConfig
+
+	// GrantStore persists delegation grants. Defaults to an in-memory store.
+	GrantStore GrantStore
+
+	// Assertions configures the JWT bearer assertion grant of machine
+	// clients, see Tokens.ExchangeAssertion.
+	Assertions AssertionGrantConfig
+
+	// Permissions configures the permission cache returned by Auth.Permissions.
+	Permissions PermissionCacheConfig
+
+	// RefreshLimit sets a minimum interval between token rotations.
+	RefreshLimit RefreshLimitConfig
+
+	// TrustedIssuers are additional issuers whose access tokens
+	// Tokens().Validate accepts, e.g. a legacy service during a migration.
+	TrustedIssuers []TrustedIssuer
+
+	// DualIssuance issues compatibility tokens for a legacy service
+	// alongside access tokens and accepts them during a migration.
+	DualIssuance DualIssuanceConfig
+
+	// Shadow mirrors user data to a secondary storage during a migration.
+	Shadow ShadowStorageConfig
+
+	// LoginCache caches Login's username lookups for a short time.
+	LoginCache LoginCacheConfig
+
+	// TokenEpoch configures the epoch used by InvalidateAllTokens.
+	TokenEpoch TokenEpochConfig
+
+	// Consent lists the document versions users must accept to log in.
+	Consent ConsentConfig
+
+	// Age gates registrations by birthdate.
+	Age AgeConfig
+
+	// Avatars configures where profile pictures are stored.
+	Avatars AvatarConfig
+
+	// ProfileVisibility masks profile fields for other callers, see GetAs.
+	ProfileVisibility ProfileVisibility
+
+	// PasswordExpiry forces password changes after a maximum age.
+	PasswordExpiry PasswordExpiryConfig
+
+	// Subscriptions adds billing state claims to issued tokens.
+	Subscriptions SubscriptionConfig
+
+	// FeatureFlags sets the rollout of gradually enabled behaviors, see
+	// FlagPasswordRehash.
+	FeatureFlags FeatureFlagsConfig
+
+	// AccountExpiry schedules the deactivation of accounts past their
+	// ExpiresAt, see Users.ExtendExpiry.
+	AccountExpiry AccountExpiryConfig
+
+	// Usernames configures the history and reservation of changed usernames.
+	Usernames UsernameConfig
+
+	// Email configures the links and templates of account emails, see Auth.Emails.
+	Email EmailConfig
+
+	// SecurityNotifications emails users about new devices and password
+	// changes, see Users.SetNotificationPrefs.
+	SecurityNotifications SecurityNotificationConfig
+
+	// RegistrationQuotas limits registrations per tenant.
+	RegistrationQuotas RegistrationQuotaConfig
+
+	// SMSReset configures password resets by text message, see
+	// Users.CreateResetTokenContext.
+	SMSReset SMSResetConfig
+
+	// Elevation configures re-authentication for sudo mode, see Auth.Elevate.
+	Elevation ElevationConfig
+
+	// Sessions tracks login sessions and ends them after a period of inactivity.
+	Sessions SessionConfig
+
+	// Revocation broadcasts revocations to the other instances of a service.
+	Revocation RevocationConfig
+
+	// EventSinks deliver authentication events to external systems.
+	EventSinks []EventSinkConfig
+
+	// Webhooks deliver signed CloudEvents to HTTP endpoints.
+	Webhooks WebhookConfig
+
+	// Audit keeps a hash-chained audit log of events with signed
+	// checkpoints.
+	Audit AuditConfig
+
+	// Pseudonymization replaces user identifiers in logs, events and
+	// webhooks with keyed pseudonyms.
+	Pseudonymization PseudonymizationConfig
+
+	// Probes configures the dependencies and failure thresholds of the
+	// Kubernetes probe handlers, see Monitor.ReadinessHandler.
+	Probes ProbeConfig
+
+	// StorageCircuitBreaker fails storage calls fast while the database keeps
+	// failing. Off when FailureThreshold is zero.
+	StorageCircuitBreaker CircuitBreakerConfig
+
+	// StorageInstrumentation records per-method storage latency and errors
+	// and logs slow queries.
+	StorageInstrumentation StorageInstrumentationConfig
+
+	// MetricsTenantLimit bounds the tenants tracked separately in
+	// Metrics.Tenants. Defaults to DefaultTenantLimit.
+	MetricsTenantLimit int
+
+	// Claims protects claims from being set by callers of Login.
+	Claims ClaimsConfig
+
+	// TokenSize limits the encoded size of access tokens.
+	TokenSize TokenSizeConfig
+
+	// SigningKeys sets the secrets of action, delegated and CSRF tokens and
+	// the audience of refresh tokens.
+	SigningKeys SigningKeysConfig
+
+	// GeoIP enriches login events with client locations and reports
+	// anomalous logins.
+	GeoIP GeoIPConfig
 }
 
 // New creates a new Auth instance with SQLite storage using the provided database path and JWT secret.
@@ -75,6 +304,80 @@ func NewSQLite(databasePath string, jwtSecret string) (*Auth, error) {
 	return newAuthWithStorage(storage, config)
 }
 
+// NewSQLiteDB creates a new Auth instance with SQLite storage on a database
+// the caller opened, e.g. with the pure-Go modernc.org/sqlite driver for
+// builds without cgo:
+//
+//	db, err := sql.Open("sqlite", "auth.db")
+//	a, err := auth.NewSQLiteDB(db, secret)
+func NewSQLiteDB(db *sql.DB, jwtSecret string) (*Auth, error) {
+	storage, err := sqlite.NewSQLiteStorageWithDB(db)
+	if err != nil {
+		return nil, WrapDatabaseError(err)
+	}
+
+	config := &AuthConfig{
+		SQLiteDB:         db,
+		JWTSecret:        jwtSecret,
+		JWTRefreshSecret: jwtSecret + "_refresh", // Default refresh secret
+		JWTIssuer:        "go-auth",
+		AccessTokenTTL:   15 * time.Minute,
+		RefreshTokenTTL:  7 * 24 * time.Hour, // 7 days
+		AppName:          "go-auth-app",
+		Version:          "2.0.0",
+		LogLevel:         "info",
+	}
+
+	return newAuthWithStorage(storage, config)
+}
+
+// NewLibSQL creates a new Auth instance with storage on a remote libSQL
+// database, such as one hosted by Turso, e.g.
+// NewLibSQL("libsql://my-db-my-org.turso.io", token, secret). It uses the
+// SQLite schema over HTTP and needs neither cgo nor a local file.
+func NewLibSQL(databaseURL, authToken, jwtSecret string) (*Auth, error) {
+	storage, err := libsql.NewLibSQLStorage(databaseURL, authToken)
+	if err != nil {
+		return nil, WrapDatabaseError(err)
+	}
+
+	config := &AuthConfig{
+		JWTSecret:        jwtSecret,
+		JWTRefreshSecret: jwtSecret + "_refresh", // Default refresh secret
+		JWTIssuer:        "go-auth",
+		AccessTokenTTL:   15 * time.Minute,
+		RefreshTokenTTL:  7 * 24 * time.Hour, // 7 days
+		AppName:          "go-auth-app",
+		Version:          "2.0.0",
+		LogLevel:         "info",
+	}
+
+	return newAuthWithStorage(storage, config)
+}
+
+// NewSQLiteInMemory creates a new Auth instance with SQLite storage in a
+// private in-memory database. Tests use it to run the real SQL code paths
+// without creating database files; the data is lost when the process exits.
+func NewSQLiteInMemory(jwtSecret string) (*Auth, error) {
+	storage, err := sqlite.NewInMemorySQLiteStorage("")
+	if err != nil {
+		return nil, WrapDatabaseError(err)
+	}
+
+	config := &AuthConfig{
+		JWTSecret:        jwtSecret,
+		JWTRefreshSecret: jwtSecret + "_refresh", // Default refresh secret
+		JWTIssuer:        "go-auth",
+		AccessTokenTTL:   15 * time.Minute,
+		RefreshTokenTTL:  7 * 24 * time.Hour, // 7 days
+		AppName:          "go-auth-app",
+		Version:          "2.0.0",
+		LogLevel:         "info",
+	}
+
+	return newAuthWithStorage(storage, config)
+}
+
 // NewPostgres creates a new Auth instance with PostgreSQL storage.
 func NewPostgres(connectionString string, jwtSecret string) (*Auth, error) {
 	storage, err := postgres.NewPostgresStorage(connectionString)
@@ -133,9 +436,19 @@ func NewWithConfig(config *AuthConfig) (*Auth, error) {
 		if err != nil {
 			return nil, WrapDatabaseError(err)
 		}
+	} else if config.SQLiteDB != nil {
+		// SQLite opened by the caller
+		storageImpl, err = sqlite.NewSQLiteStorageWithDB(config.SQLiteDB)
+		if err != nil {
+			return nil, WrapDatabaseError(err)
+		}
 	} else if config.DatabasePath != "" {
 		// SQLite
-		storageImpl, err = sqlite.NewSQLiteStorage(config.DatabasePath)
+		driver := config.SQLiteDriver
+		if driver == "" {
+			driver = sqlite.DefaultDriver
+		}
+		storageImpl, err = sqlite.NewSQLiteStorageWithDriver(driver, config.DatabasePath)
 		if err != nil {
 			return nil, WrapDatabaseError(err)
 		}
@@ -144,6 +457,19 @@ func NewWithConfig(config *AuthConfig) (*Auth, error) {
 		storageImpl = memory.NewInMemoryStorage()
 	}
 
+	tenant := config.Tenant
+	if config.Uniqueness != "" {
+		if tenant, err = scopeUniqueness(storageImpl, config); err != nil {
+			return nil, err
+		}
+	}
+	if tenant != "" {
+		storageImpl, err = isolateTenant(storageImpl, tenant)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return newAuthWithStorage(storageImpl, config)
 }
 
@@ -176,26 +502,196 @@ func newAuthWithStorage(storageImpl storage.EnhancedStorage, config *AuthConfig)
 	if config.LogLevel == "" {
 		config.LogLevel = "info"
 	}
+	if config.Clock == nil {
+		config.Clock = SystemClock()
+	}
+	if config.PasswordHasher == nil {
+		config.PasswordHasher = NewArgon2Hasher(config.PasswordHashParams)
+	}
+	if config.GrantStore == nil {
+		config.GrantStore = NewMemoryGrantStore()
+	}
+	if config.Consent.Store == nil {
+		config.Consent.Store = NewMemoryConsentStore()
+	}
 
 	// Create JWT manager
-	jwtManager := jwtutils.NewJWTManager(jwtutils.JWTConfig{
+	var newTokenManager func(jwtutils.JWTConfig) jwtutils.TokenManager
+	switch config.TokenFormat {
+	case "", TokenFormatJWT:
+		newTokenManager = jwtutils.NewJWTManager
+	case TokenFormatPASETO:
+		if config.TokenEncryption != nil {
+			return nil, NewAuthError(ErrCodeInvalidConfig, "PASETO tokens are already encrypted; TokenEncryption must not be set")
+		}
+		newTokenManager = jwtutils.NewPASETOManager
+	default:
+		return nil, NewAuthError(ErrCodeInvalidConfig, "Unsupported token format: "+config.TokenFormat)
+	}
+	signingMethod, err := signingAlgorithm(config.SigningKeys)
+	if err != nil {
+		return nil, WrapError(err, ErrCodeInvalidConfig, "Invalid signing key configuration")
+	}
+	if config.SigningKeys.Signer != nil && config.TokenFormat == TokenFormatPASETO {
+		return nil, NewAuthError(ErrCodeInvalidConfig, "PASETO tokens cannot be signed with SigningKeys.Signer")
+	}
+	jwtManager := newTokenManager(jwtutils.JWTConfig{
 		AccessSecret:    []byte(config.JWTSecret),
 		RefreshSecret:   []byte(config.JWTRefreshSecret),
 		Issuer:          config.JWTIssuer,
 		AccessTokenTTL:  config.AccessTokenTTL,
 		RefreshTokenTTL: config.RefreshTokenTTL,
-		SigningMethod:   HS256, // Default to HS256
+		SigningMethod:   signingMethod,
+		SigningKey:      config.SigningKeys.Signer,
+		Now:             config.Clock.Now,
+
+		Audience:          config.JWTAudience,
+		ExpectedIssuers:   config.ExpectedIssuers,
+		ExpectedAudiences: config.ExpectedAudiences,
+		RefreshAudience:   config.SigningKeys.RefreshAudience,
+		Leeway:            config.TokenLeeway,
 	})
+	settings := newRuntimeSettings(config)
+	jwtManager = &ttlTokenManager{TokenManager: jwtManager, settings: settings}
+	jwtManager = &refreshFamilyTokenManager{TokenManager: jwtManager}
+	if err := config.Claims.Mapping.validate(); err != nil {
+		return nil, WrapError(err, ErrCodeInvalidConfig, "Invalid claim mapping")
+	}
+	if len(config.Claims.Mapping.Names) > 0 {
+		jwtManager = &claimMappingTokenManager{TokenManager: jwtManager, names: config.Claims.Mapping.Names}
+	}
+	keys, err := newSigningKeys(config)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.ProfileVisibility.validate(); err != nil {
+		return nil, WrapError(err, ErrCodeInvalidConfig, "Invalid profile visibility configuration")
+	}
+	if err := config.FeatureFlags.validate(); err != nil {
+		return nil, WrapError(err, ErrCodeInvalidConfig, "Invalid feature flag configuration")
+	}
+	flags := newFeatureFlags(config.FeatureFlags)
+
+	trustedConfigs := config.TrustedIssuers
+	if config.DualIssuance.Issuer != "" {
+		if err := config.DualIssuance.validate(config.JWTIssuer); err != nil {
+			return nil, WrapError(err, ErrCodeInvalidConfig, "Invalid dual issuance configuration")
+		}
+		trustedConfigs = append(append([]TrustedIssuer(nil), trustedConfigs...), config.DualIssuance.trustedIssuer())
+	}
+	trusted, err := newTrustedIssuers(trustedConfigs, config.Clock, config.TokenLeeway)
+	if err != nil {
+		return nil, WrapError(err, ErrCodeInvalidConfig, "Invalid trusted issuer configuration")
+	}
+	emails, err := newEmailRenderer(config.Email)
+	if err != nil {
+		return nil, WrapError(err, ErrCodeInvalidConfig, "Invalid email configuration")
+	}
+	sms, err := newSMSResets(config.SMSReset, config.AppName, config.Clock)
+	if err != nil {
+		return nil, WrapError(err, ErrCodeInvalidConfig, "Invalid SMS reset configuration")
+	}
 
 	// Create migration manager
 	migrationManager := NewMigrationManager(storageImpl)
 
 	// Create logger
 	logger := NewLogger(ParseLogLevel(config.LogLevel), nil)
+	logger.pseudonyms = NewPseudonymizer(config.Pseudonymization)
 	eventLogger := NewAuthEventLogger(logger)
+	eventLogger.geo = newLoginGeo(config.GeoIP)
+	eventLogger.security = newSecurityTelemetry(config.Clock)
+	for _, sinkConfig := range config.EventSinks {
+		if sinkConfig.Sink == nil {
+			eventLogger.close()
+			return nil, ErrConfigError("EventSinks.Sink")
+		}
+		eventLogger.sinks = append(eventLogger.sinks, newEventDispatcher(sinkConfig, logger))
+	}
+	audit, err := newAuditLog(config.Audit, config.Clock)
+	if err != nil {
+		eventLogger.close()
+		return nil, err
+	}
+	if audit != nil {
+		eventLogger.sinks = append(eventLogger.sinks, newEventDispatcher(EventSinkConfig{
+			Sink:   auditSink{log: audit},
+			Events: config.Audit.Events,
+		}, logger))
+	}
+	if len(config.Webhooks.Endpoints) > 0 && config.Webhooks.DeadLetters == nil {
+		config.Webhooks.DeadLetters = NewMemoryDeadLetterStore()
+	}
+	webhooks := make(map[string]*webhookSink, len(config.Webhooks.Endpoints))
+	for _, endpoint := range config.Webhooks.Endpoints {
+		sink, err := newWebhookSink(endpoint, config.Webhooks, config.Clock, logger)
+		if err != nil {
+			eventLogger.close()
+			return nil, err
+		}
+		webhooks[sink.endpoint.ID] = sink
+		// The sink retries on its own, so the dispatcher only re-runs failed
+		// dead-letter writes
+		eventLogger.sinks = append(eventLogger.sinks, newEventDispatcher(EventSinkConfig{
+			Sink:   sink,
+			Events: endpoint.Events,
+		}, logger))
+	}
 
 	// Create metrics collector
 	metricsCollector := NewMetricsCollector()
+	metricsCollector.SetTenantLimit(config.MetricsTenantLimit)
+
+	if config.StorageInstrumentation.Enabled {
+		storageImpl = NewInstrumentedStorage(storageImpl, config.StorageInstrumentation, metricsCollector, logger)
+	}
+	var storageBreaker *CircuitBreaker
+	if config.StorageCircuitBreaker.FailureThreshold > 0 {
+		storageBreaker = newCircuitBreaker("storage", config.StorageCircuitBreaker, config.Clock, logger)
+		storageImpl = &circuitStorage{EnhancedStorage: storageImpl, breaker: storageBreaker}
+	}
+	if config.Shadow.Storage != nil {
+		storageImpl = newShadowStorage(storageImpl, config.Shadow, logger, metricsCollector)
+	}
+	loginCache := newLoginUserCache(config.LoginCache, config.Clock)
+	if loginCache != nil {
+		storageImpl = &loginCacheStorage{EnhancedStorage: storageImpl, cache: loginCache}
+	}
+	revocations := newRevocationBroadcaster(config.Revocation, config.Clock, logger)
+	if revocations != nil {
+		storageImpl = &revocationStorage{EnhancedStorage: storageImpl, revocations: revocations}
+	}
+	validations := &validationCaches{}
+	storageImpl = &validationCacheStorage{EnhancedStorage: storageImpl, caches: validations}
+	watcher := newRevocationWatcher(storageImpl, config.Clock)
+	storageImpl = &watcherStorage{EnhancedStorage: storageImpl, watcher: watcher}
+
+	// Tokens carry the global epoch; encryption wraps the epoch claims
+	if config.TokenEpoch.Store == nil {
+		config.TokenEpoch.Store = NewStorageEpochStore(storageImpl)
+	}
+	epoch := newEpochTokenManager(jwtManager, config.TokenEpoch, config.Clock, logger, metricsCollector)
+	jwtManager = &expiringTokenManager{TokenManager: epoch, clock: config.Clock}
+	expectedAudiences := config.ExpectedAudiences
+	if len(expectedAudiences) == 0 {
+		expectedAudiences = config.JWTAudience
+	}
+	jwtManager = &strictAudienceTokenManager{TokenManager: jwtManager, flags: flags, audiences: expectedAudiences}
+	if config.TokenEncryption != nil {
+		encrypted, err := newEncryptedTokenManager(jwtManager, *config.TokenEncryption)
+		if err != nil {
+			return nil, WrapError(err, ErrCodeInvalidConfig, "Invalid token encryption configuration")
+		}
+		encrypted.flags = flags
+		jwtManager = encrypted
+	}
+	// The size limit applies to the final, possibly encrypted, token
+	jwtManager = newSizeLimitedTokenManager(jwtManager, config.TokenSize, logger, metricsCollector)
+
+	// Billing claims only come from the subscription provider
+	if config.Subscriptions.Provider != nil {
+		config.Claims.Protected = append(append([]string(nil), config.Claims.Protected...), subscriptionClaims...)
+	}
 
 	auth := &Auth{
 		storage:          storageImpl,
@@ -205,16 +701,94 @@ func newAuthWithStorage(storageImpl storage.EnhancedStorage, config *AuthConfig)
 		logger:           logger,
 		eventLogger:      eventLogger,
 		metricsCollector: metricsCollector,
+		translator:       &translatorSwitch{translator: DefaultMessageCatalog()},
+		clock:            config.Clock,
+		hasher:           config.PasswordHasher,
+		hooks:            NewHooks(),
+		maintenance:      &maintenanceSwitch{},
+		dpop:             NewDPoP(config.DPoP, config.Clock),
+		mtls:             &mtlsBinding{config: config.MTLS},
+		actionKey:        keys.action,
+		captcha:          newCaptchaGuard(config.Captcha, config.Clock, logger),
+		risk:             newRiskEngine(config.Risk, config.Clock, logger),
+		grants:           config.GrantStore,
+		assertions:       newAssertionGrants(config.Assertions, config.JWTIssuer, config.Clock, config.TokenLeeway),
+		delegationKey:    keys.delegation,
+		csrfKey:          keys.csrf,
+		refreshLimiter:   newRefreshLimiter(config.RefreshLimit, config.Clock),
+		trustedIssuers:   trusted,
+		loginCache:       loginCache,
+		epoch:            epoch,
+		consent:          config.Consent,
+		usernames:        newUsernamePolicy(config.Usernames, config.Clock),
+		emails:           emails,
+		pending:          newPendingTokens(),
+		sessions:         newSessionTracker(config.Sessions, config.RefreshTokenTTL, config.Clock, logger),
+		revocations:      revocations,
+		watcher:          watcher,
+		deadLetters:      config.Webhooks.DeadLetters,
+		webhooks:         webhooks,
+		claims:           newClaimPolicy(config.Claims, logger),
+		subscriptions:    newSubscriptionPolicy(config.Subscriptions, logger),
+		flags:            flags,
+		validations:      validations,
+		jobs:             newJobTracker(),
+		settings:         settings,
+		dual:             newCompatibilityIssuer(config.DualIssuance, config.Clock, settings),
+		sms:              sms,
+		audit:            audit,
+		quotas:           newRegistrationQuotas(config.RegistrationQuotas, config.Clock),
+	}
+
+	permissionConfig := config.Permissions
+	if permissionConfig.Loader == nil {
+		permissionConfig.Loader = auth.metadataPermissionLoader()
+	}
+	if permissionConfig.Clock == nil {
+		permissionConfig.Clock = config.Clock
+	}
+	permissions, err := NewPermissionCache(permissionConfig)
+	if err != nil {
+		return nil, err
+	}
+	auth.permissions = permissions
+	auth.adminJobs = newAdminJobs(auth)
+	if config.SecurityNotifications.Enabled {
+		if config.Email.Sender == nil {
+			return nil, ErrConfigError("Email.Sender")
+		}
+		auth.notifier = newSecurityNotifier(auth)
+		eventLogger.notifications = newEventDispatcher(EventSinkConfig{
+			Sink:   auth.notifier,
+			Events: securityNotificationEvents,
+		}, logger)
 	}
 
 	// Create monitor
 	auth.monitor = NewMonitor(storageImpl, metricsCollector, logger, config.AppName, config.Version)
+	auth.monitor.ConfigureProbes(config.Probes)
+	auth.monitor.security = eventLogger.security
+	if storageBreaker != nil {
+		auth.monitor.RegisterCircuitBreaker(storageBreaker)
+	}
 
 	// Automatic database initialization and migration on startup
 	if err := auth.initializeDatabase(); err != nil {
 		return nil, WrapError(err, ErrCodeMigrationError, "Failed to initialize database")
 	}
 
+	// Drop state revoked by other instances
+	if err := revocations.subscribe(auth.applyRevocation); err != nil {
+		return nil, WrapError(err, ErrCodeConnectionError, "Failed to subscribe to revocations")
+	}
+
+	if config.AccountExpiry.Interval > 0 {
+		auth.stopExpiryJob = auth.startExpiryJob(config.AccountExpiry.Interval)
+	}
+	if auth.notifier != nil && config.SecurityNotifications.DigestInterval > 0 {
+		auth.stopDigestJob = auth.startDigestJob(config.SecurityNotifications.DigestInterval)
+	}
+
 	return auth, nil
 }
 
@@ -240,14 +814,21 @@ func (a *Auth) initializeDatabase() error {
 
 // RegisterRequest defines the data required to register a new user.
 type RegisterRequest struct {
-	Username string
-	Email    string
-	Password string
+	Username string `json:"username" binding:"required" validate:"required"`
+	Email    string `json:"email" binding:"omitempty,email" validate:"omitempty,email"`
+	Password string `json:"password" binding:"required" validate:"required"`
+	// Birthdate is optional, formatted as YYYY-MM-DD.
+	Birthdate string `json:"birthdate,omitempty"`
 }
 
 // Register creates a new user, hashes their password, and saves them to storage.
 // It returns the newly created user.
 func (a *Auth) Register(payload RegisterRequest) (*models.User, error) {
+	return a.RegisterContext(context.Background(), payload)
+}
+
+// RegisterContext is like Register but passes ctx to registered hooks.
+func (a *Auth) RegisterContext(ctx context.Context, payload RegisterRequest) (*models.User, error) {
 	start := time.Now()
 	var userID string
 	var success bool
@@ -255,11 +836,18 @@ func (a *Auth) Register(payload RegisterRequest) (*models.User, error) {
 
 	defer func() {
 		// Log the registration event
-		a.eventLogger.LogRegistration(userID, payload.Username, payload.Email, "", "", success, err)
+		client := clientInfoFrom(ctx)
+		tenant := tenantFrom(ctx)
+		a.eventLogger.forTenant(tenant).LogRegistration(userID, payload.Username, payload.Email, client.ip, client.userAgent, success, err)
 		// Record metrics
 		a.metricsCollector.RecordRegistrationAttempt(success)
+		a.metricsCollector.RecordTenantRegistration(tenant, success)
 	}()
 
+	if err = a.maintenance.check(maintenanceRegister); err != nil {
+		return nil, err
+	}
+
 	// Basic validation
 	if payload.Username == "" {
 		err = ErrValidationError("username")
@@ -272,14 +860,54 @@ func (a *Auth) Register(payload RegisterRequest) (*models.User, error) {
 		return nil, err
 	}
 
+	var birthdate time.Time
+	if payload.Birthdate != "" {
+		if birthdate, err = parseBirthdate(payload.Birthdate, nowFrom(a.clock)); err != nil {
+			a.metricsCollector.RecordValidationError()
+			return nil, err
+		}
+	}
+
+	flagged, ageErr := a.checkAge(ctx, payload, birthdate)
+	if ageErr != nil {
+		err = ageErr
+		a.logger.Warn("Registration rejected by age policy", map[string]interface{}{
+			"username": payload.Username,
+			"error":    ageErr,
+		})
+		return nil, err
+	}
+	if flagged {
+		a.logger.Info("Registration flagged for age review", map[string]interface{}{
+			"username": payload.Username,
+		})
+	}
+
+	// Tenants over their registration quota cannot add users
+	quotaTenant := tenantFrom(ctx)
+	if quotaTenant == "" && a.config != nil {
+		quotaTenant = a.config.Tenant
+	}
+	if quotaErr := a.quotas.check(quotaTenant); quotaErr != nil {
+		err = quotaErr
+		a.metricsCollector.RecordQuotaRejection(tenantFrom(ctx))
+		a.logger.Warn("Registration rejected: quota exceeded", map[string]interface{}{
+			"username": payload.Username,
+			"tenant":   quotaTenant,
+			"error":    quotaErr,
+		})
+		return nil, err
+	}
+
 	a.logger.Debug("Starting user registration", map[string]interface{}{
 		"username": payload.Username,
 		"email":    payload.Email,
 	})
 
-	// Check if user already exists by username
-	if _, err := a.storage.GetUserByUsername(payload.Username); err == nil {
-		err = ErrUserExists("username")
+	// Check if user already exists by username or the username is reserved
+	// for a user who changed it
+	if availableErr := a.usernames.checkAvailable(a.storage, "", payload.Username); availableErr != nil {
+		err = availableErr
 		a.logger.Warn("Registration failed: username already exists", map[string]interface{}{
 			"username": payload.Username,
 		})
@@ -297,7 +925,7 @@ func (a *Auth) Register(payload RegisterRequest) (*models.User, error) {
 		}
 	}
 
-	passwordHash, hashErr := HashPassword(payload.Password)
+	passwordHash, hashErr := a.hasher.Hash(payload.Password)
 	if hashErr != nil {
 		err = WrapError(hashErr, ErrCodeInternalError, "Failed to hash password")
 		a.logger.Error("Password hashing failed", map[string]interface{}{
@@ -307,15 +935,38 @@ func (a *Auth) Register(payload RegisterRequest) (*models.User, error) {
 		return nil, err
 	}
 
+	now := nowFrom(a.clock)
 	newUser := models.User{
 		ID:           uuid.New().String(),
 		Username:     payload.Username,
 		Email:        payload.Email,
 		PasswordHash: passwordHash,
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		CreatedAt:    now,
+		UpdatedAt:    now,
 		IsActive:     true,
 	}
+	recordTenant := a.quotas != nil && quotaTenant != ""
+	if payload.Birthdate != "" || flagged || recordTenant {
+		newUser.Metadata = make(map[string]interface{})
+		if payload.Birthdate != "" {
+			newUser.Metadata[birthdateMetadataKey] = birthdate.Format(birthdateLayout)
+		}
+		if flagged {
+			newUser.Metadata[ageReviewMetadataKey] = true
+		}
+		if recordTenant {
+			newUser.Metadata[registrationTenantMetadataKey] = quotaTenant
+		}
+	}
+
+	if hookErr := a.hooks.runRegister(ctx, false, &newUser); hookErr != nil {
+		err = hookErr
+		a.logger.Warn("Registration rejected by hook", map[string]interface{}{
+			"username": payload.Username,
+			"error":    hookErr,
+		})
+		return nil, err
+	}
 
 	userID = newUser.ID
 
@@ -331,12 +982,26 @@ func (a *Auth) Register(payload RegisterRequest) (*models.User, error) {
 	}
 
 	success = true
+	if quotaErr := a.quotas.record(quotaTenant); quotaErr != nil {
+		a.logger.Warn("Failed to count registration against quota", map[string]interface{}{
+			"user_id": userID,
+			"tenant":  quotaTenant,
+			"error":   quotaErr,
+		})
+	}
 	a.logger.Info("User registered successfully", map[string]interface{}{
 		"username": payload.Username,
 		"user_id":  userID,
 		"duration": time.Since(start),
 	})
 
+	if hookErr := a.hooks.runRegister(ctx, true, &newUser); hookErr != nil {
+		a.logger.Warn("After-register hook failed", map[string]interface{}{
+			"user_id": userID,
+			"error":   hookErr,
+		})
+	}
+
 	return &newUser, nil
 }
 
@@ -344,11 +1009,30 @@ func (a *Auth) Register(payload RegisterRequest) (*models.User, error) {
 type LoginResult struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type,omitempty"`
+	// CompatibilityToken is the access token in the legacy format, set
+	// with AuthConfig.DualIssuance.
+	CompatibilityToken string `json:"compatibility_token,omitempty"`
 }
 
 // Login authenticates a user and returns an access and refresh token pair.
 // It accepts customClaims to be embedded in the access token for authorization purposes.
+// customClaims never replace the standard claims (sub, exp, iat, nbf, jti,
+// iss, aud, token_type) or the claims stored for the user (username, email,
+// user_id, directory claims), nor set the protected claims of
+// AuthConfig.Claims; such entries are dropped and logged.
 func (a *Auth) Login(username, password string, customClaims map[string]interface{}) (*LoginResult, error) {
+	return a.LoginContext(context.Background(), username, password, customClaims)
+}
+
+// LoginContext is like Login but passes ctx to registered hooks.
+func (a *Auth) LoginContext(ctx context.Context, username, password string, customClaims map[string]interface{}) (*LoginResult, error) {
+	return a.login(ctx, username, password, customClaims, false)
+}
+
+// login authenticates a user. Unless captchaVerified is set, it is rejected
+// with CAPTCHA_REQUIRED once the username has too many recent failures.
+func (a *Auth) login(ctx context.Context, username, password string, customClaims map[string]interface{}, captchaVerified bool) (*LoginResult, error) {
 	start := time.Now()
 	var userID string
 	var success bool
@@ -357,18 +1041,40 @@ func (a *Auth) Login(username, password string, customClaims map[string]interfac
 	defer func() {
 		duration := time.Since(start)
 		// Log the login event
-		a.eventLogger.LogLogin(userID, username, "", "", success, duration, err)
+		client := clientInfoFrom(ctx)
+		tenant := tenantFrom(ctx)
+		a.eventLogger.forTenant(tenant).LogLogin(userID, username, client.ip, client.userAgent, success, duration, err)
 		// Record metrics
 		a.metricsCollector.RecordLoginAttempt(success, duration)
+		a.metricsCollector.RecordTenantLogin(tenant, success)
+		// Feed the velocity and device signals of later logins
+		a.risk.observe(userID, client.userAgent, success)
 	}()
 
+	if err = a.maintenance.check(maintenanceLogin); err != nil {
+		return nil, err
+	}
+
 	a.logger.Debug("Starting user login", map[string]interface{}{
 		"username": username,
 	})
 
-	user, getUserErr := a.storage.GetUserByUsername(username)
+	if !captchaVerified && a.captcha.required(username) {
+		err = a.captcha.challengeError()
+		a.logger.Warn("Login rejected: CAPTCHA required", map[string]interface{}{
+			"username": username,
+		})
+		return nil, err
+	}
+
+	user, getUserErr := a.loginCache.lookup(username, a.storage.GetUserByUsername)
+	if getUserErr != nil && a.usernames.config.LoginWithPrevious {
+		// Previous usernames bypass the cache, which is keyed by current ones
+		user, getUserErr = a.usernames.previousUser(a.storage, username)
+	}
 	if getUserErr != nil {
 		err = ErrInvalidCredentials() // Generic error for security
+		a.captcha.fail(username)
 		a.logger.Warn("Login failed: user not found", map[string]interface{}{
 			"username": username,
 		})
@@ -385,8 +1091,15 @@ func (a *Auth) Login(username, password string, customClaims map[string]interfac
 		})
 		return nil, err
 	}
+	if err = checkAccountExpiry(user, a.clock); err != nil {
+		a.logger.Warn("Login failed: account expired", map[string]interface{}{
+			"username": username,
+			"user_id":  userID,
+		})
+		return nil, err
+	}
 
-	match, checkErr := CheckPasswordHash(password, user.PasswordHash)
+	match, checkErr := verifyPassword(a.hasher, password, user.PasswordHash)
 	if checkErr != nil {
 		err = ErrInvalidCredentials()
 		a.logger.Error("Login failed: password check error", map[string]interface{}{
@@ -398,6 +1111,7 @@ func (a *Auth) Login(username, password string, customClaims map[string]interfac
 	}
 	if !match {
 		err = ErrInvalidCredentials()
+		a.captcha.fail(username)
 		a.logger.Warn("Login failed: invalid password", map[string]interface{}{
 			"username": username,
 			"user_id":  userID,
@@ -405,63 +1119,199 @@ func (a *Auth) Login(username, password string, customClaims map[string]interfac
 		return nil, err
 	}
 
-	// Update last login time
-	now := time.Now()
+	// Upgrade the stored hash if it was produced by another algorithm or weaker
+	// parameters, for users in the FlagPasswordRehash rollout. Imported legacy
+	// hashes are always replaced.
+	if isLegacyHash(user.PasswordHash) || (a.hasher.NeedsRehash(user.PasswordHash) && a.flags.enabled(FlagPasswordRehash, user.ID)) {
+		a.rehashPassword(user, password)
+	}
+
+	// Score the attempt; risky logins must step up or are denied
+	riskScore, riskErr := a.assessLoginRisk(ctx, user)
+	if riskErr != nil {
+		err = riskErr
+		a.logger.Warn("Login rejected: risk score", map[string]interface{}{
+			"username":   username,
+			"user_id":    userID,
+			"risk_score": riskScore,
+			"error":      riskErr,
+		})
+		return nil, err
+	}
+
+	result, loginErr := a.completeLogin(ctx, user, customClaims, riskScore)
+	if loginErr != nil {
+		err = loginErr
+		return nil, err
+	}
+	success = true
+	a.captcha.reset(username)
+	return result, nil
+}
+
+// completeLogin issues the tokens of an authenticated user, after checking
+// password expiry and consent and running the login hooks. riskScore is
+// recorded on the session.
+func (a *Auth) completeLogin(ctx context.Context, user *models.User, customClaims map[string]interface{}, riskScore float64) (*LoginResult, error) {
+	username, userID := user.Username, user.ID
+
+	// Expired passwords must be changed before logging in
+	passwordExpiresAt, err := a.checkPasswordExpiry(user)
+	if err != nil {
+		a.logger.Warn("Login rejected: password expired", map[string]interface{}{
+			"username": username,
+			"user_id":  userID,
+		})
+		return nil, err
+	}
+
+	// Users must accept updated terms before they get tokens
+	if err := a.checkConsent(user.ID); err != nil {
+		a.logger.Info("Login rejected: consent required", map[string]interface{}{
+			"username": username,
+			"user_id":  userID,
+		})
+		return nil, err
+	}
+
+	// Stored claims take precedence over customClaims, see claims.go
+	claims := a.claims.stored(user)
+	if err := a.subscriptions.apply(ctx, user, claims); err != nil {
+		a.logger.Info("Login rejected: subscription", map[string]interface{}{
+			"username": username,
+			"user_id":  userID,
+			"error":    err,
+		})
+		return nil, err
+	}
+
+	// Update last login time; retention policies rely on it
+	now := nowFrom(a.clock)
 	user.LastLoginAt = &now
 	user.UpdatedAt = now
-	// Note: We could update this in storage, but for now we'll keep it simple
+	if updateErr := a.storage.UpdateUser(user.ID, storage.UserUpdates{LastLoginAt: &now}); updateErr != nil {
+		a.logger.Warn("Failed to record last login time", map[string]interface{}{
+			"user_id": userID,
+			"error":   updateErr,
+		})
+	}
+
+	if !passwordExpiresAt.IsZero() {
+		claims["password_expires_at"] = passwordExpiresAt.Unix()
+	}
+	a.claims.merge(user.ID, claims, customClaims)
+	// Sender-constrained logins bind the tokens, see withTokenBinding
+	if cnf := tokenBindingFrom(ctx); cnf != nil {
+		claims["cnf"] = cnf
+	}
 
-	// Add standard claims
-	claims := map[string]interface{}{
-		"username": user.Username,
-		"email":    user.Email,
-		"user_id":  user.ID,
+	// Let hooks veto the login or enrich the claims
+	if err := a.hooks.runLogin(ctx, false, user, claims); err != nil {
+		a.logger.Warn("Login rejected by hook", map[string]interface{}{
+			"username": username,
+			"user_id":  userID,
+			"error":    err,
+		})
+		return nil, err
 	}
-	// Merge with custom claims
-	for k, v := range customClaims {
-		claims[k] = v
+
+	// Tokens of the login share a session subject to the idle timeout
+	sessionOptions, sessionErr := a.sessions.start(user.ID, clientInfoFrom(ctx).ip, riskScore)
+	if sessionErr != nil {
+		a.logger.Error("Failed to create session", map[string]interface{}{
+			"username": username,
+			"user_id":  userID,
+			"error":    sessionErr,
+		})
+		return nil, WrapDatabaseError(sessionErr)
 	}
 
-	accessToken, tokenErr := a.jwtManager.GenerateAccessToken(user.ID, claims)
+	accessToken, tokenErr := a.jwtManager.GenerateAccessTokenWithOptions(user.ID, claims, sessionOptions)
 	if tokenErr != nil {
-		err = WrapError(tokenErr, ErrCodeInternalError, "Failed to generate access token")
 		a.logger.Error("Failed to generate access token", map[string]interface{}{
 			"username": username,
 			"user_id":  userID,
 			"error":    tokenErr,
 		})
-		return nil, err
+		return nil, WrapError(tokenErr, ErrCodeInternalError, "Failed to generate access token")
 	}
 
-	refreshToken, refreshErr := a.jwtManager.GenerateRefreshToken(user.ID)
+	// Certificate-bound logins bind the refresh token too
+	refreshOptions := bindRefresh(sessionOptions, claims["cnf"])
+	refreshToken, refreshErr := a.jwtManager.GenerateRefreshTokenWithOptions(user.ID, refreshOptions)
 	if refreshErr != nil {
-		err = WrapError(refreshErr, ErrCodeInternalError, "Failed to generate refresh token")
 		a.logger.Error("Failed to generate refresh token", map[string]interface{}{
 			"username": username,
 			"user_id":  userID,
 			"error":    refreshErr,
 		})
-		return nil, err
+		return nil, WrapError(refreshErr, ErrCodeInternalError, "Failed to generate refresh token")
+	}
+
+	compatibilityToken, compatibilityErr := a.dual.issue(user.ID, claims, sessionOptions)
+	if compatibilityErr != nil {
+		a.logger.Error("Failed to generate compatibility token", map[string]interface{}{
+			"username": username,
+			"user_id":  userID,
+			"error":    compatibilityErr,
+		})
+		return nil, WrapError(compatibilityErr, ErrCodeInternalError, "Failed to generate compatibility token")
 	}
 
-	success = true
 	a.logger.Info("User logged in successfully", map[string]interface{}{
 		"username": username,
 		"user_id":  userID,
-		"duration": time.Since(start),
 	})
 
+	if hookErr := a.hooks.runLogin(ctx, true, user, claims); hookErr != nil {
+		a.logger.Warn("After-login hook failed", map[string]interface{}{
+			"user_id": userID,
+			"error":   hookErr,
+		})
+	}
+
 	return &LoginResult{
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
+		AccessToken:        accessToken,
+		RefreshToken:       refreshToken,
+		CompatibilityToken: compatibilityToken,
 	}, nil
 }
 
+// rehashPassword re-hashes a verified password with the configured hasher and
+// stores the result. Failures are logged and do not affect the login.
+func (a *Auth) rehashPassword(user *models.User, password string) {
+	newHash, err := a.hasher.Hash(password)
+	if err == nil {
+		err = a.storage.UpdatePassword(user.ID, newHash)
+	}
+	if err != nil {
+		a.logger.Warn("Failed to upgrade password hash", map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err,
+		})
+		return
+	}
+
+	user.PasswordHash = newHash
+	a.logger.Info("Password hash upgraded", map[string]interface{}{
+		"user_id": user.ID,
+	})
+}
+
 // ValidateAccessToken validates an access token string.
 // It returns the claims if the token is valid, otherwise an error.
 func (a *Auth) ValidateAccessToken(tokenString string) (jwt.MapClaims, error) {
+	if err := a.maintenance.check(maintenanceValidate); err != nil {
+		return nil, err
+	}
+
 	start := time.Now()
 	claims, err := a.jwtManager.ValidateAccessToken(tokenString)
+	if err == nil {
+		if err = a.sessions.touch(claims); err != nil {
+			claims = nil
+		}
+	}
 	duration := time.Since(start)
 
 	var userID string
@@ -504,7 +1354,7 @@ func (a *Auth) GetUser(userID string) (*models.UserProfile, error) {
 		return nil, err
 	}
 
-	return user.ToUserProfile(), nil
+	return toProfile(user), nil
 }
 
 // GetUserByUsername retrieves a user by their username, returning a safe UserProfile.
@@ -514,7 +1364,7 @@ func (a *Auth) GetUserByUsername(username string) (*models.UserProfile, error) {
 		return nil, err
 	}
 
-	return user.ToUserProfile(), nil
+	return toProfile(user), nil
 }
 
 // GetUserByEmail retrieves a user by their email, returning a safe UserProfile.
@@ -524,7 +1374,7 @@ func (a *Auth) GetUserByEmail(email string) (*models.UserProfile, error) {
 		return nil, err
 	}
 
-	return user.ToUserProfile(), nil
+	return toProfile(user), nil
 }
 
 // Users returns a Users component for enhanced user management operations.
@@ -533,6 +1383,19 @@ func (a *Auth) Users() *Users {
 		storage:          a.storage,
 		eventLogger:      a.eventLogger,
 		metricsCollector: a.metricsCollector,
+		clock:            a.clock,
+		hasher:           a.hasher,
+		hooks:            a.hooks,
+		logger:           a.logger,
+		permissions:      a.permissions,
+		consent:          a.consent,
+		avatars:          a.config.Avatars,
+		visibility:       a.config.ProfileVisibility,
+		usernames:        a.usernames,
+		pending:          a.pending,
+		settings:         a.settings,
+		sms:              a.sms,
+		quotas:           a.quotas,
 	}
 }
 
@@ -543,6 +1406,34 @@ func (a *Auth) Tokens() *Tokens {
 		storage:          a.storage,
 		eventLogger:      a.eventLogger,
 		metricsCollector: a.metricsCollector,
+		maintenance:      a.maintenance,
+		clock:            a.clock,
+		actionKey:        a.actionKey,
+		grants:           a.grants,
+		assertions:       a.assertions,
+		delegationKey:    a.delegationKey,
+		epoch:            a.epoch,
+		refreshLimiter:   a.refreshLimiter,
+		trustedIssuers:   a.trustedIssuers,
+		sessions:         a.sessions,
+		pending:          a.pending,
+		revocations:      a.revocations,
+		watcher:          a.watcher,
+		claims:           a.claims,
+		subscriptions:    a.subscriptions,
+		dual:             a.dual,
+	}
+}
+
+// Emails returns an Emails component building account email links and bodies.
+func (a *Auth) Emails() *Emails {
+	return &Emails{
+		renderer: a.emails,
+		storage:  a.storage,
+		users:    a.Users(),
+		tokens:   a.Tokens(),
+		appName:  a.config.AppName,
+		clock:    a.clock,
 	}
 }
 
@@ -552,6 +1443,26 @@ func (a *Auth) RefreshToken(refreshToken string) (*RefreshResult, error) {
 	return a.Tokens().Refresh(refreshToken)
 }
 
+// Close releases the subscriptions of the Auth instance to its revocation
+// and permission invalidation buses, stops the account expiry job and
+// delivers buffered events to the event sinks.
+func (a *Auth) Close() {
+	a.revocations.close()
+	if a.stopExpiryJob != nil {
+		a.stopExpiryJob()
+	}
+	if a.stopDigestJob != nil {
+		a.stopDigestJob()
+	}
+	if a.permissions != nil {
+		a.permissions.Close()
+	}
+	if a.adminJobs != nil {
+		a.adminJobs.cancelAll()
+	}
+	a.eventLogger.close()
+}
+
 // Health checks the health of the Auth service and its dependencies.
 func (a *Auth) Health() error {
 	return a.storage.Ping()
@@ -577,6 +1488,23 @@ func (a *Auth) RollbackToVersion(version int) error {
 	return a.migrationManager.Rollback(version)
 }
 
+// SetTranslator sets the translator used to localize error responses written
+// by the built-in HTTP middleware. Passing nil disables localization.
+func (a *Auth) SetTranslator(t Translator) {
+	a.translator.set(t)
+}
+
+// Hooks returns the hook registry used to customize login, registration,
+// and password changes.
+func (a *Auth) Hooks() *Hooks {
+	return a.hooks
+}
+
+// Translator returns the translator used to localize error responses.
+func (a *Auth) Translator() Translator {
+	return a.translator.get()
+}
+
 // Logger returns the logger instance for custom logging
 func (a *Auth) Logger() *Logger {
 	return a.logger
@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"database/sql"
 	"os"
 	"testing"
 	"time"
@@ -42,6 +43,60 @@ func TestNewSQLite(t *testing.T) {
 	}
 }
 
+func TestNewSQLiteInMemory(t *testing.T) {
+	first, err := NewSQLiteInMemory("test-secret")
+	if err != nil {
+		t.Fatalf("Failed to create in-memory SQLite auth: %v", err)
+	}
+	second, err := NewSQLiteInMemory("test-secret")
+	if err != nil {
+		t.Fatalf("Failed to create in-memory SQLite auth: %v", err)
+	}
+
+	if _, err := first.Register(RegisterRequest{Username: "alice", Password: "password123"}); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	if _, err := first.Login("alice", "password123", nil); err != nil {
+		t.Errorf("Failed to login: %v", err)
+	}
+
+	// Each instance gets its own database
+	if _, err := second.GetUserByUsername("alice"); err == nil {
+		t.Error("Expected databases of separate instances to be isolated")
+	}
+}
+
+func TestNewSQLiteDB(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:auth-injected?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	auth, err := NewSQLiteDB(db, "test-secret")
+	if err != nil {
+		t.Fatalf("Failed to create SQLite auth: %v", err)
+	}
+	if _, err := auth.Register(RegisterRequest{Username: "alice", Password: "password123"}); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil || count != 1 {
+		t.Errorf("Expected the user in the injected database, got %d, %v", count, err)
+	}
+
+	if _, err := NewWithConfig(&AuthConfig{JWTSecret: "test-secret", DatabasePath: "test.db", SQLiteDriver: "no-such-driver"}); err == nil {
+		t.Error("Expected an error for an unregistered driver")
+	}
+}
+
+func TestNewLibSQL(t *testing.T) {
+	if _, err := NewLibSQL("ftp://example.com/db", "token", "test-secret"); err == nil {
+		t.Error("Expected an error for an unsupported URL scheme")
+	}
+}
+
 func TestNew(t *testing.T) {
 	// Test that New() is an alias for NewSQLite()
 	dbPath := "test_auth_new.db"
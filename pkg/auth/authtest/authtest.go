@@ -0,0 +1,154 @@
+// Package authtest provides an auth.Auth tuned for fast, deterministic
+// tests: in-memory storage, weak password hashing and a frozen clock, with
+// helpers to seed users and log them in.
+//
+//	func TestProfile(t *testing.T) {
+//		ta := authtest.New(t)
+//		ta.SeedUser("alice", "alice-password", "admin")
+//		tokens := ta.LoginAs("alice")
+//		// ...
+//	}
+package authtest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/auth"
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+// TestPassword is the password given to users created by TestAuth.SeedUsers.
+const TestPassword = "test-password-123"
+
+// TestAuth is an Auth instance tuned for fast, deterministic tests.
+// It uses in-memory storage, weak password hashing, and a frozen clock.
+type TestAuth struct {
+	*auth.Auth
+
+	// Clock controls the time seen by token issuance and validation.
+	Clock *auth.FrozenClock
+
+	// Mail captures the emails sent through Emails().
+	Mail *CapturedEmails
+
+	t         testing.TB
+	passwords map[string]string
+}
+
+// New creates a TestAuth for use in tests. The clock is frozen at the time
+// of the call (truncated to the second) and only moves via Clock.Advance or Clock.Set.
+func New(t testing.TB) *TestAuth {
+	t.Helper()
+
+	clock := auth.NewFrozenClock(time.Now().UTC().Truncate(time.Second))
+	mail := NewCapturedEmails()
+	a, err := auth.NewWithConfig(&auth.AuthConfig{
+		JWTSecret:          "go-auth-test-secret",
+		JWTRefreshSecret:   "go-auth-test-refresh-secret",
+		JWTIssuer:          "go-auth-test",
+		AppName:            "go-auth-test",
+		LogLevel:           "error",
+		Clock:              clock,
+		PasswordHashParams: auth.TestHashParams,
+		Email:              auth.EmailConfig{BaseURL: "https://go-auth.test", Sender: mail},
+	})
+	if err != nil {
+		t.Fatalf("failed to create test auth: %v", err)
+	}
+
+	return &TestAuth{
+		Auth:      a,
+		Clock:     clock,
+		Mail:      mail,
+		t:         t,
+		passwords: make(map[string]string),
+	}
+}
+
+// SeedUser registers a user with the given password and optional roles.
+// Roles are stored in the user's metadata under "roles" and are added as a
+// "roles" claim by LoginAs. It fails the test on error.
+func (ta *TestAuth) SeedUser(username, password string, roles ...string) *models.User {
+	ta.t.Helper()
+
+	user, err := ta.Register(auth.RegisterRequest{
+		Username: username,
+		Email:    fmt.Sprintf("%s@example.test", username),
+		Password: password,
+	})
+	if err != nil {
+		ta.t.Fatalf("failed to seed user %q: %v", username, err)
+	}
+
+	if len(roles) > 0 {
+		metadata := map[string]interface{}{"roles": roles}
+		if err := ta.Users().Update(user.ID, auth.UserUpdate{Metadata: metadata}); err != nil {
+			ta.t.Fatalf("failed to seed roles for user %q: %v", username, err)
+		}
+		user.Metadata = metadata
+	}
+
+	ta.passwords[username] = password
+	return user
+}
+
+// SeedUsers registers one user per username, all with TestPassword.
+func (ta *TestAuth) SeedUsers(usernames ...string) []*models.User {
+	ta.t.Helper()
+
+	users := make([]*models.User, len(usernames))
+	for i, username := range usernames {
+		users[i] = ta.SeedUser(username, TestPassword)
+	}
+	return users
+}
+
+// LoginAs logs in a seeded user and returns its tokens. Seeded roles are
+// included as a "roles" claim. It fails the test on error.
+func (ta *TestAuth) LoginAs(username string) *auth.LoginResult {
+	ta.t.Helper()
+
+	password, ok := ta.passwords[username]
+	if !ok {
+		ta.t.Fatalf("user %q was not seeded", username)
+	}
+
+	var claims map[string]interface{}
+	if user, err := ta.GetUserByUsername(username); err == nil {
+		if roles, ok := user.Metadata["roles"]; ok {
+			claims = map[string]interface{}{"roles": roles}
+		}
+	}
+
+	result, err := ta.Login(username, password, claims)
+	if err != nil {
+		ta.t.Fatalf("failed to log in as %q: %v", username, err)
+	}
+	return result
+}
+
+// LastResetTokenFor returns the reset token of the last password reset
+// email sent to email. It fails the test if there is none.
+func (ta *TestAuth) LastResetTokenFor(email string) string {
+	ta.t.Helper()
+
+	token, ok := ta.Mail.LastResetTokenFor(email)
+	if !ok {
+		ta.t.Fatalf("no password reset email was sent to %q", email)
+	}
+	return token
+}
+
+// LastLinkFor returns the link of the last email of kind sent to email,
+// e.g. auth.EmailVerification. It fails the test if there is none.
+func (ta *TestAuth) LastLinkFor(email, kind string) string {
+	ta.t.Helper()
+
+	message, ok := ta.Mail.Last(email, kind)
+	if !ok {
+		ta.t.Fatalf("no %s email was sent to %q", kind, email)
+	}
+	return message.Link
+}
@@ -0,0 +1,63 @@
+package authtest
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/auth"
+)
+
+func TestNew(t *testing.T) {
+	ta := New(t)
+
+	user := ta.SeedUser("alice", "alice-password", "admin", "editor")
+	if user.ID == "" {
+		t.Fatal("Expected seeded user to have an ID")
+	}
+	if !user.CreatedAt.Equal(ta.Clock.Now()) {
+		t.Errorf("Expected CreatedAt %v to match frozen clock %v", user.CreatedAt, ta.Clock.Now())
+	}
+
+	tokens := ta.LoginAs("alice")
+	claims, err := ta.ValidateAccessToken(tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected access token to be valid, got %v", err)
+	}
+	roles, ok := claims["roles"].([]interface{})
+	if !ok || len(roles) != 2 || roles[0] != "admin" {
+		t.Errorf("Expected roles claim [admin editor], got %v", claims["roles"])
+	}
+	if int64(claims["iat"].(float64)) != ta.Clock.Now().Unix() {
+		t.Errorf("Expected iat to come from the frozen clock")
+	}
+}
+
+func TestClockControlsExpiry(t *testing.T) {
+	ta := New(t)
+	ta.SeedUsers("bob")
+
+	tokens := ta.LoginAs("bob")
+
+	ta.Clock.Advance(14 * time.Minute)
+	if _, err := ta.ValidateAccessToken(tokens.AccessToken); err != nil {
+		t.Fatalf("Expected token to be valid before expiry, got %v", err)
+	}
+
+	ta.Clock.Advance(2 * time.Minute)
+	if _, err := ta.ValidateAccessToken(tokens.AccessToken); err == nil {
+		t.Fatal("Expected token to be expired after advancing the clock past the TTL")
+	}
+}
+
+func TestUsesFastHashing(t *testing.T) {
+	ta := New(t)
+	user := ta.SeedUsers("carol")[0]
+
+	params := fmt.Sprintf("$m=%d,t=%d,p=%d$", auth.TestHashParams.Memory,
+		auth.TestHashParams.Iterations, auth.TestHashParams.Parallelism)
+	if !strings.Contains(user.PasswordHash, params) {
+		t.Errorf("Expected the password to be hashed with %s, got %s", params, user.PasswordHash)
+	}
+}
@@ -0,0 +1,72 @@
+package authtest
+
+import (
+	"context"
+	"net/url"
+	"sync"
+
+	"github.com/pragneshbagary/go-auth/pkg/auth"
+)
+
+// CapturedEmails is an auth.EmailSender that records messages instead of
+// sending them, so tests can complete email flows without a mail server.
+type CapturedEmails struct {
+	mu       sync.Mutex
+	messages []auth.EmailMessage
+}
+
+// NewCapturedEmails creates an empty CapturedEmails.
+func NewCapturedEmails() *CapturedEmails {
+	return &CapturedEmails{}
+}
+
+// SendEmail records message.
+func (c *CapturedEmails) SendEmail(ctx context.Context, message *auth.EmailMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, *message)
+	return nil
+}
+
+// Messages returns the recorded messages, oldest first.
+func (c *CapturedEmails) Messages() []auth.EmailMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]auth.EmailMessage(nil), c.messages...)
+}
+
+// Last returns the last message of kind sent to email. An empty kind
+// matches any message.
+func (c *CapturedEmails) Last(email, kind string) (auth.EmailMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := len(c.messages) - 1; i >= 0; i-- {
+		message := c.messages[i]
+		if message.To == email && (kind == "" || message.Kind == kind) {
+			return message, true
+		}
+	}
+	return auth.EmailMessage{}, false
+}
+
+// LastResetTokenFor returns the reset token of the last password reset
+// email sent to email, for Users().ResetPassword.
+func (c *CapturedEmails) LastResetTokenFor(email string) (string, bool) {
+	message, ok := c.Last(email, auth.EmailPasswordReset)
+	if !ok {
+		return "", false
+	}
+	link, err := url.Parse(message.Link)
+	if err != nil {
+		return "", false
+	}
+	token := link.Query().Get(auth.ActionTokenParam)
+	return token, token != ""
+}
+
+// Clear drops the recorded messages.
+func (c *CapturedEmails) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = nil
+}
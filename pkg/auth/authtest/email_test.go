@@ -0,0 +1,47 @@
+package authtest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pragneshbagary/go-auth/pkg/auth"
+)
+
+func TestCapturedEmails(t *testing.T) {
+	ta := New(t)
+	user := ta.SeedUser("alice", "alice-password")
+	ctx := context.Background()
+
+	// Complete a password reset from the captured email
+	if err := ta.Emails().SendPasswordReset(ctx, "alice@example.test"); err != nil {
+		t.Fatalf("SendPasswordReset failed: %v", err)
+	}
+	if err := ta.Users().ResetPassword(ta.LastResetTokenFor("alice@example.test"), "new-password-123"); err != nil {
+		t.Fatalf("Expected the captured reset token to work, got %v", err)
+	}
+	if _, err := ta.Login("alice", "new-password-123", nil); err != nil {
+		t.Errorf("Expected login with the new password, got %v", err)
+	}
+
+	// Complete a verification from the captured email
+	if err := ta.Emails().SendVerification(ctx, user.ID); err != nil {
+		t.Fatalf("SendVerification failed: %v", err)
+	}
+	link := ta.LastLinkFor("alice@example.test", auth.EmailVerification)
+	if _, err := ta.Tokens().VerifyURL(httptest.NewRequest(http.MethodGet, link, nil), auth.PurposeEmailVerification); err != nil {
+		t.Errorf("Expected the captured verification link to verify, got %v", err)
+	}
+
+	if got := len(ta.Mail.Messages()); got != 2 {
+		t.Errorf("Expected 2 captured messages, got %d", got)
+	}
+	if _, ok := ta.Mail.LastResetTokenFor("bob@example.test"); ok {
+		t.Error("Expected no reset token for an address without email")
+	}
+	ta.Mail.Clear()
+	if _, ok := ta.Mail.Last("alice@example.test", ""); ok {
+		t.Error("Expected no messages after Clear")
+	}
+}
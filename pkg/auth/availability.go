@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Availability reasons reported by CheckAvailability.
+const (
+	AvailabilityTaken   = "taken"
+	AvailabilityInvalid = "invalid"
+	// AvailabilityReserved usernames were given up recently and are
+	// reserved for their former owner, see UsernameConfig.
+	AvailabilityReserved = "reserved"
+)
+
+// maxUsernameSuggestions is the number of alternatives offered for a taken username.
+const maxUsernameSuggestions = 3
+
+// commonEmailDomainTypos maps frequent misspellings to the intended domain.
+var commonEmailDomainTypos = map[string]string{
+	"gmial.com":   "gmail.com",
+	"gmai.com":    "gmail.com",
+	"gamil.com":   "gmail.com",
+	"gmail.co":    "gmail.com",
+	"gnail.com":   "gmail.com",
+	"hotmial.com": "hotmail.com",
+	"hotmal.com":  "hotmail.com",
+	"yaho.com":    "yahoo.com",
+	"yahooo.com":  "yahoo.com",
+	"outlok.com":  "outlook.com",
+	"iclod.com":   "icloud.com",
+}
+
+// FieldAvailability describes whether a single signup field can be used.
+type FieldAvailability struct {
+	Value       string   `json:"value"`
+	Available   bool     `json:"available"`
+	Reason      string   `json:"reason,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// Availability is the result of a signup pre-check. Fields that were not
+// checked are nil.
+type Availability struct {
+	Username *FieldAvailability `json:"username,omitempty"`
+	Email    *FieldAvailability `json:"email,omitempty"`
+}
+
+// CheckAvailability reports whether username and email can be used to
+// register, without attempting a registration. Empty arguments are skipped.
+// Taken usernames come with suggested alternatives; emails with a likely
+// misspelled domain come with a corrected suggestion.
+//
+// The result reveals whether an account exists, so expose it to clients
+// only behind rate limiting, e.g. with AvailabilityHandler.
+func (u *Users) CheckAvailability(username, email string) (*Availability, error) {
+	username = strings.TrimSpace(username)
+	email = strings.TrimSpace(email)
+	if username == "" && email == "" {
+		return nil, ErrValidationError("username or email")
+	}
+
+	result := &Availability{}
+	if username != "" {
+		result.Username = u.checkUsername(username)
+	}
+	if email != "" {
+		result.Email = u.checkEmail(email)
+	}
+	return result, nil
+}
+
+// checkUsername reports the availability of username with suggestions if taken.
+func (u *Users) checkUsername(username string) *FieldAvailability {
+	field := &FieldAvailability{Value: username, Available: true}
+	if _, err := u.storage.GetUserByUsername(username); err == nil {
+		field.Reason = AvailabilityTaken
+	} else if owner, _ := u.usernames.previousOwner(username); owner != "" {
+		field.Reason = AvailabilityReserved
+	} else {
+		return field
+	}
+
+	field.Available = false
+	field.Suggestions = u.suggestUsernames(username)
+	return field
+}
+
+// suggestUsernames returns free usernames derived from base.
+func (u *Users) suggestUsernames(base string) []string {
+	candidates := []string{
+		base + strconv.Itoa(nowFrom(u.clock).Year()),
+		base + "_",
+	}
+	for i := 0; i < 5; i++ {
+		n, err := rand.Int(rand.Reader, big.NewInt(9000))
+		if err != nil {
+			break
+		}
+		candidates = append(candidates, fmt.Sprintf("%s%d", base, n.Int64()+1000))
+	}
+
+	var suggestions []string
+	seen := make(map[string]bool)
+	for _, candidate := range candidates {
+		if len(suggestions) == maxUsernameSuggestions {
+			break
+		}
+		if seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		if _, err := u.storage.GetUserByUsername(candidate); err != nil {
+			suggestions = append(suggestions, candidate)
+		}
+	}
+	return suggestions
+}
+
+// checkEmail reports the availability of email with a domain correction if
+// it looks misspelled.
+func (u *Users) checkEmail(email string) *FieldAvailability {
+	field := &FieldAvailability{Value: email, Available: true}
+
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" || domain == "" {
+		field.Available = false
+		field.Reason = AvailabilityInvalid
+		return field
+	}
+	if fixed, typo := commonEmailDomainTypos[strings.ToLower(domain)]; typo {
+		field.Suggestions = []string{local + "@" + fixed}
+	}
+
+	if _, err := u.storage.GetUserByEmail(email); err == nil {
+		field.Available = false
+		field.Reason = AvailabilityTaken
+	}
+	return field
+}
+
+// AvailabilityHandlerConfig configures AvailabilityHandler.
+type AvailabilityHandlerConfig struct {
+	// RateLimiter limits checks per client. Defaults to 30 requests per minute.
+	RateLimiter RateLimiter
+	// KeyFunc identifies the client for rate limiting. Defaults to the remote IP.
+	KeyFunc func(r *http.Request) string
+}
+
+// AvailabilityHandler returns a rate-limited HTTP handler for signup forms.
+// It answers GET requests with "username" and/or "email" query parameters
+// with the JSON-encoded Availability.
+func (a *Auth) AvailabilityHandler(config AvailabilityHandlerConfig) http.Handler {
+	if config.RateLimiter == nil {
+		config.RateLimiter = NewRateLimiter(30, time.Minute, a.clock)
+	}
+	if config.KeyFunc == nil {
+		config.KeyFunc = clientIP
+	}
+	users := a.Users()
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if allowed, retryAfter := config.RateLimiter.Allow(config.KeyFunc(r)); !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			a.writeError(w, r, ErrRateLimitExceeded())
+			return
+		}
+
+		query := r.URL.Query()
+		result, err := users.CheckAvailability(query.Get("username"), query.Get("email"))
+		if err != nil {
+			a.writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := json.NewEncoder(w).Encode(result); err != nil {
+			a.logger.Error("Failed to encode availability response", map[string]interface{}{
+				"error": err,
+			})
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckAvailability(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUsers("alice")
+	users := ta.Users()
+
+	result, err := users.CheckAvailability("alice", "alice@example.test")
+	if err != nil {
+		t.Fatalf("Failed to check availability: %v", err)
+	}
+	if result.Username.Available || result.Username.Reason != AvailabilityTaken {
+		t.Errorf("Expected username to be taken, got %+v", result.Username)
+	}
+	if len(result.Username.Suggestions) == 0 {
+		t.Error("Expected suggestions for a taken username")
+	}
+	for _, s := range result.Username.Suggestions {
+		if !strings.HasPrefix(s, "alice") || s == "alice" {
+			t.Errorf("Unexpected suggestion %q", s)
+		}
+	}
+	if result.Email.Available || result.Email.Reason != AvailabilityTaken {
+		t.Errorf("Expected email to be taken, got %+v", result.Email)
+	}
+
+	result, err = users.CheckAvailability("bob", "")
+	if err != nil {
+		t.Fatalf("Failed to check availability: %v", err)
+	}
+	if !result.Username.Available || result.Email != nil {
+		t.Errorf("Expected only a free username in result, got %+v", result)
+	}
+
+	result, _ = users.CheckAvailability("", "bob@gmial.com")
+	if !result.Email.Available || len(result.Email.Suggestions) != 1 || result.Email.Suggestions[0] != "bob@gmail.com" {
+		t.Errorf("Expected domain typo suggestion, got %+v", result.Email)
+	}
+
+	result, _ = users.CheckAvailability("", "not-an-email")
+	if result.Email.Available || result.Email.Reason != AvailabilityInvalid {
+		t.Errorf("Expected invalid email, got %+v", result.Email)
+	}
+
+	if _, err := users.CheckAvailability("", ""); err == nil {
+		t.Error("Expected an error when nothing is checked")
+	}
+}
+
+func TestAvailabilityHandler(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUsers("alice")
+	handler := ta.AvailabilityHandler(AvailabilityHandlerConfig{
+		RateLimiter: NewRateLimiter(1, time.Minute, ta.Clock),
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/availability?username=alice", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	var result Availability
+	if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if result.Username == nil || result.Username.Available {
+		t.Errorf("Expected username to be reported as taken, got %+v", result.Username)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/availability?username=bob", nil))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 once the limit is reached, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header")
+	}
+}
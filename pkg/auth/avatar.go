@@ -0,0 +1,288 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// avatarMetadataKey is the user metadata key holding the avatar's "url" and
+// blob "key". The image itself lives in the BlobStore.
+const avatarMetadataKey = "avatar"
+
+// BlobStore stores binary objects such as avatars.
+type BlobStore interface {
+	// Put stores the content of r under key and returns the URL it is served from.
+	Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error)
+	// Delete removes the object stored under key.
+	Delete(ctx context.Context, key string) error
+}
+
+// AvatarProcessor transforms an uploaded image before it is stored, e.g. to
+// resize it or strip EXIF data. It returns the new image and its content type.
+type AvatarProcessor func(ctx context.Context, image []byte, contentType string) ([]byte, string, error)
+
+// AvatarConfig configures profile pictures.
+type AvatarConfig struct {
+	// Store holds the images. Avatars are disabled when nil.
+	Store BlobStore
+	// MaxSize of uploads in bytes. Defaults to 5 MB.
+	MaxSize int64
+	// ContentTypes lists the accepted image types. Defaults to PNG, JPEG,
+	// GIF and WebP. Uploads are sniffed and must match the declared type.
+	ContentTypes []string
+	// Process runs on every upload before it is stored.
+	Process AvatarProcessor
+}
+
+// avatarExtensions maps the default content types to file extensions.
+var avatarExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+// SetAvatar stores the image read from r as the user's avatar and returns its URL.
+func (u *Users) SetAvatar(userID string, r io.Reader, contentType string) (string, error) {
+	return u.SetAvatarContext(context.Background(), userID, r, contentType)
+}
+
+// SetAvatarContext is like SetAvatar but passes ctx to the BlobStore and processor.
+func (u *Users) SetAvatarContext(ctx context.Context, userID string, r io.Reader, contentType string) (string, error) {
+	config := u.avatars
+	if config.Store == nil {
+		return "", ErrConfigError("avatar store")
+	}
+	if userID == "" {
+		return "", ErrValidationError("user ID")
+	}
+	user, err := u.storage.GetUserByID(userID)
+	if err != nil {
+		return "", ErrUserNotFound()
+	}
+
+	image, contentType, err := readAvatar(r, contentType, config)
+	if err != nil {
+		return "", err
+	}
+	if config.Process != nil {
+		if image, contentType, err = config.Process(ctx, image, contentType); err != nil {
+			return "", WrapError(err, ErrCodeInternalError, "Failed to process avatar")
+		}
+	}
+
+	key := fmt.Sprintf("avatars/%s/%s%s", userID, uuid.New().String(), avatarExtensions[contentType])
+	url, err := config.Store.Put(ctx, key, bytes.NewReader(image), contentType)
+	if err != nil {
+		return "", WrapStorageError(err)
+	}
+
+	metadata := make(map[string]interface{}, len(user.Metadata)+1)
+	for k, v := range user.Metadata {
+		metadata[k] = v
+	}
+	metadata[avatarMetadataKey] = map[string]interface{}{"url": url, "key": key}
+	if err := u.storage.UpdateUser(userID, storage.UserUpdates{Metadata: metadata}); err != nil {
+		u.deleteAvatar(ctx, userID, key)
+		return "", WrapDatabaseError(err)
+	}
+
+	if previous := avatarKey(user.Metadata); previous != "" {
+		u.deleteAvatar(ctx, userID, previous)
+	}
+	return url, nil
+}
+
+// RemoveAvatar deletes the user's avatar.
+func (u *Users) RemoveAvatar(userID string) error {
+	config := u.avatars
+	if config.Store == nil {
+		return ErrConfigError("avatar store")
+	}
+	if userID == "" {
+		return ErrValidationError("user ID")
+	}
+	user, err := u.storage.GetUserByID(userID)
+	if err != nil {
+		return ErrUserNotFound()
+	}
+	key := avatarKey(user.Metadata)
+	if key == "" {
+		return nil
+	}
+
+	metadata := make(map[string]interface{}, len(user.Metadata))
+	for k, v := range user.Metadata {
+		if k != avatarMetadataKey {
+			metadata[k] = v
+		}
+	}
+	if err := u.storage.UpdateUser(userID, storage.UserUpdates{Metadata: metadata}); err != nil {
+		return WrapDatabaseError(err)
+	}
+	u.deleteAvatar(context.Background(), userID, key)
+	return nil
+}
+
+// deleteAvatar removes a replaced avatar. Failures only leave an orphaned
+// blob behind, so they are logged rather than returned.
+func (u *Users) deleteAvatar(ctx context.Context, userID, key string) {
+	if err := u.avatars.Store.Delete(ctx, key); err != nil {
+		u.logger.Warn("Failed to delete avatar", map[string]interface{}{
+			"user_id": userID,
+			"key":     key,
+			"error":   err,
+		})
+	}
+}
+
+// readAvatar reads an upload, enforcing the size limit and checking that the
+// content matches an accepted content type.
+func readAvatar(r io.Reader, contentType string, config AvatarConfig) ([]byte, string, error) {
+	maxSize := config.MaxSize
+	if maxSize <= 0 {
+		maxSize = 5 << 20
+	}
+	allowed := config.ContentTypes
+	if len(allowed) == 0 {
+		allowed = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+	}
+
+	contentType = strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	accepted := false
+	for _, t := range allowed {
+		if t == contentType {
+			accepted = true
+			break
+		}
+	}
+	if !accepted {
+		return nil, "", NewAuthErrorWithDetails(ErrCodeValidationError, "Validation failed",
+			fmt.Sprintf("Unsupported avatar content type: %s", contentType))
+	}
+
+	image, err := io.ReadAll(io.LimitReader(r, maxSize+1))
+	if err != nil {
+		return nil, "", WrapError(err, ErrCodeValidationError, "Failed to read avatar")
+	}
+	if int64(len(image)) > maxSize {
+		return nil, "", NewAuthErrorWithDetails(ErrCodeValidationError, "Validation failed",
+			fmt.Sprintf("Avatar must not exceed %d bytes", maxSize))
+	}
+	if len(image) == 0 || http.DetectContentType(image) != contentType {
+		return nil, "", NewAuthErrorWithDetails(ErrCodeValidationError, "Validation failed",
+			"Avatar content does not match its content type")
+	}
+	return image, contentType, nil
+}
+
+// avatarKey returns the blob key of the avatar stored in metadata.
+func avatarKey(metadata map[string]interface{}) string {
+	avatar, _ := metadata[avatarMetadataKey].(map[string]interface{})
+	key, _ := avatar["key"].(string)
+	return key
+}
+
+// FileBlobStore stores blobs in a directory, e.g. one served by a static
+// file server or CDN origin.
+type FileBlobStore struct {
+	dir     string
+	baseURL string
+}
+
+// NewFileBlobStore creates a BlobStore writing to dir. URLs are baseURL
+// followed by the blob key.
+func NewFileBlobStore(dir, baseURL string) *FileBlobStore {
+	return &FileBlobStore{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// path returns the file path of key, rejecting keys escaping the directory.
+func (s *FileBlobStore) path(key string) (string, error) {
+	if !filepath.IsLocal(filepath.FromSlash(key)) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.dir, filepath.FromSlash(key)), nil
+}
+
+// Put writes the blob atomically through a temporary file.
+func (s *FileBlobStore) Put(_ context.Context, key string, r io.Reader, _ string) (string, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", err
+	}
+	return s.baseURL + "/" + key, nil
+}
+
+// Delete removes the blob. Missing blobs are not an error.
+func (s *FileBlobStore) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// S3Client is the subset of an S3 client used by S3BlobStore. Adapt the
+// AWS SDK or any S3-compatible client (MinIO, R2) to it; go-auth does not
+// depend on one.
+type S3Client interface {
+	PutObject(ctx context.Context, bucket, key string, body io.Reader, contentType string) error
+	DeleteObject(ctx context.Context, bucket, key string) error
+}
+
+// S3BlobStore stores blobs in an S3 bucket.
+type S3BlobStore struct {
+	client  S3Client
+	bucket  string
+	baseURL string
+}
+
+// NewS3BlobStore creates a BlobStore writing to bucket. URLs are baseURL
+// (e.g. the bucket's public or CDN URL) followed by the blob key.
+func NewS3BlobStore(client S3Client, bucket, baseURL string) *S3BlobStore {
+	return &S3BlobStore{client: client, bucket: bucket, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (s *S3BlobStore) Put(ctx context.Context, key string, r io.Reader, contentType string) (string, error) {
+	if err := s.client.PutObject(ctx, s.bucket, key, r, contentType); err != nil {
+		return "", err
+	}
+	return s.baseURL + "/" + key, nil
+}
+
+func (s *S3BlobStore) Delete(ctx context.Context, key string) error {
+	return s.client.DeleteObject(ctx, s.bucket, key)
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testPNG(t *testing.T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("Failed to encode image: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestSetAvatar(t *testing.T) {
+	ta := NewTestAuth(t)
+	dir := t.TempDir()
+	var processed int
+	ta.config.Avatars = AvatarConfig{
+		Store: NewFileBlobStore(dir, "https://cdn.example.com/"),
+		Process: func(ctx context.Context, image []byte, contentType string) ([]byte, string, error) {
+			processed++
+			return image, contentType, nil
+		},
+	}
+	user := ta.SeedUser("alice", "alice-password")
+
+	url, err := ta.Users().SetAvatar(user.ID, bytes.NewReader(testPNG(t)), "image/png")
+	if err != nil {
+		t.Fatalf("Failed to set avatar: %v", err)
+	}
+	if !strings.HasPrefix(url, "https://cdn.example.com/avatars/"+user.ID+"/") || processed != 1 {
+		t.Errorf("Unexpected avatar URL %q or processor calls %d", url, processed)
+	}
+	first := filepath.Join(dir, strings.TrimPrefix(url, "https://cdn.example.com/"))
+	if _, err := os.Stat(first); err != nil {
+		t.Fatalf("Expected avatar to be stored: %v", err)
+	}
+
+	// Replacing the avatar deletes the previous image
+	if _, err := ta.Users().SetAvatar(user.ID, bytes.NewReader(testPNG(t)), "image/png"); err != nil {
+		t.Fatalf("Failed to replace avatar: %v", err)
+	}
+	if _, err := os.Stat(first); !os.IsNotExist(err) {
+		t.Error("Expected previous avatar to be deleted")
+	}
+
+	if err := ta.Users().RemoveAvatar(user.ID); err != nil {
+		t.Fatalf("Failed to remove avatar: %v", err)
+	}
+	stored, _ := ta.storage.GetUserByID(user.ID)
+	if avatarKey(stored.Metadata) != "" {
+		t.Error("Expected avatar to be removed from the user")
+	}
+}
+
+func TestSetAvatarRejectsInvalidUploads(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.config.Avatars = AvatarConfig{Store: NewFileBlobStore(t.TempDir(), "/avatars"), MaxSize: 64}
+	user := ta.SeedUser("bob", "bob-password")
+
+	if _, err := ta.Users().SetAvatar(user.ID, strings.NewReader("<svg onload=alert(1)>"), "image/svg+xml"); err == nil {
+		t.Error("Expected unsupported content type to be rejected")
+	}
+	if _, err := ta.Users().SetAvatar(user.ID, strings.NewReader("not an image"), "image/png"); err == nil {
+		t.Error("Expected content not matching its type to be rejected")
+	}
+	large := append(testPNG(t), make([]byte, 64)...)
+	if _, err := ta.Users().SetAvatar(user.ID, bytes.NewReader(large), "image/png"); err == nil {
+		t.Error("Expected upload above MaxSize to be rejected")
+	}
+}
+
+func TestFileBlobStoreRejectsEscapingKeys(t *testing.T) {
+	store := NewFileBlobStore(t.TempDir(), "/")
+	if _, err := store.Put(context.Background(), "../escape", strings.NewReader("x"), "text/plain"); err == nil {
+		t.Error("Expected key outside the directory to be rejected")
+	}
+}
@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// CaptchaProvider verifies CAPTCHA responses submitted by clients.
+type CaptchaProvider interface {
+	// Name identifies the provider, e.g. "recaptcha" or "hcaptcha".
+	Name() string
+	// SiteKey returns the public key clients use to render the challenge.
+	SiteKey() string
+	// Verify checks the response token produced by the client-side widget.
+	Verify(ctx context.Context, response string) error
+}
+
+// CaptchaConfig enables CAPTCHA escalation after repeated failed logins.
+// Escalation is disabled when Provider is nil.
+type CaptchaConfig struct {
+	Provider CaptchaProvider
+	// Threshold is the number of failed logins for a username after which a
+	// CAPTCHA is required. Defaults to 3.
+	Threshold int
+	// Window is how long failed logins are remembered. Defaults to 15 minutes.
+	Window time.Duration
+	// Store counts the failed logins. Use a shared store such as
+	// NewRedisCounterStore when running several instances, so that failures
+	// on one count on all. Defaults to an in-memory store per instance.
+	Store CounterStore
+}
+
+// captchaGuard counts failed logins per username and decides when a CAPTCHA is required.
+type captchaGuard struct {
+	config CaptchaConfig
+	logger *Logger
+}
+
+// newCaptchaGuard returns nil when no provider is configured.
+func newCaptchaGuard(config CaptchaConfig, clock Clock, logger *Logger) *captchaGuard {
+	if config.Provider == nil {
+		return nil
+	}
+	if config.Threshold <= 0 {
+		config.Threshold = 3
+	}
+	if config.Window == 0 {
+		config.Window = 15 * time.Minute
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryCounterStore(clock)
+	}
+	return &captchaGuard{config: config, logger: logger}
+}
+
+// captchaKey is the counter key of the failed logins of username.
+func captchaKey(username string) string {
+	return "login_failures:" + username
+}
+
+// required reports whether the next login for username must pass a CAPTCHA.
+// It does not require one while the store fails.
+func (g *captchaGuard) required(username string) bool {
+	if g == nil {
+		return false
+	}
+	count, err := g.config.Store.Count(captchaKey(username))
+	if err != nil {
+		g.storeError("count", err)
+		return false
+	}
+	return count >= int64(g.config.Threshold)
+}
+
+// fail records a failed login for username.
+func (g *captchaGuard) fail(username string) {
+	if g == nil {
+		return
+	}
+	if _, _, err := g.config.Store.Increment(captchaKey(username), g.config.Window); err != nil {
+		g.storeError("increment", err)
+	}
+}
+
+// reset forgets the failed logins of username.
+func (g *captchaGuard) reset(username string) {
+	if g == nil {
+		return
+	}
+	if err := g.config.Store.Reset(captchaKey(username)); err != nil {
+		g.storeError("reset", err)
+	}
+}
+
+// storeError logs a failed operation on the failure counter store.
+func (g *captchaGuard) storeError(operation string, err error) {
+	if g.logger != nil {
+		g.logger.Warn("Login failure counter unavailable", map[string]interface{}{
+			"operation": operation,
+			"error":     err,
+		})
+	}
+}
+
+// challengeError returns the error telling clients which challenge to render.
+func (g *captchaGuard) challengeError() *AuthError {
+	return ErrCaptchaRequired(g.config.Provider.Name() + ":" + g.config.Provider.SiteKey())
+}
+
+// LoginWithCaptcha authenticates a user whose login requires a CAPTCHA.
+// The response is verified with the configured provider before the
+// credentials are checked.
+func (a *Auth) LoginWithCaptcha(username, password, captchaResponse string) (*LoginResult, error) {
+	return a.LoginWithCaptchaContext(context.Background(), username, password, captchaResponse, nil)
+}
+
+// LoginWithCaptchaContext is like LoginWithCaptcha but accepts custom claims
+// and passes ctx to the provider and registered hooks.
+func (a *Auth) LoginWithCaptchaContext(ctx context.Context, username, password, captchaResponse string, customClaims map[string]interface{}) (*LoginResult, error) {
+	if a.captcha == nil {
+		return a.login(ctx, username, password, customClaims, false)
+	}
+
+	if err := a.captcha.config.Provider.Verify(ctx, captchaResponse); err != nil {
+		a.logger.Warn("Login rejected: CAPTCHA verification failed", map[string]interface{}{
+			"username": username,
+			"provider": a.captcha.config.Provider.Name(),
+			"error":    err,
+		})
+		return nil, a.captcha.challengeError()
+	}
+
+	return a.login(ctx, username, password, customClaims, true)
+}
+
+// SiteVerifyCaptcha verifies CAPTCHA responses against a siteverify endpoint
+// as used by reCAPTCHA and hCaptcha.
+type SiteVerifyCaptcha struct {
+	name      string
+	siteKey   string
+	secret    string
+	verifyURL string
+
+	// MinScore rejects reCAPTCHA v3 responses scoring below it when set.
+	MinScore float64
+	// Hostname rejects responses solved on another site when set.
+	Hostname string
+	// HTTPClient is used for verification requests. Defaults to a client with a 10 second timeout.
+	HTTPClient *http.Client
+}
+
+// NewReCaptcha creates a Google reCAPTCHA provider.
+func NewReCaptcha(siteKey, secret string) *SiteVerifyCaptcha {
+	return NewSiteVerifyCaptcha("recaptcha", siteKey, secret, "https://www.google.com/recaptcha/api/siteverify")
+}
+
+// NewHCaptcha creates an hCaptcha provider.
+func NewHCaptcha(siteKey, secret string) *SiteVerifyCaptcha {
+	return NewSiteVerifyCaptcha("hcaptcha", siteKey, secret, "https://api.hcaptcha.com/siteverify")
+}
+
+// NewSiteVerifyCaptcha creates a provider for any siteverify-compatible service.
+func NewSiteVerifyCaptcha(name, siteKey, secret, verifyURL string) *SiteVerifyCaptcha {
+	return &SiteVerifyCaptcha{
+		name:       name,
+		siteKey:    siteKey,
+		secret:     secret,
+		verifyURL:  verifyURL,
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name returns the provider name.
+func (c *SiteVerifyCaptcha) Name() string {
+	return c.name
+}
+
+// SiteKey returns the public site key.
+func (c *SiteVerifyCaptcha) SiteKey() string {
+	return c.siteKey
+}
+
+// siteVerifyResponse is the siteverify response body shared by reCAPTCHA and hCaptcha.
+type siteVerifyResponse struct {
+	Success    bool     `json:"success"`
+	Score      *float64 `json:"score,omitempty"`
+	Hostname   string   `json:"hostname"`
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// Verify checks response with the siteverify endpoint.
+func (c *SiteVerifyCaptcha) Verify(ctx context.Context, response string) error {
+	if response == "" {
+		return fmt.Errorf("%s: missing response", c.name)
+	}
+
+	form := url.Values{"secret": {c.secret}, "response": {response}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("%s: %w", c.name, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	client := c.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: verification request failed: %w", c.name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: verification returned status %d", c.name, resp.StatusCode)
+	}
+
+	var result siteVerifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("%s: invalid verification response: %w", c.name, err)
+	}
+	if !result.Success {
+		return fmt.Errorf("%s: verification failed: %s", c.name, strings.Join(result.ErrorCodes, ", "))
+	}
+	if c.Hostname != "" && result.Hostname != c.Hostname {
+		return fmt.Errorf("%s: response solved on unexpected host %q", c.name, result.Hostname)
+	}
+	if c.MinScore > 0 && result.Score != nil && *result.Score < c.MinScore {
+		return fmt.Errorf("%s: score %.2f below minimum %.2f", c.name, *result.Score, c.MinScore)
+	}
+	return nil
+}
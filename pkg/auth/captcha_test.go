@@ -0,0 +1,165 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeCaptcha struct {
+	valid string
+}
+
+func (f *fakeCaptcha) Name() string    { return "fake" }
+func (f *fakeCaptcha) SiteKey() string { return "site-key" }
+func (f *fakeCaptcha) Verify(ctx context.Context, response string) error {
+	if response != f.valid {
+		return errors.New("invalid response")
+	}
+	return nil
+}
+
+func TestCaptchaGuard(t *testing.T) {
+	clock := NewFrozenClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	guard := newCaptchaGuard(CaptchaConfig{Provider: &fakeCaptcha{}, Threshold: 2, Window: time.Minute}, clock, nil)
+
+	guard.fail("alice")
+	if guard.required("alice") {
+		t.Error("CAPTCHA should not be required below the threshold")
+	}
+	guard.fail("alice")
+	if !guard.required("alice") {
+		t.Error("CAPTCHA should be required at the threshold")
+	}
+	if guard.required("bob") {
+		t.Error("Failures should be tracked per username")
+	}
+
+	clock.Advance(2 * time.Minute)
+	if guard.required("alice") {
+		t.Error("Failures should expire after the window")
+	}
+
+	guard.fail("alice")
+	guard.fail("alice")
+	guard.reset("alice")
+	if guard.required("alice") {
+		t.Error("Reset should clear failures")
+	}
+
+	if newCaptchaGuard(CaptchaConfig{}, clock, nil) != nil {
+		t.Error("Guard should be disabled without a provider")
+	}
+}
+
+func TestCaptchaGuard_SharedStore(t *testing.T) {
+	clock := NewFrozenClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	redis := newFakeCounterRedis(clock)
+	config := CaptchaConfig{Provider: &fakeCaptcha{}, Threshold: 2, Window: time.Minute, Store: NewRedisCounterStore(redis, "")}
+	first := newCaptchaGuard(config, clock, nil)
+	second := newCaptchaGuard(config, clock, nil)
+
+	// Failures on one instance count on the other
+	first.fail("alice")
+	second.fail("alice")
+	if !first.required("alice") || !second.required("alice") {
+		t.Error("CAPTCHA should be required on both instances")
+	}
+	second.reset("alice")
+	if first.required("alice") {
+		t.Error("Reset should clear failures on both instances")
+	}
+
+	// Logins are not blocked while the store fails
+	first.fail("bob")
+	first.fail("bob")
+	redis.down = true
+	if first.required("bob") {
+		t.Error("CAPTCHA should not be required while the store fails")
+	}
+}
+
+func TestLoginCaptchaEscalation(t *testing.T) {
+	a, err := NewWithConfig(&AuthConfig{
+		JWTSecret: "test-secret",
+		LogLevel:  "error",
+		Captcha:   CaptchaConfig{Provider: &fakeCaptcha{valid: "solved"}, Threshold: 2},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create auth: %v", err)
+	}
+	if _, err := a.Register(RegisterRequest{Username: "alice", Password: "password123"}); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := a.Login("alice", "wrong-password", nil); !errors.Is(err, ErrInvalidCredentials()) {
+			t.Fatalf("Expected invalid credentials, got %v", err)
+		}
+	}
+
+	_, err = a.Login("alice", "password123", nil)
+	var authErr *AuthError
+	if !errors.As(err, &authErr) || authErr.Code != ErrCodeCaptchaRequired {
+		t.Fatalf("Expected CAPTCHA_REQUIRED, got %v", err)
+	}
+	if authErr.Details != "fake:site-key" {
+		t.Errorf("Expected challenge reference, got %q", authErr.Details)
+	}
+
+	if _, err := a.LoginWithCaptcha("alice", "password123", "wrong"); !errors.Is(err, ErrCaptchaRequired("")) {
+		t.Errorf("Expected failed CAPTCHA to be rejected, got %v", err)
+	}
+	if _, err := a.LoginWithCaptcha("alice", "password123", "solved"); err != nil {
+		t.Fatalf("Expected login with solved CAPTCHA to succeed, got %v", err)
+	}
+
+	// A successful login clears the failures
+	if _, err := a.Login("alice", "password123", nil); err != nil {
+		t.Errorf("Expected login without CAPTCHA after success, got %v", err)
+	}
+}
+
+func TestSiteVerifyCaptcha(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("secret") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.FormValue("response") {
+		case "ok":
+			fmt.Fprint(w, `{"success":true,"hostname":"example.com","score":0.9}`)
+		case "bot":
+			fmt.Fprint(w, `{"success":true,"hostname":"example.com","score":0.1}`)
+		default:
+			fmt.Fprint(w, `{"success":false,"error-codes":["invalid-input-response"]}`)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewSiteVerifyCaptcha("recaptcha", "site", "secret", server.URL)
+	provider.MinScore = 0.5
+	provider.Hostname = "example.com"
+
+	if err := provider.Verify(context.Background(), "ok"); err != nil {
+		t.Errorf("Expected valid response to verify, got %v", err)
+	}
+	if err := provider.Verify(context.Background(), "bot"); err == nil {
+		t.Error("Expected low score to be rejected")
+	}
+	if err := provider.Verify(context.Background(), "bad"); err == nil {
+		t.Error("Expected failed verification to be rejected")
+	}
+	if err := provider.Verify(context.Background(), ""); err == nil {
+		t.Error("Expected empty response to be rejected")
+	}
+
+	provider.Hostname = "other.com"
+	if err := provider.Verify(context.Background(), "ok"); err == nil {
+		t.Error("Expected hostname mismatch to be rejected")
+	}
+}
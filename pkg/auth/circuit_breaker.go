@@ -0,0 +1,371 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// CircuitState is the state of a CircuitBreaker.
+type CircuitState int
+
+const (
+	// CircuitClosed lets calls through and counts consecutive failures.
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects calls until the open timeout has passed.
+	CircuitOpen
+	// CircuitHalfOpen lets a limited number of probe calls through; their
+	// success closes the circuit, a failure opens it again.
+	CircuitHalfOpen
+)
+
+// String returns the name of the state.
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half_open"
+	}
+	return "unknown"
+}
+
+// CircuitBreakerConfig configures a CircuitBreaker.
+type CircuitBreakerConfig struct {
+	// FailureThreshold is the number of consecutive failures that opens the
+	// circuit. The storage circuit breaker is off when zero.
+	FailureThreshold int
+	// OpenTimeout is how long an open circuit rejects calls before probing
+	// the dependency again. Defaults to 30 seconds.
+	OpenTimeout time.Duration
+	// HalfOpenProbes is the number of probe calls let through at once while
+	// half-open; that many successes close the circuit. Defaults to 1.
+	HalfOpenProbes int
+	// IsFailure decides which errors count as failures. By default every
+	// error does except "user not found" and "user already exists", which
+	// are answers rather than outages.
+	IsFailure func(err error) bool
+}
+
+// CircuitBreakerStats is a snapshot of a CircuitBreaker.
+type CircuitBreakerStats struct {
+	Name                string     `json:"name"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	OpenedAt            *time.Time `json:"opened_at,omitempty"`
+	// Opened counts how often the circuit opened, Rejected the calls it
+	// refused while open.
+	Opened   int64 `json:"opened"`
+	Rejected int64 `json:"rejected"`
+}
+
+// CircuitBreaker stops calling a failing dependency for a while, so that a
+// struggling database is not hammered by retries during an incident.
+type CircuitBreaker struct {
+	name   string
+	config CircuitBreakerConfig
+	clock  Clock
+	logger *Logger
+
+	mu        sync.Mutex
+	state     CircuitState
+	failures  int
+	openedAt  time.Time
+	probes    int
+	successes int
+	opened    int64
+	rejected  int64
+}
+
+// NewCircuitBreaker creates a closed circuit breaker named name.
+func NewCircuitBreaker(name string, config CircuitBreakerConfig) *CircuitBreaker {
+	return newCircuitBreaker(name, config, nil, nil)
+}
+
+// newCircuitBreaker creates a circuit breaker that logs state changes.
+func newCircuitBreaker(name string, config CircuitBreakerConfig, clock Clock, logger *Logger) *CircuitBreaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = 5
+	}
+	if config.OpenTimeout <= 0 {
+		config.OpenTimeout = 30 * time.Second
+	}
+	if config.HalfOpenProbes <= 0 {
+		config.HalfOpenProbes = 1
+	}
+	if config.IsFailure == nil {
+		config.IsFailure = isStorageFailure
+	}
+	return &CircuitBreaker{name: name, config: config, clock: clock, logger: logger}
+}
+
+// isStorageFailure reports whether err signals a storage outage rather than
+// an answer such as a missing user. It is the default
+// CircuitBreakerConfig.IsFailure.
+func isStorageFailure(err error) bool {
+	return err != nil && !isUserNotFound(err) && err.Error() != "user already exists"
+}
+
+// Do calls fn unless the circuit is open, in which case it returns a
+// CIRCUIT_OPEN error without calling fn.
+func (b *CircuitBreaker) Do(fn func() error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := fn()
+	b.record(err)
+	return err
+}
+
+// circuitCall is Do for calls returning a value.
+func circuitCall[T any](b *CircuitBreaker, fn func() (T, error)) (T, error) {
+	var result T
+	err := b.Do(func() error {
+		var err error
+		result, err = fn()
+		return err
+	})
+	return result, err
+}
+
+// allow admits a call or rejects it while the circuit is open.
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == CircuitOpen {
+		if nowFrom(b.clock).Sub(b.openedAt) < b.config.OpenTimeout {
+			b.rejected++
+			return ErrCircuitOpen(b.name)
+		}
+		b.transition(CircuitHalfOpen)
+	}
+	if b.state == CircuitHalfOpen {
+		if b.probes >= b.config.HalfOpenProbes {
+			b.rejected++
+			return ErrCircuitOpen(b.name)
+		}
+		b.probes++
+	}
+	return nil
+}
+
+// record updates the state with the outcome of an admitted call.
+func (b *CircuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	failed := b.config.IsFailure(err)
+	switch b.state {
+	case CircuitClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.config.FailureThreshold {
+			b.transition(CircuitOpen)
+		}
+	case CircuitHalfOpen:
+		b.probes--
+		if failed {
+			b.transition(CircuitOpen)
+			return
+		}
+		b.successes++
+		if b.successes >= b.config.HalfOpenProbes {
+			b.transition(CircuitClosed)
+		}
+	case CircuitOpen:
+		// A call admitted before the circuit opened; it changes nothing
+	}
+}
+
+// transition moves to state and resets its counters. The caller holds b.mu.
+func (b *CircuitBreaker) transition(state CircuitState) {
+	previous := b.state
+	b.state = state
+	b.probes = 0
+	b.successes = 0
+	switch state {
+	case CircuitOpen:
+		b.openedAt = nowFrom(b.clock)
+		b.opened++
+	case CircuitClosed:
+		b.failures = 0
+	}
+	if b.logger != nil {
+		log := b.logger.Info
+		if state == CircuitOpen {
+			log = b.logger.Warn
+		}
+		log("Circuit breaker state changed", map[string]interface{}{
+			"circuit":  b.name,
+			"from":     previous.String(),
+			"to":       state.String(),
+			"failures": b.failures,
+		})
+	}
+}
+
+// State returns the current state. An open circuit whose timeout passed is
+// reported as open until the next call probes the dependency.
+func (b *CircuitBreaker) State() CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Stats returns a snapshot of the circuit breaker.
+func (b *CircuitBreaker) Stats() CircuitBreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	stats := CircuitBreakerStats{
+		Name:                b.name,
+		State:               b.state.String(),
+		ConsecutiveFailures: b.failures,
+		Opened:              b.opened,
+		Rejected:            b.rejected,
+	}
+	if b.state != CircuitClosed {
+		openedAt := b.openedAt
+		stats.OpenedAt = &openedAt
+	}
+	return stats
+}
+
+// circuitStorage guards the storage calls of Auth with a circuit breaker.
+type circuitStorage struct {
+	storage.EnhancedStorage
+	breaker *CircuitBreaker
+}
+
+func (s *circuitStorage) CreateUser(user models.User) error {
+	return s.breaker.Do(func() error { return s.EnhancedStorage.CreateUser(user) })
+}
+
+func (s *circuitStorage) GetUserByID(userID string) (*models.User, error) {
+	return circuitCall(s.breaker, func() (*models.User, error) { return s.EnhancedStorage.GetUserByID(userID) })
+}
+
+func (s *circuitStorage) GetUserByUsername(username string) (*models.User, error) {
+	return circuitCall(s.breaker, func() (*models.User, error) { return s.EnhancedStorage.GetUserByUsername(username) })
+}
+
+func (s *circuitStorage) GetUserByEmail(email string) (*models.User, error) {
+	return circuitCall(s.breaker, func() (*models.User, error) { return s.EnhancedStorage.GetUserByEmail(email) })
+}
+
+func (s *circuitStorage) UpdateUser(userID string, updates storage.UserUpdates) error {
+	return s.breaker.Do(func() error { return s.EnhancedStorage.UpdateUser(userID, updates) })
+}
+
+func (s *circuitStorage) UpdatePassword(userID string, passwordHash string) error {
+	return s.breaker.Do(func() error { return s.EnhancedStorage.UpdatePassword(userID, passwordHash) })
+}
+
+func (s *circuitStorage) DeleteUser(userID string) error {
+	return s.breaker.Do(func() error { return s.EnhancedStorage.DeleteUser(userID) })
+}
+
+func (s *circuitStorage) ListUsers(limit, offset int) ([]*models.User, error) {
+	return circuitCall(s.breaker, func() ([]*models.User, error) { return s.EnhancedStorage.ListUsers(limit, offset) })
+}
+
+func (s *circuitStorage) BlacklistToken(tokenID string, expiresAt time.Time) error {
+	return s.breaker.Do(func() error { return s.EnhancedStorage.BlacklistToken(tokenID, expiresAt) })
+}
+
+func (s *circuitStorage) IsTokenBlacklisted(tokenID string) (bool, error) {
+	return circuitCall(s.breaker, func() (bool, error) { return s.EnhancedStorage.IsTokenBlacklisted(tokenID) })
+}
+
+func (s *circuitStorage) CleanupExpiredTokens() error {
+	return s.breaker.Do(s.EnhancedStorage.CleanupExpiredTokens)
+}
+
+func (s *circuitStorage) Ping() error {
+	return s.breaker.Do(s.EnhancedStorage.Ping)
+}
+
+func (s *circuitStorage) unwrapStorage() storage.EnhancedStorage {
+	return s.EnhancedStorage
+}
+
+// circuitCache guards a Cache with a circuit breaker.
+type circuitCache struct {
+	cache   Cache
+	breaker *CircuitBreaker
+}
+
+// NewCircuitBreakerCache wraps cache so that its calls fail fast with a
+// CIRCUIT_OPEN error while breaker is open. Register the breaker with
+// Monitor.RegisterCircuitBreaker to report it in health checks.
+func NewCircuitBreakerCache(cache Cache, breaker *CircuitBreaker) Cache {
+	return &circuitCache{cache: cache, breaker: breaker}
+}
+
+func (c *circuitCache) SetTokenValidation(tokenID string, user *models.User, ttl time.Duration) error {
+	return c.breaker.Do(func() error { return c.cache.SetTokenValidation(tokenID, user, ttl) })
+}
+
+func (c *circuitCache) GetTokenValidation(tokenID string) (*models.User, bool, error) {
+	var found bool
+	user, err := circuitCall(c.breaker, func() (*models.User, error) {
+		user, ok, err := c.cache.GetTokenValidation(tokenID)
+		found = ok
+		return user, err
+	})
+	return user, found, err
+}
+
+func (c *circuitCache) InvalidateToken(tokenID string) error {
+	return c.breaker.Do(func() error { return c.cache.InvalidateToken(tokenID) })
+}
+
+func (c *circuitCache) SetUser(userID string, user *models.User, ttl time.Duration) error {
+	return c.breaker.Do(func() error { return c.cache.SetUser(userID, user, ttl) })
+}
+
+func (c *circuitCache) GetUser(userID string) (*models.User, bool, error) {
+	var found bool
+	user, err := circuitCall(c.breaker, func() (*models.User, error) {
+		user, ok, err := c.cache.GetUser(userID)
+		found = ok
+		return user, err
+	})
+	return user, found, err
+}
+
+func (c *circuitCache) InvalidateUser(userID string) error {
+	return c.breaker.Do(func() error { return c.cache.InvalidateUser(userID) })
+}
+
+func (c *circuitCache) SetTokenBlacklist(tokenID string, ttl time.Duration) error {
+	return c.breaker.Do(func() error { return c.cache.SetTokenBlacklist(tokenID, ttl) })
+}
+
+func (c *circuitCache) IsTokenBlacklisted(tokenID string) (bool, bool, error) {
+	var found bool
+	blacklisted, err := circuitCall(c.breaker, func() (bool, error) {
+		blacklisted, ok, err := c.cache.IsTokenBlacklisted(tokenID)
+		found = ok
+		return blacklisted, err
+	})
+	return blacklisted, found, err
+}
+
+func (c *circuitCache) InvalidateTokenBlacklist(tokenID string) error {
+	return c.breaker.Do(func() error { return c.cache.InvalidateTokenBlacklist(tokenID) })
+}
+
+// Close is not guarded: it must release the cache even while the circuit is open.
+func (c *circuitCache) Close() error {
+	return c.cache.Close()
+}
+
+func (c *circuitCache) Ping() error {
+	return c.breaker.Do(c.cache.Ping)
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/internal/storage/memory"
+	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// flakyStorage fails user lookups while down is set.
+type flakyStorage struct {
+	storage.EnhancedStorage
+	down  bool
+	calls int
+}
+
+func (s *flakyStorage) GetUserByID(userID string) (*models.User, error) {
+	s.calls++
+	if s.down {
+		return nil, errors.New("connection refused")
+	}
+	return s.EnhancedStorage.GetUserByID(userID)
+}
+
+func TestCircuitBreakerOpensAndRecovers(t *testing.T) {
+	clock := NewFrozenClock(time.Now())
+	base := &flakyStorage{EnhancedStorage: memory.NewInMemoryStorage(), down: true}
+	breaker := newCircuitBreaker("storage", CircuitBreakerConfig{FailureThreshold: 3, OpenTimeout: time.Minute}, clock, nil)
+	wrapped := &circuitStorage{EnhancedStorage: base, breaker: breaker}
+
+	for i := 0; i < 5; i++ {
+		wrapped.GetUserByID("user-1")
+	}
+	if base.calls != 3 {
+		t.Errorf("Expected the open circuit to stop calling storage after 3 failures, got %d calls", base.calls)
+	}
+	_, err := wrapped.GetUserByID("user-1")
+	if authErr, ok := err.(*AuthError); !ok || authErr.Code != ErrCodeCircuitOpen {
+		t.Fatalf("Expected CIRCUIT_OPEN, got %v", err)
+	}
+	if stats := breaker.Stats(); stats.State != "open" || stats.Rejected != 3 || stats.Opened != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	// A failed probe opens the circuit again
+	clock.Advance(time.Minute)
+	wrapped.GetUserByID("user-1")
+	if base.calls != 4 || breaker.State() != CircuitOpen {
+		t.Errorf("Expected a failed probe to reopen the circuit, state %s after %d calls", breaker.State(), base.calls)
+	}
+
+	// A successful probe closes it
+	base.down = false
+	clock.Advance(time.Minute)
+	wrapped.GetUserByID("user-1")
+	if breaker.State() != CircuitClosed {
+		t.Errorf("Expected a successful probe to close the circuit, got %s", breaker.State())
+	}
+}
+
+func TestCircuitBreakerIgnoresNotFound(t *testing.T) {
+	breaker := NewCircuitBreaker("storage", CircuitBreakerConfig{FailureThreshold: 1})
+	wrapped := &circuitStorage{EnhancedStorage: memory.NewInMemoryStorage(), breaker: breaker}
+	for i := 0; i < 3; i++ {
+		if _, err := wrapped.GetUserByID("missing"); !isUserNotFound(err) {
+			t.Fatalf("Expected user not found, got %v", err)
+		}
+	}
+	if breaker.State() != CircuitClosed {
+		t.Errorf("Expected not found errors to keep the circuit closed")
+	}
+}
+
+func TestCircuitBreakerHalfOpenProbes(t *testing.T) {
+	clock := NewFrozenClock(time.Now())
+	breaker := newCircuitBreaker("cache", CircuitBreakerConfig{FailureThreshold: 1, HalfOpenProbes: 2}, clock, nil)
+	breaker.Do(func() error { return errors.New("timeout") })
+	clock.Advance(time.Hour)
+
+	// Probes in flight block further calls
+	release := make(chan struct{})
+	started := make(chan struct{}, 2)
+	done := make(chan struct{}, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			breaker.Do(func() error {
+				started <- struct{}{}
+				<-release
+				return nil
+			})
+			done <- struct{}{}
+		}()
+	}
+	<-started
+	<-started
+	if err := breaker.Do(func() error { return nil }); err == nil {
+		t.Error("Expected calls beyond the half-open probes to be rejected")
+	}
+	close(release)
+	<-done
+	<-done
+	if breaker.State() != CircuitClosed {
+		t.Errorf("Expected the circuit to close after successful probes, got %s", breaker.State())
+	}
+}
+
+func TestMonitorReportsCircuitBreakers(t *testing.T) {
+	monitor := NewMonitor(memory.NewInMemoryStorage(), NewMetricsCollector(), NewDefaultLogger(), "test-app", "1.0.0")
+	breaker := NewCircuitBreaker("storage", CircuitBreakerConfig{FailureThreshold: 1})
+	monitor.RegisterCircuitBreaker(breaker)
+
+	if health := monitor.CheckHealth(); health.Status != HealthStatusHealthy {
+		t.Errorf("Expected healthy with a closed circuit, got %s", health.Status)
+	}
+	breaker.Do(func() error { return errors.New("connection refused") })
+	if health := monitor.CheckHealth(); health.Status != HealthStatusUnhealthy {
+		t.Errorf("Expected unhealthy with an open circuit, got %s", health.Status)
+	}
+	if stats := monitor.CircuitBreakers(); len(stats) != 1 || stats[0].State != "open" {
+		t.Errorf("Unexpected circuit breakers: %+v", stats)
+	}
+}
@@ -0,0 +1,190 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pragneshbagary/go-auth/internal/jwtutils"
+)
+
+// ClaimMapping renames the claims of issued access tokens for consumers
+// that expect the conventions of another identity provider. Access tokens
+// validated by this Auth are mapped back, so hooks, middleware and
+// Tokens.Validate keep seeing the usual names.
+type ClaimMapping struct {
+	// Names maps claim names to the names tokens carry them under, e.g.
+	// "username" to "preferred_username". A name starting with "/" is a
+	// path into nested objects, e.g. "/realm_access/roles". Standard claims
+	// cannot be renamed.
+	Names map[string]string
+}
+
+// KeycloakClaimMapping issues usernames and roles as Keycloak does.
+func KeycloakClaimMapping() ClaimMapping {
+	return ClaimMapping{Names: map[string]string{
+		"username": "preferred_username",
+		"roles":    "/realm_access/roles",
+	}}
+}
+
+// Auth0ClaimMapping issues roles and usernames as namespaced custom claims,
+// as Auth0 requires, e.g. with namespace "https://example.com".
+func Auth0ClaimMapping(namespace string) ClaimMapping {
+	namespace = strings.TrimSuffix(namespace, "/")
+	return ClaimMapping{Names: map[string]string{
+		"roles":    namespace + "/roles",
+		"username": namespace + "/username",
+	}}
+}
+
+// KeycloakIssuerClaims maps the claims of Keycloak access tokens, for
+// TrustedIssuer.Claims. Realm roles become the "roles" metadata.
+func KeycloakIssuerClaims() IssuerClaimMapping {
+	return IssuerClaimMapping{
+		Username: "preferred_username",
+		Metadata: map[string]string{"roles": "/realm_access/roles"},
+	}
+}
+
+// Auth0IssuerClaims maps the claims of Auth0 access tokens whose roles are
+// a custom claim under namespace, for TrustedIssuer.Claims.
+func Auth0IssuerClaims(namespace string) IssuerClaimMapping {
+	namespace = strings.TrimSuffix(namespace, "/")
+	return IssuerClaimMapping{
+		Username: "nickname",
+		Metadata: map[string]string{
+			"roles":       namespace + "/roles",
+			"permissions": "permissions",
+		},
+	}
+}
+
+// validate rejects renamed standard claims and clashing names.
+func (m ClaimMapping) validate() error {
+	targets := make(map[string]string, len(m.Names))
+	for name, target := range m.Names {
+		if name == "" || target == "" || target == "/" {
+			return fmt.Errorf("claim mapping %q -> %q is incomplete", name, target)
+		}
+		if slices.Contains(standardClaims, name) || slices.Contains(standardClaims, target) {
+			return fmt.Errorf("standard claims cannot be mapped: %q -> %q", name, target)
+		}
+		if other, ok := targets[target]; ok {
+			return fmt.Errorf("claims %q and %q are both mapped to %q", other, name, target)
+		}
+		targets[target] = name
+	}
+	return nil
+}
+
+// claimMappingTokenManager renames the claims of issued access tokens and
+// restores them on validation.
+type claimMappingTokenManager struct {
+	jwtutils.TokenManager
+	names map[string]string
+}
+
+func (m *claimMappingTokenManager) GenerateAccessToken(userID string, customClaims map[string]any) (string, error) {
+	return m.GenerateAccessTokenWithOptions(userID, customClaims, jwtutils.IssueOptions{})
+}
+
+func (m *claimMappingTokenManager) GenerateAccessTokenWithOptions(userID string, customClaims map[string]any, opts jwtutils.IssueOptions) (string, error) {
+	opts.Claims = m.outbound(opts.Claims)
+	return m.TokenManager.GenerateAccessTokenWithOptions(userID, m.outbound(customClaims), opts)
+}
+
+func (m *claimMappingTokenManager) ValidateAccessToken(accessToken string) (jwt.MapClaims, error) {
+	claims, err := m.TokenManager.ValidateAccessToken(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	for name, target := range m.names {
+		if value, ok := takeClaim(claims, target); ok {
+			claims[name] = value
+		}
+	}
+	return claims, nil
+}
+
+// outbound returns a copy of claims with the mapped names.
+func (m *claimMappingTokenManager) outbound(claims map[string]any) map[string]any {
+	if len(claims) == 0 {
+		return claims
+	}
+	mapped := make(map[string]any, len(claims))
+	for k, v := range claims {
+		mapped[k] = v
+	}
+	for name, target := range m.names {
+		if value, ok := mapped[name]; ok {
+			delete(mapped, name)
+			setClaim(mapped, target, value)
+		}
+	}
+	return mapped
+}
+
+// claimPath splits a claim name into the keys of nested objects.
+func claimPath(name string) []string {
+	if !strings.HasPrefix(name, "/") {
+		return []string{name}
+	}
+	return strings.Split(strings.TrimPrefix(name, "/"), "/")
+}
+
+// claimValue returns the claim named name, which may be a path.
+func claimValue(claims map[string]interface{}, name string) (interface{}, bool) {
+	path := claimPath(name)
+	object := claims
+	for _, key := range path[:len(path)-1] {
+		nested, ok := object[key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		object = nested
+	}
+	value, ok := object[path[len(path)-1]]
+	return value, ok
+}
+
+// setClaim sets the claim named name, creating the nested objects of a path.
+func setClaim(claims map[string]interface{}, name string, value interface{}) {
+	path := claimPath(name)
+	object := claims
+	for _, key := range path[:len(path)-1] {
+		nested, ok := object[key].(map[string]interface{})
+		if !ok {
+			nested = make(map[string]interface{})
+			object[key] = nested
+		}
+		object = nested
+	}
+	object[path[len(path)-1]] = value
+}
+
+// takeClaim removes and returns the claim named name, dropping nested
+// objects left empty.
+func takeClaim(claims map[string]interface{}, name string) (interface{}, bool) {
+	path := claimPath(name)
+	objects := []map[string]interface{}{claims}
+	for _, key := range path[:len(path)-1] {
+		nested, ok := objects[len(objects)-1][key].(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		objects = append(objects, nested)
+	}
+	value, ok := objects[len(objects)-1][path[len(path)-1]]
+	if !ok {
+		return nil, false
+	}
+	for i := len(objects) - 1; i >= 0; i-- {
+		delete(objects[i], path[i])
+		if len(objects[i]) > 0 {
+			break
+		}
+	}
+	return value, true
+}
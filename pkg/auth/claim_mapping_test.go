@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pragneshbagary/go-auth/internal/storage/memory"
+)
+
+func TestClaimMapping_Keycloak(t *testing.T) {
+	a, err := newAuthWithStorage(memory.NewInMemoryStorage(), &AuthConfig{
+		JWTSecret:          "go-auth-test-secret",
+		JWTRefreshSecret:   "go-auth-test-refresh-secret",
+		LogLevel:           "error",
+		PasswordHashParams: TestHashParams,
+		Claims:             ClaimsConfig{Mapping: KeycloakClaimMapping()},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create auth: %v", err)
+	}
+	if _, err := a.Register(RegisterRequest{Username: "alice", Email: "alice@example.com", Password: TestPassword}); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	result, err := a.Login("alice", TestPassword, map[string]interface{}{"roles": []string{"admin"}})
+	if err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+
+	issued := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(result.AccessToken, issued); err != nil {
+		t.Fatalf("Failed to parse token: %v", err)
+	}
+	if issued["preferred_username"] != "alice" || issued["username"] != nil {
+		t.Errorf("Expected the username to be issued as preferred_username, got %v", issued)
+	}
+	realm, _ := issued["realm_access"].(map[string]interface{})
+	if roles := stringList(realm["roles"]); len(roles) != 1 || roles[0] != "admin" {
+		t.Errorf("Expected roles under realm_access, got %v", issued["realm_access"])
+	}
+
+	claims, err := a.ValidateAccessToken(result.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected the token to validate: %v", err)
+	}
+	if claims["username"] != "alice" || len(stringList(claims["roles"])) != 1 || claims["realm_access"] != nil {
+		t.Errorf("Expected the claims to be mapped back, got %v", claims)
+	}
+}
+
+func TestClaimMapping_Validate(t *testing.T) {
+	if err := (ClaimMapping{Names: map[string]string{"sub": "user"}}).validate(); err == nil {
+		t.Error("Expected a mapped standard claim to be rejected")
+	}
+	if err := (ClaimMapping{Names: map[string]string{"a": "x", "b": "x"}}).validate(); err == nil {
+		t.Error("Expected clashing names to be rejected")
+	}
+	if err := Auth0ClaimMapping("https://example.com/").validate(); err != nil {
+		t.Errorf("Expected the Auth0 mapping to be valid: %v", err)
+	}
+}
+
+func TestKeycloakIssuerClaims(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	ta := NewTestAuth(t)
+	now := ta.Clock.Now()
+	ta.trustedIssuers, err = newTrustedIssuers([]TrustedIssuer{{
+		Issuer: "https://keycloak.test/realms/main",
+		Key:    &key.PublicKey,
+		Claims: KeycloakIssuerClaims(),
+	}}, ta.Clock, 0)
+	if err != nil {
+		t.Fatalf("Failed to configure trusted issuer: %v", err)
+	}
+
+	user, err := ta.Tokens().Validate(legacyToken(t, key, jwt.MapClaims{
+		"iss":                "https://keycloak.test/realms/main",
+		"sub":                "kc-7",
+		"preferred_username": "dave",
+		"email":              "dave@example.test",
+		"realm_access":       map[string]interface{}{"roles": []interface{}{"editor"}},
+		"iat":                now.Unix(),
+		"exp":                now.Add(time.Hour).Unix(),
+	}))
+	if err != nil {
+		t.Fatalf("Expected the Keycloak token to validate: %v", err)
+	}
+	if user.Username != "dave" || user.Email != "dave@example.test" {
+		t.Errorf("Expected mapped user fields, got %+v", user)
+	}
+	if roles := stringList(user.Metadata["roles"]); len(roles) != 1 || roles[0] != "editor" {
+		t.Errorf("Expected realm roles, got %v", user.Metadata)
+	}
+}
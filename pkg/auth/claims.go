@@ -0,0 +1,125 @@
+package auth
+
+import "github.com/pragneshbagary/go-auth/pkg/models"
+
+// Access token claims come from three sources, merged by precedence:
+//
+//  1. Standard claims set by the token manager: sub, exp, iat, nbf, jti,
+//     iss, aud and token_type.
+//  2. Stored claims derived from the user record: username, email, user_id,
+//     synced directory claims, password_expires_at, elevated_until and
+//     account_expires_at.
+//  3. Per-login claims passed to Login, Tokens.Issue or added on refresh.
+//
+// A claim never replaces one of a higher level, and claims that do not
+// conflict are never dropped. Per-login claims naming a protected claim are
+// dropped and logged even when the user has no such stored claim, see
+// ClaimsConfig. The cnf binding of LoginDPoP and LoginMTLS is set after the
+// merge. Login hooks run after the merge and may change any claim
+// except the standard ones.
+
+// standardClaims are set by the token manager on every access token.
+var standardClaims = []string{"sub", "exp", "iat", "nbf", "jti", "iss", "aud", "token_type"}
+
+// defaultProtectedClaims are the claims per-login claims may not set unless
+// allowed by ClaimsConfig.AllowOverride.
+var defaultProtectedClaims = []string{"user_id", "username", "email", "password_expires_at", elevatedUntilClaim, accountExpiresClaim, "cnf", sessionClaim, epochClaim}
+
+// ClaimsConfig configures which claims callers of Login and Tokens.Issue
+// may set.
+type ClaimsConfig struct {
+	// Protected lists claims, in addition to the standard claims and
+	// user_id, username, email, password_expires_at, elevated_until,
+	// account_expires_at, cnf, sid and epoch, that per-login claims may not
+	// set, e.g. "tenant_id" or "roles" in a multi-tenant application. Login
+	// hooks may still set them.
+	Protected []string
+
+	// AllowOverride lists protected claims that per-login claims may set
+	// anyway, replacing the stored value. Standard claims are always set by
+	// the token manager and cannot be overridden.
+	AllowOverride []string
+
+	// ProfileClaims adds the display name, locale and timezone of users as
+	// the OIDC standard claims name, locale and zoneinfo, which per-login
+	// claims may then not set.
+	ProfileClaims bool
+
+	// Mapping renames claims of issued access tokens, e.g.
+	// KeycloakClaimMapping() for consumers expecting Keycloak tokens.
+	Mapping ClaimMapping
+}
+
+// claimPolicy enforces a ClaimsConfig when merging per-login claims.
+type claimPolicy struct {
+	protected map[string]bool
+	allowed   map[string]bool
+	profile   bool
+	logger    *Logger
+}
+
+func newClaimPolicy(config ClaimsConfig, logger *Logger) *claimPolicy {
+	p := &claimPolicy{protected: map[string]bool{}, allowed: map[string]bool{}, profile: config.ProfileClaims, logger: logger}
+	protected := [][]string{standardClaims, defaultProtectedClaims, config.Protected}
+	if config.ProfileClaims {
+		protected = append(protected, profileClaims)
+	}
+	for _, names := range protected {
+		for _, name := range names {
+			p.protected[name] = true
+		}
+	}
+	for _, name := range config.AllowOverride {
+		p.allowed[name] = true
+	}
+	for _, name := range standardClaims {
+		delete(p.allowed, name)
+	}
+	return p
+}
+
+// merge adds the per-login claims of the user to stored and returns it.
+// Claims that are protected or conflict with a stored claim are dropped and
+// logged.
+func (p *claimPolicy) merge(userID string, stored, perLogin map[string]interface{}) map[string]interface{} {
+	for k, v := range perLogin {
+		if p == nil {
+			// Without a policy only conflicting claims are dropped
+			if _, exists := stored[k]; !exists {
+				stored[k] = v
+			}
+			continue
+		}
+		if p.allowed[k] {
+			stored[k] = v
+			continue
+		}
+		if _, exists := stored[k]; exists || p.protected[k] {
+			p.logger.Warn("Rejected protected claim", map[string]interface{}{
+				"user_id": userID,
+				"claim":   k,
+			})
+			continue
+		}
+		stored[k] = v
+	}
+	return stored
+}
+
+// stored returns the claims derived from the record of user, including the
+// profile claims if enabled.
+func (p *claimPolicy) stored(user *models.User) map[string]interface{} {
+	claims := map[string]interface{}{
+		"username": user.Username,
+		"email":    user.Email,
+		"user_id":  user.ID,
+	}
+	addDirectoryClaims(user, claims)
+	if user.ExpiresAt != nil {
+		claims[accountExpiresClaim] = user.ExpiresAt.Unix()
+	}
+	if p != nil && p.profile {
+		addProfileClaims(user, claims)
+	}
+	return claims
+}
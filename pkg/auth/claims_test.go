@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestClaimPolicyMerge(t *testing.T) {
+	var logs bytes.Buffer
+	policy := newClaimPolicy(ClaimsConfig{
+		Protected:     []string{"tenant_id"},
+		AllowOverride: []string{"email", "sub"},
+	}, NewLogger(LogLevelWarn, &logs))
+
+	stored := map[string]interface{}{"user_id": "u1", "email": "alice@example.com", "department": "sales"}
+	merged := policy.merge("u1", stored, map[string]interface{}{
+		"tenant_id":  "other-tenant",
+		"user_id":    "u2",
+		"username":   "mallory",
+		"sub":        "u2",
+		"department": "finance",
+		"email":      "alias@example.com",
+		"role":       "viewer",
+	})
+
+	want := map[string]interface{}{"user_id": "u1", "email": "alias@example.com", "department": "sales", "role": "viewer"}
+	if len(merged) != len(want) {
+		t.Fatalf("Expected claims %v, got %v", want, merged)
+	}
+	for k, v := range want {
+		if merged[k] != v {
+			t.Errorf("Expected %s=%v, got %v", k, v, merged[k])
+		}
+	}
+
+	output := logs.String()
+	if n := strings.Count(output, "Rejected protected claim"); n != 5 {
+		t.Errorf("Expected 5 rejected claims to be logged, got %d: %s", n, output)
+	}
+	if strings.Contains(output, "role") {
+		t.Errorf("Expected unprotected claims not to be logged, got %s", output)
+	}
+}
+
+func TestLoginDropsProtectedClaims(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.claims = newClaimPolicy(ClaimsConfig{Protected: []string{"tenant_id"}}, ta.logger)
+	user := ta.SeedUser("alice", "alice-password")
+
+	result, err := ta.Login("alice", "alice-password", map[string]interface{}{
+		"tenant_id": "other-tenant",
+		"sub":       "someone-else",
+		"iss":       "forged",
+		"user_id":   "someone-else",
+		"role":      "viewer",
+	})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	claims, err := ta.ValidateAccessToken(result.AccessToken)
+	if err != nil {
+		t.Fatalf("Failed to validate access token: %v", err)
+	}
+
+	if claims["sub"] != user.ID || claims["user_id"] != user.ID || claims["iss"] != "go-auth-test" {
+		t.Errorf("Expected the standard and stored claims to be kept, got %v", claims)
+	}
+	if _, ok := claims["tenant_id"]; ok {
+		t.Errorf("Expected the protected tenant_id claim to be dropped, got %v", claims["tenant_id"])
+	}
+	if claims["role"] != "viewer" {
+		t.Errorf("Expected the role claim to be added, got %v", claims["role"])
+	}
+}
+
+func TestLoginDropsBindingClaims(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("alice", "alice-password")
+
+	result, err := ta.Login("alice", "alice-password", map[string]interface{}{
+		"cnf":   map[string]interface{}{"jkt": "attacker-key"},
+		"sid":   "someone-elses-session",
+		"epoch": 99,
+	})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	claims, err := ta.ValidateAccessToken(result.AccessToken)
+	if err != nil {
+		t.Fatalf("Failed to validate access token: %v", err)
+	}
+
+	if _, ok := claims["cnf"]; ok {
+		t.Errorf("Expected the cnf claim to be dropped, got %v", claims["cnf"])
+	}
+	if claims["sid"] == "someone-elses-session" {
+		t.Error("Expected the sid claim not to be set by custom claims")
+	}
+	if claims["epoch"] == float64(99) {
+		t.Error("Expected the epoch claim not to be set by custom claims")
+	}
+}
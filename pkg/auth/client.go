@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenRefresher exchanges a refresh token for a new token pair.
+type TokenRefresher func(ctx context.Context, refreshToken string) (*RefreshResult, error)
+
+// RefreshEndpoint returns a TokenRefresher that POSTs a RefreshRequest as JSON
+// to url and decodes a RefreshResult from the response. A nil httpClient
+// uses http.DefaultClient.
+func RefreshEndpoint(url string, httpClient *http.Client) TokenRefresher {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return func(ctx context.Context, refreshToken string) (*RefreshResult, error) {
+		body, err := json.Marshal(RefreshRequest{RefreshToken: refreshToken})
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("refresh endpoint returned %s", resp.Status)
+		}
+
+		var result RefreshResult
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return nil, err
+		}
+		if result.AccessToken == "" || result.RefreshToken == "" {
+			return nil, fmt.Errorf("refresh endpoint returned no token pair")
+		}
+		return &result, nil
+	}
+}
+
+// ClientConfig configures a Client.
+type ClientConfig struct {
+	AccessToken  string
+	RefreshToken string
+
+	// Refresh obtains a new token pair, e.g. RefreshEndpoint. Without it the
+	// Client only injects the access token.
+	Refresh TokenRefresher
+	// OnRefresh is called with every new token pair, e.g. to persist it.
+	OnRefresh func(*RefreshResult)
+
+	// RefreshBefore refreshes proactively when the access token expires
+	// within this duration. Defaults to 30 seconds.
+	RefreshBefore time.Duration
+	// Base performs the requests. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+	// Clock is the time source; defaults to the system clock.
+	Clock Clock
+}
+
+// Client is an http.RoundTripper for services calling APIs protected by
+// go-auth. It adds the access token as a Bearer Authorization header,
+// refreshes the token pair shortly before the access token expires and
+// retries a request once if it is answered with 401 Unauthorized.
+//
+//	client := auth.NewClient(auth.ClientConfig{
+//		AccessToken:  login.AccessToken,
+//		RefreshToken: login.RefreshToken,
+//		Refresh:      auth.RefreshEndpoint("https://api.example.com/auth/refresh", nil),
+//	})
+//	resp, err := client.HTTPClient().Get("https://api.example.com/orders")
+type Client struct {
+	config ClientConfig
+
+	mu           sync.Mutex
+	accessToken  string
+	refreshToken string
+	expiresAt    time.Time
+}
+
+// NewClient creates a Client holding the given token pair.
+func NewClient(config ClientConfig) *Client {
+	if config.RefreshBefore <= 0 {
+		config.RefreshBefore = 30 * time.Second
+	}
+	if config.Base == nil {
+		config.Base = http.DefaultTransport
+	}
+	c := &Client{config: config}
+	c.setTokens(config.AccessToken, config.RefreshToken)
+	return c
+}
+
+// HTTPClient returns an http.Client using c as its transport.
+func (c *Client) HTTPClient() *http.Client {
+	return &http.Client{Transport: c}
+}
+
+// Tokens returns the current token pair.
+func (c *Client) Tokens() (accessToken, refreshToken string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.accessToken, c.refreshToken
+}
+
+// SetTokens replaces the token pair, e.g. after a new login.
+func (c *Client) SetTokens(accessToken, refreshToken string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.setTokens(accessToken, refreshToken)
+}
+
+// setTokens stores a token pair. The caller must hold c.mu.
+func (c *Client) setTokens(accessToken, refreshToken string) {
+	c.accessToken = accessToken
+	c.refreshToken = refreshToken
+	c.expiresAt = tokenExpiry(accessToken)
+}
+
+// RoundTrip implements http.RoundTripper.
+func (c *Client) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := c.currentToken(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.config.Base.RoundTrip(authorize(req, token))
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || c.config.Refresh == nil {
+		return resp, err
+	}
+
+	// Retry once with a fresh token if the body can be replayed
+	if req.Body != nil && req.GetBody == nil {
+		return resp, nil
+	}
+	fresh, refreshErr := c.refresh(req.Context(), token)
+	if refreshErr != nil {
+		return resp, nil
+	}
+	retry := authorize(req, fresh)
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return resp, nil
+		}
+		retry.Body = body
+	}
+	resp.Body.Close()
+	return c.config.Base.RoundTrip(retry)
+}
+
+// currentToken returns the access token, refreshing it first if it is
+// about to expire.
+func (c *Client) currentToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	token, expiresAt := c.accessToken, c.expiresAt
+	c.mu.Unlock()
+
+	if c.config.Refresh == nil || expiresAt.IsZero() ||
+		nowFrom(c.config.Clock).Add(c.config.RefreshBefore).Before(expiresAt) {
+		return token, nil
+	}
+	fresh, err := c.refresh(ctx, token)
+	if err != nil {
+		// Let the server decide while the old token may still be valid
+		if nowFrom(c.config.Clock).Before(expiresAt) {
+			return token, nil
+		}
+		return "", err
+	}
+	return fresh, nil
+}
+
+// refresh rotates the token pair unless another request already replaced
+// stale, so concurrent requests share a single refresh.
+func (c *Client) refresh(ctx context.Context, stale string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.accessToken != stale {
+		return c.accessToken, nil
+	}
+	if c.refreshToken == "" {
+		return "", ErrMissingToken()
+	}
+
+	result, err := c.config.Refresh(ctx, c.refreshToken)
+	if err != nil {
+		return "", err
+	}
+	c.setTokens(result.AccessToken, result.RefreshToken)
+	if c.config.OnRefresh != nil {
+		c.config.OnRefresh(result)
+	}
+	return c.accessToken, nil
+}
+
+// authorize returns a copy of req carrying token.
+func authorize(req *http.Request, token string) *http.Request {
+	clone := req.Clone(req.Context())
+	if token != "" {
+		clone.Header.Set("Authorization", "Bearer "+token)
+	}
+	return clone
+}
+
+// tokenExpiry reads the exp claim of a JWT without verifying it. It returns
+// the zero time for opaque or encrypted tokens.
+func tokenExpiry(token string) time.Time {
+	if token == "" {
+		return time.Time{}
+	}
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return time.Time{}
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return time.Time{}
+	}
+	return exp.Time
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func signedTestToken(t *testing.T, exp time.Time) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"exp": exp.Unix()}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return token
+}
+
+// newClientTestServer serves /refresh, issuing "fresh" tokens, and /api,
+// accepting only the current access token.
+func newClientTestServer(t *testing.T, fresh string, refreshes *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/refresh":
+			var req RefreshRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken != "refresh-1" {
+				http.Error(w, "bad refresh token", http.StatusUnauthorized)
+				return
+			}
+			atomic.AddInt32(refreshes, 1)
+			json.NewEncoder(w).Encode(RefreshResult{AccessToken: fresh, RefreshToken: "refresh-2"})
+		case "/api":
+			if r.Header.Get("Authorization") != "Bearer "+fresh {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.Method == http.MethodPost {
+				// The retried request must carry the original body
+				if body, _ := io.ReadAll(r.Body); string(body) != `{"a":1}` {
+					http.Error(w, "missing body", http.StatusBadRequest)
+					return
+				}
+			}
+			w.Write([]byte("ok"))
+		}
+	}))
+}
+
+func TestClientRetriesOnUnauthorized(t *testing.T) {
+	var refreshes int32
+	fresh := signedTestToken(t, time.Now().Add(time.Hour))
+	server := newClientTestServer(t, fresh, &refreshes)
+	defer server.Close()
+
+	var persisted *RefreshResult
+	client := NewClient(ClientConfig{
+		AccessToken:  "revoked-token",
+		RefreshToken: "refresh-1",
+		Refresh:      RefreshEndpoint(server.URL+"/refresh", nil),
+		OnRefresh:    func(result *RefreshResult) { persisted = result },
+	})
+
+	resp, err := client.HTTPClient().Post(server.URL+"/api", "application/json", strings.NewReader(`{"a":1}`))
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected retry to succeed, got %d", resp.StatusCode)
+	}
+	if refreshes != 1 || persisted == nil || persisted.RefreshToken != "refresh-2" {
+		t.Errorf("Expected one persisted refresh, got %d (%v)", refreshes, persisted)
+	}
+	if access, refresh := client.Tokens(); access != fresh || refresh != "refresh-2" {
+		t.Error("Expected client to hold the new pair")
+	}
+}
+
+func TestClientRefreshesBeforeExpiry(t *testing.T) {
+	var refreshes int32
+	fresh := signedTestToken(t, time.Now().Add(time.Hour))
+	server := newClientTestServer(t, fresh, &refreshes)
+	defer server.Close()
+
+	client := NewClient(ClientConfig{
+		AccessToken:   signedTestToken(t, time.Now().Add(10*time.Second)),
+		RefreshToken:  "refresh-1",
+		Refresh:       RefreshEndpoint(server.URL+"/refresh", nil),
+		RefreshBefore: time.Minute,
+	})
+
+	for i := 0; i < 3; i++ {
+		resp, err := client.HTTPClient().Get(server.URL + "/api")
+		if err != nil {
+			t.Fatalf("Request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected proactive refresh, got %d", resp.StatusCode)
+		}
+	}
+	if refreshes != 1 {
+		t.Errorf("Expected a single refresh, got %d", refreshes)
+	}
+}
+
+func TestClientWithoutRefresher(t *testing.T) {
+	var refreshes int32
+	server := newClientTestServer(t, "static", &refreshes)
+	defer server.Close()
+
+	client := NewClient(ClientConfig{AccessToken: "expired"})
+	resp, err := client.HTTPClient().Get(server.URL + "/api")
+	if err != nil {
+		t.Fatalf("Request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 to be passed through, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock provides the current time. Replacing it allows tests to control
+// token issuance, expiry checks, and timestamps deterministically.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the default Clock backed by time.Now.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// SystemClock returns a Clock that reports the real wall-clock time.
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+// FrozenClock is a Clock that only moves when Set or Advance is called.
+type FrozenClock struct {
+	mu  sync.RWMutex
+	now time.Time
+}
+
+// NewFrozenClock creates a FrozenClock stopped at the given time.
+func NewFrozenClock(t time.Time) *FrozenClock {
+	return &FrozenClock{now: t}
+}
+
+// Now returns the clock's current time.
+func (c *FrozenClock) Now() time.Time {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.now
+}
+
+// Set moves the clock to the given time.
+func (c *FrozenClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+// Advance moves the clock forward by d.
+func (c *FrozenClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// nowFrom returns the current time from clock, falling back to time.Now.
+func nowFrom(clock Clock) time.Time {
+	if clock == nil {
+		return time.Now()
+	}
+	return clock.Now()
+}
@@ -39,6 +39,11 @@ func NewAuthFromLegacyConfig(cfg Config) (*Auth, error) {
 		RefreshTokenTTL: cfg.JWT.RefreshTokenTTL,
 		AppName:         "go-auth-app",
 		LogLevel:        "info",
+
+		JWTAudience:       cfg.JWT.Audience,
+		ExpectedIssuers:   cfg.JWT.ExpectedIssuers,
+		ExpectedAudiences: cfg.JWT.ExpectedAudiences,
+		TokenLeeway:       cfg.JWT.Leeway,
 	}
 
 	// Set up storage - we need to handle the case where the old storage interface
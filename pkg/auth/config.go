@@ -14,6 +14,12 @@ const (
 	RS256 = "RS256"
 )
 
+// Token formats for AuthConfig.TokenFormat.
+const (
+	TokenFormatJWT    = "jwt"
+	TokenFormatPASETO = "paseto"
+)
+
 // JWTConfig holds the configuration for JWT generation and validation.
 // These settings are used to create the internal JWTManager.
 type JWTConfig struct {
@@ -23,6 +29,15 @@ type JWTConfig struct {
 	AccessTokenTTL  time.Duration
 	RefreshTokenTTL time.Duration
 	SigningMethod   string
+
+	// Audience is set on issued tokens. ExpectedIssuers and ExpectedAudiences
+	// restrict which tokens validate; they default to Issuer and Audience.
+	Audience          []string
+	ExpectedIssuers   []string
+	ExpectedAudiences []string
+
+	// Leeway is the clock skew tolerated when checking exp, nbf and iat.
+	Leeway time.Duration
 }
 
 // Config is the main configuration struct for the AuthService.
@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// envPrefix prefixes the environment variables read by LoadConfigFromEnv.
+const envPrefix = "AUTH_"
+
+// EnvOption configures LoadConfigFromEnv and LoadConfigWithProfile.
+type EnvOption func(*envOptions)
+
+type envOptions struct {
+	strict bool
+	logger *Logger
+	dotEnv string // dotenv file to load, see WithDotEnv
+}
+
+// WithStrictEnv makes loading fail if the environment holds AUTH_*
+// variables that are not recognized, such as a misspelled
+// AUTH_ACCESS_TOKEN_TLL, which would otherwise be silently ignored.
+func WithStrictEnv() EnvOption {
+	return func(o *envOptions) {
+		o.strict = true
+	}
+}
+
+// WithEnvWarnings logs a warning for each unrecognized AUTH_* variable
+// instead of failing.
+func WithEnvWarnings(logger *Logger) EnvOption {
+	return func(o *envOptions) {
+		o.logger = logger
+	}
+}
+
+// UnknownEnvVar is an unrecognized AUTH_* environment variable.
+type UnknownEnvVar struct {
+	Name string
+	// Suggestion is the recognized variable it most likely misspells, if any.
+	Suggestion string
+}
+
+func (u UnknownEnvVar) String() string {
+	if u.Suggestion != "" {
+		return fmt.Sprintf("%s (did you mean %s?)", u.Name, u.Suggestion)
+	}
+	return u.Name
+}
+
+// KnownEnvVars returns the environment variables LoadConfigFromEnv reads,
+// sorted by name.
+func KnownEnvVars() []string {
+	names := []string{"AUTH_PROFILE"}
+	configType := reflect.TypeOf(EnhancedConfig{})
+	for i := 0; i < configType.NumField(); i++ {
+		if name := configType.Field(i).Tag.Get("env"); strings.HasPrefix(name, envPrefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// UnknownEnvVars returns the AUTH_* variables in environ, a list of
+// "KEY=value" entries as returned by os.Environ, that are not recognized.
+func UnknownEnvVars(environ []string) []UnknownEnvVar {
+	known := KnownEnvVars()
+	isKnown := make(map[string]bool, len(known))
+	for _, name := range known {
+		isKnown[name] = true
+	}
+
+	var unknown []UnknownEnvVar
+	for _, entry := range environ {
+		name, _, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(name, envPrefix) || isKnown[name] {
+			continue
+		}
+		unknown = append(unknown, UnknownEnvVar{Name: name, Suggestion: closestName(name, known)})
+	}
+	sort.Slice(unknown, func(i, j int) bool { return unknown[i].Name < unknown[j].Name })
+	return unknown
+}
+
+// prepareEnv loads the dotenv file of opts, unless profile is the
+// production profile, and applies their strict mode to the environment.
+func prepareEnv(profile string, opts []EnvOption) error {
+	var options envOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.dotEnv != "" && !strings.EqualFold(profile, ProductionProfile.Name) {
+		if err := loadDotEnv(options.dotEnv); err != nil {
+			return fmt.Errorf("failed to load %s: %w", options.dotEnv, err)
+		}
+	}
+	if !options.strict && options.logger == nil {
+		return nil
+	}
+
+	unknown := UnknownEnvVars(os.Environ())
+	if len(unknown) == 0 {
+		return nil
+	}
+	if options.strict {
+		names := make([]string, len(unknown))
+		for i, u := range unknown {
+			names[i] = u.String()
+		}
+		return fmt.Errorf("unknown environment variables: %s", strings.Join(names, ", "))
+	}
+	for _, u := range unknown {
+		options.logger.Warn("Ignoring unknown environment variable", map[string]interface{}{
+			"name":       u.Name,
+			"suggestion": u.Suggestion,
+		})
+	}
+	return nil
+}
+
+// closestName returns the name in names nearest to name, if it is close
+// enough to be a likely typo.
+func closestName(name string, names []string) string {
+	best, bestDistance := "", 4
+	for _, candidate := range names {
+		if d := editDistance(name, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Damerau-Levenshtein distance between a and b,
+// counting adjacent transpositions such as TLL for TTL as one edit.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := 0; j <= len(b); j++ {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
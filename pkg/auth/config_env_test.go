@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUnknownEnvVars(t *testing.T) {
+	unknown := UnknownEnvVars([]string{
+		"PATH=/usr/bin",
+		"AUTH_ACCESS_TOKEN_TTL=15m",
+		"AUTH_ACCESS_TOKEN_TLL=1h",
+		"AUTH_PROFILE=production",
+		"AUTH_SOMETHING_ELSE=1",
+	})
+
+	require.Len(t, unknown, 2)
+	assert.Equal(t, UnknownEnvVar{Name: "AUTH_ACCESS_TOKEN_TLL", Suggestion: "AUTH_ACCESS_TOKEN_TTL"}, unknown[0])
+	assert.Equal(t, "AUTH_SOMETHING_ELSE", unknown[1].Name)
+	assert.Empty(t, unknown[1].Suggestion)
+	assert.Equal(t, "AUTH_ACCESS_TOKEN_TLL (did you mean AUTH_ACCESS_TOKEN_TTL?)", unknown[0].String())
+}
+
+func TestLoadConfigFromEnvStrict(t *testing.T) {
+	t.Setenv("AUTH_JWT_ACCESS_SECRET", "access-secret-that-is-long-enough-123")
+	t.Setenv("AUTH_JWT_REFRESH_SECRET", "refresh-secret-that-is-long-enough-12")
+	t.Setenv("AUTH_ACCESS_TOKEN_TLL", "1h")
+
+	// Ignored by default
+	_, err := LoadConfigFromEnv()
+	require.NoError(t, err)
+
+	_, err = LoadConfigFromEnv(WithStrictEnv())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "did you mean AUTH_ACCESS_TOKEN_TTL?")
+
+	var logs bytes.Buffer
+	_, err = LoadConfigWithProfile("production", WithEnvWarnings(NewLogger(LogLevelWarn, &logs)))
+	require.NoError(t, err)
+	assert.Contains(t, logs.String(), "AUTH_ACCESS_TOKEN_TLL")
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("AUTH_DB_URL", "AUTH_DB_URL"))
+	assert.Equal(t, 1, editDistance("TLL", "TTL"))
+	assert.Equal(t, 1, editDistance("AUTH_DB_UR", "AUTH_DB_URL"))
+	assert.Equal(t, 3, editDistance("abc", "xyz"))
+}
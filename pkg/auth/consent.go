@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Consent records a user's acceptance of a version of a document such as
+// the terms of service or the privacy policy.
+type Consent struct {
+	UserID     string    `json:"user_id"`
+	DocumentID string    `json:"document_id"`
+	Version    string    `json:"version"`
+	AcceptedAt time.Time `json:"accepted_at"`
+}
+
+// ConsentStore persists consent records. Records are only ever appended so
+// that the full history stays available for audits.
+type ConsentStore interface {
+	SaveConsent(consent *Consent) error
+	// ListConsents returns all consents of userID, newest first.
+	ListConsents(userID string) ([]*Consent, error)
+}
+
+// ConsentConfig configures consent tracking.
+type ConsentConfig struct {
+	// Required maps document IDs to the version users must have accepted to
+	// log in, e.g. {"tos": "2024-05", "privacy": "3"}. Publishing a new
+	// version makes Login fail with CONSENT_REQUIRED until it is accepted.
+	Required map[string]string
+	// Store persists consents. Defaults to an in-memory store.
+	Store ConsentStore
+}
+
+// memoryConsentStore is an in-memory ConsentStore.
+type memoryConsentStore struct {
+	mu       sync.RWMutex
+	consents map[string][]*Consent
+}
+
+// NewMemoryConsentStore creates an in-memory ConsentStore. Consents are lost
+// on restart; use a persistent ConsentStore in production.
+func NewMemoryConsentStore() ConsentStore {
+	return &memoryConsentStore{consents: make(map[string][]*Consent)}
+}
+
+func (s *memoryConsentStore) SaveConsent(consent *Consent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *consent
+	s.consents[consent.UserID] = append(s.consents[consent.UserID], &stored)
+	return nil
+}
+
+func (s *memoryConsentStore) ListConsents(userID string) ([]*Consent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stored := s.consents[userID]
+	consents := make([]*Consent, 0, len(stored))
+	for i := len(stored) - 1; i >= 0; i-- {
+		consent := *stored[i]
+		consents = append(consents, &consent)
+	}
+	return consents, nil
+}
+
+// RecordConsent records that the user accepted version of the document
+// docID, e.g. after a login failed with CONSENT_REQUIRED.
+func (u *Users) RecordConsent(userID, docID, version string) error {
+	var err error
+	defer func() {
+		u.eventLogger.LogConsent(userID, docID, version, err == nil, err)
+	}()
+
+	switch {
+	case userID == "":
+		err = ErrValidationError("user ID")
+	case docID == "":
+		err = ErrValidationError("document ID")
+	case version == "":
+		err = ErrValidationError("version")
+	}
+	if err != nil {
+		return err
+	}
+
+	if _, getErr := u.storage.GetUserByID(userID); getErr != nil {
+		err = ErrUserNotFound()
+		return err
+	}
+
+	consent := &Consent{
+		UserID:     userID,
+		DocumentID: docID,
+		Version:    version,
+		AcceptedAt: nowFrom(u.clock),
+	}
+	if saveErr := u.consent.Store.SaveConsent(consent); saveErr != nil {
+		err = WrapDatabaseError(saveErr)
+		return err
+	}
+	return nil
+}
+
+// ConsentHistory returns every consent the user gave, newest first.
+func (u *Users) ConsentHistory(userID string) ([]*Consent, error) {
+	if userID == "" {
+		return nil, ErrValidationError("user ID")
+	}
+	consents, err := u.consent.Store.ListConsents(userID)
+	if err != nil {
+		return nil, WrapDatabaseError(err)
+	}
+	return consents, nil
+}
+
+// PendingConsents returns the required documents whose current version the
+// user has not accepted yet, as a map of document ID to version.
+func (u *Users) PendingConsents(userID string) (map[string]string, error) {
+	if userID == "" {
+		return nil, ErrValidationError("user ID")
+	}
+	pending, err := pendingConsents(u.consent, userID)
+	if err != nil {
+		return nil, WrapDatabaseError(err)
+	}
+	return pending, nil
+}
+
+// pendingConsents compares the latest accepted version of each required
+// document with the required one.
+func pendingConsents(config ConsentConfig, userID string) (map[string]string, error) {
+	pending := make(map[string]string)
+	if len(config.Required) == 0 {
+		return pending, nil
+	}
+	consents, err := config.Store.ListConsents(userID)
+	if err != nil {
+		return nil, err
+	}
+	accepted := make(map[string]string)
+	for _, consent := range consents {
+		if _, ok := accepted[consent.DocumentID]; !ok {
+			accepted[consent.DocumentID] = consent.Version
+		}
+	}
+	for docID, version := range config.Required {
+		if accepted[docID] != version {
+			pending[docID] = version
+		}
+	}
+	return pending, nil
+}
+
+// checkConsent returns ErrConsentRequired if the user has pending consents.
+func (a *Auth) checkConsent(userID string) error {
+	pending, err := pendingConsents(a.consent, userID)
+	if err != nil {
+		return WrapDatabaseError(err)
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	documents := make([]string, 0, len(pending))
+	for docID, version := range pending {
+		documents = append(documents, docID+":"+version)
+	}
+	sort.Strings(documents)
+	return ErrConsentRequired(documents)
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConsentRequiredOnLogin(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.consent.Required = map[string]string{"tos": "1", "privacy": "1"}
+	user := ta.SeedUser("alice", "alice-password")
+
+	_, err := ta.Login("alice", "alice-password", nil)
+	var authErr *AuthError
+	if !errors.As(err, &authErr) || authErr.Code != ErrCodeConsentRequired {
+		t.Fatalf("Expected CONSENT_REQUIRED, got %v", err)
+	}
+	if authErr.Details != "privacy:1,tos:1" {
+		t.Errorf("Expected pending documents in details, got %q", authErr.Details)
+	}
+
+	for _, doc := range []string{"tos", "privacy"} {
+		if err := ta.Users().RecordConsent(user.ID, doc, "1"); err != nil {
+			t.Fatalf("Failed to record consent: %v", err)
+		}
+	}
+	ta.LoginAs("alice")
+
+	// Publishing a new version requires accepting it again
+	ta.consent.Required["tos"] = "2"
+	pending, err := ta.Users().PendingConsents(user.ID)
+	if err != nil {
+		t.Fatalf("Failed to get pending consents: %v", err)
+	}
+	if len(pending) != 1 || pending["tos"] != "2" {
+		t.Errorf("Expected tos version 2 to be pending, got %v", pending)
+	}
+	if _, err := ta.Login("alice", "alice-password", nil); err == nil {
+		t.Error("Expected login to require the new terms")
+	}
+	if err := ta.Users().RecordConsent(user.ID, "tos", "2"); err != nil {
+		t.Fatalf("Failed to record consent: %v", err)
+	}
+	ta.LoginAs("alice")
+
+	history, err := ta.Users().ConsentHistory(user.ID)
+	if err != nil {
+		t.Fatalf("Failed to get consent history: %v", err)
+	}
+	if len(history) != 3 || history[0].DocumentID != "tos" || history[0].Version != "2" {
+		t.Errorf("Expected three consents, newest first, got %+v", history)
+	}
+}
+
+func TestRecordConsentValidation(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("bob", "bob-password")
+
+	if err := ta.Users().RecordConsent(user.ID, "", "1"); err == nil {
+		t.Error("Expected missing document ID to be rejected")
+	}
+	if err := ta.Users().RecordConsent("missing", "tos", "1"); err == nil {
+		t.Error("Expected unknown user to be rejected")
+	}
+}
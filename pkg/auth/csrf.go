@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CSRFTokenKey is the context key under which CSRF middleware stores the
+// token to embed in rendered forms.
+const CSRFTokenKey UserContextKey = "auth_csrf_token"
+
+// CSRFConfig configures the CSRF token service.
+type CSRFConfig struct {
+	// TTL is how long a token stays valid. Defaults to 12 hours.
+	TTL time.Duration
+	// HeaderName is the request and response header carrying the token.
+	// Defaults to "X-CSRF-Token".
+	HeaderName string
+	// FormField is the form field checked when the header is absent.
+	// Defaults to "csrf_token".
+	FormField string
+	// Binding returns the session or user identifier a token is bound to.
+	// Defaults to the ID of the user authenticated by the go-auth middleware.
+	Binding func(r *http.Request) string
+}
+
+// CSRF issues and validates stateless CSRF tokens bound to a session or
+// user ID. It does not rely on cookies.
+type CSRF struct {
+	key        []byte
+	config     CSRFConfig
+	clock      Clock
+	translator *translatorSwitch
+}
+
+// NewCSRF creates a CSRF token service signing tokens with key.
+func NewCSRF(key []byte, config CSRFConfig, clock Clock) *CSRF {
+	if config.TTL == 0 {
+		config.TTL = 12 * time.Hour
+	}
+	if config.HeaderName == "" {
+		config.HeaderName = "X-CSRF-Token"
+	}
+	if config.FormField == "" {
+		config.FormField = "csrf_token"
+	}
+	if config.Binding == nil {
+		config.Binding = userBinding
+	}
+	return &CSRF{key: key, config: config, clock: clock}
+}
+
+// userBinding returns the authenticated user's ID from the request context.
+func userBinding(r *http.Request) string {
+	if user, ok := GetUserFromContext(r.Context()); ok {
+		return user.ID
+	}
+	return ""
+}
+
+// CSRF returns a CSRF token service keyed from SigningKeys.CSRFSecret, or
+// from the JWT secret when it is not set.
+func (a *Auth) CSRF(config CSRFConfig) *CSRF {
+	csrf := NewCSRF(a.csrfKey, config, a.clock)
+	csrf.translator = a.translator
+	return csrf
+}
+
+// Generate creates a token bound to binding (typically a session or user ID).
+// Format: base64url(nonce | expiry) "." base64url(hmac)
+func (c *CSRF) Generate(binding string) (string, error) {
+	if binding == "" {
+		return "", ErrValidationError("binding")
+	}
+
+	payload := make([]byte, 24)
+	if _, err := rand.Read(payload[:16]); err != nil {
+		return "", WrapError(err, ErrCodeInternalError, "Failed to generate CSRF token")
+	}
+	expiresAt := nowFrom(c.clock).Add(c.config.TTL).Unix()
+	binary.BigEndian.PutUint64(payload[16:], uint64(expiresAt))
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(c.sign(binding, payload)), nil
+}
+
+// Validate checks that token was issued for binding and has not expired.
+func (c *CSRF) Validate(token, binding string) error {
+	if token == "" {
+		return ErrInvalidCSRFToken("CSRF token is missing")
+	}
+	if binding == "" {
+		return ErrInvalidCSRFToken("CSRF token cannot be validated without a session")
+	}
+
+	encodedPayload, encodedMAC, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalidCSRFToken("Malformed CSRF token")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil || len(payload) != 24 {
+		return ErrInvalidCSRFToken("Malformed CSRF token")
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(encodedMAC)
+	if err != nil || !hmac.Equal(mac, c.sign(binding, payload)) {
+		return ErrInvalidCSRFToken("CSRF token does not match session")
+	}
+
+	expiresAt := time.Unix(int64(binary.BigEndian.Uint64(payload[16:])), 0)
+	if nowFrom(c.clock).After(expiresAt) {
+		return ErrInvalidCSRFToken("CSRF token has expired")
+	}
+	return nil
+}
+
+// sign computes the token MAC over the binding and payload.
+func (c *CSRF) sign(binding string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(binding))
+	mac.Write([]byte{0})
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// TokenFromRequest returns the submitted token from the header or form field.
+func (c *CSRF) TokenFromRequest(r *http.Request) string {
+	if token := r.Header.Get(c.config.HeaderName); token != "" {
+		return token
+	}
+	return r.FormValue(c.config.FormField)
+}
+
+// HeaderName returns the header used to send and receive tokens.
+func (c *CSRF) HeaderName() string {
+	return c.config.HeaderName
+}
+
+// FormField returns the form field checked for tokens.
+func (c *CSRF) FormField() string {
+	return c.config.FormField
+}
+
+// check validates the token of state-changing requests and returns a fresh
+// token to inject into the response. Safe methods are never rejected.
+func (c *CSRF) check(method, submitted, binding string) (string, error) {
+	if !isSafeMethod(method) {
+		if err := c.Validate(submitted, binding); err != nil {
+			return "", err
+		}
+	}
+	if binding == "" {
+		return "", nil
+	}
+	return c.Generate(binding)
+}
+
+// Protect is HTTP middleware that verifies tokens on state-changing requests
+// and injects a fresh token into the request context and response header.
+// Place it after the go-auth Protect or Optional middleware.
+func (c *CSRF) Protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := c.check(r.Method, c.TokenFromRequest(r), c.config.Binding(r))
+		if err != nil {
+			WriteLocalizedJSONError(w, r, err, c.translator.get())
+			return
+		}
+
+		if token != "" {
+			w.Header().Set(c.config.HeaderName, token)
+			r = r.WithContext(context.WithValue(r.Context(), CSRFTokenKey, token))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetCSRFTokenFromContext returns the token injected by CSRF middleware.
+func GetCSRFTokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(CSRFTokenKey).(string)
+	return token, ok
+}
+
+// isSafeMethod reports whether method is defined as safe by RFC 9110.
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
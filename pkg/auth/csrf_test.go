@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+func newTestCSRF() (*CSRF, *FrozenClock) {
+	clock := NewFrozenClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	return NewCSRF(deriveKey("test-secret", "go-auth csrf tokens"), CSRFConfig{TTL: time.Hour}, clock), clock
+}
+
+func TestCSRFTokens(t *testing.T) {
+	csrf, clock := newTestCSRF()
+
+	token, err := csrf.Generate("user-1")
+	if err != nil {
+		t.Fatalf("Failed to generate CSRF token: %v", err)
+	}
+	if err := csrf.Validate(token, "user-1"); err != nil {
+		t.Errorf("Expected token to validate, got %v", err)
+	}
+
+	invalid := ErrInvalidCSRFToken("")
+	if err := csrf.Validate(token, "user-2"); !errors.Is(err, invalid) {
+		t.Errorf("Expected token bound to another user to be rejected, got %v", err)
+	}
+	if err := csrf.Validate("garbage", "user-1"); !errors.Is(err, invalid) {
+		t.Errorf("Expected malformed token to be rejected, got %v", err)
+	}
+	if err := csrf.Validate("", "user-1"); !errors.Is(err, invalid) {
+		t.Errorf("Expected missing token to be rejected, got %v", err)
+	}
+
+	clock.Advance(2 * time.Hour)
+	if err := csrf.Validate(token, "user-1"); !errors.Is(err, invalid) {
+		t.Errorf("Expected expired token to be rejected, got %v", err)
+	}
+}
+
+func TestCSRFProtect(t *testing.T) {
+	csrf, _ := newTestCSRF()
+
+	var injected string
+	handler := csrf.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		injected, _ = GetCSRFTokenFromContext(r.Context())
+	}))
+
+	withUser := func(r *http.Request) *http.Request {
+		return r.WithContext(context.WithValue(r.Context(), UserKey, &models.UserProfile{ID: "user-1"}))
+	}
+
+	// Safe requests receive a token to embed in forms
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, withUser(httptest.NewRequest(http.MethodGet, "/form", nil)))
+	if rec.Code != http.StatusOK || injected == "" {
+		t.Fatalf("Expected GET to pass with an injected token, got %d", rec.Code)
+	}
+	if rec.Header().Get(csrf.HeaderName()) != injected {
+		t.Error("Expected the injected token in the response header")
+	}
+	token := injected
+
+	// Unsafe requests without a token are rejected
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, withUser(httptest.NewRequest(http.MethodPost, "/form", nil)))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for POST without token, got %d", rec.Code)
+	}
+
+	// Unsafe requests with a valid token pass
+	req := withUser(httptest.NewRequest(http.MethodPost, "/form", nil))
+	req.Header.Set(csrf.HeaderName(), token)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected POST with valid token to pass, got %d", rec.Code)
+	}
+
+	// Tokens cannot be replayed by another user
+	req = httptest.NewRequest(http.MethodPost, "/form", nil)
+	req = req.WithContext(context.WithValue(req.Context(), UserKey, &models.UserProfile{ID: "user-2"}))
+	req.Header.Set(csrf.HeaderName(), token)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected token of another user to be rejected, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"database/sql"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DebugInfo is a snapshot of internal state for production debugging. It
+// holds no secrets: sensitive configuration values are masked.
+type DebugInfo struct {
+	Time time.Time `json:"time"`
+	// Config is the configuration with secrets masked. Unset fields are
+	// omitted; functions and interfaces show their type.
+	Config        map[string]interface{} `json:"config"`
+	SchemaVersion int                    `json:"schema_version"`
+	// SchemaError is set if the schema version could not be read.
+	SchemaError     string                `json:"schema_error,omitempty"`
+	LoginCache      LoginCacheStats       `json:"login_cache"`
+	PermissionCache PermissionCacheStats  `json:"permission_cache"`
+	CircuitBreakers []CircuitBreakerStats `json:"circuit_breakers,omitempty"`
+	// Pool holds the connection pool stats of SQL storages.
+	Pool *sql.DBStats `json:"pool,omitempty"`
+	// Jobs holds the background jobs by name, e.g. "account_expiry".
+	Jobs map[string]JobStatus `json:"jobs,omitempty"`
+}
+
+// JobStatus describes the runs of a background job.
+type JobStatus struct {
+	Runs      int64     `json:"runs"`
+	LastRun   time.Time `json:"last_run"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// jobTracker records the runs of background jobs.
+type jobTracker struct {
+	mu   sync.Mutex
+	jobs map[string]JobStatus
+}
+
+func newJobTracker() *jobTracker {
+	return &jobTracker{jobs: make(map[string]JobStatus)}
+}
+
+// record records a run of job that ended at now with err.
+func (t *jobTracker) record(job string, now time.Time, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	status := t.jobs[job]
+	status.Runs++
+	status.LastRun = now
+	status.LastError = ""
+	if err != nil {
+		status.LastError = err.Error()
+	}
+	t.jobs[job] = status
+}
+
+// snapshot returns a copy of the job statuses, nil without runs.
+func (t *jobTracker) snapshot() map[string]JobStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.jobs) == 0 {
+		return nil
+	}
+	jobs := make(map[string]JobStatus, len(t.jobs))
+	for name, status := range t.jobs {
+		jobs[name] = status
+	}
+	return jobs
+}
+
+// poolStorage is implemented by SQL storages exposing connection pool stats.
+type poolStorage interface {
+	Stats() sql.DBStats
+}
+
+// DebugInfo returns a snapshot of the internal state.
+func (a *Auth) DebugInfo() DebugInfo {
+	info := DebugInfo{
+		Time:            a.clock.Now(),
+		Config:          sanitizeConfig(a.currentConfig()),
+		LoginCache:      a.LoginCacheStats(),
+		PermissionCache: a.permissions.Stats(),
+		CircuitBreakers: a.monitor.CircuitBreakers(),
+		Jobs:            a.jobs.snapshot(),
+	}
+	if version, err := a.migrationManager.GetCurrentVersion(); err != nil {
+		info.SchemaError = err.Error()
+	} else {
+		info.SchemaVersion = version
+	}
+	if pool, ok := baseStorage(a.storage).(poolStorage); ok {
+		stats := pool.Stats()
+		info.Pool = &stats
+	}
+	return info
+}
+
+// DebugHandlerConfig configures DebugHandler.
+type DebugHandlerConfig struct {
+	// Authorize decides whether a request may read the debug info. Defaults
+	// to allowing loopback clients only.
+	Authorize func(r *http.Request) bool
+}
+
+// DebugHandler returns an HTTP handler answering GET requests with the
+// JSON-encoded DebugInfo. It is not mounted by default; expose it on an
+// internal address only.
+func (a *Auth) DebugHandler(config DebugHandlerConfig) http.Handler {
+	if config.Authorize == nil {
+		config.Authorize = loopbackClient
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if !config.Authorize(r) {
+			a.writeError(w, r, ErrPermissionDenied("debug info"))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if err := json.NewEncoder(w).Encode(a.DebugInfo()); err != nil {
+			a.logger.Error("Failed to encode debug info", map[string]interface{}{
+				"error": err,
+			})
+		}
+	})
+}
+
+// PublishExpvar publishes DebugInfo as the expvar variable name, served
+// with the other expvar variables at /debug/vars. Like expvar.Publish, it
+// panics if name is already published.
+func (a *Auth) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() interface{} {
+		return a.DebugInfo()
+	}))
+}
+
+// loopbackClient reports whether r comes from the local host.
+func loopbackClient(r *http.Request) bool {
+	ip := net.ParseIP(clientIP(r))
+	return ip != nil && ip.IsLoopback()
+}
+
+// sensitiveField matches configuration field names whose values are masked.
+var sensitiveField = regexp.MustCompile(`(?i)(secret|password|credential|token$|key$|keys$)`)
+
+// sanitizeConfig returns the set fields of config with secrets masked.
+func sanitizeConfig(config *AuthConfig) map[string]interface{} {
+	if config == nil {
+		return nil
+	}
+	fields, _ := sanitizeValue("", reflect.ValueOf(*config)).(map[string]interface{})
+	return fields
+}
+
+var (
+	durationType   = reflect.TypeOf(time.Duration(0))
+	authConfigType = reflect.TypeOf(AuthConfig{})
+)
+
+// sanitizeValue returns a JSON-friendly copy of v, a field named name, or
+// nil if it is unset.
+func sanitizeValue(name string, v reflect.Value) interface{} {
+	if !v.IsValid() || v.IsZero() {
+		return nil
+	}
+	if sensitiveField.MatchString(name) {
+		return "[HIDDEN]"
+	}
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	if v.Type() == timeType {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return v.Interface()
+	case reflect.String:
+		if strings.HasSuffix(name, "URL") {
+			return redactURL(v.String())
+		}
+		return v.String()
+	case reflect.Ptr:
+		return sanitizeValue(name, v.Elem())
+	case reflect.Struct:
+		if v.Type().PkgPath() != authConfigType.PkgPath() {
+			return fmt.Sprintf("%T", v.Interface())
+		}
+		fields := make(map[string]interface{})
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if value := sanitizeValue(field.Name, v.Field(i)); value != nil {
+				fields[field.Name] = value
+			}
+		}
+		if len(fields) == 0 {
+			return nil
+		}
+		return fields
+	case reflect.Slice, reflect.Array:
+		items := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, sanitizeValue("", v.Index(i)))
+		}
+		return items
+	case reflect.Map:
+		keys := make([]string, 0, v.Len())
+		for _, key := range v.MapKeys() {
+			keys = append(keys, fmt.Sprint(key.Interface()))
+		}
+		sort.Strings(keys)
+		return keys
+	}
+	// Functions, interfaces and channels
+	return fmt.Sprintf("%T", v.Interface())
+}
+
+// passwordParameter matches password parameters of connection strings.
+var passwordParameter = regexp.MustCompile(`(?i)(password=)[^&\s]*`)
+
+// redactURL masks the passwords of a URL or connection string such as a
+// database URL.
+func redactURL(raw string) string {
+	if u, err := url.Parse(raw); err == nil {
+		raw = u.Redacted()
+	}
+	return passwordParameter.ReplaceAllString(raw, "${1}xxxxx")
+}
@@ -0,0 +1,369 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+// Decorator wraps one call of a decorated AuthAPI, UsersAPI or TokensAPI.
+// op names the method, e.g. "Users.Get". A decorator runs call, passing
+// on ctx or a context derived from it, and returns its error; it may also
+// refuse to run it. ctx is context.Background() for methods without one.
+type Decorator func(ctx context.Context, op string, call func(ctx context.Context) error) error
+
+// Tracer starts spans for WithTracing. Adapt an OpenTelemetry tracer or
+// similar to it.
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is a span started by a Tracer.
+type Span interface {
+	// End ends the span, marking it failed if err is not nil.
+	End(err error)
+}
+
+// WithLogging logs every call with its duration, at debug level on success
+// and at warn level on failure.
+func WithLogging(logger *Logger) Decorator {
+	return func(ctx context.Context, op string, call func(ctx context.Context) error) error {
+		start := time.Now()
+		err := call(ctx)
+		fields := map[string]interface{}{
+			"operation": op,
+			"duration":  time.Since(start),
+		}
+		if err != nil {
+			fields["error"] = err
+			logger.Warn("Auth call failed", fields)
+		} else {
+			logger.Debug("Auth call", fields)
+		}
+		return err
+	}
+}
+
+// WithMetrics records the latency and errors of every call in metrics, see
+// Metrics.APICalls, and per tenant when ctx carries one, see WithTenant.
+func WithMetrics(metrics *MetricsCollector) Decorator {
+	return func(ctx context.Context, op string, call func(ctx context.Context) error) error {
+		start := time.Now()
+		err := call(ctx)
+		metrics.RecordAPICall(op, time.Since(start), err != nil)
+		metrics.RecordTenantAPICall(tenantFrom(ctx), err != nil)
+		return err
+	}
+}
+
+// WithTracing runs every call in a span named after the operation. Methods
+// taking a context see the span's context.
+func WithTracing(tracer Tracer) Decorator {
+	return func(ctx context.Context, op string, call func(ctx context.Context) error) error {
+		ctx, span := tracer.Start(ctx, op)
+		err := call(ctx)
+		span.End(err)
+		return err
+	}
+}
+
+// WithCircuitBreaker runs every call through breaker. Only outages count as
+// failures: errors other than AuthErrors, and AuthErrors with a database,
+// storage, connection or internal error code. Answers such as invalid
+// credentials or an expired token pass through without tripping it.
+func WithCircuitBreaker(breaker *CircuitBreaker) Decorator {
+	return func(ctx context.Context, op string, call func(ctx context.Context) error) error {
+		var answer error
+		err := breaker.Do(func() error {
+			err := call(ctx)
+			if err != nil && !isOutage(err) {
+				answer = err
+				return nil
+			}
+			return err
+		})
+		if answer != nil {
+			return answer
+		}
+		return err
+	}
+}
+
+// isOutage reports whether err signals a failing dependency rather than an
+// answer to the caller.
+func isOutage(err error) bool {
+	var authErr *AuthError
+	if !errors.As(err, &authErr) {
+		return isStorageFailure(err)
+	}
+	switch authErr.Code {
+	case ErrCodeDatabaseError, ErrCodeStorageError, ErrCodeConnectionError, ErrCodeInternalError:
+		return true
+	}
+	return false
+}
+
+// decorators runs calls through a list of decorators, the first outermost.
+type decorators []Decorator
+
+// run calls fn through the decorators.
+func (d decorators) run(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	call := fn
+	for i := len(d) - 1; i >= 0; i-- {
+		decorator, next := d[i], call
+		call = func(ctx context.Context) error {
+			return decorator(ctx, op, next)
+		}
+	}
+	return call(ctx)
+}
+
+// decoratedCall is decorators.run for calls returning a value.
+func decoratedCall[T any](d decorators, ctx context.Context, op string, fn func(ctx context.Context) (T, error)) (T, error) {
+	var result T
+	err := d.run(ctx, op, func(ctx context.Context) error {
+		var err error
+		result, err = fn(ctx)
+		return err
+	})
+	return result, err
+}
+
+// DecorateAuth wraps every method of a, except Close, in the decorators.
+// The first decorator is outermost, so
+// DecorateAuth(a, WithLogging(l), WithCircuitBreaker(b)) also logs the
+// calls the circuit breaker rejects.
+func DecorateAuth(a AuthAPI, decorate ...Decorator) AuthAPI {
+	return &decoratedAuth{next: a, d: decorators(decorate)}
+}
+
+// DecorateUsers wraps every method of u in the decorators, see DecorateAuth.
+func DecorateUsers(u UsersAPI, decorate ...Decorator) UsersAPI {
+	return &decoratedUsers{next: u, d: decorators(decorate)}
+}
+
+// DecorateTokens wraps every method of t in the decorators, see
+// DecorateAuth.
+func DecorateTokens(t TokensAPI, decorate ...Decorator) TokensAPI {
+	return &decoratedTokens{next: t, d: decorators(decorate)}
+}
+
+// decoratedAuth is an AuthAPI whose calls run through decorators.
+type decoratedAuth struct {
+	next AuthAPI
+	d    decorators
+}
+
+func (a *decoratedAuth) Register(payload RegisterRequest) (*models.User, error) {
+	return a.RegisterContext(context.Background(), payload)
+}
+
+func (a *decoratedAuth) RegisterContext(ctx context.Context, payload RegisterRequest) (*models.User, error) {
+	return decoratedCall(a.d, ctx, "Auth.Register", func(ctx context.Context) (*models.User, error) {
+		return a.next.RegisterContext(ctx, payload)
+	})
+}
+
+func (a *decoratedAuth) Login(username, password string, customClaims map[string]interface{}) (*LoginResult, error) {
+	return a.LoginContext(context.Background(), username, password, customClaims)
+}
+
+func (a *decoratedAuth) LoginContext(ctx context.Context, username, password string, customClaims map[string]interface{}) (*LoginResult, error) {
+	return decoratedCall(a.d, ctx, "Auth.Login", func(ctx context.Context) (*LoginResult, error) {
+		return a.next.LoginContext(ctx, username, password, customClaims)
+	})
+}
+
+func (a *decoratedAuth) ValidateAccessToken(tokenString string) (jwt.MapClaims, error) {
+	return decoratedCall(a.d, context.Background(), "Auth.ValidateAccessToken", func(context.Context) (jwt.MapClaims, error) {
+		return a.next.ValidateAccessToken(tokenString)
+	})
+}
+
+func (a *decoratedAuth) ValidateRefreshToken(tokenString string) (jwt.MapClaims, error) {
+	return decoratedCall(a.d, context.Background(), "Auth.ValidateRefreshToken", func(context.Context) (jwt.MapClaims, error) {
+		return a.next.ValidateRefreshToken(tokenString)
+	})
+}
+
+func (a *decoratedAuth) RefreshToken(refreshToken string) (*RefreshResult, error) {
+	return decoratedCall(a.d, context.Background(), "Auth.RefreshToken", func(context.Context) (*RefreshResult, error) {
+		return a.next.RefreshToken(refreshToken)
+	})
+}
+
+func (a *decoratedAuth) GetUser(userID string) (*models.UserProfile, error) {
+	return decoratedCall(a.d, context.Background(), "Auth.GetUser", func(context.Context) (*models.UserProfile, error) {
+		return a.next.GetUser(userID)
+	})
+}
+
+func (a *decoratedAuth) GetUserByUsername(username string) (*models.UserProfile, error) {
+	return decoratedCall(a.d, context.Background(), "Auth.GetUserByUsername", func(context.Context) (*models.UserProfile, error) {
+		return a.next.GetUserByUsername(username)
+	})
+}
+
+func (a *decoratedAuth) GetUserByEmail(email string) (*models.UserProfile, error) {
+	return decoratedCall(a.d, context.Background(), "Auth.GetUserByEmail", func(context.Context) (*models.UserProfile, error) {
+		return a.next.GetUserByEmail(email)
+	})
+}
+
+func (a *decoratedAuth) Health() error {
+	return a.d.run(context.Background(), "Auth.Health", func(context.Context) error {
+		return a.next.Health()
+	})
+}
+
+func (a *decoratedAuth) Close() {
+	a.next.Close()
+}
+
+// decoratedUsers is a UsersAPI whose calls run through decorators.
+type decoratedUsers struct {
+	next UsersAPI
+	d    decorators
+}
+
+func (u *decoratedUsers) Get(userID string) (*models.UserProfile, error) {
+	return decoratedCall(u.d, context.Background(), "Users.Get", func(context.Context) (*models.UserProfile, error) {
+		return u.next.Get(userID)
+	})
+}
+
+func (u *decoratedUsers) GetByEmail(email string) (*models.UserProfile, error) {
+	return decoratedCall(u.d, context.Background(), "Users.GetByEmail", func(context.Context) (*models.UserProfile, error) {
+		return u.next.GetByEmail(email)
+	})
+}
+
+func (u *decoratedUsers) GetByUsername(username string) (*models.UserProfile, error) {
+	return decoratedCall(u.d, context.Background(), "Users.GetByUsername", func(context.Context) (*models.UserProfile, error) {
+		return u.next.GetByUsername(username)
+	})
+}
+
+func (u *decoratedUsers) List(limit, offset int) ([]*models.UserProfile, error) {
+	return decoratedCall(u.d, context.Background(), "Users.List", func(context.Context) ([]*models.UserProfile, error) {
+		return u.next.List(limit, offset)
+	})
+}
+
+func (u *decoratedUsers) Update(userID string, updates UserUpdate) error {
+	return u.d.run(context.Background(), "Users.Update", func(context.Context) error {
+		return u.next.Update(userID, updates)
+	})
+}
+
+func (u *decoratedUsers) Delete(userID string) error {
+	return u.d.run(context.Background(), "Users.Delete", func(context.Context) error {
+		return u.next.Delete(userID)
+	})
+}
+
+func (u *decoratedUsers) ChangePassword(userID, oldPassword, newPassword string) error {
+	return u.ChangePasswordContext(context.Background(), userID, oldPassword, newPassword)
+}
+
+func (u *decoratedUsers) ChangePasswordContext(ctx context.Context, userID, oldPassword, newPassword string) error {
+	return u.d.run(ctx, "Users.ChangePassword", func(ctx context.Context) error {
+		return u.next.ChangePasswordContext(ctx, userID, oldPassword, newPassword)
+	})
+}
+
+func (u *decoratedUsers) CreateResetToken(email string) (*ResetToken, error) {
+	return decoratedCall(u.d, context.Background(), "Users.CreateResetToken", func(context.Context) (*ResetToken, error) {
+		return u.next.CreateResetToken(email)
+	})
+}
+
+func (u *decoratedUsers) ResetPassword(token, newPassword string) error {
+	return u.ResetPasswordContext(context.Background(), token, newPassword)
+}
+
+func (u *decoratedUsers) ResetPasswordContext(ctx context.Context, token, newPassword string) error {
+	return u.d.run(ctx, "Users.ResetPassword", func(ctx context.Context) error {
+		return u.next.ResetPasswordContext(ctx, token, newPassword)
+	})
+}
+
+// decoratedTokens is a TokensAPI whose calls run through decorators.
+type decoratedTokens struct {
+	next TokensAPI
+	d    decorators
+}
+
+func (t *decoratedTokens) Issue(userID string, opts IssueOptions) (*LoginResult, error) {
+	return decoratedCall(t.d, context.Background(), "Tokens.Issue", func(context.Context) (*LoginResult, error) {
+		return t.next.Issue(userID, opts)
+	})
+}
+
+func (t *decoratedTokens) Refresh(refreshToken string) (*RefreshResult, error) {
+	return decoratedCall(t.d, context.Background(), "Tokens.Refresh", func(context.Context) (*RefreshResult, error) {
+		return t.next.Refresh(refreshToken)
+	})
+}
+
+func (t *decoratedTokens) Validate(tokenString string) (*models.User, error) {
+	return decoratedCall(t.d, context.Background(), "Tokens.Validate", func(context.Context) (*models.User, error) {
+		return t.next.Validate(tokenString)
+	})
+}
+
+// IsValid reports false if a decorator refuses the call.
+func (t *decoratedTokens) IsValid(tokenString string) bool {
+	valid, err := decoratedCall(t.d, context.Background(), "Tokens.IsValid", func(context.Context) (bool, error) {
+		return t.next.IsValid(tokenString), nil
+	})
+	return err == nil && valid
+}
+
+// ValidateBatch reports every token invalid if a decorator refuses the
+// call.
+func (t *decoratedTokens) ValidateBatch(tokens []string) []ValidationResult {
+	results, err := decoratedCall(t.d, context.Background(), "Tokens.ValidateBatch", func(context.Context) ([]ValidationResult, error) {
+		return t.next.ValidateBatch(tokens), nil
+	})
+	if err != nil {
+		results = make([]ValidationResult, len(tokens))
+		for i := range results {
+			results[i] = ValidationResult{Valid: false, Error: err.Error()}
+		}
+	}
+	return results
+}
+
+func (t *decoratedTokens) Revoke(tokenString string) error {
+	return t.d.run(context.Background(), "Tokens.Revoke", func(context.Context) error {
+		return t.next.Revoke(tokenString)
+	})
+}
+
+func (t *decoratedTokens) RevokeAll(userID string) error {
+	return t.d.run(context.Background(), "Tokens.RevokeAll", func(context.Context) error {
+		return t.next.RevokeAll(userID)
+	})
+}
+
+func (t *decoratedTokens) GetSessionInfo(tokenString string) (*SessionInfo, error) {
+	return decoratedCall(t.d, context.Background(), "Tokens.GetSessionInfo", func(context.Context) (*SessionInfo, error) {
+		return t.next.GetSessionInfo(tokenString)
+	})
+}
+
+func (t *decoratedTokens) ListActiveSessions(userID string) ([]*SessionInfo, error) {
+	return decoratedCall(t.d, context.Background(), "Tokens.ListActiveSessions", func(context.Context) ([]*SessionInfo, error) {
+		return t.next.ListActiveSessions(userID)
+	})
+}
+
+func (t *decoratedTokens) CleanupExpired() error {
+	return t.d.run(context.Background(), "Tokens.CleanupExpired", func(context.Context) error {
+		return t.next.CleanupExpired()
+	})
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+// failingUsers is a UsersAPI whose Get returns err.
+type failingUsers struct {
+	UsersAPI
+	err   error
+	calls int
+}
+
+func (f *failingUsers) Get(userID string) (*models.UserProfile, error) {
+	f.calls++
+	return nil, f.err
+}
+
+// recordingTracer records the spans it starts and how they ended.
+type recordingTracer struct {
+	started []string
+	ended   []error
+}
+
+type recordingSpan struct {
+	tracer *recordingTracer
+}
+
+type spanKey struct{}
+
+func (r *recordingTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	r.started = append(r.started, name)
+	return context.WithValue(ctx, spanKey{}, name), recordingSpan{tracer: r}
+}
+
+func (s recordingSpan) End(err error) {
+	s.tracer.ended = append(s.tracer.ended, err)
+}
+
+func TestDecorators_LoggingAndMetrics(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("alice", TestPassword)
+
+	var logs bytes.Buffer
+	metrics := NewMetricsCollector()
+	api := DecorateAuth(ta.Auth, WithLogging(NewLogger(LogLevelDebug, &logs)), WithMetrics(metrics))
+
+	if _, err := api.Login("alice", TestPassword, nil); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if _, err := api.Login("alice", "wrong-password", nil); err == nil {
+		t.Fatal("Expected login with wrong password to fail")
+	}
+
+	login := metrics.GetMetrics().APICalls["Auth.Login"]
+	if login.Calls != 2 || login.Errors != 1 {
+		t.Errorf("Expected 2 calls and 1 error, got %+v", login)
+	}
+	if !strings.Contains(logs.String(), "Auth.Login") || !strings.Contains(logs.String(), "Auth call failed") {
+		t.Errorf("Expected logged calls, got %s", logs.String())
+	}
+}
+
+func TestDecorators_Tracing(t *testing.T) {
+	ta := NewTestAuth(t)
+	tracer := &recordingTracer{}
+
+	var seen interface{}
+	trace := WithTracing(tracer)
+	inner := Decorator(func(ctx context.Context, op string, call func(ctx context.Context) error) error {
+		seen = ctx.Value(spanKey{})
+		return call(ctx)
+	})
+	api := DecorateAuth(ta.Auth, trace, inner)
+
+	if _, err := api.RegisterContext(context.Background(), RegisterRequest{
+		Username: "bob",
+		Email:    "bob@example.com",
+		Password: TestPassword,
+	}); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if _, err := api.GetUser("missing"); err == nil {
+		t.Fatal("Expected missing user lookup to fail")
+	}
+
+	if len(tracer.started) != 2 || tracer.started[0] != "Auth.Register" || tracer.started[1] != "Auth.GetUser" {
+		t.Errorf("Unexpected spans: %v", tracer.started)
+	}
+	if tracer.ended[0] != nil || tracer.ended[1] == nil {
+		t.Errorf("Expected only the second span to fail, got %v", tracer.ended)
+	}
+	if seen != "Auth.GetUser" {
+		t.Errorf("Expected inner decorator to see the span context, got %v", seen)
+	}
+}
+
+func TestDecorators_CircuitBreaker(t *testing.T) {
+	breaker := NewCircuitBreaker("users", CircuitBreakerConfig{FailureThreshold: 2})
+
+	// Answers do not trip the breaker
+	notFound := &failingUsers{err: ErrUserNotFound()}
+	users := DecorateUsers(notFound, WithCircuitBreaker(breaker))
+	for i := 0; i < 3; i++ {
+		if _, err := users.Get("u1"); !errors.Is(err, ErrUserNotFound()) {
+			t.Fatalf("Expected user not found, got %v", err)
+		}
+	}
+	if breaker.State() != CircuitClosed {
+		t.Fatalf("Expected closed circuit, got %s", breaker.State())
+	}
+
+	// Outages do
+	down := &failingUsers{err: NewAuthError(ErrCodeStorageError, "storage unavailable")}
+	users = DecorateUsers(down, WithCircuitBreaker(breaker))
+	users.Get("u1")
+	users.Get("u1")
+	if breaker.State() != CircuitOpen {
+		t.Fatalf("Expected open circuit, got %s", breaker.State())
+	}
+	if _, err := users.Get("u1"); !errors.Is(err, ErrCircuitOpen("users")) {
+		t.Errorf("Expected circuit open error, got %v", err)
+	}
+	if down.calls != 2 {
+		t.Errorf("Expected the open circuit to skip the call, got %d calls", down.calls)
+	}
+}
+
+func TestDecorators_TokensRefused(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("carol", TestPassword)
+	result, err := ta.Login("carol", TestPassword, nil)
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	refuse := Decorator(func(ctx context.Context, op string, call func(ctx context.Context) error) error {
+		return ErrPermissionDenied(op)
+	})
+	tokens := DecorateTokens(ta.Tokens(), refuse)
+
+	if tokens.IsValid(result.AccessToken) {
+		t.Error("Expected refused IsValid to report false")
+	}
+	batch := tokens.ValidateBatch([]string{result.AccessToken})
+	if len(batch) != 1 || batch[0].Valid || batch[0].Error == "" {
+		t.Errorf("Expected refused batch result, got %+v", batch)
+	}
+	if !DecorateTokens(ta.Tokens()).IsValid(result.AccessToken) {
+		t.Error("Expected undecorated IsValid to report true")
+	}
+}
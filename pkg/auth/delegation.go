@@ -0,0 +1,318 @@
+package auth
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// DelegatedTokenType is the token_type claim of delegated tokens.
+const DelegatedTokenType = "delegated"
+
+// Grant is a user's consent for a service to act on their behalf.
+type Grant struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"user_id"`
+	ServiceID string     `json:"service_id"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Active reports whether the grant is neither revoked nor expired at now.
+func (g *Grant) Active(now time.Time) bool {
+	return g.RevokedAt == nil && now.Before(g.ExpiresAt)
+}
+
+// GrantStore persists delegation grants.
+type GrantStore interface {
+	SaveGrant(grant *Grant) error
+	// GetGrant returns the grant with id or ErrInvalidToken if it does not exist.
+	GetGrant(id string) (*Grant, error)
+	// ListGrants returns all grants of userID, newest first.
+	ListGrants(userID string) ([]*Grant, error)
+	// RevokeGrant marks the grant with id as revoked at the given time.
+	RevokeGrant(id string, at time.Time) error
+	// PurgeGrants deletes grants revoked or expired before the given time and
+	// returns how many were deleted.
+	PurgeGrants(endedBefore time.Time) (int, error)
+}
+
+// memoryGrantStore is an in-memory GrantStore.
+type memoryGrantStore struct {
+	mu     sync.RWMutex
+	grants map[string]*Grant
+}
+
+// NewMemoryGrantStore creates an in-memory GrantStore. Grants are lost on
+// restart; use a persistent GrantStore in production.
+func NewMemoryGrantStore() GrantStore {
+	return &memoryGrantStore{grants: make(map[string]*Grant)}
+}
+
+func (s *memoryGrantStore) SaveGrant(grant *Grant) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *grant
+	s.grants[grant.ID] = &stored
+	return nil
+}
+
+func (s *memoryGrantStore) GetGrant(id string) (*Grant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	grant, ok := s.grants[id]
+	if !ok {
+		return nil, ErrInvalidToken()
+	}
+	result := *grant
+	return &result, nil
+}
+
+func (s *memoryGrantStore) ListGrants(userID string) ([]*Grant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var grants []*Grant
+	for _, grant := range s.grants {
+		if grant.UserID == userID {
+			result := *grant
+			grants = append(grants, &result)
+		}
+	}
+	sort.Slice(grants, func(i, j int) bool {
+		return grants[i].CreatedAt.After(grants[j].CreatedAt)
+	})
+	return grants, nil
+}
+
+func (s *memoryGrantStore) RevokeGrant(id string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	grant, ok := s.grants[id]
+	if !ok {
+		return ErrInvalidToken()
+	}
+	if grant.RevokedAt == nil {
+		grant.RevokedAt = &at
+	}
+	return nil
+}
+
+func (s *memoryGrantStore) PurgeGrants(endedBefore time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	purged := 0
+	for id, grant := range s.grants {
+		ended := grant.ExpiresAt
+		if grant.RevokedAt != nil && grant.RevokedAt.Before(ended) {
+			ended = *grant.RevokedAt
+		}
+		if ended.Before(endedBefore) {
+			delete(s.grants, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// DelegatedToken is a token a service uses to act on behalf of a user.
+type DelegatedToken struct {
+	Token     string    `json:"token"`
+	Grant     *Grant    `json:"grant"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// DelegatedClaims describes a verified delegated token.
+type DelegatedClaims struct {
+	GrantID   string    `json:"grant_id"`
+	UserID    string    `json:"user_id"`
+	ServiceID string    `json:"service_id"`
+	Scopes    []string  `json:"scopes"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// HasScope reports whether the token grants scope.
+func (c *DelegatedClaims) HasScope(scope string) bool {
+	for _, s := range c.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IssueDelegatedToken lets userID grant serviceID limited, long-running access
+// on their behalf, e.g. for background jobs. A consent record is stored and
+// can be listed and revoked with Tokens().ListGrants and Tokens().RevokeGrant.
+//
+// Delegated tokens are signed with a separate key and are never accepted as
+// access tokens; services verify them with Tokens().ValidateDelegated, which
+// also checks that the grant has not been revoked.
+func (a *Auth) IssueDelegatedToken(userID, serviceID string, scopes []string, ttl time.Duration) (*DelegatedToken, error) {
+	return a.Tokens().issueDelegated(userID, serviceID, scopes, ttl)
+}
+
+func (t *Tokens) issueDelegated(userID, serviceID string, scopes []string, ttl time.Duration) (*DelegatedToken, error) {
+	if userID == "" {
+		return nil, ErrValidationError("user ID")
+	}
+	if serviceID == "" {
+		return nil, ErrValidationError("service ID")
+	}
+	if len(scopes) == 0 {
+		return nil, ErrValidationError("scopes")
+	}
+	if ttl <= 0 {
+		return nil, ErrValidationError("ttl")
+	}
+
+	user, err := t.storage.GetUserByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound()
+	}
+	if !user.IsActive {
+		return nil, ErrUserInactive()
+	}
+	if err := checkAccountExpiry(user, t.clock); err != nil {
+		return nil, err
+	}
+
+	now := nowFrom(t.clock)
+	grant := &Grant{
+		ID:        uuid.New().String(),
+		UserID:    userID,
+		ServiceID: serviceID,
+		Scopes:    append([]string(nil), scopes...),
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	claims := jwt.MapClaims{
+		"sub":        userID,
+		"act":        map[string]string{"sub": serviceID},
+		"scope":      strings.Join(grant.Scopes, " "),
+		"iat":        now.Unix(),
+		"exp":        grant.ExpiresAt.Unix(),
+		"jti":        grant.ID,
+		"token_type": DelegatedTokenType,
+	}
+	if t.epoch != nil {
+		claims[epochClaim] = t.epoch.current()
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(t.delegationKey)
+	if err == nil {
+		err = t.grants.SaveGrant(grant)
+	}
+	if t.eventLogger != nil {
+		t.eventLogger.LogGrant("issue", userID, serviceID, grant.ID, err == nil, err)
+	}
+	if err != nil {
+		return nil, WrapError(err, ErrCodeInternalError, "Failed to issue delegated token")
+	}
+
+	return &DelegatedToken{Token: signed, Grant: grant, ExpiresAt: grant.ExpiresAt}, nil
+}
+
+// ValidateDelegated verifies a delegated token and its grant and returns the
+// user, service and scopes it was issued for. Like access tokens, delegated
+// tokens stop validating once the account expires or InvalidateAllTokens is
+// called.
+func (t *Tokens) ValidateDelegated(tokenString string) (*DelegatedClaims, error) {
+	if tokenString == "" {
+		return nil, ErrMissingToken()
+	}
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		return t.delegationKey, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(func() time.Time { return nowFrom(t.clock) }),
+	)
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, NewAuthError(ErrCodeTokenExpired, "Delegated token has expired")
+		}
+		return nil, ErrInvalidToken()
+	}
+
+	mapClaims, _ := token.Claims.(jwt.MapClaims)
+	if tokenType, _ := mapClaims["token_type"].(string); tokenType != DelegatedTokenType {
+		return nil, ErrInvalidToken()
+	}
+	// InvalidateAllTokens revokes delegated tokens too
+	if t.epoch != nil {
+		if err := t.epoch.check(mapClaims); err != nil {
+			return nil, ErrInvalidToken()
+		}
+	}
+	grantID, _ := mapClaims["jti"].(string)
+
+	grant, err := t.grants.GetGrant(grantID)
+	if err != nil {
+		return nil, ErrInvalidToken()
+	}
+	if grant.RevokedAt != nil {
+		return nil, ErrTokenRevoked()
+	}
+	if !grant.Active(nowFrom(t.clock)) {
+		return nil, NewAuthError(ErrCodeTokenExpired, "Delegated token has expired")
+	}
+
+	user, err := t.storage.GetUserByID(grant.UserID)
+	if err != nil {
+		return nil, ErrUserNotFound()
+	}
+	if !user.IsActive {
+		return nil, ErrUserInactive()
+	}
+	if err := checkAccountExpiry(user, t.clock); err != nil {
+		return nil, err
+	}
+
+	return &DelegatedClaims{
+		GrantID:   grant.ID,
+		UserID:    grant.UserID,
+		ServiceID: grant.ServiceID,
+		Scopes:    grant.Scopes,
+		ExpiresAt: grant.ExpiresAt,
+	}, nil
+}
+
+// ListGrants returns the delegation grants of userID, newest first,
+// including revoked and expired ones.
+func (t *Tokens) ListGrants(userID string) ([]*Grant, error) {
+	if userID == "" {
+		return nil, ErrValidationError("user ID")
+	}
+	grants, err := t.grants.ListGrants(userID)
+	if err != nil {
+		return nil, WrapDatabaseError(err)
+	}
+	return grants, nil
+}
+
+// RevokeGrant revokes a delegation grant of userID. Tokens issued for the
+// grant stop validating immediately.
+func (t *Tokens) RevokeGrant(userID, grantID string) error {
+	grant, err := t.grants.GetGrant(grantID)
+	if err != nil || grant.UserID != userID {
+		// Do not reveal grants of other users
+		return NewAuthError(ErrCodeUserNotFound, "Grant not found")
+	}
+
+	err = t.grants.RevokeGrant(grantID, nowFrom(t.clock))
+	if t.eventLogger != nil {
+		t.eventLogger.LogGrant("revoke", userID, grant.ServiceID, grantID, err == nil, err)
+	}
+	if err != nil {
+		return WrapDatabaseError(err)
+	}
+	return nil
+}
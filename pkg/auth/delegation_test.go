@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDelegatedTokens(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("alice", "alice-password")
+	tokens := ta.Tokens()
+
+	delegated, err := ta.IssueDelegatedToken(user.ID, "backup-service", []string{"files:read"}, 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to issue delegated token: %v", err)
+	}
+
+	claims, err := tokens.ValidateDelegated(delegated.Token)
+	if err != nil {
+		t.Fatalf("Expected delegated token to validate, got %v", err)
+	}
+	if claims.UserID != user.ID || claims.ServiceID != "backup-service" || !claims.HasScope("files:read") {
+		t.Errorf("Unexpected delegated claims: %+v", claims)
+	}
+	if claims.HasScope("files:write") {
+		t.Error("Delegated token should only carry granted scopes")
+	}
+
+	if _, err := ta.ValidateAccessToken(delegated.Token); err == nil {
+		t.Error("Delegated token must not be accepted as an access token")
+	}
+
+	grants, err := tokens.ListGrants(user.ID)
+	if err != nil || len(grants) != 1 || grants[0].ID != claims.GrantID {
+		t.Fatalf("Expected one listed grant, got %v (%v)", grants, err)
+	}
+
+	other := ta.SeedUser("mallory", "mallory-password")
+	if err := tokens.RevokeGrant(other.ID, claims.GrantID); err == nil {
+		t.Error("Expected revoking another user's grant to fail")
+	}
+
+	if err := tokens.RevokeGrant(user.ID, claims.GrantID); err != nil {
+		t.Fatalf("Failed to revoke grant: %v", err)
+	}
+	if _, err := tokens.ValidateDelegated(delegated.Token); !errors.Is(err, ErrTokenRevoked()) {
+		t.Errorf("Expected revoked grant to be rejected, got %v", err)
+	}
+
+	grants, _ = tokens.ListGrants(user.ID)
+	if grants[0].RevokedAt == nil {
+		t.Error("Expected listed grant to be marked revoked")
+	}
+}
+
+func TestDelegatedTokenExpiry(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("alice", "alice-password")
+
+	delegated, err := ta.IssueDelegatedToken(user.ID, "reports", []string{"reports:generate"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to issue delegated token: %v", err)
+	}
+
+	ta.Clock.Advance(2 * time.Hour)
+	if _, err := ta.Tokens().ValidateDelegated(delegated.Token); !errors.Is(err, NewAuthError(ErrCodeTokenExpired, "")) {
+		t.Errorf("Expected expired delegated token error, got %v", err)
+	}
+
+	if _, err := ta.IssueDelegatedToken(user.ID, "reports", nil, time.Hour); err == nil {
+		t.Error("Expected delegation without scopes to be rejected")
+	}
+	if _, err := ta.IssueDelegatedToken("missing-user", "reports", []string{"x"}, time.Hour); !errors.Is(err, ErrUserNotFound()) {
+		t.Errorf("Expected unknown user to be rejected, got %v", err)
+	}
+}
+
+func TestDelegatedTokenAccountExpiryAndEpoch(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("alice", "alice-password")
+	tokens := ta.Tokens()
+
+	delegated, err := ta.IssueDelegatedToken(user.ID, "reports", []string{"reports:generate"}, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to issue delegated token: %v", err)
+	}
+	if err := ta.InvalidateAllTokens(); err != nil {
+		t.Fatalf("Failed to invalidate tokens: %v", err)
+	}
+	if _, err := tokens.ValidateDelegated(delegated.Token); !errors.Is(err, ErrInvalidToken()) {
+		t.Errorf("Expected the delegated token to be invalidated, got %v", err)
+	}
+
+	delegated, err = ta.IssueDelegatedToken(user.ID, "reports", []string{"reports:generate"}, 24*time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to issue delegated token: %v", err)
+	}
+	if _, err := tokens.ValidateDelegated(delegated.Token); err != nil {
+		t.Fatalf("Expected the new delegated token to validate, got %v", err)
+	}
+	if err := ta.Users().ExtendExpiry(user.ID, ta.Clock.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("ExtendExpiry failed: %v", err)
+	}
+	ta.Clock.Advance(time.Hour)
+	if _, err := tokens.ValidateDelegated(delegated.Token); !errors.Is(err, ErrAccountExpired()) {
+		t.Errorf("Expected ACCOUNT_EXPIRED for the delegated token, got %v", err)
+	}
+	if _, err := ta.IssueDelegatedToken(user.ID, "reports", []string{"reports:generate"}, time.Hour); !errors.Is(err, ErrAccountExpired()) {
+		t.Errorf("Expected ACCOUNT_EXPIRED when issuing, got %v", err)
+	}
+}
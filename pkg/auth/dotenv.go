@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"strings"
+)
+
+// DefaultDotEnvPath is the file WithDotEnv loads when given no path.
+const DefaultDotEnvPath = ".env"
+
+// WithDotEnv loads environment variables from a dotenv file, by default
+// DefaultDotEnvPath, before the configuration is read. Variables already
+// set in the environment take precedence, and a missing file is ignored.
+// The file is never loaded with the production profile, so a stray .env
+// cannot change a production deployment.
+func WithDotEnv(path string) EnvOption {
+	return func(o *envOptions) {
+		if path == "" {
+			path = DefaultDotEnvPath
+		}
+		o.dotEnv = path
+	}
+}
+
+// loadDotEnv sets the variables of the dotenv file at path that are not
+// set yet.
+func loadDotEnv(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	vars, err := parseDotEnv(file)
+	if err != nil {
+		return err
+	}
+	for _, v := range vars {
+		if _, set := os.LookupEnv(v[0]); set {
+			continue
+		}
+		if err := os.Setenv(v[0], v[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseDotEnv parses KEY=value lines in file order. Blank lines, comments
+// and an "export " prefix are skipped. Values may be single-quoted, taken
+// literally, or double-quoted, with \n, \t, \" and \\ escapes; unquoted
+// values end at " #".
+func parseDotEnv(r io.Reader) ([][2]string, error) {
+	var vars [][2]string
+	scanner := bufio.NewScanner(r)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("line %d: expected KEY=value", number)
+		}
+		value, err := parseDotEnvValue(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", number, err)
+		}
+		vars = append(vars, [2]string{key, value})
+	}
+	return vars, scanner.Err()
+}
+
+// parseDotEnvValue unquotes a dotenv value.
+func parseDotEnvValue(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	switch quote := value[0]; quote {
+	case '\'':
+		end := strings.IndexByte(value[1:], '\'')
+		if end < 0 {
+			return "", errors.New("unterminated single-quoted value")
+		}
+		return value[1 : end+1], nil
+	case '"':
+		var b strings.Builder
+		for i := 1; i < len(value); i++ {
+			c := value[i]
+			switch {
+			case c == '"':
+				return b.String(), nil
+			case c == '\\' && i+1 < len(value):
+				i++
+				switch value[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				default:
+					b.WriteByte(value[i])
+				}
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", errors.New("unterminated double-quoted value")
+	}
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value, nil
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDotEnv(t *testing.T) {
+	vars, err := parseDotEnv(strings.NewReader(`
+# Local development
+AUTH_DB_TYPE=sqlite
+export AUTH_APP_NAME="My App" # comment
+AUTH_JWT_ISSUER='literal \n value'
+AUTH_LOG_LEVEL=debug # trailing comment
+AUTH_EMPTY=
+AUTH_MULTILINE="line one\nline two"
+`))
+	require.NoError(t, err)
+	assert.Equal(t, [][2]string{
+		{"AUTH_DB_TYPE", "sqlite"},
+		{"AUTH_APP_NAME", "My App"},
+		{"AUTH_JWT_ISSUER", `literal \n value`},
+		{"AUTH_LOG_LEVEL", "debug"},
+		{"AUTH_EMPTY", ""},
+		{"AUTH_MULTILINE", "line one\nline two"},
+	}, vars)
+
+	_, err = parseDotEnv(strings.NewReader("NOT A VARIABLE"))
+	assert.ErrorContains(t, err, "line 1")
+	_, err = parseDotEnv(strings.NewReader(`AUTH_APP_NAME="unterminated`))
+	assert.Error(t, err)
+}
+
+func TestLoadConfigFromEnvWithDotEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".env")
+	require.NoError(t, os.WriteFile(path, []byte(`
+AUTH_JWT_ACCESS_SECRET=access-secret-from-dotenv-file-1234
+AUTH_JWT_REFRESH_SECRET=refresh-secret-from-dotenv-file-123
+AUTH_APP_NAME=from-dotenv
+AUTH_LOG_LEVEL=debug
+`), 0o600))
+
+	// The environment wins over the file
+	t.Setenv("AUTH_LOG_LEVEL", "warn")
+	for _, name := range []string{"AUTH_JWT_ACCESS_SECRET", "AUTH_JWT_REFRESH_SECRET", "AUTH_APP_NAME"} {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+
+	// Never loaded with the production profile
+	_, err := LoadConfigWithProfile("production", WithDotEnv(path))
+	require.Error(t, err)
+	_, set := os.LookupEnv("AUTH_APP_NAME")
+	assert.False(t, set)
+
+	config, err := LoadConfigFromEnv(WithDotEnv(path))
+	require.NoError(t, err)
+	assert.Equal(t, "from-dotenv", config.AppName)
+	assert.Equal(t, "warn", config.LogLevel)
+
+	// A missing file is ignored
+	_, err = LoadConfigFromEnv(WithDotEnv(filepath.Join(t.TempDir(), "missing.env")))
+	require.NoError(t, err)
+}
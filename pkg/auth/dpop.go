@@ -0,0 +1,432 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// DPoP (RFC 9449) binds access tokens to a key held by the client. Clients send
+// a signed proof JWT in the DPoP header; bound tokens carry the key's
+// thumbprint in the cnf.jkt claim and are presented with "Authorization: DPoP <token>".
+
+const (
+	// DPoPHeader is the request header carrying the DPoP proof JWT.
+	DPoPHeader = "DPoP"
+	// DPoPTokenType is the token_type returned for DPoP-bound tokens.
+	DPoPTokenType = "DPoP"
+	// dpopProofType is the required typ header of DPoP proofs.
+	dpopProofType = "dpop+jwt"
+)
+
+// DPoPConfig configures DPoP proof validation.
+type DPoPConfig struct {
+	// Required rejects access tokens that are not DPoP-bound.
+	Required bool
+	// ProofLifetime is how far a proof's iat may be from the current time.
+	// Defaults to one minute.
+	ProofLifetime time.Duration
+	// Algorithms lists the accepted proof signing algorithms.
+	// Defaults to ES256, ES384, ES512, RS256, PS256 and EdDSA.
+	Algorithms []string
+	// TrustForwardedProto uses the X-Forwarded-Proto header when rebuilding
+	// the request URL for htu checks. Enable it only behind a trusted proxy.
+	TrustForwardedProto bool
+}
+
+// DPoPProof holds the validated contents of a DPoP proof.
+type DPoPProof struct {
+	JKT      string    // RFC 7638 thumbprint of the proof key
+	JTI      string    // Unique proof identifier
+	Method   string    // HTTP method the proof was created for (htm)
+	URL      string    // HTTP URL the proof was created for (htu)
+	IssuedAt time.Time // Proof creation time (iat)
+}
+
+// DPoP validates DPoP proofs and tracks proof identifiers to prevent replay.
+type DPoP struct {
+	config DPoPConfig
+	clock  Clock
+
+	mu          sync.Mutex
+	seen        map[string]time.Time
+	lastCleanup time.Time
+}
+
+// NewDPoP creates a DPoP validator, applying defaults for unset fields.
+func NewDPoP(config DPoPConfig, clock Clock) *DPoP {
+	if config.ProofLifetime == 0 {
+		config.ProofLifetime = time.Minute
+	}
+	if len(config.Algorithms) == 0 {
+		config.Algorithms = []string{"ES256", "ES384", "ES512", "RS256", "PS256", "EdDSA"}
+	}
+	if clock == nil {
+		clock = SystemClock()
+	}
+	return &DPoP{
+		config: config,
+		clock:  clock,
+		seen:   make(map[string]time.Time),
+	}
+}
+
+// VerifyProof validates a DPoP proof for the given request method and URL.
+// When accessToken is non-empty the proof must contain a matching ath claim.
+// Each proof is accepted only once.
+func (d *DPoP) VerifyProof(proof, method, url, accessToken string) (*DPoPProof, error) {
+	if proof == "" {
+		return nil, ErrInvalidDPoPProof("Missing DPoP proof header")
+	}
+
+	var jkt string
+	token, err := jwt.Parse(proof, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); typ != dpopProofType {
+			return nil, fmt.Errorf("typ header must be %q", dpopProofType)
+		}
+		jwk, ok := token.Header["jwk"].(map[string]interface{})
+		if !ok {
+			return nil, errors.New("missing jwk header")
+		}
+		key, err := parsePublicJWK(jwk)
+		if err != nil {
+			return nil, err
+		}
+		jkt, err = JWKThumbprint(jwk)
+		return key, err
+	}, jwt.WithValidMethods(d.config.Algorithms), jwt.WithTimeFunc(d.clock.Now))
+	if err != nil {
+		return nil, ErrInvalidDPoPProof(err.Error())
+	}
+
+	claims, _ := token.Claims.(jwt.MapClaims)
+	jti, _ := claims["jti"].(string)
+	htm, _ := claims["htm"].(string)
+	htu, _ := claims["htu"].(string)
+	iat, _ := claims["iat"].(float64)
+	if jti == "" || htm == "" || htu == "" || iat == 0 {
+		return nil, ErrInvalidDPoPProof("Proof must contain jti, htm, htu and iat claims")
+	}
+
+	if htm != method {
+		return nil, ErrInvalidDPoPProof("Proof htm does not match request method")
+	}
+	if !sameHTU(htu, url) {
+		return nil, ErrInvalidDPoPProof("Proof htu does not match request URL")
+	}
+
+	issuedAt := time.Unix(int64(iat), 0)
+	now := d.clock.Now()
+	if issuedAt.Before(now.Add(-d.config.ProofLifetime)) || issuedAt.After(now.Add(d.config.ProofLifetime)) {
+		return nil, ErrInvalidDPoPProof("Proof iat is outside the acceptable window")
+	}
+
+	if accessToken != "" {
+		ath, _ := claims["ath"].(string)
+		if ath != accessTokenHash(accessToken) {
+			return nil, ErrInvalidDPoPProof("Proof ath does not match access token")
+		}
+	}
+
+	if !d.markSeen(jkt+":"+jti, issuedAt.Add(d.config.ProofLifetime), now) {
+		return nil, ErrInvalidDPoPProof("Proof has already been used")
+	}
+
+	return &DPoPProof{
+		JKT:      jkt,
+		JTI:      jti,
+		Method:   htm,
+		URL:      htu,
+		IssuedAt: issuedAt,
+	}, nil
+}
+
+// VerifyRequest validates the DPoP proof sent with r. Pass the access token
+// for resource requests, or an empty string at the token endpoint.
+func (d *DPoP) VerifyRequest(r *http.Request, accessToken string) (*DPoPProof, error) {
+	proofs := r.Header.Values(DPoPHeader)
+	if len(proofs) > 1 {
+		return nil, ErrInvalidDPoPProof("Multiple DPoP proof headers")
+	}
+	var proof string
+	if len(proofs) == 1 {
+		proof = proofs[0]
+	}
+	return d.VerifyProof(proof, r.Method, d.requestURL(r), accessToken)
+}
+
+// checkRequest enforces token binding for a request authenticated with the
+// given Authorization scheme and validated access token claims.
+func (d *DPoP) checkRequest(r *http.Request, scheme, accessToken string, claims jwt.MapClaims) error {
+	jkt := boundJKT(claims)
+
+	if scheme != "dpop" {
+		if jkt != "" {
+			return ErrInvalidDPoPProof("DPoP-bound token must use the DPoP authorization scheme")
+		}
+		if d.config.Required {
+			return ErrInvalidDPoPProof("Access token must be DPoP-bound")
+		}
+		return nil
+	}
+
+	if jkt == "" {
+		return ErrInvalidDPoPProof("Access token is not DPoP-bound")
+	}
+	proof, err := d.VerifyRequest(r, accessToken)
+	if err != nil {
+		return err
+	}
+	if proof.JKT != jkt {
+		return ErrInvalidDPoPProof("Proof key does not match token binding")
+	}
+	return nil
+}
+
+// markSeen records a proof identifier until expiresAt and reports whether it
+// was new. Expired entries are pruned at most once per proof lifetime.
+func (d *DPoP) markSeen(key string, expiresAt, now time.Time) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if now.Sub(d.lastCleanup) > d.config.ProofLifetime {
+		for k, exp := range d.seen {
+			if now.After(exp) {
+				delete(d.seen, k)
+			}
+		}
+		d.lastCleanup = now
+	}
+
+	if _, exists := d.seen[key]; exists {
+		return false
+	}
+	d.seen[key] = expiresAt
+	return true
+}
+
+// requestURL rebuilds the absolute URL of r without query or fragment.
+func (d *DPoP) requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if d.config.TrustForwardedProto {
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+			scheme = proto
+		}
+	}
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+	return scheme + "://" + host + r.URL.Path
+}
+
+// DPoPBindingClaims returns the cnf claim that binds a token to the proof key.
+func DPoPBindingClaims(jkt string) map[string]interface{} {
+	return map[string]interface{}{
+		"cnf": map[string]interface{}{"jkt": jkt},
+	}
+}
+
+// boundJKT returns the cnf.jkt claim of a token, or "" if it is not DPoP-bound.
+func boundJKT(claims jwt.MapClaims) string {
+	cnf, ok := claims["cnf"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	jkt, _ := cnf["jkt"].(string)
+	return jkt
+}
+
+// sameHTU compares two URLs ignoring query, fragment, and scheme/host case.
+func sameHTU(a, b string) bool {
+	return normalizeHTU(a) == normalizeHTU(b)
+}
+
+func normalizeHTU(u string) string {
+	if i := strings.IndexAny(u, "?#"); i >= 0 {
+		u = u[:i]
+	}
+	if i := strings.Index(u, "://"); i >= 0 {
+		rest := u[i+3:]
+		host, path := rest, ""
+		if j := strings.Index(rest, "/"); j >= 0 {
+			host, path = rest[:j], rest[j:]
+		}
+		if path == "" {
+			path = "/"
+		}
+		return strings.ToLower(u[:i]) + "://" + strings.ToLower(host) + path
+	}
+	return u
+}
+
+// accessTokenHash returns the ath value for an access token.
+func accessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// JWKThumbprint computes the RFC 7638 SHA-256 thumbprint of a public JWK.
+func JWKThumbprint(jwk map[string]interface{}) (string, error) {
+	var members []string
+	switch kty, _ := jwk["kty"].(string); kty {
+	case "EC":
+		members = []string{"crv", "kty", "x", "y"}
+	case "RSA":
+		members = []string{"e", "kty", "n"}
+	case "OKP":
+		members = []string{"crv", "kty", "x"}
+	default:
+		return "", fmt.Errorf("unsupported jwk key type %q", kty)
+	}
+
+	// Required members in lexicographic order, no whitespace
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, name := range members {
+		value, ok := jwk[name].(string)
+		if !ok {
+			return "", fmt.Errorf("jwk is missing %q", name)
+		}
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		encoded, _ := json.Marshal(value)
+		fmt.Fprintf(&b, "%q:%s", name, encoded)
+	}
+	b.WriteByte('}')
+
+	sum := sha256.Sum256([]byte(b.String()))
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
+
+// parsePublicJWK converts a public JWK into a crypto.PublicKey.
+func parsePublicJWK(jwk map[string]interface{}) (crypto.PublicKey, error) {
+	if _, hasPrivate := jwk["d"]; hasPrivate {
+		return nil, errors.New("jwk must not contain a private key")
+	}
+
+	field := func(name string) ([]byte, error) {
+		s, ok := jwk[name].(string)
+		if !ok {
+			return nil, fmt.Errorf("jwk is missing %q", name)
+		}
+		return base64.RawURLEncoding.DecodeString(s)
+	}
+
+	switch kty, _ := jwk["kty"].(string); kty {
+	case "EC":
+		var curve elliptic.Curve
+		switch crv, _ := jwk["crv"].(string); crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported jwk curve %q", crv)
+		}
+		x, err := field("x")
+		if err != nil {
+			return nil, err
+		}
+		y, err := field("y")
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("jwk point is not on curve")
+		}
+		return key, nil
+	case "RSA":
+		n, err := field("n")
+		if err != nil {
+			return nil, err
+		}
+		e, err := field("e")
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() < 3 || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid jwk rsa exponent")
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+		if key.N.BitLen() < 2048 {
+			return nil, errors.New("jwk rsa key must be at least 2048 bits")
+		}
+		return key, nil
+	case "OKP":
+		if crv, _ := jwk["crv"].(string); crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported jwk curve %q", crv)
+		}
+		x, err := field("x")
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid jwk ed25519 key length")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported jwk key type %q", kty)
+	}
+}
+
+// DPoP returns the DPoP proof validator.
+func (a *Auth) DPoP() *DPoP {
+	return a.dpop
+}
+
+// LoginDPoP authenticates a user like Login and binds the issued tokens to
+// the key of the DPoP proof sent with r.
+func (a *Auth) LoginDPoP(r *http.Request, username, password string, customClaims map[string]interface{}) (*LoginResult, error) {
+	proof, err := a.dpop.VerifyRequest(r, "")
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := withTokenBinding(WithClientRequest(r.Context(), r), DPoPBindingClaims(proof.JKT)["cnf"])
+	result, err := a.LoginContext(ctx, username, password, customClaims)
+	if err != nil {
+		return nil, err
+	}
+	result.TokenType = DPoPTokenType
+	return result, nil
+}
+
+// RefreshDPoP refreshes tokens like RefreshToken and binds the new tokens to
+// the key of the DPoP proof sent with r. DPoP-bound refresh tokens can only
+// be refreshed this way, with a proof of the key they are bound to.
+func (a *Auth) RefreshDPoP(r *http.Request, refreshToken string) (*RefreshResult, error) {
+	proof, err := a.dpop.VerifyRequest(r, "")
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := a.Tokens().refresh(refreshToken, DPoPBindingClaims(proof.JKT))
+	if err != nil {
+		return nil, err
+	}
+	result.TokenType = DPoPTokenType
+	return result, nil
+}
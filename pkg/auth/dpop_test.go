@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// dpopTestKey is a client key used to sign DPoP proofs in tests.
+type dpopTestKey struct {
+	priv *ecdsa.PrivateKey
+	jwk  map[string]interface{}
+}
+
+func newDPoPTestKey(t *testing.T) *dpopTestKey {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	return &dpopTestKey{
+		priv: priv,
+		jwk: map[string]interface{}{
+			"kty": "EC",
+			"crv": "P-256",
+			"x":   base64.RawURLEncoding.EncodeToString(priv.X.FillBytes(make([]byte, 32))),
+			"y":   base64.RawURLEncoding.EncodeToString(priv.Y.FillBytes(make([]byte, 32))),
+		},
+	}
+}
+
+func (k *dpopTestKey) proof(t *testing.T, method, url, accessToken string, iat time.Time) string {
+	t.Helper()
+	claims := jwt.MapClaims{
+		"jti": uuid.New().String(),
+		"htm": method,
+		"htu": url,
+		"iat": iat.Unix(),
+	}
+	if accessToken != "" {
+		claims["ath"] = accessTokenHash(accessToken)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["typ"] = "dpop+jwt"
+	token.Header["jwk"] = k.jwk
+	signed, err := token.SignedString(k.priv)
+	if err != nil {
+		t.Fatalf("Failed to sign proof: %v", err)
+	}
+	return signed
+}
+
+func TestJWKThumbprint(t *testing.T) {
+	// Example from RFC 7638 section 3.1
+	jwk := map[string]interface{}{
+		"kty": "RSA",
+		"n":   "0vx7agoebGcQSuuPiLJXZptN9nndrQmbXEps2aiAFbWhM78LhWx4cbbfAAtVT86zwu1RK7aPFFxuhDR1L6tSoc_BJECPebWKRXjBZCiFV4n3oknjhMstn64tZ_2W-5JsGY4Hc5n9yBXArwl93lqt7_RN5w6Cf0h4QyQ5v-65YGjQR0_FDW2QvzqY368QQMicAtaSqzs8KJZgnYb9c7d0zgdAZHzu6qMQvRL5hajrn1n91CbOpbISD08qNLyrdkt-bFTWhAI4vMQFh6WeZu0fM4lFd2NcRwr3XPksINHaQ-G_xBniIqbw0Ls1jF44-csFCur-kEgU8awapJzKnqDKgw",
+		"e":   "AQAB",
+		"alg": "RS256",
+		"kid": "2011-04-29",
+	}
+
+	thumbprint, err := JWKThumbprint(jwk)
+	if err != nil {
+		t.Fatalf("Failed to compute thumbprint: %v", err)
+	}
+	if thumbprint != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("Unexpected thumbprint %s", thumbprint)
+	}
+}
+
+func TestDPoPVerifyProof(t *testing.T) {
+	clock := NewFrozenClock(time.Now().Truncate(time.Second))
+	d := NewDPoP(DPoPConfig{}, clock)
+	key := newDPoPTestKey(t)
+	url := "https://api.example.com/resource"
+
+	proof := key.proof(t, "GET", url, "access-token", clock.Now())
+	result, err := d.VerifyProof(proof, "GET", url+"?page=2", "access-token")
+	if err != nil {
+		t.Fatalf("Expected proof to be valid, got %v", err)
+	}
+	expected, _ := JWKThumbprint(key.jwk)
+	if result.JKT != expected {
+		t.Errorf("Expected JKT %s, got %s", expected, result.JKT)
+	}
+
+	if _, err := d.VerifyProof(proof, "GET", url, "access-token"); err == nil {
+		t.Error("Expected replayed proof to be rejected")
+	}
+
+	tests := []struct {
+		name   string
+		proof  string
+		method string
+		url    string
+		token  string
+	}{
+		{"wrong method", key.proof(t, "GET", url, "", clock.Now()), "POST", url, ""},
+		{"wrong url", key.proof(t, "GET", url, "", clock.Now()), "GET", "https://api.example.com/other", ""},
+		{"stale iat", key.proof(t, "GET", url, "", clock.Now().Add(-5*time.Minute)), "GET", url, ""},
+		{"wrong ath", key.proof(t, "GET", url, "other-token", clock.Now()), "GET", url, "access-token"},
+		{"missing ath", key.proof(t, "GET", url, "", clock.Now()), "GET", url, "access-token"},
+		{"missing proof", "", "GET", url, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := d.VerifyProof(tt.proof, tt.method, tt.url, tt.token)
+			if !errors.Is(err, ErrInvalidDPoPProof("")) {
+				t.Errorf("Expected INVALID_DPOP_PROOF, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDPoPBoundTokenMiddleware(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUsers("alice")
+	key := newDPoPTestKey(t)
+
+	loginReq := httptest.NewRequest("POST", "http://example.com/login", nil)
+	loginReq.Header.Set(DPoPHeader, key.proof(t, "POST", "http://example.com/login", "", ta.Clock.Now()))
+	result, err := ta.LoginDPoP(loginReq, "alice", TestPassword, nil)
+	if err != nil {
+		t.Fatalf("Expected DPoP login to succeed, got %v", err)
+	}
+	if result.TokenType != DPoPTokenType {
+		t.Errorf("Expected token type DPoP, got %q", result.TokenType)
+	}
+
+	handler := ta.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	call := func(scheme, proof string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "http://example.com/me", nil)
+		req.Header.Set("Authorization", scheme+" "+result.AccessToken)
+		if proof != "" {
+			req.Header.Set(DPoPHeader, proof)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	proof := key.proof(t, "GET", "http://example.com/me", result.AccessToken, ta.Clock.Now())
+	if rec := call("DPoP", proof); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 with valid proof, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := call("DPoP", proof); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for replayed proof, got %d", rec.Code)
+	} else if rec.Header().Get("WWW-Authenticate") == "" {
+		t.Error("Expected WWW-Authenticate challenge for rejected proof")
+	}
+
+	if rec := call("Bearer", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 when bound token is used as bearer, got %d", rec.Code)
+	}
+
+	otherKey := newDPoPTestKey(t)
+	otherProof := otherKey.proof(t, "GET", "http://example.com/me", result.AccessToken, ta.Clock.Now())
+	if rec := call("DPoP", otherProof); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for proof signed by another key, got %d", rec.Code)
+	}
+}
+
+func TestDPoPBoundRefresh(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUsers("alice")
+	key, otherKey := newDPoPTestKey(t), newDPoPTestKey(t)
+	request := func(key *dpopTestKey, target string) *http.Request {
+		r := httptest.NewRequest("POST", target, nil)
+		r.Header.Set(DPoPHeader, key.proof(t, "POST", target, "", ta.Clock.Now()))
+		return r
+	}
+
+	result, err := ta.LoginDPoP(request(key, "http://example.com/login"), "alice", TestPassword, nil)
+	if err != nil {
+		t.Fatalf("Expected DPoP login to succeed, got %v", err)
+	}
+	claims, err := ta.ValidateRefreshToken(result.RefreshToken)
+	if err != nil {
+		t.Fatalf("Failed to validate refresh token: %v", err)
+	}
+	if boundJKT(claims) == "" {
+		t.Fatal("Expected the refresh token to be DPoP-bound")
+	}
+
+	// Bound refresh tokens cannot be downgraded or refreshed with another key
+	if _, err := ta.RefreshToken(result.RefreshToken); !isCode(err, ErrCodeInvalidDPoPProof) {
+		t.Errorf("Expected a plain refresh of a bound token to fail, got %v", err)
+	}
+	if _, err := ta.RefreshDPoP(request(otherKey, "http://example.com/refresh"), result.RefreshToken); !isCode(err, ErrCodeInvalidDPoPProof) {
+		t.Errorf("Expected a refresh with another key to fail, got %v", err)
+	}
+	refreshed, err := ta.RefreshDPoP(request(key, "http://example.com/refresh"), result.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshDPoP failed: %v", err)
+	}
+	if _, err := ta.RefreshToken(refreshed.RefreshToken); !isCode(err, ErrCodeInvalidDPoPProof) {
+		t.Errorf("Expected the refreshed token to stay bound, got %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/pragneshbagary/go-auth/internal/jwtutils"
+)
+
+// DualIssuanceConfig issues a compatibility token in the format of a legacy
+// service alongside each access token during a migration, so that both the
+// legacy service and go-auth accept the tokens of a login. Tokens in the
+// legacy format, issued by either side, are accepted by Tokens().Validate
+// until the window ends.
+type DualIssuanceConfig struct {
+	// Issuer is the "iss" claim of compatibility tokens. Setting it enables
+	// dual issuance.
+	Issuer string
+	// Secret signs compatibility tokens with HS256, as the legacy service
+	// does.
+	Secret []byte
+	// Claims renames the claims of compatibility tokens for the legacy
+	// service, e.g. "sub" to "uid". A name starting with "/" is a path into
+	// nested objects. The timing claims, "iss" and "jti" keep their names.
+	Claims map[string]string
+	// Until ends the window: compatibility tokens are no longer issued nor
+	// accepted after it. Zero means no end.
+	Until time.Time
+}
+
+// fixedCompatibilityClaims are the claims compatibility tokens cannot rename.
+var fixedCompatibilityClaims = []string{"exp", "iat", "nbf", "iss", "jti"}
+
+// validate checks the configuration of an enabled dual issuance.
+func (c DualIssuanceConfig) validate(primaryIssuer string) error {
+	if len(c.Secret) == 0 {
+		return errors.New("dual issuance requires a secret")
+	}
+	if c.Issuer == primaryIssuer {
+		return errors.New("compatibility tokens need an issuer of their own")
+	}
+	targets := make(map[string]string, len(c.Claims))
+	for name, target := range c.Claims {
+		if name == "" || target == "" || target == "/" {
+			return fmt.Errorf("claim mapping %q -> %q is incomplete", name, target)
+		}
+		if slices.Contains(fixedCompatibilityClaims, name) || slices.Contains(fixedCompatibilityClaims, target) {
+			return fmt.Errorf("claim %q cannot be mapped", name)
+		}
+		if other, ok := targets[target]; ok {
+			return fmt.Errorf("claims %q and %q are both mapped to %q", other, name, target)
+		}
+		targets[target] = name
+	}
+	return nil
+}
+
+// mapped returns the name a compatibility token carries claim under.
+func (c DualIssuanceConfig) mapped(claim string) string {
+	if target, ok := c.Claims[claim]; ok {
+		return target
+	}
+	return claim
+}
+
+// trustedIssuer accepts tokens in the legacy format for the window. They
+// belong to local users, found by their mapped email or username.
+func (c DualIssuanceConfig) trustedIssuer() TrustedIssuer {
+	return TrustedIssuer{
+		Issuer:     c.Issuer,
+		Key:        c.Secret,
+		Algorithms: []string{HS256},
+		Until:      c.Until,
+		Claims: IssuerClaimMapping{
+			UserID:    c.mapped("sub"),
+			Username:  c.mapped("username"),
+			Email:     c.mapped("email"),
+			LocalUser: true,
+		},
+	}
+}
+
+// compatibilityIssuer issues the compatibility tokens of dual issuance. A
+// nil *compatibilityIssuer issues none.
+type compatibilityIssuer struct {
+	config   DualIssuanceConfig
+	clock    Clock
+	settings *runtimeSettings
+}
+
+func newCompatibilityIssuer(config DualIssuanceConfig, clock Clock, settings *runtimeSettings) *compatibilityIssuer {
+	if config.Issuer == "" {
+		return nil
+	}
+	return &compatibilityIssuer{config: config, clock: clock, settings: settings}
+}
+
+// issue returns the compatibility token for an access token issued to
+// userID with claims and opts. It returns an empty string when dual
+// issuance is disabled or its window ended.
+func (c *compatibilityIssuer) issue(userID string, claims map[string]interface{}, opts jwtutils.IssueOptions) (string, error) {
+	if c == nil {
+		return "", nil
+	}
+	now := nowFrom(c.clock)
+	if !c.config.Until.IsZero() && now.After(c.config.Until) {
+		return "", nil
+	}
+
+	ttl, _ := c.settings.tokenTTLs()
+	payload := make(map[string]interface{}, len(claims)+len(opts.Claims)+6)
+	maps.Copy(payload, claims)
+	maps.Copy(payload, opts.Claims)
+	payload["sub"] = userID
+	payload["token_type"] = "access"
+	for name, target := range c.config.Claims {
+		if value, ok := payload[name]; ok {
+			delete(payload, name)
+			setClaim(payload, target, value)
+		}
+	}
+	// Scheduled like the access token
+	iat, nbf := now, opts.NotBefore
+	if !opts.IssuedAt.IsZero() {
+		iat = opts.IssuedAt
+	}
+	if nbf.IsZero() {
+		nbf = iat
+	}
+	payload["iss"] = c.config.Issuer
+	payload["iat"] = iat.Unix()
+	payload["nbf"] = nbf.Unix()
+	payload["exp"] = nbf.Add(ttl).Unix()
+	payload["jti"] = uuid.New().String()
+
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims(payload)).SignedString(c.config.Secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign compatibility token: %w", err)
+	}
+	return signed, nil
+}
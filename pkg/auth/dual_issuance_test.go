@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestDualIssuance(t *testing.T) {
+	ta := NewTestAuth(t)
+	config := DualIssuanceConfig{
+		Issuer: "legacy-auth",
+		Secret: []byte("legacy-secret"),
+		Claims: map[string]string{"sub": "uid", "username": "login"},
+		Until:  ta.Clock.Now().Add(24 * time.Hour),
+	}
+	if err := config.validate(ta.config.JWTIssuer); err != nil {
+		t.Fatalf("Expected valid configuration: %v", err)
+	}
+	var err error
+	ta.dual = newCompatibilityIssuer(config, ta.Clock, ta.settings)
+	ta.trustedIssuers, err = newTrustedIssuers([]TrustedIssuer{config.trustedIssuer()}, ta.Clock, 0)
+	if err != nil {
+		t.Fatalf("Failed to configure trusted issuer: %v", err)
+	}
+
+	user := ta.SeedUser("dave", "dave-password")
+	login := ta.LoginAs("dave")
+	if login.CompatibilityToken == "" {
+		t.Fatal("Expected a compatibility token")
+	}
+
+	// The legacy service verifies it with its own secret and claim names
+	claims := jwt.MapClaims{}
+	if _, err := jwt.ParseWithClaims(login.CompatibilityToken, claims, func(*jwt.Token) (interface{}, error) {
+		return config.Secret, nil
+	}); err != nil {
+		t.Fatalf("Expected the legacy secret to verify the token: %v", err)
+	}
+	if claims["iss"] != "legacy-auth" || claims["uid"] != user.ID || claims["login"] != "dave" {
+		t.Errorf("Expected legacy claims, got %v", claims)
+	}
+	if _, ok := claims["sub"]; ok {
+		t.Error("Expected sub to be renamed")
+	}
+
+	// Both formats are accepted during the window
+	tokens := ta.Tokens()
+	for _, token := range []string{login.AccessToken, login.CompatibilityToken} {
+		if validated, err := tokens.Validate(token); err != nil || validated.ID != user.ID {
+			t.Errorf("Expected token to validate for %s, got %v, %v", user.ID, validated, err)
+		}
+	}
+	refreshed, err := tokens.Refresh(login.RefreshToken)
+	if err != nil || refreshed.CompatibilityToken == "" {
+		t.Fatalf("Expected a compatibility token on refresh, got %v", err)
+	}
+
+	// After the window neither is issued nor accepted
+	ta.Clock.Advance(25 * time.Hour)
+	if _, err := tokens.Validate(login.CompatibilityToken); err == nil {
+		t.Error("Expected compatibility token to be rejected after the window")
+	}
+	if login := ta.LoginAs("dave"); login.CompatibilityToken != "" {
+		t.Error("Expected no compatibility token after the window")
+	}
+}
+
+func TestDualIssuanceConfigValidate(t *testing.T) {
+	cases := map[string]DualIssuanceConfig{
+		"no secret":      {Issuer: "legacy-auth"},
+		"same issuer":    {Issuer: "go-auth", Secret: []byte("secret")},
+		"fixed claim":    {Issuer: "legacy-auth", Secret: []byte("secret"), Claims: map[string]string{"exp": "expires"}},
+		"clashing names": {Issuer: "legacy-auth", Secret: []byte("secret"), Claims: map[string]string{"sub": "id", "user_id": "id"}},
+	}
+	for name, config := range cases {
+		if err := config.validate("go-auth"); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
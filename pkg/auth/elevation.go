@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pragneshbagary/go-auth/internal/jwtutils"
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+// elevatedUntilClaim is the access token claim holding the end of an
+// elevation (Unix seconds).
+const elevatedUntilClaim = "elevated_until"
+
+// ElevationConfig configures sudo mode: users re-authenticate with
+// Auth.Elevate to get an access token that Middleware.RequireElevated
+// accepts for a short while, e.g. before deleting their account.
+type ElevationConfig struct {
+	// TTL is how long an elevation lasts. Defaults to 10 minutes.
+	TTL time.Duration
+	// Verifier optionally verifies the credential passed to Elevate instead
+	// of the password, e.g. a TOTP code.
+	Verifier func(ctx context.Context, user *models.User, credential string) (bool, error)
+}
+
+// ElevationResult is returned by Auth.Elevate.
+type ElevationResult struct {
+	// AccessToken replaces the caller's access token.
+	AccessToken   string    `json:"access_token"`
+	ElevatedUntil time.Time `json:"elevated_until"`
+}
+
+// ElevatedUntil returns the end of the elevation carried by claims, false
+// if they are not elevated. The elevation may have ended already.
+func ElevatedUntil(claims jwt.MapClaims) (time.Time, bool) {
+	switch until := claims[elevatedUntilClaim].(type) {
+	case float64:
+		return time.Unix(int64(until), 0), true
+	case int64:
+		return time.Unix(until, 0), true
+	}
+	return time.Time{}, false
+}
+
+// Elevate re-authenticates userID with credential, the password unless
+// ElevationConfig.Verifier is set, and returns an access token elevated for
+// ElevationConfig.TTL. Called with the context of a request authenticated by
+// the middleware, the token keeps the claims and session of the request's
+// token; otherwise it carries the stored claims of the user. The elevation
+// ends with its TTL, on refresh, or when the token is revoked. Failed
+// attempts count towards the CAPTCHA threshold like failed logins.
+func (a *Auth) Elevate(ctx context.Context, userID, credential string) (*ElevationResult, error) {
+	var username string
+	var err error
+	defer func() {
+		client := clientInfoFrom(ctx)
+		a.eventLogger.LogElevation(userID, username, client.ip, client.userAgent, err == nil, err)
+	}()
+
+	user, getUserErr := a.storage.GetUserByID(userID)
+	if getUserErr != nil {
+		err = ErrInvalidCredentials()
+		return nil, err
+	}
+	username = user.Username
+	if !user.IsActive {
+		err = ErrUserInactive()
+		return nil, err
+	}
+	if err = checkAccountExpiry(user, a.clock); err != nil {
+		return nil, err
+	}
+
+	var match bool
+	var checkErr error
+	if verifier := a.config.Elevation.Verifier; verifier != nil {
+		match, checkErr = verifier(ctx, user, credential)
+	} else {
+		match, checkErr = verifyPassword(a.hasher, credential, user.PasswordHash)
+	}
+	if checkErr != nil || !match {
+		err = ErrInvalidCredentials()
+		if checkErr != nil {
+			a.logger.Error("Elevation failed: credential check error", map[string]interface{}{
+				"user_id": userID,
+				"error":   checkErr,
+			})
+		} else {
+			a.captcha.fail(username)
+		}
+		return nil, err
+	}
+
+	// Keep the claims and session of the request's token, minus the
+	// standard claims the token manager sets
+	claims := a.claims.stored(user)
+	var options jwtutils.IssueOptions
+	if current, ok := GetClaimsFromContext(ctx); ok {
+		if subject, _ := current.GetSubject(); subject == user.ID {
+			for k, v := range current {
+				if _, stored := claims[k]; !stored && k != sessionClaim && !slices.Contains(standardClaims, k) {
+					claims[k] = v
+				}
+			}
+			options = sessionIssueOptions(sessionID(current))
+		}
+	}
+
+	ttl := a.config.Elevation.TTL
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+	until := nowFrom(a.clock).Add(ttl).Truncate(time.Second)
+	claims[elevatedUntilClaim] = until.Unix()
+
+	accessToken, tokenErr := a.jwtManager.GenerateAccessTokenWithOptions(user.ID, claims, options)
+	if tokenErr != nil {
+		err = WrapError(tokenErr, ErrCodeInternalError, "Failed to generate access token")
+		return nil, err
+	}
+	return &ElevationResult{AccessToken: accessToken, ElevatedUntil: until}, nil
+}
+
+// RequireElevated returns a middleware that requires authentication with
+// an access token elevated by Auth.Elevate whose elevation has not ended.
+// It reuses the result of an earlier Protect or Optional on the same
+// request.
+func (m *Middleware) RequireElevated() func(http.Handler) http.Handler {
+	return m.require(func(claims jwt.MapClaims) error {
+		if until, ok := ElevatedUntil(claims); ok && nowFrom(m.auth.clock).Before(until) {
+			return nil
+		}
+		return ErrElevationRequired()
+	})
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+func TestElevate(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("alice", "alice-password")
+	result, err := ta.Login("alice", "alice-password", map[string]interface{}{"scope": "admin"})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	middleware := ta.Middleware()
+	sensitive := middleware.RequireElevated()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	if code := serveWithToken(sensitive, result.AccessToken); code != http.StatusForbidden {
+		t.Fatalf("Expected 403 without elevation, got %d", code)
+	}
+
+	// Elevate from a protected handler, keeping the claims of the request
+	var elevation *ElevationResult
+	var elevateErr error
+	elevate := middleware.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		elevation, elevateErr = ta.Elevate(r.Context(), user.ID, "wrong-password")
+		if elevateErr == nil {
+			return
+		}
+		elevation, elevateErr = ta.Elevate(r.Context(), user.ID, "alice-password")
+	}))
+	serveWithToken(elevate, result.AccessToken)
+	if elevateErr != nil {
+		t.Fatalf("Elevate failed: %v", elevateErr)
+	}
+	if !elevation.ElevatedUntil.Equal(ta.Clock.Now().Add(10 * time.Minute)) {
+		t.Errorf("Expected a 10 minute elevation, got %s", elevation.ElevatedUntil)
+	}
+	claims, err := ta.ValidateAccessToken(elevation.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected the elevated token to be valid: %v", err)
+	}
+	if claims["scope"] != "admin" {
+		t.Errorf("Expected the request's claims to be kept, got %v", claims)
+	}
+
+	if code := serveWithToken(sensitive, elevation.AccessToken); code != http.StatusOK {
+		t.Errorf("Expected 200 with elevation, got %d", code)
+	}
+	ta.Clock.Advance(11 * time.Minute)
+	if code := serveWithToken(sensitive, elevation.AccessToken); code != http.StatusForbidden {
+		t.Errorf("Expected 403 after the elevation ended, got %d", code)
+	}
+
+	// Per-login claims cannot forge an elevation
+	forged, err := ta.Login("alice", "alice-password", map[string]interface{}{elevatedUntilClaim: time.Now().Add(time.Hour).Unix()})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if code := serveWithToken(sensitive, forged.AccessToken); code != http.StatusForbidden {
+		t.Errorf("Expected a forged elevation to be rejected, got %d", code)
+	}
+}
+
+func TestElevateWithVerifier(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("alice", "alice-password")
+	ta.config.Elevation = ElevationConfig{
+		TTL: time.Minute,
+		Verifier: func(ctx context.Context, user *models.User, credential string) (bool, error) {
+			return credential == "123456", nil
+		},
+	}
+
+	if _, err := ta.Elevate(context.Background(), user.ID, "alice-password"); !errors.Is(err, ErrInvalidCredentials()) {
+		t.Errorf("Expected the password to be rejected, got %v", err)
+	}
+	elevation, err := ta.Elevate(context.Background(), user.ID, "123456")
+	if err != nil {
+		t.Fatalf("Elevate failed: %v", err)
+	}
+	claims, _ := ta.ValidateAccessToken(elevation.AccessToken)
+	if until, ok := ElevatedUntil(claims); !ok || !until.Equal(ta.Clock.Now().Add(time.Minute)) {
+		t.Errorf("Expected a one minute elevation, got %s", until)
+	}
+}
@@ -0,0 +1,438 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"net/url"
+	"strings"
+	texttemplate "text/template"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// Email kinds built by Emails.
+const (
+	EmailPasswordReset = "password_reset"
+	EmailVerification  = "email_verification"
+	EmailMagicLink     = "magic_link"
+	// Security notifications, see SecurityNotificationConfig. They carry
+	// no link.
+	EmailNewDevice       = "new_device"
+	EmailPasswordChanged = "password_changed"
+	EmailSecurityDigest  = "security_digest"
+)
+
+// Action token purposes of verification and magic links. Check them with
+// Tokens().VerifyURL on the pages the links point to.
+const (
+	PurposeEmailVerification = "email-verification"
+	PurposeMagicLink         = "magic-link"
+)
+
+// defaultEmailPaths are the pages links point to, relative to the base URL.
+var defaultEmailPaths = map[string]string{
+	EmailPasswordReset: "/reset-password",
+	EmailVerification:  "/verify-email",
+	EmailMagicLink:     "/magic-link",
+}
+
+// EmailConfig configures the links and bodies built by Auth.Emails.
+type EmailConfig struct {
+	// BaseURL is the absolute URL of the application links point to, e.g.
+	// "https://app.example.com". Required to build links.
+	BaseURL string
+	// Paths override the pages links point to by kind. Defaults to
+	// "/reset-password", "/verify-email" and "/magic-link".
+	Paths map[string]string
+	// Templates override the default templates by kind.
+	Templates map[string]EmailTemplate
+	// VerificationTTL is how long verification links are valid. Defaults to 24 hours.
+	VerificationTTL time.Duration
+	// MagicLinkTTL is how long magic links are valid. Defaults to 15 minutes.
+	MagicLinkTTL time.Duration
+	// Sender delivers the emails of the Emails.Send methods.
+	Sender EmailSender
+}
+
+// EmailSender delivers account emails, e.g. over SMTP or an email API.
+type EmailSender interface {
+	SendEmail(ctx context.Context, message *EmailMessage) error
+}
+
+// EmailTemplate is the source of an email, executed with EmailData. Subject
+// and Text are text/template templates, HTML an html/template template; an
+// empty HTML sends plain text only.
+type EmailTemplate struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// EmailData is passed to email templates.
+type EmailData struct {
+	AppName string
+	// Name is the display name of the user, or the username without one.
+	Name      string
+	Email     string
+	Link      string
+	ExpiresAt time.Time
+	// Activity lists the account activity of security notifications.
+	Activity []SecurityActivity
+}
+
+// EmailMessage is a rendered email, ready to hand to a mailer.
+type EmailMessage struct {
+	// Kind is the kind of email, e.g. EmailPasswordReset.
+	Kind    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+	// Link is the link of the email.
+	Link string
+}
+
+var defaultEmailTemplates = map[string]EmailTemplate{
+	EmailPasswordReset: {
+		Subject: "Reset your {{.AppName}} password",
+		Text: `Hi {{.Name}},
+
+Someone asked to reset the password of your {{.AppName}} account. Open the link below to choose a new one:
+
+{{.Link}}
+
+The link expires at {{.ExpiresAt.Format "2006-01-02 15:04 MST"}}. If you did not ask for it, you can ignore this email.
+`,
+		HTML: `<p>Hi {{.Name}},</p>
+<p>Someone asked to reset the password of your {{.AppName}} account. <a href="{{.Link}}">Choose a new password</a>.</p>
+<p>The link expires at {{.ExpiresAt.Format "2006-01-02 15:04 MST"}}. If you did not ask for it, you can ignore this email.</p>
+`,
+	},
+	EmailVerification: {
+		Subject: "Verify your email for {{.AppName}}",
+		Text: `Hi {{.Name}},
+
+Open the link below to verify {{.Email}}:
+
+{{.Link}}
+
+The link expires at {{.ExpiresAt.Format "2006-01-02 15:04 MST"}}.
+`,
+		HTML: `<p>Hi {{.Name}},</p>
+<p><a href="{{.Link}}">Verify {{.Email}}</a>.</p>
+<p>The link expires at {{.ExpiresAt.Format "2006-01-02 15:04 MST"}}.</p>
+`,
+	},
+	EmailMagicLink: {
+		Subject: "Sign in to {{.AppName}}",
+		Text: `Hi {{.Name}},
+
+Open the link below to sign in:
+
+{{.Link}}
+
+The link expires at {{.ExpiresAt.Format "2006-01-02 15:04 MST"}} and can only be used once. If you did not ask for it, you can ignore this email.
+`,
+		HTML: `<p>Hi {{.Name}},</p>
+<p><a href="{{.Link}}">Sign in to {{.AppName}}</a>.</p>
+<p>The link expires at {{.ExpiresAt.Format "2006-01-02 15:04 MST"}} and can only be used once. If you did not ask for it, you can ignore this email.</p>
+`,
+	},
+	EmailNewDevice: {
+		Subject: "New sign-in to your {{.AppName}} account",
+		Text: `Hi {{.Name}},
+
+Your {{.AppName}} account was signed in to from a new device:
+{{range .Activity}}
+{{.UserAgent}} ({{.IP}}) at {{.Time.Format "2006-01-02 15:04 MST"}}
+{{end}}
+If this was you, you can ignore this email. Otherwise change your password right away.
+`,
+		HTML: `<p>Hi {{.Name}},</p>
+<p>Your {{.AppName}} account was signed in to from a new device:</p>
+<ul>{{range .Activity}}<li>{{.UserAgent}} ({{.IP}}) at {{.Time.Format "2006-01-02 15:04 MST"}}</li>{{end}}</ul>
+<p>If this was you, you can ignore this email. Otherwise change your password right away.</p>
+`,
+	},
+	EmailPasswordChanged: {
+		Subject: "Your {{.AppName}} password was changed",
+		Text: `Hi {{.Name}},
+
+The password of your {{.AppName}} account was changed{{range .Activity}} at {{.Time.Format "2006-01-02 15:04 MST"}}{{end}}.
+
+If you did not change it, reset your password right away.
+`,
+		HTML: `<p>Hi {{.Name}},</p>
+<p>The password of your {{.AppName}} account was changed{{range .Activity}} at {{.Time.Format "2006-01-02 15:04 MST"}}{{end}}.</p>
+<p>If you did not change it, reset your password right away.</p>
+`,
+	},
+	EmailSecurityDigest: {
+		Subject: "Your {{.AppName}} security summary",
+		Text: `Hi {{.Name}},
+
+Recent activity on your {{.AppName}} account:
+{{range .Activity}}
+{{.Time.Format "2006-01-02 15:04 MST"}}  {{.Type}}{{if .IP}} from {{.IP}}{{end}}{{end}}
+
+If you do not recognize some of it, change your password.
+`,
+		HTML: `<p>Hi {{.Name}},</p>
+<p>Recent activity on your {{.AppName}} account:</p>
+<ul>{{range .Activity}}<li>{{.Time.Format "2006-01-02 15:04 MST"}} {{.Type}}{{if .IP}} from {{.IP}}{{end}}</li>{{end}}</ul>
+<p>If you do not recognize some of it, change your password.</p>
+`,
+	},
+}
+
+// emailTemplates is a parsed EmailTemplate.
+type emailTemplates struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// emailRenderer holds the parsed EmailConfig.
+type emailRenderer struct {
+	config    EmailConfig
+	baseURL   *url.URL
+	paths     map[string]string
+	templates map[string]*emailTemplates
+}
+
+func newEmailRenderer(config EmailConfig) (*emailRenderer, error) {
+	r := &emailRenderer{config: config, paths: make(map[string]string), templates: make(map[string]*emailTemplates)}
+	if config.BaseURL != "" {
+		baseURL, err := url.Parse(config.BaseURL)
+		if err != nil || !baseURL.IsAbs() || baseURL.Host == "" {
+			return nil, fmt.Errorf("base URL %q is not an absolute URL", config.BaseURL)
+		}
+		r.baseURL = baseURL
+	}
+	if r.config.VerificationTTL <= 0 {
+		r.config.VerificationTTL = 24 * time.Hour
+	}
+	if r.config.MagicLinkTTL <= 0 {
+		r.config.MagicLinkTTL = 15 * time.Minute
+	}
+
+	for kind, path := range defaultEmailPaths {
+		r.paths[kind] = path
+	}
+	for kind, path := range config.Paths {
+		r.paths[kind] = path
+	}
+
+	sources := make(map[string]EmailTemplate, len(defaultEmailTemplates))
+	for kind, source := range defaultEmailTemplates {
+		sources[kind] = source
+	}
+	for kind, source := range config.Templates {
+		sources[kind] = source
+	}
+	for kind, source := range sources {
+		parsed := &emailTemplates{}
+		var err error
+		if parsed.subject, err = texttemplate.New(kind + " subject").Parse(source.Subject); err != nil {
+			return nil, fmt.Errorf("template %s: %w", kind, err)
+		}
+		if parsed.text, err = texttemplate.New(kind + " text").Parse(source.Text); err != nil {
+			return nil, fmt.Errorf("template %s: %w", kind, err)
+		}
+		if source.HTML != "" {
+			if parsed.html, err = htmltemplate.New(kind + " html").Parse(source.HTML); err != nil {
+				return nil, fmt.Errorf("template %s: %w", kind, err)
+			}
+		}
+		r.templates[kind] = parsed
+	}
+	return r, nil
+}
+
+// Emails builds the links and bodies of account emails. The Send methods
+// deliver them with EmailConfig.Sender; the others leave it to the caller.
+type Emails struct {
+	renderer *emailRenderer
+	storage  storage.EnhancedStorage
+	users    *Users
+	tokens   *Tokens
+	appName  string
+	clock    Clock
+}
+
+// Link returns the URL of the page handling kind with query.
+func (e *Emails) Link(kind string, query url.Values) (string, error) {
+	if e.renderer.baseURL == nil {
+		return "", NewAuthError(ErrCodeMissingConfig, "Email.BaseURL is required to build links")
+	}
+	path, ok := e.renderer.paths[kind]
+	if !ok {
+		return "", ErrValidationError("email kind")
+	}
+	link := e.renderer.baseURL.JoinPath(path)
+	link.RawQuery = query.Encode()
+	return link.String(), nil
+}
+
+// signedLink returns the link of kind carrying an action token for
+// purpose, bound to the link path and params. The token is read back with
+// Tokens().VerifyURL.
+func (e *Emails) signedLink(kind, purpose, subject string, params map[string]string, ttl time.Duration) (string, error) {
+	link, err := e.Link(kind, nil)
+	if err != nil {
+		return "", err
+	}
+	parsed, _ := url.Parse(link)
+	signed := map[string]string{"path": parsed.EscapedPath()}
+	for k, v := range params {
+		signed[k] = v
+	}
+	token, err := e.tokens.SignAction(purpose, subject, signed, ttl)
+	if err != nil {
+		return "", err
+	}
+	return e.Link(kind, url.Values{ActionTokenParam: {token}})
+}
+
+// Render executes the template of kind with data.
+func (e *Emails) Render(kind string, data EmailData) (*EmailMessage, error) {
+	templates, ok := e.renderer.templates[kind]
+	if !ok {
+		return nil, ErrValidationError("email kind")
+	}
+	if data.AppName == "" {
+		data.AppName = e.appName
+	}
+
+	message := &EmailMessage{Kind: kind, To: data.Email, Link: data.Link}
+	var subject, text, html strings.Builder
+	if err := templates.subject.Execute(&subject, data); err != nil {
+		return nil, WrapError(err, ErrCodeInternalError, "Failed to render email subject")
+	}
+	if err := templates.text.Execute(&text, data); err != nil {
+		return nil, WrapError(err, ErrCodeInternalError, "Failed to render email text")
+	}
+	if templates.html != nil {
+		if err := templates.html.Execute(&html, data); err != nil {
+			return nil, WrapError(err, ErrCodeInternalError, "Failed to render email HTML")
+		}
+	}
+	message.Subject = strings.TrimSpace(subject.String())
+	message.Text = text.String()
+	message.HTML = html.String()
+	return message, nil
+}
+
+// PasswordReset creates a reset token for the user with email and returns
+// the email carrying its link, whose "token" parameter is passed to
+// Users().ResetPassword.
+func (e *Emails) PasswordReset(email string) (*EmailMessage, error) {
+	resetToken, err := e.users.CreateResetToken(email)
+	if err != nil {
+		return nil, err
+	}
+	user, err := e.storage.GetUserByID(resetToken.UserID)
+	if err != nil {
+		return nil, ErrUserNotFound()
+	}
+	link, err := e.Link(EmailPasswordReset, url.Values{ActionTokenParam: {resetToken.Token}})
+	if err != nil {
+		return nil, err
+	}
+	return e.Render(EmailPasswordReset, emailData(user, link, resetToken.ExpiresAt))
+}
+
+// Verification returns the email verifying the current address of userID.
+// The link's token has purpose PurposeEmailVerification and carries the
+// address as the "email" param, so it stops matching once the address
+// changes.
+func (e *Emails) Verification(userID string) (*EmailMessage, error) {
+	user, err := e.storage.GetUserByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound()
+	}
+	if user.Email == "" {
+		return nil, ErrValidationError("email")
+	}
+	ttl := e.renderer.config.VerificationTTL
+	link, err := e.signedLink(EmailVerification, PurposeEmailVerification, user.ID, map[string]string{"email": user.Email}, ttl)
+	if err != nil {
+		return nil, err
+	}
+	message, err := e.Render(EmailVerification, emailData(user, link, nowFrom(e.clock).Add(ttl)))
+	if err != nil {
+		return nil, err
+	}
+	e.tokens.metricsCollector.RecordFunnelStep(FunnelEmailVerification, false)
+	return message, nil
+}
+
+// MagicLink returns the sign-in email of the user with email. The link's
+// token has purpose PurposeMagicLink; the page it points to should verify
+// it, reject reuse of its ID and issue tokens with Tokens().Issue.
+func (e *Emails) MagicLink(email string) (*EmailMessage, error) {
+	if email == "" {
+		return nil, ErrValidationError("email")
+	}
+	user, err := e.storage.GetUserByEmail(email)
+	if err != nil {
+		return nil, ErrUserNotFound()
+	}
+	if !user.IsActive {
+		return nil, ErrUserInactive()
+	}
+	if err := checkAccountExpiry(user, e.clock); err != nil {
+		return nil, err
+	}
+	ttl := e.renderer.config.MagicLinkTTL
+	link, err := e.signedLink(EmailMagicLink, PurposeMagicLink, user.ID, nil, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return e.Render(EmailMagicLink, emailData(user, link, nowFrom(e.clock).Add(ttl)))
+}
+
+// emailData returns the template data of an email to user.
+func emailData(user *models.User, link string, expiresAt time.Time) EmailData {
+	name := user.DisplayName
+	if name == "" {
+		name = user.Username
+	}
+	return EmailData{Name: name, Email: user.Email, Link: link, ExpiresAt: expiresAt}
+}
+
+// SendPasswordReset sends the PasswordReset email of email with
+// EmailConfig.Sender.
+func (e *Emails) SendPasswordReset(ctx context.Context, email string) error {
+	return e.send(ctx, func() (*EmailMessage, error) { return e.PasswordReset(email) })
+}
+
+// SendVerification sends the Verification email of userID with
+// EmailConfig.Sender.
+func (e *Emails) SendVerification(ctx context.Context, userID string) error {
+	return e.send(ctx, func() (*EmailMessage, error) { return e.Verification(userID) })
+}
+
+// SendMagicLink sends the MagicLink email of email with EmailConfig.Sender.
+func (e *Emails) SendMagicLink(ctx context.Context, email string) error {
+	return e.send(ctx, func() (*EmailMessage, error) { return e.MagicLink(email) })
+}
+
+// send builds a message and sends it with the configured sender.
+func (e *Emails) send(ctx context.Context, build func() (*EmailMessage, error)) error {
+	sender := e.renderer.config.Sender
+	if sender == nil {
+		return NewAuthError(ErrCodeMissingConfig, "Email.Sender is required to send emails")
+	}
+	message, err := build()
+	if err != nil {
+		return err
+	}
+	if err := sender.SendEmail(ctx, message); err != nil {
+		return WrapError(err, ErrCodeInternalError, "Failed to send email")
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newEmailTestAuth(t *testing.T, config EmailConfig) *TestAuth {
+	ta := NewTestAuth(t)
+	emails, err := newEmailRenderer(config)
+	if err != nil {
+		t.Fatalf("Failed to create email renderer: %v", err)
+	}
+	ta.emails = emails
+	return ta
+}
+
+func TestEmailLinks(t *testing.T) {
+	ta := newEmailTestAuth(t, EmailConfig{BaseURL: "https://app.example.com/portal"})
+	user := ta.SeedUser("alice", "alice-password")
+	emails := ta.Emails()
+
+	// Password reset links carry the reset token
+	message, err := emails.PasswordReset("alice@example.test")
+	if err != nil {
+		t.Fatalf("PasswordReset failed: %v", err)
+	}
+	if message.To != "alice@example.test" || message.Subject != "Reset your go-auth-test password" {
+		t.Errorf("Unexpected message: %+v", message)
+	}
+	link := findLink(t, message.Text)
+	if !strings.HasPrefix(link, "https://app.example.com/portal/reset-password?token=") {
+		t.Fatalf("Unexpected reset link %q", link)
+	}
+	if !strings.Contains(message.HTML, "<a href=\"https://app.example.com/portal/reset-password?token=") {
+		t.Errorf("Expected the link in the HTML body, got %s", message.HTML)
+	}
+	parsed, _ := url.Parse(link)
+	if err := ta.Users().ResetPassword(parsed.Query().Get("token"), "new-password-123"); err != nil {
+		t.Errorf("Expected the reset token to work, got %v", err)
+	}
+
+	// Verification links are signed and bound to the address
+	message, err = emails.Verification(user.ID)
+	if err != nil {
+		t.Fatalf("Verification failed: %v", err)
+	}
+	link = findLink(t, message.Text)
+	claims, err := ta.Tokens().VerifyURL(httptest.NewRequest(http.MethodGet, link, nil), PurposeEmailVerification)
+	if err != nil {
+		t.Fatalf("Expected the verification link to verify, got %v", err)
+	}
+	if claims.Subject != user.ID || claims.Params["email"] != "alice@example.test" {
+		t.Errorf("Unexpected verification claims: %+v", claims)
+	}
+	if _, err := ta.Tokens().VerifyURL(httptest.NewRequest(http.MethodGet, link, nil), PurposeMagicLink); err == nil {
+		t.Error("Expected a verification link to be rejected as a magic link")
+	}
+
+	message, err = emails.MagicLink("alice@example.test")
+	if err != nil {
+		t.Fatalf("MagicLink failed: %v", err)
+	}
+	if _, err := ta.Tokens().VerifyURL(httptest.NewRequest(http.MethodGet, findLink(t, message.Text), nil), PurposeMagicLink); err != nil {
+		t.Errorf("Expected the magic link to verify, got %v", err)
+	}
+}
+
+func TestEmailTemplateOverrides(t *testing.T) {
+	ta := newEmailTestAuth(t, EmailConfig{
+		BaseURL: "https://app.example.com",
+		Paths:   map[string]string{EmailMagicLink: "/login/email"},
+		Templates: map[string]EmailTemplate{
+			EmailMagicLink: {Subject: "Your link", Text: "{{.Name}}: {{.Link}}"},
+		},
+	})
+	ta.SeedUser("alice", "alice-password")
+
+	message, err := ta.Emails().MagicLink("alice@example.test")
+	if err != nil {
+		t.Fatalf("MagicLink failed: %v", err)
+	}
+	if message.Subject != "Your link" || !strings.HasPrefix(message.Text, "alice: https://app.example.com/login/email?token=") {
+		t.Errorf("Expected the overridden template, got %+v", message)
+	}
+	if message.HTML != "" {
+		t.Errorf("Expected a text-only message, got %q", message.HTML)
+	}
+
+	if _, err := newEmailRenderer(EmailConfig{Templates: map[string]EmailTemplate{"custom": {Text: "{{.Link"}}}); err == nil {
+		t.Error("Expected an invalid template to be rejected")
+	}
+	if _, err := newEmailRenderer(EmailConfig{BaseURL: "/relative"}); err == nil {
+		t.Error("Expected a relative base URL to be rejected")
+	}
+
+	// Links need a base URL and sending needs a sender
+	ta = newEmailTestAuth(t, EmailConfig{})
+	if _, err := ta.Emails().Link(EmailPasswordReset, nil); !errors.Is(err, NewAuthError(ErrCodeMissingConfig, "")) {
+		t.Errorf("Expected MISSING_CONFIG without a base URL, got %v", err)
+	}
+	if err := ta.Emails().SendMagicLink(context.Background(), "alice@example.test"); !errors.Is(err, NewAuthError(ErrCodeMissingConfig, "")) {
+		t.Errorf("Expected MISSING_CONFIG without a sender, got %v", err)
+	}
+}
+
+// findLink returns the first URL in text.
+func findLink(t *testing.T, text string) string {
+	t.Helper()
+	for _, field := range strings.Fields(text) {
+		if strings.HasPrefix(field, "https://") {
+			return field
+		}
+	}
+	t.Fatalf("No link in %q", text)
+	return ""
+}
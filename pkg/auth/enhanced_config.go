@@ -1,6 +1,9 @@
 package auth
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
@@ -21,9 +24,18 @@ type EnhancedConfig struct {
 	JWTRefreshSecret string        `env:"AUTH_JWT_REFRESH_SECRET" required:"true"`
 	JWTIssuer        string        `env:"AUTH_JWT_ISSUER" default:"go-auth"`
 	JWTSigningMethod string        `env:"AUTH_JWT_SIGNING_METHOD" default:"HS256"`
+	TokenFormat      string        `env:"AUTH_TOKEN_FORMAT" default:"jwt"`
 	AccessTokenTTL   time.Duration `env:"AUTH_ACCESS_TOKEN_TTL" default:"15m"`
 	RefreshTokenTTL  time.Duration `env:"AUTH_REFRESH_TOKEN_TTL" default:"168h"`
 
+	// Issuer and audience validation; lists are comma-separated in the environment
+	JWTAudience          []string `env:"AUTH_JWT_AUDIENCE"`
+	JWTExpectedIssuers   []string `env:"AUTH_JWT_EXPECTED_ISSUERS"`
+	JWTExpectedAudiences []string `env:"AUTH_JWT_EXPECTED_AUDIENCES"`
+
+	// Clock skew tolerated when validating token timestamps
+	JWTLeeway time.Duration `env:"AUTH_JWT_LEEWAY" default:"0s"`
+
 	// Security configuration
 	PasswordMinLength int  `env:"AUTH_PASSWORD_MIN_LENGTH" default:"8"`
 	RequireEmail      bool `env:"AUTH_REQUIRE_EMAIL" default:"true"`
@@ -82,13 +94,21 @@ var (
 	}
 )
 
-// LoadConfigFromEnv loads configuration from environment variables with defaults
-func LoadConfigFromEnv() (*EnhancedConfig, error) {
+// LoadConfigFromEnv loads configuration from environment variables with
+// defaults, after loading a dotenv file if WithDotEnv is given.
+// Unrecognized AUTH_* variables are ignored unless WithStrictEnv or
+// WithEnvWarnings is given.
+func LoadConfigFromEnv(opts ...EnvOption) (*EnhancedConfig, error) {
+	if err := prepareEnv(os.Getenv("AUTH_PROFILE"), opts); err != nil {
+		return nil, err
+	}
+
 	// Start with default configuration
 	config := NewEnhancedConfig()
 
 	// Apply profile-based configuration if specified
-	if profile := os.Getenv("AUTH_PROFILE"); profile != "" {
+	profile := os.Getenv("AUTH_PROFILE")
+	if profile != "" {
 		if err := applyProfile(config, profile); err != nil {
 			return nil, fmt.Errorf("failed to apply profile %s: %w", profile, err)
 		}
@@ -98,6 +118,9 @@ func LoadConfigFromEnv() (*EnhancedConfig, error) {
 	if err := loadFromEnv(config); err != nil {
 		return nil, fmt.Errorf("failed to load configuration from environment: %w", err)
 	}
+	if profile != "" {
+		applyAudienceDefault(config)
+	}
 
 	// Validate the configuration
 	if err := config.Validate(); err != nil {
@@ -108,7 +131,11 @@ func LoadConfigFromEnv() (*EnhancedConfig, error) {
 }
 
 // LoadConfigWithProfile loads configuration with a specific profile
-func LoadConfigWithProfile(profileName string) (*EnhancedConfig, error) {
+func LoadConfigWithProfile(profileName string, opts ...EnvOption) (*EnhancedConfig, error) {
+	if err := prepareEnv(profileName, opts); err != nil {
+		return nil, err
+	}
+
 	// Start with default configuration
 	config := NewEnhancedConfig()
 
@@ -121,6 +148,7 @@ func LoadConfigWithProfile(profileName string) (*EnhancedConfig, error) {
 	if err := loadFromEnv(config); err != nil {
 		return nil, fmt.Errorf("failed to load configuration from environment: %w", err)
 	}
+	applyAudienceDefault(config)
 
 	// Validate the configuration
 	if err := config.Validate(); err != nil {
@@ -137,6 +165,7 @@ func NewEnhancedConfig() *EnhancedConfig {
 		DatabaseURL:       "auth.db",
 		JWTIssuer:         "go-auth",
 		JWTSigningMethod:  "HS256",
+		TokenFormat:       TokenFormatJWT,
 		AccessTokenTTL:    15 * time.Minute,
 		RefreshTokenTTL:   168 * time.Hour, // 7 days
 		PasswordMinLength: 8,
@@ -165,6 +194,11 @@ func (c *EnhancedConfig) Validate() error {
 		errors = append(errors, fmt.Sprintf("invalid JWT signing method: %s, must be one of %v", c.JWTSigningMethod, validSigningMethods))
 	}
 
+	validTokenFormats := []string{TokenFormatJWT, TokenFormatPASETO}
+	if c.TokenFormat != "" && !contains(validTokenFormats, c.TokenFormat) {
+		errors = append(errors, fmt.Sprintf("invalid token format: %s, must be one of %v", c.TokenFormat, validTokenFormats))
+	}
+
 	// Validate token TTLs
 	if c.AccessTokenTTL <= 0 {
 		errors = append(errors, "access token TTL must be positive")
@@ -175,6 +209,12 @@ func (c *EnhancedConfig) Validate() error {
 	if c.AccessTokenTTL >= c.RefreshTokenTTL {
 		errors = append(errors, "access token TTL must be less than refresh token TTL")
 	}
+	if c.JWTLeeway < 0 {
+		errors = append(errors, "JWT leeway must not be negative")
+	}
+	if c.AccessTokenTTL > 0 && c.JWTLeeway >= c.AccessTokenTTL {
+		errors = append(errors, "JWT leeway must be less than access token TTL")
+	}
 
 	// Validate password requirements
 	if c.PasswordMinLength < 4 {
@@ -218,9 +258,40 @@ func (c *EnhancedConfig) ToJWTConfig() JWTConfig {
 		AccessTokenTTL:  c.AccessTokenTTL,
 		RefreshTokenTTL: c.RefreshTokenTTL,
 		SigningMethod:   c.JWTSigningMethod,
+
+		Audience:          c.JWTAudience,
+		ExpectedIssuers:   c.JWTExpectedIssuers,
+		ExpectedAudiences: c.JWTExpectedAudiences,
+		Leeway:            c.JWTLeeway,
 	}
 }
 
+// ToAuthConfig converts EnhancedConfig to an AuthConfig for NewWithConfig.
+func (c *EnhancedConfig) ToAuthConfig() *AuthConfig {
+	config := &AuthConfig{
+		JWTSecret:        c.JWTAccessSecret,
+		JWTRefreshSecret: c.JWTRefreshSecret,
+		JWTIssuer:        c.JWTIssuer,
+		AccessTokenTTL:   c.AccessTokenTTL,
+		RefreshTokenTTL:  c.RefreshTokenTTL,
+		TokenFormat:      c.TokenFormat,
+		AppName:          c.AppName,
+		LogLevel:         c.LogLevel,
+
+		JWTAudience:       c.JWTAudience,
+		ExpectedIssuers:   c.JWTExpectedIssuers,
+		ExpectedAudiences: c.JWTExpectedAudiences,
+		TokenLeeway:       c.JWTLeeway,
+	}
+	switch c.DatabaseType {
+	case "postgres":
+		config.DatabaseURL = c.DatabaseURL
+	case "sqlite":
+		config.DatabasePath = c.DatabaseURL
+	}
+	return config
+}
+
 // ToConfig converts EnhancedConfig to the legacy Config format
 func (c *EnhancedConfig) ToConfig() Config {
 	return Config{
@@ -252,6 +323,18 @@ func loadFromEnv(config *EnhancedConfig) error {
 	if val := os.Getenv("AUTH_JWT_SIGNING_METHOD"); val != "" {
 		config.JWTSigningMethod = val
 	}
+	if val := os.Getenv("AUTH_TOKEN_FORMAT"); val != "" {
+		config.TokenFormat = val
+	}
+	if val := os.Getenv("AUTH_JWT_AUDIENCE"); val != "" {
+		config.JWTAudience = splitList(val)
+	}
+	if val := os.Getenv("AUTH_JWT_EXPECTED_ISSUERS"); val != "" {
+		config.JWTExpectedIssuers = splitList(val)
+	}
+	if val := os.Getenv("AUTH_JWT_EXPECTED_AUDIENCES"); val != "" {
+		config.JWTExpectedAudiences = splitList(val)
+	}
 
 	// Parse duration values
 	if val := os.Getenv("AUTH_ACCESS_TOKEN_TTL"); val != "" {
@@ -268,6 +351,13 @@ func loadFromEnv(config *EnhancedConfig) error {
 		}
 		config.RefreshTokenTTL = duration
 	}
+	if val := os.Getenv("AUTH_JWT_LEEWAY"); val != "" {
+		duration, err := time.ParseDuration(val)
+		if err != nil {
+			return fmt.Errorf("invalid AUTH_JWT_LEEWAY: %w", err)
+		}
+		config.JWTLeeway = duration
+	}
 
 	// Security configuration
 	if val := os.Getenv("AUTH_PASSWORD_MIN_LENGTH"); val != "" {
@@ -344,6 +434,12 @@ func applyProfile(config *EnhancedConfig, profileName string) error {
 					config.RefreshTokenTTL = duration
 				}
 			}
+		case "AUTH_JWT_LEEWAY":
+			if v, ok := value.(string); ok {
+				if duration, err := time.ParseDuration(v); err == nil {
+					config.JWTLeeway = duration
+				}
+			}
 		case "AUTH_PASSWORD_MIN_LENGTH":
 			if v, ok := value.(int); ok {
 				config.PasswordMinLength = v
@@ -354,6 +450,26 @@ func applyProfile(config *EnhancedConfig, profileName string) error {
 	return nil
 }
 
+// applyAudienceDefault scopes tokens to the app and environment when no
+// audience is configured, so tokens minted by one profile (e.g. staging) are
+// rejected by another (e.g. production) even if their secrets are shared.
+func applyAudienceDefault(config *EnhancedConfig) {
+	if len(config.JWTAudience) == 0 {
+		config.JWTAudience = []string{config.AppName + ":" + config.Environment}
+	}
+}
+
+// splitList splits a comma-separated environment value, dropping empty items.
+func splitList(val string) []string {
+	var items []string
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // contains checks if a slice contains a string
 func contains(slice []string, item string) bool {
 	for _, s := range slice {
@@ -382,8 +498,17 @@ func (c *EnhancedConfig) PrintConfig() {
 	fmt.Printf("Database URL: %s\n", c.DatabaseURL)
 	fmt.Printf("JWT Issuer: %s\n", c.JWTIssuer)
 	fmt.Printf("JWT Signing Method: %s\n", c.JWTSigningMethod)
+	fmt.Printf("Token Format: %s\n", c.TokenFormat)
+	fmt.Printf("JWT Audience: %s\n", strings.Join(c.JWTAudience, ", "))
+	if len(c.JWTExpectedIssuers) > 0 {
+		fmt.Printf("JWT Expected Issuers: %s\n", strings.Join(c.JWTExpectedIssuers, ", "))
+	}
+	if len(c.JWTExpectedAudiences) > 0 {
+		fmt.Printf("JWT Expected Audiences: %s\n", strings.Join(c.JWTExpectedAudiences, ", "))
+	}
 	fmt.Printf("Access Token TTL: %s\n", c.AccessTokenTTL)
 	fmt.Printf("Refresh Token TTL: %s\n", c.RefreshTokenTTL)
+	fmt.Printf("JWT Leeway: %s\n", c.JWTLeeway)
 	fmt.Printf("Password Min Length: %d\n", c.PasswordMinLength)
 	fmt.Printf("Require Email: %t\n", c.RequireEmail)
 	fmt.Printf("Log Level: %s\n", c.LogLevel)
@@ -392,6 +517,82 @@ func (c *EnhancedConfig) PrintConfig() {
 	fmt.Println("=====================================")
 }
 
+// EffectiveConfig is the resolved configuration a service runs with, after
+// profiles, environment variables and defaults, with secrets masked. Its
+// JSON encoding is deterministic, so it can be logged at startup and
+// diffed between deployments.
+type EffectiveConfig struct {
+	DatabaseType string `json:"database_type"`
+	// DatabaseURL has its password masked.
+	DatabaseURL string `json:"database_url"`
+
+	// Secrets are shown as fingerprints, which change with the secret but
+	// do not reveal it.
+	JWTAccessSecret      string   `json:"jwt_access_secret"`
+	JWTRefreshSecret     string   `json:"jwt_refresh_secret"`
+	JWTIssuer            string   `json:"jwt_issuer"`
+	JWTSigningMethod     string   `json:"jwt_signing_method"`
+	TokenFormat          string   `json:"token_format"`
+	AccessTokenTTL       string   `json:"access_token_ttl"`
+	RefreshTokenTTL      string   `json:"refresh_token_ttl"`
+	JWTAudience          []string `json:"jwt_audience"`
+	JWTExpectedIssuers   []string `json:"jwt_expected_issuers"`
+	JWTExpectedAudiences []string `json:"jwt_expected_audiences"`
+	JWTLeeway            string   `json:"jwt_leeway"`
+
+	PasswordMinLength int  `json:"password_min_length"`
+	RequireEmail      bool `json:"require_email"`
+
+	AppName     string `json:"app_name"`
+	Environment string `json:"environment"`
+	LogLevel    string `json:"log_level"`
+
+	// Storage is the type of the storage instance, if one is set.
+	Storage string `json:"storage,omitempty"`
+}
+
+// Effective returns the resolved configuration with secrets masked.
+func (c *EnhancedConfig) Effective() EffectiveConfig {
+	effective := EffectiveConfig{
+		DatabaseType:         c.DatabaseType,
+		DatabaseURL:          redactURL(c.DatabaseURL),
+		JWTAccessSecret:      fingerprintSecret(c.JWTAccessSecret),
+		JWTRefreshSecret:     fingerprintSecret(c.JWTRefreshSecret),
+		JWTIssuer:            c.JWTIssuer,
+		JWTSigningMethod:     c.JWTSigningMethod,
+		TokenFormat:          c.TokenFormat,
+		AccessTokenTTL:       c.AccessTokenTTL.String(),
+		RefreshTokenTTL:      c.RefreshTokenTTL.String(),
+		JWTAudience:          append([]string{}, c.JWTAudience...),
+		JWTExpectedIssuers:   append([]string{}, c.JWTExpectedIssuers...),
+		JWTExpectedAudiences: append([]string{}, c.JWTExpectedAudiences...),
+		JWTLeeway:            c.JWTLeeway.String(),
+		PasswordMinLength:    c.PasswordMinLength,
+		RequireEmail:         c.RequireEmail,
+		AppName:              c.AppName,
+		Environment:          c.Environment,
+		LogLevel:             c.LogLevel,
+	}
+	if c.Storage != nil {
+		effective.Storage = fmt.Sprintf("%T", c.Storage)
+	}
+	return effective
+}
+
+// JSON returns the indented JSON encoding of the configuration.
+func (e EffectiveConfig) JSON() ([]byte, error) {
+	return json.MarshalIndent(e, "", "  ")
+}
+
+// fingerprintSecret identifies a secret by a short hash of it.
+func fingerprintSecret(secret string) string {
+	if secret == "" {
+		return "[NOT SET]"
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return "sha256:" + hex.EncodeToString(sum[:4])
+}
+
 // maskSecret masks a secret string for safe printing
 func maskSecret(secret string) string {
 	if secret == "" {
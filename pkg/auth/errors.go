@@ -2,8 +2,12 @@ package auth
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
+
+	"github.com/pragneshbagary/go-auth/pkg/storage"
 )
 
 // AuthError represents structured authentication errors with error codes and context.
@@ -11,6 +15,10 @@ type AuthError struct {
 	Code    string `json:"code"`
 	Message string `json:"message"`
 	Details string `json:"details,omitempty"`
+
+	// detailsKey and detailsArgs identify the catalog entry used to localize Details.
+	detailsKey  string
+	detailsArgs []interface{}
 }
 
 // Error codes for common authentication errors
@@ -22,24 +30,36 @@ const (
 	ErrCodeTokenRevoked      = "TOKEN_REVOKED"
 	ErrCodeMissingToken      = "MISSING_TOKEN"
 	ErrCodeMalformedToken    = "MALFORMED_TOKEN"
+	ErrCodeInvalidDPoPProof  = "INVALID_DPOP_PROOF"
+	ErrCodeCaptchaRequired   = "CAPTCHA_REQUIRED"
+	ErrCodeSessionExpired    = "SESSION_EXPIRED"
+	ErrCodeStepUpRequired    = "STEP_UP_REQUIRED"
+	ErrCodeLoginDenied       = "LOGIN_DENIED"
+	ErrCodeElevationRequired = "ELEVATION_REQUIRED"
 	
 	// User management errors
 	ErrCodeUserExists        = "USER_EXISTS"
 	ErrCodeUserNotFound      = "USER_NOT_FOUND"
 	ErrCodeUserInactive      = "USER_INACTIVE"
 	ErrCodeUserDeleted       = "USER_DELETED"
+	ErrCodeConsentRequired   = "CONSENT_REQUIRED"
+	ErrCodeUnderAge          = "UNDER_AGE"
+	ErrCodeAccountExpired    = "ACCOUNT_EXPIRED"
+	ErrCodeSubscriptionInactive = "SUBSCRIPTION_INACTIVE"
 	
 	// Password errors
 	ErrCodeWeakPassword      = "WEAK_PASSWORD"
 	ErrCodePasswordMismatch  = "PASSWORD_MISMATCH"
 	ErrCodeInvalidResetToken = "INVALID_RESET_TOKEN"
 	ErrCodeResetTokenExpired = "RESET_TOKEN_EXPIRED"
+	ErrCodePasswordExpired   = "PASSWORD_EXPIRED"
 	
 	// Database and storage errors
 	ErrCodeDatabaseError     = "DATABASE_ERROR"
 	ErrCodeStorageError      = "STORAGE_ERROR"
 	ErrCodeConnectionError   = "CONNECTION_ERROR"
 	ErrCodeMigrationError    = "MIGRATION_ERROR"
+	ErrCodeCircuitOpen       = "CIRCUIT_OPEN"
 	
 	// Configuration errors
 	ErrCodeConfigError       = "CONFIG_ERROR"
@@ -51,6 +71,10 @@ const (
 	ErrCodeValidationError   = "VALIDATION_ERROR"
 	ErrCodePermissionDenied  = "PERMISSION_DENIED"
 	ErrCodeRateLimitExceeded = "RATE_LIMIT_EXCEEDED"
+	ErrCodeMaintenanceMode   = "MAINTENANCE_MODE"
+	ErrCodeInvalidCSRFToken  = "INVALID_CSRF_TOKEN"
+	ErrCodeTokenTooLarge     = "TOKEN_TOO_LARGE"
+	ErrCodeQuotaExceeded     = "QUOTA_EXCEEDED"
 )
 
 // NewAuthError creates a new AuthError with the specified code and message.
@@ -132,21 +156,27 @@ func getHTTPStatusFromError(err error) int {
 	if authErr, ok := err.(*AuthError); ok {
 		switch authErr.Code {
 		case ErrCodeInvalidCredentials, ErrCodeInvalidToken, ErrCodeTokenExpired, 
-			 ErrCodeTokenRevoked, ErrCodeMissingToken, ErrCodeMalformedToken:
+			 ErrCodeTokenRevoked, ErrCodeMissingToken, ErrCodeMalformedToken, ErrCodeInvalidDPoPProof,
+			 ErrCodeCaptchaRequired, ErrCodeSessionExpired, ErrCodeStepUpRequired:
 			return http.StatusUnauthorized
 		case ErrCodeUserNotFound, ErrCodeInvalidResetToken:
 			return http.StatusNotFound
 		case ErrCodeUserExists:
 			return http.StatusConflict
-		case ErrCodeUserInactive, ErrCodeUserDeleted, ErrCodePermissionDenied:
+		case ErrCodeUserInactive, ErrCodeUserDeleted, ErrCodePermissionDenied, ErrCodeInvalidCSRFToken,
+			 ErrCodeConsentRequired, ErrCodeUnderAge, ErrCodePasswordExpired, ErrCodeLoginDenied,
+			 ErrCodeElevationRequired, ErrCodeAccountExpired, ErrCodeSubscriptionInactive,
+			 ErrCodeQuotaExceeded:
 			return http.StatusForbidden
 		case ErrCodeWeakPassword, ErrCodePasswordMismatch, ErrCodeValidationError,
 			 ErrCodeInvalidConfig, ErrCodeMissingConfig:
 			return http.StatusBadRequest
 		case ErrCodeRateLimitExceeded:
 			return http.StatusTooManyRequests
+		case ErrCodeMaintenanceMode, ErrCodeCircuitOpen:
+			return http.StatusServiceUnavailable
 		case ErrCodeDatabaseError, ErrCodeStorageError, ErrCodeConnectionError,
-			 ErrCodeMigrationError, ErrCodeInternalError:
+			 ErrCodeMigrationError, ErrCodeInternalError, ErrCodeTokenTooLarge:
 			return http.StatusInternalServerError
 		case ErrCodeConfigError:
 			return http.StatusInternalServerError
@@ -172,7 +202,7 @@ func ErrUserNotFound() *AuthError {
 // ErrUserExists creates a standard user already exists error.
 func ErrUserExists(identifier string) *AuthError {
 	return NewAuthErrorWithDetails(ErrCodeUserExists, "User already exists", 
-		fmt.Sprintf("A user with this %s already exists", identifier))
+		fmt.Sprintf("A user with this %s already exists", identifier)).withDetailsKey(MsgKeyUserExists, identifier)
 }
 
 // ErrInvalidToken creates a standard invalid token error.
@@ -185,6 +215,12 @@ func ErrTokenRevoked() *AuthError {
 	return NewAuthError(ErrCodeTokenRevoked, "Token has been revoked")
 }
 
+// ErrSessionExpired creates an error for tokens of a session that ended
+// after a period of inactivity.
+func ErrSessionExpired() *AuthError {
+	return NewAuthError(ErrCodeSessionExpired, "Session has expired due to inactivity")
+}
+
 // ErrMissingToken creates a standard missing token error.
 func ErrMissingToken() *AuthError {
 	return NewAuthError(ErrCodeMissingToken, "Authorization token is required")
@@ -195,11 +231,114 @@ func ErrUserInactive() *AuthError {
 	return NewAuthError(ErrCodeUserInactive, "User account is inactive")
 }
 
+// ErrPermissionDenied creates an error for callers lacking a role or scope.
+func ErrPermissionDenied(requirement string) *AuthError {
+	return NewAuthErrorWithDetails(ErrCodePermissionDenied, "Permission denied", requirement)
+}
+
+// ErrConsentRequired creates an error for logins of users who have not
+// accepted the current version of every required document. The details list
+// the pending documents as "<document ID>:<version>", comma-separated.
+func ErrConsentRequired(pending []string) *AuthError {
+	return NewAuthErrorWithDetails(ErrCodeConsentRequired, "Acceptance of updated terms required",
+		strings.Join(pending, ","))
+}
+
+// ErrPasswordExpired creates an error for logins with a password older than
+// the configured maximum age.
+func ErrPasswordExpired() *AuthError {
+	return NewAuthError(ErrCodePasswordExpired, "Password has expired and must be changed")
+}
+
+// ErrAccountExpired creates an error for users past their account expiry,
+// see Users.ExtendExpiry.
+func ErrAccountExpired() *AuthError {
+	return NewAuthError(ErrCodeAccountExpired, "Account has expired")
+}
+
+// ErrSubscriptionInactive creates an error for users whose subscription
+// status is blocked by SubscriptionConfig.BlockedStatuses.
+func ErrSubscriptionInactive(status string) *AuthError {
+	return NewAuthErrorWithDetails(ErrCodeSubscriptionInactive, "Subscription is not active", status)
+}
+
+// ErrUnderAge creates an error for registrations rejected by the age policy.
+func ErrUnderAge() *AuthError {
+	return NewAuthError(ErrCodeUnderAge, "Registration requires a minimum age")
+}
+
 // ErrWeakPassword creates a standard weak password error.
 func ErrWeakPassword(requirements string) *AuthError {
 	return NewAuthErrorWithDetails(ErrCodeWeakPassword, "Password does not meet requirements", requirements)
 }
 
+// ErrPasswordTooShort creates a weak password error for a minimum length violation.
+func ErrPasswordTooShort(minLength int) *AuthError {
+	return ErrWeakPassword(fmt.Sprintf("Password must be at least %d characters long", minLength)).
+		withDetailsKey(MsgKeyPasswordTooShort, minLength)
+}
+
+// ErrInvalidDPoPProof creates an error for a missing or invalid DPoP proof.
+func ErrInvalidDPoPProof(details string) *AuthError {
+	return NewAuthErrorWithDetails(ErrCodeInvalidDPoPProof, "Invalid DPoP proof", details)
+}
+
+// ErrRateLimitExceeded creates a standard rate limit error.
+func ErrRateLimitExceeded() *AuthError {
+	return NewAuthError(ErrCodeRateLimitExceeded, "Too many requests")
+}
+
+// ErrCaptchaRequired creates an error for logins that must pass a CAPTCHA first.
+// The details carry the challenge reference as "<provider>:<site key>".
+func ErrCaptchaRequired(challenge string) *AuthError {
+	return NewAuthErrorWithDetails(ErrCodeCaptchaRequired, "CAPTCHA verification required", challenge)
+}
+
+// ErrStepUpRequired creates an error for risky logins that need a second
+// factor. The details carry the challenge for Auth.CompleteStepUp.
+func ErrStepUpRequired(challenge string) *AuthError {
+	return NewAuthErrorWithDetails(ErrCodeStepUpRequired, "Additional verification required", challenge)
+}
+
+// ErrLoginDenied creates an error for logins rejected as too risky.
+func ErrLoginDenied() *AuthError {
+	return NewAuthError(ErrCodeLoginDenied, "Login denied")
+}
+
+// ErrElevationRequired creates an error for requests that need an elevated
+// access token, see Auth.Elevate.
+func ErrElevationRequired() *AuthError {
+	return NewAuthError(ErrCodeElevationRequired, "Recent re-authentication required")
+}
+
+// ErrInvalidCSRFToken creates an error for a missing, expired or mismatched CSRF token.
+func ErrInvalidCSRFToken(details string) *AuthError {
+	return NewAuthErrorWithDetails(ErrCodeInvalidCSRFToken, "Invalid CSRF token", details)
+}
+
+// ErrMaintenanceMode creates an error for operations rejected during maintenance.
+// The operator-supplied message, if any, is returned as the error details.
+func ErrMaintenanceMode(message string) *AuthError {
+	return NewAuthErrorWithDetails(ErrCodeMaintenanceMode, "Service is under maintenance", message)
+}
+
+// ErrCircuitOpen creates an error for calls rejected by an open circuit breaker.
+func ErrCircuitOpen(circuit string) *AuthError {
+	return NewAuthErrorWithDetails(ErrCodeCircuitOpen, "Service temporarily unavailable",
+		fmt.Sprintf("Circuit %s is open", circuit))
+}
+
+// ErrTokenTooLarge creates an error for an access token exceeding the size limit.
+func ErrTokenTooLarge(size, limit int) *AuthError {
+	return NewAuthErrorWithDetails(ErrCodeTokenTooLarge, "Access token is too large",
+		fmt.Sprintf("Encoded token is %d bytes, the limit is %d; reduce the claims or configure TokenSize.TrimClaims", size, limit))
+}
+
+// ErrQuotaExceeded creates an error for a registration over quota.
+func ErrQuotaExceeded(details string) *AuthError {
+	return NewAuthErrorWithDetails(ErrCodeQuotaExceeded, "Registration quota exceeded", details)
+}
+
 // ErrDatabaseError creates a database error without exposing internal details.
 func ErrDatabaseError() *AuthError {
 	return NewAuthError(ErrCodeDatabaseError, "Database operation failed")
@@ -214,7 +353,7 @@ func ErrConfigError(field string) *AuthError {
 // ErrValidationError creates a validation error.
 func ErrValidationError(field string) *AuthError {
 	return NewAuthErrorWithDetails(ErrCodeValidationError, "Validation failed", 
-		fmt.Sprintf("Invalid value for field: %s", field))
+		fmt.Sprintf("Invalid value for field: %s", field)).withDetailsKey(MsgKeyInvalidField, field)
 }
 
 // WrapError wraps a generic error as an AuthError with the specified code.
@@ -234,10 +373,16 @@ func WrapError(err error, code, message string) *AuthError {
 }
 
 // WrapDatabaseError wraps database errors as AuthErrors without exposing sensitive details.
+// A *storage.DuplicateError becomes USER_EXISTS.
 func WrapDatabaseError(err error) *AuthError {
 	if err == nil {
 		return nil
 	}
+	// Unique index violations are reported like the checks done before writes
+	var duplicate *storage.DuplicateError
+	if errors.As(err, &duplicate) {
+		return ErrUserExists(duplicate.Field)
+	}
 	return WrapError(err, ErrCodeDatabaseError, "Database operation failed")
 }
 
@@ -0,0 +1,382 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuthEvent is an authentication event as delivered to event sinks.
+type AuthEvent struct {
+	ID      string    `json:"id"`
+	Type    string    `json:"type"` // e.g. "user_login"
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Message string    `json:"message"`
+	UserID  string    `json:"user_id,omitempty"`
+	// Data holds the remaining event fields. Errors and durations are
+	// encoded as strings.
+	Data map[string]interface{} `json:"data,omitempty"`
+}
+
+// newAuthEvent builds an event from the fields of an event log entry.
+func newAuthEvent(level LogLevel, message string, fields map[string]interface{}) AuthEvent {
+	event := AuthEvent{
+		ID:      uuid.New().String(),
+		Time:    time.Now().UTC(),
+		Level:   level.String(),
+		Message: message,
+		Data:    make(map[string]interface{}, len(fields)),
+	}
+	for key, value := range fields {
+		switch key {
+		case "event":
+			event.Type, _ = value.(string)
+			continue
+		case "user_id":
+			event.UserID, _ = value.(string)
+			continue
+		}
+		switch v := value.(type) {
+		case error:
+			value = v.Error()
+		case time.Duration:
+			value = v.String()
+		}
+		event.Data[key] = value
+	}
+	return event
+}
+
+// EventSink delivers authentication events to an external system, e.g. a
+// message broker feeding login analytics.
+type EventSink interface {
+	// Publish delivers an event. It must only return nil once the event is
+	// durably accepted; failed events are retried, so delivery is
+	// at-least-once and consumers should deduplicate by AuthEvent.ID.
+	Publish(ctx context.Context, event AuthEvent) error
+}
+
+// EventSinkConfig attaches an EventSink to the event logger. Events are
+// delivered asynchronously in order, retrying failures with exponential
+// backoff until they succeed.
+type EventSinkConfig struct {
+	Sink EventSink
+	// Events lists the event types to deliver, e.g. "user_login". All
+	// events are delivered when empty, including a "token_validation"
+	// event per validated token.
+	Events []string
+	// BufferSize bounds the events waiting for delivery. Events arriving
+	// while the buffer is full are dropped and logged. Defaults to 1,000.
+	BufferSize int
+	// RetryBackoff is the delay before the first retry; it doubles up to
+	// MaxRetryBackoff. Defaults to 100ms and 30s.
+	RetryBackoff    time.Duration
+	MaxRetryBackoff time.Duration
+	// ShutdownTimeout bounds how long Auth.Close waits for buffered events
+	// to be delivered. Defaults to 5 seconds.
+	ShutdownTimeout time.Duration
+}
+
+// eventDispatcher delivers events to one sink from a background goroutine.
+type eventDispatcher struct {
+	config EventSinkConfig
+	types  map[string]bool
+	logger *Logger
+
+	queue   chan AuthEvent
+	stop    chan struct{}
+	done    chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	closing sync.Once
+	dropped atomic.Int64
+}
+
+// newEventDispatcher starts delivering to config.Sink.
+func newEventDispatcher(config EventSinkConfig, logger *Logger) *eventDispatcher {
+	if config.BufferSize <= 0 {
+		config.BufferSize = 1000
+	}
+	if config.RetryBackoff <= 0 {
+		config.RetryBackoff = 100 * time.Millisecond
+	}
+	if config.MaxRetryBackoff <= 0 {
+		config.MaxRetryBackoff = 30 * time.Second
+	}
+	if config.ShutdownTimeout <= 0 {
+		config.ShutdownTimeout = 5 * time.Second
+	}
+	d := &eventDispatcher{
+		config: config,
+		logger: logger,
+		queue:  make(chan AuthEvent, config.BufferSize),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if len(config.Events) > 0 {
+		d.types = make(map[string]bool, len(config.Events))
+		for _, eventType := range config.Events {
+			d.types[eventType] = true
+		}
+	}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	go d.run()
+	return d
+}
+
+// enqueue schedules event for delivery if the sink wants it.
+func (d *eventDispatcher) enqueue(event AuthEvent) {
+	if d.types != nil && !d.types[event.Type] {
+		return
+	}
+	select {
+	case <-d.stop:
+		d.drop(event, "event sink closed")
+		return
+	default:
+	}
+	select {
+	case d.queue <- event:
+	default:
+		d.drop(event, "event sink buffer full")
+	}
+}
+
+// drop records an event that will not be delivered.
+func (d *eventDispatcher) drop(event AuthEvent, reason string) {
+	d.dropped.Add(1)
+	d.logger.Warn("Dropped auth event", map[string]interface{}{
+		"event_id":   event.ID,
+		"event_type": event.Type,
+		"reason":     reason,
+	})
+}
+
+// run delivers queued events until the dispatcher is closed, then drains
+// the queue.
+func (d *eventDispatcher) run() {
+	defer close(d.done)
+	for {
+		select {
+		case event := <-d.queue:
+			d.deliver(event)
+		case <-d.stop:
+			for {
+				select {
+				case event := <-d.queue:
+					d.deliver(event)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver publishes event, retrying with exponential backoff until it is
+// accepted or the dispatcher gives up on shutdown.
+func (d *eventDispatcher) deliver(event AuthEvent) {
+	backoff := d.config.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		if d.ctx.Err() != nil {
+			d.drop(event, "shutdown timeout exceeded")
+			return
+		}
+		err := d.config.Sink.Publish(d.ctx, event)
+		if err == nil {
+			return
+		}
+		d.logger.Warn("Failed to publish auth event", map[string]interface{}{
+			"event_id":   event.ID,
+			"event_type": event.Type,
+			"attempt":    attempt,
+			"error":      err,
+		})
+		select {
+		case <-time.After(backoff):
+		case <-d.ctx.Done():
+		}
+		backoff = min(2*backoff, d.config.MaxRetryBackoff)
+	}
+}
+
+// close stops accepting events and waits up to the shutdown timeout for the
+// buffered ones to be delivered.
+func (d *eventDispatcher) close() {
+	d.closing.Do(func() {
+		close(d.stop)
+		select {
+		case <-d.done:
+		case <-time.After(d.config.ShutdownTimeout):
+			d.cancel()
+			<-d.done
+		}
+		d.cancel()
+	})
+}
+
+// EventFormat is the wire format of events published by broker sinks.
+type EventFormat int
+
+const (
+	// EventFormatJSON encodes the AuthEvent as JSON.
+	EventFormatJSON EventFormat = iota
+	// EventFormatCloudEvents encodes a CloudEvents 1.0 structured-mode JSON
+	// envelope with the AuthEvent as data.
+	EventFormatCloudEvents
+)
+
+// CloudEventTypePrefix prefixes the event type in CloudEvents envelopes,
+// e.g. "com.github.pragneshbagary.go-auth.user_login".
+const CloudEventTypePrefix = "com.github.pragneshbagary.go-auth."
+
+// CloudEvent is a CloudEvents 1.0 envelope in structured JSON mode.
+type CloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            AuthEvent `json:"data"`
+}
+
+// NewCloudEvent wraps event in a CloudEvents envelope. source identifies the
+// emitting service, e.g. "/auth/eu-west-1"; the subject is the user ID.
+func NewCloudEvent(event AuthEvent, source string) CloudEvent {
+	return CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              event.ID,
+		Source:          source,
+		Type:            CloudEventTypePrefix + event.Type,
+		Subject:         event.UserID,
+		Time:            event.Time,
+		DataContentType: "application/json",
+		Data:            event,
+	}
+}
+
+// EventRouting configures how broker sinks encode and address events.
+type EventRouting struct {
+	// Topic receives events without an entry in Topics. Defaults to
+	// "go-auth.events".
+	Topic string
+	// Topics routes event types to their own topic, e.g.
+	// {"user_login": "auth.logins"}.
+	Topics map[string]string
+	Format EventFormat
+	// Source is the CloudEvents source. Defaults to "go-auth".
+	Source string
+}
+
+// topic returns the topic of an event type.
+func (r EventRouting) topic(eventType string) string {
+	if topic, ok := r.Topics[eventType]; ok {
+		return topic
+	}
+	if r.Topic == "" {
+		return "go-auth.events"
+	}
+	return r.Topic
+}
+
+// encode serializes event in the configured format.
+func (r EventRouting) encode(event AuthEvent) ([]byte, error) {
+	switch r.Format {
+	case EventFormatJSON:
+		return json.Marshal(event)
+	case EventFormatCloudEvents:
+		source := r.Source
+		if source == "" {
+			source = "go-auth"
+		}
+		return json.Marshal(NewCloudEvent(event, source))
+	}
+	return nil, fmt.Errorf("unknown event format %d", r.Format)
+}
+
+// contentType returns the MIME type of encoded events.
+func (r EventRouting) contentType() string {
+	if r.Format == EventFormatCloudEvents {
+		return "application/cloudevents+json"
+	}
+	return "application/json"
+}
+
+// NATSPublisher is the subset of a NATS client used by the NATS event sink.
+// For at-least-once delivery use JetStream, whose Publish waits for the
+// stream's acknowledgement: adapt js.Publish by discarding the PubAck.
+// A NATSConn also satisfies it, but core NATS may lose messages.
+type NATSPublisher interface {
+	Publish(subject string, data []byte) error
+}
+
+// natsEventSink publishes events on NATS subjects.
+type natsEventSink struct {
+	publisher NATSPublisher
+	routing   EventRouting
+}
+
+// NewNATSEventSink creates an EventSink publishing on NATS subjects chosen
+// by routing.
+func NewNATSEventSink(publisher NATSPublisher, routing EventRouting) EventSink {
+	return &natsEventSink{publisher: publisher, routing: routing}
+}
+
+func (s *natsEventSink) Publish(ctx context.Context, event AuthEvent) error {
+	payload, err := s.routing.encode(event)
+	if err != nil {
+		return err
+	}
+	return s.publisher.Publish(s.routing.topic(event.Type), payload)
+}
+
+// KafkaMessage is a record produced by the Kafka event sink.
+type KafkaMessage struct {
+	Topic   string
+	Key     []byte // the user ID, so that a user's events stay ordered
+	Value   []byte
+	Headers map[string]string
+}
+
+// KafkaProducer is the subset of a Kafka client used by the Kafka event
+// sink. Produce must return once the record is acknowledged, e.g. a
+// kafka-go Writer with RequiredAcks set to all, or franz-go's ProduceSync.
+type KafkaProducer interface {
+	Produce(ctx context.Context, message KafkaMessage) error
+}
+
+// kafkaEventSink produces events to Kafka topics.
+type kafkaEventSink struct {
+	producer KafkaProducer
+	routing  EventRouting
+}
+
+// NewKafkaEventSink creates an EventSink producing to Kafka topics chosen
+// by routing.
+func NewKafkaEventSink(producer KafkaProducer, routing EventRouting) EventSink {
+	return &kafkaEventSink{producer: producer, routing: routing}
+}
+
+func (s *kafkaEventSink) Publish(ctx context.Context, event AuthEvent) error {
+	payload, err := s.routing.encode(event)
+	if err != nil {
+		return err
+	}
+	return s.producer.Produce(ctx, KafkaMessage{
+		Topic: s.routing.topic(event.Type),
+		Key:   []byte(event.UserID),
+		Value: payload,
+		Headers: map[string]string{
+			"content-type": s.routing.contentType(),
+			"event-type":   event.Type,
+		},
+	})
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakySink fails the first failures publish attempts.
+type flakySink struct {
+	mu       sync.Mutex
+	failures int
+	attempts int
+	events   []AuthEvent
+}
+
+func (s *flakySink) Publish(ctx context.Context, event AuthEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts++
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("broker unavailable")
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *flakySink) delivered() []AuthEvent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]AuthEvent(nil), s.events...)
+}
+
+func TestEventSinkRetriesUntilDelivered(t *testing.T) {
+	sink := &flakySink{failures: 3}
+	eventLogger := NewAuthEventLogger(NewLogger(LogLevelError, nil))
+	eventLogger.sinks = []*eventDispatcher{newEventDispatcher(EventSinkConfig{
+		Sink:         sink,
+		Events:       []string{"user_login"},
+		RetryBackoff: time.Millisecond,
+	}, eventLogger.logger)}
+
+	eventLogger.LogRegistration("user-1", "alice", "alice@example.com", "", "", true, nil)
+	eventLogger.LogLogin("user-1", "alice", "203.0.113.1", "test", false, time.Millisecond, errors.New("invalid credentials"))
+	eventLogger.close()
+
+	events := sink.delivered()
+	if len(events) != 1 {
+		t.Fatalf("Expected only the login event to be delivered, got %v", events)
+	}
+	event := events[0]
+	if event.Type != "user_login" || event.UserID != "user-1" || event.ID == "" {
+		t.Errorf("Unexpected event: %+v", event)
+	}
+	if event.Data["error"] != "invalid credentials" || event.Data["ip"] != "203.0.113.1" || event.Data["duration"] != "1ms" {
+		t.Errorf("Expected the event fields in Data, got %v", event.Data)
+	}
+	if sink.attempts != 4 {
+		t.Errorf("Expected 4 attempts, got %d", sink.attempts)
+	}
+}
+
+type recordingProducer struct {
+	messages []KafkaMessage
+}
+
+func (p *recordingProducer) Produce(ctx context.Context, message KafkaMessage) error {
+	p.messages = append(p.messages, message)
+	return nil
+}
+
+type recordingPublisher map[string][][]byte
+
+func (p recordingPublisher) Publish(subject string, data []byte) error {
+	p[subject] = append(p[subject], data)
+	return nil
+}
+
+func TestKafkaEventSinkCloudEvents(t *testing.T) {
+	producer := &recordingProducer{}
+	sink := NewKafkaEventSink(producer, EventRouting{
+		Topic:  "auth.events",
+		Topics: map[string]string{"user_login": "auth.logins"},
+		Format: EventFormatCloudEvents,
+		Source: "/auth/test",
+	})
+	event := newAuthEvent(LogLevelInfo, "User logged in successfully", map[string]interface{}{
+		"event":   "user_login",
+		"user_id": "user-1",
+	})
+	if err := sink.Publish(context.Background(), event); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+
+	message := producer.messages[0]
+	if message.Topic != "auth.logins" || string(message.Key) != "user-1" {
+		t.Errorf("Unexpected routing: topic %s, key %s", message.Topic, message.Key)
+	}
+	if message.Headers["content-type"] != "application/cloudevents+json" {
+		t.Errorf("Unexpected content type %s", message.Headers["content-type"])
+	}
+	var envelope CloudEvent
+	if err := json.Unmarshal(message.Value, &envelope); err != nil {
+		t.Fatalf("Failed to decode CloudEvent: %v", err)
+	}
+	if envelope.SpecVersion != "1.0" || envelope.ID != event.ID || envelope.Source != "/auth/test" ||
+		envelope.Type != CloudEventTypePrefix+"user_login" || envelope.Subject != "user-1" {
+		t.Errorf("Unexpected CloudEvent: %+v", envelope)
+	}
+}
+
+func TestNATSEventSinkJSON(t *testing.T) {
+	publisher := recordingPublisher{}
+	sink := NewNATSEventSink(publisher, EventRouting{})
+	event := newAuthEvent(LogLevelInfo, "Consent recorded", map[string]interface{}{"event": "consent"})
+	if err := sink.Publish(context.Background(), event); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+
+	payloads := publisher["go-auth.events"]
+	if len(payloads) != 1 {
+		t.Fatalf("Expected one message on the default subject, got %v", publisher)
+	}
+	var decoded AuthEvent
+	if err := json.Unmarshal(payloads[0], &decoded); err != nil || decoded.ID != event.ID || decoded.Type != "consent" {
+		t.Errorf("Unexpected payload %s (%v)", payloads[0], err)
+	}
+}
+
+func TestEventSinkDropsWhenFull(t *testing.T) {
+	block := make(chan struct{})
+	sink := sinkFunc(func(ctx context.Context, event AuthEvent) error {
+		<-block
+		return nil
+	})
+	d := newEventDispatcher(EventSinkConfig{Sink: sink, BufferSize: 1}, NewLogger(LogLevelError, nil))
+	for i := 0; i < 5; i++ {
+		d.enqueue(AuthEvent{ID: "event"})
+	}
+	close(block)
+	d.close()
+	if d.dropped.Load() == 0 {
+		t.Error("Expected events beyond the buffer to be dropped")
+	}
+}
+
+type sinkFunc func(ctx context.Context, event AuthEvent) error
+
+func (f sinkFunc) Publish(ctx context.Context, event AuthEvent) error {
+	return f(ctx, event)
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/internal/jwtutils"
+)
+
+func TestFeatureFlags(t *testing.T) {
+	ta := NewTestAuth(t)
+
+	if ta.FeatureRollout(FlagPasswordRehash) != 100 || ta.FeatureRollout(FlagStrictAudience) != 0 {
+		t.Errorf("Unexpected default rollout: %d, %d", ta.FeatureRollout(FlagPasswordRehash), ta.FeatureRollout(FlagStrictAudience))
+	}
+
+	if err := ta.SetFeatureRollout(FlagStrictAudience, 25); err != nil {
+		t.Fatalf("SetFeatureRollout failed: %v", err)
+	}
+	enabled := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		if ta.FeatureEnabled(FlagStrictAudience, userID) {
+			enabled[userID] = true
+		}
+	}
+	if len(enabled) < 200 || len(enabled) > 300 {
+		t.Errorf("Expected about 25%% of users enabled, got %d of 1000", len(enabled))
+	}
+
+	// Widening the rollout keeps the users already enabled
+	if err := ta.SetFeatureRollout(FlagStrictAudience, 50); err != nil {
+		t.Fatalf("SetFeatureRollout failed: %v", err)
+	}
+	for userID := range enabled {
+		if !ta.FeatureEnabled(FlagStrictAudience, userID) {
+			t.Fatalf("Expected %s to stay enabled", userID)
+		}
+	}
+
+	if err := ta.SetFeatureRollout(FlagStrictAudience, 101); !errors.Is(err, ErrValidationError("")) {
+		t.Errorf("Expected an invalid percentage to be rejected, got %v", err)
+	}
+	if err := (FeatureFlagsConfig{Rollout: map[string]int{FlagPasswordRehash: -1}}).validate(); err == nil {
+		t.Error("Expected an invalid configured percentage to be rejected")
+	}
+}
+
+func TestStrictAudienceFlag(t *testing.T) {
+	issuer := jwtutils.NewJWTManager(jwtutils.JWTConfig{
+		AccessSecret:   []byte("access-secret"),
+		RefreshSecret:  []byte("refresh-secret"),
+		AccessTokenTTL: time.Minute,
+		SigningMethod:  HS256,
+		Audience:       []string{"api", "admin"},
+	})
+	flags := newFeatureFlags(FeatureFlagsConfig{})
+	manager := &strictAudienceTokenManager{TokenManager: issuer, flags: flags, audiences: []string{"api"}}
+
+	token, err := issuer.GenerateAccessToken("user-1", nil)
+	if err != nil {
+		t.Fatalf("GenerateAccessToken failed: %v", err)
+	}
+	if _, err := manager.ValidateAccessToken(token); err != nil {
+		t.Errorf("Expected the token to pass with the flag off, got %v", err)
+	}
+	flags.rollout[FlagStrictAudience] = 100
+	if _, err := manager.ValidateAccessToken(token); err == nil {
+		t.Error("Expected the extra audience to be rejected with the flag on")
+	}
+}
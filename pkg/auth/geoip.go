@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// GeoLocation is where an IP address is located.
+type GeoLocation struct {
+	Country string `json:"country"` // ISO 3166-1 alpha-2 code
+	City    string `json:"city,omitempty"`
+	ASN     uint   `json:"asn,omitempty"` // autonomous system number, 0 if unknown
+}
+
+// GeoIPResolver locates IP addresses, e.g. backed by a MaxMind database.
+type GeoIPResolver interface {
+	Lookup(ip string) (*GeoLocation, error)
+}
+
+// GeoIPConfig enriches login events with the location of the client and
+// reports logins from a country or network not seen in the user's recent
+// logins as "anomalous_login" events. Client addresses are taken from the
+// context, see WithClientInfo.
+type GeoIPConfig struct {
+	// Resolver locates client IPs. Geo-IP enrichment is off when nil.
+	Resolver GeoIPResolver
+	// HistorySize is the number of recent login locations kept per user.
+	// Defaults to 5.
+	HistorySize int
+	// MaxUsers bounds the number of users whose history is kept in memory.
+	// Defaults to 100,000.
+	MaxUsers int
+}
+
+// clientInfoKey is the context key of the client address and user agent.
+type clientInfoKey struct{}
+
+// clientInfo identifies the client of a request.
+type clientInfo struct {
+	ip        string
+	userAgent string
+}
+
+// WithClientInfo returns a context carrying the client's IP address and user
+// agent. Login and Register include them in their events.
+func WithClientInfo(ctx context.Context, ip, userAgent string) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, clientInfo{ip: ip, userAgent: userAgent})
+}
+
+// WithClientRequest is like WithClientInfo, taking both from r.
+func WithClientRequest(ctx context.Context, r *http.Request) context.Context {
+	return WithClientInfo(ctx, clientIP(r), r.UserAgent())
+}
+
+// clientInfoFrom returns the client info stored in ctx.
+func clientInfoFrom(ctx context.Context) clientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(clientInfo)
+	return info
+}
+
+// loginGeo resolves login locations and remembers recent ones per user.
+// A nil *loginGeo does nothing.
+type loginGeo struct {
+	config GeoIPConfig
+
+	mu      sync.Mutex
+	history map[string][]GeoLocation // user ID -> recent locations, oldest first
+}
+
+// newLoginGeo returns nil unless a resolver is configured.
+func newLoginGeo(config GeoIPConfig) *loginGeo {
+	if config.Resolver == nil {
+		return nil
+	}
+	if config.HistorySize <= 0 {
+		config.HistorySize = 5
+	}
+	if config.MaxUsers <= 0 {
+		config.MaxUsers = 100000
+	}
+	return &loginGeo{config: config, history: make(map[string][]GeoLocation)}
+}
+
+// lookup resolves ip, returning nil if it cannot be located.
+func (g *loginGeo) lookup(ip string) *GeoLocation {
+	if g == nil || ip == "" {
+		return nil
+	}
+	location, err := g.config.Resolver.Lookup(ip)
+	if err != nil {
+		return nil
+	}
+	return location
+}
+
+// observe records a successful login from location and returns the recent
+// locations if the country or network is new to the user. The first login
+// of a user is never anomalous.
+func (g *loginGeo) observe(userID string, location GeoLocation) []GeoLocation {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	recent := g.history[userID]
+	anomalous := len(recent) > 0
+	for _, seen := range recent {
+		if seen.Country == location.Country && (location.ASN == 0 || seen.ASN == location.ASN) {
+			anomalous = false
+			break
+		}
+	}
+
+	if _, tracked := g.history[userID]; !tracked && len(g.history) >= g.config.MaxUsers {
+		// Forget an arbitrary user to stay within bounds
+		for id := range g.history {
+			delete(g.history, id)
+			break
+		}
+	}
+	updated := append(append(make([]GeoLocation, 0, len(recent)+1), recent...), location)
+	if len(updated) > g.config.HistorySize {
+		updated = updated[len(updated)-g.config.HistorySize:]
+	}
+	g.history[userID] = updated
+
+	if anomalous {
+		return recent
+	}
+	return nil
+}
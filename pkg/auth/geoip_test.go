@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mapGeoIPResolver map[string]*GeoLocation
+
+func (m mapGeoIPResolver) Lookup(ip string) (*GeoLocation, error) {
+	if location, ok := m[ip]; ok {
+		return location, nil
+	}
+	return nil, errors.New("unknown address")
+}
+
+// loggedEvents parses the log lines in buf.
+func loggedEvents(t *testing.T, buf *bytes.Buffer) []LogEntry {
+	t.Helper()
+	var events []LogEntry
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry LogEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("Failed to parse log entry: %v", err)
+		}
+		events = append(events, entry)
+	}
+	return events
+}
+
+func TestLoginGeoIPEnrichment(t *testing.T) {
+	resolver := mapGeoIPResolver{
+		"203.0.113.1":  {Country: "DE", City: "Berlin", ASN: 3320},
+		"203.0.113.2":  {Country: "DE", City: "Munich", ASN: 3320},
+		"198.51.100.1": {Country: "BR", City: "São Paulo", ASN: 28573},
+	}
+	var buf bytes.Buffer
+	eventLogger := NewAuthEventLogger(NewLogger(LogLevelInfo, &buf))
+	eventLogger.geo = newLoginGeo(GeoIPConfig{Resolver: resolver})
+
+	eventLogger.LogLogin("user1", "alice", "203.0.113.1", "test", true, time.Millisecond, nil)
+	events := loggedEvents(t, &buf)
+	if len(events) != 1 || events[0].Event != "user_login" {
+		t.Fatalf("Expected a single user_login event, got %v", events)
+	}
+	if events[0].Fields["country"] != "DE" || events[0].Fields["city"] != "Berlin" {
+		t.Errorf("Expected login to be enriched with the location, got %v", events[0])
+	}
+
+	buf.Reset()
+	eventLogger.LogLogin("user1", "alice", "203.0.113.2", "test", true, time.Millisecond, nil)
+	if events := loggedEvents(t, &buf); len(events) != 1 {
+		t.Errorf("Expected no anomaly for a known country and network, got %v", events)
+	}
+
+	buf.Reset()
+	eventLogger.LogLogin("user1", "alice", "198.51.100.1", "test", true, time.Millisecond, nil)
+	events = loggedEvents(t, &buf)
+	if len(events) != 2 || events[1].Event != "anomalous_login" {
+		t.Fatalf("Expected an anomalous_login event, got %v", events)
+	}
+	if events[1].Fields["country"] != "BR" {
+		t.Errorf("Expected the anomaly to carry the new country, got %v", events[1].Fields["country"])
+	}
+
+	// Failed logins and unresolvable addresses do not count
+	buf.Reset()
+	eventLogger.LogLogin("user2", "bob", "203.0.113.1", "test", false, time.Millisecond, errors.New("invalid credentials"))
+	eventLogger.LogLogin("user2", "bob", "192.0.2.1", "test", true, time.Millisecond, nil)
+	eventLogger.LogLogin("user2", "bob", "198.51.100.1", "test", true, time.Millisecond, nil)
+	for _, event := range loggedEvents(t, &buf) {
+		if event.Event == "anomalous_login" {
+			t.Errorf("Expected no anomaly on the first located login, got %v", event)
+		}
+	}
+}
+
+func TestLoginGeoHistoryBounds(t *testing.T) {
+	geo := newLoginGeo(GeoIPConfig{Resolver: mapGeoIPResolver{}, HistorySize: 2, MaxUsers: 1})
+
+	geo.observe("user1", GeoLocation{Country: "DE"})
+	geo.observe("user1", GeoLocation{Country: "FR"})
+	geo.observe("user1", GeoLocation{Country: "IT"})
+	if recent := geo.observe("user1", GeoLocation{Country: "DE"}); recent == nil {
+		t.Error("Expected locations beyond HistorySize to be forgotten")
+	}
+
+	geo.observe("user2", GeoLocation{Country: "DE"})
+	if len(geo.history) != 1 {
+		t.Errorf("Expected at most 1 tracked user, got %d", len(geo.history))
+	}
+}
+
+func TestLoginLogsClientInfo(t *testing.T) {
+	ta := NewTestAuth(t)
+	var buf bytes.Buffer
+	ta.eventLogger = NewAuthEventLogger(NewLogger(LogLevelInfo, &buf))
+	ta.eventLogger.geo = newLoginGeo(GeoIPConfig{Resolver: mapGeoIPResolver{
+		"203.0.113.1": {Country: "DE", City: "Berlin", ASN: 3320},
+	}})
+	ta.SeedUser("alice", "password123")
+
+	ctx := WithClientInfo(context.Background(), "203.0.113.1", "test-agent")
+	if _, err := ta.LoginContext(ctx, "alice", "password123", nil); err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	var login *LogEntry
+	for _, event := range loggedEvents(t, &buf) {
+		if event.Event == "user_login" {
+			login = &event
+		}
+	}
+	if login == nil {
+		t.Fatal("Expected a user_login event")
+	}
+	if login.IP != "203.0.113.1" || login.UserAgent != "test-agent" || login.Fields["country"] != "DE" {
+		t.Errorf("Expected client info and location in the login event, got %v", login)
+	}
+}
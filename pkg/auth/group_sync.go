@@ -0,0 +1,440 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// directoryMetadataKey is the user metadata key holding synced directory data.
+const directoryMetadataKey = "directory"
+
+// GroupMembership lists the members of a directory group. Members are
+// identified by email or username, see GroupSyncConfig.MatchBy.
+type GroupMembership struct {
+	Group   string
+	Members []string
+}
+
+// GroupSource pulls group memberships from an external directory.
+type GroupSource interface {
+	Name() string
+	Memberships(ctx context.Context) ([]GroupMembership, error)
+}
+
+// groupSourceFunc adapts a function to GroupSource.
+type groupSourceFunc struct {
+	name string
+	fn   func(ctx context.Context) ([]GroupMembership, error)
+}
+
+// GroupSourceFunc adapts fn to a GroupSource, e.g. to wrap an LDAP search or
+// the Google Workspace Directory API.
+func GroupSourceFunc(name string, fn func(ctx context.Context) ([]GroupMembership, error)) GroupSource {
+	return &groupSourceFunc{name: name, fn: fn}
+}
+
+func (s *groupSourceFunc) Name() string { return s.name }
+
+func (s *groupSourceFunc) Memberships(ctx context.Context) ([]GroupMembership, error) {
+	return s.fn(ctx)
+}
+
+// SCIMGroupSource reads groups from a SCIM 2.0 service provider (RFC 7644).
+type SCIMGroupSource struct {
+	// BaseURL is the SCIM endpoint, e.g. https://example.com/scim/v2.
+	BaseURL string
+	// BearerToken authenticates requests when set.
+	BearerToken string
+	// HTTPClient defaults to a client with a 30 second timeout.
+	HTTPClient *http.Client
+	// PageSize is the number of groups per request. Defaults to 100.
+	PageSize int
+}
+
+// NewSCIMGroupSource creates a SCIM group source. Members are identified by
+// their SCIM display value, which providers usually set to the userName or email.
+func NewSCIMGroupSource(baseURL, bearerToken string) *SCIMGroupSource {
+	return &SCIMGroupSource{BaseURL: baseURL, BearerToken: bearerToken}
+}
+
+func (s *SCIMGroupSource) Name() string { return "scim" }
+
+type scimGroupList struct {
+	TotalResults int `json:"totalResults"`
+	Resources    []struct {
+		DisplayName string `json:"displayName"`
+		Members     []struct {
+			Value   string `json:"value"`
+			Display string `json:"display"`
+		} `json:"members"`
+	} `json:"Resources"`
+}
+
+func (s *SCIMGroupSource) Memberships(ctx context.Context) ([]GroupMembership, error) {
+	client := s.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	pageSize := s.PageSize
+	if pageSize <= 0 {
+		pageSize = 100
+	}
+
+	var memberships []GroupMembership
+	for startIndex := 1; ; startIndex += pageSize {
+		query := url.Values{
+			"startIndex": {strconv.Itoa(startIndex)},
+			"count":      {strconv.Itoa(pageSize)},
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(s.BaseURL, "/")+"/Groups?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", "application/scim+json")
+		if s.BearerToken != "" {
+			req.Header.Set("Authorization", "Bearer "+s.BearerToken)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		var page scimGroupList
+		if resp.StatusCode != http.StatusOK {
+			err = fmt.Errorf("scim: unexpected status %s", resp.Status)
+		} else {
+			err = json.NewDecoder(resp.Body).Decode(&page)
+		}
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, group := range page.Resources {
+			membership := GroupMembership{Group: group.DisplayName}
+			for _, member := range group.Members {
+				if member.Display != "" {
+					membership.Members = append(membership.Members, member.Display)
+				} else {
+					membership.Members = append(membership.Members, member.Value)
+				}
+			}
+			memberships = append(memberships, membership)
+		}
+
+		if len(page.Resources) == 0 || startIndex+len(page.Resources) > page.TotalResults {
+			return memberships, nil
+		}
+	}
+}
+
+// GroupMappingRule maps directory groups to roles and claims.
+type GroupMappingRule struct {
+	// Group is a group name or a path.Match pattern such as "eng-*".
+	Group string
+	// Roles are added to the "roles" claim of matching users.
+	Roles []string
+	// Claims are added to the access token of matching users. Later rules
+	// override earlier ones.
+	Claims map[string]interface{}
+}
+
+// GroupSyncConfig configures a GroupSyncer.
+type GroupSyncConfig struct {
+	Sources []GroupSource
+	Rules   []GroupMappingRule
+	// MatchBy selects the user field directory members are matched on:
+	// "email" (default) or "username".
+	MatchBy string
+	// IncludeGroups adds the raw group names as a "groups" claim.
+	IncludeGroups bool
+	// Interval between syncs started with Start. Defaults to 15 minutes.
+	Interval time.Duration
+}
+
+// GroupSyncResult summarizes a sync run.
+type GroupSyncResult struct {
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+	Groups    int           `json:"groups"`
+	Users     int           `json:"users"`
+	Updated   int           `json:"updated"`
+	Errors    []string      `json:"errors,omitempty"`
+}
+
+// GroupSyncer periodically pulls group memberships from external directories,
+// maps them to roles and claims and stores the result per user. The stored
+// claims are added to access tokens at login and refresh.
+type GroupSyncer struct {
+	auth   *Auth
+	config GroupSyncConfig
+
+	mu   sync.Mutex
+	last *GroupSyncResult
+	stop context.CancelFunc
+}
+
+// NewGroupSyncer creates a GroupSyncer. Call Sync for a single run or Start
+// to sync periodically.
+func (a *Auth) NewGroupSyncer(config GroupSyncConfig) (*GroupSyncer, error) {
+	if len(config.Sources) == 0 {
+		return nil, NewAuthError(ErrCodeInvalidConfig, "At least one group source is required")
+	}
+	switch config.MatchBy {
+	case "":
+		config.MatchBy = "email"
+	case "email", "username":
+	default:
+		return nil, NewAuthError(ErrCodeInvalidConfig, "MatchBy must be \"email\" or \"username\"")
+	}
+	for _, rule := range config.Rules {
+		if _, err := path.Match(rule.Group, ""); err != nil {
+			return nil, NewAuthErrorWithDetails(ErrCodeInvalidConfig, "Invalid group pattern", rule.Group)
+		}
+	}
+	if config.Interval <= 0 {
+		config.Interval = 15 * time.Minute
+	}
+	return &GroupSyncer{auth: a, config: config}, nil
+}
+
+// Sync pulls memberships from all sources and updates the directory data of
+// every user. If a source fails, no user is updated so that a directory
+// outage does not strip everyone's roles.
+func (s *GroupSyncer) Sync(ctx context.Context) (*GroupSyncResult, error) {
+	start := time.Now()
+	result := &GroupSyncResult{StartedAt: nowFrom(s.auth.clock)}
+	defer func() {
+		result.Duration = time.Since(start)
+		s.mu.Lock()
+		s.last = result
+		s.mu.Unlock()
+	}()
+
+	groupsByMember := make(map[string][]string)
+	for _, source := range s.config.Sources {
+		memberships, err := source.Memberships(ctx)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", source.Name(), err))
+			s.auth.logger.Error("Group sync source failed", map[string]interface{}{
+				"source": source.Name(),
+				"error":  err,
+			})
+			return result, WrapError(err, ErrCodeConnectionError, "Failed to fetch group memberships")
+		}
+		result.Groups += len(memberships)
+		for _, membership := range memberships {
+			for _, member := range membership.Members {
+				key := strings.ToLower(member)
+				groupsByMember[key] = append(groupsByMember[key], membership.Group)
+			}
+		}
+	}
+
+	const pageSize = 100
+	for offset := 0; ; offset += pageSize {
+		users, err := s.auth.storage.ListUsers(pageSize, offset)
+		if err != nil {
+			return result, WrapDatabaseError(err)
+		}
+		for _, user := range users {
+			result.Users++
+			key := user.Email
+			if s.config.MatchBy == "username" {
+				key = user.Username
+			}
+			updated, err := s.apply(user, groupsByMember[strings.ToLower(key)])
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", user.ID, err))
+				continue
+			}
+			if updated {
+				result.Updated++
+			}
+		}
+		if len(users) < pageSize {
+			break
+		}
+	}
+
+	s.auth.logger.Info("Group sync completed", map[string]interface{}{
+		"groups":   result.Groups,
+		"users":    result.Users,
+		"updated":  result.Updated,
+		"errors":   len(result.Errors),
+		"duration": time.Since(start),
+	})
+	return result, nil
+}
+
+// apply stores the mapped groups of user if they changed.
+func (s *GroupSyncer) apply(user *models.User, groups []string) (bool, error) {
+	groups = uniqueSorted(groups)
+	claims := s.mapGroups(groups)
+
+	current, _ := user.Metadata[directoryMetadataKey].(map[string]interface{})
+	if sameJSON(current["groups"], groups) && sameJSON(current["claims"], claims) {
+		return false, nil
+	}
+	if len(groups) == 0 && current == nil {
+		return false, nil
+	}
+
+	metadata := make(map[string]interface{}, len(user.Metadata)+1)
+	for k, v := range user.Metadata {
+		metadata[k] = v
+	}
+	metadata[directoryMetadataKey] = map[string]interface{}{
+		"groups":    groups,
+		"claims":    claims,
+		"synced_at": nowFrom(s.auth.clock),
+	}
+	if err := s.auth.storage.UpdateUser(user.ID, storage.UserUpdates{Metadata: metadata}); err != nil {
+		return false, err
+	}
+
+	if s.auth.permissions != nil {
+		s.auth.permissions.Invalidate(user.ID, "")
+	}
+	return true, nil
+}
+
+// mapGroups applies the mapping rules to groups.
+func (s *GroupSyncer) mapGroups(groups []string) map[string]interface{} {
+	claims := make(map[string]interface{})
+	var roles []string
+	for _, rule := range s.config.Rules {
+		for _, group := range groups {
+			if matched, _ := path.Match(rule.Group, group); !matched {
+				continue
+			}
+			roles = append(roles, rule.Roles...)
+			for k, v := range rule.Claims {
+				claims[k] = v
+			}
+			break
+		}
+	}
+	if roles = uniqueSorted(roles); len(roles) > 0 {
+		claims["roles"] = roles
+	}
+	if s.config.IncludeGroups && len(groups) > 0 {
+		claims["groups"] = groups
+	}
+	return claims
+}
+
+// Start syncs immediately and then every Interval until ctx is done or Stop
+// is called. Failed runs are logged and retried at the next interval.
+func (s *GroupSyncer) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	s.mu.Lock()
+	if s.stop != nil {
+		s.stop()
+	}
+	s.stop = cancel
+	s.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(s.config.Interval)
+		defer ticker.Stop()
+		for {
+			s.Sync(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops periodic syncing started with Start.
+func (s *GroupSyncer) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stop != nil {
+		s.stop()
+		s.stop = nil
+	}
+}
+
+// LastResult returns the result of the most recent sync, or nil.
+func (s *GroupSyncer) LastResult() *GroupSyncResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.last
+}
+
+// addDirectoryClaims adds the synced directory claims of user to claims
+// without overriding existing entries.
+func addDirectoryClaims(user *models.User, claims map[string]interface{}) {
+	directory, _ := user.Metadata[directoryMetadataKey].(map[string]interface{})
+	synced, _ := directory["claims"].(map[string]interface{})
+	for k, v := range synced {
+		if _, exists := claims[k]; !exists {
+			claims[k] = v
+		}
+	}
+}
+
+// directoryRoles returns the synced directory roles of user.
+func directoryRoles(user *models.User) []string {
+	directory, _ := user.Metadata[directoryMetadataKey].(map[string]interface{})
+	claims, _ := directory["claims"].(map[string]interface{})
+	return stringList(claims["roles"])
+}
+
+// stringList converts a []string or a JSON-decoded []interface{} to []string.
+func stringList(v interface{}) []string {
+	switch values := v.(type) {
+	case []string:
+		return values
+	case []interface{}:
+		list := make([]string, 0, len(values))
+		for _, value := range values {
+			if s, ok := value.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+// uniqueSorted returns the sorted distinct values.
+func uniqueSorted(values []string) []string {
+	if len(values) == 0 {
+		return []string{}
+	}
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	unique := sorted[:1]
+	for _, v := range sorted[1:] {
+		if v != unique[len(unique)-1] {
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
+
+// sameJSON reports whether a and b have the same JSON encoding, so values
+// read back from storage compare equal to freshly built ones.
+func sameJSON(a, b interface{}) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && bytes.Equal(encodedA, encodedB)
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGroupSyncClaims(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("alice", "alice-password")
+	ta.SeedUser("bob", "bob-password")
+
+	memberships := []GroupMembership{
+		{Group: "eng-platform", Members: []string{"ALICE@example.test"}},
+		{Group: "admins", Members: []string{"alice@example.test"}},
+	}
+	var sourceErr error
+	source := GroupSourceFunc("test", func(ctx context.Context) ([]GroupMembership, error) {
+		return memberships, sourceErr
+	})
+
+	syncer, err := ta.NewGroupSyncer(GroupSyncConfig{
+		Sources: []GroupSource{source},
+		Rules: []GroupMappingRule{
+			{Group: "eng-*", Roles: []string{"engineer"}, Claims: map[string]interface{}{"department": "engineering"}},
+			{Group: "admins", Roles: []string{"admin"}},
+		},
+		IncludeGroups: true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create syncer: %v", err)
+	}
+
+	result, err := syncer.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync failed: %v", err)
+	}
+	if result.Users != 2 || result.Updated != 1 {
+		t.Errorf("Unexpected sync result: %+v", result)
+	}
+
+	login, err := ta.Login("alice", "alice-password", nil)
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	claims, _ := ta.ValidateAccessToken(login.AccessToken)
+	roles, _ := json.Marshal(claims["roles"])
+	if string(roles) != `["admin","engineer"]` || claims["department"] != "engineering" {
+		t.Errorf("Expected directory claims in access token, got %v", claims)
+	}
+	if groups, _ := json.Marshal(claims["groups"]); string(groups) != `["admins","eng-platform"]` {
+		t.Errorf("Expected groups claim, got %s", groups)
+	}
+
+	// Unchanged memberships do not rewrite users
+	if result, _ := syncer.Sync(context.Background()); result.Updated != 0 {
+		t.Errorf("Expected no updates on unchanged sync, got %d", result.Updated)
+	}
+
+	// A failing source must not strip roles
+	sourceErr = errors.New("directory unavailable")
+	if _, err := syncer.Sync(context.Background()); err == nil {
+		t.Error("Expected sync to fail with failing source")
+	}
+	alice, _ := ta.Users().GetByUsername("alice")
+	if ok, _ := ta.Permissions().HasPermission(alice.ID, "", "admin"); !ok {
+		t.Error("Expected directory roles to survive a failed sync")
+	}
+}
+
+func TestGroupSyncerConfig(t *testing.T) {
+	ta := NewTestAuth(t)
+	if _, err := ta.NewGroupSyncer(GroupSyncConfig{}); err == nil {
+		t.Error("Expected error without sources")
+	}
+	source := GroupSourceFunc("test", func(ctx context.Context) ([]GroupMembership, error) { return nil, nil })
+	if _, err := ta.NewGroupSyncer(GroupSyncConfig{Sources: []GroupSource{source}, MatchBy: "phone"}); err == nil {
+		t.Error("Expected error for unsupported MatchBy")
+	}
+	if _, err := ta.NewGroupSyncer(GroupSyncConfig{Sources: []GroupSource{source}, Rules: []GroupMappingRule{{Group: "["}}}); err == nil {
+		t.Error("Expected error for invalid group pattern")
+	}
+}
+
+func TestSCIMGroupSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/scim/v2/Groups" || r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		resources := []map[string]interface{}{
+			{"displayName": "admins", "members": []map[string]string{{"value": "1", "display": "alice@example.test"}}},
+			{"displayName": "eng", "members": []map[string]string{{"value": "bob@example.test"}}},
+		}
+		start := r.URL.Query().Get("startIndex")
+		page := resources[:1]
+		if start == "2" {
+			page = resources[1:]
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"totalResults": 2, "Resources": page})
+	}))
+	defer server.Close()
+
+	source := NewSCIMGroupSource(server.URL+"/scim/v2/", "secret")
+	source.PageSize = 1
+	memberships, err := source.Memberships(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to read SCIM groups: %v", err)
+	}
+	if len(memberships) != 2 || memberships[0].Members[0] != "alice@example.test" || memberships[1].Members[0] != "bob@example.test" {
+		t.Errorf("Unexpected memberships: %+v", memberships)
+	}
+}
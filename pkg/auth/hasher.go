@@ -0,0 +1,225 @@
+package auth
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Hasher hashes and verifies passwords. Implementations must produce
+// self-describing encoded hashes so that parameters can be recovered later.
+type Hasher interface {
+	// Hash returns the encoded hash of password.
+	Hash(password string) (string, error)
+	// Compare reports whether password matches encodedHash.
+	Compare(password, encodedHash string) (bool, error)
+	// NeedsRehash reports whether encodedHash was produced by a different
+	// algorithm or with weaker parameters than this hasher is configured for.
+	NeedsRehash(encodedHash string) bool
+}
+
+// Argon2Hasher hashes passwords with Argon2id. It is the default Hasher.
+type Argon2Hasher struct {
+	Params *Argon2Params
+}
+
+// NewArgon2Hasher creates an Argon2id hasher. A nil p uses DefaultParams.
+func NewArgon2Hasher(p *Argon2Params) *Argon2Hasher {
+	if p == nil {
+		p = DefaultParams
+	}
+	return &Argon2Hasher{Params: p}
+}
+
+// Hash generates an Argon2id hash of password.
+func (h *Argon2Hasher) Hash(password string) (string, error) {
+	return HashPasswordWithParams(password, h.Params)
+}
+
+// Compare checks password against an Argon2id hash.
+func (h *Argon2Hasher) Compare(password, encodedHash string) (bool, error) {
+	return CheckPasswordHash(password, encodedHash)
+}
+
+// NeedsRehash reports whether encodedHash is not Argon2id or uses different parameters.
+func (h *Argon2Hasher) NeedsRehash(encodedHash string) bool {
+	p, _, _, err := decodeHash(encodedHash)
+	if err != nil {
+		return true
+	}
+	want := h.Params
+	if want == nil {
+		want = DefaultParams
+	}
+	return p.Memory != want.Memory ||
+		p.Iterations != want.Iterations ||
+		p.Parallelism != want.Parallelism ||
+		p.KeyLength != want.KeyLength
+}
+
+// BcryptHasher hashes passwords with bcrypt.
+type BcryptHasher struct {
+	Cost int
+}
+
+// NewBcryptHasher creates a bcrypt hasher. A cost of 0 uses bcrypt.DefaultCost.
+func NewBcryptHasher(cost int) *BcryptHasher {
+	if cost == 0 {
+		cost = bcrypt.DefaultCost
+	}
+	return &BcryptHasher{Cost: cost}
+}
+
+// Hash generates a bcrypt hash of password.
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate bcrypt hash: %w", err)
+	}
+	return string(hash), nil
+}
+
+// Compare checks password against a bcrypt hash.
+func (h *BcryptHasher) Compare(password, encodedHash string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+	if err == nil {
+		return true, nil
+	}
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to compare bcrypt hash: %w", err)
+}
+
+// NeedsRehash reports whether encodedHash is not bcrypt or uses a lower cost.
+func (h *BcryptHasher) NeedsRehash(encodedHash string) bool {
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+	if err != nil {
+		return true
+	}
+	return cost < h.Cost
+}
+
+// pbkdf2Prefix identifies hashes produced by PBKDF2Hasher.
+const pbkdf2Prefix = "$pbkdf2-sha256$"
+
+// PBKDF2Hasher hashes passwords with PBKDF2-HMAC-SHA256 using the standard
+// library implementation, which is covered by the Go FIPS 140-3 module.
+type PBKDF2Hasher struct {
+	Iterations int
+	SaltLength int
+	KeyLength  int
+}
+
+// NewPBKDF2Hasher creates a PBKDF2-SHA256 hasher. An iteration count of 0
+// uses 600,000, the current OWASP recommendation.
+func NewPBKDF2Hasher(iterations int) *PBKDF2Hasher {
+	if iterations == 0 {
+		iterations = 600000
+	}
+	return &PBKDF2Hasher{
+		Iterations: iterations,
+		SaltLength: 16,
+		KeyLength:  32,
+	}
+}
+
+// Hash generates a PBKDF2 hash of password.
+// Format: $pbkdf2-sha256$i=<iterations>$<salt>$<hash>
+func (h *PBKDF2Hasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key, err := pbkdf2.Key(sha256.New, password, salt, h.Iterations, h.KeyLength)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive pbkdf2 key: %w", err)
+	}
+
+	return fmt.Sprintf("%si=%d$%s$%s", pbkdf2Prefix, h.Iterations,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Compare checks password against a PBKDF2 hash in constant time.
+func (h *PBKDF2Hasher) Compare(password, encodedHash string) (bool, error) {
+	iterations, salt, key, err := decodePBKDF2Hash(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	otherKey, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(key))
+	if err != nil {
+		return false, fmt.Errorf("failed to derive pbkdf2 key: %w", err)
+	}
+
+	return subtle.ConstantTimeCompare(key, otherKey) == 1, nil
+}
+
+// NeedsRehash reports whether encodedHash is not PBKDF2 or uses fewer iterations.
+func (h *PBKDF2Hasher) NeedsRehash(encodedHash string) bool {
+	iterations, _, key, err := decodePBKDF2Hash(encodedHash)
+	if err != nil {
+		return true
+	}
+	return iterations < h.Iterations || len(key) != h.KeyLength
+}
+
+// decodePBKDF2Hash parses a hash produced by PBKDF2Hasher.
+func decodePBKDF2Hash(encodedHash string) (iterations int, salt, key []byte, err error) {
+	if !strings.HasPrefix(encodedHash, pbkdf2Prefix) {
+		return 0, nil, nil, errors.New("unsupported hashing algorithm")
+	}
+
+	vals := strings.Split(strings.TrimPrefix(encodedHash, pbkdf2Prefix), "$")
+	if len(vals) != 3 {
+		return 0, nil, nil, errors.New("invalid encoded hash format")
+	}
+
+	if _, err := fmt.Sscanf(vals[0], "i=%d", &iterations); err != nil || iterations <= 0 {
+		return 0, nil, nil, fmt.Errorf("failed to parse pbkdf2 iterations: %w", err)
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(vals[1])
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("failed to decode salt: %w", err)
+	}
+
+	key, err = base64.RawStdEncoding.DecodeString(vals[2])
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("failed to decode hash: %w", err)
+	}
+
+	return iterations, salt, key, nil
+}
+
+// verifyPassword checks password against encodedHash. Hashes produced by any
+// built-in hasher are verified with the matching algorithm so that users can be
+// upgraded transparently after the configured hasher changes; other formats
+// are passed to h.
+func verifyPassword(h Hasher, password, encodedHash string) (bool, error) {
+	switch {
+	case strings.HasPrefix(encodedHash, "$argon2id$"):
+		return CheckPasswordHash(password, encodedHash)
+	case strings.HasPrefix(encodedHash, "$2a$"), strings.HasPrefix(encodedHash, "$2b$"), strings.HasPrefix(encodedHash, "$2y$"):
+		return (&BcryptHasher{}).Compare(password, encodedHash)
+	case strings.HasPrefix(encodedHash, pbkdf2Prefix):
+		return (&PBKDF2Hasher{}).Compare(password, encodedHash)
+	case strings.HasPrefix(encodedHash, md5CryptPrefix):
+		return compareMD5Crypt(password, encodedHash)
+	case strings.HasPrefix(encodedHash, sha1Prefix):
+		return compareSHA1(password, encodedHash)
+	}
+	if h == nil {
+		return false, errors.New("unsupported hashing algorithm")
+	}
+	return h.Compare(password, encodedHash)
+}
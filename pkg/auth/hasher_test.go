@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashers(t *testing.T) {
+	hashers := map[string]Hasher{
+		"argon2id": NewArgon2Hasher(TestHashParams),
+		"bcrypt":   NewBcryptHasher(4),
+		"pbkdf2":   NewPBKDF2Hasher(1000),
+	}
+
+	for name, h := range hashers {
+		t.Run(name, func(t *testing.T) {
+			hash, err := h.Hash("correct-password")
+			if err != nil {
+				t.Fatalf("Failed to hash password: %v", err)
+			}
+
+			match, err := h.Compare("correct-password", hash)
+			if err != nil || !match {
+				t.Errorf("Expected password to match, got match=%v err=%v", match, err)
+			}
+
+			match, err = h.Compare("wrong-password", hash)
+			if err != nil || match {
+				t.Errorf("Expected wrong password not to match, got match=%v err=%v", match, err)
+			}
+
+			if h.NeedsRehash(hash) {
+				t.Error("Fresh hash should not need rehashing")
+			}
+
+			match, err = verifyPassword(nil, "correct-password", hash)
+			if err != nil || !match {
+				t.Errorf("Expected verifyPassword to detect %s format, got match=%v err=%v", name, match, err)
+			}
+		})
+	}
+}
+
+func TestHasherNeedsRehash(t *testing.T) {
+	bcryptHash, _ := NewBcryptHasher(4).Hash("password123")
+	pbkdf2Hash, _ := NewPBKDF2Hasher(1000).Hash("password123")
+	argonHash, _ := NewArgon2Hasher(TestHashParams).Hash("password123")
+
+	if !NewArgon2Hasher(TestHashParams).NeedsRehash(bcryptHash) {
+		t.Error("Argon2 hasher should rehash bcrypt hashes")
+	}
+	if !NewArgon2Hasher(nil).NeedsRehash(argonHash) {
+		t.Error("Argon2 hasher should rehash hashes with different parameters")
+	}
+	if !NewBcryptHasher(5).NeedsRehash(bcryptHash) {
+		t.Error("Bcrypt hasher should rehash hashes with a lower cost")
+	}
+	if !NewPBKDF2Hasher(2000).NeedsRehash(pbkdf2Hash) {
+		t.Error("PBKDF2 hasher should rehash hashes with fewer iterations")
+	}
+	if !NewPBKDF2Hasher(1000).NeedsRehash(argonHash) {
+		t.Error("PBKDF2 hasher should rehash Argon2 hashes")
+	}
+}
+
+func TestLoginUpgradesPasswordHash(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("dave", "dave-password")
+
+	legacyHash, err := NewBcryptHasher(4).Hash("dave-password")
+	if err != nil {
+		t.Fatalf("Failed to create legacy hash: %v", err)
+	}
+	if err := ta.storage.UpdatePassword(user.ID, legacyHash); err != nil {
+		t.Fatalf("Failed to store legacy hash: %v", err)
+	}
+
+	ta.LoginAs("dave")
+
+	stored, err := ta.storage.GetUserByID(user.ID)
+	if err != nil {
+		t.Fatalf("Failed to reload user: %v", err)
+	}
+	if !strings.HasPrefix(stored.PasswordHash, "$argon2id$") {
+		t.Errorf("Expected password hash to be upgraded to Argon2id, got %q", stored.PasswordHash)
+	}
+
+	if _, err := ta.Login("dave", "dave-password", nil); err != nil {
+		t.Errorf("Expected login with upgraded hash to succeed, got %v", err)
+	}
+}
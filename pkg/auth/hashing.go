@@ -29,10 +29,29 @@ var DefaultParams = &Argon2Params{
 	KeyLength:   32,
 }
 
+// TestHashParams are deliberately weak Argon2id parameters used by the
+// authtest package. They make hashing orders of magnitude faster and must
+// never be used in production.
+var TestHashParams = &Argon2Params{
+	Memory:      64, // 64 KB
+	Iterations:  1,
+	Parallelism: 1,
+	SaltLength:  8,
+	KeyLength:   16,
+}
+
 // HashPassword generates a secure Argon2id hash of a password.
 // The output format is a modular crypt format string containing all parameters.
 func HashPassword(password string) (string, error) {
-	p := DefaultParams
+	return HashPasswordWithParams(password, DefaultParams)
+}
+
+// HashPasswordWithParams generates an Argon2id hash using the given parameters.
+// A nil p uses DefaultParams. Weaker parameters should only be used in tests.
+func HashPasswordWithParams(password string, p *Argon2Params) (string, error) {
+	if p == nil {
+		p = DefaultParams
+	}
 
 	// 1. Generate a cryptographically secure random salt.
 	salt := make([]byte, p.SaltLength)
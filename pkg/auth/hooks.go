@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+// LoginHook runs during login. Before-login hooks run after the password has
+// been verified and before tokens are issued; they may veto the login by
+// returning an error or enrich the access token by adding entries to claims.
+// After-login hooks receive the final claims and cannot veto the login.
+type LoginHook func(ctx context.Context, user *models.User, claims map[string]interface{}) error
+
+// RegisterHook runs during registration. Before-register hooks receive the user
+// about to be stored and may veto with an error or modify fields such as Metadata.
+// After-register hooks receive the stored user and cannot veto the registration.
+type RegisterHook func(ctx context.Context, user *models.User) error
+
+// PasswordChangeHook runs when a user's password is changed or reset.
+// Before hooks may veto the change with an error; after hooks cannot.
+type PasswordChangeHook func(ctx context.Context, user *models.User) error
+
+// Hooks holds the lifecycle hooks registered on an Auth instance.
+// Hooks run in registration order and are safe to register concurrently.
+type Hooks struct {
+	mu                   sync.RWMutex
+	beforeLogin          []LoginHook
+	afterLogin           []LoginHook
+	beforeRegister       []RegisterHook
+	afterRegister        []RegisterHook
+	beforePasswordChange []PasswordChangeHook
+	afterPasswordChange  []PasswordChangeHook
+}
+
+// NewHooks creates an empty hook registry.
+func NewHooks() *Hooks {
+	return &Hooks{}
+}
+
+// BeforeLogin registers a hook that runs before tokens are issued.
+func (h *Hooks) BeforeLogin(hook LoginHook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.beforeLogin = append(h.beforeLogin, hook)
+}
+
+// AfterLogin registers a hook that runs after a successful login.
+func (h *Hooks) AfterLogin(hook LoginHook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.afterLogin = append(h.afterLogin, hook)
+}
+
+// BeforeRegister registers a hook that runs before a new user is stored.
+func (h *Hooks) BeforeRegister(hook RegisterHook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.beforeRegister = append(h.beforeRegister, hook)
+}
+
+// AfterRegister registers a hook that runs after a new user is stored.
+func (h *Hooks) AfterRegister(hook RegisterHook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.afterRegister = append(h.afterRegister, hook)
+}
+
+// BeforePasswordChange registers a hook that runs before a password is changed or reset.
+func (h *Hooks) BeforePasswordChange(hook PasswordChangeHook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.beforePasswordChange = append(h.beforePasswordChange, hook)
+}
+
+// AfterPasswordChange registers a hook that runs after a password is changed or reset.
+func (h *Hooks) AfterPasswordChange(hook PasswordChangeHook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.afterPasswordChange = append(h.afterPasswordChange, hook)
+}
+
+// hasPasswordChangeHooks reports whether any password change hooks are registered.
+func (h *Hooks) hasPasswordChangeHooks() bool {
+	if h == nil {
+		return false
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.beforePasswordChange) > 0 || len(h.afterPasswordChange) > 0
+}
+
+// runLogin runs the given login hooks, stopping at the first error.
+func (h *Hooks) runLogin(ctx context.Context, after bool, user *models.User, claims map[string]interface{}) error {
+	if h == nil {
+		return nil
+	}
+	h.mu.RLock()
+	hooks := h.beforeLogin
+	if after {
+		hooks = h.afterLogin
+	}
+	h.mu.RUnlock()
+
+	for _, hook := range hooks {
+		if err := hook(ctx, user, claims); err != nil {
+			return hookError(err)
+		}
+	}
+	return nil
+}
+
+// runRegister runs the given register hooks, stopping at the first error.
+func (h *Hooks) runRegister(ctx context.Context, after bool, user *models.User) error {
+	if h == nil {
+		return nil
+	}
+	h.mu.RLock()
+	hooks := h.beforeRegister
+	if after {
+		hooks = h.afterRegister
+	}
+	h.mu.RUnlock()
+
+	for _, hook := range hooks {
+		if err := hook(ctx, user); err != nil {
+			return hookError(err)
+		}
+	}
+	return nil
+}
+
+// runPasswordChange runs the given password change hooks, stopping at the first error.
+func (h *Hooks) runPasswordChange(ctx context.Context, after bool, user *models.User) error {
+	if h == nil {
+		return nil
+	}
+	h.mu.RLock()
+	hooks := h.beforePasswordChange
+	if after {
+		hooks = h.afterPasswordChange
+	}
+	h.mu.RUnlock()
+
+	for _, hook := range hooks {
+		if err := hook(ctx, user); err != nil {
+			return hookError(err)
+		}
+	}
+	return nil
+}
+
+// hookError passes AuthErrors returned by hooks through unchanged so callers
+// can choose the error code, and wraps anything else as PERMISSION_DENIED.
+func hookError(err error) error {
+	if authErr, ok := err.(*AuthError); ok {
+		return authErr
+	}
+	return WrapError(err, ErrCodePermissionDenied, "Operation rejected")
+}
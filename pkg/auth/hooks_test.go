@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+type ctxKey string
+
+func TestLoginHooks(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("alice", "alice-password")
+	ta.SeedUser("bob", "bob-password")
+
+	var afterCalls int
+	ta.Hooks().BeforeLogin(func(ctx context.Context, user *models.User, claims map[string]interface{}) error {
+		if user.Username == "bob" {
+			return errors.New("subscription expired")
+		}
+		claims["plan"] = ctx.Value(ctxKey("plan"))
+		return nil
+	})
+	ta.Hooks().AfterLogin(func(ctx context.Context, user *models.User, claims map[string]interface{}) error {
+		afterCalls++
+		return errors.New("after hooks cannot veto")
+	})
+
+	ctx := context.WithValue(context.Background(), ctxKey("plan"), "pro")
+	result, err := ta.LoginContext(ctx, "alice", "alice-password", nil)
+	if err != nil {
+		t.Fatalf("Expected login to succeed, got %v", err)
+	}
+	claims, err := ta.ValidateAccessToken(result.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected valid access token, got %v", err)
+	}
+	if claims["plan"] != "pro" {
+		t.Errorf("Expected hook to add plan claim, got %v", claims["plan"])
+	}
+	if afterCalls != 1 {
+		t.Errorf("Expected after-login hook to run once, ran %d times", afterCalls)
+	}
+
+	_, err = ta.Login("bob", "bob-password", nil)
+	if !errors.Is(err, NewAuthError(ErrCodePermissionDenied, "")) {
+		t.Errorf("Expected PERMISSION_DENIED from vetoing hook, got %v", err)
+	}
+	if afterCalls != 1 {
+		t.Error("After-login hook should not run when login is vetoed")
+	}
+}
+
+func TestLoginHookNotCalledForBadPassword(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("alice", "alice-password")
+
+	called := false
+	ta.Hooks().BeforeLogin(func(ctx context.Context, user *models.User, claims map[string]interface{}) error {
+		called = true
+		return nil
+	})
+
+	if _, err := ta.Login("alice", "wrong-password", nil); err == nil {
+		t.Fatal("Expected login with wrong password to fail")
+	}
+	if called {
+		t.Error("Before-login hook should only run after the password is verified")
+	}
+}
+
+func TestRegisterHooks(t *testing.T) {
+	ta := NewTestAuth(t)
+
+	var registered string
+	ta.Hooks().BeforeRegister(func(ctx context.Context, user *models.User) error {
+		if user.Username == "blocked" {
+			return NewAuthError(ErrCodeValidationError, "Username not allowed")
+		}
+		user.Metadata = map[string]interface{}{"source": "hook"}
+		return nil
+	})
+	ta.Hooks().AfterRegister(func(ctx context.Context, user *models.User) error {
+		registered = user.ID
+		return nil
+	})
+
+	user := ta.SeedUser("carol", "carol-password")
+	if user.Metadata["source"] != "hook" {
+		t.Errorf("Expected before-register hook to set metadata, got %v", user.Metadata)
+	}
+	if registered != user.ID {
+		t.Errorf("Expected after-register hook to receive user %s, got %s", user.ID, registered)
+	}
+
+	_, err := ta.Register(RegisterRequest{Username: "blocked", Password: "password123"})
+	var authErr *AuthError
+	if !errors.As(err, &authErr) || authErr.Code != ErrCodeValidationError {
+		t.Errorf("Expected hook's AuthError to be returned unchanged, got %v", err)
+	}
+	if _, err := ta.GetUserByUsername("blocked"); err == nil {
+		t.Error("Vetoed user should not be stored")
+	}
+}
+
+func TestPasswordChangeHooks(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("dave", "dave-password")
+
+	var changed []string
+	ta.Hooks().BeforePasswordChange(func(ctx context.Context, u *models.User) error {
+		if ctx.Value(ctxKey("deny")) != nil {
+			return errors.New("password changes are frozen")
+		}
+		return nil
+	})
+	ta.Hooks().AfterPasswordChange(func(ctx context.Context, u *models.User) error {
+		changed = append(changed, u.ID)
+		return nil
+	})
+
+	denyCtx := context.WithValue(context.Background(), ctxKey("deny"), true)
+	if err := ta.Users().ChangePasswordContext(denyCtx, user.ID, "dave-password", "new-password-1"); err == nil {
+		t.Fatal("Expected password change to be vetoed")
+	}
+	if len(changed) != 0 {
+		t.Error("After-password-change hook should not run when the change is vetoed")
+	}
+
+	if err := ta.Users().ChangePassword(user.ID, "dave-password", "new-password-1"); err != nil {
+		t.Fatalf("Expected password change to succeed, got %v", err)
+	}
+
+	reset, err := ta.Users().CreateResetToken(user.Email)
+	if err != nil {
+		t.Fatalf("Failed to create reset token: %v", err)
+	}
+	if err := ta.Users().ResetPassword(reset.Token, "new-password-2"); err != nil {
+		t.Fatalf("Expected password reset to succeed, got %v", err)
+	}
+
+	if len(changed) != 2 || changed[0] != user.ID || changed[1] != user.ID {
+		t.Errorf("Expected after-password-change hook to run for change and reset, got %v", changed)
+	}
+}
@@ -0,0 +1,307 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLanguage is the language used when no translation matches the request.
+const DefaultLanguage = "en"
+
+// Message keys for localizable error details. Error messages themselves are
+// keyed by their error code (e.g. ErrCodeInvalidCredentials).
+const (
+	MsgKeyInvalidField     = "details.invalid_field"
+	MsgKeyUserExists       = "details.user_exists"
+	MsgKeyPasswordTooShort = "details.password_too_short"
+	MsgKeyMalformedAuthHdr = "details.malformed_authorization_header"
+	MsgKeyMissingClaim     = "details.missing_claim"
+)
+
+// Translator translates user-facing messages into a given language.
+// Implementations can be backed by static catalogs, files, or external services.
+type Translator interface {
+	// Translate returns the localized message for key in lang, formatted with args.
+	// The boolean result reports whether a translation was found.
+	Translate(lang, key string, args ...interface{}) (string, bool)
+}
+
+// MessageCatalog is a thread-safe, map-based Translator.
+// Messages are stored per language tag and formatted with fmt.Sprintf.
+type MessageCatalog struct {
+	mu          sync.RWMutex
+	defaultLang string
+	messages    map[string]map[string]string
+}
+
+// NewMessageCatalog creates an empty catalog that falls back to defaultLang.
+func NewMessageCatalog(defaultLang string) *MessageCatalog {
+	if defaultLang == "" {
+		defaultLang = DefaultLanguage
+	}
+	return &MessageCatalog{
+		defaultLang: normalizeLanguage(defaultLang),
+		messages:    make(map[string]map[string]string),
+	}
+}
+
+// DefaultMessageCatalog returns a catalog pre-populated with the built-in English messages.
+func DefaultMessageCatalog() *MessageCatalog {
+	catalog := NewMessageCatalog(DefaultLanguage)
+	catalog.AddMessages(DefaultLanguage, englishMessages)
+	return catalog
+}
+
+// AddMessages registers (or overrides) messages for a language.
+func (c *MessageCatalog) AddMessages(lang string, messages map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	lang = normalizeLanguage(lang)
+	if c.messages[lang] == nil {
+		c.messages[lang] = make(map[string]string, len(messages))
+	}
+	for k, v := range messages {
+		c.messages[lang][k] = v
+	}
+}
+
+// Languages returns the languages that have at least one message, sorted.
+func (c *MessageCatalog) Languages() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	langs := make([]string, 0, len(c.messages))
+	for lang := range c.messages {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Translate looks up key for lang, falling back to the base language
+// (e.g. "pt" for "pt-br") and then to the catalog's default language.
+func (c *MessageCatalog) Translate(lang, key string, args ...interface{}) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, candidate := range languageFallbacks(lang, c.defaultLang) {
+		if msg, ok := c.messages[candidate][key]; ok {
+			if len(args) > 0 {
+				return fmt.Sprintf(msg, args...), true
+			}
+			return msg, true
+		}
+	}
+	return "", false
+}
+
+// Localize returns a copy of the error with its message and details translated.
+// Fields without a translation keep their original (English) text.
+func (e *AuthError) Localize(t Translator, lang string) *AuthError {
+	if e == nil || t == nil {
+		return e
+	}
+
+	localized := *e
+	if msg, ok := t.Translate(lang, e.Code); ok {
+		localized.Message = msg
+	}
+	if e.detailsKey != "" {
+		if details, ok := t.Translate(lang, e.detailsKey, e.detailsArgs...); ok {
+			localized.Details = details
+		}
+	}
+	return &localized
+}
+
+// withDetailsKey attaches a catalog key to the error's details so they can be localized.
+func (e *AuthError) withDetailsKey(key string, args ...interface{}) *AuthError {
+	e.detailsKey = key
+	e.detailsArgs = args
+	return e
+}
+
+// ParseAcceptLanguage parses an Accept-Language header into language tags
+// ordered by preference (highest quality first). Wildcards are ignored.
+func ParseAcceptLanguage(header string) []string {
+	type weightedLang struct {
+		tag     string
+		quality float64
+	}
+
+	var weighted []weightedLang
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		tag := part
+		quality := 1.0
+		if idx := strings.Index(part, ";"); idx >= 0 {
+			tag = strings.TrimSpace(part[:idx])
+			param := strings.TrimSpace(part[idx+1:])
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					quality = q
+				}
+			}
+		}
+
+		if tag == "" || tag == "*" || quality <= 0 {
+			continue
+		}
+		weighted = append(weighted, weightedLang{tag: normalizeLanguage(tag), quality: quality})
+	}
+
+	sort.SliceStable(weighted, func(i, j int) bool {
+		return weighted[i].quality > weighted[j].quality
+	})
+
+	langs := make([]string, len(weighted))
+	for i, w := range weighted {
+		langs[i] = w.tag
+	}
+	return langs
+}
+
+// LanguageFromRequest returns the most preferred language from the request's
+// Accept-Language header, or DefaultLanguage if none is provided.
+func LanguageFromRequest(r *http.Request) string {
+	if r == nil {
+		return DefaultLanguage
+	}
+	if langs := ParseAcceptLanguage(r.Header.Get("Accept-Language")); len(langs) > 0 {
+		return langs[0]
+	}
+	return DefaultLanguage
+}
+
+// WriteLocalizedJSONError writes an error response translated according to the
+// request's Accept-Language header. Requests without the header, or a nil
+// translator, produce the same response as WriteJSONError.
+func WriteLocalizedJSONError(w http.ResponseWriter, r *http.Request, err error, t Translator) {
+	authErr, ok := err.(*AuthError)
+	if !ok || r == nil {
+		WriteJSONError(w, err)
+		return
+	}
+
+	localized, lang := localizeAuthError(authErr, r.Header.Get("Accept-Language"), t)
+	if lang != "" {
+		w.Header().Set("Content-Language", lang)
+	}
+	WriteJSONError(w, localized)
+}
+
+// writeError writes err as a JSON error response localized with the
+// translator of the Auth instance.
+func (a *Auth) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	WriteLocalizedJSONError(w, r, err, a.Translator())
+}
+
+// localizeAuthError translates authErr into the most preferred language of
+// the Accept-Language header value and returns the language used. An empty
+// header or a nil translator leaves the error untranslated and returns "".
+func localizeAuthError(authErr *AuthError, acceptLanguage string, t Translator) (*AuthError, string) {
+	if t == nil || acceptLanguage == "" {
+		return authErr, ""
+	}
+
+	lang := DefaultLanguage
+	if langs := ParseAcceptLanguage(acceptLanguage); len(langs) > 0 {
+		lang = langs[0]
+	}
+	return authErr.Localize(t, lang), lang
+}
+
+// translatorSwitch holds the translator of an Auth instance, which can be
+// replaced while requests are being served.
+type translatorSwitch struct {
+	mu         sync.RWMutex
+	translator Translator
+}
+
+// set replaces the translator.
+func (s *translatorSwitch) set(t Translator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.translator = t
+}
+
+// get returns the current translator, or nil if localization is disabled.
+func (s *translatorSwitch) get() Translator {
+	if s == nil {
+		return nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.translator
+}
+
+// normalizeLanguage lowercases a language tag and uses '-' as the separator.
+func normalizeLanguage(lang string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(lang), "_", "-"))
+}
+
+// languageFallbacks returns the lookup order for a language tag.
+func languageFallbacks(lang, defaultLang string) []string {
+	lang = normalizeLanguage(lang)
+	candidates := make([]string, 0, 3)
+	if lang != "" {
+		candidates = append(candidates, lang)
+		if idx := strings.Index(lang, "-"); idx > 0 {
+			candidates = append(candidates, lang[:idx])
+		}
+	}
+	return append(candidates, defaultLang)
+}
+
+// englishMessages is the built-in English catalog.
+var englishMessages = map[string]string{
+	ErrCodeInvalidCredentials:   "Invalid username or password",
+	ErrCodeInvalidToken:         "Invalid or expired token",
+	ErrCodeTokenExpired:         "Token has expired",
+	ErrCodeTokenRevoked:         "Token has been revoked",
+	ErrCodeMissingToken:         "Authorization token is required",
+	ErrCodeMalformedToken:       "Authorization header must be in format 'Bearer <token>'",
+	ErrCodeInvalidDPoPProof:     "Invalid DPoP proof",
+	ErrCodeCaptchaRequired:      "CAPTCHA verification required",
+	ErrCodeStepUpRequired:       "Additional verification required",
+	ErrCodeLoginDenied:          "Login denied",
+	ErrCodeElevationRequired:    "Recent re-authentication required",
+	ErrCodeSessionExpired:       "Session has expired due to inactivity",
+	ErrCodeUserExists:           "User already exists",
+	ErrCodeAccountExpired:       "Account has expired",
+	ErrCodeSubscriptionInactive: "Subscription is not active",
+	ErrCodeUserNotFound:         "User not found",
+	ErrCodeUserInactive:         "User account is inactive",
+	ErrCodeUserDeleted:          "User account has been deleted",
+	ErrCodeConsentRequired:      "Acceptance of updated terms required",
+	ErrCodeUnderAge:             "Registration requires a minimum age",
+	ErrCodeWeakPassword:         "Password does not meet requirements",
+	ErrCodePasswordMismatch:     "Old password is incorrect",
+	ErrCodeInvalidResetToken:    "Invalid or expired reset token",
+	ErrCodeResetTokenExpired:    "Reset token has expired",
+	ErrCodePasswordExpired:      "Password has expired and must be changed",
+	ErrCodeValidationError:      "Validation failed",
+	ErrCodePermissionDenied:     "Permission denied",
+	ErrCodeRateLimitExceeded:    "Too many requests",
+	ErrCodeInternalError:        "An internal error occurred",
+	ErrCodeMaintenanceMode:      "Service is under maintenance",
+	ErrCodeCircuitOpen:          "Service temporarily unavailable",
+	ErrCodeInvalidCSRFToken:     "Invalid CSRF token",
+	ErrCodeTokenTooLarge:        "Access token is too large",
+	ErrCodeQuotaExceeded:        "Registration quota exceeded",
+
+	MsgKeyInvalidField:     "Invalid value for field: %s",
+	MsgKeyUserExists:       "A user with this %s already exists",
+	MsgKeyPasswordTooShort: "Password must be at least %d characters long",
+	MsgKeyMalformedAuthHdr: "Expected format: Authorization: Bearer <jwt-token>",
+	MsgKeyMissingClaim:     "Token must contain a valid '%s' claim",
+}
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		header   string
+		expected []string
+	}{
+		{"", []string{}},
+		{"fr", []string{"fr"}},
+		{"fr-CH, fr;q=0.9, en;q=0.8, de;q=0.7, *;q=0.5", []string{"fr-ch", "fr", "en", "de"}},
+		{"en;q=0.1, es", []string{"es", "en"}},
+		{"de;q=0, pt_BR", []string{"pt-br"}},
+	}
+
+	for _, tt := range tests {
+		got := ParseAcceptLanguage(tt.header)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("ParseAcceptLanguage(%q) = %v, expected %v", tt.header, got, tt.expected)
+		}
+	}
+}
+
+func TestMessageCatalogTranslate(t *testing.T) {
+	catalog := DefaultMessageCatalog()
+	catalog.AddMessages("es", map[string]string{
+		ErrCodeValidationError: "La validación falló",
+		MsgKeyInvalidField:     "Valor no válido para el campo: %s",
+	})
+
+	t.Run("exact language", func(t *testing.T) {
+		msg, ok := catalog.Translate("es", ErrCodeValidationError)
+		if !ok || msg != "La validación falló" {
+			t.Errorf("Expected Spanish message, got %q (found=%v)", msg, ok)
+		}
+	})
+
+	t.Run("base language fallback", func(t *testing.T) {
+		msg, ok := catalog.Translate("es-MX", MsgKeyInvalidField, "email")
+		if !ok || msg != "Valor no válido para el campo: email" {
+			t.Errorf("Expected formatted Spanish message, got %q (found=%v)", msg, ok)
+		}
+	})
+
+	t.Run("default language fallback", func(t *testing.T) {
+		msg, ok := catalog.Translate("ja", ErrCodeUserNotFound)
+		if !ok || msg != "User not found" {
+			t.Errorf("Expected English fallback, got %q (found=%v)", msg, ok)
+		}
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		if _, ok := catalog.Translate("es", "no.such.key"); ok {
+			t.Error("Expected unknown key to be reported as missing")
+		}
+	})
+
+	if langs := catalog.Languages(); !reflect.DeepEqual(langs, []string{"en", "es"}) {
+		t.Errorf("Expected languages [en es], got %v", langs)
+	}
+}
+
+func TestAuthErrorLocalize(t *testing.T) {
+	catalog := DefaultMessageCatalog()
+	catalog.AddMessages("de", map[string]string{
+		ErrCodeWeakPassword:    "Passwort erfüllt nicht die Anforderungen",
+		MsgKeyPasswordTooShort: "Das Passwort muss mindestens %d Zeichen lang sein",
+	})
+
+	original := ErrPasswordTooShort(8)
+	localized := original.Localize(catalog, "de")
+
+	if localized.Code != ErrCodeWeakPassword {
+		t.Errorf("Expected code to be preserved, got %s", localized.Code)
+	}
+	if localized.Message != "Passwort erfüllt nicht die Anforderungen" {
+		t.Errorf("Unexpected localized message: %s", localized.Message)
+	}
+	if localized.Details != "Das Passwort muss mindestens 8 Zeichen lang sein" {
+		t.Errorf("Unexpected localized details: %s", localized.Details)
+	}
+	if original.Message != "Password does not meet requirements" {
+		t.Errorf("Localize must not modify the original error, got %s", original.Message)
+	}
+
+	// Errors without a details key keep their details untouched.
+	plain := NewAuthErrorWithDetails(ErrCodeWeakPassword, "Too weak", "custom requirement")
+	if got := plain.Localize(catalog, "de").Details; got != "custom requirement" {
+		t.Errorf("Expected details to be unchanged, got %s", got)
+	}
+}
+
+func TestWriteLocalizedJSONError(t *testing.T) {
+	catalog := DefaultMessageCatalog()
+	catalog.AddMessages("fr", map[string]string{
+		ErrCodeMissingToken: "Un jeton d'autorisation est requis",
+	})
+
+	t.Run("with Accept-Language", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Language", "fr-FR,fr;q=0.9")
+		w := httptest.NewRecorder()
+
+		WriteLocalizedJSONError(w, r, ErrMissingToken(), catalog)
+
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected status %d, got %d", http.StatusUnauthorized, w.Code)
+		}
+		if w.Header().Get("Content-Language") != "fr-fr" {
+			t.Errorf("Expected Content-Language fr-fr, got %s", w.Header().Get("Content-Language"))
+		}
+
+		var resp HTTPErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if resp.Message != "Un jeton d'autorisation est requis" {
+			t.Errorf("Expected French message, got %s", resp.Message)
+		}
+	})
+
+	t.Run("without Accept-Language", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		w := httptest.NewRecorder()
+
+		WriteLocalizedJSONError(w, r, ErrMissingToken(), catalog)
+
+		var resp HTTPErrorResponse
+		if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		if resp.Message != "Authorization token is required" {
+			t.Errorf("Expected original message, got %s", resp.Message)
+		}
+	})
+}
+
+func TestHandlersLocalizeErrors(t *testing.T) {
+	ta := NewTestAuth(t)
+	catalog := DefaultMessageCatalog()
+	catalog.AddMessages("fr", map[string]string{
+		ErrCodePermissionDenied: "Permission refusée",
+	})
+	ta.SetTranslator(catalog)
+
+	handler := ta.DebugHandler(DebugHandlerConfig{
+		Authorize: func(r *http.Request) bool { return false },
+	})
+	r := httptest.NewRequest(http.MethodGet, "/debug", nil)
+	r.Header.Set("Accept-Language", "fr")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	var resp HTTPErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+	if resp.Message != "Permission refusée" {
+		t.Errorf("Expected French message, got %s", resp.Message)
+	}
+}
+
+func TestSetTranslatorConcurrentWithRequests(t *testing.T) {
+	ta := NewTestAuth(t)
+	handler := ta.Middleware().Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			ta.SetTranslator(DefaultMessageCatalog())
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Language", "fr")
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	<-done
+}
@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// StorageInstrumentationConfig records the latency and errors of every
+// storage call in the metrics, see Metrics.StorageQueries.
+type StorageInstrumentationConfig struct {
+	// Enabled turns the instrumentation on.
+	Enabled bool
+	// SlowQueryThreshold logs calls that take longer as warnings. Slow
+	// queries are not logged when zero.
+	SlowQueryThreshold time.Duration
+}
+
+// instrumentedStorage times the calls of the storage it wraps.
+type instrumentedStorage struct {
+	storage.EnhancedStorage
+	config  StorageInstrumentationConfig
+	metrics *MetricsCollector
+	logger  *Logger
+}
+
+// NewInstrumentedStorage wraps s so that every call is recorded in metrics
+// and slow calls are logged. Auth does this itself when
+// AuthConfig.StorageInstrumentation is enabled.
+func NewInstrumentedStorage(s storage.EnhancedStorage, config StorageInstrumentationConfig, metrics *MetricsCollector, logger *Logger) storage.EnhancedStorage {
+	return &instrumentedStorage{EnhancedStorage: s, config: config, metrics: metrics, logger: logger}
+}
+
+// observe records a call of method that started at start.
+func (s *instrumentedStorage) observe(method string, start time.Time, err error) {
+	duration := time.Since(start)
+	slow := s.config.SlowQueryThreshold > 0 && duration > s.config.SlowQueryThreshold
+	s.metrics.RecordStorageQuery(method, duration, isStorageFailure(err), slow)
+	if slow {
+		fields := map[string]interface{}{
+			"method":    method,
+			"duration":  duration,
+			"threshold": s.config.SlowQueryThreshold,
+		}
+		if err != nil {
+			fields["error"] = err
+		}
+		s.logger.Warn("Slow storage query", fields)
+	}
+}
+
+func (s *instrumentedStorage) CreateUser(user models.User) error {
+	start := time.Now()
+	err := s.EnhancedStorage.CreateUser(user)
+	s.observe("CreateUser", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) GetUserByID(userID string) (*models.User, error) {
+	start := time.Now()
+	user, err := s.EnhancedStorage.GetUserByID(userID)
+	s.observe("GetUserByID", start, err)
+	return user, err
+}
+
+func (s *instrumentedStorage) GetUserByUsername(username string) (*models.User, error) {
+	start := time.Now()
+	user, err := s.EnhancedStorage.GetUserByUsername(username)
+	s.observe("GetUserByUsername", start, err)
+	return user, err
+}
+
+func (s *instrumentedStorage) GetUserByEmail(email string) (*models.User, error) {
+	start := time.Now()
+	user, err := s.EnhancedStorage.GetUserByEmail(email)
+	s.observe("GetUserByEmail", start, err)
+	return user, err
+}
+
+func (s *instrumentedStorage) UpdateUser(userID string, updates storage.UserUpdates) error {
+	start := time.Now()
+	err := s.EnhancedStorage.UpdateUser(userID, updates)
+	s.observe("UpdateUser", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) UpdatePassword(userID string, passwordHash string) error {
+	start := time.Now()
+	err := s.EnhancedStorage.UpdatePassword(userID, passwordHash)
+	s.observe("UpdatePassword", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) DeleteUser(userID string) error {
+	start := time.Now()
+	err := s.EnhancedStorage.DeleteUser(userID)
+	s.observe("DeleteUser", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) ListUsers(limit, offset int) ([]*models.User, error) {
+	start := time.Now()
+	users, err := s.EnhancedStorage.ListUsers(limit, offset)
+	s.observe("ListUsers", start, err)
+	return users, err
+}
+
+func (s *instrumentedStorage) BlacklistToken(tokenID string, expiresAt time.Time) error {
+	start := time.Now()
+	err := s.EnhancedStorage.BlacklistToken(tokenID, expiresAt)
+	s.observe("BlacklistToken", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) IsTokenBlacklisted(tokenID string) (bool, error) {
+	start := time.Now()
+	blacklisted, err := s.EnhancedStorage.IsTokenBlacklisted(tokenID)
+	s.observe("IsTokenBlacklisted", start, err)
+	return blacklisted, err
+}
+
+func (s *instrumentedStorage) CleanupExpiredTokens() error {
+	start := time.Now()
+	err := s.EnhancedStorage.CleanupExpiredTokens()
+	s.observe("CleanupExpiredTokens", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) Ping() error {
+	start := time.Now()
+	err := s.EnhancedStorage.Ping()
+	s.observe("Ping", start, err)
+	return err
+}
+
+func (s *instrumentedStorage) GetSchemaVersion() (int, error) {
+	start := time.Now()
+	version, err := s.EnhancedStorage.GetSchemaVersion()
+	s.observe("GetSchemaVersion", start, err)
+	return version, err
+}
+
+func (s *instrumentedStorage) unwrapStorage() storage.EnhancedStorage {
+	return s.EnhancedStorage
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/internal/storage/memory"
+	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// slowStorage delays user lookups by ID.
+type slowStorage struct {
+	storage.EnhancedStorage
+	delay time.Duration
+}
+
+func (s *slowStorage) GetUserByID(userID string) (*models.User, error) {
+	time.Sleep(s.delay)
+	return s.EnhancedStorage.GetUserByID(userID)
+}
+
+func TestInstrumentedStorage(t *testing.T) {
+	var logs bytes.Buffer
+	metrics := NewMetricsCollector()
+	base := &slowStorage{EnhancedStorage: memory.NewInMemoryStorage(), delay: 20 * time.Millisecond}
+	instrumented := NewInstrumentedStorage(base, StorageInstrumentationConfig{
+		Enabled:            true,
+		SlowQueryThreshold: 10 * time.Millisecond,
+	}, metrics, NewLogger(LogLevelWarn, &logs))
+
+	instrumented.GetUserByID("missing")
+	instrumented.GetUserByUsername("missing")
+	instrumented.Ping()
+
+	queries := metrics.GetMetrics().StorageQueries
+	byID := queries["GetUserByID"]
+	if byID.Calls != 1 || byID.Slow != 1 || byID.MaxDuration < 20*time.Millisecond {
+		t.Errorf("Unexpected metrics for the slow lookup: %+v", byID)
+	}
+	if byID.Errors != 0 {
+		t.Errorf("Expected a missing user not to count as an error, got %d", byID.Errors)
+	}
+	if byID.Buckets[0]+byID.Buckets[1]+byID.Buckets[2] != 0 {
+		t.Errorf("Expected the lookup above the 10ms bucket, got %v", byID.Buckets)
+	}
+	if queries["Ping"].Calls != 1 || queries["GetUserByUsername"].Slow != 0 {
+		t.Errorf("Expected every method to be recorded, got %+v", queries)
+	}
+
+	output := logs.String()
+	if strings.Count(output, "Slow storage query") != 1 || !strings.Contains(output, "GetUserByID") {
+		t.Errorf("Expected one slow query to be logged, got %q", output)
+	}
+}
+
+func TestQueryMetricsRates(t *testing.T) {
+	metrics := NewMetricsCollector()
+	metrics.RecordStorageQuery("UpdateUser", 2*time.Millisecond, false, false)
+	metrics.RecordStorageQuery("UpdateUser", 4*time.Millisecond, true, false)
+	metrics.RecordStorageQuery("UpdateUser", 10*time.Second, false, true)
+
+	query := metrics.GetMetrics().StorageQueries["UpdateUser"]
+	if rate := query.ErrorRate(); rate < 0.33 || rate > 0.34 {
+		t.Errorf("Expected an error rate of 1/3, got %f", rate)
+	}
+	if query.Buckets[len(StorageLatencyBuckets)] != 1 {
+		t.Errorf("Expected the slowest call in the overflow bucket, got %v", query.Buckets)
+	}
+
+	// Snapshots are not affected by later calls
+	metrics.RecordStorageQuery("UpdateUser", time.Millisecond, false, false)
+	if query.Calls != 3 || query.Buckets[0] != 0 {
+		t.Errorf("Expected the snapshot to be a copy, got %+v", query)
+	}
+}
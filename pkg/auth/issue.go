@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/internal/jwtutils"
+)
+
+// IssueOptions configures a token pair issued without a login.
+type IssueOptions struct {
+	// Claims are added to the access token. Like the custom claims of
+	// Login, they do not replace standard, stored or protected claims.
+	Claims map[string]interface{}
+	// IssueAt issues the pair as of this time instead of now. All
+	// timestamps count from it, so a future time schedules the pair.
+	IssueAt time.Time
+	// ActivateAt is when the pair becomes valid (its nbf claim), e.g. store
+	// opening for a pre-provisioned kiosk. Token lifetimes count from it.
+	ActivateAt time.Time
+}
+
+// Issue creates a token pair for an existing active user without checking
+// a password, e.g. to pre-provision devices. Tokens issued with a future
+// IssueAt or ActivateAt are rejected until that time.
+func (t *Tokens) Issue(userID string, opts IssueOptions) (*LoginResult, error) {
+	if !opts.IssueAt.IsZero() && !opts.ActivateAt.IsZero() && opts.ActivateAt.Before(opts.IssueAt) {
+		return nil, NewAuthErrorWithDetails(ErrCodeValidationError,
+			"Invalid activation time", "ActivateAt must not be before IssueAt")
+	}
+
+	user, err := t.storage.GetUserByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound()
+	}
+	if !user.IsActive {
+		return nil, ErrUserInactive()
+	}
+	if err := checkAccountExpiry(user, t.clock); err != nil {
+		return nil, err
+	}
+
+	stored := t.claims.stored(user)
+	if err := t.subscriptions.apply(context.Background(), user, stored); err != nil {
+		return nil, err
+	}
+	claims := t.claims.merge(user.ID, stored, opts.Claims)
+
+	schedule := jwtutils.IssueOptions{IssuedAt: opts.IssueAt, NotBefore: opts.ActivateAt}
+	accessToken, err := t.jwtManager.GenerateAccessTokenWithOptions(user.ID, claims, schedule)
+	if err != nil {
+		return nil, WrapError(err, ErrCodeInternalError, "Failed to generate access token")
+	}
+	refreshToken, err := t.jwtManager.GenerateRefreshTokenWithOptions(user.ID, schedule)
+	if err != nil {
+		return nil, WrapError(err, ErrCodeInternalError, "Failed to generate refresh token")
+	}
+	compatibilityToken, err := t.dual.issue(user.ID, claims, schedule)
+	if err != nil {
+		return nil, WrapError(err, ErrCodeInternalError, "Failed to generate compatibility token")
+	}
+
+	return &LoginResult{
+		AccessToken:        accessToken,
+		RefreshToken:       refreshToken,
+		CompatibilityToken: compatibilityToken,
+	}, nil
+}
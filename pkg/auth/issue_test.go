@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIssueActivateAt(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("kiosk-1", "kiosk-password")
+	opening := ta.Clock.Now().Add(8 * time.Hour)
+
+	result, err := ta.Tokens().Issue(user.ID, IssueOptions{
+		ActivateAt: opening,
+		Claims:     map[string]interface{}{"device": "kiosk"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to issue tokens: %v", err)
+	}
+
+	if _, err := ta.Tokens().Validate(result.AccessToken); err == nil {
+		t.Error("Expected access token to be rejected before activation")
+	}
+	if _, err := ta.Tokens().Refresh(result.RefreshToken); err == nil {
+		t.Error("Expected refresh token to be rejected before activation")
+	}
+
+	ta.Clock.Set(opening)
+	claims, err := ta.ValidateAccessToken(result.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected access token to be valid at activation: %v", err)
+	}
+	if claims["device"] != "kiosk" || claims["username"] != "kiosk-1" {
+		t.Errorf("Expected user and custom claims, got %v", claims)
+	}
+
+	// The lifetime counts from activation, not from issuance
+	ta.Clock.Advance(ta.config.AccessTokenTTL - time.Second)
+	if _, err := ta.Tokens().Validate(result.AccessToken); err != nil {
+		t.Errorf("Expected access token to last its full TTL after activation: %v", err)
+	}
+	if _, err := ta.Tokens().Refresh(result.RefreshToken); err != nil {
+		t.Errorf("Expected refresh token to be usable after activation: %v", err)
+	}
+}
+
+func TestIssueInvalidSchedule(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("kiosk-2", "kiosk-password")
+	now := ta.Clock.Now()
+
+	_, err := ta.Tokens().Issue(user.ID, IssueOptions{IssueAt: now.Add(time.Hour), ActivateAt: now})
+	if err == nil {
+		t.Error("Expected activation before issuance to be rejected")
+	}
+	if _, err := ta.Tokens().Issue("missing", IssueOptions{}); err == nil {
+		t.Error("Expected unknown user to be rejected")
+	}
+}
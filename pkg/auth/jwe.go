@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pragneshbagary/go-auth/internal/jwtutils"
+)
+
+// encryptedClaimsKey is the claim holding the encrypted claims subset.
+const encryptedClaimsKey = "jwe"
+
+// TokenEncryptionConfig enables JWE encryption (alg "dir", enc "A256GCM") of access tokens.
+// By default the whole signed token is encrypted as a nested JWT. When Claims is
+// set, only those claims are encrypted into a "jwe" claim and the token stays a
+// readable JWS. Refresh tokens are never encrypted.
+type TokenEncryptionConfig struct {
+	// Key is the 32-byte AES-256 key used to encrypt new tokens.
+	Key []byte
+	// KeyID is sent as the kid header so the decryption key can be selected.
+	KeyID string
+	// DecryptionKeys holds retired keys by kid that are still accepted for decryption.
+	DecryptionKeys map[string][]byte
+	// Claims limits encryption to the named claims.
+	Claims []string
+}
+
+// jweHeader is the protected header of a compact JWE.
+type jweHeader struct {
+	Alg string `json:"alg"`
+	Enc string `json:"enc"`
+	Cty string `json:"cty,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// encryptedTokenManager wraps a TokenManager and encrypts access tokens.
+type encryptedTokenManager struct {
+	jwtutils.TokenManager
+	config TokenEncryptionConfig
+	keys   map[string]cipher.AEAD
+	aead   cipher.AEAD
+	// flags limits encryption to users with FlagTokenEncryption enabled
+	flags *featureFlags
+}
+
+// newEncryptedTokenManager wraps inner so that access tokens are encrypted on
+// issue and transparently decrypted on validation.
+func newEncryptedTokenManager(inner jwtutils.TokenManager, config TokenEncryptionConfig) (*encryptedTokenManager, error) {
+	aead, err := newA256GCM(config.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := map[string]cipher.AEAD{config.KeyID: aead}
+	for kid, key := range config.DecryptionKeys {
+		if kid == config.KeyID {
+			continue
+		}
+		if keys[kid], err = newA256GCM(key); err != nil {
+			return nil, fmt.Errorf("decryption key %q: %w", kid, err)
+		}
+	}
+
+	return &encryptedTokenManager{
+		TokenManager: inner,
+		config:       config,
+		keys:         keys,
+		aead:         aead,
+	}, nil
+}
+
+// newA256GCM creates an AES-256-GCM cipher for key.
+func newA256GCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, errors.New("token encryption key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// GenerateAccessToken issues an access token with the configured claims encrypted.
+func (m *encryptedTokenManager) GenerateAccessToken(userID string, customClaims map[string]any) (string, error) {
+	return m.GenerateAccessTokenWithOptions(userID, customClaims, jwtutils.IssueOptions{})
+}
+
+// GenerateAccessTokenWithOptions issues a scheduled access token with the
+// configured claims encrypted.
+func (m *encryptedTokenManager) GenerateAccessTokenWithOptions(userID string, customClaims map[string]any, opts jwtutils.IssueOptions) (string, error) {
+	if !m.flags.enabled(FlagTokenEncryption, userID) {
+		return m.TokenManager.GenerateAccessTokenWithOptions(userID, customClaims, opts)
+	}
+	if len(m.config.Claims) == 0 {
+		token, err := m.TokenManager.GenerateAccessTokenWithOptions(userID, customClaims, opts)
+		if err != nil {
+			return "", err
+		}
+		return m.encrypt([]byte(token), "JWT")
+	}
+
+	claims := make(map[string]any, len(customClaims))
+	secret := make(map[string]any)
+	for k, v := range customClaims {
+		claims[k] = v
+	}
+	for _, name := range m.config.Claims {
+		if v, ok := claims[name]; ok {
+			secret[name] = v
+			delete(claims, name)
+		}
+	}
+
+	if len(secret) > 0 {
+		payload, err := json.Marshal(secret)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode claims for encryption: %w", err)
+		}
+		if claims[encryptedClaimsKey], err = m.encrypt(payload, "json"); err != nil {
+			return "", err
+		}
+	}
+
+	return m.TokenManager.GenerateAccessTokenWithOptions(userID, claims, opts)
+}
+
+// RefreshAccessToken issues a new access token from a refresh token, encrypting it if needed.
+func (m *encryptedTokenManager) RefreshAccessToken(refreshToken string) (string, error) {
+	token, err := m.TokenManager.RefreshAccessToken(refreshToken)
+	if err != nil || len(m.config.Claims) > 0 {
+		return token, err
+	}
+	return m.encrypt([]byte(token), "JWT")
+}
+
+// ValidateAccessToken decrypts and validates an access token. Unencrypted
+// tokens are still accepted since their signature is checked as usual.
+func (m *encryptedTokenManager) ValidateAccessToken(accessToken string) (jwt.MapClaims, error) {
+	if strings.Count(accessToken, ".") == 4 {
+		plaintext, _, err := m.decrypt(accessToken)
+		if err != nil {
+			return nil, err
+		}
+		accessToken = string(plaintext)
+	}
+
+	claims, err := m.TokenManager.ValidateAccessToken(accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	if encrypted, ok := claims[encryptedClaimsKey].(string); ok {
+		plaintext, _, err := m.decrypt(encrypted)
+		if err != nil {
+			return nil, err
+		}
+		var secret map[string]any
+		if err := json.Unmarshal(plaintext, &secret); err != nil {
+			return nil, fmt.Errorf("failed to decode encrypted claims: %w", err)
+		}
+		delete(claims, encryptedClaimsKey)
+		for k, v := range secret {
+			claims[k] = v
+		}
+	}
+
+	return claims, nil
+}
+
+// encrypt produces a compact JWE of plaintext with the primary key.
+func (m *encryptedTokenManager) encrypt(plaintext []byte, cty string) (string, error) {
+	header, err := json.Marshal(jweHeader{Alg: "dir", Enc: "A256GCM", Cty: cty, Kid: m.config.KeyID})
+	if err != nil {
+		return "", err
+	}
+	protected := base64.RawURLEncoding.EncodeToString(header)
+
+	iv := make([]byte, m.aead.NonceSize())
+	if _, err := rand.Read(iv); err != nil {
+		return "", fmt.Errorf("failed to generate iv: %w", err)
+	}
+
+	sealed := m.aead.Seal(nil, iv, plaintext, []byte(protected))
+	tagStart := len(sealed) - m.aead.Overhead()
+
+	return strings.Join([]string{
+		protected,
+		"", // no encrypted key with direct encryption
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(sealed[:tagStart]),
+		base64.RawURLEncoding.EncodeToString(sealed[tagStart:]),
+	}, "."), nil
+}
+
+// decrypt opens a compact JWE produced by encrypt with the key named by its kid.
+func (m *encryptedTokenManager) decrypt(token string) ([]byte, *jweHeader, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 || parts[1] != "" {
+		return nil, nil, errors.New("invalid JWE format")
+	}
+
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode JWE header: %w", err)
+	}
+	var header jweHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse JWE header: %w", err)
+	}
+	if header.Alg != "dir" || header.Enc != "A256GCM" {
+		return nil, nil, fmt.Errorf("unsupported JWE algorithm %s/%s", header.Alg, header.Enc)
+	}
+
+	aead, ok := m.keys[header.Kid]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown JWE key id %q", header.Kid)
+	}
+
+	iv, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(iv) != aead.NonceSize() {
+		return nil, nil, errors.New("invalid JWE iv")
+	}
+	ciphertext, err := base64.RawURLEncoding.DecodeString(parts[3])
+	if err != nil {
+		return nil, nil, errors.New("invalid JWE ciphertext")
+	}
+	tag, err := base64.RawURLEncoding.DecodeString(parts[4])
+	if err != nil || len(tag) != aead.Overhead() {
+		return nil, nil, errors.New("invalid JWE authentication tag")
+	}
+
+	plaintext, err := aead.Open(nil, iv, append(ciphertext, tag...), []byte(parts[0]))
+	if err != nil {
+		return nil, nil, errors.New("failed to decrypt JWE")
+	}
+	return plaintext, &header, nil
+}
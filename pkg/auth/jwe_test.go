@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/internal/jwtutils"
+)
+
+func newTestJWTManager() jwtutils.TokenManager {
+	return jwtutils.NewJWTManager(jwtutils.JWTConfig{
+		AccessSecret:    []byte("access-secret"),
+		RefreshSecret:   []byte("refresh-secret"),
+		Issuer:          "go-auth-test",
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: time.Hour,
+		SigningMethod:   HS256,
+	})
+}
+
+func TestEncryptedAccessTokens(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	m, err := newEncryptedTokenManager(newTestJWTManager(), TokenEncryptionConfig{Key: key, KeyID: "k1"})
+	if err != nil {
+		t.Fatalf("Failed to create encrypted token manager: %v", err)
+	}
+
+	token, err := m.GenerateAccessToken("user-1", map[string]any{"ssn": "123-45-6789"})
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	if strings.Count(token, ".") != 4 {
+		t.Fatalf("Expected a 5-part compact JWE, got %q", token)
+	}
+
+	claims, err := m.ValidateAccessToken(token)
+	if err != nil {
+		t.Fatalf("Failed to validate encrypted token: %v", err)
+	}
+	if claims["sub"] != "user-1" || claims["ssn"] != "123-45-6789" {
+		t.Errorf("Unexpected claims after decryption: %v", claims)
+	}
+
+	// Tampering with the ciphertext must be detected
+	parts := strings.Split(token, ".")
+	if parts[3][0] == 'A' {
+		parts[3] = "B" + parts[3][1:]
+	} else {
+		parts[3] = "A" + parts[3][1:]
+	}
+	if _, err := m.ValidateAccessToken(strings.Join(parts, ".")); err == nil {
+		t.Error("Expected tampered token to be rejected")
+	}
+
+	// Refresh tokens stay unencrypted
+	refresh, err := m.GenerateRefreshToken("user-1")
+	if err != nil {
+		t.Fatalf("Failed to generate refresh token: %v", err)
+	}
+	if strings.Count(refresh, ".") != 2 {
+		t.Error("Expected refresh token to remain a plain JWS")
+	}
+}
+
+func TestEncryptedClaimsSubset(t *testing.T) {
+	key := bytes.Repeat([]byte{2}, 32)
+	m, err := newEncryptedTokenManager(newTestJWTManager(), TokenEncryptionConfig{
+		Key:    key,
+		Claims: []string{"ssn"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create encrypted token manager: %v", err)
+	}
+
+	token, err := m.GenerateAccessToken("user-1", map[string]any{"ssn": "123-45-6789", "role": "admin"})
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+	if strings.Count(token, ".") != 2 {
+		t.Fatalf("Expected outer token to remain a JWS, got %q", token)
+	}
+
+	raw, err := newTestJWTManager().ValidateAccessToken(token)
+	if err != nil {
+		t.Fatalf("Failed to validate outer token: %v", err)
+	}
+	if _, exposed := raw["ssn"]; exposed {
+		t.Error("Encrypted claim should not be readable without the key")
+	}
+	if raw["role"] != "admin" {
+		t.Error("Unencrypted claims should remain readable")
+	}
+
+	claims, err := m.ValidateAccessToken(token)
+	if err != nil {
+		t.Fatalf("Failed to validate token: %v", err)
+	}
+	if claims["ssn"] != "123-45-6789" {
+		t.Errorf("Expected decrypted ssn claim, got %v", claims["ssn"])
+	}
+	if _, ok := claims[encryptedClaimsKey]; ok {
+		t.Error("Encrypted claims container should be removed after decryption")
+	}
+}
+
+func TestEncryptedAccessTokenKeyRotation(t *testing.T) {
+	oldKey := bytes.Repeat([]byte{3}, 32)
+	newKey := bytes.Repeat([]byte{4}, 32)
+
+	oldManager, _ := newEncryptedTokenManager(newTestJWTManager(), TokenEncryptionConfig{Key: oldKey, KeyID: "old"})
+	token, err := oldManager.GenerateAccessToken("user-1", nil)
+	if err != nil {
+		t.Fatalf("Failed to generate token: %v", err)
+	}
+
+	rotated, err := newEncryptedTokenManager(newTestJWTManager(), TokenEncryptionConfig{
+		Key:            newKey,
+		KeyID:          "new",
+		DecryptionKeys: map[string][]byte{"old": oldKey},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create rotated manager: %v", err)
+	}
+	if _, err := rotated.ValidateAccessToken(token); err != nil {
+		t.Errorf("Expected token encrypted with retired key to validate, got %v", err)
+	}
+
+	if _, err := newEncryptedTokenManager(newTestJWTManager(), TokenEncryptionConfig{Key: []byte("short")}); err == nil {
+		t.Error("Expected short key to be rejected")
+	}
+}
+
+func TestAuthWithTokenEncryption(t *testing.T) {
+	a, err := NewWithConfig(&AuthConfig{
+		JWTSecret:       "test-secret",
+		LogLevel:        "error",
+		TokenEncryption: &TokenEncryptionConfig{Key: bytes.Repeat([]byte{5}, 32)},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create auth: %v", err)
+	}
+
+	if _, err := a.Register(RegisterRequest{Username: "alice", Password: "password123"}); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	result, err := a.Login("alice", "password123", map[string]interface{}{"plan": "pro"})
+	if err != nil {
+		t.Fatalf("Failed to login: %v", err)
+	}
+
+	claims, err := a.ValidateAccessToken(result.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected encrypted token to validate, got %v", err)
+	}
+	if claims["plan"] != "pro" {
+		t.Errorf("Expected plan claim, got %v", claims["plan"])
+	}
+}
@@ -0,0 +1,243 @@
+package auth
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pragneshbagary/go-auth/pkg/models"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Password hash schemes of systems users can be imported from.
+const (
+	// SchemeMD5Crypt is the crypt(3) MD5 scheme, e.g. "$1$salt$hash".
+	SchemeMD5Crypt = "md5crypt"
+	// SchemeSHA1 is a hex-encoded SHA-1 of Salt followed by the password.
+	SchemeSHA1 = "sha1"
+	// SchemeBcrypt is a bcrypt hash, e.g. "$2y$10$...".
+	SchemeBcrypt = "bcrypt"
+)
+
+// Prefixes of imported hashes that are stored as-is or re-encoded.
+const (
+	md5CryptPrefix = "$1$"
+	sha1Prefix     = "$sha1$"
+)
+
+// ImportedCredential is a password hash taken over from another system. It
+// is verified with its original scheme on the user's first login and then
+// replaced by a hash from the configured Hasher.
+type ImportedCredential struct {
+	// Scheme is one of SchemeMD5Crypt, SchemeSHA1 or SchemeBcrypt.
+	Scheme string
+	// Hash is the hash as stored by the old system.
+	Hash string
+	// Salt is prepended to the password for SchemeSHA1; empty if unsalted.
+	Salt string
+}
+
+// encode returns the self-describing form of the credential stored as the
+// user's password hash.
+func (c ImportedCredential) encode() (string, error) {
+	switch c.Scheme {
+	case SchemeMD5Crypt:
+		if _, _, ok := splitMD5Crypt(c.Hash); !ok {
+			return "", errors.New("invalid md5crypt hash")
+		}
+		return c.Hash, nil
+	case SchemeSHA1:
+		digest, err := hex.DecodeString(c.Hash)
+		if err != nil || len(digest) != sha1.Size {
+			return "", errors.New("invalid sha1 hash")
+		}
+		return sha1Prefix + base64.RawStdEncoding.EncodeToString([]byte(c.Salt)) + "$" + strings.ToLower(c.Hash), nil
+	case SchemeBcrypt:
+		if _, err := bcrypt.Cost([]byte(c.Hash)); err != nil {
+			return "", fmt.Errorf("invalid bcrypt hash: %w", err)
+		}
+		return c.Hash, nil
+	default:
+		return "", fmt.Errorf("unsupported password scheme %q", c.Scheme)
+	}
+}
+
+// isLegacyHash reports whether encodedHash uses an imported scheme that must
+// be replaced after a successful login.
+func isLegacyHash(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, md5CryptPrefix) || strings.HasPrefix(encodedHash, sha1Prefix)
+}
+
+// compareMD5Crypt checks password against a crypt(3) MD5 hash.
+func compareMD5Crypt(password, encodedHash string) (bool, error) {
+	salt, _, ok := splitMD5Crypt(encodedHash)
+	if !ok {
+		return false, errors.New("invalid md5crypt hash")
+	}
+	computed := md5Crypt([]byte(password), []byte(salt))
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(encodedHash)) == 1, nil
+}
+
+// compareSHA1 checks password against a hash stored by ImportedCredential.encode.
+func compareSHA1(password, encodedHash string) (bool, error) {
+	vals := strings.Split(strings.TrimPrefix(encodedHash, sha1Prefix), "$")
+	if len(vals) != 2 {
+		return false, errors.New("invalid sha1 hash")
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(vals[0])
+	if err != nil {
+		return false, fmt.Errorf("failed to decode salt: %w", err)
+	}
+	want, err := hex.DecodeString(vals[1])
+	if err != nil {
+		return false, fmt.Errorf("failed to decode hash: %w", err)
+	}
+	sum := sha1.Sum(append(salt, password...))
+	return subtle.ConstantTimeCompare(sum[:], want) == 1, nil
+}
+
+// splitMD5Crypt splits "$1$salt$hash" into salt and hash.
+func splitMD5Crypt(encodedHash string) (salt, hash string, ok bool) {
+	if !strings.HasPrefix(encodedHash, md5CryptPrefix) {
+		return "", "", false
+	}
+	salt, hash, ok = strings.Cut(strings.TrimPrefix(encodedHash, md5CryptPrefix), "$")
+	if !ok || len(salt) > 8 || len(hash) != 22 {
+		return "", "", false
+	}
+	return salt, hash, true
+}
+
+// md5Crypt implements the crypt(3) MD5 scheme introduced by FreeBSD.
+func md5Crypt(password, salt []byte) string {
+	alternate := md5.New()
+	alternate.Write(password)
+	alternate.Write(salt)
+	alternate.Write(password)
+	alt := alternate.Sum(nil)
+
+	ctx := md5.New()
+	ctx.Write(password)
+	ctx.Write([]byte(md5CryptPrefix))
+	ctx.Write(salt)
+	for i := len(password); i > 0; i -= md5.Size {
+		ctx.Write(alt[:min(i, md5.Size)])
+	}
+	for i := len(password); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			ctx.Write([]byte{0})
+		} else {
+			ctx.Write(password[:1])
+		}
+	}
+	final := ctx.Sum(nil)
+
+	// Deliberately slow down brute forcing
+	for i := 0; i < 1000; i++ {
+		round := md5.New()
+		if i&1 != 0 {
+			round.Write(password)
+		} else {
+			round.Write(final)
+		}
+		if i%3 != 0 {
+			round.Write(salt)
+		}
+		if i%7 != 0 {
+			round.Write(password)
+		}
+		if i&1 != 0 {
+			round.Write(final)
+		} else {
+			round.Write(password)
+		}
+		final = round.Sum(nil)
+	}
+
+	const itoa64 = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	var encoded strings.Builder
+	to64 := func(v uint32, n int) {
+		for ; n > 0; n-- {
+			encoded.WriteByte(itoa64[v&0x3f])
+			v >>= 6
+		}
+	}
+	for _, group := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		to64(uint32(final[group[0]])<<16|uint32(final[group[1]])<<8|uint32(final[group[2]]), 4)
+	}
+	to64(uint32(final[11]), 2)
+
+	return md5CryptPrefix + string(salt) + "$" + encoded.String()
+}
+
+// ImportedUser is a user migrated from another system together with their
+// existing password hash.
+type ImportedUser struct {
+	// ID keeps the user's identifier from the old system. Defaults to a new UUID.
+	ID         string
+	Username   string
+	Email      string
+	Credential ImportedCredential
+	Metadata   map[string]interface{}
+	// CreatedAt keeps the original registration time. Defaults to now.
+	CreatedAt time.Time
+}
+
+// Import creates a user with a password hash from another system, so users
+// can be migrated without forcing password resets. The legacy hash is
+// verified on the user's first login and transparently replaced by a hash
+// from the configured Hasher.
+func (u *Users) Import(imported ImportedUser) (*models.User, error) {
+	if imported.Username == "" {
+		return nil, ErrValidationError("username")
+	}
+	passwordHash, err := imported.Credential.encode()
+	if err != nil {
+		return nil, NewAuthErrorWithDetails(ErrCodeValidationError, "Invalid imported credential", err.Error())
+	}
+
+	if _, err := u.storage.GetUserByUsername(imported.Username); err == nil {
+		return nil, ErrUserExists("username")
+	}
+	if imported.Email != "" {
+		if _, err := u.storage.GetUserByEmail(imported.Email); err == nil {
+			return nil, ErrUserExists("email")
+		}
+	}
+
+	now := nowFrom(u.clock)
+	user := models.User{
+		ID:           imported.ID,
+		Username:     imported.Username,
+		Email:        imported.Email,
+		PasswordHash: passwordHash,
+		Metadata:     imported.Metadata,
+		CreatedAt:    imported.CreatedAt,
+		UpdatedAt:    now,
+		IsActive:     true,
+	}
+	if user.ID == "" {
+		user.ID = uuid.New().String()
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+
+	if err := u.storage.CreateUser(user); err != nil {
+		return nil, WrapDatabaseError(err)
+	}
+	if u.logger != nil {
+		u.logger.Info("User imported", map[string]interface{}{
+			"user_id": user.ID,
+			"scheme":  imported.Credential.Scheme,
+		})
+	}
+	return &user, nil
+}
@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLegacyHashSchemes(t *testing.T) {
+	// Vectors generated with `openssl passwd -1`
+	vectors := map[string]string{
+		"password":                     "$1$saltstri$qQY4WxjABChYG1ccLpfkz/",
+		"correct horse battery staple": "$1$ab$BGweqrca.3UvnWSg8yFZd1",
+		"":                             "$1$12345678$xek.CpjQUVgdf/P2N9KQf/",
+	}
+	for password, hash := range vectors {
+		match, err := verifyPassword(nil, password, hash)
+		if err != nil || !match {
+			t.Errorf("Expected md5crypt hash of %q to match, got match=%v err=%v", password, match, err)
+		}
+		if match, _ := verifyPassword(nil, password+"x", hash); match {
+			t.Errorf("Expected wrong password not to match %q", hash)
+		}
+	}
+
+	encoded, err := ImportedCredential{
+		Scheme: SchemeSHA1,
+		Hash:   "2b38da78c7a49f327da23132d41482ff98268b5d",
+		Salt:   "salt1",
+	}.encode()
+	if err != nil {
+		t.Fatalf("Failed to encode sha1 credential: %v", err)
+	}
+	if match, err := verifyPassword(nil, "secret", encoded); err != nil || !match {
+		t.Errorf("Expected salted sha1 to match, got match=%v err=%v", match, err)
+	}
+	if match, _ := verifyPassword(nil, "Secret", encoded); match {
+		t.Error("Expected wrong password not to match salted sha1")
+	}
+
+	invalid := []ImportedCredential{
+		{Scheme: SchemeMD5Crypt, Hash: "$1$toolongsalt$qQY4WxjABChYG1ccLpfkz/"},
+		{Scheme: SchemeSHA1, Hash: "not-hex"},
+		{Scheme: SchemeBcrypt, Hash: "$2y$broken"},
+		{Scheme: "md4", Hash: "whatever"},
+	}
+	for _, credential := range invalid {
+		if _, err := credential.encode(); err == nil {
+			t.Errorf("Expected %s credential %q to be rejected", credential.Scheme, credential.Hash)
+		}
+	}
+}
+
+func TestImportedUserMigratesOnLogin(t *testing.T) {
+	ta := NewTestAuth(t)
+	users := ta.Users()
+
+	imported, err := users.Import(ImportedUser{
+		ID:         "legacy-7",
+		Username:   "erin",
+		Email:      "erin@example.test",
+		Credential: ImportedCredential{Scheme: SchemeMD5Crypt, Hash: "$1$saltstri$qQY4WxjABChYG1ccLpfkz/"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to import user: %v", err)
+	}
+	if imported.ID != "legacy-7" {
+		t.Errorf("Expected legacy ID to be kept, got %s", imported.ID)
+	}
+
+	if _, err := ta.Login("erin", "wrong-password", nil); err == nil {
+		t.Error("Expected wrong password to be rejected")
+	}
+	if _, err := ta.Login("erin", "password", nil); err != nil {
+		t.Fatalf("Expected login with legacy hash to succeed: %v", err)
+	}
+
+	stored, err := ta.storage.GetUserByID(imported.ID)
+	if err != nil {
+		t.Fatalf("Failed to reload user: %v", err)
+	}
+	if !strings.HasPrefix(stored.PasswordHash, "$argon2id$") {
+		t.Errorf("Expected legacy hash to be replaced by Argon2id, got %q", stored.PasswordHash)
+	}
+	if _, err := ta.Login("erin", "password", nil); err != nil {
+		t.Errorf("Expected login with migrated hash to succeed: %v", err)
+	}
+
+	if _, err := users.Import(ImportedUser{
+		Username:   "erin",
+		Credential: ImportedCredential{Scheme: SchemeMD5Crypt, Hash: "$1$saltstri$qQY4WxjABChYG1ccLpfkz/"},
+	}); err == nil {
+		t.Error("Expected duplicate username to be rejected")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -53,9 +54,10 @@ func ParseLogLevel(level string) LogLevel {
 
 // Logger provides structured logging for authentication operations
 type Logger struct {
-	level  LogLevel
-	output io.Writer
-	logger *log.Logger
+	level      atomic.Int32 // LogLevel; changed at runtime by Auth.UpdateConfig
+	output     io.Writer
+	logger     *log.Logger
+	pseudonyms *Pseudonymizer // replaces user identifiers, nil when disabled
 }
 
 // LogEntry represents a structured log entry
@@ -80,11 +82,12 @@ func NewLogger(level LogLevel, output io.Writer) *Logger {
 		output = os.Stdout
 	}
 
-	return &Logger{
-		level:  level,
+	l := &Logger{
 		output: output,
 		logger: log.New(output, "", 0), // No prefix, we'll handle formatting
 	}
+	l.level.Store(int32(level))
+	return l
 }
 
 // NewDefaultLogger creates a logger with default settings (INFO level, stdout)
@@ -94,12 +97,17 @@ func NewDefaultLogger() *Logger {
 
 // SetLevel sets the logging level
 func (l *Logger) SetLevel(level LogLevel) {
-	l.level = level
+	l.level.Store(int32(level))
+}
+
+// Level returns the logging level
+func (l *Logger) Level() LogLevel {
+	return LogLevel(l.level.Load())
 }
 
 // IsEnabled checks if a log level is enabled
 func (l *Logger) IsEnabled(level LogLevel) bool {
-	return level >= l.level
+	return level >= l.Level()
 }
 
 // log writes a structured log entry
@@ -107,6 +115,7 @@ func (l *Logger) log(level LogLevel, message string, fields map[string]interface
 	if !l.IsEnabled(level) {
 		return
 	}
+	fields = l.pseudonyms.apply(fields)
 
 	entry := LogEntry{
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
@@ -258,7 +267,13 @@ func (fl *FieldLogger) mergeFields(additionalFields ...map[string]interface{}) m
 
 // AuthEventLogger provides specialized logging for authentication events
 type AuthEventLogger struct {
-	logger *Logger
+	logger   *Logger
+	geo      *loginGeo // enriches login events, nil without a GeoIPResolver
+	sinks    []*eventDispatcher
+	security *securityTelemetry // aggregates logins for Monitor.SecurityStats
+	tenant   string             // labels events, see forTenant
+	// notifications receives events for security emails, unpseudonymized
+	notifications *eventDispatcher
 }
 
 // NewAuthEventLogger creates a new authentication event logger
@@ -268,6 +283,45 @@ func NewAuthEventLogger(logger *Logger) *AuthEventLogger {
 	}
 }
 
+// forTenant returns an event logger labelling its events with tenant.
+func (ael *AuthEventLogger) forTenant(tenant string) *AuthEventLogger {
+	if tenant == "" {
+		return ael
+	}
+	labelled := *ael
+	labelled.tenant = tenant
+	return &labelled
+}
+
+// emit logs an event and hands it to the event sinks.
+func (ael *AuthEventLogger) emit(level LogLevel, message string, fields map[string]interface{}) {
+	if ael.tenant != "" {
+		fields["tenant"] = ael.tenant
+	}
+	if ael.notifications != nil {
+		ael.notifications.enqueue(newAuthEvent(level, message, fields))
+	}
+	if len(ael.sinks) > 0 {
+		// Build the event first; logging consumes the common fields.
+		// Pseudonyms are applied to a copy, so they are not applied twice.
+		event := newAuthEvent(level, message, ael.logger.pseudonyms.apply(fields))
+		for _, sink := range ael.sinks {
+			sink.enqueue(event)
+		}
+	}
+	ael.logger.log(level, message, fields)
+}
+
+// close delivers the buffered events and stops the event sinks.
+func (ael *AuthEventLogger) close() {
+	for _, sink := range ael.sinks {
+		sink.close()
+	}
+	if ael.notifications != nil {
+		ael.notifications.close()
+	}
+}
+
 // LogRegistration logs a user registration event
 func (ael *AuthEventLogger) LogRegistration(userID, username, email, ip, userAgent string, success bool, err error) {
 	fields := map[string]interface{}{
@@ -282,9 +336,9 @@ func (ael *AuthEventLogger) LogRegistration(userID, username, email, ip, userAge
 
 	if err != nil {
 		fields["error"] = err
-		ael.logger.Error("User registration failed", fields)
+		ael.emit(LogLevelError, "User registration failed", fields)
 	} else {
-		ael.logger.Info("User registered successfully", fields)
+		ael.emit(LogLevelInfo, "User registered successfully", fields)
 	}
 }
 
@@ -300,11 +354,69 @@ func (ael *AuthEventLogger) LogLogin(userID, username, ip, userAgent string, suc
 		"duration":   duration,
 	}
 
+	location := ael.geo.lookup(ip)
+	if location != nil {
+		fields["country"] = location.Country
+		fields["city"] = location.City
+		fields["asn"] = location.ASN
+	}
+	ael.security.recordLogin(ip, location, success, err)
+
 	if err != nil {
 		fields["error"] = err
-		ael.logger.Warn("User login failed", fields)
+		ael.emit(LogLevelWarn, "User login failed", fields)
 	} else {
-		ael.logger.Info("User logged in successfully", fields)
+		ael.emit(LogLevelInfo, "User logged in successfully", fields)
+		if location != nil && userID != "" {
+			if recent := ael.geo.observe(userID, *location); recent != nil {
+				ael.LogAnomalousLogin(userID, username, ip, userAgent, *location, recent)
+			}
+		}
+	}
+}
+
+// LogAnomalousLogin logs a successful login from a country or network not
+// seen in the user's recent logins
+func (ael *AuthEventLogger) LogAnomalousLogin(userID, username, ip, userAgent string, location GeoLocation, recent []GeoLocation) {
+	fields := map[string]interface{}{
+		"event":      "anomalous_login",
+		"user_id":    userID,
+		"username":   username,
+		"ip":         ip,
+		"user_agent": userAgent,
+		"country":    location.Country,
+		"city":       location.City,
+		"asn":        location.ASN,
+		"recent":     recent,
+	}
+
+	ael.emit(LogLevelWarn, "Login from unusual location", fields)
+}
+
+// LogLoginRisk logs the risk score of a login with valid credentials and
+// the resulting action: allow, step_up or deny
+func (ael *AuthEventLogger) LogLoginRisk(signals RiskSignals, score float64, action string, err error) {
+	fields := map[string]interface{}{
+		"event":           "login_risk",
+		"user_id":         signals.UserID,
+		"username":        signals.Username,
+		"ip":              signals.IP,
+		"user_agent":      signals.UserAgent,
+		"score":           score,
+		"action":          action,
+		"new_device":      signals.NewDevice,
+		"recent_attempts": signals.RecentAttempts,
+		"recent_failures": signals.RecentFailures,
+		"ip_reputation":   signals.IPReputation,
+	}
+
+	if err != nil {
+		fields["error"] = err
+	}
+	if action == RiskActionAllow {
+		ael.emit(LogLevelInfo, "Login risk assessed", fields)
+	} else {
+		ael.emit(LogLevelWarn, "Risky login challenged", fields)
 	}
 }
 
@@ -321,9 +433,9 @@ func (ael *AuthEventLogger) LogTokenRefresh(userID, ip, userAgent string, succes
 
 	if err != nil {
 		fields["error"] = err
-		ael.logger.Warn("Token refresh failed", fields)
+		ael.emit(LogLevelWarn, "Token refresh failed", fields)
 	} else {
-		ael.logger.Info("Token refreshed successfully", fields)
+		ael.emit(LogLevelInfo, "Token refreshed successfully", fields)
 	}
 }
 
@@ -340,9 +452,9 @@ func (ael *AuthEventLogger) LogTokenRevocation(userID, tokenID, ip, userAgent st
 
 	if err != nil {
 		fields["error"] = err
-		ael.logger.Error("Token revocation failed", fields)
+		ael.emit(LogLevelError, "Token revocation failed", fields)
 	} else {
-		ael.logger.Info("Token revoked successfully", fields)
+		ael.emit(LogLevelInfo, "Token revoked successfully", fields)
 	}
 }
 
@@ -359,12 +471,103 @@ func (ael *AuthEventLogger) LogPasswordChange(userID, username, ip, userAgent st
 
 	if err != nil {
 		fields["error"] = err
-		ael.logger.Error("Password change failed", fields)
+		ael.emit(LogLevelError, "Password change failed", fields)
+	} else {
+		ael.emit(LogLevelInfo, "Password changed successfully", fields)
+	}
+}
+
+// LogElevation logs a re-authentication for sudo mode
+func (ael *AuthEventLogger) LogElevation(userID, username, ip, userAgent string, success bool, err error) {
+	fields := map[string]interface{}{
+		"event":      "elevation",
+		"user_id":    userID,
+		"username":   username,
+		"ip":         ip,
+		"user_agent": userAgent,
+		"success":    success,
+	}
+
+	if err != nil {
+		fields["error"] = err
+		ael.emit(LogLevelWarn, "Elevation failed", fields)
 	} else {
-		ael.logger.Info("Password changed successfully", fields)
+		ael.emit(LogLevelInfo, "Session elevated", fields)
 	}
 }
 
+// LogUsernameChange logs a username change
+func (ael *AuthEventLogger) LogUsernameChange(userID, oldUsername, newUsername string, success bool, err error) {
+	fields := map[string]interface{}{
+		"event":        "username_change",
+		"user_id":      userID,
+		"old_username": oldUsername,
+		"new_username": newUsername,
+		"success":      success,
+	}
+
+	if err != nil {
+		fields["error"] = err
+		ael.emit(LogLevelWarn, "Username change failed", fields)
+	} else {
+		ael.emit(LogLevelInfo, "Username changed", fields)
+	}
+}
+
+// LogAccountExpiry logs a change of the account expiry of a user
+func (ael *AuthEventLogger) LogAccountExpiry(userID string, expiresAt time.Time, reactivated bool) {
+	fields := map[string]interface{}{
+		"event":       "account_expiry",
+		"user_id":     userID,
+		"reactivated": reactivated,
+	}
+	if !expiresAt.IsZero() {
+		fields["expires_at"] = expiresAt.UTC().Format(time.RFC3339)
+	}
+	ael.emit(LogLevelInfo, "Account expiry changed", fields)
+}
+
+// LogAccountDeactivated logs the deactivation of an expired account
+func (ael *AuthEventLogger) LogAccountDeactivated(userID string, expiredAt time.Time) {
+	ael.emit(LogLevelInfo, "Expired account deactivated", map[string]interface{}{
+		"event":      "account_deactivated",
+		"user_id":    userID,
+		"expired_at": expiredAt.UTC().Format(time.RFC3339),
+	})
+}
+
+// LogConfigChange logs settings changed at runtime, each with its old and
+// new value
+func (ael *AuthEventLogger) LogConfigChange(changes map[string]interface{}) {
+	ael.emit(LogLevelWarn, "Configuration changed", map[string]interface{}{
+		"event":   "config_changed",
+		"changes": changes,
+	})
+}
+
+// LogAdminJob logs the start and end of an administrative bulk job
+func (ael *AuthEventLogger) LogAdminJob(status AdminJobStatus) {
+	ael.emit(LogLevelWarn, "Admin job "+status.State, map[string]interface{}{
+		"event":     "admin_job",
+		"job_id":    status.ID,
+		"kind":      status.Kind,
+		"state":     status.State,
+		"total":     status.Total,
+		"succeeded": status.Succeeded,
+		"failed":    status.Failed,
+	})
+}
+
+// LogPendingTokenRevocation logs the revocation of pending emailed tokens
+func (ael *AuthEventLogger) LogPendingTokenRevocation(userID, kind string, revoked int) {
+	ael.emit(LogLevelInfo, "Pending tokens revoked", map[string]interface{}{
+		"event":   "pending_token_revocation",
+		"user_id": userID,
+		"kind":    kind,
+		"revoked": revoked,
+	})
+}
+
 // LogPasswordReset logs a password reset event
 func (ael *AuthEventLogger) LogPasswordReset(userID, username, ip, userAgent string, success bool, err error) {
 	fields := map[string]interface{}{
@@ -378,9 +581,81 @@ func (ael *AuthEventLogger) LogPasswordReset(userID, username, ip, userAgent str
 
 	if err != nil {
 		fields["error"] = err
-		ael.logger.Error("Password reset failed", fields)
+		ael.emit(LogLevelError, "Password reset failed", fields)
+	} else {
+		ael.emit(LogLevelInfo, "Password reset successfully", fields)
+	}
+}
+
+// LogActionToken logs the issue or verification of a single-purpose action token
+func (ael *AuthEventLogger) LogActionToken(operation, purpose, subject, tokenID string, success bool, err error) {
+	fields := map[string]interface{}{
+		"event":     "action_token",
+		"operation": operation,
+		"purpose":   purpose,
+		"subject":   subject,
+		"token_id":  tokenID,
+		"success":   success,
+	}
+
+	if err != nil {
+		fields["error"] = err
+		ael.emit(LogLevelWarn, "Action token "+operation+" failed", fields)
+	} else {
+		ael.emit(LogLevelInfo, "Action token "+operation+" succeeded", fields)
+	}
+}
+
+// LogGrant logs a delegation grant event
+func (ael *AuthEventLogger) LogGrant(operation, userID, serviceID, grantID string, success bool, err error) {
+	fields := map[string]interface{}{
+		"event":      "delegation_grant",
+		"operation":  operation,
+		"user_id":    userID,
+		"service_id": serviceID,
+		"grant_id":   grantID,
+		"success":    success,
+	}
+
+	if err != nil {
+		fields["error"] = err
+		ael.emit(LogLevelWarn, "Delegation grant "+operation+" failed", fields)
+	} else {
+		ael.emit(LogLevelInfo, "Delegation grant "+operation+" succeeded", fields)
+	}
+}
+
+// LogConsent logs a user's acceptance of a document version
+func (ael *AuthEventLogger) LogConsent(userID, documentID, version string, success bool, err error) {
+	fields := map[string]interface{}{
+		"event":       "consent",
+		"user_id":     userID,
+		"document_id": documentID,
+		"version":     version,
+		"success":     success,
+	}
+
+	if err != nil {
+		fields["error"] = err
+		ael.emit(LogLevelWarn, "Recording consent failed", fields)
+	} else {
+		ael.emit(LogLevelInfo, "Consent recorded", fields)
+	}
+}
+
+// LogTokenEpoch logs the global invalidation of all tokens
+func (ael *AuthEventLogger) LogTokenEpoch(epoch int64, success bool, err error) {
+	fields := map[string]interface{}{
+		"event":   "token_epoch",
+		"epoch":   epoch,
+		"success": success,
+	}
+
+	if err != nil {
+		fields["error"] = err
+		ael.emit(LogLevelError, "Invalidating all tokens failed", fields)
 	} else {
-		ael.logger.Info("Password reset successfully", fields)
+		ael.emit(LogLevelWarn, "All tokens invalidated", fields)
 	}
 }
 
@@ -397,8 +672,8 @@ func (ael *AuthEventLogger) LogTokenValidation(userID, ip, userAgent string, suc
 
 	if err != nil {
 		fields["error"] = err
-		ael.logger.Debug("Token validation failed", fields)
+		ael.emit(LogLevelDebug, "Token validation failed", fields)
 	} else {
-		ael.logger.Debug("Token validated successfully", fields)
+		ael.emit(LogLevelDebug, "Token validated successfully", fields)
 	}
 }
\ No newline at end of file
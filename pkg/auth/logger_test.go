@@ -17,8 +17,8 @@ func TestLogger(t *testing.T) {
 			t.Fatal("Expected logger to be created")
 		}
 
-		if logger.level != LogLevelInfo {
-			t.Errorf("Expected log level to be Info, got %v", logger.level)
+		if logger.Level() != LogLevelInfo {
+			t.Errorf("Expected log level to be Info, got %v", logger.Level())
 		}
 	})
 
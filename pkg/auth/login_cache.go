@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// LoginCacheConfig enables a short-lived cache of the username lookups done
+// by Login, which dominate storage load during login bursts. Entries are
+// invalidated when the user changes through this Auth instance; changes made
+// by other instances become visible after the TTL at the latest, so keep it
+// short, or broadcast them with AuthConfig.Revocation.
+type LoginCacheConfig struct {
+	// TTL of found users. Zero disables the cache.
+	TTL time.Duration
+	// NegativeTTL of unknown usernames. Defaults to TTL.
+	NegativeTTL time.Duration
+	// MaxEntries bounds the cache size. Defaults to 10,000.
+	MaxEntries int
+}
+
+// LoginCacheStats reports the effectiveness of the login cache.
+type LoginCacheStats struct {
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Entries int   `json:"entries"`
+}
+
+// loginUserCache caches users by username for login. A nil
+// *loginUserCache always reads through.
+type loginUserCache struct {
+	config LoginCacheConfig
+	clock  Clock
+
+	mu        sync.Mutex
+	entries   map[string]*loginCacheEntry
+	usernames map[string]string // user ID -> cached username
+	// generation changes on every invalidation so that a load racing an
+	// update does not cache the stale user.
+	generation uint64
+	hits       int64
+	misses     int64
+}
+
+// loginCacheEntry is a cached lookup. A nil user caches a miss.
+type loginCacheEntry struct {
+	user      *models.User
+	expiresAt time.Time
+}
+
+// newLoginUserCache returns nil unless a TTL is configured.
+func newLoginUserCache(config LoginCacheConfig, clock Clock) *loginUserCache {
+	if config.TTL <= 0 {
+		return nil
+	}
+	if config.NegativeTTL <= 0 {
+		config.NegativeTTL = config.TTL
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = 10000
+	}
+	return &loginUserCache{
+		config:    config,
+		clock:     clock,
+		entries:   make(map[string]*loginCacheEntry),
+		usernames: make(map[string]string),
+	}
+}
+
+// lookup returns the user with username, calling load on a miss. Callers get
+// a copy they may modify.
+func (c *loginUserCache) lookup(username string, load func(string) (*models.User, error)) (*models.User, error) {
+	if c == nil {
+		return load(username)
+	}
+	now := nowFrom(c.clock)
+
+	c.mu.Lock()
+	if entry, ok := c.entries[username]; ok && now.Before(entry.expiresAt) {
+		c.hits++
+		c.mu.Unlock()
+		if entry.user == nil {
+			return nil, errors.New("user not found")
+		}
+		user := *entry.user
+		return &user, nil
+	}
+	c.misses++
+	generation := c.generation
+	c.mu.Unlock()
+
+	user, err := load(username)
+	if err != nil && !isUserNotFound(err) {
+		// Only cache definite answers, not storage failures
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation {
+		return user, err
+	}
+	if len(c.entries) >= c.config.MaxEntries {
+		c.prune(now)
+	}
+	if len(c.entries) < c.config.MaxEntries {
+		if err != nil {
+			c.entries[username] = &loginCacheEntry{expiresAt: now.Add(c.config.NegativeTTL)}
+		} else {
+			cached := *user
+			c.entries[username] = &loginCacheEntry{user: &cached, expiresAt: now.Add(c.config.TTL)}
+			c.usernames[user.ID] = username
+		}
+	}
+	return user, err
+}
+
+// isUserNotFound reports whether err is the "user not found" error the
+// built-in storages return for unknown users.
+func isUserNotFound(err error) bool {
+	return err != nil && err.Error() == "user not found"
+}
+
+// forgetUsername drops the entry for username.
+func (c *loginUserCache) forgetUsername(username string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	if entry, ok := c.entries[username]; ok && entry.user != nil {
+		delete(c.usernames, entry.user.ID)
+	}
+	delete(c.entries, username)
+}
+
+// forgetUser drops the entry of the user with userID.
+func (c *loginUserCache) forgetUser(userID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	if username, ok := c.usernames[userID]; ok {
+		delete(c.entries, username)
+		delete(c.usernames, userID)
+	}
+}
+
+// prune drops expired entries. The caller must hold c.mu.
+func (c *loginUserCache) prune(now time.Time) {
+	for username, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			if entry.user != nil {
+				delete(c.usernames, entry.user.ID)
+			}
+			delete(c.entries, username)
+		}
+	}
+}
+
+// stats returns the cache counters.
+func (c *loginUserCache) stats() LoginCacheStats {
+	if c == nil {
+		return LoginCacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return LoginCacheStats{Hits: c.hits, Misses: c.misses, Entries: len(c.entries)}
+}
+
+// LoginCacheStats returns hit and miss counts of the login cache.
+func (a *Auth) LoginCacheStats() LoginCacheStats {
+	return a.loginCache.stats()
+}
+
+// loginCacheStorage invalidates the login cache on user mutations.
+type loginCacheStorage struct {
+	storage.EnhancedStorage
+	cache *loginUserCache
+}
+
+func (s *loginCacheStorage) CreateUser(user models.User) error {
+	// Clear a cached miss so the new user can log in right away
+	defer s.cache.forgetUsername(user.Username)
+	return s.EnhancedStorage.CreateUser(user)
+}
+
+func (s *loginCacheStorage) UpdateUser(userID string, updates storage.UserUpdates) error {
+	// Recording a login does not change what login checks
+	loginOnly := updates.LastLoginAt != nil && updates.Email == nil && updates.Username == nil && updates.Metadata == nil
+	if !loginOnly {
+		defer s.cache.forgetUser(userID)
+	}
+	if updates.Username != nil {
+		defer s.cache.forgetUsername(*updates.Username)
+	}
+	return s.EnhancedStorage.UpdateUser(userID, updates)
+}
+
+func (s *loginCacheStorage) UpdatePassword(userID string, passwordHash string) error {
+	defer s.cache.forgetUser(userID)
+	return s.EnhancedStorage.UpdatePassword(userID, passwordHash)
+}
+
+func (s *loginCacheStorage) DeleteUser(userID string) error {
+	defer s.cache.forgetUser(userID)
+	return s.EnhancedStorage.DeleteUser(userID)
+}
+
+func (s *loginCacheStorage) unwrapStorage() storage.EnhancedStorage {
+	return s.EnhancedStorage
+}
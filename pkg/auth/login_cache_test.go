@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func newLoginCacheTestAuth(t *testing.T) *TestAuth {
+	ta := NewTestAuth(t)
+	ta.loginCache = newLoginUserCache(LoginCacheConfig{TTL: 5 * time.Second}, ta.Clock)
+	ta.storage = &loginCacheStorage{EnhancedStorage: ta.storage, cache: ta.loginCache}
+	return ta
+}
+
+func TestLoginCache(t *testing.T) {
+	ta := newLoginCacheTestAuth(t)
+	ta.SeedUser("alice", "alice-password")
+
+	ta.LoginAs("alice")
+	ta.LoginAs("alice")
+	if stats := ta.LoginCacheStats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Expected second login to hit the cache, got %+v", stats)
+	}
+
+	// Unknown usernames are cached too, until the user registers
+	if _, err := ta.Login("bob", "bob-password", nil); err == nil {
+		t.Fatal("Expected unknown user to be rejected")
+	}
+	ta.SeedUser("bob", "bob-password")
+	ta.LoginAs("bob")
+
+	ta.Clock.Advance(6 * time.Second)
+	ta.LoginAs("alice")
+	if stats := ta.LoginCacheStats(); stats.Misses != 4 {
+		t.Errorf("Expected expired entry to be reloaded, got %+v", stats)
+	}
+}
+
+func TestLoginCacheInvalidatedOnPasswordChange(t *testing.T) {
+	ta := newLoginCacheTestAuth(t)
+	user := ta.SeedUser("carol", "old-password")
+	ta.LoginAs("carol")
+
+	if err := ta.Users().ChangePassword(user.ID, "old-password", "new-password-123"); err != nil {
+		t.Fatalf("Failed to change password: %v", err)
+	}
+	if _, err := ta.Login("carol", "old-password", nil); err == nil {
+		t.Error("Expected old password to be rejected right after the change")
+	}
+	if _, err := ta.Login("carol", "new-password-123", nil); err != nil {
+		t.Errorf("Expected new password to be accepted: %v", err)
+	}
+}
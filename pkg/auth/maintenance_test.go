@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceModeBlocksLoginAndRegister(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUsers("alice")
+	tokens := ta.LoginAs("alice")
+
+	ta.SetMaintenanceMode(true, "Database migration in progress")
+
+	if !ta.InMaintenance() {
+		t.Fatal("Expected maintenance mode to be enabled")
+	}
+	if !ta.Maintenance().Since.Equal(ta.Clock.Now()) {
+		t.Errorf("Expected Since to come from the clock, got %v", ta.Maintenance().Since)
+	}
+
+	_, err := ta.Login("alice", TestPassword, nil)
+	var authErr *AuthError
+	if !errors.As(err, &authErr) || authErr.Code != ErrCodeMaintenanceMode {
+		t.Fatalf("Expected MAINTENANCE_MODE error on login, got %v", err)
+	}
+	if authErr.Details != "Database migration in progress" {
+		t.Errorf("Expected maintenance message in details, got %q", authErr.Details)
+	}
+	if status := getHTTPStatusFromError(authErr); status != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", status)
+	}
+
+	_, err = ta.Register(RegisterRequest{Username: "bob", Password: "password123"})
+	if !errors.Is(err, ErrMaintenanceMode("")) {
+		t.Errorf("Expected MAINTENANCE_MODE error on register, got %v", err)
+	}
+
+	// Existing sessions keep working by default
+	if _, err := ta.ValidateAccessToken(tokens.AccessToken); err != nil {
+		t.Errorf("Expected validation to be allowed, got %v", err)
+	}
+	if _, err := ta.RefreshToken(tokens.RefreshToken); err != nil {
+		t.Errorf("Expected refresh to be allowed, got %v", err)
+	}
+
+	ta.SetMaintenanceMode(false, "")
+	if _, err := ta.Login("alice", TestPassword, nil); err != nil {
+		t.Errorf("Expected login to succeed after maintenance, got %v", err)
+	}
+	if !ta.Maintenance().Since.IsZero() {
+		t.Error("Expected Since to be cleared when maintenance is disabled")
+	}
+}
+
+func TestMaintenanceModeBlockSessions(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUsers("alice")
+	tokens := ta.LoginAs("alice")
+
+	ta.SetMaintenance(MaintenanceMode{
+		Enabled:         true,
+		BlockRefresh:    true,
+		BlockValidation: true,
+	})
+
+	if _, err := ta.RefreshToken(tokens.RefreshToken); !errors.Is(err, ErrMaintenanceMode("")) {
+		t.Errorf("Expected refresh to be blocked, got %v", err)
+	}
+	if _, err := ta.ValidateAccessToken(tokens.AccessToken); !errors.Is(err, ErrMaintenanceMode("")) {
+		t.Errorf("Expected validation to be blocked, got %v", err)
+	}
+	if _, err := ta.Tokens().Validate(tokens.AccessToken); !errors.Is(err, ErrMaintenanceMode("")) {
+		t.Errorf("Expected Tokens.Validate to be blocked, got %v", err)
+	}
+	simple := &SimpleAuth{auth: ta.Auth}
+	if _, err := simple.RefreshToken(tokens.RefreshToken); !errors.Is(err, ErrMaintenanceMode("")) {
+		t.Errorf("Expected SimpleAuth.RefreshToken to be blocked, got %v", err)
+	}
+
+	// Toggling keeps the session policy
+	ta.Clock.Advance(time.Minute)
+	ta.SetMaintenanceMode(true, "still migrating")
+	mode := ta.Maintenance()
+	if !mode.BlockRefresh || !mode.BlockValidation {
+		t.Error("Expected SetMaintenanceMode to preserve the session policy")
+	}
+	if mode.Since.Equal(ta.Clock.Now()) {
+		t.Error("Expected Since to be preserved while maintenance stays enabled")
+	}
+}
@@ -13,6 +13,9 @@ type Metrics struct {
 	RegistrationAttempts int64 `json:"registration_attempts"`
 	RegistrationSuccess  int64 `json:"registration_success"`
 	RegistrationFailures int64 `json:"registration_failures"`
+	// RegistrationQuotaRejections counts registrations rejected over quota,
+	// see RegistrationQuotaConfig.
+	RegistrationQuotaRejections int64 `json:"registration_quota_rejections"`
 
 	// Login metrics
 	LoginAttempts int64 `json:"login_attempts"`
@@ -25,6 +28,9 @@ type Metrics struct {
 	TokenValidations    int64 `json:"token_validations"`
 	TokenRevocations    int64 `json:"token_revocations"`
 	TokenValidationFail int64 `json:"token_validation_failures"`
+	// RefreshChurn counts refreshes requested within the minimum refresh
+	// interval that returned the previous pair instead of rotating.
+	RefreshChurn int64 `json:"refresh_churn"`
 
 	// Password metrics
 	PasswordChanges int64 `json:"password_changes"`
@@ -50,14 +56,132 @@ type Metrics struct {
 	ValidationErrors  int64 `json:"validation_errors"`
 	AuthenticationErrors int64 `json:"authentication_errors"`
 
+	// Shadow storage metrics
+	ShadowWriteErrors int64 `json:"shadow_write_errors"`
+	ShadowDivergences int64 `json:"shadow_divergences"`
+
+	// Token epoch metrics
+	TokenEpochAdvances   int64 `json:"token_epoch_advances"`
+	TokenEpochRejections int64 `json:"token_epoch_rejections"`
+
+	// Storage query metrics by method, recorded by the instrumented storage
+	StorageQueries map[string]QueryMetrics `json:"storage_queries,omitempty"`
+
+	// API call metrics by operation, recorded by the WithMetrics decorator
+	APICalls map[string]QueryMetrics `json:"api_calls,omitempty"`
+
+	// Encoded access token sizes, see TokenSizeConfig
+	AccessTokenSizes TokenSizeMetrics `json:"access_token_sizes"`
+
+	// Account flow funnels by name, see FunnelPasswordReset
+	Funnels map[string]FunnelMetrics `json:"funnels,omitempty"`
+
+	// Metrics by tenant label, see WithTenant
+	Tenants map[string]TenantMetrics `json:"tenants,omitempty"`
+
 	// System metrics
 	StartTime    time.Time `json:"start_time"`
 	LastActivity time.Time `json:"last_activity"`
 }
 
+// StorageLatencyBuckets are the upper bounds of the storage latency
+// histogram buckets; QueryMetrics.Buckets has one more for slower queries.
+var StorageLatencyBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+}
+
+// QueryMetrics holds the metrics of one storage method
+type QueryMetrics struct {
+	Calls  int64 `json:"calls"`
+	Errors int64 `json:"errors"`
+	// Slow counts calls slower than the slow query threshold
+	Slow          int64         `json:"slow"`
+	TotalDuration time.Duration `json:"total_duration"`
+	MaxDuration   time.Duration `json:"max_duration"`
+	// Buckets counts calls by latency, see StorageLatencyBuckets
+	Buckets []int64 `json:"buckets"`
+}
+
+// ErrorRate returns the share of calls that failed
+func (q QueryMetrics) ErrorRate() float64 {
+	if q.Calls == 0 {
+		return 0
+	}
+	return float64(q.Errors) / float64(q.Calls)
+}
+
+// AverageDuration returns the mean latency of the calls
+func (q QueryMetrics) AverageDuration() time.Duration {
+	if q.Calls == 0 {
+		return 0
+	}
+	return q.TotalDuration / time.Duration(q.Calls)
+}
+
+// TokenSizeBuckets are the upper bounds in bytes of the token size histogram
+// buckets; TokenSizeMetrics.Buckets has one more for larger tokens.
+var TokenSizeBuckets = []int{256, 512, 1024, 2048, 4096, 8192, 16384}
+
+// TokenSizeMetrics holds the size distribution of issued tokens
+type TokenSizeMetrics struct {
+	Count      int64 `json:"count"`
+	TotalBytes int64 `json:"total_bytes"`
+	MaxBytes   int   `json:"max_bytes"`
+	// Trimmed counts tokens issued without some claims to fit the size limit
+	Trimmed int64 `json:"trimmed"`
+	// Rejected counts tokens not issued because they exceeded the size limit
+	Rejected int64 `json:"rejected"`
+	// Buckets counts issued tokens by size, see TokenSizeBuckets
+	Buckets []int64 `json:"buckets"`
+}
+
+// AverageBytes returns the mean size of the issued tokens
+func (t TokenSizeMetrics) AverageBytes() int {
+	if t.Count == 0 {
+		return 0
+	}
+	return int(t.TotalBytes / t.Count)
+}
+
+// Funnels tracked by the metrics collector
+const (
+	// FunnelPasswordReset counts reset tokens created and consumed
+	FunnelPasswordReset = "password_reset"
+	// FunnelEmailVerification counts verification emails built and verified
+	FunnelEmailVerification = "email_verification"
+	// FunnelStepUp counts step-up challenges issued and completed
+	FunnelStepUp = "step_up"
+)
+
+// FunnelMetrics holds the progress of one account flow
+type FunnelMetrics struct {
+	Started   int64 `json:"started"`
+	Completed int64 `json:"completed"`
+}
+
+// DropOff returns the share of started flows that were not completed
+func (f FunnelMetrics) DropOff() float64 {
+	if f.Started == 0 {
+		return 0
+	}
+	return max(0, float64(f.Started-f.Completed)/float64(f.Started))
+}
+
 // MetricsCollector provides thread-safe metrics collection
 type MetricsCollector struct {
 	metrics *Metrics
+	// tenantLimit bounds Metrics.Tenants, see SetTenantLimit
+	tenantLimit int
 }
 
 // NewMetricsCollector creates a new metrics collector
@@ -77,6 +201,33 @@ func (mc *MetricsCollector) GetMetrics() Metrics {
 
 	// Create a copy to avoid race conditions
 	metricsCopy := *mc.metrics
+	if mc.metrics.StorageQueries != nil {
+		metricsCopy.StorageQueries = make(map[string]QueryMetrics, len(mc.metrics.StorageQueries))
+		for method, query := range mc.metrics.StorageQueries {
+			query.Buckets = append([]int64(nil), query.Buckets...)
+			metricsCopy.StorageQueries[method] = query
+		}
+	}
+	if mc.metrics.APICalls != nil {
+		metricsCopy.APICalls = make(map[string]QueryMetrics, len(mc.metrics.APICalls))
+		for op, call := range mc.metrics.APICalls {
+			call.Buckets = append([]int64(nil), call.Buckets...)
+			metricsCopy.APICalls[op] = call
+		}
+	}
+	metricsCopy.AccessTokenSizes.Buckets = append([]int64(nil), mc.metrics.AccessTokenSizes.Buckets...)
+	if mc.metrics.Funnels != nil {
+		metricsCopy.Funnels = make(map[string]FunnelMetrics, len(mc.metrics.Funnels))
+		for name, funnel := range mc.metrics.Funnels {
+			metricsCopy.Funnels[name] = funnel
+		}
+	}
+	if mc.metrics.Tenants != nil {
+		metricsCopy.Tenants = make(map[string]TenantMetrics, len(mc.metrics.Tenants))
+		for tenant, metrics := range mc.metrics.Tenants {
+			metricsCopy.Tenants[tenant] = metrics
+		}
+	}
 	return metricsCopy
 }
 
@@ -132,6 +283,15 @@ func (mc *MetricsCollector) RecordTokenRefresh(success bool, duration time.Durat
 	}
 }
 
+// RecordRefreshChurn records a refresh answered with the previous token pair
+func (mc *MetricsCollector) RecordRefreshChurn() {
+	mc.metrics.mu.Lock()
+	defer mc.metrics.mu.Unlock()
+
+	mc.metrics.RefreshChurn++
+	mc.metrics.LastActivity = time.Now()
+}
+
 // RecordTokenValidation records a token validation operation
 func (mc *MetricsCollector) RecordTokenValidation(success bool, duration time.Duration) {
 	mc.metrics.mu.Lock()
@@ -237,6 +397,168 @@ func (mc *MetricsCollector) RecordValidationError() {
 	mc.metrics.LastActivity = time.Now()
 }
 
+// RecordShadowWriteError records a write to the shadow storage that failed
+func (mc *MetricsCollector) RecordShadowWriteError() {
+	mc.metrics.mu.Lock()
+	defer mc.metrics.mu.Unlock()
+
+	mc.metrics.ShadowWriteErrors++
+	mc.metrics.LastActivity = time.Now()
+}
+
+// RecordShadowDivergence records a read where the shadow storage disagreed with the primary
+func (mc *MetricsCollector) RecordShadowDivergence() {
+	mc.metrics.mu.Lock()
+	defer mc.metrics.mu.Unlock()
+
+	mc.metrics.ShadowDivergences++
+	mc.metrics.LastActivity = time.Now()
+}
+
+// RecordTokenEpochAdvance records a global invalidation of all tokens
+func (mc *MetricsCollector) RecordTokenEpochAdvance() {
+	mc.metrics.mu.Lock()
+	defer mc.metrics.mu.Unlock()
+
+	mc.metrics.TokenEpochAdvances++
+	mc.metrics.LastActivity = time.Now()
+}
+
+// RecordTokenEpochRejection records a token rejected for an outdated epoch
+func (mc *MetricsCollector) RecordTokenEpochRejection() {
+	mc.metrics.mu.Lock()
+	defer mc.metrics.mu.Unlock()
+
+	mc.metrics.TokenEpochRejections++
+	mc.metrics.LastActivity = time.Now()
+}
+
+// RecordQuotaRejection records a registration of tenant rejected over quota
+func (mc *MetricsCollector) RecordQuotaRejection(tenant string) {
+	mc.metrics.mu.Lock()
+	mc.metrics.RegistrationQuotaRejections++
+	mc.metrics.LastActivity = time.Now()
+	mc.metrics.mu.Unlock()
+
+	mc.recordTenant(tenant, func(t *TenantMetrics) {
+		t.QuotaRejections++
+	})
+}
+
+// RecordStorageQuery records a call of a storage method
+func (mc *MetricsCollector) RecordStorageQuery(method string, duration time.Duration, failed, slow bool) {
+	mc.metrics.mu.Lock()
+	defer mc.metrics.mu.Unlock()
+
+	if mc.metrics.StorageQueries == nil {
+		mc.metrics.StorageQueries = make(map[string]QueryMetrics)
+	}
+	query := mc.metrics.StorageQueries[method]
+	if query.Buckets == nil {
+		query.Buckets = make([]int64, len(StorageLatencyBuckets)+1)
+	}
+	query.Calls++
+	if failed {
+		query.Errors++
+	}
+	if slow {
+		query.Slow++
+	}
+	query.TotalDuration += duration
+	query.MaxDuration = max(query.MaxDuration, duration)
+	bucket := len(StorageLatencyBuckets)
+	for i, bound := range StorageLatencyBuckets {
+		if duration <= bound {
+			bucket = i
+			break
+		}
+	}
+	query.Buckets[bucket]++
+	mc.metrics.StorageQueries[method] = query
+}
+
+// RecordAPICall records a call of a decorated API operation
+func (mc *MetricsCollector) RecordAPICall(op string, duration time.Duration, failed bool) {
+	mc.metrics.mu.Lock()
+	defer mc.metrics.mu.Unlock()
+
+	if mc.metrics.APICalls == nil {
+		mc.metrics.APICalls = make(map[string]QueryMetrics)
+	}
+	call := mc.metrics.APICalls[op]
+	if call.Buckets == nil {
+		call.Buckets = make([]int64, len(StorageLatencyBuckets)+1)
+	}
+	call.Calls++
+	if failed {
+		call.Errors++
+	}
+	call.TotalDuration += duration
+	call.MaxDuration = max(call.MaxDuration, duration)
+	bucket := len(StorageLatencyBuckets)
+	for i, bound := range StorageLatencyBuckets {
+		if duration <= bound {
+			bucket = i
+			break
+		}
+	}
+	call.Buckets[bucket]++
+	mc.metrics.APICalls[op] = call
+	mc.metrics.LastActivity = time.Now()
+}
+
+// RecordAccessTokenSize records the encoded size of an access token. Tokens
+// that were rejected are not counted in the distribution.
+func (mc *MetricsCollector) RecordAccessTokenSize(size int, trimmed, rejected bool) {
+	mc.metrics.mu.Lock()
+	defer mc.metrics.mu.Unlock()
+
+	sizes := &mc.metrics.AccessTokenSizes
+	if rejected {
+		sizes.Rejected++
+		return
+	}
+	if sizes.Buckets == nil {
+		sizes.Buckets = make([]int64, len(TokenSizeBuckets)+1)
+	}
+	if trimmed {
+		sizes.Trimmed++
+	}
+	sizes.Count++
+	sizes.TotalBytes += int64(size)
+	sizes.MaxBytes = max(sizes.MaxBytes, size)
+	bucket := len(TokenSizeBuckets)
+	for i, bound := range TokenSizeBuckets {
+		if size <= bound {
+			bucket = i
+			break
+		}
+	}
+	sizes.Buckets[bucket]++
+}
+
+// RecordFunnelStep records a flow of funnel that was started or, when
+// completed is true, finished. It is a no-op on a nil collector.
+func (mc *MetricsCollector) RecordFunnelStep(funnel string, completed bool) {
+	if mc == nil {
+		return
+	}
+	mc.metrics.mu.Lock()
+	defer mc.metrics.mu.Unlock()
+
+	if mc.metrics.Funnels == nil {
+		mc.metrics.Funnels = make(map[string]FunnelMetrics)
+	}
+	step := mc.metrics.Funnels[funnel]
+	if completed {
+		step.Completed++
+	} else {
+		step.Started++
+	}
+	mc.metrics.Funnels[funnel] = step
+	mc.metrics.LastActivity = time.Now()
+}
+
 // Reset resets all metrics to zero
 func (mc *MetricsCollector) Reset() {
 	mc.metrics.mu.Lock()
@@ -3,6 +3,7 @@ package auth
 import (
 	"context"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -12,7 +13,8 @@ import (
 // Middleware provides HTTP middleware functionality for authentication.
 // It supports both framework-agnostic HTTP middleware and framework-specific adapters.
 type Middleware struct {
-	auth *Auth
+	auth  *Auth
+	cache *validationCache
 }
 
 // UserContextKey is the key used to store user information in request context
@@ -23,8 +25,20 @@ const (
 	UserKey UserContextKey = "auth_user"
 	// ClaimsKey is the context key for storing JWT claims
 	ClaimsKey UserContextKey = "auth_claims"
+	// authResultKey is the context key of the memoized authResult
+	authResultKey UserContextKey = "auth_result"
 )
 
+// authResult is the outcome of authenticating a request. It is memoized in
+// the request context so that further middlewares, e.g. Optional followed by
+// RequireRole, do not validate the token again.
+type authResult struct {
+	authorization string
+	user          *models.UserProfile
+	claims        jwt.MapClaims
+	err           error
+}
+
 
 
 // extractTokenFromHeader extracts the JWT token from the Authorization header
@@ -39,12 +53,61 @@ func extractTokenFromHeader(r *http.Request) (string, error) {
 	if len(parts) != 2 || strings.ToLower(parts[0]) != "bearer" {
 		return "", NewAuthErrorWithDetails(ErrCodeMalformedToken, 
 			"Authorization header must be in format 'Bearer <token>'",
-			"Expected format: Authorization: Bearer <jwt-token>")
+			"Expected format: Authorization: Bearer <jwt-token>").withDetailsKey(MsgKeyMalformedAuthHdr)
 	}
 
 	return parts[1], nil
 }
 
+// extractAuthorization extracts the scheme and token from the Authorization header.
+// Both the Bearer and DPoP schemes are accepted; the scheme is returned in lower case.
+func extractAuthorization(r *http.Request) (string, string, error) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		return "", "", ErrMissingToken()
+	}
+
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) == 2 && strings.EqualFold(parts[0], "dpop") {
+		return "dpop", parts[1], nil
+	}
+
+	tokenString, err := extractTokenFromHeader(r)
+	return "bearer", tokenString, err
+}
+
+// authenticate validates the request's access token, enforcing DPoP and
+// certificate binding.
+func (m *Middleware) authenticate(r *http.Request) (*models.UserProfile, jwt.MapClaims, error) {
+	scheme, tokenString, err := extractAuthorization(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, claims, err := m.cache.lookup(tokenString, m.validateTokenAndGetUser, m.checkCachedToken)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := m.auth.dpop.checkRequest(r, scheme, tokenString, claims); err != nil {
+		return nil, nil, err
+	}
+	if err := m.auth.mtls.checkRequest(r, claims); err != nil {
+		return nil, nil, err
+	}
+
+	return user, claims, nil
+}
+
+// writeAuthError writes an authentication failure, adding the DPoP
+// WWW-Authenticate challenge when a proof was rejected.
+func (m *Middleware) writeAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	if authErr, ok := err.(*AuthError); ok && authErr.Code == ErrCodeInvalidDPoPProof {
+		w.Header().Set("WWW-Authenticate", `DPoP error="invalid_dpop_proof"`)
+	}
+	WriteLocalizedJSONError(w, r, err, m.auth.Translator())
+}
+
 // validateTokenAndGetUser validates a token and retrieves the associated user
 func (m *Middleware) validateTokenAndGetUser(tokenString string) (*models.UserProfile, jwt.MapClaims, error) {
 	// Validate the access token
@@ -57,7 +120,7 @@ func (m *Middleware) validateTokenAndGetUser(tokenString string) (*models.UserPr
 	userID, ok := claims["sub"].(string)
 	if !ok {
 		return nil, nil, NewAuthErrorWithDetails(ErrCodeInvalidToken, 
-			"Token missing user ID", "Token must contain a valid 'sub' claim")
+			"Token missing user ID", "Token must contain a valid 'sub' claim").withDetailsKey(MsgKeyMissingClaim, "sub")
 	}
 
 	// Check if token is blacklisted (if storage supports it)
@@ -77,33 +140,55 @@ func (m *Middleware) validateTokenAndGetUser(tokenString string) (*models.UserPr
 	if !user.IsActive {
 		return nil, nil, ErrUserInactive()
 	}
+	if err := checkAccountExpiry(user, m.auth.clock); err != nil {
+		return nil, nil, err
+	}
+	if err := checkLoggedOut(user.Metadata, claims); err != nil {
+		return nil, nil, err
+	}
 
 	return user, claims, nil
 }
 
+// checkCachedToken repeats the checks of ValidateAccessToken that do not
+// depend on the token alone for a token found in the validation cache.
+func (m *Middleware) checkCachedToken(claims jwt.MapClaims) error {
+	if m.auth.maintenance.check(maintenanceValidate) != nil || m.auth.sessions.touch(claims) != nil {
+		return ErrInvalidToken()
+	}
+	return nil
+}
+
+// resolve authenticates r once: the result is stored in the context of the
+// returned request, along with the user and claims on success, and reused
+// while the Authorization header stays the same.
+func (m *Middleware) resolve(r *http.Request) (*http.Request, *authResult) {
+	authorization := r.Header.Get("Authorization")
+	if result, ok := r.Context().Value(authResultKey).(*authResult); ok && result.authorization == authorization {
+		return r, result
+	}
+
+	user, claims, err := m.authenticate(r)
+	result := &authResult{authorization: authorization, user: user, claims: claims, err: err}
+	ctx := context.WithValue(r.Context(), authResultKey, result)
+	if err == nil {
+		ctx = context.WithValue(ctx, UserKey, user)
+		ctx = context.WithValue(ctx, ClaimsKey, claims)
+	}
+	return r.WithContext(ctx), result
+}
+
 // Protect is a generic HTTP middleware that requires authentication.
 // It validates the JWT token and injects user information into the request context.
 func (m *Middleware) Protect(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Extract token from Authorization header
-		tokenString, err := extractTokenFromHeader(r)
-		if err != nil {
-			WriteJSONError(w, err)
-			return
-		}
-
-		// Validate token and get user
-		user, claims, err := m.validateTokenAndGetUser(tokenString)
-		if err != nil {
-			WriteJSONError(w, err)
+		// Validate the token from the Authorization header and get user
+		r, result := m.resolve(r)
+		if result.err != nil {
+			m.writeAuthError(w, r, result.err)
 			return
 		}
 
-		// Add user and claims to request context
-		ctx := context.WithValue(r.Context(), UserKey, user)
-		ctx = context.WithValue(ctx, ClaimsKey, claims)
-		r = r.WithContext(ctx)
-
 		// Call the next handler
 		next.ServeHTTP(w, r)
 	})
@@ -114,32 +199,68 @@ func (m *Middleware) Protect(next http.Handler) http.Handler {
 // If no token or an invalid token is provided, it continues without authentication.
 func (m *Middleware) Optional(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Try to extract token from Authorization header
-		tokenString, err := extractTokenFromHeader(r)
-		if err != nil {
-			// No token provided or invalid format, continue without authentication
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		// Try to validate token and get user
-		user, claims, err := m.validateTokenAndGetUser(tokenString)
-		if err != nil {
-			// Invalid token, continue without authentication
-			next.ServeHTTP(w, r)
-			return
-		}
-
-		// Add user and claims to request context
-		ctx := context.WithValue(r.Context(), UserKey, user)
-		ctx = context.WithValue(ctx, ClaimsKey, claims)
-		r = r.WithContext(ctx)
+		// Try to validate the token; without a valid one the request
+		// continues unauthenticated
+		r, _ = m.resolve(r)
 
 		// Call the next handler
 		next.ServeHTTP(w, r)
 	})
 }
 
+// RequireRole returns a middleware that requires authentication and at
+// least one of roles in the "roles" claim. It reuses the result of an
+// earlier Protect or Optional on the same request.
+func (m *Middleware) RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return m.require(func(claims jwt.MapClaims) error {
+		granted := stringList(claims["roles"])
+		for _, role := range roles {
+			if slices.Contains(granted, role) {
+				return nil
+			}
+		}
+		return ErrPermissionDenied("Requires one of the roles: " + strings.Join(roles, ", "))
+	})
+}
+
+// RequireScopes returns a middleware that requires authentication and all
+// of scopes, read from the space-separated "scope" claim or the "scopes"
+// list. It reuses the result of an earlier Protect or Optional on the same
+// request.
+func (m *Middleware) RequireScopes(scopes ...string) func(http.Handler) http.Handler {
+	return m.require(func(claims jwt.MapClaims) error {
+		granted := stringList(claims["scopes"])
+		if scope, ok := claims["scope"].(string); ok {
+			granted = append(granted, strings.Fields(scope)...)
+		}
+		for _, scope := range scopes {
+			if !slices.Contains(granted, scope) {
+				return ErrPermissionDenied("Requires the scopes: " + strings.Join(scopes, " "))
+			}
+		}
+		return nil
+	})
+}
+
+// require returns a middleware that authenticates the request and rejects
+// claims for which check fails.
+func (m *Middleware) require(check func(jwt.MapClaims) error) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			r, result := m.resolve(r)
+			err := result.err
+			if err == nil {
+				err = check(result.claims)
+			}
+			if err != nil {
+				m.writeAuthError(w, r, err)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // GetUserFromContext retrieves the authenticated user from the request context
 func GetUserFromContext(ctx context.Context) (*models.UserProfile, bool) {
 	user, ok := ctx.Value(UserKey).(*models.UserProfile)
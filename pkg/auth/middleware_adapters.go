@@ -6,44 +6,47 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 	"github.com/pragneshbagary/go-auth/pkg/models"
 )
 
 // Framework-specific middleware adapters
 
+// unauthorizedResponse builds the JSON body for a request rejected by the Gin
+// and Fiber adapters, translated according to the Accept-Language header
+// value. It also returns the language used, if any, for Content-Language.
+func (m *Middleware) unauthorizedResponse(err error, acceptLanguage string) (HTTPErrorResponse, string) {
+	var authErr *AuthError
+	if !errors.As(err, &authErr) {
+		return HTTPErrorResponse{
+			Error:   "INTERNAL_ERROR",
+			Message: "An internal error occurred",
+			Code:    http.StatusUnauthorized,
+		}, ""
+	}
+
+	localized, lang := localizeAuthError(authErr, acceptLanguage, m.auth.Translator())
+	return HTTPErrorResponse{
+		Error:   localized.Code,
+		Message: localized.Message,
+		Code:    http.StatusUnauthorized,
+	}, lang
+}
+
 // Gin returns a Gin middleware function that requires authentication
 func (m *Middleware) Gin() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Extract token from Authorization header
-		tokenString, err := extractTokenFromHeader(c.Request)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, HTTPErrorResponse{
-				Error:   err.(*AuthError).Code,
-				Message: err.(*AuthError).Message,
-				Code:    http.StatusUnauthorized,
-			})
-			c.Abort()
-			return
-		}
-
-		// Validate token and get user
-		user, claims, err := m.validateTokenAndGetUser(tokenString)
+		// Validate the token from the Authorization header and get user
+		r, result := m.resolve(c.Request)
+		c.Request = r
+		user, claims, err := result.user, result.claims, result.err
 		if err != nil {
-			var authErr *AuthError
-			if errors.As(err, &authErr) {
-				c.JSON(http.StatusUnauthorized, HTTPErrorResponse{
-					Error:   authErr.Code,
-					Message: authErr.Message,
-					Code:    http.StatusUnauthorized,
-				})
-			} else {
-				c.JSON(http.StatusUnauthorized, HTTPErrorResponse{
-					Error:   "INTERNAL_ERROR",
-					Message: "An internal error occurred",
-					Code:    http.StatusUnauthorized,
-				})
+			response, lang := m.unauthorizedResponse(err, c.GetHeader("Accept-Language"))
+			if lang != "" {
+				c.Header("Content-Language", lang)
 			}
+			c.JSON(http.StatusUnauthorized, response)
 			c.Abort()
 			return
 		}
@@ -59,18 +62,12 @@ func (m *Middleware) Gin() gin.HandlerFunc {
 // GinOptional returns a Gin middleware function that optionally validates authentication
 func (m *Middleware) GinOptional() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Try to extract token from Authorization header
-		tokenString, err := extractTokenFromHeader(c.Request)
+		// Try to validate the token from the Authorization header and get user
+		r, result := m.resolve(c.Request)
+		c.Request = r
+		user, claims, err := result.user, result.claims, result.err
 		if err != nil {
-			// No token provided or invalid format, continue without authentication
-			c.Next()
-			return
-		}
-
-		// Try to validate token and get user
-		user, claims, err := m.validateTokenAndGetUser(tokenString)
-		if err != nil {
-			// Invalid token, continue without authentication
+			// No token, invalid format or invalid token, continue without authentication
 			c.Next()
 			return
 		}
@@ -149,40 +146,22 @@ func (m *Middleware) Fiber() fiber.Handler {
 		// Extract token from Authorization header
 		tokenString := c.Get("Authorization")
 		if tokenString == "" {
-			return c.Status(fiber.StatusUnauthorized).JSON(HTTPErrorResponse{
-				Error:   ErrCodeMissingToken,
-				Message: "Authorization header is required",
-				Code:    fiber.StatusUnauthorized,
-			})
+			return m.fiberUnauthorized(c, NewAuthError(ErrCodeMissingToken, "Authorization header is required"))
 		}
 
 		// Remove "Bearer " prefix
 		if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
 			tokenString = tokenString[7:]
 		} else {
-			return c.Status(fiber.StatusUnauthorized).JSON(HTTPErrorResponse{
-				Error:   ErrCodeInvalidToken,
-				Message: "Authorization header must be in format 'Bearer <token>'",
-				Code:    fiber.StatusUnauthorized,
-			})
+			return m.fiberUnauthorized(c, NewAuthError(ErrCodeInvalidToken,
+				"Authorization header must be in format 'Bearer <token>'"))
 		}
 
-		// Validate token and get user
-		user, claims, err := m.validateTokenAndGetUser(tokenString)
+		// Validate token and get user. Only the Bearer scheme is supported here,
+		// so DPoP-bound tokens are rejected.
+		user, claims, err := m.resolveFiber(c, tokenString)
 		if err != nil {
-			var authErr *AuthError
-			if errors.As(err, &authErr) {
-				return c.Status(fiber.StatusUnauthorized).JSON(HTTPErrorResponse{
-					Error:   authErr.Code,
-					Message: authErr.Message,
-					Code:    fiber.StatusUnauthorized,
-				})
-			}
-			return c.Status(fiber.StatusUnauthorized).JSON(HTTPErrorResponse{
-				Error:   "INTERNAL_ERROR",
-				Message: "An internal error occurred",
-				Code:    fiber.StatusUnauthorized,
-			})
+			return m.fiberUnauthorized(c, err)
 		}
 
 		// Store user and claims in Fiber context
@@ -193,6 +172,33 @@ func (m *Middleware) Fiber() fiber.Handler {
 	}
 }
 
+// fiberUnauthorized rejects a Fiber request with a 401 response for err.
+func (m *Middleware) fiberUnauthorized(c *fiber.Ctx, err error) error {
+	response, lang := m.unauthorizedResponse(err, c.Get("Accept-Language"))
+	if lang != "" {
+		c.Set("Content-Language", lang)
+	}
+	return c.Status(fiber.StatusUnauthorized).JSON(response)
+}
+
+// resolveFiber validates the Bearer token of c once per request, like
+// resolve. Only the Bearer scheme is supported, so DPoP-bound tokens are
+// rejected.
+func (m *Middleware) resolveFiber(c *fiber.Ctx, tokenString string) (*models.UserProfile, jwt.MapClaims, error) {
+	if result, ok := c.Locals(string(authResultKey)).(*authResult); ok && result.authorization == tokenString {
+		return result.user, result.claims, result.err
+	}
+	user, claims, err := m.cache.lookup(tokenString, m.validateTokenAndGetUser, m.checkCachedToken)
+	if err == nil {
+		err = m.auth.dpop.checkRequest(nil, "bearer", tokenString, claims)
+	}
+	if err != nil {
+		user, claims = nil, nil
+	}
+	c.Locals(string(authResultKey), &authResult{authorization: tokenString, user: user, claims: claims, err: err})
+	return user, claims, err
+}
+
 // FiberOptional returns a Fiber middleware function that optionally validates authentication
 func (m *Middleware) FiberOptional() fiber.Handler {
 	return func(c *fiber.Ctx) error {
@@ -212,7 +218,7 @@ func (m *Middleware) FiberOptional() fiber.Handler {
 		}
 
 		// Try to validate token and get user
-		user, claims, err := m.validateTokenAndGetUser(tokenString)
+		user, claims, err := m.resolveFiber(c, tokenString)
 		if err != nil {
 			// Invalid token, continue without authentication
 			return c.Next()
@@ -226,46 +232,161 @@ func (m *Middleware) FiberOptional() fiber.Handler {
 	}
 }
 
+// CSRF adapters
+
+// errorResponse builds the JSON body for a rejected CSRF check, translated
+// according to the Accept-Language header value. It also returns the language
+// used, if any, for Content-Language.
+func (c *CSRF) errorResponse(err error, acceptLanguage string) (HTTPErrorResponse, string) {
+	response := HTTPErrorResponse{
+		Error:   ErrCodeInvalidCSRFToken,
+		Message: "Invalid CSRF token",
+		Code:    http.StatusForbidden,
+	}
+	var authErr *AuthError
+	if !errors.As(err, &authErr) {
+		return response, ""
+	}
+
+	localized, lang := localizeAuthError(authErr, acceptLanguage, c.translator.get())
+	response.Error = localized.Code
+	response.Message = localized.Message
+	response.Details = localized.Details
+	return response, lang
+}
+
+// Gin returns a Gin middleware function that verifies CSRF tokens on
+// state-changing requests and stores a fresh token under "csrf_token".
+// Without a custom Binding, tokens are bound to the user set by Middleware.Gin.
+func (c *CSRF) Gin() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		binding := c.config.Binding(ctx.Request)
+		if binding == "" {
+			if user, ok := GetUserFromGin(ctx); ok {
+				binding = user.ID
+			}
+		}
+
+		token, err := c.check(ctx.Request.Method, c.TokenFromRequest(ctx.Request), binding)
+		if err != nil {
+			response, lang := c.errorResponse(err, ctx.GetHeader("Accept-Language"))
+			if lang != "" {
+				ctx.Header("Content-Language", lang)
+			}
+			ctx.AbortWithStatusJSON(http.StatusForbidden, response)
+			return
+		}
+
+		if token != "" {
+			ctx.Header(c.config.HeaderName, token)
+			ctx.Set("csrf_token", token)
+		}
+		ctx.Next()
+	}
+}
+
+// Echo returns an Echo middleware function that verifies CSRF tokens on
+// state-changing requests and stores a fresh token under "csrf_token".
+// Without a custom Binding, tokens are bound to the user set by Middleware.Echo.
+func (c *CSRF) Echo() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(ctx echo.Context) error {
+			binding := c.config.Binding(ctx.Request())
+			if binding == "" {
+				if user, ok := GetUserFromEcho(ctx); ok {
+					binding = user.ID
+				}
+			}
+
+			token, err := c.check(ctx.Request().Method, c.TokenFromRequest(ctx.Request()), binding)
+			if err != nil {
+				response, lang := c.errorResponse(err, ctx.Request().Header.Get("Accept-Language"))
+				if lang != "" {
+					ctx.Response().Header().Set("Content-Language", lang)
+				}
+				return ctx.JSON(http.StatusForbidden, response)
+			}
+
+			if token != "" {
+				ctx.Response().Header().Set(c.config.HeaderName, token)
+				ctx.Set("csrf_token", token)
+			}
+			return next(ctx)
+		}
+	}
+}
+
+// Fiber returns a Fiber middleware function that verifies CSRF tokens on
+// state-changing requests and stores a fresh token in Locals under "csrf_token".
+// Tokens are bound to the user set by Middleware.Fiber; the Binding option
+// does not apply because Fiber does not use net/http requests.
+func (c *CSRF) Fiber() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		var binding string
+		if user, ok := GetUserFromFiber(ctx); ok {
+			binding = user.ID
+		}
+
+		submitted := ctx.Get(c.config.HeaderName)
+		if submitted == "" {
+			submitted = ctx.FormValue(c.config.FormField)
+		}
+
+		token, err := c.check(ctx.Method(), submitted, binding)
+		if err != nil {
+			response, lang := c.errorResponse(err, ctx.Get("Accept-Language"))
+			if lang != "" {
+				ctx.Set("Content-Language", lang)
+			}
+			return ctx.Status(fiber.StatusForbidden).JSON(response)
+		}
+
+		if token != "" {
+			ctx.Set(c.config.HeaderName, token)
+			ctx.Locals("csrf_token", token)
+		}
+		return ctx.Next()
+	}
+}
+
 // Helper functions for framework-specific contexts
 
-// GetUserFromGin retrieves the authenticated user from Gin context
+// GetUserFromGin retrieves the authenticated user from Gin context, or from
+// the request context if a net/http middleware authenticated the request
 func GetUserFromGin(c *gin.Context) (*models.UserProfile, bool) {
-	user, exists := c.Get("user")
-	if !exists {
-		return nil, false
+	if user, ok := c.Get("user"); ok {
+		userProfile, ok := user.(*models.UserProfile)
+		return userProfile, ok
 	}
-	userProfile, ok := user.(*models.UserProfile)
-	return userProfile, ok
+	return GetUserFromContext(c.Request.Context())
 }
 
-// GetClaimsFromGin retrieves the JWT claims from Gin context
+// GetClaimsFromGin retrieves the JWT claims from Gin context, or from the
+// request context if a net/http middleware authenticated the request
 func GetClaimsFromGin(c *gin.Context) (map[string]interface{}, bool) {
-	claims, exists := c.Get("claims")
-	if !exists {
-		return nil, false
+	if claims, ok := c.Get("claims"); ok {
+		return claimsMap(claims)
 	}
-	claimsMap, ok := claims.(map[string]interface{})
-	return claimsMap, ok
+	return claimsMap(c.Request.Context().Value(ClaimsKey))
 }
 
-// GetUserFromEcho retrieves the authenticated user from Echo context
+// GetUserFromEcho retrieves the authenticated user from Echo context, or
+// from the request context if a net/http middleware authenticated the request
 func GetUserFromEcho(c echo.Context) (*models.UserProfile, bool) {
-	user := c.Get("user")
-	if user == nil {
-		return nil, false
+	if user := c.Get("user"); user != nil {
+		userProfile, ok := user.(*models.UserProfile)
+		return userProfile, ok
 	}
-	userProfile, ok := user.(*models.UserProfile)
-	return userProfile, ok
+	return GetUserFromContext(c.Request().Context())
 }
 
-// GetClaimsFromEcho retrieves the JWT claims from Echo context
+// GetClaimsFromEcho retrieves the JWT claims from Echo context, or from the
+// request context if a net/http middleware authenticated the request
 func GetClaimsFromEcho(c echo.Context) (map[string]interface{}, bool) {
-	claims := c.Get("claims")
-	if claims == nil {
-		return nil, false
+	if claims := c.Get("claims"); claims != nil {
+		return claimsMap(claims)
 	}
-	claimsMap, ok := claims.(map[string]interface{})
-	return claimsMap, ok
+	return claimsMap(c.Request().Context().Value(ClaimsKey))
 }
 
 // GetUserFromFiber retrieves the authenticated user from Fiber context
@@ -280,10 +401,16 @@ func GetUserFromFiber(c *fiber.Ctx) (*models.UserProfile, bool) {
 
 // GetClaimsFromFiber retrieves the JWT claims from Fiber context
 func GetClaimsFromFiber(c *fiber.Ctx) (map[string]interface{}, bool) {
-	claims := c.Locals("claims")
-	if claims == nil {
-		return nil, false
+	return claimsMap(c.Locals("claims"))
+}
+
+// claimsMap returns claims stored by a middleware as a plain map.
+func claimsMap(claims interface{}) (map[string]interface{}, bool) {
+	switch claims := claims.(type) {
+	case jwt.MapClaims:
+		return claims, true
+	case map[string]interface{}:
+		return claims, true
 	}
-	claimsMap, ok := claims.(map[string]interface{})
-	return claimsMap, ok
+	return nil, false
 }
\ No newline at end of file
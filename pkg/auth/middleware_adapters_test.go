@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -315,4 +316,55 @@ func TestGetUserFromFrameworks(t *testing.T) {
 
 	// Note: Fiber context testing is more complex due to its internal structure
 	// The Fiber middleware functionality is tested in the integration test above
-}
\ No newline at end of file
+}
+func TestMiddlewareAdaptersLocalizeErrors(t *testing.T) {
+	ta := NewTestAuth(t)
+	catalog := DefaultMessageCatalog()
+	catalog.AddMessages("fr", map[string]string{
+		ErrCodeMissingToken: "Un jeton d'autorisation est requis",
+	})
+	ta.SetTranslator(catalog)
+	middleware := ta.Middleware()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/test", middleware.Gin(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Accept-Language", "fr")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	var resp HTTPErrorResponse
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode Gin response: %v", err)
+	}
+	if resp.Message != "Un jeton d'autorisation est requis" {
+		t.Errorf("Expected French message from Gin, got %s", resp.Message)
+	}
+	if w.Header().Get("Content-Language") != "fr" {
+		t.Errorf("Expected Content-Language fr from Gin, got %s", w.Header().Get("Content-Language"))
+	}
+
+	app := fiber.New()
+	app.Get("/test", middleware.Fiber(), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	req = httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Accept-Language", "fr")
+	fiberResp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("Failed to execute request: %v", err)
+	}
+	resp = HTTPErrorResponse{}
+	if err := json.NewDecoder(fiberResp.Body).Decode(&resp); err != nil {
+		t.Fatalf("Failed to decode Fiber response: %v", err)
+	}
+	if resp.Message != "Un jeton d'autorisation est requis" {
+		t.Errorf("Expected French message from Fiber, got %s", resp.Message)
+	}
+	if fiberResp.Header.Get("Content-Language") != "fr" {
+		t.Errorf("Expected Content-Language fr from Fiber, got %s", fiberResp.Header.Get("Content-Language"))
+	}
+}
@@ -356,4 +356,51 @@ func TestAuth_DirectMiddlewareMethods(t *testing.T) {
 	if optionalHandler == nil {
 		t.Error("Expected optional handler, got nil")
 	}
-}
\ No newline at end of file
+}
+func TestRequireRoleReusesAuthentication(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("alice", "alice-password", "editor")
+	token := ta.LoginAs("alice").AccessToken
+
+	// The cache counts validations; the chain must validate only once
+	middleware := ta.Middleware().WithValidationCache(ValidationCacheConfig{TTL: time.Hour})
+	var user *models.UserProfile
+	handler := middleware.Optional(middleware.RequireRole("admin", "editor")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, _ = GetUserFromContext(r.Context())
+	})))
+
+	if code := serveWithToken(handler, token); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if user == nil || user.Username != "alice" {
+		t.Errorf("Expected the user in the context, got %+v", user)
+	}
+	if stats := middleware.CacheStats(); stats.Misses != 1 || stats.Hits != 0 {
+		t.Errorf("Expected the token to be validated once, got %+v", stats)
+	}
+
+	if code := serveWithToken(middleware.RequireRole("admin")(handler), token); code != http.StatusForbidden {
+		t.Errorf("Expected 403 without the role, got %d", code)
+	}
+	if code := serveWithToken(middleware.RequireRole("editor")(handler), "invalid"); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an invalid token, got %d", code)
+	}
+}
+
+func TestRequireScopes(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("alice", "alice-password")
+	result, err := ta.Login("alice", "alice-password", map[string]interface{}{"scope": "read write"})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	middleware := ta.Middleware()
+	if code := serveWithToken(middleware.RequireScopes("read", "write")(ok), result.AccessToken); code != http.StatusOK {
+		t.Errorf("Expected 200 with the scopes, got %d", code)
+	}
+	if code := serveWithToken(middleware.RequireScopes("read", "admin")(ok), result.AccessToken); code != http.StatusForbidden {
+		t.Errorf("Expected 403 without all scopes, got %d", code)
+	}
+}
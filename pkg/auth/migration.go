@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"database/sql"
 	"fmt"
 	"sort"
 
@@ -14,6 +15,13 @@ type MigrationStep struct {
 	Description string
 	Up          func(storage.EnhancedStorage) error
 	Down        func(storage.EnhancedStorage) error
+	// SQL previews the changes of the step in Plan. It is informational only.
+	SQL string
+
+	// upTx and downTx are set for custom migrations, which run in the
+	// transaction that records them.
+	upTx   func(tx *sql.Tx) error
+	downTx func(tx *sql.Tx) error
 }
 
 // MigrationManager handles database schema migrations
@@ -44,8 +52,46 @@ func (mm *MigrationManager) RegisterMigration(step MigrationStep) {
 	})
 }
 
-// Migrate runs all pending migrations up to the latest version
-func (mm *MigrationManager) Migrate() error {
+// RegisterCustomMigration adds an application migration, such as one
+// creating roles or organization tables, to the migration stream of the
+// go-auth schema. up and down run in the transaction that records or removes
+// the migration, so a failing migration leaves neither the tables nor the
+// record changed. down may be nil if the migration cannot be rolled back.
+//
+// Versions share one sequence with the built-in migrations and must be
+// unique; the storage must implement storage.TxMigrationStorage, as the SQL
+// storages do.
+func (mm *MigrationManager) RegisterCustomMigration(version int, up, down func(tx *sql.Tx) error) error {
+	if version <= 0 {
+		return fmt.Errorf("invalid migration version %d", version)
+	}
+	if up == nil {
+		return fmt.Errorf("migration %d has no up function", version)
+	}
+	for _, step := range mm.steps {
+		if step.Version == version {
+			return fmt.Errorf("migration %d is already registered", version)
+		}
+	}
+
+	step := MigrationStep{
+		Version:     version,
+		Description: fmt.Sprintf("Custom migration %d", version),
+		upTx:        up,
+		downTx:      down,
+	}
+	mm.RegisterMigration(step)
+	return nil
+}
+
+// Migrate runs all pending migrations up to the latest version. With
+// WithDryRun it only validates them, see WithDryRun.
+func (mm *MigrationManager) Migrate(opts ...MigrateOption) error {
+	var options migrateOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	currentVersion, err := mm.storage.GetSchemaVersion()
 	if err != nil {
 		return fmt.Errorf("failed to get current schema version: %w", err)
@@ -63,6 +109,10 @@ func (mm *MigrationManager) Migrate() error {
 		return nil // No migrations to run
 	}
 
+	if options.dryRun {
+		return mm.dryRun(pendingMigrations)
+	}
+
 	// Apply each pending migration
 	for _, migration := range pendingMigrations {
 		if err := mm.applyMigration(migration); err != nil {
@@ -148,6 +198,14 @@ func (mm *MigrationManager) GetAppliedMigrations() ([]models.Migration, error) {
 
 // applyMigration applies a single migration and records it
 func (mm *MigrationManager) applyMigration(migration MigrationStep) error {
+	if migration.upTx != nil {
+		store, err := mm.txMigrationStorage()
+		if err != nil {
+			return err
+		}
+		return store.ApplyMigrationTx(migration.Version, migration.Description, migration.upTx)
+	}
+
 	// Apply the migration
 	if err := migration.Up(mm.storage); err != nil {
 		return err
@@ -159,6 +217,17 @@ func (mm *MigrationManager) applyMigration(migration MigrationStep) error {
 
 // rollbackMigration rolls back a single migration
 func (mm *MigrationManager) rollbackMigration(migration MigrationStep) error {
+	if migration.upTx != nil {
+		if migration.downTx == nil {
+			return fmt.Errorf("migration %d does not support rollback", migration.Version)
+		}
+		store, err := mm.txMigrationStorage()
+		if err != nil {
+			return err
+		}
+		return store.RevertMigrationTx(migration.Version, migration.downTx)
+	}
+
 	if migration.Down == nil {
 		return fmt.Errorf("migration %d does not support rollback", migration.Version)
 	}
@@ -172,6 +241,14 @@ func (mm *MigrationManager) rollbackMigration(migration MigrationStep) error {
 	return mm.removeMigrationRecord(migration)
 }
 
+// txMigrationStorage returns the TxMigrationStorage behind the storage.
+func (mm *MigrationManager) txMigrationStorage() (storage.TxMigrationStorage, error) {
+	if store, ok := baseStorage(mm.storage).(storage.TxMigrationStorage); ok {
+		return store, nil
+	}
+	return nil, fmt.Errorf("storage does not support custom migrations")
+}
+
 // recordMigration records a migration as applied in the database
 func (mm *MigrationManager) recordMigration(migration MigrationStep) error {
 	return mm.storage.RecordMigration(migration.Version, migration.Description)
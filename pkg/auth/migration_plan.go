@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// PlannedMigration describes a pending migration step.
+type PlannedMigration struct {
+	Version     int
+	Description string
+	// SQL previews the changes of the step, empty if it was not given.
+	SQL string
+	// Reversible reports whether the step can be rolled back.
+	Reversible bool
+	// DryRunnable reports whether the step runs in a transaction, and so
+	// is executed by a dry run.
+	DryRunnable bool
+}
+
+// MigrateOption configures Migrate.
+type MigrateOption func(*migrateOptions)
+
+type migrateOptions struct {
+	dryRun bool
+}
+
+// WithDryRun makes Migrate validate the pending migrations against the
+// database without applying them: the transactional steps run in order in
+// one transaction that is rolled back. Steps registered with
+// RegisterMigration run Go code on the storage and are skipped.
+func WithDryRun() MigrateOption {
+	return func(o *migrateOptions) {
+		o.dryRun = true
+	}
+}
+
+// Plan returns the pending migrations in the order Migrate applies them,
+// for review before they run.
+func (mm *MigrationManager) Plan() ([]PlannedMigration, error) {
+	pending, err := mm.GetPendingMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := make([]PlannedMigration, 0, len(pending))
+	for _, step := range pending {
+		plan = append(plan, PlannedMigration{
+			Version:     step.Version,
+			Description: step.Description,
+			SQL:         step.SQL,
+			Reversible:  step.Down != nil || step.downTx != nil,
+			DryRunnable: step.upTx != nil,
+		})
+	}
+	return plan, nil
+}
+
+// RegisterCustomMigrationSQL is RegisterCustomMigration for migrations
+// written as SQL. upSQL is shown by Plan; downSQL may be empty if the
+// migration cannot be rolled back.
+func (mm *MigrationManager) RegisterCustomMigrationSQL(version int, upSQL, downSQL string) error {
+	var down func(tx *sql.Tx) error
+	if downSQL != "" {
+		down = execSQL(downSQL)
+	}
+	if err := mm.RegisterCustomMigration(version, execSQL(upSQL), down); err != nil {
+		return err
+	}
+	for i := range mm.steps {
+		if mm.steps[i].Version == version {
+			mm.steps[i].SQL = upSQL
+		}
+	}
+	return nil
+}
+
+// execSQL returns a migration function executing query.
+func execSQL(query string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec(query)
+		return err
+	}
+}
+
+// dryRun runs the transactional steps of pending in a transaction that is
+// rolled back.
+func (mm *MigrationManager) dryRun(pending []MigrationStep) error {
+	steps := make([]MigrationStep, 0, len(pending))
+	for _, step := range pending {
+		if step.upTx != nil {
+			steps = append(steps, step)
+		}
+	}
+	if len(steps) == 0 {
+		return nil
+	}
+
+	store, err := mm.txMigrationStorage()
+	if err != nil {
+		return err
+	}
+	return store.DryRunTx(func(tx *sql.Tx) error {
+		for _, step := range steps {
+			if err := step.upTx(tx); err != nil {
+				return fmt.Errorf("migration %d failed dry run: %w", step.Version, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/pragneshbagary/go-auth/internal/storage/sqlite"
+)
+
+func TestMigrationManager_Plan(t *testing.T) {
+	store, err := sqlite.NewInMemorySQLiteStorage(t.Name())
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	mm := NewMigrationManager(store)
+	if err := mm.RegisterCustomMigrationSQL(2, "CREATE TABLE orgs (id TEXT PRIMARY KEY)", "DROP TABLE orgs"); err != nil {
+		t.Fatalf("RegisterCustomMigrationSQL failed: %v", err)
+	}
+
+	plan, err := mm.Plan()
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+	if len(plan) != 2 || plan[0].Version != 1 || plan[1].Version != 2 {
+		t.Fatalf("Unexpected plan: %+v", plan)
+	}
+	if plan[1].SQL != "CREATE TABLE orgs (id TEXT PRIMARY KEY)" || !plan[1].Reversible || !plan[1].DryRunnable {
+		t.Errorf("Unexpected planned custom migration: %+v", plan[1])
+	}
+	if plan[0].DryRunnable {
+		t.Error("Expected the built-in migration not to be dry-runnable")
+	}
+}
+
+func TestMigrationManager_DryRun(t *testing.T) {
+	store, err := sqlite.NewInMemorySQLiteStorage(t.Name())
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	mm := NewMigrationManager(store)
+	mm.RegisterCustomMigrationSQL(2, "CREATE TABLE orgs (id TEXT PRIMARY KEY)", "")
+	// Depends on the table of migration 2
+	mm.RegisterCustomMigrationSQL(3, "CREATE INDEX idx_orgs_id ON orgs(id)", "")
+
+	if err := mm.Migrate(WithDryRun()); err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if version, _ := mm.GetCurrentVersion(); version != 0 {
+		t.Errorf("Expected a dry run not to apply migrations, got version %d", version)
+	}
+
+	// The dry run left nothing behind, so the migrations apply cleanly
+	if err := mm.Migrate(); err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if version, _ := mm.GetCurrentVersion(); version != 3 {
+		t.Errorf("Expected version 3, got %d", version)
+	}
+
+	broken := NewMigrationManager(store)
+	broken.RegisterCustomMigration(4, func(tx *sql.Tx) error {
+		_, err := tx.Exec("ALTER TABLE missing ADD COLUMN name TEXT")
+		return err
+	}, nil)
+	if err := broken.Migrate(WithDryRun()); err == nil {
+		t.Error("Expected the dry run to report the failing migration")
+	}
+	if version, _ := broken.GetCurrentVersion(); version != 3 {
+		t.Errorf("Expected version 3 after the dry run, got %d", version)
+	}
+}
@@ -0,0 +1,267 @@
+package auth
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// authImportPath is the import path of this package, whose v1 API
+// RewriteFile migrates.
+const authImportPath = "github.com/pragneshbagary/go-auth/pkg/auth"
+
+// rewriteDatabasePath is the SQLite database path of rewritten
+// NewAuthService calls.
+const rewriteDatabasePath = "auth.db"
+
+// renamedTypes are the v1 types RewriteFile replaces with their v2 names.
+var renamedTypes = map[string]string{
+	"RegisterPayload": "RegisterRequest",
+	"LoginResponse":   "LoginResult",
+}
+
+// RewriteResult describes the mechanical changes RewriteFile made, or in a
+// preview would make, to one file.
+type RewriteResult struct {
+	Path string
+	// Changes lists the rewrites by line, e.g. "Line 12: auth.LoginResponse -> auth.LoginResult".
+	Changes []string
+	// Diff shows the changed lines of the file.
+	Diff string
+	// BackupPath holds the original file once applied with backups.
+	BackupPath string
+}
+
+// RewriteFile rewrites the mechanical v1 to v2 API changes in a Go file:
+// RegisterPayload becomes RegisterRequest, LoginResponse becomes
+// LoginResult and NewAuthService(cfg) becomes
+// NewSQLite("auth.db", string(cfg.JWT.AccessSecret)). Only references
+// through an import of this package are changed.
+//
+// Unless apply is set the file is left unchanged and the result previews
+// the rewrite. With apply and backup set the original is kept next to it
+// with a .bak suffix. It returns nil if nothing needs rewriting.
+func (t *CodeMigrationTool) RewriteFile(filePath string, apply, backup bool) (*RewriteResult, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file %s: %w", filePath, err)
+	}
+	original, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", filePath, err)
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, filePath, original, parser.ParseComments)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse file %s: %w", filePath, err)
+	}
+	changes := rewriteAuthAPI(fset, file)
+	if len(changes) == 0 {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return nil, fmt.Errorf("failed to format file %s: %w", filePath, err)
+	}
+	result := &RewriteResult{
+		Path:    filePath,
+		Changes: changes,
+		Diff:    lineDiff(string(original), buf.String()),
+	}
+	if !apply {
+		return result, nil
+	}
+
+	if backup {
+		result.BackupPath = filePath + ".bak"
+		if err := os.WriteFile(result.BackupPath, original, info.Mode().Perm()); err != nil {
+			return nil, fmt.Errorf("failed to back up file %s: %w", filePath, err)
+		}
+	}
+	if err := os.WriteFile(filePath, buf.Bytes(), info.Mode().Perm()); err != nil {
+		return nil, fmt.Errorf("failed to write file %s: %w", filePath, err)
+	}
+	return result, nil
+}
+
+// RewriteProject runs RewriteFile on every Go file of a project, skipping
+// vendor and .git directories, and returns the results of the files that
+// need rewriting.
+func (t *CodeMigrationTool) RewriteProject(projectPath string, apply, backup bool) ([]*RewriteResult, error) {
+	var results []*RewriteResult
+	err := filepath.Walk(projectPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			if info.Name() == "vendor" || info.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+
+		result, err := t.RewriteFile(path, apply, backup)
+		if err != nil {
+			return err
+		}
+		if result != nil {
+			results = append(results, result)
+		}
+		return nil
+	})
+	return results, err
+}
+
+// Print outputs the changes and diff of the result to stdout.
+func (r *RewriteResult) Print() {
+	fmt.Printf("📁 %s\n", r.Path)
+	for _, change := range r.Changes {
+		fmt.Printf("   %s\n", change)
+	}
+	fmt.Println()
+	fmt.Print(r.Diff)
+	if r.BackupPath != "" {
+		fmt.Printf("   Backup: %s\n", r.BackupPath)
+	}
+	fmt.Println()
+}
+
+// rewriteAuthAPI rewrites the v1 API references of file in place and
+// returns a description of each change.
+func rewriteAuthAPI(fset *token.FileSet, file *ast.File) []string {
+	name := authImportName(file)
+	if name == "" {
+		return nil
+	}
+	isAuth := func(expr ast.Expr, sel string) (*ast.SelectorExpr, bool) {
+		selector, ok := expr.(*ast.SelectorExpr)
+		if !ok {
+			return nil, false
+		}
+		ident, ok := selector.X.(*ast.Ident)
+		return selector, ok && ident.Name == name && (sel == "" || selector.Sel.Name == sel)
+	}
+
+	var changes []string
+	record := func(pos token.Pos, from, to string) {
+		changes = append(changes, fmt.Sprintf("Line %d: %s.%s -> %s.%s", fset.Position(pos).Line, name, from, name, to))
+	}
+	ast.Inspect(file, func(node ast.Node) bool {
+		switch n := node.(type) {
+		case *ast.CallExpr:
+			// NewAuthService(cfg) -> NewSQLite("auth.db", string(cfg.JWT.AccessSecret))
+			selector, ok := isAuth(n.Fun, "NewAuthService")
+			if !ok || len(n.Args) != 1 {
+				return true
+			}
+			record(selector.Pos(), "NewAuthService", "NewSQLite")
+			selector.Sel.Name = "NewSQLite"
+			secret := &ast.SelectorExpr{
+				X:   &ast.SelectorExpr{X: n.Args[0], Sel: ast.NewIdent("JWT")},
+				Sel: ast.NewIdent("AccessSecret"),
+			}
+			n.Args = []ast.Expr{
+				&ast.BasicLit{Kind: token.STRING, Value: strconv.Quote(rewriteDatabasePath)},
+				&ast.CallExpr{Fun: ast.NewIdent("string"), Args: []ast.Expr{secret}},
+			}
+		case *ast.SelectorExpr:
+			if _, ok := isAuth(n, ""); !ok {
+				return true
+			}
+			if renamed, ok := renamedTypes[n.Sel.Name]; ok {
+				record(n.Pos(), n.Sel.Name, renamed)
+				n.Sel.Name = renamed
+			}
+		}
+		return true
+	})
+	return changes
+}
+
+// authImportName returns the name this package is imported as in file,
+// or "" if it is not imported by name.
+func authImportName(file *ast.File) string {
+	for _, spec := range file.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil || path != authImportPath {
+			continue
+		}
+		if spec.Name == nil {
+			return "auth"
+		}
+		if spec.Name.Name == "_" || spec.Name.Name == "." {
+			return ""
+		}
+		return spec.Name.Name
+	}
+	return ""
+}
+
+// lineDiff returns the lines that differ between before and after, as
+// "-" and "+" lines below a "@@ line N @@" header per changed block.
+func lineDiff(before, after string) string {
+	a := strings.Split(before, "\n")
+	b := strings.Split(after, "\n")
+
+	// Trim the common prefix and suffix, then diff the middle
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a, b = a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+
+	// Longest common subsequence of the middle lines
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out strings.Builder
+	inBlock := false
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			inBlock = false
+			i++
+			j++
+			continue
+		case !inBlock:
+			fmt.Fprintf(&out, "@@ line %d @@\n", prefix+i+1)
+			inBlock = true
+		}
+		if j >= len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]) {
+			fmt.Fprintf(&out, "-%s\n", a[i])
+			i++
+		} else {
+			fmt.Fprintf(&out, "+%s\n", b[j])
+			j++
+		}
+	}
+	return out.String()
+}
@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const v1Source = `package main
+
+import (
+	goauth "github.com/pragneshbagary/go-auth/pkg/auth"
+)
+
+func main() {
+	cfg := goauth.Config{}
+	svc, _ := goauth.NewAuthService(cfg)
+	var res *goauth.LoginResponse
+	_ = goauth.RegisterPayload{Username: "alice"}
+	_, _ = svc, res
+}
+`
+
+func writeV1File(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "main.go")
+	if err := os.WriteFile(path, []byte(v1Source), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	return path
+}
+
+func TestRewriteFile_Preview(t *testing.T) {
+	path := writeV1File(t)
+	tool := NewCodeMigrationTool()
+
+	result, err := tool.RewriteFile(path, false, true)
+	if err != nil {
+		t.Fatalf("RewriteFile failed: %v", err)
+	}
+	if result == nil {
+		t.Fatal("Expected a rewrite result")
+	}
+	if len(result.Changes) != 3 {
+		t.Errorf("Expected 3 changes, got %d: %v", len(result.Changes), result.Changes)
+	}
+	if !strings.Contains(result.Diff, "+\tvar res *goauth.LoginResult") {
+		t.Errorf("Expected diff to show LoginResult rename, got:\n%s", result.Diff)
+	}
+	if !strings.Contains(result.Diff, "-\tsvc, _ := goauth.NewAuthService(cfg)") {
+		t.Errorf("Expected diff to show removed NewAuthService call, got:\n%s", result.Diff)
+	}
+
+	content, _ := os.ReadFile(path)
+	if string(content) != v1Source {
+		t.Error("Expected preview to leave the file unchanged")
+	}
+	if result.BackupPath != "" {
+		t.Error("Expected no backup for a preview")
+	}
+}
+
+func TestRewriteFile_Apply(t *testing.T) {
+	path := writeV1File(t)
+	tool := NewCodeMigrationTool()
+
+	result, err := tool.RewriteFile(path, true, true)
+	if err != nil {
+		t.Fatalf("RewriteFile failed: %v", err)
+	}
+
+	content, _ := os.ReadFile(path)
+	rewritten := string(content)
+	for _, want := range []string{
+		`goauth.NewSQLite("auth.db", string(cfg.JWT.AccessSecret))`,
+		`*goauth.LoginResult`,
+		`goauth.RegisterRequest{Username: "alice"}`,
+	} {
+		if !strings.Contains(rewritten, want) {
+			t.Errorf("Expected rewritten file to contain %q, got:\n%s", want, rewritten)
+		}
+	}
+
+	backup, err := os.ReadFile(result.BackupPath)
+	if err != nil {
+		t.Fatalf("Expected backup file: %v", err)
+	}
+	if string(backup) != v1Source {
+		t.Error("Expected backup to hold the original source")
+	}
+
+	// A second run finds nothing left to rewrite
+	again, err := tool.RewriteFile(path, true, true)
+	if err != nil {
+		t.Fatalf("RewriteFile failed: %v", err)
+	}
+	if again != nil {
+		t.Errorf("Expected no further changes, got %v", again.Changes)
+	}
+}
+
+func TestRewriteFile_IgnoresOtherPackages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "other.go")
+	source := `package other
+
+import "example.com/auth"
+
+var _ auth.LoginResponse
+`
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	result, err := NewCodeMigrationTool().RewriteFile(path, true, false)
+	if err != nil {
+		t.Fatalf("RewriteFile failed: %v", err)
+	}
+	if result != nil {
+		t.Errorf("Expected no rewrite outside this package, got %v", result.Changes)
+	}
+}
+
+func TestRewriteProject_NoBackup(t *testing.T) {
+	path := writeV1File(t)
+	dir := filepath.Dir(path)
+	if err := os.WriteFile(filepath.Join(dir, "clean.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	results, err := NewCodeMigrationTool().RewriteProject(dir, true, false)
+	if err != nil {
+		t.Fatalf("RewriteProject failed: %v", err)
+	}
+	if len(results) != 1 || results[0].Path != path {
+		t.Fatalf("Expected only %s to be rewritten, got %d results", path, len(results))
+	}
+	if _, err := os.Stat(path + ".bak"); !os.IsNotExist(err) {
+		t.Error("Expected no backup file")
+	}
+}
@@ -1,9 +1,12 @@
 package auth
 
 import (
+	"database/sql"
+	"errors"
 	"testing"
 
 	"github.com/pragneshbagary/go-auth/internal/storage/memory"
+	"github.com/pragneshbagary/go-auth/internal/storage/sqlite"
 	"github.com/pragneshbagary/go-auth/pkg/storage"
 )
 
@@ -214,4 +217,83 @@ func TestMigrationManager_Integration_WithAuth(t *testing.T) {
 	if version != 10 {
 		t.Errorf("Expected version 10, got %d", version)
 	}
-}
\ No newline at end of file
+}
+func TestMigrationManager_RegisterCustomMigration(t *testing.T) {
+	store, err := sqlite.NewInMemorySQLiteStorage(t.Name())
+	if err != nil {
+		t.Fatalf("Failed to create storage: %v", err)
+	}
+	defer store.Close()
+
+	createRoles := func(tx *sql.Tx) error {
+		_, err := tx.Exec("CREATE TABLE roles (user_id TEXT NOT NULL REFERENCES users(id), role TEXT NOT NULL)")
+		return err
+	}
+	dropRoles := func(tx *sql.Tx) error {
+		_, err := tx.Exec("DROP TABLE roles")
+		return err
+	}
+	createOrgs := func(tx *sql.Tx) error {
+		_, err := tx.Exec("CREATE TABLE orgs (id TEXT PRIMARY KEY)")
+		return err
+	}
+
+	mm := NewMigrationManager(store)
+	if err := mm.RegisterCustomMigration(2, createRoles, dropRoles); err != nil {
+		t.Fatalf("RegisterCustomMigration failed: %v", err)
+	}
+	if err := mm.RegisterCustomMigration(2, createRoles, nil); err == nil {
+		t.Error("Expected an error for a duplicate version")
+	}
+	if err := mm.RegisterCustomMigration(1, createRoles, nil); err == nil {
+		t.Error("Expected an error for a built-in version")
+	}
+
+	failed := errors.New("boom")
+	if err := mm.RegisterCustomMigration(3, func(tx *sql.Tx) error {
+		if err := createOrgs(tx); err != nil {
+			return err
+		}
+		return failed
+	}, nil); err != nil {
+		t.Fatalf("RegisterCustomMigration failed: %v", err)
+	}
+
+	if err := mm.Migrate(); !errors.Is(err, failed) {
+		t.Fatalf("Expected the migration error, got %v", err)
+	}
+	if version, _ := mm.GetCurrentVersion(); version != 2 {
+		t.Errorf("Expected version 2 after the failed migration, got %d", version)
+	}
+
+	if err := mm.Rollback(1); err != nil {
+		t.Fatalf("Failed to roll back the custom migration: %v", err)
+	}
+	if version, _ := mm.GetCurrentVersion(); version != 1 {
+		t.Errorf("Expected version 1 after rollback, got %d", version)
+	}
+
+	// Neither the dropped roles table nor the failed orgs table remain
+	retry := NewMigrationManager(store)
+	retry.RegisterCustomMigration(2, createRoles, dropRoles)
+	retry.RegisterCustomMigration(3, createOrgs, nil)
+	if err := retry.Migrate(); err != nil {
+		t.Fatalf("Failed to migrate again: %v", err)
+	}
+	if version, _ := retry.GetCurrentVersion(); version != 3 {
+		t.Errorf("Expected version 3, got %d", version)
+	}
+	if err := retry.Rollback(2); err == nil {
+		t.Error("Expected an error rolling back a migration without down")
+	}
+}
+
+func TestMigrationManager_CustomMigrationUnsupportedStorage(t *testing.T) {
+	mm := NewMigrationManager(memory.NewInMemoryStorage())
+	if err := mm.RegisterCustomMigration(2, func(tx *sql.Tx) error { return nil }, nil); err != nil {
+		t.Fatalf("RegisterCustomMigration failed: %v", err)
+	}
+	if err := mm.Migrate(); err == nil {
+		t.Error("Expected an error for a storage without transactional migrations")
+	}
+}
@@ -1,10 +1,13 @@
 package auth
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"runtime"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/pragneshbagary/go-auth/pkg/storage"
@@ -73,6 +76,12 @@ type Monitor struct {
 	startTime       time.Time
 	appName         string
 	version         string
+
+	probes       ProbeConfig
+	probeMu      sync.Mutex
+	failingSince map[string]time.Time
+	breakers     []*CircuitBreaker
+	security     *securityTelemetry
 }
 
 // NewMonitor creates a new monitor instance
@@ -103,6 +112,11 @@ func (m *Monitor) CheckHealth() SystemHealth {
 	loggerHealth := m.checkLoggerHealth()
 	components = append(components, loggerHealth)
 
+	// Check circuit breakers
+	if breakers := m.CircuitBreakers(); len(breakers) > 0 {
+		components = append(components, checkCircuitBreakers(breakers))
+	}
+
 	// Determine overall status
 	overallStatus := HealthStatusHealthy
 	for _, component := range components {
@@ -208,6 +222,51 @@ func (m *Monitor) checkLoggerHealth() ComponentHealth {
 	return health
 }
 
+// RegisterCircuitBreaker reports breaker in CheckHealth and CircuitBreakers.
+func (m *Monitor) RegisterCircuitBreaker(breaker *CircuitBreaker) {
+	m.probeMu.Lock()
+	defer m.probeMu.Unlock()
+	m.breakers = append(m.breakers, breaker)
+}
+
+// CircuitBreakers returns the state of the registered circuit breakers.
+func (m *Monitor) CircuitBreakers() []CircuitBreakerStats {
+	m.probeMu.Lock()
+	breakers := append([]*CircuitBreaker(nil), m.breakers...)
+	m.probeMu.Unlock()
+	stats := make([]CircuitBreakerStats, 0, len(breakers))
+	for _, breaker := range breakers {
+		stats = append(stats, breaker.Stats())
+	}
+	return stats
+}
+
+// checkCircuitBreakers reports open circuits as unhealthy and half-open ones
+// as degraded.
+func checkCircuitBreakers(breakers []CircuitBreakerStats) ComponentHealth {
+	health := ComponentHealth{
+		Name:      "circuit_breakers",
+		Status:    HealthStatusHealthy,
+		Message:   "All circuits are closed",
+		Details:   breakers,
+		CheckedAt: time.Now(),
+		Duration:  "0s",
+	}
+	for _, breaker := range breakers {
+		switch breaker.State {
+		case CircuitOpen.String():
+			health.Status = HealthStatusUnhealthy
+			health.Message = "Circuit " + breaker.Name + " is open"
+		case CircuitHalfOpen.String():
+			if health.Status == HealthStatusHealthy {
+				health.Status = HealthStatusDegraded
+				health.Message = "Circuit " + breaker.Name + " is half-open"
+			}
+		}
+	}
+	return health
+}
+
 // GetSystemInfo returns detailed system information
 func (m *Monitor) GetSystemInfo() SystemInfo {
 	var memStats runtime.MemStats
@@ -241,7 +300,7 @@ func (m *Monitor) GetSystemInfo() SystemInfo {
 		}
 		
 		// Try to determine database type from storage implementation
-		switch m.storage.(type) {
+		switch baseStorage(m.storage).(type) {
 		case interface{ IsSQLite() bool }:
 			info.DatabaseType = "sqlite"
 		case interface{ IsPostgres() bool }:
@@ -320,6 +379,8 @@ func (m *Monitor) HTTPSystemInfoHandler() http.HandlerFunc {
 }
 
 // HTTPReadinessHandler returns an HTTP handler for readiness checks
+//
+// Deprecated: use ReadinessHandler, which reports every dependency as JSON.
 func (m *Monitor) HTTPReadinessHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Simple readiness check - just verify database connectivity
@@ -335,6 +396,8 @@ func (m *Monitor) HTTPReadinessHandler() http.HandlerFunc {
 }
 
 // HTTPLivenessHandler returns an HTTP handler for liveness checks
+//
+// Deprecated: use LivenessHandler.
 func (m *Monitor) HTTPLivenessHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Simple liveness check - service is alive if it can respond
@@ -346,8 +409,178 @@ func (m *Monitor) HTTPLivenessHandler() http.HandlerFunc {
 // RegisterHTTPHandlers registers all monitoring HTTP handlers with a mux
 func (m *Monitor) RegisterHTTPHandlers(mux *http.ServeMux) {
 	mux.HandleFunc("/health", m.HTTPHealthHandler())
-	mux.HandleFunc("/health/ready", m.HTTPReadinessHandler())
-	mux.HandleFunc("/health/live", m.HTTPLivenessHandler())
+	mux.HandleFunc("/health/ready", m.ReadinessHandler())
+	mux.HandleFunc("/health/live", m.LivenessHandler())
 	mux.HandleFunc("/metrics", m.HTTPMetricsHandler())
+	mux.HandleFunc("/metrics/openmetrics", m.HTTPOpenMetricsHandler())
 	mux.HandleFunc("/info", m.HTTPSystemInfoHandler())
-}
\ No newline at end of file
+}
+// ProbeConfig configures the Kubernetes probe handlers.
+type ProbeConfig struct {
+	// FailureThresholds keeps the service ready while a dependency has been
+	// failing for less than its threshold, so that a brief database hiccup
+	// does not pull every replica out of the load balancer. Keyed by
+	// dependency name, e.g. {"database": 10 * time.Second}; failures of
+	// other dependencies count immediately.
+	FailureThresholds map[string]time.Duration
+	// Dependencies adds readiness checks besides "database", keyed by name.
+	Dependencies map[string]func(ctx context.Context) error
+	// Timeout bounds each dependency check. Defaults to 2 seconds.
+	Timeout time.Duration
+}
+
+// DependencyStatus is the state of one dependency in a probe response.
+type DependencyStatus struct {
+	Name         string       `json:"name"`
+	Status       HealthStatus `json:"status"`
+	Error        string       `json:"error,omitempty"`
+	FailingSince *time.Time   `json:"failing_since,omitempty"`
+	Duration     string       `json:"duration"`
+}
+
+// ProbeResponse is the body written by the probe handlers.
+type ProbeResponse struct {
+	Status       HealthStatus       `json:"status"`
+	Timestamp    time.Time          `json:"timestamp"`
+	Uptime       string             `json:"uptime"`
+	Version      string             `json:"version,omitempty"`
+	Dependencies []DependencyStatus `json:"dependencies,omitempty"`
+}
+
+// ConfigureProbes sets the dependencies and failure thresholds used by
+// ReadinessHandler.
+func (m *Monitor) ConfigureProbes(config ProbeConfig) {
+	m.probeMu.Lock()
+	defer m.probeMu.Unlock()
+	m.probes = config
+	m.failingSince = nil
+}
+
+// CheckReadiness checks every dependency. A dependency failing for less than
+// its threshold is reported as degraded; the service is unhealthy once any
+// dependency exceeds it.
+func (m *Monitor) CheckReadiness(ctx context.Context) ProbeResponse {
+	m.probeMu.Lock()
+	config := m.probes
+	m.probeMu.Unlock()
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
+
+	checks := map[string]func(ctx context.Context) error{
+		"database": func(context.Context) error { return m.storage.Ping() },
+	}
+	for name, check := range config.Dependencies {
+		checks[name] = check
+	}
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	response := ProbeResponse{
+		Status:    HealthStatusHealthy,
+		Timestamp: time.Now(),
+		Uptime:    time.Since(m.startTime).String(),
+		Version:   m.version,
+	}
+	for _, name := range names {
+		start := time.Now()
+		err := runProbeCheck(ctx, checks[name], timeout)
+		dependency := m.recordProbe(name, err, start, config.FailureThresholds[name])
+		dependency.Duration = time.Since(start).String()
+		response.Dependencies = append(response.Dependencies, dependency)
+
+		if dependency.Status == HealthStatusUnhealthy {
+			response.Status = HealthStatusUnhealthy
+		} else if dependency.Status == HealthStatusDegraded && response.Status == HealthStatusHealthy {
+			response.Status = HealthStatusDegraded
+		}
+	}
+	return response
+}
+
+// runProbeCheck runs check, giving up after timeout even if check ignores
+// its context.
+func runProbeCheck(ctx context.Context, check func(ctx context.Context) error, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	result := make(chan error, 1)
+	go func() { result <- check(ctx) }()
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// recordProbe tracks since when a dependency has been failing and rates it
+// against its failure threshold.
+func (m *Monitor) recordProbe(name string, err error, now time.Time, threshold time.Duration) DependencyStatus {
+	m.probeMu.Lock()
+	defer m.probeMu.Unlock()
+	dependency := DependencyStatus{Name: name, Status: HealthStatusHealthy}
+	if err == nil {
+		delete(m.failingSince, name)
+		return dependency
+	}
+
+	if m.failingSince == nil {
+		m.failingSince = make(map[string]time.Time)
+	}
+	since, ok := m.failingSince[name]
+	if !ok {
+		since = now
+		m.failingSince[name] = since
+	}
+	dependency.Error = err.Error()
+	dependency.FailingSince = &since
+	dependency.Status = HealthStatusUnhealthy
+	if now.Sub(since) < threshold {
+		dependency.Status = HealthStatusDegraded
+	}
+	return dependency
+}
+
+// LivenessHandler returns an HTTP handler for Kubernetes liveness probes.
+// It does not check dependencies: restarting the process does not fix an
+// unreachable database, so their failures only affect readiness.
+func (m *Monitor) LivenessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.writeProbe(w, ProbeResponse{
+			Status:    HealthStatusHealthy,
+			Timestamp: time.Now(),
+			Uptime:    time.Since(m.startTime).String(),
+			Version:   m.version,
+		})
+	}
+}
+
+// ReadinessHandler returns an HTTP handler for Kubernetes readiness and
+// startup probes, responding 503 once a dependency fails beyond its
+// threshold, see ConfigureProbes.
+func (m *Monitor) ReadinessHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		m.writeProbe(w, m.CheckReadiness(r.Context()))
+	}
+}
+
+// writeProbe writes a probe response with the matching status code.
+func (m *Monitor) writeProbe(w http.ResponseWriter, response ProbeResponse) {
+	statusCode := http.StatusOK
+	if response.Status == HealthStatusUnhealthy {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		m.logger.Error("Failed to encode probe response", map[string]interface{}{
+			"error": err,
+		})
+	}
+}
@@ -1,10 +1,13 @@
 package auth
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/pragneshbagary/go-auth/internal/storage/memory"
 )
@@ -217,4 +220,56 @@ func TestHealthStatus(t *testing.T) {
 			t.Errorf("Expected %s, got %s", test.expected, string(test.status))
 		}
 	}
-}
\ No newline at end of file
+}
+func TestProbeHandlers(t *testing.T) {
+	monitor := NewMonitor(memory.NewInMemoryStorage(), NewMetricsCollector(), NewDefaultLogger(), "test-app", "1.0.0")
+	var cacheErr, queueErr error
+	monitor.ConfigureProbes(ProbeConfig{
+		FailureThresholds: map[string]time.Duration{"cache": time.Hour},
+		Dependencies: map[string]func(ctx context.Context) error{
+			"cache": func(context.Context) error { return cacheErr },
+			"queue": func(context.Context) error { return queueErr },
+		},
+	})
+
+	probe := func(handler http.HandlerFunc) (int, ProbeResponse) {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", "/health/ready", nil))
+		var response ProbeResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+			t.Fatalf("Failed to decode probe response: %v", err)
+		}
+		return w.Code, response
+	}
+
+	code, response := probe(monitor.ReadinessHandler())
+	if code != http.StatusOK || response.Status != HealthStatusHealthy || len(response.Dependencies) != 3 {
+		t.Fatalf("Expected a healthy response with 3 dependencies, got %d %+v", code, response)
+	}
+
+	// Failures within the threshold keep the service ready
+	cacheErr = errors.New("connection refused")
+	code, response = probe(monitor.ReadinessHandler())
+	if code != http.StatusOK || response.Status != HealthStatusDegraded {
+		t.Errorf("Expected a degraded but ready response, got %d %s", code, response.Status)
+	}
+	if cache := response.Dependencies[0]; cache.Name != "cache" || cache.Error == "" || cache.FailingSince == nil {
+		t.Errorf("Expected the failing cache to be reported, got %+v", cache)
+	}
+
+	queueErr = errors.New("timeout")
+	code, response = probe(monitor.ReadinessHandler())
+	if code != http.StatusServiceUnavailable || response.Status != HealthStatusUnhealthy {
+		t.Errorf("Expected a dependency without threshold to fail readiness, got %d %s", code, response.Status)
+	}
+
+	// Liveness ignores dependencies
+	if code, response := probe(monitor.LivenessHandler()); code != http.StatusOK || response.Status != HealthStatusHealthy {
+		t.Errorf("Expected liveness to succeed, got %d %s", code, response.Status)
+	}
+
+	cacheErr, queueErr = nil, nil
+	if code, _ := probe(monitor.ReadinessHandler()); code != http.StatusOK {
+		t.Errorf("Expected recovery to restore readiness, got %d", code)
+	}
+}
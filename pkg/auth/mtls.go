@@ -0,0 +1,149 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"net/url"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Certificate-bound tokens (RFC 8705) carry the SHA-256 thumbprint of the
+// client's TLS certificate in the cnf["x5t#S256"] claim and are only
+// accepted over connections presenting that certificate.
+
+// x5tClaim is the cnf member holding a certificate thumbprint.
+const x5tClaim = "x5t#S256"
+
+// MTLSConfig configures certificate-bound access tokens.
+type MTLSConfig struct {
+	// Required rejects access tokens that are not certificate-bound.
+	Required bool
+	// CertificateHeader reads the client certificate from this request
+	// header instead of the TLS connection, as URL-escaped PEM like
+	// nginx's $ssl_client_escaped_cert. Set it only behind a proxy that
+	// terminates TLS and overwrites the header on every request.
+	CertificateHeader string
+}
+
+// mtlsBinding enforces certificate binding.
+type mtlsBinding struct {
+	config MTLSConfig
+}
+
+// CertificateThumbprint returns the base64url SHA-256 thumbprint of cert,
+// the value of the cnf["x5t#S256"] claim.
+func CertificateThumbprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// MTLSBindingClaims returns the cnf claim that binds a token to the
+// certificate with thumbprint.
+func MTLSBindingClaims(thumbprint string) map[string]interface{} {
+	return map[string]interface{}{
+		"cnf": map[string]interface{}{x5tClaim: thumbprint},
+	}
+}
+
+// boundThumbprint returns the certificate thumbprint of a cnf claim, or ""
+// if it is not certificate-bound.
+func boundThumbprint(cnf interface{}) string {
+	binding, _ := cnf.(map[string]interface{})
+	thumbprint, _ := binding[x5tClaim].(string)
+	return thumbprint
+}
+
+// errCertificateBinding is the error of a request not matching the
+// certificate binding of its token.
+func errCertificateBinding(details string) *AuthError {
+	return NewAuthErrorWithDetails(ErrCodeInvalidToken, "Invalid certificate binding", details)
+}
+
+// clientCertificate returns the client certificate of r, or nil if none was
+// presented.
+func (m *mtlsBinding) clientCertificate(r *http.Request) (*x509.Certificate, error) {
+	if m.config.CertificateHeader == "" {
+		if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+			return nil, nil
+		}
+		return r.TLS.PeerCertificates[0], nil
+	}
+
+	header := r.Header.Get(m.config.CertificateHeader)
+	if header == "" {
+		return nil, nil
+	}
+	decoded, err := url.QueryUnescape(header)
+	if err != nil {
+		return nil, errCertificateBinding("Malformed client certificate header")
+	}
+	block, _ := pem.Decode([]byte(decoded))
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errCertificateBinding("Malformed client certificate header")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errCertificateBinding("Malformed client certificate header")
+	}
+	return cert, nil
+}
+
+// thumbprint returns the thumbprint of the client certificate of r.
+func (m *mtlsBinding) thumbprint(r *http.Request) (string, error) {
+	cert, err := m.clientCertificate(r)
+	if err != nil {
+		return "", err
+	}
+	if cert == nil {
+		return "", errCertificateBinding("No client certificate was presented")
+	}
+	return CertificateThumbprint(cert), nil
+}
+
+// checkRequest enforces the certificate binding of validated access token
+// claims for r.
+func (m *mtlsBinding) checkRequest(r *http.Request, claims jwt.MapClaims) error {
+	bound := boundThumbprint(claims["cnf"])
+	if bound == "" {
+		if m.config.Required {
+			return errCertificateBinding("Access token must be certificate-bound")
+		}
+		return nil
+	}
+	presented, err := m.thumbprint(r)
+	if err != nil {
+		return err
+	}
+	if !constantTimeEqual(presented, bound) {
+		return errCertificateBinding("Client certificate does not match token binding")
+	}
+	return nil
+}
+
+// LoginMTLS authenticates a user like Login and binds the issued tokens to
+// the client certificate presented with r.
+func (a *Auth) LoginMTLS(r *http.Request, username, password string, customClaims map[string]interface{}) (*LoginResult, error) {
+	thumbprint, err := a.mtls.thumbprint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := withTokenBinding(WithClientRequest(r.Context(), r), MTLSBindingClaims(thumbprint)["cnf"])
+	return a.LoginContext(ctx, username, password, customClaims)
+}
+
+// RefreshMTLS refreshes tokens like RefreshToken over a connection
+// presenting a client certificate, binding the new tokens to it.
+// Certificate-bound refresh tokens can only be refreshed this way, with the
+// certificate they are bound to.
+func (a *Auth) RefreshMTLS(r *http.Request, refreshToken string) (*RefreshResult, error) {
+	thumbprint, err := a.mtls.thumbprint(r)
+	if err != nil {
+		return nil, err
+	}
+	return a.Tokens().refresh(refreshToken, MTLSBindingClaims(thumbprint))
+}
@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// testClientCertificate creates a self-signed client certificate.
+func testClientCertificate(t *testing.T, name string) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert
+}
+
+// mtlsRequest returns a request over a connection presenting cert.
+func mtlsRequest(target string, cert *x509.Certificate) *http.Request {
+	r := httptest.NewRequest("POST", target, nil)
+	if cert != nil {
+		r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+	}
+	return r
+}
+
+func TestMTLSBoundTokens(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("svc", "svc-password")
+	cert, other := testClientCertificate(t, "svc"), testClientCertificate(t, "other")
+
+	if _, err := ta.LoginMTLS(mtlsRequest("/login", nil), "svc", "svc-password", nil); err == nil {
+		t.Error("Expected a login without client certificate to fail")
+	}
+	result, err := ta.LoginMTLS(mtlsRequest("/login", cert), "svc", "svc-password", nil)
+	if err != nil {
+		t.Fatalf("LoginMTLS failed: %v", err)
+	}
+
+	handler := ta.Middleware().Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	call := func(accessToken string, cert *x509.Certificate) int {
+		r := mtlsRequest("/api", cert)
+		r.Header.Set("Authorization", "Bearer "+accessToken)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec.Code
+	}
+	if code := call(result.AccessToken, cert); code != http.StatusOK {
+		t.Errorf("Expected the bound certificate to be accepted, got %d", code)
+	}
+	if code := call(result.AccessToken, other); code != http.StatusUnauthorized {
+		t.Errorf("Expected another certificate to be rejected, got %d", code)
+	}
+	if code := call(result.AccessToken, nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected a request without certificate to be rejected, got %d", code)
+	}
+
+	// Bound refresh tokens cannot be refreshed without the certificate
+	if _, err := ta.RefreshToken(result.RefreshToken); !isCode(err, ErrCodeInvalidToken) {
+		t.Errorf("Expected a plain refresh of a bound token to fail, got %v", err)
+	}
+	if _, err := ta.RefreshMTLS(mtlsRequest("/refresh", other), result.RefreshToken); !isCode(err, ErrCodeInvalidToken) {
+		t.Errorf("Expected a refresh with another certificate to fail, got %v", err)
+	}
+	refreshed, err := ta.RefreshMTLS(mtlsRequest("/refresh", cert), result.RefreshToken)
+	if err != nil {
+		t.Fatalf("RefreshMTLS failed: %v", err)
+	}
+	if code := call(refreshed.AccessToken, other); code != http.StatusUnauthorized {
+		t.Errorf("Expected the refreshed token to stay bound, got %d", code)
+	}
+}
+
+func TestMTLSCertificateHeader(t *testing.T) {
+	binding := &mtlsBinding{config: MTLSConfig{CertificateHeader: "X-Client-Cert"}}
+	cert := testClientCertificate(t, "svc")
+	r := httptest.NewRequest("GET", "/api", nil)
+	r.Header.Set("X-Client-Cert", url.QueryEscape(string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}))))
+
+	thumbprint, err := binding.thumbprint(r)
+	if err != nil || thumbprint != CertificateThumbprint(cert) {
+		t.Errorf("Expected the thumbprint of the forwarded certificate, got %q, %v", thumbprint, err)
+	}
+	if err := binding.checkRequest(r, MTLSBindingClaims(thumbprint)); err != nil {
+		t.Errorf("Expected the forwarded certificate to match: %v", err)
+	}
+}
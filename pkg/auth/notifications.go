@@ -0,0 +1,284 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// Metadata keys of a user's notification preferences and of the devices
+// they logged in from, as hashes of their user agents.
+const (
+	notificationPrefsMetadataKey   = "notification_prefs"
+	notificationDevicesMetadataKey = "notification_devices"
+)
+
+// maxNotificationDevices bounds the devices remembered per user.
+const maxNotificationDevices = 10
+
+// maxDigestActivities bounds the activities kept per user between digests.
+const maxDigestActivities = 50
+
+// securityNotificationEvents are the events security notifications react to.
+var securityNotificationEvents = []string{"user_login", "anomalous_login", "password_change", "password_reset"}
+
+// NotificationPrefs are the security emails a user wants to receive.
+type NotificationPrefs struct {
+	// NewDevice notifies of logins from a device not used before.
+	NewDevice bool `json:"new_device"`
+	// PasswordChange notifies of password changes and resets.
+	PasswordChange bool `json:"password_change"`
+	// SecurityDigest sends a periodic summary of security activity.
+	SecurityDigest bool `json:"security_digest"`
+}
+
+// DefaultNotificationPrefs apply to users who did not set preferences.
+var DefaultNotificationPrefs = NotificationPrefs{NewDevice: true, PasswordChange: true}
+
+// SecurityNotificationConfig sends security emails about account activity
+// with EmailConfig.Sender, as each user's NotificationPrefs allow.
+type SecurityNotificationConfig struct {
+	// Enabled turns the notifications on.
+	Enabled bool
+	// DigestInterval sends the security digests this often. Without it
+	// they are only sent by Auth.SendSecurityDigests.
+	DigestInterval time.Duration
+}
+
+// SecurityActivity is an entry of a security digest.
+type SecurityActivity struct {
+	// Type is the event type, e.g. "user_login".
+	Type      string
+	Time      time.Time
+	IP        string
+	UserAgent string
+}
+
+// notificationPrefsOf returns the preferences stored in user's metadata.
+func notificationPrefsOf(user *models.User) NotificationPrefs {
+	prefs := DefaultNotificationPrefs
+	stored, ok := user.Metadata[notificationPrefsMetadataKey].(map[string]interface{})
+	if !ok {
+		return prefs
+	}
+	if v, ok := stored["new_device"].(bool); ok {
+		prefs.NewDevice = v
+	}
+	if v, ok := stored["password_change"].(bool); ok {
+		prefs.PasswordChange = v
+	}
+	if v, ok := stored["security_digest"].(bool); ok {
+		prefs.SecurityDigest = v
+	}
+	return prefs
+}
+
+// GetNotificationPrefs returns the security email preferences of a user,
+// DefaultNotificationPrefs if they were never set.
+func (u *Users) GetNotificationPrefs(userID string) (NotificationPrefs, error) {
+	if userID == "" {
+		return NotificationPrefs{}, ErrValidationError("user ID")
+	}
+	user, err := u.storage.GetUserByID(userID)
+	if err != nil {
+		return NotificationPrefs{}, ErrUserNotFound()
+	}
+	return notificationPrefsOf(user), nil
+}
+
+// SetNotificationPrefs stores the security email preferences of a user.
+func (u *Users) SetNotificationPrefs(userID string, prefs NotificationPrefs) error {
+	if userID == "" {
+		return ErrValidationError("user ID")
+	}
+	user, err := u.storage.GetUserByID(userID)
+	if err != nil {
+		return ErrUserNotFound()
+	}
+	metadata := make(map[string]interface{}, len(user.Metadata)+1)
+	for k, v := range user.Metadata {
+		metadata[k] = v
+	}
+	metadata[notificationPrefsMetadataKey] = map[string]interface{}{
+		"new_device":      prefs.NewDevice,
+		"password_change": prefs.PasswordChange,
+		"security_digest": prefs.SecurityDigest,
+	}
+	if err := u.storage.UpdateUser(userID, storage.UserUpdates{Metadata: metadata}); err != nil {
+		return WrapDatabaseError(err)
+	}
+	return nil
+}
+
+// securityNotifier turns security events into emails. It receives events
+// with their identifiers unpseudonymized, so that it can find the users.
+type securityNotifier struct {
+	auth *Auth
+
+	mu      sync.Mutex
+	digests map[string][]SecurityActivity
+}
+
+func newSecurityNotifier(auth *Auth) *securityNotifier {
+	return &securityNotifier{auth: auth, digests: make(map[string][]SecurityActivity)}
+}
+
+// Publish handles an event. Failures are logged rather than retried, so
+// that an unreachable mailer cannot hold up later notifications.
+func (n *securityNotifier) Publish(ctx context.Context, event AuthEvent) error {
+	if success, _ := event.Data["success"].(bool); !success && event.Type != "anomalous_login" {
+		return nil
+	}
+	if event.UserID == "" {
+		return nil
+	}
+	user, err := n.auth.storage.GetUserByID(event.UserID)
+	if err != nil {
+		return nil
+	}
+	prefs := notificationPrefsOf(user)
+	activity := SecurityActivity{Type: event.Type, Time: event.Time}
+	activity.IP, _ = event.Data["ip"].(string)
+	activity.UserAgent, _ = event.Data["user_agent"].(string)
+
+	var kind string
+	switch event.Type {
+	case "user_login":
+		if n.newDevice(user, activity.UserAgent) && prefs.NewDevice {
+			kind = EmailNewDevice
+		}
+	case "password_change", "password_reset":
+		if prefs.PasswordChange {
+			kind = EmailPasswordChanged
+		}
+	}
+	if prefs.SecurityDigest {
+		n.record(user.ID, activity)
+	}
+	if kind != "" {
+		n.send(ctx, kind, user, []SecurityActivity{activity})
+	}
+	return nil
+}
+
+// newDevice remembers the device of a login and reports whether it is new.
+// The first login of a user has no devices to compare with and is not new.
+func (n *securityNotifier) newDevice(user *models.User, userAgent string) bool {
+	sum := sha256.Sum256([]byte(userAgent))
+	device := hex.EncodeToString(sum[:8])
+	known := stringList(user.Metadata[notificationDevicesMetadataKey])
+	if slices.Contains(known, device) {
+		return false
+	}
+
+	devices := append(known, device)
+	if len(devices) > maxNotificationDevices {
+		devices = devices[len(devices)-maxNotificationDevices:]
+	}
+	metadata := make(map[string]interface{}, len(user.Metadata)+1)
+	for k, v := range user.Metadata {
+		metadata[k] = v
+	}
+	metadata[notificationDevicesMetadataKey] = devices
+	if err := n.auth.storage.UpdateUser(user.ID, storage.UserUpdates{Metadata: metadata}); err != nil {
+		n.auth.logger.Warn("Failed to remember login device", map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err,
+		})
+	}
+	return len(known) > 0
+}
+
+// record adds activity to the next digest of userID.
+func (n *securityNotifier) record(userID string, activity SecurityActivity) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	activities := append(n.digests[userID], activity)
+	if len(activities) > maxDigestActivities {
+		activities = activities[len(activities)-maxDigestActivities:]
+	}
+	n.digests[userID] = activities
+}
+
+// send renders and sends a notification email to user, logging failures.
+func (n *securityNotifier) send(ctx context.Context, kind string, user *models.User, activities []SecurityActivity) error {
+	emails := n.auth.Emails()
+	data := emailData(user, "", time.Time{})
+	data.Activity = activities
+	message, err := emails.Render(kind, data)
+	if err == nil {
+		err = emails.send(ctx, func() (*EmailMessage, error) { return message, nil })
+	}
+	if err != nil {
+		n.auth.logger.Warn("Failed to send security notification", map[string]interface{}{
+			"user_id": user.ID,
+			"kind":    kind,
+			"error":   err,
+		})
+	}
+	return err
+}
+
+// SendSecurityDigests emails the users who opted in to a security digest a
+// summary of the activity since their last one. It returns the number of
+// digests sent. Activity is kept in memory by the instance that saw it.
+func (a *Auth) SendSecurityDigests(ctx context.Context) (int, error) {
+	n := a.notifier
+	if n == nil {
+		return 0, ErrConfigError("SecurityNotifications.Enabled")
+	}
+	n.mu.Lock()
+	digests := n.digests
+	n.digests = make(map[string][]SecurityActivity)
+	n.mu.Unlock()
+
+	userIDs := make([]string, 0, len(digests))
+	for userID := range digests {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Strings(userIDs)
+
+	sent := 0
+	for _, userID := range userIDs {
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+		user, err := a.storage.GetUserByID(userID)
+		if err != nil || !notificationPrefsOf(user).SecurityDigest {
+			continue
+		}
+		if n.send(ctx, EmailSecurityDigest, user, digests[userID]) == nil {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// startDigestJob sends the security digests every interval until stopped.
+func (a *Auth) startDigestJob(interval time.Duration) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			_, err := a.SendSecurityDigests(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			a.jobs.record("security_digest", a.clock.Now(), err)
+		}
+	}()
+	return cancel
+}
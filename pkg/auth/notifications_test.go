@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func loginEvent(userID, userAgent string) AuthEvent {
+	return newAuthEvent(LogLevelInfo, "User logged in successfully", map[string]interface{}{
+		"event":      "user_login",
+		"user_id":    userID,
+		"ip":         "203.0.113.1",
+		"user_agent": userAgent,
+		"success":    true,
+	})
+}
+
+func TestSecurityNotificationsNewDevice(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.notifier = newSecurityNotifier(ta.Auth)
+	user := ta.SeedUser("alice", "alice-password")
+	ctx := context.Background()
+
+	ta.notifier.Publish(ctx, loginEvent(user.ID, "laptop"))
+	ta.notifier.Publish(ctx, loginEvent(user.ID, "laptop"))
+	if n := len(ta.Mail.Messages()); n != 0 {
+		t.Fatalf("Expected no email for the first and known devices, got %d", n)
+	}
+
+	ta.notifier.Publish(ctx, loginEvent(user.ID, "phone"))
+	messages := ta.Mail.Messages()
+	if len(messages) != 1 || messages[0].Kind != EmailNewDevice || messages[0].To != user.Email {
+		t.Fatalf("Expected a new device email, got %+v", messages)
+	}
+	if !strings.Contains(messages[0].Text, "phone") {
+		t.Errorf("Expected the email to name the device, got %q", messages[0].Text)
+	}
+
+	// Opted out
+	if err := ta.Users().SetNotificationPrefs(user.ID, NotificationPrefs{PasswordChange: true}); err != nil {
+		t.Fatalf("SetNotificationPrefs failed: %v", err)
+	}
+	ta.notifier.Publish(ctx, loginEvent(user.ID, "tablet"))
+	if n := len(ta.Mail.Messages()); n != 1 {
+		t.Errorf("Expected no email after opting out, got %d", n)
+	}
+	if prefs, _ := ta.Users().GetNotificationPrefs(user.ID); prefs.NewDevice || !prefs.PasswordChange {
+		t.Errorf("Unexpected preferences %+v", prefs)
+	}
+}
+
+func TestSecurityNotificationsPasswordChange(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.notifier = newSecurityNotifier(ta.Auth)
+	ta.eventLogger.notifications = newEventDispatcher(EventSinkConfig{
+		Sink:   ta.notifier,
+		Events: securityNotificationEvents,
+	}, ta.logger)
+	user := ta.SeedUser("bob", "bob-password")
+
+	if err := ta.Users().ChangePassword(user.ID, "bob-password", "bob-new-password"); err != nil {
+		t.Fatalf("ChangePassword failed: %v", err)
+	}
+	ta.eventLogger.notifications.close()
+
+	messages := ta.Mail.Messages()
+	if len(messages) != 1 || messages[0].Kind != EmailPasswordChanged {
+		t.Fatalf("Expected a password changed email, got %+v", messages)
+	}
+}
+
+func TestSecurityDigest(t *testing.T) {
+	ta := NewTestAuth(t)
+	ctx := context.Background()
+	if _, err := ta.SendSecurityDigests(ctx); err == nil {
+		t.Error("Expected digests to require notifications")
+	}
+	ta.notifier = newSecurityNotifier(ta.Auth)
+	alice := ta.SeedUser("alice", "alice-password")
+	bob := ta.SeedUser("bob", "bob-password")
+	ta.Users().SetNotificationPrefs(alice.ID, NotificationPrefs{SecurityDigest: true})
+
+	for _, user := range []string{alice.ID, bob.ID} {
+		ta.notifier.Publish(ctx, loginEvent(user, "laptop"))
+	}
+
+	sent, err := ta.SendSecurityDigests(ctx)
+	if err != nil || sent != 1 {
+		t.Fatalf("Expected one digest, got %d, %v", sent, err)
+	}
+	messages := ta.Mail.Messages()
+	if len(messages) != 1 || messages[0].Kind != EmailSecurityDigest || messages[0].To != alice.Email {
+		t.Fatalf("Expected a digest for alice, got %+v", messages)
+	}
+	if sent, _ := ta.SendSecurityDigests(ctx); sent != 0 {
+		t.Errorf("Expected the digest activity to be cleared, sent %d", sent)
+	}
+}
@@ -0,0 +1,567 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// oidcMetadataKey is the user metadata key linking a local user to the
+// subject of an OpenID provider.
+const oidcMetadataKey = "oidc"
+
+// oidcStateTTL bounds the time between the redirect to the provider and the
+// callback.
+const oidcStateTTL = 10 * time.Minute
+
+// oidcKeysRefreshInterval limits how often unknown key IDs trigger a JWKS
+// refetch.
+const oidcKeysRefreshInterval = time.Minute
+
+// OIDCConfig configures an OpenID Connect relying party, see Auth.OIDC.
+type OIDCConfig struct {
+	// Issuer is the provider's issuer URL. Its metadata is discovered at
+	// Issuer + "/.well-known/openid-configuration".
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the callback URL registered with the provider.
+	RedirectURL string
+	// Scopes requested besides "openid". Defaults to "email" and "profile".
+	Scopes []string
+	// Claims maps the ID token claims to the local user. UserID defaults
+	// to "sub" and Username to "preferred_username".
+	Claims IssuerClaimMapping
+	// CreateUsers registers unknown users on their first login. Otherwise
+	// only users that already exist locally can log in.
+	CreateUsers bool
+	// CookieName is the cookie holding the state of a login in progress.
+	// Defaults to "oidc_state".
+	CookieName string
+	// Insecure drops the Secure attribute of the cookie for local
+	// development over HTTP.
+	Insecure bool
+	// HTTPClient defaults to a client with a 10 second timeout.
+	HTTPClient *http.Client
+}
+
+// OIDC is an OpenID Connect relying party for backend-for-frontend setups:
+// it sends browsers to the provider with the authorization code flow and
+// PKCE, validates the returned ID token and logs the matching local user
+// in, so that the backend issues its own tokens and sessions.
+type OIDC struct {
+	auth   *Auth
+	config OIDCConfig
+
+	mu       sync.Mutex
+	provider *oidcProvider
+	keys     map[string]interface{}
+	keysAt   time.Time
+}
+
+// oidcProvider is the discovered metadata of a provider.
+type oidcProvider struct {
+	Issuer                string   `json:"issuer"`
+	AuthorizationEndpoint string   `json:"authorization_endpoint"`
+	TokenEndpoint         string   `json:"token_endpoint"`
+	JWKSURI               string   `json:"jwks_uri"`
+	SigningAlgorithms     []string `json:"id_token_signing_alg_values_supported"`
+}
+
+// OIDC returns a relying party for config. The provider metadata is
+// discovered on first use.
+func (a *Auth) OIDC(config OIDCConfig) (*OIDC, error) {
+	if config.Issuer == "" || config.ClientID == "" || config.RedirectURL == "" {
+		return nil, NewAuthErrorWithDetails(ErrCodeInvalidConfig, "Invalid configuration",
+			"OpenID Connect requires Issuer, ClientID and RedirectURL")
+	}
+	config.Issuer = strings.TrimSuffix(config.Issuer, "/")
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"email", "profile"}
+	}
+	if config.Claims.UserID == "" {
+		config.Claims.UserID = "sub"
+	}
+	if config.Claims.Username == "" {
+		config.Claims.Username = "preferred_username"
+	}
+	if config.Claims.Email == "" {
+		config.Claims.Email = "email"
+	}
+	if config.CookieName == "" {
+		config.CookieName = "oidc_state"
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &OIDC{auth: a, config: config}, nil
+}
+
+// oidcState is the state of a login in progress, kept in a cookie.
+type oidcState struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// AuthURL starts a login: it stores a fresh state, nonce and PKCE verifier
+// in a cookie and returns the provider URL to redirect the browser to.
+func (o *OIDC) AuthURL(w http.ResponseWriter, r *http.Request) (string, error) {
+	provider, err := o.discover(r.Context())
+	if err != nil {
+		return "", err
+	}
+	var state oidcState
+	for _, value := range []*string{&state.State, &state.Nonce, &state.Verifier} {
+		if *value, err = randomURLToken(); err != nil {
+			return "", WrapError(err, ErrCodeInternalError, "Failed to start OpenID Connect login")
+		}
+	}
+	http.SetCookie(w, o.cookie(state.State+"."+state.Nonce+"."+state.Verifier, int(oidcStateTTL/time.Second)))
+
+	challenge := sha256.Sum256([]byte(state.Verifier))
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {o.config.ClientID},
+		"redirect_uri":          {o.config.RedirectURL},
+		"scope":                 {strings.Join(append([]string{"openid"}, o.config.Scopes...), " ")},
+		"state":                 {state.State},
+		"nonce":                 {state.Nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(provider.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return provider.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// LoginHandler returns an HTTP handler redirecting browsers to the provider.
+func (o *OIDC) LoginHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		target, err := o.AuthURL(w, r)
+		if err != nil {
+			o.auth.writeError(w, r, err)
+			return
+		}
+		http.Redirect(w, r, target, http.StatusFound)
+	})
+}
+
+// Callback completes a login at the redirect URL: it checks the state,
+// exchanges the code, validates the ID token and logs in the local user
+// matching its claims. Users are matched by verified email, then by
+// username; users not linked to the provider subject yet are linked on a
+// verified email match only, and users linked to another subject are
+// rejected. Login checks other than the password apply,
+// and the session is recorded like a password login.
+func (o *OIDC) Callback(w http.ResponseWriter, r *http.Request) (*LoginResult, error) {
+	ctx := r.Context()
+	start := time.Now()
+	var user *models.User
+	var success bool
+	var err error
+
+	defer func() {
+		var userID, username string
+		if user != nil {
+			userID, username = user.ID, user.Username
+		}
+		client := clientInfoFrom(ctx)
+		tenant := tenantFrom(ctx)
+		o.auth.eventLogger.forTenant(tenant).LogLogin(userID, username, client.ip, client.userAgent, success, time.Since(start), err)
+		o.auth.metricsCollector.RecordLoginAttempt(success, time.Since(start))
+		o.auth.metricsCollector.RecordTenantLogin(tenant, success)
+		o.auth.risk.observe(userID, client.userAgent, success)
+	}()
+
+	if err = o.auth.maintenance.check(maintenanceLogin); err != nil {
+		return nil, err
+	}
+
+	state, stateErr := o.takeState(w, r)
+	if stateErr != nil {
+		err = stateErr
+		return nil, err
+	}
+	if providerErr := r.URL.Query().Get("error"); providerErr != "" {
+		err = oidcError("The provider returned " + providerErr)
+		return nil, err
+	}
+
+	claims, exchangeErr := o.exchange(ctx, r.URL.Query().Get("code"), state)
+	if exchangeErr != nil {
+		err = exchangeErr
+		o.auth.logger.Warn("OpenID Connect login failed", map[string]interface{}{
+			"issuer": o.config.Issuer,
+			"error":  exchangeErr,
+		})
+		return nil, err
+	}
+
+	if user, err = o.localUser(ctx, claims); err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		err = ErrUserInactive()
+		return nil, err
+	}
+	if err = checkAccountExpiry(user, o.auth.clock); err != nil {
+		return nil, err
+	}
+
+	riskScore, riskErr := o.auth.assessLoginRisk(ctx, user)
+	if riskErr != nil {
+		err = riskErr
+		return nil, err
+	}
+	result, loginErr := o.auth.completeLogin(ctx, user, nil, riskScore)
+	if loginErr != nil {
+		err = loginErr
+		return nil, err
+	}
+	success = true
+	return result, nil
+}
+
+// takeState reads and clears the state cookie and checks it against the
+// state parameter of r.
+func (o *OIDC) takeState(w http.ResponseWriter, r *http.Request) (*oidcState, error) {
+	cookie, err := r.Cookie(o.config.CookieName)
+	http.SetCookie(w, o.cookie("", -1))
+	if err != nil {
+		return nil, oidcError("No login is in progress")
+	}
+	parts := strings.Split(cookie.Value, ".")
+	if len(parts) != 3 {
+		return nil, oidcError("Malformed state cookie")
+	}
+	state := &oidcState{State: parts[0], Nonce: parts[1], Verifier: parts[2]}
+	if !constantTimeEqual(state.State, r.URL.Query().Get("state")) {
+		return nil, oidcError("State mismatch")
+	}
+	return state, nil
+}
+
+func (o *OIDC) cookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     o.config.CookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		Secure:   !o.config.Insecure,
+		HttpOnly: true,
+		// The callback is a cross-site navigation from the provider
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// exchange redeems code at the token endpoint and returns the claims of the
+// validated ID token.
+func (o *OIDC) exchange(ctx context.Context, code string, state *oidcState) (jwt.MapClaims, error) {
+	if code == "" {
+		return nil, oidcError("Missing authorization code")
+	}
+	provider, err := o.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {o.config.RedirectURL},
+		"code_verifier": {state.Verifier},
+	}
+	if o.config.ClientSecret == "" {
+		form.Set("client_id", o.config.ClientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, WrapError(err, ErrCodeInternalError, "Failed to exchange authorization code")
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if o.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(o.config.ClientID), url.QueryEscape(o.config.ClientSecret))
+	}
+
+	var tokens struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := o.fetchJSON(req, &tokens); err != nil {
+		return nil, WrapError(err, ErrCodeConnectionError, "Failed to exchange authorization code")
+	}
+	if tokens.IDToken == "" {
+		return nil, oidcError("The token response has no ID token")
+	}
+	return o.verifyIDToken(ctx, provider, tokens.IDToken, state.Nonce)
+}
+
+// verifyIDToken validates the signature, issuer, audience, lifetime and
+// nonce of an ID token.
+func (o *OIDC) verifyIDToken(ctx context.Context, provider *oidcProvider, idToken, nonce string) (jwt.MapClaims, error) {
+	algorithms := provider.SigningAlgorithms
+	if len(algorithms) == 0 {
+		algorithms = []string{"RS256"}
+	}
+	var asymmetric []string
+	for _, alg := range algorithms {
+		// Provider keys come from the JWKS, so HMAC and "none" never apply
+		if alg != "none" && !strings.HasPrefix(alg, "HS") {
+			asymmetric = append(asymmetric, alg)
+		}
+	}
+
+	now := nowFrom(o.auth.clock)
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return o.key(ctx, provider, kid)
+	},
+		jwt.WithValidMethods(asymmetric),
+		jwt.WithIssuer(provider.Issuer),
+		jwt.WithAudience(o.config.ClientID),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+		jwt.WithLeeway(o.auth.currentConfig().TokenLeeway),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || !token.Valid {
+		return nil, NewAuthErrorWithDetails(ErrCodeInvalidToken, "Invalid ID token", fmt.Sprint(err))
+	}
+	if got, _ := claims["nonce"].(string); !constantTimeEqual(got, nonce) {
+		return nil, NewAuthErrorWithDetails(ErrCodeInvalidToken, "Invalid ID token", "Nonce mismatch")
+	}
+	// With several audiences, the authorized party must be this client
+	if audience, _ := claims.GetAudience(); len(audience) > 1 {
+		if azp, _ := claims["azp"].(string); azp != o.config.ClientID {
+			return nil, NewAuthErrorWithDetails(ErrCodeInvalidToken, "Invalid ID token", "Unexpected authorized party")
+		}
+	}
+	if sub, _ := claims.GetSubject(); sub == "" {
+		return nil, NewAuthErrorWithDetails(ErrCodeInvalidToken, "Invalid ID token", "Missing subject")
+	}
+	return claims, nil
+}
+
+// localUser returns the local user of the ID token claims, linking or
+// registering it as needed.
+func (o *OIDC) localUser(ctx context.Context, claims jwt.MapClaims) (*models.User, error) {
+	subject, _ := claims.GetSubject()
+	mapped := o.config.Claims.user(claims)
+	emailVerified, _ := claims["email_verified"].(bool)
+
+	var user *models.User
+	var err error = ErrUserNotFound()
+	byEmail := mapped.Email != "" && emailVerified
+	if byEmail {
+		user, err = o.auth.storage.GetUserByEmail(mapped.Email)
+	}
+	if err != nil && mapped.Username != "" {
+		byEmail = false
+		user, err = o.auth.storage.GetUserByUsername(mapped.Username)
+	}
+	if err != nil {
+		if !o.config.CreateUsers {
+			return nil, ErrInvalidCredentials()
+		}
+		return o.register(ctx, subject, mapped, emailVerified)
+	}
+
+	link, _ := user.Metadata[oidcMetadataKey].(map[string]interface{})
+	if link != nil {
+		if link["issuer"] != o.config.Issuer || link["subject"] != subject {
+			o.auth.logger.Warn("OpenID Connect login rejected: user linked to another subject", map[string]interface{}{
+				"user_id": user.ID,
+				"issuer":  o.config.Issuer,
+			})
+			return nil, ErrInvalidCredentials()
+		}
+		return user, nil
+	}
+	// Providers may let users pick their username, so only a verified
+	// email proves ownership of an unlinked account
+	if !byEmail {
+		return nil, ErrInvalidCredentials()
+	}
+	if err := o.link(user, subject); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// register creates the local user of a first login.
+func (o *OIDC) register(ctx context.Context, subject string, mapped *models.User, emailVerified bool) (*models.User, error) {
+	username := mapped.Username
+	if username == "" {
+		username = mapped.Email
+	}
+	if username == "" {
+		username = subject
+	}
+	email := mapped.Email
+	if !emailVerified {
+		email = ""
+	}
+	// The password is never revealed; users log in through the provider
+	// until they reset it
+	password, err := randomURLToken()
+	if err != nil {
+		return nil, WrapError(err, ErrCodeInternalError, "Failed to register user")
+	}
+	user, err := o.auth.RegisterContext(ctx, RegisterRequest{Username: username, Email: email, Password: password})
+	if err != nil {
+		return nil, err
+	}
+	if err := o.link(user, subject); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// link records the provider subject of user.
+func (o *OIDC) link(user *models.User, subject string) error {
+	metadata := make(map[string]interface{}, len(user.Metadata)+1)
+	for k, v := range user.Metadata {
+		metadata[k] = v
+	}
+	metadata[oidcMetadataKey] = map[string]interface{}{
+		"issuer":  o.config.Issuer,
+		"subject": subject,
+	}
+	if err := o.auth.storage.UpdateUser(user.ID, storage.UserUpdates{Metadata: metadata}); err != nil {
+		return WrapDatabaseError(err)
+	}
+	user.Metadata = metadata
+	return nil
+}
+
+// discover returns the provider metadata, fetching it on first use.
+func (o *OIDC) discover(ctx context.Context) (*oidcProvider, error) {
+	o.mu.Lock()
+	provider := o.provider
+	o.mu.Unlock()
+	if provider != nil {
+		return provider, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.config.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, WrapError(err, ErrCodeInvalidConfig, "Invalid OpenID Connect issuer")
+	}
+	provider = &oidcProvider{}
+	if err := o.fetchJSON(req, provider); err != nil {
+		return nil, WrapError(err, ErrCodeConnectionError, "Failed to discover OpenID provider")
+	}
+	if strings.TrimSuffix(provider.Issuer, "/") != o.config.Issuer {
+		return nil, NewAuthErrorWithDetails(ErrCodeInvalidConfig, "Invalid configuration",
+			fmt.Sprintf("The provider reports issuer %q", provider.Issuer))
+	}
+	if provider.AuthorizationEndpoint == "" || provider.TokenEndpoint == "" || provider.JWKSURI == "" {
+		return nil, NewAuthErrorWithDetails(ErrCodeInvalidConfig, "Invalid configuration",
+			"The provider metadata lacks an authorization, token or JWKS endpoint")
+	}
+
+	o.mu.Lock()
+	o.provider = provider
+	o.mu.Unlock()
+	return provider, nil
+}
+
+// key returns the provider key with ID kid, refetching the JWKS when it is
+// unknown, at most once per oidcKeysRefreshInterval.
+func (o *OIDC) key(ctx context.Context, provider *oidcProvider, kid string) (interface{}, error) {
+	o.mu.Lock()
+	key, ok := o.keys[kid]
+	stale := o.keys == nil || nowFrom(o.auth.clock).Sub(o.keysAt) >= oidcKeysRefreshInterval
+	o.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	if !stale {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, provider.JWKSURI, nil)
+	if err != nil {
+		return nil, err
+	}
+	var set struct {
+		Keys []map[string]interface{} `json:"keys"`
+	}
+	if err := o.fetchJSON(req, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if use, _ := jwk["use"].(string); use != "" && use != "sig" {
+			continue
+		}
+		// Keys of unsupported types are skipped rather than failing the set
+		if publicKey, err := parsePublicJWK(jwk); err == nil {
+			id, _ := jwk["kid"].(string)
+			keys[id] = publicKey
+		}
+	}
+
+	o.mu.Lock()
+	o.keys, o.keysAt = keys, nowFrom(o.auth.clock)
+	o.mu.Unlock()
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	// Providers with a single key may omit key IDs
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown key %q", kid)
+}
+
+// fetchJSON sends req and decodes the JSON response into v.
+func (o *OIDC) fetchJSON(req *http.Request, v interface{}) error {
+	resp, err := o.config.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, req.URL.Redacted())
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// oidcError is the error of a callback that cannot complete a login.
+func oidcError(details string) *AuthError {
+	return NewAuthErrorWithDetails(ErrCodeInvalidCredentials, "OpenID Connect login failed", details)
+}
+
+// constantTimeEqual compares a and b in constant time.
+func constantTimeEqual(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// randomURLToken returns 32 random bytes, base64url encoded.
+func randomURLToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testOIDCProvider is an OpenID provider issuing ID tokens with claims for
+// any authorization code.
+type testOIDCProvider struct {
+	*httptest.Server
+	key    *rsa.PrivateKey
+	clock  Clock
+	claims jwt.MapClaims
+	nonce  string
+}
+
+func newTestOIDCProvider(t *testing.T, clock Clock) *testOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	p := &testOIDCProvider{key: key, clock: clock}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+			"jwks_uri":               p.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]interface{}{{
+			"kty": "RSA",
+			"kid": "key-1",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if id, secret, _ := r.BasicAuth(); id != "bff" || secret != "bff-secret" || r.FormValue("code_verifier") == "" {
+			http.Error(w, "invalid_client", http.StatusUnauthorized)
+			return
+		}
+		now := nowFrom(p.clock)
+		claims := jwt.MapClaims{
+			"iss":   p.URL,
+			"aud":   "bff",
+			"iat":   now.Unix(),
+			"exp":   now.Add(time.Hour).Unix(),
+			"nonce": p.nonce,
+		}
+		for k, v := range p.claims {
+			claims[k] = v
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "key-1"
+		idToken, _ := token.SignedString(key)
+		json.NewEncoder(w).Encode(map[string]interface{}{"id_token": idToken, "token_type": "Bearer"})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+// login runs a login through the provider and returns the result of the
+// callback.
+func (p *testOIDCProvider) login(t *testing.T, rp *OIDC, tamper func(query url.Values)) (*LoginResult, error) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	target, err := rp.AuthURL(rec, httptest.NewRequest("GET", "/login", nil))
+	if err != nil {
+		t.Fatalf("AuthURL failed: %v", err)
+	}
+	authorize, _ := url.Parse(target)
+	if authorize.Query().Get("code_challenge_method") != "S256" {
+		t.Errorf("Expected a PKCE challenge, got %s", target)
+	}
+	p.nonce = authorize.Query().Get("nonce")
+
+	query := url.Values{"code": {"code-1"}, "state": {authorize.Query().Get("state")}}
+	if tamper != nil {
+		tamper(query)
+	}
+	r := httptest.NewRequest("GET", "/callback?"+query.Encode(), nil)
+	for _, cookie := range rec.Result().Cookies() {
+		r.AddCookie(cookie)
+	}
+	return rp.Callback(httptest.NewRecorder(), r)
+}
+
+func TestOIDCLogin(t *testing.T) {
+	ta := NewTestAuth(t)
+	provider := newTestOIDCProvider(t, ta.Clock)
+	rp, err := ta.OIDC(OIDCConfig{
+		Issuer:       provider.URL,
+		ClientID:     "bff",
+		ClientSecret: "bff-secret",
+		RedirectURL:  "https://bff.test/callback",
+		CreateUsers:  true,
+	})
+	if err != nil {
+		t.Fatalf("OIDC failed: %v", err)
+	}
+
+	// First logins register the user
+	provider.claims = jwt.MapClaims{"sub": "idp-1", "preferred_username": "dana", "email": "dana@corp.test", "email_verified": true}
+	result, err := provider.login(t, rp, nil)
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	user, err := ta.Tokens().Validate(result.AccessToken)
+	if err != nil || user.Username != "dana" {
+		t.Fatalf("Expected a session of the new user, got %+v, %v", user, err)
+	}
+	if _, err := provider.login(t, rp, nil); err != nil {
+		t.Errorf("Expected the linked user to log in again: %v", err)
+	}
+
+	// Existing users are linked by verified email only
+	ta.SeedUser("erin", "erin-password")
+	provider.claims = jwt.MapClaims{"sub": "idp-2", "preferred_username": "erin", "email": "erin@example.test"}
+	if _, err := provider.login(t, rp, nil); err == nil {
+		t.Error("Expected an unverified email not to link an existing user")
+	}
+	provider.claims["email_verified"] = true
+	if _, err := provider.login(t, rp, nil); err != nil {
+		t.Errorf("Expected a verified email to link the user: %v", err)
+	}
+	provider.claims["sub"] = "idp-3"
+	if _, err := provider.login(t, rp, nil); err == nil {
+		t.Error("Expected another subject to be rejected for a linked user")
+	}
+}
+
+func TestOIDCCallbackRejections(t *testing.T) {
+	ta := NewTestAuth(t)
+	provider := newTestOIDCProvider(t, ta.Clock)
+	rp, _ := ta.OIDC(OIDCConfig{
+		Issuer:       provider.URL,
+		ClientID:     "bff",
+		ClientSecret: "bff-secret",
+		RedirectURL:  "https://bff.test/callback",
+		CreateUsers:  true,
+	})
+	provider.claims = jwt.MapClaims{"sub": "idp-1", "preferred_username": "dana"}
+
+	if _, err := provider.login(t, rp, func(q url.Values) { q.Set("state", "forged") }); !isCode(err, ErrCodeInvalidCredentials) {
+		t.Errorf("Expected a state mismatch to be rejected, got %v", err)
+	}
+	provider.claims["nonce"] = "replayed"
+	if _, err := provider.login(t, rp, nil); !isCode(err, ErrCodeInvalidToken) {
+		t.Errorf("Expected a nonce mismatch to be rejected, got %v", err)
+	}
+	delete(provider.claims, "nonce")
+	provider.claims["aud"] = "other-client"
+	if _, err := provider.login(t, rp, nil); !isCode(err, ErrCodeInvalidToken) {
+		t.Errorf("Expected another audience to be rejected, got %v", err)
+	}
+}
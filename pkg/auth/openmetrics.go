@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+)
+
+// OpenMetricsContentType is the content type of the OpenMetrics text format.
+const OpenMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// defaultFunnels are always exposed, so dashboards see zeros before the
+// first flow starts.
+var defaultFunnels = []string{FunnelPasswordReset, FunnelEmailVerification, FunnelStepUp}
+
+// WriteOpenMetrics writes the funnel metrics in the OpenMetrics text
+// format: one started and one completed counter per funnel, labelled
+// with the funnel name.
+func (mc *MetricsCollector) WriteOpenMetrics(w io.Writer) error {
+	funnels := mc.GetMetrics().Funnels
+	names := append([]string(nil), defaultFunnels...)
+	for name := range funnels {
+		if !slices.Contains(defaultFunnels, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	buf := bufio.NewWriter(w)
+	for _, family := range []struct {
+		name, help string
+		value      func(FunnelMetrics) int64
+	}{
+		{"goauth_funnel_started", "Account flows started, e.g. reset tokens created.", func(f FunnelMetrics) int64 { return f.Started }},
+		{"goauth_funnel_completed", "Account flows completed, e.g. reset tokens consumed.", func(f FunnelMetrics) int64 { return f.Completed }},
+	} {
+		fmt.Fprintf(buf, "# TYPE %s counter\n# HELP %s %s\n", family.name, family.name, family.help)
+		for _, name := range names {
+			fmt.Fprintf(buf, "%s_total{funnel=%q} %d\n", family.name, name, family.value(funnels[name]))
+		}
+	}
+	fmt.Fprint(buf, "# EOF\n")
+	return buf.Flush()
+}
+
+// HTTPOpenMetricsHandler returns an HTTP handler exposing the funnel
+// metrics in the OpenMetrics text format.
+func (m *Monitor) HTTPOpenMetricsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if m.metricsCollector == nil {
+			http.Error(w, "Metrics collector not available", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", OpenMetricsContentType)
+		if err := m.metricsCollector.WriteOpenMetrics(w); err != nil {
+			m.logger.Error("Failed to write OpenMetrics response", map[string]interface{}{
+				"error": err,
+			})
+		}
+	}
+}
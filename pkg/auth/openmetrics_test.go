@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestFunnelMetrics(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("alice", "alice-password")
+	ctx := context.Background()
+
+	// Two resets requested, one completed
+	for i := 0; i < 2; i++ {
+		if err := ta.Emails().SendPasswordReset(ctx, "alice@example.test"); err != nil {
+			t.Fatalf("SendPasswordReset failed: %v", err)
+		}
+	}
+	if err := ta.Users().ResetPassword(ta.LastResetTokenFor("alice@example.test"), "new-password-123"); err != nil {
+		t.Fatalf("ResetPassword failed: %v", err)
+	}
+
+	// One verification sent and verified
+	if err := ta.Emails().SendVerification(ctx, user.ID); err != nil {
+		t.Fatalf("SendVerification failed: %v", err)
+	}
+	link := ta.LastLinkFor("alice@example.test", EmailVerification)
+	if _, err := ta.Tokens().VerifyURL(httptest.NewRequest(http.MethodGet, link, nil), PurposeEmailVerification); err != nil {
+		t.Fatalf("VerifyURL failed: %v", err)
+	}
+
+	funnels := ta.GetMetrics().Funnels
+	if reset := funnels[FunnelPasswordReset]; reset.Started != 2 || reset.Completed != 1 || reset.DropOff() != 0.5 {
+		t.Errorf("Unexpected password reset funnel: %+v", reset)
+	}
+	if verification := funnels[FunnelEmailVerification]; verification.Started != 1 || verification.Completed != 1 {
+		t.Errorf("Unexpected verification funnel: %+v", verification)
+	}
+
+	mux := http.NewServeMux()
+	ta.monitor.RegisterHTTPHandlers(mux)
+	recorder := httptest.NewRecorder()
+	mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics/openmetrics", nil))
+	if got := recorder.Header().Get("Content-Type"); got != OpenMetricsContentType {
+		t.Errorf("Expected the OpenMetrics content type, got %q", got)
+	}
+	body := recorder.Body.String()
+	for _, line := range []string{
+		"# TYPE goauth_funnel_started counter",
+		`goauth_funnel_started_total{funnel="password_reset"} 2`,
+		`goauth_funnel_completed_total{funnel="password_reset"} 1`,
+		`goauth_funnel_completed_total{funnel="email_verification"} 1`,
+		`goauth_funnel_started_total{funnel="step_up"} 0`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected %q in the exposition, got:\n%s", line, body)
+		}
+	}
+	if !strings.HasSuffix(body, "# EOF\n") {
+		t.Errorf("Expected the exposition to end with # EOF, got:\n%s", body)
+	}
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pragneshbagary/go-auth/internal/storage/memory"
+)
+
+func newPASETOAuth(t *testing.T) *Auth {
+	t.Helper()
+	a, err := newAuthWithStorage(memory.NewInMemoryStorage(), &AuthConfig{
+		JWTSecret:          "go-auth-test-secret",
+		JWTRefreshSecret:   "go-auth-test-refresh-secret",
+		LogLevel:           "error",
+		PasswordHashParams: TestHashParams,
+		TokenFormat:        TokenFormatPASETO,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create auth: %v", err)
+	}
+	t.Cleanup(func() { a.Close() })
+	if _, err := a.Register(RegisterRequest{Username: "alice", Email: "alice@example.com", Password: "alice-password"}); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	return a
+}
+
+func TestPASETOTokens(t *testing.T) {
+	a := newPASETOAuth(t)
+
+	result, err := a.Login("alice", "alice-password", map[string]interface{}{"role": "admin"})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	for _, token := range []string{result.AccessToken, result.RefreshToken} {
+		if !strings.HasPrefix(token, "v4.local.") {
+			t.Fatalf("Expected a PASETO v4.local token, got %q", token)
+		}
+	}
+
+	claims, err := a.ValidateAccessToken(result.AccessToken)
+	if err != nil {
+		t.Fatalf("Failed to validate access token: %v", err)
+	}
+	if claims["role"] != "admin" || claims["username"] != "alice" || claims["token_type"] != "access" {
+		t.Errorf("Unexpected claims: %v", claims)
+	}
+	if _, ok := claims["exp"].(float64); !ok {
+		t.Errorf("Expected exp as Unix seconds, got %T", claims["exp"])
+	}
+
+	if _, err := a.ValidateAccessToken(result.RefreshToken); err == nil {
+		t.Error("Expected a refresh token to be rejected as an access token")
+	}
+	tampered := result.AccessToken[:len(result.AccessToken)-4] + "AAAA"
+	if tampered == result.AccessToken {
+		tampered = result.AccessToken[:len(result.AccessToken)-4] + "BBBB"
+	}
+	if _, err := a.ValidateAccessToken(tampered); err == nil {
+		t.Error("Expected a tampered token to be rejected")
+	}
+
+	refreshed, err := a.RefreshToken(result.RefreshToken)
+	if err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+	if _, err := a.ValidateAccessToken(refreshed.AccessToken); err != nil {
+		t.Errorf("Failed to validate refreshed access token: %v", err)
+	}
+
+	if err := a.Tokens().Revoke(refreshed.AccessToken); err != nil {
+		t.Fatalf("Failed to revoke: %v", err)
+	}
+	if _, err := a.ValidateAccessToken(refreshed.AccessToken); err == nil {
+		t.Error("Expected a revoked token to be rejected")
+	}
+}
+
+func TestTokenFormatConfig(t *testing.T) {
+	_, err := newAuthWithStorage(memory.NewInMemoryStorage(), &AuthConfig{
+		JWTSecret:   "go-auth-test-secret",
+		LogLevel:    "error",
+		TokenFormat: "macaroon",
+	})
+	if !isCode(err, ErrCodeInvalidConfig) {
+		t.Errorf("Expected INVALID_CONFIG for an unknown token format, got %v", err)
+	}
+}
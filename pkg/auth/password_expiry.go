@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// passwordChangedMetadataKey is the user metadata key holding the time of
+// the last password change. Users without it count from CreatedAt.
+const passwordChangedMetadataKey = "password_changed_at"
+
+// passwordResetRequiredMetadataKey is the user metadata key set by
+// AdminJobs.ForcePasswordReset. The password counts as expired until it is
+// changed or reset.
+const passwordResetRequiredMetadataKey = "password_reset_required"
+
+// PasswordExpiryConfig expires passwords after a maximum age. Users with an
+// expired password cannot log in until they change it with
+// Users().ChangePassword or reset it.
+type PasswordExpiryConfig struct {
+	// MaxAge of passwords. Zero disables expiry.
+	MaxAge time.Duration
+	// WarnBefore is how long before expiry access tokens carry a
+	// "password_expires_at" claim (Unix seconds). Defaults to 14 days.
+	WarnBefore time.Duration
+}
+
+// passwordChangedAt returns when the user's password was last changed.
+func passwordChangedAt(user *models.User) time.Time {
+	if value, ok := user.Metadata[passwordChangedMetadataKey].(string); ok {
+		if changedAt, err := time.Parse(time.RFC3339, value); err == nil {
+			return changedAt
+		}
+	}
+	return user.CreatedAt
+}
+
+// PasswordExpiresAt returns when the user's password expires, false if
+// passwords do not expire.
+func (a *Auth) PasswordExpiresAt(user *models.User) (time.Time, bool) {
+	maxAge := a.config.PasswordExpiry.MaxAge
+	if maxAge <= 0 {
+		return time.Time{}, false
+	}
+	return passwordChangedAt(user).Add(maxAge), true
+}
+
+// checkPasswordExpiry returns ErrPasswordExpired for expired passwords. If
+// expiry is near it returns the expiry time to warn about, else zero.
+func (a *Auth) checkPasswordExpiry(user *models.User) (time.Time, error) {
+	if required, _ := user.Metadata[passwordResetRequiredMetadataKey].(bool); required {
+		return time.Time{}, ErrPasswordExpired()
+	}
+	expiresAt, ok := a.PasswordExpiresAt(user)
+	if !ok {
+		return time.Time{}, nil
+	}
+	now := nowFrom(a.clock)
+	if !now.Before(expiresAt) {
+		return time.Time{}, ErrPasswordExpired()
+	}
+	warnBefore := a.config.PasswordExpiry.WarnBefore
+	if warnBefore <= 0 {
+		warnBefore = 14 * 24 * time.Hour
+	}
+	if expiresAt.Sub(now) > warnBefore {
+		return time.Time{}, nil
+	}
+	return expiresAt, nil
+}
+
+// recordPasswordChange stores the time of a password change and lifts a
+// forced reset.
+func (u *Users) recordPasswordChange(userID string) error {
+	user, err := u.storage.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	metadata := make(map[string]interface{}, len(user.Metadata)+1)
+	for k, v := range user.Metadata {
+		metadata[k] = v
+	}
+	delete(metadata, passwordResetRequiredMetadataKey)
+	metadata[passwordChangedMetadataKey] = nowFrom(u.clock).UTC().Format(time.RFC3339)
+	return u.storage.UpdateUser(userID, storage.UserUpdates{Metadata: metadata})
+}
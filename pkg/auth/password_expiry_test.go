@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPasswordExpiry(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.config.PasswordExpiry = PasswordExpiryConfig{MaxAge: 90 * 24 * time.Hour, WarnBefore: 7 * 24 * time.Hour}
+	user := ta.SeedUser("alice", "alice-password")
+
+	result := ta.LoginAs("alice")
+	claims, err := ta.ValidateAccessToken(result.AccessToken)
+	if err != nil {
+		t.Fatalf("Failed to validate token: %v", err)
+	}
+	if _, ok := claims["password_expires_at"]; ok {
+		t.Error("Expected no expiry warning for a fresh password")
+	}
+
+	ta.Clock.Advance(85 * 24 * time.Hour)
+	result = ta.LoginAs("alice")
+	claims, _ = ta.ValidateAccessToken(result.AccessToken)
+	expiresAt, _ := ta.PasswordExpiresAt(user)
+	if warning, _ := claims["password_expires_at"].(float64); int64(warning) != expiresAt.Unix() {
+		t.Errorf("Expected expiry warning %d, got %v", expiresAt.Unix(), claims["password_expires_at"])
+	}
+
+	ta.Clock.Advance(6 * 24 * time.Hour)
+	_, err = ta.Login("alice", "alice-password", nil)
+	var authErr *AuthError
+	if !errors.As(err, &authErr) || authErr.Code != ErrCodePasswordExpired {
+		t.Fatalf("Expected PASSWORD_EXPIRED, got %v", err)
+	}
+
+	// Changing the password restarts its age
+	if err := ta.Users().ChangePassword(user.ID, "alice-password", "alice-new-password"); err != nil {
+		t.Fatalf("Failed to change password: %v", err)
+	}
+	if _, err := ta.Login("alice", "alice-new-password", nil); err != nil {
+		t.Errorf("Expected login with the new password to succeed: %v", err)
+	}
+}
+
+func TestPasswordExpiryReset(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.config.PasswordExpiry = PasswordExpiryConfig{MaxAge: 90 * 24 * time.Hour}
+	user := ta.SeedUser("bob", "bob-password")
+
+	ta.Clock.Advance(91 * 24 * time.Hour)
+	if _, err := ta.Login("bob", "bob-password", nil); !errors.Is(err, ErrPasswordExpired()) {
+		t.Fatalf("Expected PASSWORD_EXPIRED, got %v", err)
+	}
+
+	// Resetting restarts the age even without password change hooks
+	reset, err := ta.Users().CreateResetToken(user.Email)
+	if err != nil {
+		t.Fatalf("Failed to create reset token: %v", err)
+	}
+	if err := ta.Users().ResetPassword(reset.Token, "bob-new-password"); err != nil {
+		t.Fatalf("Failed to reset password: %v", err)
+	}
+	if _, err := ta.Login("bob", "bob-new-password", nil); err != nil {
+		t.Errorf("Expected login after the reset to succeed: %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// PurposeInvite is the action token purpose of invite links. Sign them
+// with Tokens().SignAction and the invited user's ID as subject so they
+// show up in Users().ListPendingTokens.
+const PurposeInvite = "invite"
+
+// Kinds of pending tokens, see Users.ListPendingTokens.
+const (
+	PendingPasswordReset = EmailPasswordReset
+	PendingVerification  = EmailVerification
+	PendingMagicLink     = EmailMagicLink
+	PendingInvite        = "invite"
+)
+
+// pendingPurposes maps the tracked action token purposes to their kind.
+var pendingPurposes = map[string]string{
+	PurposeEmailVerification: PendingVerification,
+	PurposeMagicLink:         PendingMagicLink,
+	PurposeInvite:            PendingInvite,
+}
+
+// PendingToken is an emailed token that has not expired yet. The token
+// itself is never exposed.
+type PendingToken struct {
+	// ID identifies the token: the action token ID, or a fingerprint of
+	// a reset token or code.
+	ID        string    `json:"id"`
+	Kind      string    `json:"kind"`
+	UserID    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// pendingTokens remembers the action tokens issued for tracked purposes
+// until they expire. A nil registry tracks nothing.
+type pendingTokens struct {
+	mu     sync.Mutex
+	byUser map[string][]PendingToken
+}
+
+func newPendingTokens() *pendingTokens {
+	return &pendingTokens{byUser: make(map[string][]PendingToken)}
+}
+
+// add records token if its purpose is tracked.
+func (p *pendingTokens) add(purpose string, token PendingToken) {
+	kind, ok := pendingPurposes[purpose]
+	if p == nil || !ok || token.UserID == "" {
+		return
+	}
+	token.Kind = kind
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.byUser[token.UserID] = append(p.byUser[token.UserID], token)
+}
+
+// list returns the unexpired tokens of userID, dropping expired ones.
+func (p *pendingTokens) list(userID string, now time.Time) []PendingToken {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var live []PendingToken
+	for _, token := range p.byUser[userID] {
+		if now.Before(token.ExpiresAt) {
+			live = append(live, token)
+		}
+	}
+	if len(live) == 0 {
+		delete(p.byUser, userID)
+	} else {
+		p.byUser[userID] = live
+	}
+	return append([]PendingToken(nil), live...)
+}
+
+// remove forgets the tokens of userID of kind, or of every kind if kind
+// is empty, and returns the unexpired ones.
+func (p *pendingTokens) remove(userID, kind string, now time.Time) []PendingToken {
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var kept, removed []PendingToken
+	for _, token := range p.byUser[userID] {
+		switch {
+		case !now.Before(token.ExpiresAt):
+		case kind == "" || token.Kind == kind:
+			removed = append(removed, token)
+		default:
+			kept = append(kept, token)
+		}
+	}
+	if len(kept) == 0 {
+		delete(p.byUser, userID)
+	} else {
+		p.byUser[userID] = kept
+	}
+	return removed
+}
+
+// resetTokenID returns the fingerprint identifying a stored reset token.
+func resetTokenID(token storage.ResetToken) string {
+	return token.ID[:16]
+}
+
+// ListPendingTokens returns the unexpired reset, verification, magic-link
+// and invite tokens of userID, oldest first.
+func (u *Users) ListPendingTokens(userID string) ([]PendingToken, error) {
+	if userID == "" {
+		return nil, ErrValidationError("user ID")
+	}
+	now := nowFrom(u.clock)
+
+	resets, err := resetTokenStorage(u.storage).ListResetTokens(userID)
+	if err != nil {
+		return nil, WrapDatabaseError(err)
+	}
+	var tokens []PendingToken
+	for _, reset := range resets {
+		if !now.After(reset.ExpiresAt) {
+			tokens = append(tokens, PendingToken{
+				ID:        resetTokenID(reset),
+				Kind:      PendingPasswordReset,
+				UserID:    userID,
+				CreatedAt: reset.CreatedAt,
+				ExpiresAt: reset.ExpiresAt,
+			})
+		}
+	}
+	tokens = append(tokens, u.pending.list(userID, now)...)
+	sort.SliceStable(tokens, func(i, j int) bool { return tokens[i].CreatedAt.Before(tokens[j].CreatedAt) })
+	return tokens, nil
+}
+
+// RevokePendingTokens invalidates the pending tokens of userID of kind, or
+// of every kind if kind is empty, e.g. after an email went to the wrong
+// address. It returns the number of tokens revoked.
+func (u *Users) RevokePendingTokens(userID, kind string) (int, error) {
+	if userID == "" {
+		return 0, ErrValidationError("user ID")
+	}
+	switch kind {
+	case "", PendingPasswordReset, PendingVerification, PendingMagicLink, PendingInvite:
+	default:
+		return 0, ErrValidationError("kind")
+	}
+
+	now := nowFrom(u.clock)
+	revoked := 0
+	if kind == "" || kind == PendingPasswordReset {
+		tokens := resetTokenStorage(u.storage)
+		resets, err := tokens.ListResetTokens(userID)
+		if err != nil {
+			return 0, WrapDatabaseError(err)
+		}
+		for _, reset := range resets {
+			deleted, err := tokens.DeleteResetToken(reset.ID)
+			if err != nil {
+				return revoked, WrapDatabaseError(err)
+			}
+			if deleted && !now.After(reset.ExpiresAt) {
+				revoked++
+			}
+		}
+	}
+	for _, token := range u.pending.remove(userID, kind, now) {
+		if err := u.storage.BlacklistToken(token.ID, token.ExpiresAt); err != nil {
+			return revoked, WrapDatabaseError(err)
+		}
+		revoked++
+	}
+
+	if u.eventLogger != nil {
+		u.eventLogger.LogPendingTokenRevocation(userID, kind, revoked)
+	}
+	return revoked, nil
+}
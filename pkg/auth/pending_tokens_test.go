@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPendingTokens(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("alice", "alice-password")
+	users := ta.Users()
+
+	reset, err := users.CreateResetToken("alice@example.test")
+	if err != nil {
+		t.Fatalf("CreateResetToken failed: %v", err)
+	}
+	ta.Clock.Advance(time.Second)
+	verification, err := ta.Emails().Verification(user.ID)
+	if err != nil {
+		t.Fatalf("Verification failed: %v", err)
+	}
+	ta.Clock.Advance(time.Second)
+	invite, err := ta.Tokens().SignAction(PurposeInvite, user.ID, nil, time.Hour)
+	if err != nil {
+		t.Fatalf("SignAction failed: %v", err)
+	}
+	// Other purposes are not tracked
+	if _, err := ta.Tokens().SignAction("unsubscribe", user.ID, nil, time.Hour); err != nil {
+		t.Fatalf("SignAction failed: %v", err)
+	}
+
+	pending, err := users.ListPendingTokens(user.ID)
+	if err != nil {
+		t.Fatalf("ListPendingTokens failed: %v", err)
+	}
+	if len(pending) != 3 || pending[0].Kind != PendingPasswordReset || pending[1].Kind != PendingVerification || pending[2].Kind != PendingInvite {
+		t.Fatalf("Expected the reset, verification and invite tokens, got %+v", pending)
+	}
+	if pending[0].ID == reset.Token {
+		t.Error("Expected the reset token itself not to be exposed")
+	}
+
+	// Revoking one kind leaves the others
+	if revoked, err := users.RevokePendingTokens(user.ID, PendingVerification); err != nil || revoked != 1 {
+		t.Fatalf("Expected 1 verification token revoked, got %d, %v", revoked, err)
+	}
+	link := findLink(t, verification.Text)
+	if _, err := ta.Tokens().VerifyURL(httptest.NewRequest(http.MethodGet, link, nil), PurposeEmailVerification); !errors.Is(err, ErrInvalidToken()) {
+		t.Errorf("Expected the revoked verification link to be rejected, got %v", err)
+	}
+	if _, err := ta.Tokens().VerifyAction(invite, PurposeInvite); err != nil {
+		t.Errorf("Expected the invite to stay valid, got %v", err)
+	}
+
+	// Revoking every kind
+	if revoked, err := users.RevokePendingTokens(user.ID, ""); err != nil || revoked != 2 {
+		t.Fatalf("Expected 2 tokens revoked, got %d, %v", revoked, err)
+	}
+	if err := users.ResetPassword(reset.Token, "new-password-123"); err == nil {
+		t.Error("Expected the revoked reset token to be rejected")
+	}
+	if _, err := ta.Tokens().VerifyAction(invite, PurposeInvite); err == nil {
+		t.Error("Expected the revoked invite to be rejected")
+	}
+	if pending, _ := users.ListPendingTokens(user.ID); len(pending) != 0 {
+		t.Errorf("Expected no pending tokens, got %+v", pending)
+	}
+
+	if _, err := users.RevokePendingTokens(user.ID, "unknown"); !errors.Is(err, ErrValidationError("")) {
+		t.Errorf("Expected an unknown kind to be rejected, got %v", err)
+	}
+}
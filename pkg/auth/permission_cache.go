@@ -0,0 +1,356 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PermissionLoader loads the permissions of a user within a tenant. An empty
+// tenantID denotes the default tenant.
+type PermissionLoader func(userID, tenantID string) ([]string, error)
+
+// PermissionInvalidation announces that cached permissions are out of date.
+// An empty UserID matches all users and an empty TenantID matches all
+// tenants, so a changed role definition can drop a whole tenant at once.
+type PermissionInvalidation struct {
+	UserID   string    `json:"user_id,omitempty"`
+	TenantID string    `json:"tenant_id,omitempty"`
+	IssuedAt time.Time `json:"issued_at"`
+	// Source identifies the publishing cache so it can skip its own events.
+	Source string `json:"source,omitempty"`
+}
+
+// InvalidationBus distributes permission invalidations between caches,
+// e.g. across the instances of a service.
+type InvalidationBus interface {
+	Publish(event PermissionInvalidation) error
+	// Subscribe calls handler for every published event until the returned
+	// function is called.
+	Subscribe(handler func(PermissionInvalidation)) (unsubscribe func(), err error)
+}
+
+// localInvalidationBus delivers events to subscribers in the same process.
+type localInvalidationBus struct {
+	mu       sync.RWMutex
+	next     int
+	handlers map[int]func(PermissionInvalidation)
+}
+
+// NewLocalInvalidationBus creates an in-process InvalidationBus. Events are
+// delivered synchronously.
+func NewLocalInvalidationBus() InvalidationBus {
+	return &localInvalidationBus{handlers: make(map[int]func(PermissionInvalidation))}
+}
+
+func (b *localInvalidationBus) Publish(event PermissionInvalidation) error {
+	b.mu.RLock()
+	handlers := make([]func(PermissionInvalidation), 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+	return nil
+}
+
+func (b *localInvalidationBus) Subscribe(handler func(PermissionInvalidation)) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.next
+	b.next++
+	b.handlers[id] = handler
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, id)
+	}, nil
+}
+
+// RedisPubSub is the subset of a Redis client used by the Redis invalidation
+// bus. A go-redis client is adapted with a few lines: Publish forwards to
+// client.Publish, Subscribe forwards the payloads of client.Subscribe(...).Channel()
+// and closes the subscription when ctx is done.
+type RedisPubSub interface {
+	Publish(ctx context.Context, channel string, message []byte) error
+	// Subscribe delivers messages published to channel until ctx is done.
+	Subscribe(ctx context.Context, channel string) (<-chan []byte, error)
+}
+
+// DefaultInvalidationChannel is the Redis channel used for permission invalidations.
+const DefaultInvalidationChannel = "go-auth:permissions:invalidate"
+
+// redisInvalidationBus publishes JSON-encoded events on a Redis channel.
+type redisInvalidationBus struct {
+	client  RedisPubSub
+	channel string
+}
+
+// NewRedisInvalidationBus creates an InvalidationBus on a Redis channel so
+// that role changes on one instance invalidate the caches of all instances.
+// channel defaults to DefaultInvalidationChannel.
+func NewRedisInvalidationBus(client RedisPubSub, channel string) InvalidationBus {
+	if channel == "" {
+		channel = DefaultInvalidationChannel
+	}
+	return &redisInvalidationBus{client: client, channel: channel}
+}
+
+func (b *redisInvalidationBus) Publish(event PermissionInvalidation) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(context.Background(), b.channel, payload)
+}
+
+func (b *redisInvalidationBus) Subscribe(handler func(PermissionInvalidation)) (func(), error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	messages, err := b.client.Subscribe(ctx, b.channel)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go func() {
+		for payload := range messages {
+			var event PermissionInvalidation
+			if err := json.Unmarshal(payload, &event); err != nil {
+				continue
+			}
+			handler(event)
+		}
+	}()
+	return cancel, nil
+}
+
+// PermissionCacheConfig configures a PermissionCache.
+type PermissionCacheConfig struct {
+	// Loader loads permissions on a cache miss. For the cache of an Auth
+	// instance it defaults to the "roles" and "permissions" lists in the
+	// user's metadata and the roles synced by a GroupSyncer.
+	Loader PermissionLoader
+	// Bus distributes invalidations. Defaults to an in-process bus; use
+	// NewRedisInvalidationBus when running several instances.
+	Bus InvalidationBus
+	// TTL bounds how long permissions are cached if an invalidation is lost.
+	// Defaults to 5 minutes.
+	TTL time.Duration
+	// Clock is the time source; defaults to the system clock.
+	Clock Clock
+}
+
+// PermissionCacheStats reports cache effectiveness and staleness.
+type PermissionCacheStats struct {
+	Hits          int64 `json:"hits"`
+	Misses        int64 `json:"misses"`
+	Entries       int   `json:"entries"`
+	Invalidations int64 `json:"invalidations"`
+	Evictions     int64 `json:"evictions"`
+	// InvalidationLag is the time between an invalidation being published
+	// and it being applied, i.e. how long stale permissions may be served.
+	LastInvalidationLag time.Duration `json:"last_invalidation_lag"`
+	MaxInvalidationLag  time.Duration `json:"max_invalidation_lag"`
+	// MaxEntryAge is the age of the oldest entry evicted by an invalidation.
+	MaxEntryAge time.Duration `json:"max_entry_age"`
+}
+
+// permissionEntry is a cached permission set.
+type permissionEntry struct {
+	permissions []string
+	loadedAt    time.Time
+}
+
+type permissionKey struct {
+	userID   string
+	tenantID string
+}
+
+// PermissionCache caches permission lookups per user and tenant so that
+// per-request authorization does not hit storage. Entries are dropped when
+// an invalidation arrives on the bus or after the TTL.
+type PermissionCache struct {
+	id          string
+	loader      PermissionLoader
+	bus         InvalidationBus
+	ttl         time.Duration
+	clock       Clock
+	unsubscribe func()
+
+	mu      sync.Mutex
+	entries map[permissionKey]*permissionEntry
+	stats   PermissionCacheStats
+	// generation counts applied invalidations so that a load racing with
+	// an invalidation does not cache its stale result.
+	generation uint64
+}
+
+// NewPermissionCache creates a PermissionCache subscribed to config.Bus.
+// Call Close to unsubscribe.
+func NewPermissionCache(config PermissionCacheConfig) (*PermissionCache, error) {
+	if config.Loader == nil {
+		return nil, NewAuthError(ErrCodeInvalidConfig, "Permission loader is required")
+	}
+	if config.Bus == nil {
+		config.Bus = NewLocalInvalidationBus()
+	}
+	if config.TTL <= 0 {
+		config.TTL = 5 * time.Minute
+	}
+
+	c := &PermissionCache{
+		id:      uuid.New().String(),
+		loader:  config.Loader,
+		bus:     config.Bus,
+		ttl:     config.TTL,
+		clock:   config.Clock,
+		entries: make(map[permissionKey]*permissionEntry),
+	}
+
+	unsubscribe, err := config.Bus.Subscribe(func(event PermissionInvalidation) {
+		if event.Source != c.id {
+			c.apply(event)
+		}
+	})
+	if err != nil {
+		return nil, WrapError(err, ErrCodeConnectionError, "Failed to subscribe to permission invalidations")
+	}
+	c.unsubscribe = unsubscribe
+	return c, nil
+}
+
+// Permissions returns the permissions of userID within tenantID, loading
+// them on a miss.
+func (c *PermissionCache) Permissions(userID, tenantID string) ([]string, error) {
+	if userID == "" {
+		return nil, ErrValidationError("user ID")
+	}
+	key := permissionKey{userID: userID, tenantID: tenantID}
+	now := nowFrom(c.clock)
+
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok && now.Sub(entry.loadedAt) < c.ttl {
+		c.stats.Hits++
+		c.mu.Unlock()
+		return entry.permissions, nil
+	}
+	c.stats.Misses++
+	generation := c.generation
+	c.mu.Unlock()
+
+	permissions, err := c.loader(userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if c.generation == generation {
+		c.entries[key] = &permissionEntry{permissions: permissions, loadedAt: now}
+	}
+	c.mu.Unlock()
+	return permissions, nil
+}
+
+// HasPermission reports whether userID holds permission within tenantID.
+func (c *PermissionCache) HasPermission(userID, tenantID, permission string) (bool, error) {
+	permissions, err := c.Permissions(userID, tenantID)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range permissions {
+		if p == permission {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Invalidate drops the cached permissions of userID within tenantID and
+// notifies the other caches on the bus. Call it whenever roles or role
+// assignments change; empty arguments act as wildcards.
+func (c *PermissionCache) Invalidate(userID, tenantID string) error {
+	event := PermissionInvalidation{
+		UserID:   userID,
+		TenantID: tenantID,
+		IssuedAt: nowFrom(c.clock),
+		Source:   c.id,
+	}
+	c.apply(event)
+	return c.bus.Publish(event)
+}
+
+// apply evicts the entries matched by event and records its staleness.
+func (c *PermissionCache) apply(event PermissionInvalidation) {
+	now := nowFrom(c.clock)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.stats.Invalidations++
+	if lag := now.Sub(event.IssuedAt); lag >= 0 {
+		c.stats.LastInvalidationLag = lag
+		if lag > c.stats.MaxInvalidationLag {
+			c.stats.MaxInvalidationLag = lag
+		}
+	}
+
+	for key, entry := range c.entries {
+		if (event.UserID != "" && key.userID != event.UserID) ||
+			(event.TenantID != "" && key.tenantID != event.TenantID) {
+			continue
+		}
+		if age := now.Sub(entry.loadedAt); age > c.stats.MaxEntryAge {
+			c.stats.MaxEntryAge = age
+		}
+		delete(c.entries, key)
+		c.stats.Evictions++
+	}
+}
+
+// Stats returns a snapshot of the cache metrics.
+func (c *PermissionCache) Stats() PermissionCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = len(c.entries)
+	return stats
+}
+
+// Close unsubscribes the cache from its bus.
+func (c *PermissionCache) Close() {
+	if c.unsubscribe != nil {
+		c.unsubscribe()
+	}
+}
+
+// metadataPermissionLoader returns a PermissionLoader reading the "roles" and
+// "permissions" lists of the user's metadata and the roles synced from
+// external directories. Tenants are not distinguished.
+func (a *Auth) metadataPermissionLoader() PermissionLoader {
+	return func(userID, tenantID string) ([]string, error) {
+		user, err := a.storage.GetUserByID(userID)
+		if err != nil {
+			return nil, ErrUserNotFound()
+		}
+
+		var permissions []string
+		permissions = append(permissions, stringList(user.Metadata["roles"])...)
+		permissions = append(permissions, stringList(user.Metadata["permissions"])...)
+		permissions = append(permissions, directoryRoles(user)...)
+		return permissions, nil
+	}
+}
+
+// Permissions returns the permission cache of the Auth instance. Its entries
+// are invalidated automatically when user metadata is updated through
+// Users().Update; call Invalidate after changing roles elsewhere.
+func (a *Auth) Permissions() *PermissionCache {
+	return a.permissions
+}
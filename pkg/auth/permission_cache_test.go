@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestPermissionCacheInvalidation(t *testing.T) {
+	clock := NewFrozenClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	roles := map[string][]string{"alice|acme": {"reader"}}
+	loads := 0
+	loader := func(userID, tenantID string) ([]string, error) {
+		loads++
+		return roles[userID+"|"+tenantID], nil
+	}
+
+	bus := NewLocalInvalidationBus()
+	first, err := NewPermissionCache(PermissionCacheConfig{Loader: loader, Bus: bus, Clock: clock})
+	if err != nil {
+		t.Fatalf("Failed to create cache: %v", err)
+	}
+	defer first.Close()
+	second, _ := NewPermissionCache(PermissionCacheConfig{Loader: loader, Bus: bus, Clock: clock})
+	defer second.Close()
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := first.HasPermission("alice", "acme", "reader"); !ok {
+			t.Fatal("Expected alice to be a reader")
+		}
+	}
+	second.Permissions("alice", "acme")
+	second.Permissions("alice", "other")
+	if loads != 3 {
+		t.Errorf("Expected one load per cache and tenant, got %d", loads)
+	}
+
+	// A role change on one instance must reach the other
+	roles["alice|acme"] = []string{"writer"}
+	clock.Advance(time.Minute)
+	if err := first.Invalidate("alice", "acme"); err != nil {
+		t.Fatalf("Failed to invalidate: %v", err)
+	}
+	if ok, _ := second.HasPermission("alice", "acme", "writer"); !ok {
+		t.Error("Expected invalidation to reach the second cache")
+	}
+
+	stats := second.Stats()
+	if stats.Invalidations != 1 || stats.Evictions != 1 || stats.Entries != 2 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.MaxEntryAge != time.Minute {
+		t.Errorf("Expected evicted entry age of 1m, got %s", stats.MaxEntryAge)
+	}
+	if s := first.Stats(); s.Hits != 2 || s.Misses != 1 || s.Invalidations != 1 {
+		t.Errorf("Unexpected stats of publishing cache: %+v", s)
+	}
+
+	// Empty user invalidates the whole tenant
+	first.Invalidate("", "other")
+	if second.Stats().Entries != 1 {
+		t.Error("Expected tenant-wide invalidation to evict the other tenant only")
+	}
+}
+
+func TestPermissionCacheTTL(t *testing.T) {
+	clock := NewFrozenClock(time.Now())
+	loads := 0
+	cache, _ := NewPermissionCache(PermissionCacheConfig{
+		Loader: func(userID, tenantID string) ([]string, error) { loads++; return nil, nil },
+		TTL:    time.Minute,
+		Clock:  clock,
+	})
+	defer cache.Close()
+
+	cache.Permissions("alice", "")
+	clock.Advance(2 * time.Minute)
+	cache.Permissions("alice", "")
+	if loads != 2 {
+		t.Errorf("Expected expired entry to be reloaded, got %d loads", loads)
+	}
+}
+
+// fakeRedis is an in-memory RedisPubSub.
+type fakeRedis struct {
+	mu          sync.Mutex
+	subscribers map[string][]chan []byte
+}
+
+func (r *fakeRedis) Publish(ctx context.Context, channel string, message []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, ch := range r.subscribers[channel] {
+		ch <- message
+	}
+	return nil
+}
+
+func (r *fakeRedis) Subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ch := make(chan []byte, 16)
+	r.subscribers[channel] = append(r.subscribers[channel], ch)
+	return ch, nil
+}
+
+func TestRedisInvalidationBus(t *testing.T) {
+	redis := &fakeRedis{subscribers: make(map[string][]chan []byte)}
+	received := make(chan PermissionInvalidation, 1)
+
+	bus := NewRedisInvalidationBus(redis, "")
+	unsubscribe, err := bus.Subscribe(func(event PermissionInvalidation) { received <- event })
+	if err != nil {
+		t.Fatalf("Failed to subscribe: %v", err)
+	}
+	defer unsubscribe()
+
+	if err := bus.Publish(PermissionInvalidation{UserID: "alice", TenantID: "acme"}); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	select {
+	case event := <-received:
+		if event.UserID != "alice" || event.TenantID != "acme" {
+			t.Errorf("Unexpected event: %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected event on the default channel")
+	}
+}
+
+func TestUsersUpdateInvalidatesPermissions(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("alice", "alice-password", "reader")
+
+	if ok, _ := ta.Permissions().HasPermission(user.ID, "", "reader"); !ok {
+		t.Fatal("Expected seeded role to be loaded")
+	}
+
+	err := ta.Users().Update(user.ID, UserUpdate{Metadata: map[string]interface{}{"roles": []string{"admin"}}})
+	if err != nil {
+		t.Fatalf("Failed to update user: %v", err)
+	}
+	if ok, _ := ta.Permissions().HasPermission(user.ID, "", "admin"); !ok {
+		t.Error("Expected role change to invalidate cached permissions")
+	}
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+// maxDisplayNameLength is the maximum length of display names in characters.
+const maxDisplayNameLength = 100
+
+// localePattern matches BCP 47 language tags such as "en", "en-US" or
+// "zh-Hant-TW".
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// profileClaims are the OIDC standard claims carrying the profile basics.
+var profileClaims = []string{"name", "locale", "zoneinfo"}
+
+// validateProfileBasics checks the display name, locale and timezone of updates.
+func validateProfileBasics(updates UserUpdate) error {
+	if updates.DisplayName != nil && utf8.RuneCountInString(*updates.DisplayName) > maxDisplayNameLength {
+		return ErrValidationError("display name")
+	}
+	if updates.Locale != nil && *updates.Locale != "" && !localePattern.MatchString(*updates.Locale) {
+		return ErrValidationError("locale")
+	}
+	if updates.Timezone != nil && *updates.Timezone != "" {
+		if _, err := time.LoadLocation(*updates.Timezone); err != nil || *updates.Timezone == "Local" {
+			return ErrValidationError("timezone")
+		}
+	}
+	return nil
+}
+
+// addProfileClaims adds the name, locale and zoneinfo claims of user to
+// claims. Empty fields are left out.
+func addProfileClaims(user *models.User, claims map[string]interface{}) {
+	for claim, value := range map[string]string{
+		"name":     user.DisplayName,
+		"locale":   user.Locale,
+		"zoneinfo": user.Timezone,
+	} {
+		if value != "" {
+			claims[claim] = value
+		}
+	}
+}
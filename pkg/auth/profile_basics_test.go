@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestProfileBasics(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("alice", "alice-password")
+	users := ta.Users()
+
+	for name, update := range map[string]UserUpdate{
+		"locale":   {Locale: ptr("not a locale")},
+		"timezone": {Timezone: ptr("Mars/Olympus_Mons")},
+		"name":     {DisplayName: ptr(string(make([]rune, maxDisplayNameLength+1)))},
+	} {
+		if err := users.Update(user.ID, update); !errors.Is(err, ErrValidationError("")) {
+			t.Errorf("Expected an invalid %s to be rejected, got %v", name, err)
+		}
+	}
+
+	err := users.Update(user.ID, UserUpdate{
+		DisplayName: ptr("Alice Smith"),
+		Locale:      ptr("de-DE"),
+		Timezone:    ptr("Europe/Berlin"),
+	})
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	profile, err := users.Get(user.ID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if profile.DisplayName != "Alice Smith" || profile.Locale != "de-DE" || profile.Timezone != "Europe/Berlin" {
+		t.Errorf("Expected the profile basics in the profile, got %+v", profile)
+	}
+
+	// Profile claims are opt-in
+	claims, _ := ta.ValidateAccessToken(ta.LoginAs("alice").AccessToken)
+	if _, ok := claims["name"]; ok {
+		t.Errorf("Expected no profile claims by default, got %v", claims)
+	}
+	ta.claims = newClaimPolicy(ClaimsConfig{ProfileClaims: true}, ta.logger)
+	result, err := ta.Login("alice", "alice-password", map[string]interface{}{"locale": "fr"})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	claims, _ = ta.ValidateAccessToken(result.AccessToken)
+	if claims["name"] != "Alice Smith" || claims["locale"] != "de-DE" || claims["zoneinfo"] != "Europe/Berlin" {
+		t.Errorf("Expected the profile claims, got %v", claims)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
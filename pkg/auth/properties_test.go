@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+)
+
+// quickConfig bounds the number of cases of the slower properties.
+var quickConfig = &quick.Config{MaxCount: 50}
+
+func isCode(err error, code string) bool {
+	authErr, ok := err.(*AuthError)
+	return ok && authErr.Code == code
+}
+
+func TestPropertyPasswordLengthPolicy(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("alice", "alice-password")
+
+	property := func(password string) bool {
+		err := ResetPasswordRequest{Token: "token", NewPassword: password}.Validate()
+		switch {
+		case password == "":
+			return isCode(err, ErrCodeValidationError)
+		case len(password) < 8:
+			return isCode(err, ErrCodeWeakPassword) &&
+				isCode(ta.Users().ChangePassword(user.ID, "alice-password", password), ErrCodeWeakPassword)
+		default:
+			return err == nil
+		}
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+
+	// Short passwords are rare among random strings
+	short := func(password [7]byte, n uint8) bool {
+		return property(string(password[:int(n)%8]))
+	}
+	if err := quick.Check(short, nil); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPropertyPasswordHashRoundTrip(t *testing.T) {
+	hasher := NewArgon2Hasher(TestHashParams)
+	property := func(password, other string) bool {
+		hash, err := hasher.Hash(password)
+		if err != nil {
+			return false
+		}
+		match, err := hasher.Compare(password, hash)
+		if err != nil || !match {
+			return false
+		}
+		if other == password {
+			return true
+		}
+		match, err = hasher.Compare(other, hash)
+		return err == nil && !match
+	}
+	if err := quick.Check(property, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+// claimSet is a set of per-login claims whose names often collide with
+// standard and stored claims.
+type claimSet map[string]string
+
+var claimNames = []string{
+	"sub", "exp", "iat", "nbf", "jti", "iss", "token_type",
+	"username", "email", "user_id",
+	"role", "tenant", "plan", "scope",
+}
+
+func (claimSet) Generate(rng *rand.Rand, size int) reflect.Value {
+	claims := claimSet{}
+	for i := rng.Intn(len(claimNames) + 1); i > 0; i-- {
+		value, _ := quick.Value(reflect.TypeOf(""), rng)
+		claims[claimNames[rng.Intn(len(claimNames))]] = value.String()
+	}
+	return reflect.ValueOf(claims)
+}
+
+func TestPropertyLoginClaimsPrecedence(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("alice", "alice-password")
+	stored := ta.claims.stored(user)
+	standard := map[string]bool{"sub": true, "exp": true, "iat": true, "nbf": true, "jti": true, "iss": true, "token_type": true}
+
+	property := func(perLogin claimSet) bool {
+		custom := make(map[string]interface{}, len(perLogin))
+		for k, v := range perLogin {
+			custom[k] = v
+		}
+		result, err := ta.Login("alice", "alice-password", custom)
+		if err != nil {
+			return false
+		}
+		claims, err := ta.ValidateAccessToken(result.AccessToken)
+		if err != nil {
+			return false
+		}
+
+		if claims["sub"] != user.ID || claims["token_type"] != "access" || claims["iss"] != ta.config.JWTIssuer {
+			return false
+		}
+		for k, v := range stored {
+			if claims[k] != v {
+				return false
+			}
+		}
+		// Claims without a conflict are never dropped
+		for k, v := range perLogin {
+			if _, isStored := stored[k]; !standard[k] && !isStored && claims[k] != v {
+				return false
+			}
+		}
+		return true
+	}
+	if err := quick.Check(property, quickConfig); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestPropertyMergeClaims(t *testing.T) {
+	var policy *claimPolicy
+	property := func(stored, perLogin map[string]int) bool {
+		storedClaims := make(map[string]interface{}, len(stored))
+		for k, v := range stored {
+			storedClaims[k] = v
+		}
+		perLoginClaims := make(map[string]interface{}, len(perLogin))
+		for k, v := range perLogin {
+			perLoginClaims[k] = v
+		}
+
+		merged := policy.merge("", storedClaims, perLoginClaims)
+		for k, v := range stored {
+			if merged[k] != v {
+				return false
+			}
+		}
+		for k, v := range perLogin {
+			if _, conflict := stored[k]; !conflict && merged[k] != v {
+				return false
+			}
+		}
+		return len(merged) <= len(stored)+len(perLogin)
+	}
+	if err := quick.Check(property, nil); err != nil {
+		t.Error(err)
+	}
+}
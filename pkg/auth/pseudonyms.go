@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+// DefaultPseudonymizedFields are the log and event fields replaced by
+// pseudonyms unless PseudonymizationConfig.Fields is set.
+var DefaultPseudonymizedFields = []string{"user_id", "username", "old_username", "new_username", "email", "subject", "ip"}
+
+// PseudonymizationConfig replaces user identifiers in logs, events and
+// webhooks with keyed pseudonyms, so that event streams can be analysed
+// without exposing them. The same value always has the same pseudonym, and
+// holders of the key can re-identify users with Auth.Reidentify.
+type PseudonymizationConfig struct {
+	// Key is the HMAC-SHA256 key of the pseudonyms. Setting it enables
+	// pseudonymization. Keep it away from the analytics systems.
+	Key []byte
+	// Fields lists the fields to pseudonymize. Defaults to
+	// DefaultPseudonymizedFields.
+	Fields []string
+}
+
+// Pseudonymizer computes the pseudonyms of identifiers. A nil
+// *Pseudonymizer leaves them unchanged.
+type Pseudonymizer struct {
+	key    []byte
+	fields map[string]bool
+}
+
+// NewPseudonymizer returns the pseudonymizer of config, nil without a key.
+func NewPseudonymizer(config PseudonymizationConfig) *Pseudonymizer {
+	if len(config.Key) == 0 {
+		return nil
+	}
+	if len(config.Fields) == 0 {
+		config.Fields = DefaultPseudonymizedFields
+	}
+	fields := make(map[string]bool, len(config.Fields))
+	for _, field := range config.Fields {
+		fields[field] = true
+	}
+	return &Pseudonymizer{key: append([]byte(nil), config.Key...), fields: fields}
+}
+
+// Pseudonym returns the pseudonym of value: "ps_" and 32 hex characters
+// of its HMAC-SHA256.
+func (p *Pseudonymizer) Pseudonym(value string) string {
+	if p == nil {
+		return value
+	}
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(value))
+	return "ps_" + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// apply returns fields with the configured string fields replaced by their
+// pseudonyms. It returns fields itself when disabled.
+func (p *Pseudonymizer) apply(fields map[string]interface{}) map[string]interface{} {
+	if p == nil || fields == nil {
+		return fields
+	}
+	pseudonymized := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		if s, ok := value.(string); ok && s != "" && p.fields[key] {
+			value = p.Pseudonym(s)
+		}
+		pseudonymized[key] = value
+	}
+	return pseudonymized
+}
+
+// Pseudonymizer returns the pseudonymizer of logs and events, or nil if
+// AuthConfig.Pseudonymization has no key.
+func (a *Auth) Pseudonymizer() *Pseudonymizer {
+	return a.logger.pseudonyms
+}
+
+// Reidentify returns the user whose ID, username or email has pseudonym.
+// It computes the pseudonyms of all users, so it is meant for occasional
+// use by administrators, not for request paths.
+func (a *Auth) Reidentify(ctx context.Context, pseudonym string) (*models.UserProfile, error) {
+	p := a.logger.pseudonyms
+	if p == nil {
+		return nil, ErrConfigError("Pseudonymization.Key")
+	}
+	const pageSize = 100
+	for offset := 0; ; offset += pageSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		users, err := a.storage.ListUsers(pageSize, offset)
+		if err != nil {
+			return nil, WrapDatabaseError(err)
+		}
+		for _, user := range users {
+			for _, value := range []string{user.ID, user.Username, user.Email} {
+				if value != "" && hmac.Equal([]byte(p.Pseudonym(value)), []byte(pseudonym)) {
+					return toProfile(user), nil
+				}
+			}
+		}
+		if len(users) < pageSize {
+			return nil, ErrUserNotFound()
+		}
+	}
+}
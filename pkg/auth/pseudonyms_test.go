@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPseudonymizedEvents(t *testing.T) {
+	var output bytes.Buffer
+	logger := NewLogger(LogLevelInfo, &output)
+	logger.pseudonyms = NewPseudonymizer(PseudonymizationConfig{Key: []byte("analytics-key")})
+	sink := &flakySink{}
+	eventLogger := NewAuthEventLogger(logger)
+	eventLogger.sinks = []*eventDispatcher{newEventDispatcher(EventSinkConfig{Sink: sink}, logger)}
+
+	eventLogger.LogLogin("user-1", "alice", "203.0.113.1", "test", true, time.Millisecond, nil)
+	eventLogger.close()
+
+	for _, raw := range []string{"user-1", "alice", "203.0.113.1"} {
+		if strings.Contains(output.String(), raw) {
+			t.Errorf("Expected %q to be pseudonymized in the log: %s", raw, output.String())
+		}
+	}
+	events := sink.delivered()
+	if len(events) != 1 {
+		t.Fatalf("Expected one event, got %v", events)
+	}
+	pseudonym := logger.pseudonyms.Pseudonym("user-1")
+	if events[0].UserID != pseudonym || !strings.HasPrefix(pseudonym, "ps_") {
+		t.Errorf("Expected user ID pseudonym %s, got %s", pseudonym, events[0].UserID)
+	}
+	if events[0].Data["user_agent"] != "test" {
+		t.Errorf("Expected other fields to be kept, got %v", events[0].Data)
+	}
+	if !strings.Contains(output.String(), pseudonym) {
+		t.Errorf("Expected the log to carry the same pseudonym, got %s", output.String())
+	}
+}
+
+func TestReidentify(t *testing.T) {
+	ta := NewTestAuth(t)
+	if _, err := ta.Reidentify(context.Background(), "ps_x"); err == nil {
+		t.Error("Expected re-identification to require a key")
+	}
+	ta.logger.pseudonyms = NewPseudonymizer(PseudonymizationConfig{Key: []byte("analytics-key")})
+	user := ta.SeedUser("bob", "bob-password")
+
+	profile, err := ta.Reidentify(context.Background(), ta.Pseudonymizer().Pseudonym(user.ID))
+	if err != nil || profile.ID != user.ID {
+		t.Fatalf("Expected to re-identify the user, got %v, %v", profile, err)
+	}
+	other := NewPseudonymizer(PseudonymizationConfig{Key: []byte("other-key")})
+	if _, err := ta.Reidentify(context.Background(), other.Pseudonym(user.ID)); err == nil {
+		t.Error("Expected pseudonyms of another key not to match")
+	}
+}
@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RateLimiter decides whether a request identified by key may proceed.
+// When it may not, retryAfter reports how long the caller should wait.
+type RateLimiter interface {
+	Allow(key string) (allowed bool, retryAfter time.Duration)
+}
+
+// memoryRateLimiter is an in-memory fixed-window RateLimiter.
+type memoryRateLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	clock   Clock
+	windows map[string]*rateWindow
+	pruned  time.Time
+}
+
+// rateWindow counts requests for a key within the current window.
+type rateWindow struct {
+	count int
+	start time.Time
+}
+
+// NewRateLimiter creates an in-memory RateLimiter allowing limit requests per
+// key within each window. A nil clock uses the system clock.
+func NewRateLimiter(limit int, window time.Duration, clock Clock) RateLimiter {
+	return &memoryRateLimiter{
+		limit:   limit,
+		window:  window,
+		clock:   clock,
+		windows: make(map[string]*rateWindow),
+	}
+}
+
+// Allow records a request for key and reports whether it is within the limit.
+func (l *memoryRateLimiter) Allow(key string) (bool, time.Duration) {
+	now := nowFrom(l.clock)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		l.prune(now)
+		w = &rateWindow{start: now}
+		l.windows[key] = w
+	}
+
+	if w.count >= l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// prune drops expired windows so idle keys do not accumulate. It runs at
+// most once per window. The caller must hold l.mu.
+func (l *memoryRateLimiter) prune(now time.Time) {
+	if now.Sub(l.pruned) < l.window {
+		return
+	}
+	l.pruned = now
+	for key, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, key)
+		}
+	}
+}
+
+// CounterStore holds windowed counters, such as request counts or failed
+// logins. Back limits with a shared store, e.g. NewRedisCounterStore, so
+// that they hold across all replicas rather than per instance.
+type CounterStore interface {
+	// Increment adds one to the counter at key and returns the new count and
+	// the time left in its window. A missing or expired counter starts a new
+	// window of length window.
+	Increment(key string, window time.Duration) (count int64, ttl time.Duration, err error)
+	// Count returns the counter at key, zero if it is missing or expired.
+	Count(key string) (int64, error)
+	// Reset deletes the counter at key.
+	Reset(key string) error
+}
+
+// memoryCounterStore is an in-memory CounterStore.
+type memoryCounterStore struct {
+	mu       sync.Mutex
+	clock    Clock
+	counters map[string]*counterWindow
+	pruned   time.Time
+}
+
+// counterWindow is a counter that expires at the end of its window.
+type counterWindow struct {
+	count   int64
+	expires time.Time
+}
+
+// NewMemoryCounterStore creates a CounterStore local to this instance. A
+// nil clock uses the system clock.
+func NewMemoryCounterStore(clock Clock) CounterStore {
+	return &memoryCounterStore{clock: clock, counters: make(map[string]*counterWindow)}
+}
+
+func (s *memoryCounterStore) Increment(key string, window time.Duration) (int64, time.Duration, error) {
+	now := nowFrom(s.clock)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.counters[key]
+	if !ok || !now.Before(c.expires) {
+		s.prune(now)
+		c = &counterWindow{expires: now.Add(window)}
+		s.counters[key] = c
+	}
+	c.count++
+	return c.count, c.expires.Sub(now), nil
+}
+
+func (s *memoryCounterStore) Count(key string) (int64, error) {
+	now := nowFrom(s.clock)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.counters[key]
+	if !ok || !now.Before(c.expires) {
+		return 0, nil
+	}
+	return c.count, nil
+}
+
+func (s *memoryCounterStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.counters, key)
+	return nil
+}
+
+// prune drops expired counters, at most once a minute. The caller must
+// hold s.mu.
+func (s *memoryCounterStore) prune(now time.Time) {
+	if now.Sub(s.pruned) < time.Minute {
+		return
+	}
+	s.pruned = now
+	for key, c := range s.counters {
+		if !now.Before(c.expires) {
+			delete(s.counters, key)
+		}
+	}
+}
+
+// storeRateLimiter is a fixed-window RateLimiter on a CounterStore.
+type storeRateLimiter struct {
+	store  CounterStore
+	name   string
+	limit  int
+	window time.Duration
+}
+
+// NewSharedRateLimiter creates a RateLimiter allowing limit requests per key
+// within each window, counted in store so that the limit holds across all
+// instances sharing it. name keeps the keys of different limiters apart.
+// Requests are allowed while the store fails, so that an outage of the
+// store does not lock every client out.
+func NewSharedRateLimiter(store CounterStore, name string, limit int, window time.Duration) RateLimiter {
+	return &storeRateLimiter{store: store, name: name, limit: limit, window: window}
+}
+
+// Allow records a request for key and reports whether it is within the limit.
+func (l *storeRateLimiter) Allow(key string) (bool, time.Duration) {
+	count, ttl, err := l.store.Increment("ratelimit:"+l.name+":"+key, l.window)
+	if err != nil || count <= int64(l.limit) {
+		return true, 0
+	}
+	return false, ttl
+}
+
+// clientIP returns the remote address of r without the port.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	clock := NewFrozenClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	limiter := NewRateLimiter(2, time.Minute, clock)
+
+	for i := 0; i < 2; i++ {
+		if allowed, _ := limiter.Allow("client-a"); !allowed {
+			t.Fatalf("Expected request %d to be allowed", i+1)
+		}
+	}
+
+	clock.Advance(20 * time.Second)
+	allowed, retryAfter := limiter.Allow("client-a")
+	if allowed {
+		t.Fatal("Expected request over the limit to be rejected")
+	}
+	if retryAfter != 40*time.Second {
+		t.Errorf("Expected retry after 40s, got %v", retryAfter)
+	}
+
+	if allowed, _ := limiter.Allow("client-b"); !allowed {
+		t.Error("Expected limits to be tracked per key")
+	}
+
+	clock.Advance(time.Minute)
+	if allowed, _ := limiter.Allow("client-a"); !allowed {
+		t.Error("Expected the limit to reset after the window")
+	}
+}
+
+func TestMemoryCounterStore(t *testing.T) {
+	clock := NewFrozenClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	store := NewMemoryCounterStore(clock)
+
+	store.Increment("k", time.Minute)
+	clock.Advance(15 * time.Second)
+	count, ttl, err := store.Increment("k", time.Minute)
+	if err != nil || count != 2 || ttl != 45*time.Second {
+		t.Fatalf("Expected count 2 with 45s left, got %d, %v, %v", count, ttl, err)
+	}
+	if count, _ := store.Count("k"); count != 2 {
+		t.Errorf("Expected count 2, got %d", count)
+	}
+
+	clock.Advance(time.Minute)
+	if count, _ := store.Count("k"); count != 0 {
+		t.Errorf("Expected the counter to expire, got %d", count)
+	}
+	store.Increment("k", time.Minute)
+	store.Reset("k")
+	if count, _ := store.Count("k"); count != 0 {
+		t.Errorf("Expected reset counter, got %d", count)
+	}
+}
+
+// fakeCounterRedis emulates the counter scripts of the Redis counter store.
+type fakeCounterRedis struct {
+	clock    Clock
+	counters map[string]int64
+	expires  map[string]time.Time
+	down     bool
+}
+
+func newFakeCounterRedis(clock Clock) *fakeCounterRedis {
+	return &fakeCounterRedis{clock: clock, counters: map[string]int64{}, expires: map[string]time.Time{}}
+}
+
+func (r *fakeCounterRedis) Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error) {
+	if r.down {
+		return nil, errors.New("connection refused")
+	}
+	key := keys[0]
+	if expires, ok := r.expires[key]; ok && !r.clock.Now().Before(expires) {
+		delete(r.counters, key)
+		delete(r.expires, key)
+	}
+	switch script {
+	case redisIncrementScript:
+		r.counters[key]++
+		if _, ok := r.expires[key]; !ok {
+			r.expires[key] = r.clock.Now().Add(time.Duration(args[0].(int64)) * time.Millisecond)
+		}
+		return []interface{}{r.counters[key], r.expires[key].Sub(r.clock.Now()).Milliseconds()}, nil
+	case redisCountScript:
+		return r.counters[key], nil
+	case redisResetScript:
+		delete(r.counters, key)
+		delete(r.expires, key)
+		return int64(1), nil
+	}
+	return nil, errors.New("unknown script")
+}
+
+func TestSharedRateLimiter(t *testing.T) {
+	clock := NewFrozenClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	redis := newFakeCounterRedis(clock)
+
+	// Two instances share one limit
+	first := NewSharedRateLimiter(NewRedisCounterStore(redis, ""), "login", 2, time.Minute)
+	second := NewSharedRateLimiter(NewRedisCounterStore(redis, ""), "login", 2, time.Minute)
+	if allowed, _ := first.Allow("1.2.3.4"); !allowed {
+		t.Fatal("Expected first request to be allowed")
+	}
+	if allowed, _ := second.Allow("1.2.3.4"); !allowed {
+		t.Fatal("Expected second request to be allowed")
+	}
+	clock.Advance(20 * time.Second)
+	allowed, retryAfter := first.Allow("1.2.3.4")
+	if allowed {
+		t.Fatal("Expected request over the shared limit to be rejected")
+	}
+	if retryAfter != 40*time.Second {
+		t.Errorf("Expected retry after 40s, got %v", retryAfter)
+	}
+	if _, ok := redis.counters[DefaultCounterPrefix+"ratelimit:login:1.2.3.4"]; !ok {
+		t.Errorf("Expected prefixed key, got %v", redis.counters)
+	}
+
+	clock.Advance(time.Minute)
+	if allowed, _ := second.Allow("1.2.3.4"); !allowed {
+		t.Error("Expected the limit to reset after the window")
+	}
+
+	// Requests are allowed while Redis is down
+	redis.down = true
+	for i := 0; i < 5; i++ {
+		if allowed, _ := first.Allow("1.2.3.4"); !allowed {
+			t.Fatal("Expected requests to be allowed while the store fails")
+		}
+	}
+}
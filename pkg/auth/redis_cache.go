@@ -1,4 +1,103 @@
 package auth
 
-// TODO: Implement Redis cache functionality
-// This file is a placeholder for future Redis integration
\ No newline at end of file
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// TODO: Implement a Redis-backed Cache. Only the shared counters of
+// rate limits and login failures are backed by Redis so far.
+
+// RedisScripter is the subset of a Redis client used by the Redis counter
+// store. A go-redis client is adapted with one line: Eval forwards to
+// client.Eval(ctx, script, keys, args...).Result().
+type RedisScripter interface {
+	Eval(ctx context.Context, script string, keys []string, args ...interface{}) (interface{}, error)
+}
+
+// DefaultCounterPrefix prefixes the Redis keys of the counter store.
+const DefaultCounterPrefix = "go-auth:counters:"
+
+// Lua scripts run atomically by Redis, so concurrent increments from
+// several instances never lose a count or leave a counter without expiry.
+const (
+	// redisIncrementScript increments KEYS[1], starting a window of ARGV[1]
+	// milliseconds if it has none, and returns the count and the time left.
+	redisIncrementScript = `local count = redis.call('INCR', KEYS[1])
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl < 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+	ttl = tonumber(ARGV[1])
+end
+return {count, ttl}`
+
+	// redisCountScript returns KEYS[1], zero if it does not exist.
+	redisCountScript = `return tonumber(redis.call('GET', KEYS[1]) or '0')`
+
+	// redisResetScript deletes KEYS[1].
+	redisResetScript = `return redis.call('DEL', KEYS[1])`
+)
+
+// redisCounterStore is a CounterStore in Redis.
+type redisCounterStore struct {
+	client RedisScripter
+	prefix string
+}
+
+// NewRedisCounterStore creates a CounterStore in Redis, shared by every
+// instance using the same Redis and prefix. prefix defaults to
+// DefaultCounterPrefix.
+func NewRedisCounterStore(client RedisScripter, prefix string) CounterStore {
+	if prefix == "" {
+		prefix = DefaultCounterPrefix
+	}
+	return &redisCounterStore{client: client, prefix: prefix}
+}
+
+func (s *redisCounterStore) Increment(key string, window time.Duration) (int64, time.Duration, error) {
+	reply, err := s.client.Eval(context.Background(), redisIncrementScript, []string{s.prefix + key}, window.Milliseconds())
+	if err != nil {
+		return 0, 0, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return 0, 0, fmt.Errorf("unexpected reply from counter script: %v", reply)
+	}
+	count, err := redisInt(values[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	ttl, err := redisInt(values[1])
+	if err != nil {
+		return 0, 0, err
+	}
+	return count, time.Duration(ttl) * time.Millisecond, nil
+}
+
+func (s *redisCounterStore) Count(key string) (int64, error) {
+	reply, err := s.client.Eval(context.Background(), redisCountScript, []string{s.prefix + key})
+	if err != nil {
+		return 0, err
+	}
+	return redisInt(reply)
+}
+
+func (s *redisCounterStore) Reset(key string) error {
+	_, err := s.client.Eval(context.Background(), redisResetScript, []string{s.prefix + key})
+	return err
+}
+
+// redisInt converts an integer reply to int64.
+func redisInt(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("unexpected integer reply: %v", value)
+}
@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"context"
+	"maps"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pragneshbagary/go-auth/internal/jwtutils"
+)
+
+// Refresh tokens issued to a sender-constrained client carry the binding of
+// its access token, the cnf["jkt"] of DPoP or the cnf["x5t#S256"] of mTLS,
+// so that a stolen refresh token cannot be redeemed without the client's key
+// or certificate, nor downgraded to unbound tokens.
+
+// tokenBindingKey is the context key of the cnf claim binding the tokens of
+// a login.
+type tokenBindingKey struct{}
+
+// withTokenBinding returns a context binding the tokens of a login to cnf.
+// The cnf claim is protected, so LoginDPoP and LoginMTLS pass it this way
+// rather than as a custom claim.
+func withTokenBinding(ctx context.Context, cnf interface{}) context.Context {
+	return context.WithValue(ctx, tokenBindingKey{}, cnf)
+}
+
+// tokenBindingFrom returns the cnf claim stored in ctx, or nil.
+func tokenBindingFrom(ctx context.Context) interface{} {
+	return ctx.Value(tokenBindingKey{})
+}
+
+// refreshBinding returns the sender-constraining members of a cnf claim, or
+// nil if it has none.
+func refreshBinding(cnf interface{}) map[string]interface{} {
+	claim, _ := cnf.(map[string]interface{})
+	var binding map[string]interface{}
+	for _, member := range []string{"jkt", x5tClaim} {
+		if value, _ := claim[member].(string); value != "" {
+			if binding == nil {
+				binding = make(map[string]interface{}, 2)
+			}
+			binding[member] = value
+		}
+	}
+	return binding
+}
+
+// bindRefresh adds the sender binding of cnf to the options of a refresh
+// token, so that only the key or certificate holder can refresh it.
+func bindRefresh(opts jwtutils.IssueOptions, cnf interface{}) jwtutils.IssueOptions {
+	binding := refreshBinding(cnf)
+	if binding == nil {
+		return opts
+	}
+	claims := make(map[string]any, len(opts.Claims)+1)
+	maps.Copy(claims, opts.Claims)
+	claims["cnf"] = binding
+	opts.Claims = claims
+	return opts
+}
+
+// checkRefreshBinding rejects the refresh of a sender-constrained refresh
+// token unless the new access token is bound to the same key and
+// certificate.
+func checkRefreshBinding(claims jwt.MapClaims, extraClaims map[string]interface{}) error {
+	bound := refreshBinding(claims["cnf"])
+	presented := refreshBinding(extraClaims["cnf"])
+	if jkt, ok := bound["jkt"].(string); ok {
+		if presentedJKT, _ := presented["jkt"].(string); !constantTimeEqual(presentedJKT, jkt) {
+			return ErrInvalidDPoPProof("The refresh token is bound to another DPoP key")
+		}
+	}
+	if thumbprint, ok := bound[x5tClaim].(string); ok {
+		if presentedThumbprint, _ := presented[x5tClaim].(string); !constantTimeEqual(presentedThumbprint, thumbprint) {
+			return errCertificateBinding("The refresh token is bound to another client certificate")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+// RefreshCookieConfig configures the HttpOnly refresh token cookie.
+type RefreshCookieConfig struct {
+	// Name of the cookie. Defaults to "refresh_token".
+	Name string
+	// Path the cookie is sent to. Restrict it to the refresh endpoint where
+	// possible. Defaults to "/".
+	Path   string
+	Domain string
+	// SameSite defaults to http.SameSiteStrictMode, which keeps cross-site
+	// requests from triggering a rotation.
+	SameSite http.SameSite
+	// Insecure drops the Secure attribute for local development over HTTP.
+	Insecure bool
+}
+
+// AccessTokenResponse is the JSON body returned to browsers in the cookie
+// flow. The refresh token never appears in it.
+type AccessTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in,omitempty"`
+}
+
+// RefreshCookie implements silent refresh for single-page apps: the refresh
+// token lives in an HttpOnly cookie that scripts cannot read, while access
+// tokens are returned in JSON and kept in memory.
+type RefreshCookie struct {
+	auth   *Auth
+	config RefreshCookieConfig
+}
+
+// RefreshCookie returns the silent-refresh helper for the given cookie settings.
+func (a *Auth) RefreshCookie(config RefreshCookieConfig) *RefreshCookie {
+	if config.Name == "" {
+		config.Name = "refresh_token"
+	}
+	if config.Path == "" {
+		config.Path = "/"
+	}
+	if config.SameSite == 0 {
+		config.SameSite = http.SameSiteStrictMode
+	}
+	return &RefreshCookie{auth: a, config: config}
+}
+
+// Issue stores the refresh token of a login in the cookie and returns the
+// body to send to the browser.
+func (rc *RefreshCookie) Issue(w http.ResponseWriter, login *LoginResult) *AccessTokenResponse {
+	rc.Set(w, login.RefreshToken)
+	return rc.response(login.AccessToken)
+}
+
+// Set writes refreshToken to the cookie.
+func (rc *RefreshCookie) Set(w http.ResponseWriter, refreshToken string) {
+	http.SetCookie(w, rc.cookie(refreshToken, int(rc.auth.currentConfig().RefreshTokenTTL/time.Second)))
+}
+
+// Clear removes the cookie.
+func (rc *RefreshCookie) Clear(w http.ResponseWriter) {
+	http.SetCookie(w, rc.cookie("", -1))
+}
+
+func (rc *RefreshCookie) cookie(value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     rc.config.Name,
+		Value:    value,
+		Path:     rc.config.Path,
+		Domain:   rc.config.Domain,
+		MaxAge:   maxAge,
+		Secure:   !rc.config.Insecure,
+		HttpOnly: true,
+		SameSite: rc.config.SameSite,
+	}
+}
+
+// Refresh rotates the refresh token read from the cookie, updates the
+// cookie and returns the new access token. An invalid or revoked cookie is
+// cleared.
+func (rc *RefreshCookie) Refresh(w http.ResponseWriter, r *http.Request) (*AccessTokenResponse, error) {
+	cookie, err := r.Cookie(rc.config.Name)
+	if err != nil || cookie.Value == "" {
+		return nil, ErrMissingToken()
+	}
+
+	result, err := rc.auth.Tokens().Refresh(cookie.Value)
+	if err != nil {
+		if getHTTPStatusFromError(err) == http.StatusUnauthorized {
+			rc.Clear(w)
+		}
+		return nil, err
+	}
+
+	rc.Set(w, result.RefreshToken)
+	return rc.response(result.AccessToken), nil
+}
+
+// Logout revokes the refresh token in the cookie and clears the cookie.
+func (rc *RefreshCookie) Logout(w http.ResponseWriter, r *http.Request) error {
+	rc.Clear(w)
+	cookie, err := r.Cookie(rc.config.Name)
+	if err != nil || cookie.Value == "" {
+		return nil
+	}
+	return rc.auth.Tokens().Revoke(cookie.Value)
+}
+
+// Handler returns an HTTP handler for the silent-refresh endpoint. It answers
+// POST requests with an AccessTokenResponse.
+func (rc *RefreshCookie) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		response, err := rc.Refresh(w, r)
+		if err != nil {
+			rc.auth.writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			rc.auth.logger.Error("Failed to encode refresh response", map[string]interface{}{
+				"error": err,
+			})
+		}
+	})
+}
+
+// response builds the browser response for accessToken.
+func (rc *RefreshCookie) response(accessToken string) *AccessTokenResponse {
+	response := &AccessTokenResponse{AccessToken: accessToken, TokenType: "Bearer"}
+	if expiresAt := tokenExpiry(accessToken); !expiresAt.IsZero() {
+		response.ExpiresIn = int64(expiresAt.Sub(nowFrom(rc.auth.clock)) / time.Second)
+	} else if ttl := rc.auth.currentConfig().AccessTokenTTL; ttl > 0 {
+		response.ExpiresIn = int64(ttl / time.Second)
+	}
+	return response
+}
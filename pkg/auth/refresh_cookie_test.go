@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRefreshCookieFlow(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("alice", "alice-password")
+	rc := ta.RefreshCookie(RefreshCookieConfig{Path: "/auth"})
+
+	login := ta.LoginAs("alice")
+	rec := httptest.NewRecorder()
+	body := rc.Issue(rec, login)
+	if body.AccessToken != login.AccessToken || body.TokenType != "Bearer" || body.ExpiresIn <= 0 {
+		t.Errorf("Unexpected login body: %+v", body)
+	}
+	cookie := rec.Result().Cookies()[0]
+	if !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteStrictMode || cookie.Path != "/auth" {
+		t.Errorf("Expected a secure HttpOnly cookie, got %+v", cookie)
+	}
+	if cookie.Value != login.RefreshToken {
+		t.Error("Expected the refresh token in the cookie")
+	}
+
+	// Silent refresh rotates the cookie and returns only the access token
+	req := httptest.NewRequest(http.MethodPost, "/auth/refresh", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	rc.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected refresh to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var raw map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &raw)
+	if raw["access_token"] == "" || raw["refresh_token"] != nil {
+		t.Errorf("Expected only the access token in the body, got %v", raw)
+	}
+	rotated := rec.Result().Cookies()[0]
+	if rotated.Value == "" || rotated.Value == cookie.Value {
+		t.Error("Expected rotation to update the cookie")
+	}
+
+	// The rotated-out token is rejected and its cookie cleared
+	rec = httptest.NewRecorder()
+	rc.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized || rec.Result().Cookies()[0].MaxAge >= 0 {
+		t.Errorf("Expected reused cookie to be rejected and cleared, got %d", rec.Code)
+	}
+
+	// Logout revokes the current cookie
+	req = httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
+	req.AddCookie(rotated)
+	if err := rc.Logout(httptest.NewRecorder(), req); err != nil {
+		t.Fatalf("Logout failed: %v", err)
+	}
+	if _, err := ta.Tokens().Refresh(rotated.Value); err == nil {
+		t.Error("Expected logged out refresh token to be revoked")
+	}
+}
+
+func TestRefreshCookieMissing(t *testing.T) {
+	ta := NewTestAuth(t)
+	rec := httptest.NewRecorder()
+	ta.RefreshCookie(RefreshCookieConfig{}).Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/refresh", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without cookie, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	ta.RefreshCookie(RefreshCookieConfig{}).Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/refresh", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"fmt"
+	"maps"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/pragneshbagary/go-auth/internal/jwtutils"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// Refresh tokens carry their family, the chain of rotations descending from
+// one login, and their generation within it.
+const (
+	familyClaim     = "fam"
+	generationClaim = "gen"
+)
+
+// refreshFamily identifies a refresh token within its family.
+type refreshFamily struct {
+	id         string
+	generation int64
+}
+
+// familyOf returns the family of refresh token claims. Tokens issued before
+// families existed have none.
+func familyOf(claims jwt.MapClaims) (refreshFamily, bool) {
+	id, _ := claims[familyClaim].(string)
+	generation, ok := claims[generationClaim].(float64)
+	if id == "" || !ok {
+		return refreshFamily{}, false
+	}
+	return refreshFamily{id: id, generation: int64(generation)}, true
+}
+
+// next returns the issue options of the token replacing this one, merged
+// into opts.
+func (f refreshFamily) next(opts jwtutils.IssueOptions) jwtutils.IssueOptions {
+	claims := make(map[string]any, len(opts.Claims)+2)
+	maps.Copy(claims, opts.Claims)
+	claims[familyClaim] = f.id
+	claims[generationClaim] = f.generation + 1
+	opts.Claims = claims
+	return opts
+}
+
+// refreshFamilyTokenManager starts a new family for refresh tokens issued
+// outside of a rotation.
+type refreshFamilyTokenManager struct {
+	jwtutils.TokenManager
+}
+
+func (m *refreshFamilyTokenManager) GenerateRefreshToken(userID string) (string, error) {
+	return m.GenerateRefreshTokenWithOptions(userID, jwtutils.IssueOptions{})
+}
+
+func (m *refreshFamilyTokenManager) GenerateRefreshTokenWithOptions(userID string, opts jwtutils.IssueOptions) (string, error) {
+	if _, ok := opts.Claims[familyClaim]; !ok {
+		opts = refreshFamily{id: uuid.New().String(), generation: -1}.next(opts)
+	}
+	return m.TokenManager.GenerateRefreshTokenWithOptions(userID, opts)
+}
+
+// refreshRevoked reports whether refresh token claims were revoked, either
+// individually or by a rotation of their family.
+func (t *Tokens) refreshRevoked(claims jwt.MapClaims) (bool, error) {
+	tokenID, _ := claims["jti"].(string)
+	blacklisted, err := t.storage.IsTokenBlacklisted(tokenID)
+	if err != nil || blacklisted {
+		return blacklisted, err
+	}
+	family, ok := familyOf(claims)
+	if !ok {
+		return false, nil
+	}
+	return t.familyRevoked(family)
+}
+
+// familyRevoked reports whether a rotation of the family replaced the
+// generation.
+func (t *Tokens) familyRevoked(family refreshFamily) (bool, error) {
+	families, ok := baseStorage(t.storage).(storage.RefreshFamilyStorage)
+	if !ok || family.id == "" {
+		return false, nil
+	}
+	cutoff, err := families.RefreshFamilyCutoff(family.id)
+	if err != nil {
+		return false, err
+	}
+	return family.generation < cutoff, nil
+}
+
+// retireRefreshToken rejects a rotated refresh token from then on. With a
+// storage keeping family records the family's cutoff is raised past the
+// token, so that a session needs one record however often it refreshes.
+// Tokens without a family, issued before families existed, are blacklisted
+// as before; their entries expire with them.
+func (t *Tokens) retireRefreshToken(claims jwt.MapClaims) error {
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil
+	}
+	expiresAt := time.Unix(int64(exp), 0)
+
+	families, supported := baseStorage(t.storage).(storage.RefreshFamilyStorage)
+	if family, ok := familyOf(claims); ok && supported {
+		// Earlier generations expire before the rotated token
+		if err := families.RevokeRefreshFamily(family.id, family.generation+1, expiresAt); err != nil {
+			return fmt.Errorf("failed to revoke refresh token family: %w", err)
+		}
+		return nil
+	}
+
+	tokenID, _ := claims["jti"].(string)
+	return t.storage.BlacklistToken(tokenID, expiresAt)
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+func TestRefreshFamilyRotation(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("alice", "alice-password")
+	login := ta.LoginAs("alice")
+	tokens := ta.Tokens()
+
+	claims, err := ta.ValidateRefreshToken(login.RefreshToken)
+	if err != nil {
+		t.Fatalf("Failed to validate refresh token: %v", err)
+	}
+	family, ok := familyOf(claims)
+	if !ok || family.generation != 0 {
+		t.Fatalf("Expected a new family at generation 0, got %+v", family)
+	}
+
+	current := login.RefreshToken
+	rotated := []string{current}
+	for i := 0; i < 3; i++ {
+		ta.Clock.Advance(time.Second)
+		result, err := tokens.Refresh(current)
+		if err != nil {
+			t.Fatalf("Refresh %d failed: %v", i, err)
+		}
+		current = result.RefreshToken
+		rotated = append(rotated, current)
+	}
+
+	claims, _ = ta.ValidateRefreshToken(current)
+	if next, _ := familyOf(claims); next.id != family.id || next.generation != 3 {
+		t.Errorf("Expected generation 3 of the family, got %+v", next)
+	}
+
+	// Every rotated token is rejected, through one record
+	for _, token := range rotated[:len(rotated)-1] {
+		if _, err := tokens.Refresh(token); err == nil {
+			t.Error("Expected rotated refresh token to be rejected")
+		}
+	}
+	families := baseStorage(ta.storage).(storage.RefreshFamilyStorage)
+	if cutoff, err := families.RefreshFamilyCutoff(family.id); err != nil || cutoff != 3 {
+		t.Errorf("Expected cutoff 3, got %d, %v", cutoff, err)
+	}
+	if blacklisted, _ := ta.storage.IsTokenBlacklisted(claims["jti"].(string)); blacklisted {
+		t.Error("Expected the current token not to be blacklisted")
+	}
+	if _, err := tokens.Refresh(current); err != nil {
+		t.Errorf("Expected the current refresh token to work: %v", err)
+	}
+}
+
+func TestRefreshFamilyLegacyToken(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("bob", "bob-password")
+	login := ta.LoginAs("bob")
+	tokens := ta.Tokens()
+
+	// Tokens issued before families existed carry neither claim
+	claims, err := ta.ValidateRefreshToken(login.RefreshToken)
+	if err != nil {
+		t.Fatalf("Failed to validate refresh token: %v", err)
+	}
+	delete(claims, familyClaim)
+	delete(claims, generationClaim)
+	if _, ok := familyOf(claims); ok {
+		t.Fatal("Expected no family without the claims")
+	}
+
+	// They are blacklisted individually, as before
+	if err := tokens.retireRefreshToken(claims); err != nil {
+		t.Fatalf("Failed to retire legacy token: %v", err)
+	}
+	if blacklisted, _ := ta.storage.IsTokenBlacklisted(claims["jti"].(string)); !blacklisted {
+		t.Error("Expected the legacy token to be blacklisted")
+	}
+	if revoked, err := tokens.refreshRevoked(claims); err != nil || !revoked {
+		t.Errorf("Expected the legacy token to be revoked, got %v, %v", revoked, err)
+	}
+}
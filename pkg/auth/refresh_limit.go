@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// RefreshLimitConfig softly limits how often a session may rotate its tokens.
+// Refreshes requested sooner than MinInterval after the previous rotation
+// return the pair issued by that rotation instead of minting a new one. A
+// session is only ever handed its own pair.
+type RefreshLimitConfig struct {
+	// MinInterval is the minimum time between rotations. Zero disables the limit.
+	MinInterval time.Duration
+	// PerUser also applies the limit across all sessions of a user: while
+	// one session is within the interval, refreshes of the user's other
+	// sessions fail with RATE_LIMIT_EXCEEDED.
+	PerUser bool
+}
+
+// refreshLimiter remembers recently issued refresh results. A nil
+// *refreshLimiter never limits.
+type refreshLimiter struct {
+	config RefreshLimitConfig
+	clock  Clock
+
+	mu      sync.Mutex
+	entries map[string]*recentRefresh
+	users   map[string]time.Time // last rotation per user, with PerUser
+	pruned  time.Time
+}
+
+// recentRefresh is a pair issued by a rotation.
+type recentRefresh struct {
+	result         *RefreshResult
+	refreshTokenID string
+	refreshFamily  refreshFamily
+	issuedAt       time.Time
+}
+
+// newRefreshLimiter returns nil unless a minimum interval is configured.
+func newRefreshLimiter(config RefreshLimitConfig, clock Clock) *refreshLimiter {
+	if config.MinInterval <= 0 {
+		return nil
+	}
+	return &refreshLimiter{
+		config:  config,
+		clock:   clock,
+		entries: make(map[string]*recentRefresh),
+		users:   make(map[string]time.Time),
+	}
+}
+
+// recent returns the pair issued for the session of refreshToken within the
+// minimum interval, or nil if the session may rotate.
+func (l *refreshLimiter) recent(refreshToken string) *recentRefresh {
+	if l == nil {
+		return nil
+	}
+	now := nowFrom(l.clock)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	entry, ok := l.entries[l.key(refreshToken)]
+	if !ok || now.Sub(entry.issuedAt) >= l.config.MinInterval {
+		return nil
+	}
+	return entry
+}
+
+// record remembers the pair issued when refreshToken was rotated. The pair
+// is found both by the rotated token, for duplicate requests, and by the new
+// refresh token, for clients refreshing again too soon.
+func (l *refreshLimiter) record(refreshToken, userID, refreshTokenID string, family refreshFamily, result *RefreshResult) {
+	if l == nil {
+		return
+	}
+	now := nowFrom(l.clock)
+	entry := &recentRefresh{result: result, refreshTokenID: refreshTokenID, refreshFamily: family, issuedAt: now}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(now)
+	l.entries[l.key(refreshToken)] = entry
+	l.entries[l.key(result.RefreshToken)] = entry
+	if l.config.PerUser {
+		l.users[userID] = now
+	}
+}
+
+// userLimited reports whether another session of the user rotated within
+// the minimum interval, with PerUser.
+func (l *refreshLimiter) userLimited(userID string) bool {
+	if l == nil || !l.config.PerUser {
+		return false
+	}
+	now := nowFrom(l.clock)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	rotated, ok := l.users[userID]
+	return ok && now.Sub(rotated) < l.config.MinInterval
+}
+
+// key identifies the session of refreshToken: the token rotated into a pair
+// and the refresh token of that pair share an entry.
+func (l *refreshLimiter) key(refreshToken string) string {
+	sum := sha256.Sum256([]byte(refreshToken))
+	return "token:" + hex.EncodeToString(sum[:])
+}
+
+// prune drops entries past the minimum interval. It runs at most once per
+// interval. The caller must hold l.mu.
+func (l *refreshLimiter) prune(now time.Time) {
+	if now.Sub(l.pruned) < l.config.MinInterval {
+		return
+	}
+	l.pruned = now
+	for key, entry := range l.entries {
+		if now.Sub(entry.issuedAt) >= l.config.MinInterval {
+			delete(l.entries, key)
+		}
+	}
+	for userID, rotated := range l.users {
+		if now.Sub(rotated) >= l.config.MinInterval {
+			delete(l.users, userID)
+		}
+	}
+}
+
+// recentRefresh returns the pair of the previous rotation if the session of
+// refreshToken is refreshing again within the minimum interval and that pair
+// has not been revoked since. The caller still checks the session and the
+// user before handing it out.
+func (t *Tokens) recentRefresh(refreshToken string) *RefreshResult {
+	recent := t.refreshLimiter.recent(refreshToken)
+	if recent == nil {
+		return nil
+	}
+	if blacklisted, err := t.storage.IsTokenBlacklisted(recent.refreshTokenID); err != nil || blacklisted {
+		return nil
+	}
+	if revoked, err := t.familyRevoked(recent.refreshFamily); err != nil || revoked {
+		return nil
+	}
+	return recent.result
+}
+
+// recordRefresh remembers a rotation for the refresh limit.
+func (t *Tokens) recordRefresh(refreshToken, userID string, result *RefreshResult) {
+	if t.refreshLimiter == nil {
+		return
+	}
+	claims, err := t.jwtManager.ValidateRefreshToken(result.RefreshToken)
+	if err != nil {
+		return
+	}
+	tokenID, _ := claims["jti"].(string)
+	family, _ := familyOf(claims)
+	t.refreshLimiter.record(refreshToken, userID, tokenID, family, result)
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRefreshMinInterval(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.refreshLimiter = newRefreshLimiter(RefreshLimitConfig{MinInterval: time.Minute}, ta.Clock)
+	ta.SeedUser("alice", "alice-password")
+	login := ta.LoginAs("alice")
+	tokens := ta.Tokens()
+
+	first, err := tokens.Refresh(login.RefreshToken)
+	if err != nil {
+		t.Fatalf("First refresh failed: %v", err)
+	}
+
+	// Too soon: both the new and the rotated token return the same pair
+	ta.Clock.Advance(5 * time.Second)
+	for _, token := range []string{first.RefreshToken, login.RefreshToken} {
+		again, err := tokens.Refresh(token)
+		if err != nil {
+			t.Fatalf("Refresh within the interval failed: %v", err)
+		}
+		if again.AccessToken != first.AccessToken || again.RefreshToken != first.RefreshToken {
+			t.Error("Expected refresh within the interval to return the existing pair")
+		}
+	}
+	if churn := ta.metricsCollector.GetMetrics().RefreshChurn; churn != 2 {
+		t.Errorf("Expected refresh churn of 2, got %d", churn)
+	}
+
+	ta.Clock.Advance(time.Minute)
+	rotated, err := tokens.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh after the interval failed: %v", err)
+	}
+	if rotated.RefreshToken == first.RefreshToken {
+		t.Error("Expected refresh after the interval to rotate")
+	}
+
+	// A revoked pair is never handed out again
+	if err := tokens.Revoke(rotated.RefreshToken); err != nil {
+		t.Fatalf("Failed to revoke: %v", err)
+	}
+	if _, err := tokens.Refresh(rotated.RefreshToken); err == nil {
+		t.Error("Expected revoked refresh token to be rejected")
+	}
+}
+
+func TestRefreshLimitDisabled(t *testing.T) {
+	if newRefreshLimiter(RefreshLimitConfig{}, nil) != nil {
+		t.Error("Expected no limiter without a minimum interval")
+	}
+	var limiter *refreshLimiter
+	if limiter.recent("token") != nil || limiter.userLimited("user") {
+		t.Error("Expected nil limiter to never limit")
+	}
+}
+
+func TestRefreshLimitPerUser(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.refreshLimiter = newRefreshLimiter(RefreshLimitConfig{MinInterval: time.Minute, PerUser: true}, ta.Clock)
+	ta.SeedUser("alice", "alice-password")
+	ta.SeedUser("bob", "bob-password")
+	phone, laptop := ta.LoginAs("alice"), ta.LoginAs("alice")
+	tokens := ta.Tokens()
+
+	first, err := tokens.Refresh(phone.RefreshToken)
+	if err != nil {
+		t.Fatalf("First refresh failed: %v", err)
+	}
+	if again, err := tokens.Refresh(phone.RefreshToken); err != nil || again.RefreshToken != first.RefreshToken {
+		t.Errorf("Expected the session to get its own pair again, got %v", err)
+	}
+
+	// Other sessions never get that pair
+	result, err := tokens.Refresh(laptop.RefreshToken)
+	if !isCode(err, ErrCodeRateLimitExceeded) {
+		t.Errorf("Expected RATE_LIMIT_EXCEEDED for another session, got %v", err)
+	}
+	if result != nil {
+		t.Error("Expected no pair for another session")
+	}
+	if _, err := tokens.Refresh(ta.LoginAs("bob").RefreshToken); err != nil {
+		t.Errorf("Expected other users not to be limited, got %v", err)
+	}
+
+	ta.Clock.Advance(time.Minute)
+	if _, err := tokens.Refresh(laptop.RefreshToken); err != nil {
+		t.Errorf("Expected the limit to end after the interval, got %v", err)
+	}
+}
+
+func TestRefreshLimitChecksUser(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.refreshLimiter = newRefreshLimiter(RefreshLimitConfig{MinInterval: time.Minute}, ta.Clock)
+	user := ta.SeedUser("alice", "alice-password")
+	login := ta.LoginAs("alice")
+
+	if err := ta.Users().ExtendExpiry(user.ID, ta.Clock.Now().Add(30*time.Second)); err != nil {
+		t.Fatalf("ExtendExpiry failed: %v", err)
+	}
+	first, err := ta.Tokens().Refresh(login.RefreshToken)
+	if err != nil {
+		t.Fatalf("First refresh failed: %v", err)
+	}
+
+	// The recent pair is not handed out past the account expiry
+	ta.Clock.Advance(40 * time.Second)
+	if _, err := ta.Tokens().Refresh(first.RefreshToken); !errors.Is(err, ErrAccountExpired()) {
+		t.Errorf("Expected ACCOUNT_EXPIRED within the interval, got %v", err)
+	}
+}
@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// registrationTenantMetadataKey is the user metadata key recording the
+// tenant a user registered under, so that deleting the user frees quota.
+const registrationTenantMetadataKey = "registration_tenant"
+
+// RegistrationQuota limits the registrations of a tenant. Zero fields are
+// unlimited.
+type RegistrationQuota struct {
+	// MaxPerDay limits the registrations per UTC day.
+	MaxPerDay int64
+	// MaxUsers limits the users registered in total, less those deleted.
+	MaxUsers int64
+}
+
+// RegistrationQuotaConfig limits registrations per tenant, the label set by
+// WithTenant or AuthConfig.Tenant. Registrations without a tenant share the
+// quota of the application. Register rejects registrations over quota with
+// QUOTA_EXCEEDED.
+//
+// The quotas are soft: concurrent registrations may exceed them slightly,
+// and users registered before they were configured are not counted.
+type RegistrationQuotaConfig struct {
+	// Default applies to the tenants not listed in Tenants.
+	Default RegistrationQuota
+	// Tenants overrides the quota of some tenants, e.g. on paid plans.
+	Tenants map[string]RegistrationQuota
+	// Store counts registrations. Defaults to an in-memory store, which
+	// is not shared between replicas and forgets the counts on restart.
+	Store RegistrationQuotaStore
+}
+
+// enabled reports whether any quota is configured.
+func (c RegistrationQuotaConfig) enabled() bool {
+	return c.Default != (RegistrationQuota{}) || len(c.Tenants) > 0
+}
+
+// RegistrationQuotaStore persists the registration counts of tenants.
+type RegistrationQuotaStore interface {
+	// RegistrationCounts returns the registrations of tenant on day, a UTC
+	// date, and its number of users.
+	RegistrationCounts(tenant string, day time.Time) (registered, users int64, err error)
+	// AddRegistration counts a registration of tenant on day.
+	AddRegistration(tenant string, day time.Time) error
+	// RemoveUser counts the deletion of a user of tenant.
+	RemoveUser(tenant string) error
+}
+
+// memoryQuotaStore is an in-memory RegistrationQuotaStore.
+type memoryQuotaStore struct {
+	mu      sync.Mutex
+	tenants map[string]*tenantRegistrations
+}
+
+// tenantRegistrations are the counts of one tenant.
+type tenantRegistrations struct {
+	day        time.Time
+	registered int64
+	users      int64
+}
+
+// NewMemoryRegistrationQuotaStore creates an in-memory RegistrationQuotaStore.
+func NewMemoryRegistrationQuotaStore() RegistrationQuotaStore {
+	return &memoryQuotaStore{tenants: make(map[string]*tenantRegistrations)}
+}
+
+func (s *memoryQuotaStore) RegistrationCounts(tenant string, day time.Time) (int64, int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts, ok := s.tenants[tenant]
+	if !ok {
+		return 0, 0, nil
+	}
+	if !counts.day.Equal(day) {
+		return 0, counts.users, nil
+	}
+	return counts.registered, counts.users, nil
+}
+
+func (s *memoryQuotaStore) AddRegistration(tenant string, day time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts, ok := s.tenants[tenant]
+	if !ok {
+		counts = &tenantRegistrations{}
+		s.tenants[tenant] = counts
+	}
+	if !counts.day.Equal(day) {
+		counts.day, counts.registered = day, 0
+	}
+	counts.registered++
+	counts.users++
+	return nil
+}
+
+func (s *memoryQuotaStore) RemoveUser(tenant string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if counts, ok := s.tenants[tenant]; ok && counts.users > 0 {
+		counts.users--
+	}
+	return nil
+}
+
+// registrationQuotas enforces RegistrationQuotaConfig. A nil
+// *registrationQuotas enforces nothing.
+type registrationQuotas struct {
+	config RegistrationQuotaConfig
+	clock  Clock
+}
+
+func newRegistrationQuotas(config RegistrationQuotaConfig, clock Clock) *registrationQuotas {
+	if !config.enabled() {
+		return nil
+	}
+	if config.Store == nil {
+		config.Store = NewMemoryRegistrationQuotaStore()
+	}
+	return &registrationQuotas{config: config, clock: clock}
+}
+
+// today returns the current UTC date.
+func (q *registrationQuotas) today() time.Time {
+	return nowFrom(q.clock).UTC().Truncate(24 * time.Hour)
+}
+
+// check rejects a registration of tenant over its quota.
+func (q *registrationQuotas) check(tenant string) error {
+	if q == nil {
+		return nil
+	}
+	quota, ok := q.config.Tenants[tenant]
+	if !ok {
+		quota = q.config.Default
+	}
+	if quota == (RegistrationQuota{}) {
+		return nil
+	}
+	registered, users, err := q.config.Store.RegistrationCounts(tenant, q.today())
+	if err != nil {
+		return WrapDatabaseError(err)
+	}
+	if quota.MaxPerDay > 0 && registered >= quota.MaxPerDay {
+		return ErrQuotaExceeded(fmt.Sprintf("The limit of %d registrations per day is reached", quota.MaxPerDay))
+	}
+	if quota.MaxUsers > 0 && users >= quota.MaxUsers {
+		return ErrQuotaExceeded(fmt.Sprintf("The limit of %d users is reached", quota.MaxUsers))
+	}
+	return nil
+}
+
+// record counts a registration of tenant.
+func (q *registrationQuotas) record(tenant string) error {
+	if q == nil {
+		return nil
+	}
+	return q.config.Store.AddRegistration(tenant, q.today())
+}
+
+// release counts the deletion of a user of tenant.
+func (q *registrationQuotas) release(tenant string) error {
+	if q == nil {
+		return nil
+	}
+	return q.config.Store.RemoveUser(tenant)
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistrationQuotas(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.quotas = newRegistrationQuotas(RegistrationQuotaConfig{
+		Default: RegistrationQuota{MaxPerDay: 2},
+		Tenants: map[string]RegistrationQuota{"free": {MaxUsers: 1}},
+	}, ta.Clock)
+	register := func(ctx context.Context, username string) error {
+		_, err := ta.RegisterContext(ctx, RegisterRequest{Username: username, Password: TestPassword})
+		return err
+	}
+	exceeded := ErrQuotaExceeded("")
+
+	// Per-tenant total
+	free := WithTenant(context.Background(), "free")
+	if err := register(free, "alice"); err != nil {
+		t.Fatalf("Register failed: %v", err)
+	}
+	if err := register(free, "bob"); !errors.Is(err, exceeded) {
+		t.Fatalf("Expected QUOTA_EXCEEDED over the user limit, got %v", err)
+	}
+	metrics := ta.GetMetrics()
+	if metrics.RegistrationQuotaRejections != 1 || metrics.Tenants["free"].QuotaRejections != 1 {
+		t.Errorf("Expected the rejection to be counted, got %d, %+v", metrics.RegistrationQuotaRejections, metrics.Tenants)
+	}
+	alice, _ := ta.Users().GetByUsername("alice")
+	if err := ta.Users().Delete(alice.ID); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if err := register(free, "bob"); err != nil {
+		t.Errorf("Expected deleting a user to free quota, got %v", err)
+	}
+
+	// Default daily limit
+	ctx := WithTenant(context.Background(), "acme")
+	for _, username := range []string{"carol", "dave"} {
+		if err := register(ctx, username); err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+	}
+	if err := register(ctx, "erin"); !errors.Is(err, exceeded) {
+		t.Fatalf("Expected QUOTA_EXCEEDED over the daily limit, got %v", err)
+	}
+	if err := register(context.Background(), "erin"); err != nil {
+		t.Errorf("Expected other tenants to have their own quota, got %v", err)
+	}
+	ta.Clock.Advance(24 * time.Hour)
+	if err := register(ctx, "erin"); err != nil {
+		t.Errorf("Expected the daily limit to reset, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxRequestBodySize limits the size of JSON bodies decoded by BindJSON.
+const maxRequestBodySize = 1 << 20 // 1 MB
+
+// Validatable is implemented by request DTOs that can validate themselves.
+type Validatable interface {
+	Validate() error
+}
+
+// LoginRequest defines the data required to log a user in.
+// Custom claims are not part of the request: clients must not choose the
+// claims of their own tokens, so pass server-side claims to Auth.Login.
+type LoginRequest struct {
+	Username string `json:"username" binding:"required" validate:"required"`
+	Password string `json:"password" binding:"required" validate:"required"`
+}
+
+// Validate checks that the username and password are present.
+func (r LoginRequest) Validate() error {
+	if strings.TrimSpace(r.Username) == "" {
+		return ErrValidationError("username")
+	}
+	if r.Password == "" {
+		return ErrValidationError("password")
+	}
+	return nil
+}
+
+// RefreshRequest defines the data required to refresh a token pair.
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required" validate:"required"`
+}
+
+// Validate checks that the refresh token is present.
+func (r RefreshRequest) Validate() error {
+	if strings.TrimSpace(r.RefreshToken) == "" {
+		return ErrValidationError("refresh_token")
+	}
+	return nil
+}
+
+// ResetPasswordRequest defines the data required to complete a password reset.
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required" validate:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8" validate:"required,min=8"`
+}
+
+// Validate checks that the reset token is present and the new password is long enough.
+func (r ResetPasswordRequest) Validate() error {
+	if strings.TrimSpace(r.Token) == "" {
+		return ErrValidationError("token")
+	}
+	if r.NewPassword == "" {
+		return ErrValidationError("new_password")
+	}
+	if len(r.NewPassword) < 8 {
+		return ErrPasswordTooShort(8)
+	}
+	return nil
+}
+
+// Validate checks that the username and password are present and that the
+// email, if provided, looks like an email address.
+func (r RegisterRequest) Validate() error {
+	if strings.TrimSpace(r.Username) == "" {
+		return ErrValidationError("username")
+	}
+	if r.Password == "" {
+		return ErrValidationError("password")
+	}
+	if r.Email != "" && !strings.Contains(r.Email, "@") {
+		return ErrValidationError("email")
+	}
+	if r.Birthdate != "" {
+		if _, err := time.Parse(birthdateLayout, r.Birthdate); err != nil {
+			return ErrValidationError("birthdate")
+		}
+	}
+	return nil
+}
+
+// BindJSON decodes a JSON request body into dst and validates it.
+// It is intended for net/http handlers; framework users can bind with their
+// framework and call Validate directly.
+func BindJSON(r *http.Request, dst Validatable) error {
+	if r.Body == nil {
+		return NewAuthError(ErrCodeValidationError, "Request body is required")
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxRequestBodySize))
+	if err := decoder.Decode(dst); err != nil {
+		return NewAuthErrorWithDetails(ErrCodeValidationError, "Invalid request format", "Request body must be valid JSON")
+	}
+
+	return dst.Validate()
+}
@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     Validatable
+		wantErr bool
+	}{
+		{"valid login", LoginRequest{Username: "john", Password: "secret"}, false},
+		{"login missing username", LoginRequest{Password: "secret"}, true},
+		{"login missing password", LoginRequest{Username: "john"}, true},
+		{"valid refresh", RefreshRequest{RefreshToken: "token"}, false},
+		{"refresh missing token", RefreshRequest{RefreshToken: "  "}, true},
+		{"valid reset", ResetPasswordRequest{Token: "abc", NewPassword: "newpassword"}, false},
+		{"reset missing token", ResetPasswordRequest{NewPassword: "newpassword"}, true},
+		{"reset short password", ResetPasswordRequest{Token: "abc", NewPassword: "short"}, true},
+		{"valid register", RegisterRequest{Username: "john", Email: "john@example.com", Password: "secret"}, false},
+		{"register without email", RegisterRequest{Username: "john", Password: "secret"}, false},
+		{"register invalid email", RegisterRequest{Username: "john", Email: "not-an-email", Password: "secret"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.req.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				var authErr *AuthError
+				if !errors.As(err, &authErr) {
+					t.Fatalf("Expected AuthError, got %T", err)
+				}
+			}
+		})
+	}
+}
+
+func TestResetPasswordRequestWeakPassword(t *testing.T) {
+	err := ResetPasswordRequest{Token: "abc", NewPassword: "short"}.Validate()
+
+	authErr, ok := err.(*AuthError)
+	if !ok {
+		t.Fatalf("Expected AuthError, got %T", err)
+	}
+	if authErr.Code != ErrCodeWeakPassword {
+		t.Errorf("Expected code %s, got %s", ErrCodeWeakPassword, authErr.Code)
+	}
+}
+
+func TestBindJSON(t *testing.T) {
+	t.Run("valid body", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(`{"username":"john","password":"secret","claims":{"role":"admin"}}`))
+
+		var req LoginRequest
+		if err := BindJSON(r, &req); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if req.Username != "john" || req.Password != "secret" {
+			t.Errorf("Unexpected decoded request: %+v", req)
+		}
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/refresh", strings.NewReader(`{not json`))
+
+		var req RefreshRequest
+		err := BindJSON(r, &req)
+		if err == nil {
+			t.Fatal("Expected error for invalid JSON")
+		}
+		if getHTTPStatusFromError(err) != http.StatusBadRequest {
+			t.Errorf("Expected bad request status, got %d", getHTTPStatusFromError(err))
+		}
+	})
+
+	t.Run("failed validation", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/refresh", strings.NewReader(`{}`))
+
+		var req RefreshRequest
+		if err := BindJSON(r, &req); err == nil {
+			t.Fatal("Expected validation error for missing refresh token")
+		}
+	})
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPurgeInactiveUsers(t *testing.T) {
+	ta := NewTestAuth(t)
+	stale := ta.SeedUser("stale", "stale-password")
+	ta.SeedUser("returning", "returning-password")
+
+	ta.Clock.Advance(400 * 24 * time.Hour)
+	ta.LoginAs("returning")
+	fresh := ta.SeedUser("fresh", "fresh-password")
+
+	report, err := ta.Users().PurgeInactive(365*24*time.Hour, true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if report.Scanned != 3 || len(report.Users) != 1 || report.Users[0].ID != stale.ID || report.Purged != 0 {
+		t.Fatalf("Unexpected dry run report: %+v", report)
+	}
+	if _, err := ta.Users().Get(stale.ID); err != nil {
+		t.Error("Dry run must not delete users")
+	}
+
+	var out bytes.Buffer
+	report.Fprint(&out)
+	if !strings.Contains(out.String(), "Would purge 1 of 3") {
+		t.Errorf("Unexpected dry run output:\n%s", out.String())
+	}
+
+	report, err = ta.Users().PurgeInactive(365*24*time.Hour, false)
+	if err != nil || report.Purged != 1 {
+		t.Fatalf("Expected one purged user, got %+v (%v)", report, err)
+	}
+	if _, err := ta.Users().Get(stale.ID); err == nil {
+		t.Error("Expected stale user to be deleted")
+	}
+	if _, err := ta.Users().Get(fresh.ID); err != nil {
+		t.Error("Expected fresh user to be kept")
+	}
+
+	if _, err := ta.Users().PurgeInactive(0, true); err == nil {
+		t.Error("Expected zero retention period to be rejected")
+	}
+}
+
+func TestPurgeSessions(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("alice", "alice-password")
+
+	old, _ := ta.IssueDelegatedToken(user.ID, "reports", []string{"reports:read"}, time.Hour)
+	current, _ := ta.IssueDelegatedToken(user.ID, "backup", []string{"files:read"}, 90*24*time.Hour)
+
+	ta.Clock.Advance(40 * 24 * time.Hour)
+	report, err := ta.Tokens().PurgeSessions(30 * 24 * time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to purge sessions: %v", err)
+	}
+	if report.GrantsPurged != 1 || !report.BlacklistCleaned {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+
+	grants, _ := ta.Tokens().ListGrants(user.ID)
+	if len(grants) != 1 || grants[0].ID != current.Grant.ID || grants[0].ID == old.Grant.ID {
+		t.Errorf("Expected only the active grant to remain, got %v", grants)
+	}
+}
@@ -0,0 +1,287 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// Types of RevocationEvent.
+const (
+	// RevocationUserUpdated announces a changed or deleted user.
+	RevocationUserUpdated = "user_updated"
+	// RevocationLogoutAll announces that all sessions of a user were revoked.
+	RevocationLogoutAll = "logout_all"
+	// RevocationSession announces that a single session was revoked.
+	RevocationSession = "session_revoked"
+	// RevocationToken announces that a single token was revoked.
+	RevocationToken = "token_revoked"
+)
+
+// RevocationEvent announces that state cached about a user or session is
+// out of date.
+type RevocationEvent struct {
+	Type      string    `json:"type"`
+	UserID    string    `json:"user_id,omitempty"`
+	SessionID string    `json:"session_id,omitempty"`
+	TokenID   string    `json:"token_id,omitempty"`
+	IssuedAt  time.Time `json:"issued_at"`
+	// Source identifies the publishing instance so it can skip its own events.
+	Source string `json:"source,omitempty"`
+}
+
+// RevocationBus distributes revocation events between the instances of a
+// service, so that their in-memory caches drop revoked state immediately
+// instead of serving it until it expires.
+type RevocationBus interface {
+	Publish(event RevocationEvent) error
+	// Subscribe calls handler for every published event until the returned
+	// function is called.
+	Subscribe(handler func(RevocationEvent)) (unsubscribe func(), err error)
+}
+
+// RevocationConfig broadcasts revocations and user changes to other instances.
+type RevocationConfig struct {
+	// Bus distributes the events, e.g. NewRedisRevocationBus. Nothing is
+	// broadcast when nil, which suits a single instance.
+	Bus RevocationBus
+}
+
+// DefaultRevocationChannel is the channel or subject used for revocation events.
+const DefaultRevocationChannel = "go-auth:revocations"
+
+// redisRevocationBus publishes JSON-encoded events on a Redis channel.
+type redisRevocationBus struct {
+	client  RedisPubSub
+	channel string
+}
+
+// NewRedisRevocationBus creates a RevocationBus on a Redis channel. channel
+// defaults to DefaultRevocationChannel.
+func NewRedisRevocationBus(client RedisPubSub, channel string) RevocationBus {
+	if channel == "" {
+		channel = DefaultRevocationChannel
+	}
+	return &redisRevocationBus{client: client, channel: channel}
+}
+
+func (b *redisRevocationBus) Publish(event RevocationEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return b.client.Publish(context.Background(), b.channel, payload)
+}
+
+func (b *redisRevocationBus) Subscribe(handler func(RevocationEvent)) (func(), error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	messages, err := b.client.Subscribe(ctx, b.channel)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go func() {
+		for payload := range messages {
+			var event RevocationEvent
+			if err := json.Unmarshal(payload, &event); err != nil {
+				continue
+			}
+			handler(event)
+		}
+	}()
+	return cancel, nil
+}
+
+// NATSConn is the subset of a NATS connection used by the NATS revocation
+// bus. A *nats.Conn is adapted by forwarding Publish and wrapping the
+// handler of Subscribe to pass msg.Data; unsubscribe calls
+// Subscription.Unsubscribe.
+type NATSConn interface {
+	Publish(subject string, data []byte) error
+	// Subscribe calls handler with the data of every message published to
+	// subject until unsubscribe is called.
+	Subscribe(subject string, handler func(data []byte)) (unsubscribe func() error, err error)
+}
+
+// natsRevocationBus publishes JSON-encoded events on a NATS subject.
+type natsRevocationBus struct {
+	conn    NATSConn
+	subject string
+}
+
+// NewNATSRevocationBus creates a RevocationBus on a NATS subject. subject
+// defaults to DefaultRevocationChannel.
+func NewNATSRevocationBus(conn NATSConn, subject string) RevocationBus {
+	if subject == "" {
+		subject = DefaultRevocationChannel
+	}
+	return &natsRevocationBus{conn: conn, subject: subject}
+}
+
+func (b *natsRevocationBus) Publish(event RevocationEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return b.conn.Publish(b.subject, payload)
+}
+
+func (b *natsRevocationBus) Subscribe(handler func(RevocationEvent)) (func(), error) {
+	unsubscribe, err := b.conn.Subscribe(b.subject, func(data []byte) {
+		var event RevocationEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			return
+		}
+		handler(event)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return func() { _ = unsubscribe() }, nil
+}
+
+// revocationBroadcaster publishes the revocations of an Auth instance and
+// applies those of other instances. A nil *revocationBroadcaster does nothing.
+type revocationBroadcaster struct {
+	id          string
+	bus         RevocationBus
+	clock       Clock
+	logger      *Logger
+	unsubscribe func()
+}
+
+// newRevocationBroadcaster returns nil unless a bus is configured.
+func newRevocationBroadcaster(config RevocationConfig, clock Clock, logger *Logger) *revocationBroadcaster {
+	if config.Bus == nil {
+		return nil
+	}
+	return &revocationBroadcaster{id: uuid.New().String(), bus: config.Bus, clock: clock, logger: logger}
+}
+
+// subscribe calls apply for the events of other instances.
+func (b *revocationBroadcaster) subscribe(apply func(RevocationEvent)) error {
+	if b == nil {
+		return nil
+	}
+	unsubscribe, err := b.bus.Subscribe(func(event RevocationEvent) {
+		if event.Source != b.id {
+			apply(event)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	b.unsubscribe = unsubscribe
+	return nil
+}
+
+// publish broadcasts an event. Failures are logged: the caches of other
+// instances then expire on their own.
+func (b *revocationBroadcaster) publish(eventType, userID, sessionID string) {
+	if b == nil {
+		return
+	}
+	b.send(RevocationEvent{Type: eventType, UserID: userID, SessionID: sessionID})
+}
+
+// publishToken broadcasts the revocation of the token with tokenID.
+func (b *revocationBroadcaster) publishToken(tokenID string) {
+	if b == nil {
+		return
+	}
+	b.send(RevocationEvent{Type: RevocationToken, TokenID: tokenID})
+}
+
+func (b *revocationBroadcaster) send(event RevocationEvent) {
+	event.IssuedAt = nowFrom(b.clock)
+	event.Source = b.id
+	if err := b.bus.Publish(event); err != nil {
+		b.logger.Error("Failed to broadcast revocation", map[string]interface{}{
+			"type":       event.Type,
+			"user_id":    event.UserID,
+			"session_id": event.SessionID,
+			"token_id":   event.TokenID,
+			"error":      err,
+		})
+	}
+}
+
+// close unsubscribes from the bus.
+func (b *revocationBroadcaster) close() {
+	if b != nil && b.unsubscribe != nil {
+		b.unsubscribe()
+	}
+}
+
+// applyRevocation drops the state cached about the subject of an event
+// published by another instance.
+func (a *Auth) applyRevocation(event RevocationEvent) {
+	switch event.Type {
+	case RevocationUserUpdated, RevocationLogoutAll:
+		a.loginCache.forgetUser(event.UserID)
+		a.validations.forgetUser(event.UserID)
+		if a.permissions != nil && event.UserID != "" {
+			a.permissions.apply(PermissionInvalidation{UserID: event.UserID, IssuedAt: event.IssuedAt})
+		}
+		if event.Type == RevocationLogoutAll {
+			a.sessions.forgetUser(event.UserID)
+		}
+	case RevocationSession:
+		a.sessions.forget(event.SessionID)
+	case RevocationToken:
+		a.validations.forgetToken(event.TokenID)
+	}
+	if event.Type == RevocationUserUpdated {
+		a.watcher.notifyUserChanged(event.UserID)
+	} else {
+		a.watcher.notify(event)
+	}
+	a.logger.Debug("Applied revocation", map[string]interface{}{
+		"type":       event.Type,
+		"user_id":    event.UserID,
+		"session_id": event.SessionID,
+	})
+}
+
+// revocationStorage broadcasts user mutations so that other instances drop
+// the users they cached.
+type revocationStorage struct {
+	storage.EnhancedStorage
+	revocations *revocationBroadcaster
+}
+
+func (s *revocationStorage) UpdateUser(userID string, updates storage.UserUpdates) error {
+	if err := s.EnhancedStorage.UpdateUser(userID, updates); err != nil {
+		return err
+	}
+	// Recording a login does not change what other instances cache
+	loginOnly := updates.LastLoginAt != nil && updates.Email == nil && updates.Username == nil && updates.Metadata == nil
+	if !loginOnly {
+		s.revocations.publish(RevocationUserUpdated, userID, "")
+	}
+	return nil
+}
+
+func (s *revocationStorage) UpdatePassword(userID string, passwordHash string) error {
+	if err := s.EnhancedStorage.UpdatePassword(userID, passwordHash); err != nil {
+		return err
+	}
+	s.revocations.publish(RevocationUserUpdated, userID, "")
+	return nil
+}
+
+func (s *revocationStorage) DeleteUser(userID string) error {
+	if err := s.EnhancedStorage.DeleteUser(userID); err != nil {
+		return err
+	}
+	s.revocations.publish(RevocationUserUpdated, userID, "")
+	return nil
+}
+
+func (s *revocationStorage) unwrapStorage() storage.EnhancedStorage {
+	return s.EnhancedStorage
+}
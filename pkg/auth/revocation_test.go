@@ -0,0 +1,153 @@
+package auth
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/internal/storage/memory"
+)
+
+// syncRevocationBus delivers events synchronously to all subscribers.
+type syncRevocationBus struct {
+	mu       sync.Mutex
+	handlers []func(RevocationEvent)
+	events   []RevocationEvent
+}
+
+func (b *syncRevocationBus) Publish(event RevocationEvent) error {
+	b.mu.Lock()
+	handlers := append([]func(RevocationEvent){}, b.handlers...)
+	b.events = append(b.events, event)
+	b.mu.Unlock()
+	for _, handler := range handlers {
+		handler(event)
+	}
+	return nil
+}
+
+func (b *syncRevocationBus) Subscribe(handler func(RevocationEvent)) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+	return func() {}, nil
+}
+
+// newReplicas creates two Auth instances sharing storage, sessions and a
+// revocation bus, each with its own login cache.
+func newReplicas(t *testing.T) (*Auth, *Auth) {
+	t.Helper()
+	shared := memory.NewInMemoryStorage()
+	bus := &syncRevocationBus{}
+	sessions := NewMemorySessionStore()
+	clock := NewFrozenClock(time.Now().UTC().Truncate(time.Second))
+
+	replicas := make([]*Auth, 2)
+	for i := range replicas {
+		a, err := newAuthWithStorage(shared, &AuthConfig{
+			JWTSecret:          "go-auth-test-secret",
+			JWTRefreshSecret:   "go-auth-test-refresh-secret",
+			LogLevel:           "error",
+			Clock:              clock,
+			PasswordHashParams: TestHashParams,
+			LoginCache:         LoginCacheConfig{TTL: time.Hour},
+			Sessions:           SessionConfig{Store: sessions},
+			Revocation:         RevocationConfig{Bus: bus},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create replica: %v", err)
+		}
+		t.Cleanup(a.Close)
+		replicas[i] = a
+	}
+	return replicas[0], replicas[1]
+}
+
+func TestRevocationInvalidatesOtherReplicas(t *testing.T) {
+	a, b := newReplicas(t)
+	user, err := a.Register(RegisterRequest{Username: "alice", Email: "alice@example.com", Password: "old-password-1"})
+	if err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+
+	// Warm the login cache of b
+	if _, err := b.Login("alice", "old-password-1", nil); err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+
+	if err := a.Users().ChangePassword(user.ID, "old-password-1", "new-password-1"); err != nil {
+		t.Fatalf("Failed to change password: %v", err)
+	}
+	if _, err := b.Login("alice", "old-password-1", nil); err == nil {
+		t.Error("Expected the other replica to reject the old password immediately")
+	}
+	if _, err := b.Login("alice", "new-password-1", nil); err != nil {
+		t.Errorf("Expected the other replica to accept the new password: %v", err)
+	}
+}
+
+func TestRevokeAllAcrossReplicas(t *testing.T) {
+	a, b := newReplicas(t)
+	user, _ := a.Register(RegisterRequest{Username: "alice", Email: "alice@example.com", Password: "password-1"})
+	result, err := b.Login("alice", "password-1", nil)
+	if err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+	// Leaves unflushed activity on b
+	if _, err := b.ValidateAccessToken(result.AccessToken); err != nil {
+		t.Fatalf("Expected the token to be valid: %v", err)
+	}
+
+	if err := a.Tokens().RevokeAll(user.ID); err != nil {
+		t.Fatalf("Failed to revoke all: %v", err)
+	}
+	_, err = b.ValidateAccessToken(result.AccessToken)
+	if authErr, ok := err.(*AuthError); !ok || authErr.Code != ErrCodeSessionExpired {
+		t.Errorf("Expected the other replica to reject the token, got %v", err)
+	}
+	if _, err := b.RefreshToken(result.RefreshToken); err == nil {
+		t.Error("Expected the refresh token to be revoked")
+	}
+}
+
+func TestRevokeAllWithoutSessions(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("alice", "alice-password")
+	before := ta.LoginAs("alice")
+
+	if err := ta.Tokens().RevokeAll(user.ID); err != nil {
+		t.Fatalf("Failed to revoke all: %v", err)
+	}
+	if _, err := ta.Tokens().Validate(before.AccessToken); !isCode(err, ErrCodeTokenRevoked) {
+		t.Errorf("Expected the access token to be revoked, got %v", err)
+	}
+	if _, err := ta.Tokens().Refresh(before.RefreshToken); !isCode(err, ErrCodeTokenRevoked) {
+		t.Errorf("Expected the refresh token to be revoked, got %v", err)
+	}
+
+	// Tokens issued after the logout are accepted
+	ta.Clock.Advance(time.Second)
+	after := ta.LoginAs("alice")
+	if _, err := ta.Tokens().Validate(after.AccessToken); err != nil {
+		t.Errorf("Expected the new access token to be valid: %v", err)
+	}
+	if _, err := ta.Tokens().Refresh(after.RefreshToken); err != nil {
+		t.Errorf("Expected the new refresh token to be valid: %v", err)
+	}
+}
+
+func TestRevocationSkipsOwnEvents(t *testing.T) {
+	bus := &syncRevocationBus{}
+	broadcaster := newRevocationBroadcaster(RevocationConfig{Bus: bus}, nil, NewLogger(LogLevelError, nil))
+	applied := 0
+	broadcaster.subscribe(func(RevocationEvent) { applied++ })
+
+	broadcaster.publish(RevocationLogoutAll, "user-1", "")
+	bus.Publish(RevocationEvent{Type: RevocationLogoutAll, UserID: "user-1", Source: "other"})
+	if applied != 1 {
+		t.Errorf("Expected only the foreign event to be applied, got %d", applied)
+	}
+	if len(bus.events) != 2 || bus.events[0].Source == "" {
+		t.Errorf("Expected published events to carry their source, got %v", bus.events)
+	}
+}
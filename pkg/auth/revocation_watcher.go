@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// RevocationUserDisabled announces that a user was deleted or deactivated.
+// It is only delivered to revocation watches, not broadcast on the bus.
+const RevocationUserDisabled = "user_disabled"
+
+// RevocationTarget identifies what a RevocationWatch waits on. Empty fields
+// are not watched.
+type RevocationTarget struct {
+	UserID    string
+	SessionID string
+	TokenID   string
+}
+
+// matches reports whether event revokes the target.
+func (t RevocationTarget) matches(event RevocationEvent) bool {
+	switch event.Type {
+	case RevocationToken:
+		return t.TokenID != "" && event.TokenID == t.TokenID
+	case RevocationSession:
+		return t.SessionID != "" && event.SessionID == t.SessionID
+	case RevocationLogoutAll, RevocationUserDisabled:
+		return t.UserID != "" && event.UserID == t.UserID
+	}
+	return false
+}
+
+// RevocationWatcher notifies long-lived connections, such as server-sent
+// event streams and websockets, when the user, session or token they were
+// opened with is revoked, so they can be closed instead of outliving the
+// revocation. It sees the revocations of this instance and, with a
+// revocation bus (AuthConfig.Revocation), those of other instances.
+type RevocationWatcher struct {
+	storage storage.EnhancedStorage
+	clock   Clock
+
+	mu      sync.Mutex
+	watches map[*RevocationWatch]struct{}
+}
+
+func newRevocationWatcher(storage storage.EnhancedStorage, clock Clock) *RevocationWatcher {
+	return &RevocationWatcher{storage: storage, clock: clock, watches: make(map[*RevocationWatch]struct{})}
+}
+
+// RevocationWatcher returns the watcher of revocations.
+func (a *Auth) RevocationWatcher() *RevocationWatcher {
+	return a.watcher
+}
+
+// RevocationWatch waits for the revocation of a RevocationTarget.
+type RevocationWatch struct {
+	target  RevocationTarget
+	watcher *RevocationWatcher
+	done    chan struct{}
+
+	mu    sync.Mutex
+	event *RevocationEvent
+}
+
+// Watch starts watching target. Call Stop once the connection ends.
+func (w *RevocationWatcher) Watch(target RevocationTarget) *RevocationWatch {
+	watch := &RevocationWatch{target: target, watcher: w, done: make(chan struct{})}
+	w.mu.Lock()
+	w.watches[watch] = struct{}{}
+	w.mu.Unlock()
+	return watch
+}
+
+// WatchClaims watches the user, session and token of validated access
+// token claims.
+func (w *RevocationWatcher) WatchClaims(claims map[string]interface{}) *RevocationWatch {
+	var target RevocationTarget
+	target.UserID, _ = claims["user_id"].(string)
+	target.SessionID, _ = claims[sessionClaim].(string)
+	target.TokenID, _ = claims["jti"].(string)
+	return w.Watch(target)
+}
+
+// Done returns a channel that is closed when the target is revoked.
+func (w *RevocationWatch) Done() <-chan struct{} {
+	return w.done
+}
+
+// Revoked reports whether the target was revoked, for callers that poll.
+func (w *RevocationWatch) Revoked() bool {
+	return w.Event() != nil
+}
+
+// Event returns the revocation of the target, or nil if it was not revoked.
+func (w *RevocationWatch) Event() *RevocationEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.event
+}
+
+// Stop stops watching. It is safe to call more than once.
+func (w *RevocationWatch) Stop() {
+	w.watcher.mu.Lock()
+	delete(w.watcher.watches, w)
+	w.watcher.mu.Unlock()
+}
+
+// revoke records event and closes Done.
+func (w *RevocationWatch) revoke(event RevocationEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.event == nil {
+		w.event = &event
+		close(w.done)
+	}
+}
+
+// notify revokes the watches matching event. A nil watcher does nothing.
+func (w *RevocationWatcher) notify(event RevocationEvent) {
+	if w == nil {
+		return
+	}
+	if event.IssuedAt.IsZero() {
+		event.IssuedAt = nowFrom(w.clock)
+	}
+	for _, watch := range w.take(event) {
+		watch.revoke(event)
+	}
+}
+
+// notifyUserChanged revokes the watches of userID if the user was deleted
+// or deactivated. Other instances only announce that a user changed, so
+// the user is looked up to tell.
+func (w *RevocationWatcher) notifyUserChanged(userID string) {
+	if w == nil || userID == "" || !w.watching(userID) {
+		return
+	}
+	user, err := w.storage.GetUserByID(userID)
+	if err == nil && user.IsActive {
+		return
+	}
+	w.notify(RevocationEvent{Type: RevocationUserDisabled, UserID: userID})
+}
+
+// watching reports whether a watch targets userID.
+func (w *RevocationWatcher) watching(userID string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for watch := range w.watches {
+		if watch.target.UserID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// take removes and returns the watches revoked by event.
+func (w *RevocationWatcher) take(event RevocationEvent) []*RevocationWatch {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var matched []*RevocationWatch
+	for watch := range w.watches {
+		if watch.target.matches(event) {
+			delete(w.watches, watch)
+			matched = append(matched, watch)
+		}
+	}
+	return matched
+}
+
+// watcherStorage notifies revocation watches of deleted and deactivated
+// users and blacklisted tokens.
+type watcherStorage struct {
+	storage.EnhancedStorage
+	watcher *RevocationWatcher
+}
+
+func (s *watcherStorage) UpdateUser(userID string, updates storage.UserUpdates) error {
+	if err := s.EnhancedStorage.UpdateUser(userID, updates); err != nil {
+		return err
+	}
+	if updates.IsActive != nil && !*updates.IsActive {
+		s.watcher.notify(RevocationEvent{Type: RevocationUserDisabled, UserID: userID})
+	}
+	return nil
+}
+
+func (s *watcherStorage) DeleteUser(userID string) error {
+	if err := s.EnhancedStorage.DeleteUser(userID); err != nil {
+		return err
+	}
+	s.watcher.notify(RevocationEvent{Type: RevocationUserDisabled, UserID: userID})
+	return nil
+}
+
+func (s *watcherStorage) BlacklistToken(tokenID string, expiresAt time.Time) error {
+	if err := s.EnhancedStorage.BlacklistToken(tokenID, expiresAt); err != nil {
+		return err
+	}
+	s.watcher.notify(RevocationEvent{Type: RevocationToken, TokenID: tokenID})
+	return nil
+}
+
+func (s *watcherStorage) unwrapStorage() storage.EnhancedStorage {
+	return s.EnhancedStorage
+}
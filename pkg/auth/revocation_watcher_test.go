@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"testing"
+)
+
+func TestRevocationWatcher(t *testing.T) {
+	a, b := newReplicas(t)
+	user, _ := a.Register(RegisterRequest{Username: "alice", Email: "alice@example.com", Password: "password-1"})
+	first, err := a.Login("alice", "password-1", nil)
+	if err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+	second, _ := a.Login("alice", "password-1", nil)
+
+	claims, err := a.ValidateAccessToken(first.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected the token to be valid: %v", err)
+	}
+	local := a.RevocationWatcher().WatchClaims(claims)
+	defer local.Stop()
+	remote := b.RevocationWatcher().WatchClaims(claims)
+	defer remote.Stop()
+	otherClaims, _ := a.ValidateAccessToken(second.AccessToken)
+	other := a.RevocationWatcher().WatchClaims(otherClaims)
+	defer other.Stop()
+
+	if err := a.Tokens().RevokeSession(claims[sessionClaim].(string)); err != nil {
+		t.Fatalf("Failed to revoke session: %v", err)
+	}
+	select {
+	case <-local.Done():
+	default:
+		t.Fatal("Expected the local watch to be revoked")
+	}
+	if !remote.Revoked() || remote.Event().Type != RevocationSession {
+		t.Errorf("Expected the remote watch to be revoked by the session, got %+v", remote.Event())
+	}
+	if other.Revoked() {
+		t.Error("Expected the watch of another session to stay open")
+	}
+
+	if err := a.Users().Delete(user.ID); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+	if !other.Revoked() || other.Event().Type != RevocationUserDisabled {
+		t.Errorf("Expected the deletion to revoke the watch, got %+v", other.Event())
+	}
+}
+
+func TestRevocationWatcher_Token(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("alice", TestPassword)
+	result, err := ta.Login("alice", TestPassword, nil)
+	if err != nil {
+		t.Fatalf("Failed to log in: %v", err)
+	}
+	claims, _ := ta.ValidateAccessToken(result.AccessToken)
+	watch := ta.RevocationWatcher().WatchClaims(claims)
+
+	stopped := ta.RevocationWatcher().Watch(RevocationTarget{TokenID: claims["jti"].(string)})
+	stopped.Stop()
+	stopped.Stop()
+
+	if err := ta.Tokens().Revoke(result.AccessToken); err != nil {
+		t.Fatalf("Failed to revoke: %v", err)
+	}
+	if !watch.Revoked() || watch.Event().TokenID != claims["jti"] {
+		t.Errorf("Expected the token revocation to revoke the watch, got %+v", watch.Event())
+	}
+	if stopped.Revoked() {
+		t.Error("Expected a stopped watch not to be revoked")
+	}
+}
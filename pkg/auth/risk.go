@@ -0,0 +1,360 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+// stepUpPurpose is the action token purpose of step-up challenges.
+const stepUpPurpose = "login-step-up"
+
+// Limits of the login history kept per user for risk signals.
+const (
+	maxRiskAttempts = 100
+	maxRiskDevices  = 10
+	riskHistoryTTL  = 30 * 24 * time.Hour
+)
+
+// Risk actions recorded in login_risk events.
+const (
+	RiskActionAllow  = "allow"
+	RiskActionStepUp = "step_up"
+	RiskActionDeny   = "deny"
+)
+
+// RiskSignals describes a login attempt with valid credentials.
+type RiskSignals struct {
+	UserID    string
+	Username  string
+	IP        string
+	UserAgent string
+	// Location is the location of IP, with a GeoIPResolver configured.
+	Location *GeoLocation
+	// IPReputation is the result of RiskConfig.IPReputation, e.g. 0 for a
+	// clean address and 1 for a known bad one. Zero without a callback.
+	IPReputation float64
+	// RecentAttempts and RecentFailures count the earlier login attempts of
+	// the user within RiskConfig.VelocityWindow.
+	RecentAttempts int
+	RecentFailures int
+	// NewDevice reports that the user agent is not among those of the
+	// user's recent successful logins, which is also the case for the first
+	// login.
+	NewDevice bool
+}
+
+// RiskScorer scores login attempts. Higher scores are riskier; they are only
+// compared with the thresholds of RiskConfig, so any scale works.
+type RiskScorer interface {
+	Score(ctx context.Context, signals RiskSignals) (float64, error)
+}
+
+// RiskScorerFunc adapts a function to a RiskScorer.
+type RiskScorerFunc func(ctx context.Context, signals RiskSignals) (float64, error)
+
+// Score calls f.
+func (f RiskScorerFunc) Score(ctx context.Context, signals RiskSignals) (float64, error) {
+	return f(ctx, signals)
+}
+
+// RiskConfig scores logins with valid credentials. Logins scoring at least
+// DenyThreshold fail with LOGIN_DENIED; those scoring at least
+// StepUpThreshold fail with STEP_UP_REQUIRED, whose details carry a
+// challenge that Auth.CompleteStepUp exchanges for tokens once the
+// application has verified a second factor. Scores are recorded on the
+// session and in login_risk events.
+//
+// Velocity and device signals are kept in memory and are not shared between
+// instances.
+type RiskConfig struct {
+	// Scorer scores logins. Nil disables risk scoring.
+	Scorer RiskScorer
+	// IPReputation optionally rates the client IP, e.g. from a threat
+	// intelligence feed.
+	IPReputation func(ctx context.Context, ip string) (float64, error)
+	// VelocityWindow is how far back login attempts are counted. Defaults to an hour.
+	VelocityWindow time.Duration
+	// StepUpThreshold and DenyThreshold are the scores from which logins
+	// need a step-up or are denied. Zero disables a threshold.
+	StepUpThreshold float64
+	DenyThreshold   float64
+	// StepUpTTL is how long a step-up challenge is valid. Defaults to 5 minutes.
+	StepUpTTL time.Duration
+	// FailClosed denies logins when the scorer or the reputation callback
+	// fails. By default such logins are allowed and the error is logged.
+	FailClosed bool
+}
+
+// riskEngine scores logins and keeps the per-user history their signals
+// are derived from. A nil *riskEngine allows every login.
+type riskEngine struct {
+	config RiskConfig
+	clock  Clock
+	logger *Logger
+
+	mu        sync.Mutex
+	history   map[string]*riskHistory // by user ID
+	lastPrune time.Time
+}
+
+// riskHistory is the recent login history of a user.
+type riskHistory struct {
+	attempts []riskAttempt // oldest first
+	devices  []string      // user agents of successful logins, most recent last
+	lastSeen time.Time
+}
+
+type riskAttempt struct {
+	at     time.Time
+	failed bool
+}
+
+// riskAssessment is the outcome of scoring a login.
+type riskAssessment struct {
+	score  float64
+	action string
+}
+
+// newRiskEngine returns nil unless a scorer is configured.
+func newRiskEngine(config RiskConfig, clock Clock, logger *Logger) *riskEngine {
+	if config.Scorer == nil {
+		return nil
+	}
+	if config.VelocityWindow <= 0 {
+		config.VelocityWindow = time.Hour
+	}
+	if config.StepUpTTL <= 0 {
+		config.StepUpTTL = 5 * time.Minute
+	}
+	return &riskEngine{
+		config:    config,
+		clock:     clock,
+		logger:    logger,
+		history:   make(map[string]*riskHistory),
+		lastPrune: nowFrom(clock),
+	}
+}
+
+// signals collects the signals of a login by user from client.
+func (e *riskEngine) signals(user *models.User, client clientInfo, location *GeoLocation) RiskSignals {
+	signals := RiskSignals{
+		UserID:    user.ID,
+		Username:  user.Username,
+		IP:        client.ip,
+		UserAgent: client.userAgent,
+		Location:  location,
+		NewDevice: true,
+	}
+	since := nowFrom(e.clock).Add(-e.config.VelocityWindow)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if history, ok := e.history[user.ID]; ok {
+		for _, attempt := range history.attempts {
+			if attempt.at.After(since) {
+				signals.RecentAttempts++
+				if attempt.failed {
+					signals.RecentFailures++
+				}
+			}
+		}
+		signals.NewDevice = !slices.Contains(history.devices, client.userAgent)
+	}
+	return signals
+}
+
+// assess scores signals, after adding the IP reputation, and decides
+// whether the login may proceed.
+func (e *riskEngine) assess(ctx context.Context, signals *RiskSignals) (riskAssessment, error) {
+	if e.config.IPReputation != nil && signals.IP != "" {
+		reputation, err := e.config.IPReputation(ctx, signals.IP)
+		if err != nil {
+			if e.config.FailClosed {
+				return riskAssessment{action: RiskActionDeny}, err
+			}
+			e.logger.Warn("IP reputation lookup failed", map[string]interface{}{
+				"user_id": signals.UserID,
+				"ip":      signals.IP,
+				"error":   err,
+			})
+		}
+		signals.IPReputation = reputation
+	}
+
+	score, err := e.config.Scorer.Score(ctx, *signals)
+	if err != nil {
+		if e.config.FailClosed {
+			return riskAssessment{action: RiskActionDeny}, err
+		}
+		e.logger.Warn("Login risk scoring failed", map[string]interface{}{
+			"user_id": signals.UserID,
+			"error":   err,
+		})
+		return riskAssessment{action: RiskActionAllow}, nil
+	}
+
+	switch {
+	case e.config.DenyThreshold != 0 && score >= e.config.DenyThreshold:
+		return riskAssessment{score: score, action: RiskActionDeny}, nil
+	case e.config.StepUpThreshold != 0 && score >= e.config.StepUpThreshold:
+		return riskAssessment{score: score, action: RiskActionStepUp}, nil
+	default:
+		return riskAssessment{score: score, action: RiskActionAllow}, nil
+	}
+}
+
+// observe records a login attempt of userID from userAgent.
+func (e *riskEngine) observe(userID, userAgent string, success bool) {
+	if e == nil || userID == "" {
+		return
+	}
+	now := nowFrom(e.clock)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if now.Sub(e.lastPrune) > e.config.VelocityWindow {
+		e.prune(now)
+	}
+	history, ok := e.history[userID]
+	if !ok {
+		history = &riskHistory{}
+		e.history[userID] = history
+	}
+	history.lastSeen = now
+
+	since := now.Add(-e.config.VelocityWindow)
+	attempts := history.attempts[:0]
+	for _, attempt := range history.attempts {
+		if attempt.at.After(since) {
+			attempts = append(attempts, attempt)
+		}
+	}
+	if len(attempts) >= maxRiskAttempts {
+		attempts = attempts[1:]
+	}
+	history.attempts = append(attempts, riskAttempt{at: now, failed: !success})
+
+	if success && userAgent != "" {
+		history.devices = slices.DeleteFunc(history.devices, func(device string) bool { return device == userAgent })
+		if len(history.devices) >= maxRiskDevices {
+			history.devices = history.devices[1:]
+		}
+		history.devices = append(history.devices, userAgent)
+	}
+}
+
+// prune drops the history of users not seen for a while. The caller must
+// hold e.mu.
+func (e *riskEngine) prune(now time.Time) {
+	e.lastPrune = now
+	for userID, history := range e.history {
+		if now.Sub(history.lastSeen) > riskHistoryTTL {
+			delete(e.history, userID)
+		}
+	}
+}
+
+// assessLoginRisk scores a login by user with valid credentials. Risky
+// logins fail with LOGIN_DENIED or with STEP_UP_REQUIRED and a challenge.
+// It returns the score to record on the session.
+func (a *Auth) assessLoginRisk(ctx context.Context, user *models.User) (float64, error) {
+	if a.risk == nil {
+		return 0, nil
+	}
+	client := clientInfoFrom(ctx)
+	location := a.eventLogger.geo.lookup(client.ip)
+	signals := a.risk.signals(user, client, location)
+	assessment, err := a.risk.assess(ctx, &signals)
+	a.eventLogger.LogLoginRisk(signals, assessment.score, assessment.action, err)
+
+	switch assessment.action {
+	case RiskActionDeny:
+		return assessment.score, ErrLoginDenied()
+	case RiskActionStepUp:
+		challenge, signErr := a.Tokens().SignAction(stepUpPurpose, user.ID, map[string]string{
+			"score": strconv.FormatFloat(assessment.score, 'g', -1, 64),
+		}, a.risk.config.StepUpTTL)
+		if signErr != nil {
+			return assessment.score, signErr
+		}
+		a.metricsCollector.RecordFunnelStep(FunnelStepUp, false)
+		return assessment.score, ErrStepUpRequired(challenge)
+	}
+	return assessment.score, nil
+}
+
+// CompleteStepUp finishes a login that failed with STEP_UP_REQUIRED. The
+// application must verify a second factor of the user, e.g. a TOTP code,
+// before calling it with the challenge from the error details. Each
+// challenge can be used once.
+func (a *Auth) CompleteStepUp(ctx context.Context, challenge string, customClaims map[string]interface{}) (*LoginResult, error) {
+	start := time.Now()
+	var userID, username string
+	var success bool
+	var err error
+
+	defer func() {
+		duration := time.Since(start)
+		client := clientInfoFrom(ctx)
+		tenant := tenantFrom(ctx)
+		a.eventLogger.forTenant(tenant).LogLogin(userID, username, client.ip, client.userAgent, success, duration, err)
+		a.metricsCollector.RecordLoginAttempt(success, duration)
+		a.metricsCollector.RecordTenantLogin(tenant, success)
+		a.risk.observe(userID, client.userAgent, success)
+		if success {
+			a.metricsCollector.RecordFunnelStep(FunnelStepUp, true)
+		}
+	}()
+
+	if err = a.maintenance.check(maintenanceLogin); err != nil {
+		return nil, err
+	}
+
+	claims, verifyErr := a.Tokens().VerifyAction(challenge, stepUpPurpose)
+	if verifyErr != nil {
+		err = verifyErr
+		return nil, err
+	}
+	blacklisted, blacklistErr := a.storage.IsTokenBlacklisted(claims.ID)
+	if blacklistErr != nil {
+		err = WrapDatabaseError(blacklistErr)
+		return nil, err
+	}
+	if blacklisted {
+		err = ErrInvalidToken()
+		return nil, err
+	}
+	if blacklistErr = a.storage.BlacklistToken(claims.ID, claims.ExpiresAt); blacklistErr != nil {
+		err = WrapDatabaseError(blacklistErr)
+		return nil, err
+	}
+
+	user, getUserErr := a.storage.GetUserByID(claims.Subject)
+	if getUserErr != nil {
+		err = ErrInvalidToken()
+		return nil, err
+	}
+	userID, username = user.ID, user.Username
+	if !user.IsActive {
+		err = ErrUserInactive()
+		return nil, err
+	}
+	if err = checkAccountExpiry(user, a.clock); err != nil {
+		return nil, err
+	}
+
+	score, _ := strconv.ParseFloat(claims.Params["score"], 64)
+	result, loginErr := a.completeLogin(ctx, user, customClaims, score)
+	if loginErr != nil {
+		err = loginErr
+		return nil, err
+	}
+	success = true
+	a.captcha.reset(username)
+	return result, nil
+}
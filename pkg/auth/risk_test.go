@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLoginRiskScoring(t *testing.T) {
+	ta, _ := newSessionTestAuth(t, 0)
+	user := ta.SeedUser("alice", "alice-password")
+	var seen RiskSignals
+	ta.risk = newRiskEngine(RiskConfig{
+		Scorer: RiskScorerFunc(func(ctx context.Context, signals RiskSignals) (float64, error) {
+			seen = signals
+			score := signals.IPReputation
+			if signals.NewDevice {
+				score += 0.5
+			}
+			return score, nil
+		}),
+		IPReputation: func(ctx context.Context, ip string) (float64, error) {
+			if ip == "198.51.100.1" {
+				return 1, nil
+			}
+			return 0, nil
+		},
+		StepUpThreshold: 0.5,
+		DenyThreshold:   1,
+	}, ta.Clock, ta.logger)
+
+	login := func(ip, userAgent string) (*LoginResult, error) {
+		ctx := WithClientInfo(context.Background(), ip, userAgent)
+		return ta.LoginContext(ctx, "alice", "alice-password", nil)
+	}
+
+	// The first login is from a new device and needs a step-up
+	_, err := login("203.0.113.1", "laptop")
+	var authErr *AuthError
+	if !errors.As(err, &authErr) || authErr.Code != ErrCodeStepUpRequired || authErr.Details == "" {
+		t.Fatalf("Expected STEP_UP_REQUIRED with a challenge, got %v", err)
+	}
+	if !seen.NewDevice || seen.RecentAttempts != 0 || seen.UserID != user.ID {
+		t.Errorf("Unexpected signals: %+v", seen)
+	}
+
+	result, err := ta.CompleteStepUp(context.Background(), authErr.Details, nil)
+	if err != nil {
+		t.Fatalf("Expected the step-up to complete, got %v", err)
+	}
+	if _, err := ta.CompleteStepUp(context.Background(), authErr.Details, nil); err == nil {
+		t.Error("Expected a used challenge to be rejected")
+	}
+
+	claims, _ := ta.ValidateAccessToken(result.AccessToken)
+	session, err := ta.Tokens().GetSession(sessionID(claims))
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+	if session.RiskScore != 0.5 {
+		t.Errorf("Expected the risk score on the session, got %v", session.RiskScore)
+	}
+
+	// The device is known after the completed step-up
+	if _, err := login("203.0.113.1", "laptop"); err != nil {
+		t.Fatalf("Expected a login from a known device to succeed, got %v", err)
+	}
+	if seen.NewDevice || seen.RecentAttempts != 2 {
+		t.Errorf("Expected a known device and 2 recent attempts, got %+v", seen)
+	}
+
+	// A bad IP reputation denies the login
+	if _, err := login("198.51.100.1", "laptop"); !errors.Is(err, ErrLoginDenied()) {
+		t.Errorf("Expected LOGIN_DENIED, got %v", err)
+	}
+}
+
+func TestLoginRiskScoringFailClosed(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("alice", "alice-password")
+	scorer := RiskScorerFunc(func(ctx context.Context, signals RiskSignals) (float64, error) {
+		return 0, errors.New("scorer unavailable")
+	})
+
+	ta.risk = newRiskEngine(RiskConfig{Scorer: scorer, StepUpThreshold: 1}, ta.Clock, ta.logger)
+	if _, err := ta.Login("alice", "alice-password", nil); err != nil {
+		t.Errorf("Expected scorer failures to be ignored by default, got %v", err)
+	}
+
+	ta.risk = newRiskEngine(RiskConfig{Scorer: scorer, StepUpThreshold: 1, FailClosed: true}, ta.Clock, ta.logger)
+	if _, err := ta.Login("alice", "alice-password", nil); !errors.Is(err, ErrLoginDenied()) {
+		t.Errorf("Expected LOGIN_DENIED when failing closed, got %v", err)
+	}
+}
@@ -0,0 +1,217 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/internal/jwtutils"
+)
+
+// DefaultPasswordMinLength is the minimum length of new passwords unless
+// changed with UpdateConfig.
+const DefaultPasswordMinLength = 8
+
+// ConfigUpdate changes the settings of a running Auth, see UpdateConfig.
+// Nil fields are left unchanged.
+type ConfigUpdate struct {
+	// AccessTokenTTL and RefreshTokenTTL apply to tokens issued from then
+	// on; issued tokens keep their expiry.
+	AccessTokenTTL  *time.Duration
+	RefreshTokenTTL *time.Duration
+	// LogLevel is "debug", "info", "warn" or "error".
+	LogLevel *string
+	// RefreshMinInterval changes RefreshLimit.MinInterval. The limit must
+	// have been enabled when the Auth was created; zero lifts it.
+	RefreshMinInterval *time.Duration
+	// PasswordMinLength is the minimum length of passwords set by
+	// ChangePassword and ResetPassword.
+	PasswordMinLength *int
+}
+
+// runtimeSettings holds the settings changed by UpdateConfig. A nil
+// *runtimeSettings uses the defaults.
+type runtimeSettings struct {
+	mu                sync.RWMutex
+	accessTTL         time.Duration
+	refreshTTL        time.Duration
+	passwordMinLength int
+}
+
+func newRuntimeSettings(config *AuthConfig) *runtimeSettings {
+	return &runtimeSettings{
+		accessTTL:         config.AccessTokenTTL,
+		refreshTTL:        config.RefreshTokenTTL,
+		passwordMinLength: DefaultPasswordMinLength,
+	}
+}
+
+// tokenTTLs returns the TTLs of issued access and refresh tokens, zero for
+// the configured ones.
+func (s *runtimeSettings) tokenTTLs() (access, refresh time.Duration) {
+	if s == nil {
+		return 0, 0
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.accessTTL, s.refreshTTL
+}
+
+// minPasswordLength returns the minimum length of new passwords.
+func (s *runtimeSettings) minPasswordLength() int {
+	if s == nil {
+		return DefaultPasswordMinLength
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.passwordMinLength
+}
+
+// UpdateConfig applies update without restarting: all of its changes take
+// effect at once, or none if one is invalid. Each applied change is
+// audited with a config_changed event listing the old and new values.
+func (a *Auth) UpdateConfig(update ConfigUpdate) error {
+	if err := update.validate(a.refreshLimiter != nil); err != nil {
+		return err
+	}
+
+	s := a.settings
+	s.mu.Lock()
+	changes := make(map[string]interface{})
+	record := func(name string, old, new interface{}) {
+		if old != new {
+			changes[name] = map[string]interface{}{"old": old, "new": new}
+		}
+	}
+	if update.AccessTokenTTL != nil {
+		record("access_token_ttl", s.accessTTL.String(), update.AccessTokenTTL.String())
+		s.accessTTL = *update.AccessTokenTTL
+	}
+	if update.RefreshTokenTTL != nil {
+		record("refresh_token_ttl", s.refreshTTL.String(), update.RefreshTokenTTL.String())
+		s.refreshTTL = *update.RefreshTokenTTL
+	}
+	if update.PasswordMinLength != nil {
+		record("password_min_length", s.passwordMinLength, *update.PasswordMinLength)
+		s.passwordMinLength = *update.PasswordMinLength
+	}
+	if update.RefreshMinInterval != nil {
+		old := a.refreshLimiter.setMinInterval(*update.RefreshMinInterval)
+		record("refresh_min_interval", old.String(), update.RefreshMinInterval.String())
+	}
+	if update.LogLevel != nil {
+		level := ParseLogLevel(*update.LogLevel)
+		record("log_level", a.logger.Level().String(), level.String())
+		a.logger.SetLevel(level)
+	}
+	s.mu.Unlock()
+
+	if len(changes) > 0 {
+		a.eventLogger.LogConfigChange(changes)
+	}
+	return nil
+}
+
+// validate checks update before any of it is applied.
+func (u ConfigUpdate) validate(refreshLimited bool) error {
+	if u.AccessTokenTTL != nil && *u.AccessTokenTTL <= 0 {
+		return ErrConfigError("AccessTokenTTL")
+	}
+	if u.RefreshTokenTTL != nil && *u.RefreshTokenTTL <= 0 {
+		return ErrConfigError("RefreshTokenTTL")
+	}
+	if u.LogLevel != nil {
+		switch strings.ToLower(*u.LogLevel) {
+		case "debug", "info", "warn", "warning", "error":
+		default:
+			return ErrConfigError("LogLevel")
+		}
+	}
+	if u.RefreshMinInterval != nil && (*u.RefreshMinInterval < 0 || !refreshLimited) {
+		return ErrConfigError("RefreshMinInterval")
+	}
+	if u.PasswordMinLength != nil && (*u.PasswordMinLength < 4 || *u.PasswordMinLength > 128) {
+		return ErrConfigError("PasswordMinLength")
+	}
+	return nil
+}
+
+// currentConfig returns a copy of the configuration with the changes of
+// UpdateConfig applied.
+func (a *Auth) currentConfig() *AuthConfig {
+	if a.config == nil {
+		return nil
+	}
+	config := *a.config
+	if access, refresh := a.settings.tokenTTLs(); access > 0 {
+		config.AccessTokenTTL, config.RefreshTokenTTL = access, refresh
+	}
+	config.LogLevel = strings.ToLower(a.logger.Level().String())
+	if a.refreshLimiter != nil {
+		config.RefreshLimit.MinInterval = a.refreshLimiter.minInterval()
+	}
+	return &config
+}
+
+// setMinInterval changes the minimum interval and returns the previous one.
+func (l *refreshLimiter) setMinInterval(interval time.Duration) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	old := l.config.MinInterval
+	l.config.MinInterval = interval
+	return old
+}
+
+// minInterval returns the minimum interval.
+func (l *refreshLimiter) minInterval() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.config.MinInterval
+}
+
+// ttlTokenManager issues tokens with the TTLs set by UpdateConfig.
+type ttlTokenManager struct {
+	jwtutils.TokenManager
+	settings *runtimeSettings
+}
+
+func (m *ttlTokenManager) GenerateAccessToken(userID string, customClaims map[string]any) (string, error) {
+	return m.GenerateAccessTokenWithOptions(userID, customClaims, jwtutils.IssueOptions{})
+}
+
+func (m *ttlTokenManager) GenerateAccessTokenWithOptions(userID string, customClaims map[string]any, opts jwtutils.IssueOptions) (string, error) {
+	if access, _ := m.settings.tokenTTLs(); opts.TTL == 0 {
+		opts.TTL = access
+	}
+	return m.TokenManager.GenerateAccessTokenWithOptions(userID, customClaims, opts)
+}
+
+func (m *ttlTokenManager) GenerateRefreshToken(userID string) (string, error) {
+	return m.GenerateRefreshTokenWithOptions(userID, jwtutils.IssueOptions{})
+}
+
+func (m *ttlTokenManager) GenerateRefreshTokenWithOptions(userID string, opts jwtutils.IssueOptions) (string, error) {
+	if _, refresh := m.settings.tokenTTLs(); opts.TTL == 0 {
+		opts.TTL = refresh
+	}
+	return m.TokenManager.GenerateRefreshTokenWithOptions(userID, opts)
+}
+
+// RefreshAccessToken issues the new access token itself so that it gets
+// the current TTL.
+func (m *ttlTokenManager) RefreshAccessToken(refreshToken string) (string, error) {
+	claims, err := m.TokenManager.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		return "", fmt.Errorf("could not validate refresh token: %w", err)
+	}
+	if tokenType, _ := claims["token_type"].(string); tokenType != "refresh" {
+		return "", errors.New("token is not a valid refresh token")
+	}
+	userID, ok := claims["sub"].(string)
+	if !ok {
+		return "", errors.New("invalid user ID in refresh token claims")
+	}
+	return m.GenerateAccessToken(userID, nil)
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUpdateConfig(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("alice", TestPassword)
+
+	accessTTL, passwordMinLength, level := 5*time.Minute, 12, "error"
+	if err := ta.UpdateConfig(ConfigUpdate{AccessTokenTTL: &accessTTL, PasswordMinLength: &passwordMinLength, LogLevel: &level}); err != nil {
+		t.Fatalf("UpdateConfig failed: %v", err)
+	}
+
+	result := ta.LoginAs("alice")
+	claims, err := ta.ValidateAccessToken(result.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected the token to be valid: %v", err)
+	}
+	if exp := int64(claims["exp"].(float64)); exp != ta.Clock.Now().Add(accessTTL).Unix() {
+		t.Errorf("Expected the new access token TTL, got expiry %d", exp)
+	}
+	if ta.logger.Level() != LogLevelError || ta.currentConfig().AccessTokenTTL != accessTTL {
+		t.Errorf("Expected the changes to be applied, got %v, %v", ta.logger.Level(), ta.currentConfig().AccessTokenTTL)
+	}
+
+	err = ta.Users().ChangePassword(user.ID, TestPassword, "short-pass1")
+	if authErr, ok := err.(*AuthError); !ok || authErr.Code != ErrCodeWeakPassword {
+		t.Errorf("Expected the new minimum length to apply, got %v", err)
+	}
+}
+
+func TestUpdateConfig_Invalid(t *testing.T) {
+	ta := NewTestAuth(t)
+	accessTTL, interval := time.Hour, time.Second
+	before := ta.currentConfig().AccessTokenTTL
+
+	// Rejected as a whole: the refresh limit was not enabled
+	err := ta.UpdateConfig(ConfigUpdate{AccessTokenTTL: &accessTTL, RefreshMinInterval: &interval})
+	if authErr, ok := err.(*AuthError); !ok || authErr.Code != ErrCodeConfigError {
+		t.Fatalf("Expected a config error, got %v", err)
+	}
+	if ta.currentConfig().AccessTokenTTL != before {
+		t.Error("Expected no change to be applied")
+	}
+
+	level := "verbose"
+	if err := ta.UpdateConfig(ConfigUpdate{LogLevel: &level}); err == nil {
+		t.Error("Expected an unknown log level to be rejected")
+	}
+}
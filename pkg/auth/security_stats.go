@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// securityStatsRetention is the longest window SecurityStats reports.
+	securityStatsRetention = 24 * time.Hour
+	// maxSecurityStatsIPs bounds the distinct IPs tracked per minute, so
+	// that a distributed attack cannot exhaust memory. Further IPs are only
+	// counted in the totals.
+	maxSecurityStatsIPs = 1000
+	// securityStatsTopIPs is the number of offending IPs reported.
+	securityStatsTopIPs = 10
+)
+
+// SecurityStats aggregates login telemetry over a window, e.g. to feed a
+// security dashboard without scraping logs.
+type SecurityStats struct {
+	Window           time.Duration `json:"window"`
+	Since            time.Time     `json:"since"`
+	SuccessfulLogins int64         `json:"successful_logins"`
+	FailedLogins     int64         `json:"failed_logins"`
+	// Lockouts counts logins rejected because of too many failures, with
+	// CAPTCHA_REQUIRED or RATE_LIMIT_EXCEEDED.
+	Lockouts int64 `json:"lockouts"`
+
+	// FailedLoginsPerMinute has one entry per minute of the window, oldest first.
+	FailedLoginsPerMinute []MinuteCount `json:"failed_logins_per_minute"`
+	// TopIPs are the IPs with the most failed logins, most first.
+	TopIPs []IPCount `json:"top_ips"`
+	// Countries counts logins by country, with a GeoIPResolver configured.
+	Countries []CountryCount `json:"countries,omitempty"`
+}
+
+// MinuteCount is the number of events in the minute starting at Minute.
+type MinuteCount struct {
+	Minute time.Time `json:"minute"`
+	Count  int64     `json:"count"`
+}
+
+// IPCount is the number of failed logins from an IP.
+type IPCount struct {
+	IP           string `json:"ip"`
+	FailedLogins int64  `json:"failed_logins"`
+}
+
+// CountryCount is the number of logins from a country.
+type CountryCount struct {
+	Country          string `json:"country"`
+	SuccessfulLogins int64  `json:"successful_logins"`
+	FailedLogins     int64  `json:"failed_logins"`
+}
+
+// securityTelemetry counts login outcomes in one-minute buckets. A nil
+// *securityTelemetry records nothing.
+type securityTelemetry struct {
+	clock Clock
+
+	mu      sync.Mutex
+	buckets map[int64]*securityBucket // by Unix minute
+}
+
+// securityBucket holds the login outcomes of one minute.
+type securityBucket struct {
+	succeeded int64
+	failed    int64
+	lockouts  int64
+	ips       map[string]int64 // failed logins
+	countries map[string]*CountryCount
+}
+
+func newSecurityTelemetry(clock Clock) *securityTelemetry {
+	return &securityTelemetry{clock: clock, buckets: make(map[int64]*securityBucket)}
+}
+
+// recordLogin counts a login from ip. location may be nil.
+func (s *securityTelemetry) recordLogin(ip string, location *GeoLocation, success bool, err error) {
+	if s == nil {
+		return
+	}
+	minute := nowFrom(s.clock).Unix() / 60
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bucket, ok := s.buckets[minute]
+	if !ok {
+		s.prune(minute)
+		bucket = &securityBucket{ips: make(map[string]int64), countries: make(map[string]*CountryCount)}
+		s.buckets[minute] = bucket
+	}
+
+	if success {
+		bucket.succeeded++
+	} else {
+		bucket.failed++
+		var authErr *AuthError
+		if errors.As(err, &authErr) && (authErr.Code == ErrCodeCaptchaRequired || authErr.Code == ErrCodeRateLimitExceeded) {
+			bucket.lockouts++
+		}
+		if _, tracked := bucket.ips[ip]; ip != "" && (tracked || len(bucket.ips) < maxSecurityStatsIPs) {
+			bucket.ips[ip]++
+		}
+	}
+
+	if location != nil && location.Country != "" {
+		country, ok := bucket.countries[location.Country]
+		if !ok {
+			country = &CountryCount{Country: location.Country}
+			bucket.countries[location.Country] = country
+		}
+		if success {
+			country.SuccessfulLogins++
+		} else {
+			country.FailedLogins++
+		}
+	}
+}
+
+// prune drops buckets older than the retention. The caller must hold s.mu.
+func (s *securityTelemetry) prune(minute int64) {
+	oldest := minute - int64(securityStatsRetention/time.Minute)
+	for m := range s.buckets {
+		if m <= oldest {
+			delete(s.buckets, m)
+		}
+	}
+}
+
+// stats aggregates the buckets of the last window, including the current
+// minute. The window is rounded up to whole minutes and capped at 24 hours.
+// A nil *securityTelemetry reports empty stats.
+func (s *securityTelemetry) stats(window time.Duration) SecurityStats {
+	minutes := int64((window + time.Minute - 1) / time.Minute)
+	if minutes < 1 {
+		minutes = 1
+	}
+	if max := int64(securityStatsRetention / time.Minute); minutes > max {
+		minutes = max
+	}
+	var clock Clock
+	var buckets map[int64]*securityBucket
+	if s != nil {
+		clock = s.clock
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		buckets = s.buckets
+	}
+	first := nowFrom(clock).Unix()/60 - minutes + 1
+	stats := SecurityStats{
+		Window:                time.Duration(minutes) * time.Minute,
+		Since:                 time.Unix(first*60, 0).UTC(),
+		FailedLoginsPerMinute: make([]MinuteCount, 0, minutes),
+		TopIPs:                []IPCount{},
+	}
+
+	ips := make(map[string]int64)
+	countries := make(map[string]*CountryCount)
+	for m := first; m < first+minutes; m++ {
+		entry := MinuteCount{Minute: time.Unix(m*60, 0).UTC()}
+		if bucket, ok := buckets[m]; ok {
+			entry.Count = bucket.failed
+			stats.SuccessfulLogins += bucket.succeeded
+			stats.FailedLogins += bucket.failed
+			stats.Lockouts += bucket.lockouts
+			for ip, count := range bucket.ips {
+				ips[ip] += count
+			}
+			for name, count := range bucket.countries {
+				country, ok := countries[name]
+				if !ok {
+					country = &CountryCount{Country: name}
+					countries[name] = country
+				}
+				country.SuccessfulLogins += count.SuccessfulLogins
+				country.FailedLogins += count.FailedLogins
+			}
+		}
+		stats.FailedLoginsPerMinute = append(stats.FailedLoginsPerMinute, entry)
+	}
+
+	for ip, count := range ips {
+		stats.TopIPs = append(stats.TopIPs, IPCount{IP: ip, FailedLogins: count})
+	}
+	sort.Slice(stats.TopIPs, func(i, j int) bool {
+		if stats.TopIPs[i].FailedLogins != stats.TopIPs[j].FailedLogins {
+			return stats.TopIPs[i].FailedLogins > stats.TopIPs[j].FailedLogins
+		}
+		return stats.TopIPs[i].IP < stats.TopIPs[j].IP
+	})
+	if len(stats.TopIPs) > securityStatsTopIPs {
+		stats.TopIPs = stats.TopIPs[:securityStatsTopIPs]
+	}
+
+	for _, country := range countries {
+		stats.Countries = append(stats.Countries, *country)
+	}
+	sort.Slice(stats.Countries, func(i, j int) bool {
+		a, b := stats.Countries[i], stats.Countries[j]
+		if a.SuccessfulLogins+a.FailedLogins != b.SuccessfulLogins+b.FailedLogins {
+			return a.SuccessfulLogins+a.FailedLogins > b.SuccessfulLogins+b.FailedLogins
+		}
+		return a.Country < b.Country
+	})
+	return stats
+}
+
+// SecurityStats returns login telemetry of the last window: failed logins
+// per minute, the top offending IPs, lockouts and the country distribution.
+// Windows are rounded up to whole minutes and capped at 24 hours.
+func (m *Monitor) SecurityStats(window time.Duration) SecurityStats {
+	return m.security.stats(window)
+}
+
+// HTTPSecurityStatsHandler returns an HTTP handler for SecurityStats. The
+// window is read from the "window" query parameter, e.g. "15m", and
+// defaults to one hour. The response includes client IPs, so the handler is
+// not registered by RegisterHTTPHandlers and should only be served to
+// operators.
+func (m *Monitor) HTTPSecurityStatsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		window := time.Hour
+		if value := r.URL.Query().Get("window"); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed <= 0 {
+				http.Error(w, "Invalid window", http.StatusBadRequest)
+				return
+			}
+			window = parsed
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(m.SecurityStats(window)); err != nil {
+			m.logger.Error("Failed to encode security stats response", map[string]interface{}{
+				"error": err,
+			})
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+	}
+}
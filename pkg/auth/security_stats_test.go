@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSecurityStats(t *testing.T) {
+	clock := NewFrozenClock(time.Date(2024, 1, 1, 12, 0, 30, 0, time.UTC))
+	eventLogger := NewAuthEventLogger(NewLogger(LogLevelError, io.Discard))
+	eventLogger.geo = newLoginGeo(GeoIPConfig{Resolver: mapGeoIPResolver{
+		"203.0.113.1":  {Country: "DE"},
+		"198.51.100.1": {Country: "BR"},
+	}})
+	eventLogger.security = newSecurityTelemetry(clock)
+	monitor := &Monitor{logger: NewLogger(LogLevelError, io.Discard), security: eventLogger.security}
+
+	fail := func(ip string, err error) {
+		eventLogger.LogLogin("", "alice", ip, "test", false, time.Millisecond, err)
+	}
+	fail("198.51.100.1", ErrInvalidCredentials())
+	clock.Advance(time.Minute)
+	fail("198.51.100.1", ErrInvalidCredentials())
+	fail("198.51.100.1", ErrCaptchaRequired("recaptcha:site-key"))
+	fail("192.0.2.1", ErrInvalidCredentials())
+	eventLogger.LogLogin("user-1", "alice", "203.0.113.1", "test", true, time.Millisecond, nil)
+
+	stats := monitor.SecurityStats(5 * time.Minute)
+	if stats.FailedLogins != 4 || stats.SuccessfulLogins != 1 || stats.Lockouts != 1 {
+		t.Errorf("Unexpected totals: %+v", stats)
+	}
+	if len(stats.FailedLoginsPerMinute) != 5 {
+		t.Fatalf("Expected 5 minutes, got %v", stats.FailedLoginsPerMinute)
+	}
+	if last := stats.FailedLoginsPerMinute[4]; last.Count != 3 || !last.Minute.Equal(time.Date(2024, 1, 1, 12, 1, 0, 0, time.UTC)) {
+		t.Errorf("Expected 3 failures in the current minute, got %+v", last)
+	}
+	if stats.FailedLoginsPerMinute[3].Count != 1 {
+		t.Errorf("Expected 1 failure in the previous minute, got %+v", stats.FailedLoginsPerMinute[3])
+	}
+	if len(stats.TopIPs) != 2 || stats.TopIPs[0] != (IPCount{IP: "198.51.100.1", FailedLogins: 3}) {
+		t.Errorf("Unexpected top IPs: %v", stats.TopIPs)
+	}
+	if len(stats.Countries) != 2 || stats.Countries[0] != (CountryCount{Country: "BR", FailedLogins: 3}) ||
+		stats.Countries[1] != (CountryCount{Country: "DE", SuccessfulLogins: 1}) {
+		t.Errorf("Unexpected countries: %v", stats.Countries)
+	}
+
+	// The window only covers the current minute
+	if stats := monitor.SecurityStats(time.Second); stats.FailedLogins != 3 || len(stats.FailedLoginsPerMinute) != 1 {
+		t.Errorf("Expected only the current minute, got %+v", stats)
+	}
+
+	// Old buckets are dropped once a new minute is recorded after the retention
+	clock.Advance(25 * time.Hour)
+	fail("192.0.2.1", ErrInvalidCredentials())
+	if stats := monitor.SecurityStats(48 * time.Hour); stats.FailedLogins != 1 || stats.Window != 24*time.Hour {
+		t.Errorf("Expected old logins to expire, got %d in %s", stats.FailedLogins, stats.Window)
+	}
+	if len(eventLogger.security.buckets) != 1 {
+		t.Errorf("Expected expired buckets to be pruned, got %d", len(eventLogger.security.buckets))
+	}
+
+	// HTTP handler
+	rec := httptest.NewRecorder()
+	monitor.HTTPSecurityStatsHandler()(rec, httptest.NewRequest(http.MethodGet, "/security?window=10m", nil))
+	var body SecurityStats
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Unexpected response %d: %s", rec.Code, rec.Body)
+	}
+	if body.FailedLogins != 1 || len(body.FailedLoginsPerMinute) != 10 {
+		t.Errorf("Unexpected response body: %+v", body)
+	}
+	rec = httptest.NewRecorder()
+	monitor.HTTPSecurityStatsHandler()(rec, httptest.NewRequest(http.MethodGet, "/security?window=soon", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an invalid window, got %d", rec.Code)
+	}
+}
+
+func TestSecurityStatsThroughLogin(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("alice", "alice-password")
+
+	ta.Login("alice", "wrong-password", nil)
+	ta.Login("alice", "alice-password", nil)
+
+	stats := ta.Monitor().SecurityStats(time.Hour)
+	if stats.FailedLogins != 1 || stats.SuccessfulLogins != 1 {
+		t.Errorf("Expected one failed and one successful login, got %+v", stats)
+	}
+}
@@ -517,4 +517,34 @@ func TestSecurityErrorHandling(t *testing.T) {
 		auth.GetUserByEmail("")
 		auth.ValidateAccessToken("")
 	})
-}
\ No newline at end of file
+}
+func TestSecurityCrossEnvironmentTokens(t *testing.T) {
+	newEnv := func(audience string) *Auth {
+		a, err := NewWithConfig(&AuthConfig{
+			JWTSecret:   "shared-secret-across-environments",
+			LogLevel:    "error",
+			JWTAudience: []string{audience},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create auth instance: %v", err)
+		}
+		return a
+	}
+	staging := newEnv("app:staging")
+	production := newEnv("app:production")
+
+	if _, err := staging.Register(RegisterRequest{Username: "alice", Password: "password123"}); err != nil {
+		t.Fatalf("Failed to register user: %v", err)
+	}
+	tokens, err := staging.Login("alice", "password123", nil)
+	if err != nil {
+		t.Fatalf("Failed to login user: %v", err)
+	}
+
+	if _, err := staging.ValidateAccessToken(tokens.AccessToken); err != nil {
+		t.Errorf("Expected token to validate in its own environment, got %v", err)
+	}
+	if _, err := production.ValidateAccessToken(tokens.AccessToken); err == nil {
+		t.Error("Expected staging token to be rejected by production despite the shared secret")
+	}
+}
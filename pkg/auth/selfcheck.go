@@ -0,0 +1,290 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// CheckStatus is the outcome of a single self-check.
+type CheckStatus string
+
+const (
+	CheckPass CheckStatus = "pass"
+	CheckWarn CheckStatus = "warn"
+	CheckFail CheckStatus = "fail"
+)
+
+// Secret entropy thresholds in estimated bits.
+const (
+	minSecretEntropyBits         = 64
+	recommendedSecretEntropyBits = 128
+)
+
+// CheckResult is the outcome of one self-check.
+type CheckResult struct {
+	Name    string      `json:"name"`
+	Status  CheckStatus `json:"status"`
+	Message string      `json:"message"`
+}
+
+// SelfCheckReport collects the results of a configuration and startup self-check.
+type SelfCheckReport struct {
+	CheckedAt time.Time     `json:"checked_at"`
+	Checks    []CheckResult `json:"checks"`
+}
+
+// SelfCheckOptions tunes SelfCheckWithOptions.
+type SelfCheckOptions struct {
+	// TimeReference returns a trusted current time for the clock skew check,
+	// e.g. HTTPTimeReference. Without it the configured Clock is compared
+	// with the system clock.
+	TimeReference func() (time.Time, error)
+	// MaxClockSkew is the tolerated clock difference. Defaults to 30 seconds.
+	MaxClockSkew time.Duration
+}
+
+// Passed reports whether no check failed. Warnings do not fail the report.
+func (r *SelfCheckReport) Passed() bool {
+	for _, check := range r.Checks {
+		if check.Status == CheckFail {
+			return false
+		}
+	}
+	return true
+}
+
+// HasWarnings reports whether any check produced a warning.
+func (r *SelfCheckReport) HasWarnings() bool {
+	for _, check := range r.Checks {
+		if check.Status == CheckWarn {
+			return true
+		}
+	}
+	return false
+}
+
+// add appends a check result.
+func (r *SelfCheckReport) add(name string, status CheckStatus, format string, args ...interface{}) {
+	r.Checks = append(r.Checks, CheckResult{Name: name, Status: status, Message: fmt.Sprintf(format, args...)})
+}
+
+// Print writes the report to stdout.
+func (r *SelfCheckReport) Print() {
+	r.Fprint(os.Stdout)
+}
+
+// Fprint writes a human-readable pass/fail report to w.
+func (r *SelfCheckReport) Fprint(w io.Writer) {
+	fmt.Fprintln(w, "Go-Auth Self-Check Report")
+	fmt.Fprintln(w, strings.Repeat("=", 60))
+
+	icons := map[CheckStatus]string{CheckPass: "✅", CheckWarn: "⚠️ ", CheckFail: "❌"}
+	for _, check := range r.Checks {
+		fmt.Fprintf(w, "%s %-18s %s\n", icons[check.Status], check.Name, check.Message)
+	}
+
+	fmt.Fprintln(w, strings.Repeat("=", 60))
+	if r.Passed() {
+		fmt.Fprintln(w, "Result: PASS")
+	} else {
+		fmt.Fprintln(w, "Result: FAIL")
+	}
+}
+
+// JSON returns the report encoded as indented JSON.
+func (r *SelfCheckReport) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// CheckConfig validates an AuthConfig without connecting to any database:
+// secret entropy and token TTL sanity. Unset values are checked with the
+// defaults NewWithConfig would apply.
+func CheckConfig(config *AuthConfig) *SelfCheckReport {
+	report := &SelfCheckReport{CheckedAt: time.Now()}
+	if config == nil {
+		report.add("config", CheckFail, "configuration is missing")
+		return report
+	}
+
+	checkSecret(report, "jwt_secret", config.JWTSecret)
+	if config.JWTRefreshSecret == "" || config.JWTRefreshSecret == config.JWTSecret+"_refresh" {
+		report.add("jwt_refresh_secret", CheckWarn, "derived from the JWT secret; set an independent refresh secret")
+	} else if config.JWTRefreshSecret == config.JWTSecret {
+		report.add("jwt_refresh_secret", CheckFail, "must differ from the JWT secret")
+	} else {
+		checkSecret(report, "jwt_refresh_secret", config.JWTRefreshSecret)
+	}
+	if config.SigningKeys.ActionSecret != "" {
+		checkSecret(report, "action_secret", config.SigningKeys.ActionSecret)
+	}
+	if config.SigningKeys.DelegationSecret != "" {
+		checkSecret(report, "delegation_secret", config.SigningKeys.DelegationSecret)
+	}
+	if config.SigningKeys.CSRFSecret != "" {
+		checkSecret(report, "csrf_secret", config.SigningKeys.CSRFSecret)
+	}
+	if message := sharedSecret(config); message != "" {
+		report.add("token_class_secrets", CheckFail, "%s", message)
+	}
+
+	accessTTL, refreshTTL := config.AccessTokenTTL, config.RefreshTokenTTL
+	if accessTTL == 0 {
+		accessTTL = 15 * time.Minute
+	}
+	if refreshTTL == 0 {
+		refreshTTL = 7 * 24 * time.Hour
+	}
+	switch {
+	case accessTTL < 0 || refreshTTL < 0:
+		report.add("token_ttl", CheckFail, "token TTLs must be positive")
+	case accessTTL >= refreshTTL:
+		report.add("token_ttl", CheckFail, "access token TTL (%s) must be shorter than refresh token TTL (%s)", accessTTL, refreshTTL)
+	case accessTTL > time.Hour:
+		report.add("token_ttl", CheckWarn, "access token TTL of %s is long; revocation takes effect slowly", accessTTL)
+	case refreshTTL > 90*24*time.Hour:
+		report.add("token_ttl", CheckWarn, "refresh token TTL of %s exceeds 90 days", refreshTTL)
+	default:
+		report.add("token_ttl", CheckPass, "access %s, refresh %s", accessTTL, refreshTTL)
+	}
+
+	return report
+}
+
+// checkSecret adds an entropy check for a signing secret.
+func checkSecret(report *SelfCheckReport, name, secret string) {
+	if secret == "" {
+		report.add(name, CheckFail, "not set")
+		return
+	}
+
+	bits := estimateEntropyBits(secret)
+	switch {
+	case bits < minSecretEntropyBits:
+		report.add(name, CheckFail, "estimated entropy %.0f bits is below %d bits", bits, minSecretEntropyBits)
+	case bits < recommendedSecretEntropyBits:
+		report.add(name, CheckWarn, "estimated entropy %.0f bits is below the recommended %d bits", bits, recommendedSecretEntropyBits)
+	default:
+		report.add(name, CheckPass, "estimated entropy %.0f bits", bits)
+	}
+}
+
+// estimateEntropyBits estimates the entropy of s from its character
+// frequencies. It overestimates for dictionary words but reliably flags
+// short or repetitive secrets.
+func estimateEntropyBits(s string) float64 {
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+
+	var perChar float64
+	for _, n := range counts {
+		p := float64(n) / float64(total)
+		perChar -= p * math.Log2(p)
+	}
+	return perChar * float64(total)
+}
+
+// SelfCheck validates the configuration and the runtime environment:
+// secret entropy, TTL sanity, database connectivity, migration status,
+// clock skew and signing key health.
+func (a *Auth) SelfCheck() *SelfCheckReport {
+	return a.SelfCheckWithOptions(SelfCheckOptions{})
+}
+
+// SelfCheckWithOptions is like SelfCheck with a custom clock reference.
+func (a *Auth) SelfCheckWithOptions(opts SelfCheckOptions) *SelfCheckReport {
+	if opts.MaxClockSkew == 0 {
+		opts.MaxClockSkew = 30 * time.Second
+	}
+
+	report := CheckConfig(a.config)
+
+	if err := a.storage.Ping(); err != nil {
+		report.add("database", CheckFail, "connection failed: %v", err)
+	} else {
+		report.add("database", CheckPass, "connection is healthy")
+
+		if pending, err := a.migrationManager.GetPendingMigrations(); err != nil {
+			report.add("migrations", CheckFail, "failed to read migration status: %v", err)
+		} else if len(pending) > 0 {
+			report.add("migrations", CheckFail, "%d pending migration(s)", len(pending))
+		} else {
+			version, _ := a.migrationManager.GetCurrentVersion()
+			report.add("migrations", CheckPass, "schema is up to date (version %d)", version)
+		}
+	}
+
+	a.checkClockSkew(report, opts)
+	a.checkSigningKeys(report)
+
+	a.logger.Info("Self-check completed", map[string]interface{}{
+		"passed":   report.Passed(),
+		"warnings": report.HasWarnings(),
+	})
+	return report
+}
+
+// checkClockSkew compares the configured clock with the reference time.
+func (a *Auth) checkClockSkew(report *SelfCheckReport, opts SelfCheckOptions) {
+	reference, source := time.Now(), "system clock"
+	if opts.TimeReference != nil {
+		t, err := opts.TimeReference()
+		if err != nil {
+			report.add("clock_skew", CheckWarn, "time reference unavailable: %v", err)
+			return
+		}
+		reference, source = t, "time reference"
+	}
+
+	skew := nowFrom(a.clock).Sub(reference)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > opts.MaxClockSkew {
+		report.add("clock_skew", CheckFail, "clock differs from %s by %s (max %s)", source, skew.Round(time.Second), opts.MaxClockSkew)
+		return
+	}
+	report.add("clock_skew", CheckPass, "within %s of %s", opts.MaxClockSkew, source)
+}
+
+// checkSigningKeys issues and validates a probe token with the configured keys.
+func (a *Auth) checkSigningKeys(report *SelfCheckReport) {
+	token, err := a.jwtManager.GenerateAccessToken("self-check", nil)
+	if err != nil {
+		report.add("signing_keys", CheckFail, "failed to sign probe token: %v", err)
+		return
+	}
+	if _, err := a.jwtManager.ValidateAccessToken(token); err != nil {
+		report.add("signing_keys", CheckFail, "failed to validate probe token: %v", err)
+		return
+	}
+	report.add("signing_keys", CheckPass, "probe token signed and verified")
+}
+
+// HTTPTimeReference returns a SelfCheckOptions.TimeReference reading the
+// Date header of a HEAD request to url.
+func HTTPTimeReference(url string) func() (time.Time, error) {
+	return func() (time.Time, error) {
+		client := &http.Client{Timeout: 5 * time.Second}
+		resp, err := client.Head(url)
+		if err != nil {
+			return time.Time{}, err
+		}
+		resp.Body.Close()
+
+		date := resp.Header.Get("Date")
+		if date == "" {
+			return time.Time{}, fmt.Errorf("no Date header from %s", url)
+		}
+		return http.ParseTime(date)
+	}
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func findCheck(report *SelfCheckReport, name string) CheckResult {
+	for _, check := range report.Checks {
+		if check.Name == name {
+			return check
+		}
+	}
+	return CheckResult{}
+}
+
+func TestCheckConfig(t *testing.T) {
+	strong := "q8Zr2LxP0vN7kT4wYb1mC6sJ9hD3fG5aE0uI2oR8tW7y"
+
+	report := CheckConfig(&AuthConfig{
+		JWTSecret:        strong,
+		JWTRefreshSecret: strings.ToUpper(strong[:20]) + strong[20:] + "Xk",
+		AccessTokenTTL:   15 * time.Minute,
+		RefreshTokenTTL:  24 * time.Hour,
+	})
+	if !report.Passed() {
+		t.Errorf("Expected strong configuration to pass, got %+v", report.Checks)
+	}
+
+	report = CheckConfig(&AuthConfig{JWTSecret: "secret", AccessTokenTTL: 2 * time.Hour, RefreshTokenTTL: time.Hour})
+	if report.Passed() {
+		t.Error("Expected weak configuration to fail")
+	}
+	if check := findCheck(report, "jwt_secret"); check.Status != CheckFail {
+		t.Errorf("Expected weak secret to fail, got %+v", check)
+	}
+	if check := findCheck(report, "jwt_refresh_secret"); check.Status != CheckWarn {
+		t.Errorf("Expected derived refresh secret warning, got %+v", check)
+	}
+	if check := findCheck(report, "token_ttl"); check.Status != CheckFail {
+		t.Errorf("Expected TTL misconfiguration to fail, got %+v", check)
+	}
+
+	var out bytes.Buffer
+	report.Fprint(&out)
+	if !strings.Contains(out.String(), "Result: FAIL") {
+		t.Errorf("Expected FAIL in printed report, got:\n%s", out.String())
+	}
+}
+
+func TestEstimateEntropyBits(t *testing.T) {
+	if bits := estimateEntropyBits("aaaaaaaaaaaaaaaaaaaaaaaa"); bits != 0 {
+		t.Errorf("Expected repeated characters to have no entropy, got %.1f", bits)
+	}
+	if estimateEntropyBits("q8Zr2LxP0vN7kT4wYb1mC6sJ") <= estimateEntropyBits("password") {
+		t.Error("Expected random secret to score higher than a short word")
+	}
+}
+
+func TestSelfCheck(t *testing.T) {
+	ta := NewTestAuth(t)
+
+	report := ta.SelfCheck()
+	for _, name := range []string{"database", "migrations", "signing_keys"} {
+		if check := findCheck(report, name); check.Status != CheckPass {
+			t.Errorf("Expected %s check to pass, got %+v", name, check)
+		}
+	}
+
+	// The frozen test clock is far from real time
+	report = ta.SelfCheckWithOptions(SelfCheckOptions{
+		TimeReference: func() (time.Time, error) { return ta.Clock.Now().Add(time.Hour), nil },
+	})
+	if check := findCheck(report, "clock_skew"); check.Status != CheckFail {
+		t.Errorf("Expected clock skew to fail, got %+v", check)
+	}
+
+	report = ta.SelfCheckWithOptions(SelfCheckOptions{
+		TimeReference: func() (time.Time, error) { return time.Time{}, errors.New("offline") },
+	})
+	if check := findCheck(report, "clock_skew"); check.Status != CheckWarn {
+		t.Errorf("Expected unavailable reference to warn, got %+v", check)
+	}
+}
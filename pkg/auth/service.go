@@ -40,6 +40,11 @@ func NewAuthService(cfg Config) (*AuthService, error) {
 		AccessTokenTTL:  cfg.JWT.AccessTokenTTL,
 		RefreshTokenTTL: cfg.JWT.RefreshTokenTTL,
 		SigningMethod:   cfg.JWT.SigningMethod,
+
+		Audience:          cfg.JWT.Audience,
+		ExpectedIssuers:   cfg.JWT.ExpectedIssuers,
+		ExpectedAudiences: cfg.JWT.ExpectedAudiences,
+		Leeway:            cfg.JWT.Leeway,
 	})
 
 	return &AuthService{
@@ -0,0 +1,516 @@
+package auth
+
+import (
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/pragneshbagary/go-auth/internal/jwtutils"
+)
+
+// sessionClaim is the claim carrying the ID of the session a token belongs to.
+const sessionClaim = "sid"
+
+// Session is a login session shared by the tokens issued on login and
+// every refresh that follows.
+type Session struct {
+	ID             string    `json:"id"`
+	UserID         string    `json:"user_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+	// Name is a user-chosen device name, e.g. "Work laptop".
+	Name string `json:"name,omitempty"`
+	// Labels are free-form key/value pairs, e.g. set from the user agent.
+	Labels map[string]string `json:"labels,omitempty"`
+	// Notes are left by administrators.
+	Notes string `json:"notes,omitempty"`
+	// RiskScore is the login risk score, with RiskConfig.Scorer configured.
+	RiskScore float64 `json:"risk_score,omitempty"`
+	// IP is the client address of the login, see WithClientInfo.
+	IP string `json:"ip,omitempty"`
+}
+
+// SessionUpdate changes the descriptive fields of a session. Nil fields are
+// left unchanged; a non-nil Labels replaces all labels.
+type SessionUpdate struct {
+	Name   *string
+	Labels map[string]string
+	Notes  *string
+}
+
+// Limits of the descriptive session fields.
+const (
+	maxSessionNameLength  = 100
+	maxSessionNotesLength = 2000
+	maxSessionLabels      = 20
+)
+
+// validate checks the lengths of the updated fields.
+func (u SessionUpdate) validate() error {
+	if u.Name != nil && utf8.RuneCountInString(*u.Name) > maxSessionNameLength {
+		return ErrValidationError("name")
+	}
+	if u.Notes != nil && utf8.RuneCountInString(*u.Notes) > maxSessionNotesLength {
+		return ErrValidationError("notes")
+	}
+	if len(u.Labels) > maxSessionLabels {
+		return ErrValidationError("labels")
+	}
+	for key, value := range u.Labels {
+		if key == "" || utf8.RuneCountInString(key) > maxSessionNameLength || utf8.RuneCountInString(value) > maxSessionNameLength {
+			return ErrValidationError("labels")
+		}
+	}
+	return nil
+}
+
+// apply applies the update to session.
+func (u SessionUpdate) apply(session *Session) {
+	if u.Name != nil {
+		session.Name = *u.Name
+	}
+	if u.Notes != nil {
+		session.Notes = *u.Notes
+	}
+	if u.Labels != nil {
+		session.Labels = make(map[string]string, len(u.Labels))
+		for key, value := range u.Labels {
+			session.Labels[key] = value
+		}
+	}
+}
+
+// SessionStore persists login sessions.
+type SessionStore interface {
+	SaveSession(session *Session) error
+	// GetSession returns the session with id or ErrInvalidToken if it does not exist.
+	GetSession(id string) (*Session, error)
+	// ListSessions returns all sessions of userID, most recently active first.
+	ListSessions(userID string) ([]*Session, error)
+	// UpdateSession applies update to the session with id and returns the
+	// result, or ErrInvalidToken if it does not exist.
+	UpdateSession(id string, update SessionUpdate) (*Session, error)
+	// TouchSessions sets the last activity of several sessions at once.
+	// Unknown sessions are skipped.
+	TouchSessions(activity map[string]time.Time) error
+	// DeleteSession removes the session with id, if it exists.
+	DeleteSession(id string) error
+	// PurgeSessions deletes sessions last active before the given time and
+	// returns how many were deleted.
+	PurgeSessions(idleBefore time.Time) (int, error)
+}
+
+// memorySessionStore is an in-memory SessionStore.
+type memorySessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string]*Session
+}
+
+// NewMemorySessionStore creates an in-memory SessionStore. Sessions are lost
+// on restart and not shared between replicas; use a persistent SessionStore
+// in production.
+func NewMemorySessionStore() SessionStore {
+	return &memorySessionStore{sessions: make(map[string]*Session)}
+}
+
+func (s *memorySessionStore) SaveSession(session *Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *session
+	s.sessions[session.ID] = &stored
+	return nil
+}
+
+func (s *memorySessionStore) GetSession(id string) (*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, ErrInvalidToken()
+	}
+	result := *session
+	return &result, nil
+}
+
+func (s *memorySessionStore) ListSessions(userID string) ([]*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var sessions []*Session
+	for _, session := range s.sessions {
+		if session.UserID == userID {
+			result := *session
+			sessions = append(sessions, &result)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastActivityAt.After(sessions[j].LastActivityAt)
+	})
+	return sessions, nil
+}
+
+func (s *memorySessionStore) UpdateSession(id string, update SessionUpdate) (*Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok {
+		return nil, ErrInvalidToken()
+	}
+	update.apply(session)
+	result := *session
+	return &result, nil
+}
+
+func (s *memorySessionStore) TouchSessions(activity map[string]time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, at := range activity {
+		if session, ok := s.sessions[id]; ok && at.After(session.LastActivityAt) {
+			session.LastActivityAt = at
+		}
+	}
+	return nil
+}
+
+func (s *memorySessionStore) DeleteSession(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *memorySessionStore) PurgeSessions(idleBefore time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	purged := 0
+	for id, session := range s.sessions {
+		if session.LastActivityAt.Before(idleBefore) {
+			delete(s.sessions, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// SessionConfig tracks login sessions, see Tokens().ListSessions, and
+// optionally ends them after a period of inactivity, independently of token
+// lifetimes. Tokens carry the session ID in the "sid" claim; validating or
+// refreshing them counts as activity.
+type SessionConfig struct {
+	// IdleTimeout ends sessions without activity for this long, e.g. 30
+	// minutes. Zero disables the idle timeout.
+	IdleTimeout time.Duration
+	// Store persists sessions. Sessions are tracked if it is set or an
+	// IdleTimeout is configured, in which case it defaults to an in-memory store.
+	Store SessionStore
+	// FlushInterval is how often recorded activity is written to the store
+	// in one batch. Replicas see each other's activity only after a flush,
+	// so keep it well below IdleTimeout. Defaults to a minute or half the
+	// IdleTimeout, whichever is shorter.
+	FlushInterval time.Duration
+}
+
+// maxIdle returns how long a session lasts without activity: the idle
+// timeout if configured, else the lifetime of its refresh token.
+func (c SessionConfig) maxIdle(refreshTTL time.Duration) time.Duration {
+	if c.IdleTimeout > 0 {
+		return c.IdleTimeout
+	}
+	return refreshTTL
+}
+
+// sessionTracker records session activity and enforces the idle timeout.
+// A nil *sessionTracker tracks nothing.
+type sessionTracker struct {
+	store         SessionStore
+	idleTimeout   time.Duration // zero if sessions do not idle out
+	maxIdle       time.Duration
+	flushInterval time.Duration
+	clock         Clock
+	logger        *Logger
+
+	mu        sync.Mutex
+	pending   map[string]pendingActivity // by session ID
+	lastFlush time.Time
+}
+
+// pendingActivity is the last activity of a session not yet flushed.
+type pendingActivity struct {
+	userID string
+	at     time.Time
+}
+
+// newSessionTracker returns nil unless a store or an idle timeout is
+// configured. Sessions without an idle timeout end with their refresh token.
+func newSessionTracker(config SessionConfig, refreshTTL time.Duration, clock Clock, logger *Logger) *sessionTracker {
+	if config.IdleTimeout <= 0 && config.Store == nil {
+		return nil
+	}
+	if config.Store == nil {
+		config.Store = NewMemorySessionStore()
+	}
+	maxIdle := config.maxIdle(refreshTTL)
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = min(time.Minute, maxIdle/2)
+	}
+	return &sessionTracker{
+		store:         config.Store,
+		idleTimeout:   max(config.IdleTimeout, 0),
+		maxIdle:       maxIdle,
+		flushInterval: config.FlushInterval,
+		clock:         clock,
+		logger:        logger,
+		pending:       make(map[string]pendingActivity),
+		lastFlush:     nowFrom(clock),
+	}
+}
+
+// start creates a session for userID, recording the client address and
+// risk score of the login, and returns the issue options adding its ID to
+// tokens.
+func (s *sessionTracker) start(userID, ip string, riskScore float64) (jwtutils.IssueOptions, error) {
+	if s == nil {
+		return jwtutils.IssueOptions{}, nil
+	}
+	now := nowFrom(s.clock)
+	session := &Session{
+		ID:             uuid.New().String(),
+		UserID:         userID,
+		CreatedAt:      now,
+		LastActivityAt: now,
+		RiskScore:      riskScore,
+		IP:             ip,
+	}
+	if err := s.store.SaveSession(session); err != nil {
+		return jwtutils.IssueOptions{}, err
+	}
+	return sessionIssueOptions(session.ID), nil
+}
+
+// sessionIssueOptions returns issue options adding sessionID to tokens.
+func sessionIssueOptions(sessionID string) jwtutils.IssueOptions {
+	if sessionID == "" {
+		return jwtutils.IssueOptions{}
+	}
+	return jwtutils.IssueOptions{Claims: map[string]any{sessionClaim: sessionID}}
+}
+
+// sessionID returns the session ID carried by claims, if any.
+func sessionID(claims jwt.MapClaims) string {
+	id, _ := claims[sessionClaim].(string)
+	return id
+}
+
+// touch rejects claims of revoked sessions and of sessions that have been
+// idle too long, and records activity for the others. Tokens without a
+// session ID were issued before tracking was enabled and are accepted.
+func (s *sessionTracker) touch(claims jwt.MapClaims) error {
+	id := sessionID(claims)
+	if s == nil || id == "" {
+		return nil
+	}
+	now := nowFrom(s.clock)
+
+	s.mu.Lock()
+	pending, ok := s.pending[id]
+	s.mu.Unlock()
+	lastActivity := pending.at
+	if !ok {
+		session, err := s.store.GetSession(id)
+		if err != nil {
+			if authErr, ok := err.(*AuthError); ok && authErr.Code == ErrCodeInvalidToken {
+				return ErrSessionExpired()
+			}
+			return WrapDatabaseError(err)
+		}
+		lastActivity = session.LastActivityAt
+	}
+	if s.idleTimeout > 0 && now.Sub(lastActivity) > s.idleTimeout {
+		return ErrSessionExpired()
+	}
+
+	userID, _ := claims["sub"].(string)
+	s.mu.Lock()
+	if now.After(s.pending[id].at) {
+		s.pending[id] = pendingActivity{userID: userID, at: now}
+	}
+	due := now.Sub(s.lastFlush) >= s.flushInterval
+	s.mu.Unlock()
+	if due {
+		s.flush()
+	}
+	return nil
+}
+
+// flush writes the recorded activity to the store. On failure the activity
+// is kept for the next flush.
+func (s *sessionTracker) flush() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	batch := s.pending
+	s.pending = make(map[string]pendingActivity)
+	s.lastFlush = nowFrom(s.clock)
+	s.mu.Unlock()
+	if len(batch) == 0 {
+		return
+	}
+
+	activity := make(map[string]time.Time, len(batch))
+	for id, pending := range batch {
+		activity[id] = pending.at
+	}
+	if err := s.store.TouchSessions(activity); err != nil {
+		s.logger.Error("Failed to record session activity", map[string]interface{}{
+			"sessions": len(batch),
+			"error":    err,
+		})
+		s.mu.Lock()
+		for id, pending := range batch {
+			if pending.at.After(s.pending[id].at) {
+				s.pending[id] = pending
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// forget drops the unflushed activity of the session with id.
+func (s *sessionTracker) forget(id string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, id)
+}
+
+// forgetUser drops the unflushed activity of all sessions of userID.
+func (s *sessionTracker) forgetUser(userID string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, pending := range s.pending {
+		if pending.userID == userID {
+			delete(s.pending, id)
+		}
+	}
+}
+
+// end forgets activity of the session with id and deletes it.
+func (s *sessionTracker) end(id string) error {
+	s.forget(id)
+	return s.store.DeleteSession(id)
+}
+
+// purge deletes sessions that ended by idling before the given time.
+func (s *sessionTracker) purge(endedBefore time.Time) (int, error) {
+	s.flush()
+	return s.store.PurgeSessions(endedBefore.Add(-s.maxIdle))
+}
+
+// withPending returns session with its activity not yet flushed applied.
+func (s *sessionTracker) withPending(session *Session) *Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if pending, ok := s.pending[session.ID]; ok && pending.at.After(session.LastActivityAt) {
+		session.LastActivityAt = pending.at
+	}
+	return session
+}
+
+// errSessionsDisabled is returned by session management without a tracker.
+func errSessionsDisabled() *AuthError {
+	return NewAuthErrorWithDetails(ErrCodeInvalidConfig, "Invalid configuration",
+		"Session tracking is not enabled, see AuthConfig.Sessions")
+}
+
+// errSessionNotFound is returned for unknown session IDs.
+func errSessionNotFound() *AuthError {
+	return NewAuthError(ErrCodeUserNotFound, "Session not found")
+}
+
+// GetSession returns the session with sessionID. Callers acting for a user
+// must check that Session.UserID matches.
+func (t *Tokens) GetSession(sessionID string) (*Session, error) {
+	if t.sessions == nil {
+		return nil, errSessionsDisabled()
+	}
+	if sessionID == "" {
+		return nil, ErrValidationError("session ID")
+	}
+	session, err := t.sessions.store.GetSession(sessionID)
+	if err != nil {
+		if authErr, ok := err.(*AuthError); ok && authErr.Code == ErrCodeInvalidToken {
+			return nil, errSessionNotFound()
+		}
+		return nil, WrapDatabaseError(err)
+	}
+	return t.sessions.withPending(session), nil
+}
+
+// ListSessions returns the sessions of userID, most recently active first.
+// Sessions that ended but were not purged yet are included.
+func (t *Tokens) ListSessions(userID string) ([]*Session, error) {
+	if t.sessions == nil {
+		return nil, errSessionsDisabled()
+	}
+	if userID == "" {
+		return nil, ErrValidationError("user ID")
+	}
+	sessions, err := t.sessions.store.ListSessions(userID)
+	if err != nil {
+		return nil, WrapDatabaseError(err)
+	}
+	for _, session := range sessions {
+		t.sessions.withPending(session)
+	}
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].LastActivityAt.After(sessions[j].LastActivityAt)
+	})
+	return sessions, nil
+}
+
+// UpdateSession changes the name, labels or notes of a session, e.g. to let
+// users rename devices or administrators annotate them.
+func (t *Tokens) UpdateSession(sessionID string, update SessionUpdate) (*Session, error) {
+	if t.sessions == nil {
+		return nil, errSessionsDisabled()
+	}
+	if sessionID == "" {
+		return nil, ErrValidationError("session ID")
+	}
+	if err := update.validate(); err != nil {
+		return nil, err
+	}
+	session, err := t.sessions.store.UpdateSession(sessionID, update)
+	if err != nil {
+		if authErr, ok := err.(*AuthError); ok && authErr.Code == ErrCodeInvalidToken {
+			return nil, errSessionNotFound()
+		}
+		return nil, WrapDatabaseError(err)
+	}
+	return t.sessions.withPending(session), nil
+}
+
+// RevokeSession ends a session. Its access and refresh tokens are rejected
+// with SESSION_EXPIRED from then on.
+func (t *Tokens) RevokeSession(sessionID string) error {
+	if t.sessions == nil {
+		return errSessionsDisabled()
+	}
+	if sessionID == "" {
+		return ErrValidationError("session ID")
+	}
+	if err := t.sessions.end(sessionID); err != nil {
+		return WrapDatabaseError(err)
+	}
+	t.revocations.publish(RevocationSession, "", sessionID)
+	t.watcher.notify(RevocationEvent{Type: RevocationSession, SessionID: sessionID})
+	return nil
+}
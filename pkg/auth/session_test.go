@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func newSessionTestAuth(t *testing.T, idleTimeout time.Duration) (*TestAuth, SessionStore) {
+	ta := NewTestAuth(t)
+	store := NewMemorySessionStore()
+	ta.sessions = newSessionTracker(SessionConfig{
+		IdleTimeout:   idleTimeout,
+		Store:         store,
+		FlushInterval: time.Minute,
+	}, ta.config.RefreshTokenTTL, ta.Clock, ta.logger)
+	return ta, store
+}
+
+func TestSessionIdleTimeout(t *testing.T) {
+	ta, _ := newSessionTestAuth(t, 30*time.Minute)
+	ta.SeedUser("alice", "alice-password")
+	result := ta.LoginAs("alice")
+
+	claims, err := ta.ValidateAccessToken(result.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected a fresh session to be valid: %v", err)
+	}
+	if sessionID(claims) == "" {
+		t.Fatal("Expected the access token to carry a session ID")
+	}
+
+	// Activity keeps the session alive beyond the idle timeout
+	for i := 0; i < 3; i++ {
+		ta.Clock.Advance(20 * time.Minute)
+		if _, err := ta.Tokens().Validate(result.AccessToken); err != nil {
+			t.Fatalf("Expected an active session to stay valid: %v", err)
+		}
+	}
+
+	ta.Clock.Advance(31 * time.Minute)
+	_, err = ta.ValidateAccessToken(result.AccessToken)
+	if authErr, ok := err.(*AuthError); !ok || authErr.Code != ErrCodeSessionExpired {
+		t.Fatalf("Expected SESSION_EXPIRED after inactivity, got %v", err)
+	}
+	_, err = ta.RefreshToken(result.RefreshToken)
+	if authErr, ok := err.(*AuthError); !ok || authErr.Code != ErrCodeSessionExpired {
+		t.Errorf("Expected refresh of an idle session to fail, got %v", err)
+	}
+}
+
+func TestSessionRefreshKeepsSession(t *testing.T) {
+	ta, _ := newSessionTestAuth(t, 30*time.Minute)
+	ta.SeedUser("alice", "alice-password")
+	result := ta.LoginAs("alice")
+	original, _ := ta.ValidateAccessToken(result.AccessToken)
+
+	ta.Clock.Advance(20 * time.Minute)
+	refreshed, err := ta.RefreshToken(result.RefreshToken)
+	if err != nil {
+		t.Fatalf("Failed to refresh: %v", err)
+	}
+	claims, err := ta.ValidateAccessToken(refreshed.AccessToken)
+	if err != nil {
+		t.Fatalf("Expected the refreshed token to be valid: %v", err)
+	}
+	if sessionID(claims) != sessionID(original) {
+		t.Errorf("Expected refresh to keep session %s, got %s", sessionID(original), sessionID(claims))
+	}
+}
+
+func TestSessionActivityIsBatched(t *testing.T) {
+	ta, store := newSessionTestAuth(t, 30*time.Minute)
+	ta.SeedUser("alice", "alice-password")
+	result := ta.LoginAs("alice")
+	claims, _ := ta.ValidateAccessToken(result.AccessToken)
+	id := sessionID(claims)
+	session, _ := store.GetSession(id)
+	loggedInAt := session.LastActivityAt
+
+	ta.Clock.Advance(10 * time.Second)
+	ta.ValidateAccessToken(result.AccessToken)
+	if session, _ := store.GetSession(id); !session.LastActivityAt.Equal(loggedInAt) {
+		t.Errorf("Expected activity to be buffered until the flush interval")
+	}
+
+	ta.Clock.Advance(time.Minute)
+	ta.ValidateAccessToken(result.AccessToken)
+	if session, _ := store.GetSession(id); !session.LastActivityAt.Equal(ta.Clock.Now()) {
+		t.Errorf("Expected activity to be flushed, got %v", session.LastActivityAt)
+	}
+}
+
+func TestSessionTrackingDisabled(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("alice", "alice-password")
+	result := ta.LoginAs("alice")
+
+	claims, err := ta.ValidateAccessToken(result.AccessToken)
+	if err != nil {
+		t.Fatalf("Failed to validate: %v", err)
+	}
+	if _, ok := claims[sessionClaim]; ok {
+		t.Error("Expected no session claim without an idle timeout")
+	}
+}
+
+func TestPurgeIdleSessions(t *testing.T) {
+	ta, store := newSessionTestAuth(t, 30*time.Minute)
+	ta.SeedUser("alice", "alice-password")
+	result := ta.LoginAs("alice")
+	claims, _ := ta.ValidateAccessToken(result.AccessToken)
+
+	ta.Clock.Advance(2 * time.Hour)
+	report, err := ta.Tokens().PurgeSessions(time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to purge sessions: %v", err)
+	}
+	if report.SessionsPurged != 1 {
+		t.Errorf("Expected 1 idle session purged, got %d", report.SessionsPurged)
+	}
+	if _, err := store.GetSession(sessionID(claims)); err == nil {
+		t.Error("Expected the idle session to be deleted")
+	}
+}
+
+func TestUpdateSession(t *testing.T) {
+	ta, _ := newSessionTestAuth(t, 0)
+	user := ta.SeedUser("alice", "alice-password")
+	result := ta.LoginAs("alice")
+	claims, _ := ta.ValidateAccessToken(result.AccessToken)
+	id := sessionID(claims)
+
+	name := "Work laptop"
+	session, err := ta.Tokens().UpdateSession(id, SessionUpdate{
+		Name:   &name,
+		Labels: map[string]string{"os": "linux"},
+	})
+	if err != nil {
+		t.Fatalf("Failed to update session: %v", err)
+	}
+	if session.Name != name || session.Labels["os"] != "linux" {
+		t.Errorf("Unexpected session after update: %+v", session)
+	}
+
+	notes := "Reported lost"
+	if _, err := ta.Tokens().UpdateSession(id, SessionUpdate{Notes: &notes}); err != nil {
+		t.Fatalf("Failed to add notes: %v", err)
+	}
+	session, err = ta.Tokens().GetSession(id)
+	if err != nil {
+		t.Fatalf("Failed to get session: %v", err)
+	}
+	if session.UserID != user.ID || session.Name != name || session.Notes != notes {
+		t.Errorf("Expected name to be kept and notes added, got %+v", session)
+	}
+
+	long := string(make([]byte, maxSessionNameLength+1))
+	if _, err := ta.Tokens().UpdateSession(id, SessionUpdate{Name: &long}); err == nil {
+		t.Error("Expected an overlong name to be rejected")
+	}
+	_, err = ta.Tokens().GetSession("unknown")
+	if authErr, ok := err.(*AuthError); !ok || authErr.Code != ErrCodeUserNotFound {
+		t.Errorf("Expected not found for an unknown session, got %v", err)
+	}
+}
+
+func TestListAndRevokeSessions(t *testing.T) {
+	ta, _ := newSessionTestAuth(t, 0)
+	user := ta.SeedUser("alice", "alice-password")
+	first := ta.LoginAs("alice")
+	ta.Clock.Advance(time.Minute)
+	second := ta.LoginAs("alice")
+
+	sessions, err := ta.Tokens().ListSessions(user.ID)
+	if err != nil {
+		t.Fatalf("Failed to list sessions: %v", err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %d", len(sessions))
+	}
+	claims, _ := ta.ValidateAccessToken(second.AccessToken)
+	if sessions[0].ID != sessionID(claims) {
+		t.Errorf("Expected the most recent session first")
+	}
+	infos, _ := ta.Tokens().ListActiveSessions(user.ID)
+	if len(infos) != 2 {
+		t.Errorf("Expected 2 active sessions, got %d", len(infos))
+	}
+
+	if err := ta.Tokens().RevokeSession(sessions[0].ID); err != nil {
+		t.Fatalf("Failed to revoke session: %v", err)
+	}
+	_, err = ta.ValidateAccessToken(second.AccessToken)
+	if authErr, ok := err.(*AuthError); !ok || authErr.Code != ErrCodeSessionExpired {
+		t.Errorf("Expected tokens of a revoked session to be rejected, got %v", err)
+	}
+	if _, err := ta.ValidateAccessToken(first.AccessToken); err != nil {
+		t.Errorf("Expected other sessions to stay valid: %v", err)
+	}
+}
+
+func TestSessionManagementDisabled(t *testing.T) {
+	ta := NewTestAuth(t)
+	_, err := ta.Tokens().GetSession("any")
+	if authErr, ok := err.(*AuthError); !ok || authErr.Code != ErrCodeInvalidConfig {
+		t.Errorf("Expected INVALID_CONFIG without session tracking, got %v", err)
+	}
+}
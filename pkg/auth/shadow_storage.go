@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// ShadowStorageConfig runs a secondary storage alongside the primary one,
+// e.g. while migrating from SQLite to PostgreSQL. The primary storage stays
+// authoritative: failures and divergences of the secondary are only
+// reported through logs and metrics, never returned to callers.
+type ShadowStorageConfig struct {
+	// Storage is the secondary storage. Shadow mode is off when nil.
+	Storage storage.EnhancedStorage
+	// DualWrite mirrors user mutations (create, update, password change
+	// and delete) to Storage after they succeeded on the primary.
+	DualWrite bool
+	// CompareReads also reads users from Storage and reports fields that
+	// differ from the primary. It adds the latency of the second read.
+	CompareReads bool
+}
+
+// shadowStorage wraps the primary storage and mirrors it to a shadow.
+type shadowStorage struct {
+	storage.EnhancedStorage
+	shadow  storage.EnhancedStorage
+	config  ShadowStorageConfig
+	logger  *Logger
+	metrics *MetricsCollector
+}
+
+// newShadowStorage wraps primary according to config.
+func newShadowStorage(primary storage.EnhancedStorage, config ShadowStorageConfig, logger *Logger, metrics *MetricsCollector) *shadowStorage {
+	return &shadowStorage{
+		EnhancedStorage: primary,
+		shadow:          config.Storage,
+		config:          config,
+		logger:          logger,
+		metrics:         metrics,
+	}
+}
+
+func (s *shadowStorage) CreateUser(user models.User) error {
+	if err := s.EnhancedStorage.CreateUser(user); err != nil {
+		return err
+	}
+	if s.config.DualWrite {
+		s.writeFailed("CreateUser", user.ID, s.shadow.CreateUser(user))
+	}
+	return nil
+}
+
+func (s *shadowStorage) UpdateUser(userID string, updates storage.UserUpdates) error {
+	if err := s.EnhancedStorage.UpdateUser(userID, updates); err != nil {
+		return err
+	}
+	if s.config.DualWrite {
+		s.writeFailed("UpdateUser", userID, s.shadow.UpdateUser(userID, updates))
+	}
+	return nil
+}
+
+func (s *shadowStorage) UpdatePassword(userID string, passwordHash string) error {
+	if err := s.EnhancedStorage.UpdatePassword(userID, passwordHash); err != nil {
+		return err
+	}
+	if s.config.DualWrite {
+		s.writeFailed("UpdatePassword", userID, s.shadow.UpdatePassword(userID, passwordHash))
+	}
+	return nil
+}
+
+func (s *shadowStorage) DeleteUser(userID string) error {
+	if err := s.EnhancedStorage.DeleteUser(userID); err != nil {
+		return err
+	}
+	if s.config.DualWrite {
+		s.writeFailed("DeleteUser", userID, s.shadow.DeleteUser(userID))
+	}
+	return nil
+}
+
+func (s *shadowStorage) GetUserByID(userID string) (*models.User, error) {
+	user, err := s.EnhancedStorage.GetUserByID(userID)
+	if s.config.CompareReads {
+		s.compare("GetUserByID", userID, user, err, s.shadow.GetUserByID)
+	}
+	return user, err
+}
+
+func (s *shadowStorage) GetUserByUsername(username string) (*models.User, error) {
+	user, err := s.EnhancedStorage.GetUserByUsername(username)
+	if s.config.CompareReads {
+		s.compare("GetUserByUsername", username, user, err, s.shadow.GetUserByUsername)
+	}
+	return user, err
+}
+
+func (s *shadowStorage) GetUserByEmail(email string) (*models.User, error) {
+	user, err := s.EnhancedStorage.GetUserByEmail(email)
+	if s.config.CompareReads {
+		s.compare("GetUserByEmail", email, user, err, s.shadow.GetUserByEmail)
+	}
+	return user, err
+}
+
+// writeFailed reports a failed shadow write.
+func (s *shadowStorage) writeFailed(operation, userID string, err error) {
+	if err == nil {
+		return
+	}
+	s.metrics.RecordShadowWriteError()
+	s.logger.Warn("Shadow storage write failed", map[string]interface{}{
+		"operation": operation,
+		"user_id":   userID,
+		"error":     err,
+	})
+}
+
+// compare reads key from the shadow and reports whether it matches the
+// primary's result. Lookup keys are not logged since they may be emails.
+func (s *shadowStorage) compare(operation, key string, primary *models.User, primaryErr error, read func(string) (*models.User, error)) {
+	shadow, shadowErr := read(key)
+
+	fields := map[string]interface{}{"operation": operation}
+	switch {
+	case primaryErr != nil && shadowErr != nil:
+		return
+	case primaryErr != nil:
+		fields["user_id"] = shadow.ID
+		fields["diff"] = []string{"missing_in_primary"}
+	case shadowErr != nil:
+		fields["user_id"] = primary.ID
+		fields["diff"] = []string{"missing_in_shadow"}
+		fields["error"] = shadowErr
+	default:
+		diff := userDiff(primary, shadow)
+		if len(diff) == 0 {
+			return
+		}
+		fields["user_id"] = primary.ID
+		fields["diff"] = diff
+	}
+
+	s.metrics.RecordShadowDivergence()
+	s.logger.Warn("Shadow storage diverged from primary", fields)
+}
+
+// userDiff returns the names of the fields that differ between a and b.
+// Values are left out as they include password hashes.
+func userDiff(a, b *models.User) []string {
+	var diff []string
+	if a.ID != b.ID {
+		diff = append(diff, "id")
+	}
+	if a.Username != b.Username {
+		diff = append(diff, "username")
+	}
+	if a.Email != b.Email {
+		diff = append(diff, "email")
+	}
+	if a.PasswordHash != b.PasswordHash {
+		diff = append(diff, "password_hash")
+	}
+	if a.IsActive != b.IsActive {
+		diff = append(diff, "is_active")
+	}
+	if (len(a.Metadata) > 0 || len(b.Metadata) > 0) && !sameJSON(a.Metadata, b.Metadata) {
+		diff = append(diff, "metadata")
+	}
+	return diff
+}
+
+func (s *shadowStorage) unwrapStorage() storage.EnhancedStorage {
+	return s.EnhancedStorage
+}
@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/pragneshbagary/go-auth/internal/storage/memory"
+)
+
+func TestShadowStorage(t *testing.T) {
+	ta := NewTestAuth(t)
+	secondary := memory.NewInMemoryStorage()
+	ta.storage = newShadowStorage(ta.storage, ShadowStorageConfig{
+		Storage:      secondary,
+		DualWrite:    true,
+		CompareReads: true,
+	}, ta.logger, ta.metricsCollector)
+
+	user := ta.SeedUser("alice", "alice-password")
+	ta.LoginAs("alice")
+
+	mirrored, err := secondary.GetUserByUsername("alice")
+	if err != nil {
+		t.Fatalf("Expected user to be written to the shadow: %v", err)
+	}
+	if mirrored.ID != user.ID {
+		t.Errorf("Expected mirrored user %s, got %s", user.ID, mirrored.ID)
+	}
+	if divergences := ta.metricsCollector.GetMetrics().ShadowDivergences; divergences != 0 {
+		t.Errorf("Expected no divergences, got %d", divergences)
+	}
+
+	// A change that only reached the shadow is reported but not served
+	if err := secondary.UpdatePassword(user.ID, "tampered"); err != nil {
+		t.Fatalf("Failed to update shadow: %v", err)
+	}
+	ta.LoginAs("alice")
+	if divergences := ta.metricsCollector.GetMetrics().ShadowDivergences; divergences == 0 {
+		t.Error("Expected divergent password hash to be reported")
+	}
+
+	if err := ta.Users().Delete(user.ID); err != nil {
+		t.Fatalf("Failed to delete user: %v", err)
+	}
+	if _, err := secondary.GetUserByID(user.ID); err == nil {
+		t.Error("Expected delete to be mirrored to the shadow")
+	}
+}
+
+func TestShadowStorageWriteFailure(t *testing.T) {
+	ta := NewTestAuth(t)
+	secondary := memory.NewInMemoryStorage()
+	ta.storage = newShadowStorage(ta.storage, ShadowStorageConfig{Storage: secondary, DualWrite: true}, ta.logger, ta.metricsCollector)
+
+	// Once the user is missing from the shadow, mirroring the delete fails
+	user := ta.SeedUser("bob", "bob-password")
+	if err := secondary.DeleteUser(user.ID); err != nil {
+		t.Fatalf("Failed to delete shadow user: %v", err)
+	}
+	if err := ta.Users().Delete(user.ID); err != nil {
+		t.Fatalf("Expected primary delete to succeed despite the shadow: %v", err)
+	}
+	if errors := ta.metricsCollector.GetMetrics().ShadowWriteErrors; errors != 1 {
+		t.Errorf("Expected one shadow write error, got %d", errors)
+	}
+}
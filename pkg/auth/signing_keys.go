@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// SigningKeysConfig gives each class of token its own credentials so that a
+// leaked key of one class cannot be used to forge tokens of another. Access
+// and refresh tokens are signed with JWTSecret and JWTRefreshSecret; the
+// secrets below default to keys derived from JWTSecret, which keeps the
+// classes apart but lets a leaked JWTSecret forge all of them.
+type SigningKeysConfig struct {
+	// ActionSecret signs the single-purpose tokens of Tokens.SignAction.
+	ActionSecret string
+
+	// DelegationSecret signs the tokens of Auth.IssueDelegatedToken.
+	DelegationSecret string
+
+	// CSRFSecret keys the tokens of Auth.CSRF.
+	CSRFSecret string
+
+	// RefreshAudience is the audience of refresh tokens, e.g. the URL of the
+	// refresh endpoint, so that they are never accepted where access tokens
+	// are expected. Defaults to JWTAudience.
+	RefreshAudience []string
+
+	// Signer signs access and refresh tokens in place of JWTSecret and
+	// JWTRefreshSecret, e.g. a key in AWS KMS, GCP KMS or a PKCS#11 HSM.
+	// Each signature is delegated to it, so only its public key is held in
+	// memory. It requires the JWT token format.
+	Signer crypto.Signer
+
+	// Algorithm is the JWS algorithm of Signer: RS256, RS384, RS512,
+	// PS256, PS384, PS512, ES256, ES384 or ES512. Defaults to RS256 for RSA
+	// keys and to the ES algorithm of the curve for ECDSA keys.
+	Algorithm string
+}
+
+// signingAlgorithm returns the algorithm of access and refresh tokens:
+// HS256 without a Signer, the algorithm of its key otherwise.
+func signingAlgorithm(config SigningKeysConfig) (string, error) {
+	if config.Signer == nil {
+		if config.Algorithm != "" && config.Algorithm != HS256 {
+			return "", fmt.Errorf("algorithm %s requires a signer", config.Algorithm)
+		}
+		return HS256, nil
+	}
+
+	var curveAlgorithm string
+	switch public := config.Signer.Public().(type) {
+	case *rsa.PublicKey:
+		if public.N.BitLen() < 2048 {
+			return "", errors.New("RSA signing keys must be at least 2048 bits")
+		}
+		if config.Algorithm == "" {
+			return "RS256", nil
+		}
+		if strings.HasPrefix(config.Algorithm, "RS") || strings.HasPrefix(config.Algorithm, "PS") {
+			break
+		}
+		return "", fmt.Errorf("algorithm %s does not match an RSA key", config.Algorithm)
+	case *ecdsa.PublicKey:
+		switch public.Curve {
+		case elliptic.P256():
+			curveAlgorithm = "ES256"
+		case elliptic.P384():
+			curveAlgorithm = "ES384"
+		case elliptic.P521():
+			curveAlgorithm = "ES512"
+		default:
+			return "", fmt.Errorf("unsupported ECDSA curve %s", public.Curve.Params().Name)
+		}
+		if config.Algorithm == "" {
+			return curveAlgorithm, nil
+		}
+		if config.Algorithm != curveAlgorithm {
+			return "", fmt.Errorf("algorithm %s does not match a %s key", config.Algorithm, public.Curve.Params().Name)
+		}
+	default:
+		return "", fmt.Errorf("unsupported signing key type %T", public)
+	}
+
+	for _, supported := range []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"} {
+		if config.Algorithm == supported {
+			return supported, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported signing algorithm %s", config.Algorithm)
+}
+
+// signingKeys are the keys of the token classes signed by Auth itself.
+type signingKeys struct {
+	action     []byte
+	delegation []byte
+	csrf       []byte
+}
+
+// sharedSecret returns a message naming a class secret that is also used by
+// another token class, or "" if there is none. A refresh secret equal to the
+// JWT secret is reported by CheckConfig only, for compatibility.
+func sharedSecret(config *AuthConfig) string {
+	secrets := []struct{ name, secret string }{
+		{"JWTSecret", config.JWTSecret},
+		{"JWTRefreshSecret", config.JWTRefreshSecret},
+		{"SigningKeys.ActionSecret", config.SigningKeys.ActionSecret},
+		{"SigningKeys.DelegationSecret", config.SigningKeys.DelegationSecret},
+		{"SigningKeys.CSRFSecret", config.SigningKeys.CSRFSecret},
+	}
+	for i, a := range secrets {
+		for _, b := range secrets[max(i+1, 2):] {
+			if a.secret != "" && a.secret == b.secret {
+				return a.name + " and " + b.name + " must differ"
+			}
+		}
+	}
+	return ""
+}
+
+// newSigningKeys derives the key of each token class from its secret, or
+// from the JWT secret when none is set. Secrets must not be shared between
+// classes.
+func newSigningKeys(config *AuthConfig) (signingKeys, error) {
+	if message := sharedSecret(config); message != "" {
+		return signingKeys{}, NewAuthError(ErrCodeInvalidConfig, message)
+	}
+	key := func(secret, label string) []byte {
+		if secret == "" {
+			secret = config.JWTSecret
+		}
+		return deriveKey(secret, label)
+	}
+	return signingKeys{
+		action:     key(config.SigningKeys.ActionSecret, "go-auth action tokens"),
+		delegation: key(config.SigningKeys.DelegationSecret, "go-auth delegated tokens"),
+		csrf:       key(config.SigningKeys.CSRFSecret, "go-auth csrf tokens"),
+	}, nil
+}
@@ -0,0 +1,182 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/internal/storage/memory"
+)
+
+func newSigningKeysAuth(t *testing.T, jwtSecret string, keys SigningKeysConfig) *Auth {
+	t.Helper()
+	a, err := newAuthWithStorage(memory.NewInMemoryStorage(), &AuthConfig{
+		JWTSecret:          jwtSecret,
+		LogLevel:           "error",
+		PasswordHashParams: TestHashParams,
+		SigningKeys:        keys,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create auth: %v", err)
+	}
+	t.Cleanup(func() { a.Close() })
+	return a
+}
+
+func TestSigningKeysSeparateTokenClasses(t *testing.T) {
+	keys := SigningKeysConfig{
+		ActionSecret:     "go-auth-test-action-secret",
+		DelegationSecret: "go-auth-test-delegation-secret",
+		CSRFSecret:       "go-auth-test-csrf-secret",
+	}
+	a := newSigningKeysAuth(t, "go-auth-test-secret", keys)
+	// Same class secrets, different JWT secret
+	b := newSigningKeysAuth(t, "go-auth-other-secret", keys)
+	// Same JWT secret, derived class keys
+	c := newSigningKeysAuth(t, "go-auth-test-secret", SigningKeysConfig{})
+
+	action, err := a.Tokens().SignAction("unsubscribe", "user-1", nil, time.Hour)
+	if err != nil {
+		t.Fatalf("Failed to sign action token: %v", err)
+	}
+	if _, err := b.Tokens().VerifyAction(action, "unsubscribe"); err != nil {
+		t.Errorf("Expected the action secret alone to verify action tokens, got %v", err)
+	}
+	if _, err := c.Tokens().VerifyAction(action, "unsubscribe"); err == nil {
+		t.Error("Expected the JWT secret not to verify action tokens signed with ActionSecret")
+	}
+
+	csrf, err := a.CSRF(CSRFConfig{TTL: time.Hour}).Generate("user-1")
+	if err != nil {
+		t.Fatalf("Failed to generate CSRF token: %v", err)
+	}
+	if err := b.CSRF(CSRFConfig{TTL: time.Hour}).Validate(csrf, "user-1"); err != nil {
+		t.Errorf("Expected the CSRF secret alone to validate CSRF tokens, got %v", err)
+	}
+	if err := c.CSRF(CSRFConfig{TTL: time.Hour}).Validate(csrf, "user-1"); err == nil {
+		t.Error("Expected the JWT secret not to validate CSRF tokens keyed with CSRFSecret")
+	}
+
+	if string(a.delegationKey) == string(c.delegationKey) || string(a.delegationKey) != string(b.delegationKey) {
+		t.Error("Expected the delegation key to depend on DelegationSecret only")
+	}
+}
+
+func TestSigningKeysRejectSharedSecrets(t *testing.T) {
+	for _, keys := range []SigningKeysConfig{
+		{ActionSecret: "go-auth-test-secret"},
+		{DelegationSecret: "go-auth-test-refresh-secret"},
+		{ActionSecret: "go-auth-shared-secret", CSRFSecret: "go-auth-shared-secret"},
+	} {
+		_, err := newAuthWithStorage(memory.NewInMemoryStorage(), &AuthConfig{
+			JWTSecret:        "go-auth-test-secret",
+			JWTRefreshSecret: "go-auth-test-refresh-secret",
+			LogLevel:         "error",
+			SigningKeys:      keys,
+		})
+		if !isCode(err, ErrCodeInvalidConfig) {
+			t.Errorf("Expected INVALID_CONFIG for %+v, got %v", keys, err)
+		}
+	}
+
+	report := CheckConfig(&AuthConfig{
+		JWTSecret:   "go-auth-test-secret",
+		SigningKeys: SigningKeysConfig{CSRFSecret: "go-auth-test-secret"},
+	})
+	if report.Passed() {
+		t.Error("Expected CheckConfig to fail for a shared CSRF secret")
+	}
+}
+
+func TestRefreshAudience(t *testing.T) {
+	a, err := newAuthWithStorage(memory.NewInMemoryStorage(), &AuthConfig{
+		JWTSecret:          "go-auth-test-secret",
+		LogLevel:           "error",
+		PasswordHashParams: TestHashParams,
+		JWTAudience:        []string{"api"},
+		SigningKeys:        SigningKeysConfig{RefreshAudience: []string{"https://auth.example.com/refresh"}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create auth: %v", err)
+	}
+	t.Cleanup(func() { a.Close() })
+	if _, err := a.Register(RegisterRequest{Username: "alice", Email: "alice@example.com", Password: "alice-password"}); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	result, err := a.Login("alice", "alice-password", nil)
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+
+	claims, err := a.ValidateRefreshToken(result.RefreshToken)
+	if err != nil {
+		t.Fatalf("Failed to validate refresh token: %v", err)
+	}
+	if audience, _ := claims.GetAudience(); len(audience) != 1 || audience[0] != "https://auth.example.com/refresh" {
+		t.Errorf("Expected the refresh audience, got %v", audience)
+	}
+	claims, err = a.ValidateAccessToken(result.AccessToken)
+	if err != nil {
+		t.Fatalf("Failed to validate access token: %v", err)
+	}
+	if audience, _ := claims.GetAudience(); len(audience) != 1 || audience[0] != "api" {
+		t.Errorf("Expected the access audience, got %v", audience)
+	}
+	if _, err := a.RefreshToken(result.RefreshToken); err != nil {
+		t.Errorf("Failed to refresh: %v", err)
+	}
+}
+
+// kmsSigner hides the private key type, like a signer backed by a KMS.
+type kmsSigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s kmsSigner) Public() crypto.PublicKey { return s.key.Public() }
+
+func (s kmsSigner) Sign(rand io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	return s.key.Sign(rand, digest, opts)
+}
+
+func TestSigningKeysSigner(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	a := newSigningKeysAuth(t, "go-auth-test-secret", SigningKeysConfig{Signer: kmsSigner{key}})
+	if _, err := a.Register(RegisterRequest{Username: "alice", Email: "alice@example.com", Password: "alice-password"}); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	result, err := a.Login("alice", "alice-password", nil)
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	if _, err := a.ValidateAccessToken(result.AccessToken); err != nil {
+		t.Errorf("Failed to validate access token: %v", err)
+	}
+	if _, err := a.RefreshToken(result.RefreshToken); err != nil {
+		t.Errorf("Failed to refresh: %v", err)
+	}
+	if _, err := newSigningKeysAuth(t, "go-auth-test-secret", SigningKeysConfig{}).ValidateAccessToken(result.AccessToken); err == nil {
+		t.Error("Expected the JWT secret not to verify tokens signed by Signer")
+	}
+
+	for _, keys := range []SigningKeysConfig{
+		{Signer: kmsSigner{key}, Algorithm: "ES256"},
+		{Signer: kmsSigner{key}, Algorithm: "RS256"},
+		{Algorithm: "ES384"},
+	} {
+		_, err := newAuthWithStorage(memory.NewInMemoryStorage(), &AuthConfig{
+			JWTSecret:   "go-auth-test-secret",
+			LogLevel:    "error",
+			SigningKeys: keys,
+		})
+		if !isCode(err, ErrCodeInvalidConfig) {
+			t.Errorf("Expected INVALID_CONFIG for algorithm %q, got %v", keys.Algorithm, err)
+		}
+	}
+}
@@ -2,8 +2,10 @@ package auth
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/pragneshbagary/go-auth/pkg/models"
 )
@@ -76,6 +78,61 @@ func QuickPostgres(databaseURL string, jwtSecret string) (*SimpleAuth, error) {
 	return &SimpleAuth{auth: auth}, nil
 }
 
+// QuickFromDSN creates a new SimpleAuth instance, choosing the storage backend from the DSN scheme.
+// Supported forms:
+//   - postgres://... or postgresql://... for PostgreSQL
+//   - sqlite://path/to/auth.db, file:auth.db or a plain file path for SQLite
+//   - memory:// or :memory: for in-memory storage
+func QuickFromDSN(dsn string, jwtSecret string) (*SimpleAuth, error) {
+	if jwtSecret == "" {
+		return nil, fmt.Errorf("JWT secret cannot be empty")
+	}
+
+	dbType, target, err := parseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	switch dbType {
+	case "postgres":
+		return QuickPostgres(target, jwtSecret)
+	case "sqlite":
+		return QuickSQLite(target, jwtSecret)
+	default:
+		return QuickInMemory(jwtSecret)
+	}
+}
+
+// parseDSN determines the database type from a DSN and returns the value
+// to pass to the matching storage constructor.
+func parseDSN(dsn string) (dbType string, target string, err error) {
+	dsn = strings.TrimSpace(dsn)
+	lower := strings.ToLower(dsn)
+
+	switch {
+	case dsn == "":
+		return "", "", fmt.Errorf("database DSN cannot be empty")
+	case strings.HasPrefix(lower, "postgres://"), strings.HasPrefix(lower, "postgresql://"):
+		return "postgres", dsn, nil
+	case lower == "memory://" || lower == "memory" || lower == ":memory:":
+		return "memory", "", nil
+	case strings.HasPrefix(lower, "sqlite://"):
+		target = dsn[len("sqlite://"):]
+	case strings.HasPrefix(lower, "sqlite3://"):
+		target = dsn[len("sqlite3://"):]
+	case strings.Contains(dsn, "://"):
+		return "", "", fmt.Errorf("unsupported database DSN scheme: %s", dsn[:strings.Index(dsn, "://")])
+	default:
+		// file: URIs and plain paths are passed to the SQLite driver as-is
+		target = dsn
+	}
+
+	if target == "" {
+		return "", "", fmt.Errorf("database path cannot be empty")
+	}
+	return "sqlite", target, nil
+}
+
 // QuickInMemory creates a new SimpleAuth instance with in-memory storage.
 // This is perfect for testing, development, or temporary applications.
 func QuickInMemory(jwtSecret string) (*SimpleAuth, error) {
@@ -162,6 +219,12 @@ func (s *SimpleAuth) LoginWithClaims(username, password string, customClaims map
 	return s.auth.Login(username, password, customClaims)
 }
 
+// LoginWithCaptcha authenticates a user after a CAPTCHA_REQUIRED error,
+// verifying the CAPTCHA response before the credentials.
+func (s *SimpleAuth) LoginWithCaptcha(username, password, captchaResponse string) (*LoginResult, error) {
+	return s.auth.LoginWithCaptcha(username, password, captchaResponse)
+}
+
 // ValidateToken validates an access token and returns the user claims.
 // This is the most commonly used validation method.
 func (s *SimpleAuth) ValidateToken(tokenString string) (map[string]interface{}, error) {
@@ -180,50 +243,18 @@ func (s *SimpleAuth) ValidateToken(tokenString string) (map[string]interface{},
 }
 
 // RefreshToken refreshes an access token using a refresh token.
-// This returns a new access token and refresh token pair.
+// This returns a new access token and refresh token pair and retires the
+// old refresh token, like Tokens().Refresh.
 func (s *SimpleAuth) RefreshToken(refreshToken string) (*LoginResult, error) {
-	// Validate the refresh token first
-	claims, err := s.auth.ValidateRefreshToken(refreshToken)
+	result, err := s.auth.Tokens().Refresh(refreshToken)
 	if err != nil {
-		return nil, fmt.Errorf("invalid refresh token: %w", err)
-	}
-
-	// Extract user ID from claims
-	userID, ok := claims["sub"].(string)
-	if !ok {
-		return nil, fmt.Errorf("invalid refresh token: missing user ID")
-	}
-
-	// Get user information
-	user, err := s.auth.storage.GetUserByID(userID)
-	if err != nil {
-		return nil, fmt.Errorf("user not found: %w", err)
-	}
-
-	if !user.IsActive {
-		return nil, fmt.Errorf("user account is inactive")
-	}
-
-	// Generate new tokens
-	userClaims := map[string]interface{}{
-		"username": user.Username,
-		"email":    user.Email,
-		"user_id":  user.ID,
-	}
-
-	accessToken, err := s.auth.jwtManager.GenerateAccessToken(user.ID, userClaims)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate access token: %w", err)
-	}
-
-	newRefreshToken, err := s.auth.jwtManager.GenerateRefreshToken(user.ID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
+		return nil, err
 	}
-
 	return &LoginResult{
-		AccessToken:  accessToken,
-		RefreshToken: newRefreshToken,
+		AccessToken:        result.AccessToken,
+		RefreshToken:       result.RefreshToken,
+		TokenType:          result.TokenType,
+		CompatibilityToken: result.CompatibilityToken,
 	}, nil
 }
 
@@ -245,6 +276,64 @@ func (s *SimpleAuth) GetUserByEmail(email string) (*models.UserProfile, error) {
 	return s.auth.GetUserByEmail(email)
 }
 
+// CheckAvailability reports whether a username and email can still be registered.
+func (s *SimpleAuth) CheckAvailability(username, email string) (*Availability, error) {
+	return s.auth.Users().CheckAvailability(username, email)
+}
+
+// ChangePassword changes a user's password after verifying the old password.
+func (s *SimpleAuth) ChangePassword(userID, oldPassword, newPassword string) error {
+	return s.auth.Users().ChangePassword(userID, oldPassword, newPassword)
+}
+
+// CreateResetToken creates a password reset token for the user with the given email.
+// Deliver the token to the user out of band (e.g. by email).
+func (s *SimpleAuth) CreateResetToken(email string) (*ResetToken, error) {
+	return s.auth.Users().CreateResetToken(email)
+}
+
+// ResetPassword sets a new password using a reset token from CreateResetToken.
+func (s *SimpleAuth) ResetPassword(token, newPassword string) error {
+	return s.auth.Users().ResetPassword(token, newPassword)
+}
+
+// Logout revokes the given token so it can no longer be used.
+// Pass the refresh token to end the session; access tokens can be revoked too.
+func (s *SimpleAuth) Logout(token string) error {
+	return s.auth.Tokens().Revoke(token)
+}
+
+// LogoutAll revokes all tokens issued to the given user so far.
+func (s *SimpleAuth) LogoutAll(userID string) error {
+	return s.auth.Tokens().RevokeAll(userID)
+}
+
+// SetMaintenanceMode enables or disables maintenance mode, rejecting new
+// logins and registrations with the given message while enabled.
+func (s *SimpleAuth) SetMaintenanceMode(enabled bool, message string) {
+	s.auth.SetMaintenanceMode(enabled, message)
+}
+
+// Hooks returns the hook registry for customizing login, registration, and password changes.
+func (s *SimpleAuth) Hooks() *Hooks {
+	return s.auth.Hooks()
+}
+
+// Middleware returns the authentication middleware, including the Gin, Echo and Fiber adapters.
+func (s *SimpleAuth) Middleware() *Middleware {
+	return s.auth.Middleware()
+}
+
+// Protect is an HTTP middleware that requires a valid access token.
+func (s *SimpleAuth) Protect(next http.Handler) http.Handler {
+	return s.auth.Protect(next)
+}
+
+// Optional is an HTTP middleware that injects the user when a valid token is present.
+func (s *SimpleAuth) Optional(next http.Handler) http.Handler {
+	return s.auth.Optional(next)
+}
+
 // Health checks if the authentication service is healthy.
 // This includes checking database connectivity.
 func (s *SimpleAuth) Health() error {
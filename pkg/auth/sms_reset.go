@@ -0,0 +1,316 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"math/big"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// Metadata keys of a user's phone number, in E.164 format such as
+// "+4915112345678", and of whether it was verified. Only verified numbers
+// receive reset codes.
+const (
+	PhoneMetadataKey         = "phone"
+	PhoneVerifiedMetadataKey = "phone_verified"
+)
+
+// Channels a password reset is delivered over, see ResetToken.Channel.
+const (
+	ResetChannelEmail = "email"
+	ResetChannelSMS   = "sms"
+)
+
+// DefaultSMSResetMessage is the text of reset code messages unless
+// SMSResetConfig.Message is set.
+const DefaultSMSResetMessage = "Your {{.AppName}} password reset code is {{.Code}}. It expires in {{.Minutes}} minutes."
+
+// SMSSender delivers text messages, e.g. over an SMS gateway API.
+type SMSSender interface {
+	SendSMS(ctx context.Context, phone, message string) error
+}
+
+// SMSResetConfig configures password resets by text message, see
+// ResetChannelSMS. They are disabled without a Sender. Storages implementing
+// storage.PhoneStorage find users by phone number through an index; others
+// are scanned.
+type SMSResetConfig struct {
+	Sender SMSSender
+	// CodeLength is the number of digits of reset codes. Defaults to 6.
+	CodeLength int
+	// TTL is how long reset codes are valid. Defaults to 10 minutes.
+	TTL time.Duration
+	// MaxAttempts is how many wrong codes invalidate the code of a number.
+	// Defaults to 5.
+	MaxAttempts int
+	// RateLimit is how many codes a number may be sent per RateWindow,
+	// independently of email resets. Defaults to 3 per hour.
+	RateLimit  int
+	RateWindow time.Duration
+	// ClientRateLimit is how many codes one client may request per
+	// RateWindow across all numbers, by the IP address passed with
+	// WithClientRequest. Requests without one share a single limit.
+	// Defaults to 10.
+	ClientRateLimit int
+	// Message is a text/template of the message, executed with SMSData.
+	// Defaults to DefaultSMSResetMessage.
+	Message string
+}
+
+// SMSData is passed to the reset message template.
+type SMSData struct {
+	AppName string
+	Code    string
+	Minutes int
+}
+
+// smsResets sends reset codes by text message. The codes are kept with the
+// reset tokens, by phone number. A nil *smsResets sends none.
+type smsResets struct {
+	config  SMSResetConfig
+	appName string
+	message *template.Template
+	limiter RateLimiter // per number
+	clients RateLimiter // per client IP address
+}
+
+// newSMSResets validates config and fills in defaults. It returns nil
+// without a sender.
+func newSMSResets(config SMSResetConfig, appName string, clock Clock) (*smsResets, error) {
+	if config.Sender == nil {
+		return nil, nil
+	}
+	if config.CodeLength == 0 {
+		config.CodeLength = 6
+	}
+	if config.TTL == 0 {
+		config.TTL = 10 * time.Minute
+	}
+	if config.MaxAttempts == 0 {
+		config.MaxAttempts = 5
+	}
+	if config.RateLimit == 0 {
+		config.RateLimit = 3
+	}
+	if config.RateWindow == 0 {
+		config.RateWindow = time.Hour
+	}
+	if config.ClientRateLimit == 0 {
+		config.ClientRateLimit = 10
+	}
+	if config.Message == "" {
+		config.Message = DefaultSMSResetMessage
+	}
+	if config.CodeLength < 4 || config.CodeLength > 10 {
+		return nil, errors.New("SMS reset codes must have 4 to 10 digits")
+	}
+	if config.TTL < 0 || config.MaxAttempts < 0 || config.RateLimit < 0 || config.RateWindow < 0 || config.ClientRateLimit < 0 {
+		return nil, errors.New("SMS reset limits must not be negative")
+	}
+	message, err := template.New("sms reset").Parse(config.Message)
+	if err != nil {
+		return nil, err
+	}
+	return &smsResets{
+		config:  config,
+		appName: appName,
+		message: message,
+		limiter: NewRateLimiter(config.RateLimit, config.RateWindow, clock),
+		clients: NewRateLimiter(config.ClientRateLimit, config.RateWindow, clock),
+	}, nil
+}
+
+// resetCode returns a random numeric code of length digits.
+func resetCode(length int) (string, error) {
+	var code strings.Builder
+	for i := 0; i < length; i++ {
+		digit, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code.WriteByte(byte('0' + digit.Int64()))
+	}
+	return code.String(), nil
+}
+
+// phoneVerified reports whether phone is the verified number of user.
+func phoneVerified(user *models.User, phone string) bool {
+	number, _ := user.Metadata[PhoneMetadataKey].(string)
+	verified, _ := user.Metadata[PhoneVerifiedMetadataKey].(bool)
+	return verified && number == phone
+}
+
+// phoneResetID returns the stored ID of the reset code of phone.
+func phoneResetID(phone string) string {
+	return resetTokenHash(ResetChannelSMS + ":" + phone)
+}
+
+// userByPhone returns the user whose verified number is phone. Storages
+// without a phone index are scanned.
+func (u *Users) userByPhone(ctx context.Context, phone string) (*models.User, error) {
+	phones, ok := baseStorage(u.storage).(storage.PhoneStorage)
+	if !ok {
+		return u.scanUserByPhone(ctx, phone)
+	}
+	users, err := phones.GetUsersByPhone(phone)
+	if err != nil {
+		return nil, WrapDatabaseError(err)
+	}
+	for _, user := range users {
+		if phoneVerified(user, phone) {
+			return user, nil
+		}
+	}
+	return nil, ErrUserNotFound()
+}
+
+// scanUserByPhone is userByPhone for storages without a phone index.
+func (u *Users) scanUserByPhone(ctx context.Context, phone string) (*models.User, error) {
+	const pageSize = 100
+	for offset := 0; ; offset += pageSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		users, err := u.storage.ListUsers(pageSize, offset)
+		if err != nil {
+			return nil, WrapDatabaseError(err)
+		}
+		for _, user := range users {
+			if phoneVerified(user, phone) {
+				return user, nil
+			}
+		}
+		if len(users) < pageSize {
+			return nil, ErrUserNotFound()
+		}
+	}
+}
+
+// createSMSResetToken texts a reset code to phone, the verified number of
+// a user.
+func (u *Users) createSMSResetToken(ctx context.Context, phone string) (*ResetToken, error) {
+	if u.sms == nil {
+		return nil, ErrConfigError("SMSReset.Sender")
+	}
+	if phone == "" {
+		return nil, ErrValidationError("phone")
+	}
+	if allowed, _ := u.sms.clients.Allow(clientInfoFrom(ctx).ip); !allowed {
+		return nil, ErrRateLimitExceeded()
+	}
+	if allowed, _ := u.sms.limiter.Allow(phone); !allowed {
+		return nil, ErrRateLimitExceeded()
+	}
+
+	user, err := u.userByPhone(ctx, phone)
+	if err != nil {
+		return nil, err
+	}
+	code, err := resetCode(u.sms.config.CodeLength)
+	if err != nil {
+		return nil, WrapError(err, ErrCodeInternalError, "Failed to generate reset code")
+	}
+
+	now := nowFrom(u.clock)
+	resetToken := ResetToken{
+		Token:     code,
+		UserID:    user.ID,
+		Channel:   ResetChannelSMS,
+		CreatedAt: now,
+		ExpiresAt: now.Add(u.sms.config.TTL),
+	}
+	var message strings.Builder
+	data := SMSData{AppName: u.sms.appName, Code: code, Minutes: int(u.sms.config.TTL.Minutes())}
+	if err := u.sms.message.Execute(&message, data); err != nil {
+		return nil, WrapError(err, ErrCodeInternalError, "Failed to render reset message")
+	}
+
+	// The code is stored before it is sent, so that it can be redeemed on
+	// any instance as soon as it arrives
+	if err := resetTokenStorage(u.storage).SaveResetToken(storage.ResetToken{
+		ID:        phoneResetID(phone),
+		CodeHash:  resetTokenHash(code),
+		UserID:    user.ID,
+		Channel:   ResetChannelSMS,
+		CreatedAt: now,
+		ExpiresAt: resetToken.ExpiresAt,
+	}); err != nil {
+		return nil, WrapDatabaseError(err)
+	}
+	if err := u.sms.config.Sender.SendSMS(ctx, phone, message.String()); err != nil {
+		return nil, WrapError(err, ErrCodeInternalError, "Failed to send reset code")
+	}
+	u.metricsCollector.RecordFunnelStep(FunnelPasswordReset, false)
+
+	return &resetToken, nil
+}
+
+// ResetPasswordWithCode resets the password of the user with the verified
+// number phone using a code created over ResetChannelSMS. Too many wrong
+// codes invalidate the code of the number.
+func (u *Users) ResetPasswordWithCode(ctx context.Context, phone, code, newPassword string) error {
+	if u.sms == nil {
+		return ErrConfigError("SMSReset.Sender")
+	}
+	if phone == "" {
+		return ErrValidationError("phone")
+	}
+	if code == "" {
+		return ErrValidationError("reset code")
+	}
+	if newPassword == "" {
+		return ErrValidationError("new password")
+	}
+	if minLength := u.settings.minPasswordLength(); len(newPassword) < minLength {
+		return ErrPasswordTooShort(minLength)
+	}
+
+	userID, err := u.sms.redeem(resetTokenStorage(u.storage), phone, code, nowFrom(u.clock))
+	if err != nil {
+		return err
+	}
+	return u.resetPassword(ctx, userID, newPassword)
+}
+
+// redeem checks code against the code of phone and consumes it on a match.
+func (s *smsResets) redeem(tokens storage.ResetTokenStorage, phone, code string, now time.Time) (string, error) {
+	id := phoneResetID(phone)
+	reset, err := tokens.GetResetToken(id)
+	if err != nil {
+		return "", WrapDatabaseError(err)
+	}
+	if reset == nil {
+		return "", NewAuthError(ErrCodeInvalidResetToken, "Invalid or expired reset code")
+	}
+	if now.After(reset.ExpiresAt) {
+		tokens.DeleteResetToken(id)
+		return "", NewAuthError(ErrCodeResetTokenExpired, "Reset code has expired")
+	}
+	if subtle.ConstantTimeCompare([]byte(resetTokenHash(code)), []byte(reset.CodeHash)) != 1 {
+		attempts, err := tokens.RecordResetTokenAttempt(id)
+		if err != nil {
+			return "", WrapDatabaseError(err)
+		}
+		if attempts >= s.config.MaxAttempts {
+			tokens.DeleteResetToken(id)
+		}
+		return "", NewAuthError(ErrCodeInvalidResetToken, "Invalid or expired reset code")
+	}
+
+	// Concurrent redemptions of one code consume it once
+	deleted, err := tokens.DeleteResetToken(id)
+	if err != nil {
+		return "", WrapDatabaseError(err)
+	}
+	if !deleted {
+		return "", NewAuthError(ErrCodeInvalidResetToken, "Invalid or expired reset code")
+	}
+	return reset.UserID, nil
+}
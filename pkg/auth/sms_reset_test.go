@@ -0,0 +1,181 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// capturedSMS records sent text messages.
+type capturedSMS struct {
+	phones   []string
+	messages []string
+}
+
+func (c *capturedSMS) SendSMS(ctx context.Context, phone, message string) error {
+	c.phones = append(c.phones, phone)
+	c.messages = append(c.messages, message)
+	return nil
+}
+
+func TestPhoneReset(t *testing.T) {
+	ta := NewTestAuth(t)
+	sent := &capturedSMS{}
+	var err error
+	ta.sms, err = newSMSResets(SMSResetConfig{Sender: sent}, "Acme", ta.Clock)
+	if err != nil {
+		t.Fatalf("Failed to configure SMS resets: %v", err)
+	}
+	user := ta.SeedUser("erin", "erin-password")
+	users := ta.Users()
+	if err := users.Update(user.ID, UserUpdate{Metadata: map[string]interface{}{
+		PhoneMetadataKey:         "+15550100",
+		PhoneVerifiedMetadataKey: true,
+	}}); err != nil {
+		t.Fatalf("Failed to set phone: %v", err)
+	}
+	ctx := context.Background()
+
+	reset, err := users.CreateResetTokenContext(ctx, ResetRequest{Channel: ResetChannelSMS, Phone: "+15550100"})
+	if err != nil {
+		t.Fatalf("CreateResetTokenContext failed: %v", err)
+	}
+	if len(reset.Token) != 6 || reset.Channel != ResetChannelSMS || reset.UserID != user.ID {
+		t.Errorf("Expected a 6 digit SMS code for the user, got %+v", reset)
+	}
+	if !reset.ExpiresAt.Equal(reset.CreatedAt.Add(10 * time.Minute)) {
+		t.Errorf("Expected codes to expire after 10 minutes, got %v", reset.ExpiresAt)
+	}
+	if len(sent.messages) != 1 || sent.phones[0] != "+15550100" || !strings.Contains(sent.messages[0], reset.Token) {
+		t.Fatalf("Expected the code to be sent, got %v", sent.messages)
+	}
+	stored, err := resetTokenStorage(ta.storage).GetResetToken(phoneResetID("+15550100"))
+	if err != nil || stored == nil || stored.CodeHash == reset.Token || stored.UserID != user.ID {
+		t.Errorf("Expected the code to be stored hashed, got %+v, %v", stored, err)
+	}
+
+	// Unknown numbers get nothing
+	if _, err := users.CreateResetTokenContext(ctx, ResetRequest{Channel: ResetChannelSMS, Phone: "+15550199"}); !errors.Is(err, ErrUserNotFound()) {
+		t.Errorf("Expected unknown number to be rejected, got %v", err)
+	}
+
+	if err := users.ResetPasswordWithCode(ctx, "+15550100", "x", "new-password"); err == nil {
+		t.Error("Expected a wrong code to be rejected")
+	}
+	if err := users.ResetPasswordWithCode(ctx, "+15550100", reset.Token, "new-password"); err != nil {
+		t.Fatalf("ResetPasswordWithCode failed: %v", err)
+	}
+	if _, err := ta.Login("erin", "new-password", nil); err != nil {
+		t.Errorf("Expected login with the new password: %v", err)
+	}
+	if err := users.ResetPasswordWithCode(ctx, "+15550100", reset.Token, "other-password"); err == nil {
+		t.Error("Expected a used code to be rejected")
+	}
+}
+
+func TestPhoneResetLimits(t *testing.T) {
+	ta := NewTestAuth(t)
+	var err error
+	ta.sms, err = newSMSResets(SMSResetConfig{Sender: &capturedSMS{}, RateLimit: 2, MaxAttempts: 2}, "Acme", ta.Clock)
+	if err != nil {
+		t.Fatalf("Failed to configure SMS resets: %v", err)
+	}
+	user := ta.SeedUser("frank", "frank-password")
+	users := ta.Users()
+	users.Update(user.ID, UserUpdate{Metadata: map[string]interface{}{
+		PhoneMetadataKey:         "+15550101",
+		PhoneVerifiedMetadataKey: true,
+	}})
+	ctx := context.Background()
+
+	reset, err := users.CreateResetTokenContext(ctx, ResetRequest{Channel: ResetChannelSMS, Phone: "+15550101"})
+	if err != nil {
+		t.Fatalf("CreateResetTokenContext failed: %v", err)
+	}
+
+	// Wrong codes use up the attempts of the code
+	for i := 0; i < 2; i++ {
+		users.ResetPasswordWithCode(ctx, "+15550101", "x", "new-password")
+	}
+	if err := users.ResetPasswordWithCode(ctx, "+15550101", reset.Token, "new-password"); err == nil {
+		t.Error("Expected the code to be invalidated after too many attempts")
+	}
+
+	// Sending is limited per number, apart from email resets
+	if _, err := users.CreateResetTokenContext(ctx, ResetRequest{Channel: ResetChannelSMS, Phone: "+15550101"}); err != nil {
+		t.Fatalf("CreateResetTokenContext failed: %v", err)
+	}
+	if _, err := users.CreateResetTokenContext(ctx, ResetRequest{Channel: ResetChannelSMS, Phone: "+15550101"}); !errors.Is(err, ErrRateLimitExceeded()) {
+		t.Errorf("Expected the rate limit, got %v", err)
+	}
+	if _, err := users.CreateResetToken(user.Email); err != nil {
+		t.Errorf("Expected email resets to be unaffected: %v", err)
+	}
+
+	// Expired codes are rejected
+	ta.Clock.Advance(time.Hour)
+	reset, err = users.CreateResetTokenContext(ctx, ResetRequest{Channel: ResetChannelSMS, Phone: "+15550101"})
+	if err != nil {
+		t.Fatalf("CreateResetTokenContext failed after the window: %v", err)
+	}
+	ta.Clock.Advance(11 * time.Minute)
+	if err := users.ResetPasswordWithCode(ctx, "+15550101", reset.Token, "new-password"); err == nil {
+		t.Error("Expected an expired code to be rejected")
+	}
+}
+
+func TestPhoneResetUnverified(t *testing.T) {
+	ta := NewTestAuth(t)
+	var err error
+	ta.sms, err = newSMSResets(SMSResetConfig{Sender: &capturedSMS{}}, "Acme", ta.Clock)
+	if err != nil {
+		t.Fatalf("Failed to configure SMS resets: %v", err)
+	}
+	user := ta.SeedUser("gina", "gina-password")
+	ta.Users().Update(user.ID, UserUpdate{Metadata: map[string]interface{}{PhoneMetadataKey: "+15550102"}})
+
+	if _, err := ta.Users().CreateResetTokenContext(context.Background(), ResetRequest{Channel: ResetChannelSMS, Phone: "+15550102"}); err == nil {
+		t.Error("Expected unverified numbers to be rejected")
+	}
+	if _, err := newSMSResets(SMSResetConfig{Sender: &capturedSMS{}, CodeLength: 3}, "Acme", nil); err == nil {
+		t.Error("Expected too short codes to be rejected")
+	}
+}
+
+func TestPhoneResetClientLimit(t *testing.T) {
+	ta := NewTestAuth(t)
+	var err error
+	ta.sms, err = newSMSResets(SMSResetConfig{Sender: &capturedSMS{}, ClientRateLimit: 1}, "Acme", ta.Clock)
+	if err != nil {
+		t.Fatalf("Failed to configure SMS resets: %v", err)
+	}
+	users := ta.Users()
+	for _, phone := range []string{"+15550103", "+15550104"} {
+		user := ta.SeedUser("user"+phone[len(phone)-1:], "user-password")
+		users.Update(user.ID, UserUpdate{Metadata: map[string]interface{}{
+			PhoneMetadataKey:         phone,
+			PhoneVerifiedMetadataKey: true,
+		}})
+	}
+	request := func(ip, phone string) error {
+		ctx := WithClientInfo(context.Background(), ip, "")
+		_, err := users.CreateResetTokenContext(ctx, ResetRequest{Channel: ResetChannelSMS, Phone: phone})
+		return err
+	}
+
+	// One client cannot text many numbers
+	if err := request("192.0.2.1", "+15550103"); err != nil {
+		t.Fatalf("CreateResetTokenContext failed: %v", err)
+	}
+	if err := request("192.0.2.1", "+15550104"); !errors.Is(err, ErrRateLimitExceeded()) {
+		t.Errorf("Expected the client limit, got %v", err)
+	}
+	if err := request("192.0.2.2", "+15550104"); err != nil {
+		t.Errorf("Expected other clients to be unaffected: %v", err)
+	}
+	if _, err := users.CreateResetTokenContext(context.Background(), ResetRequest{Channel: "fax"}); err == nil {
+		t.Error("Expected an unknown channel to be rejected")
+	}
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+// Subscription statuses. Providers may report others; they are passed
+// through in the subscription_status claim.
+const (
+	SubscriptionActive    = "active"
+	SubscriptionTrialing  = "trialing"
+	SubscriptionPastDue   = "past_due"
+	SubscriptionCancelled = "cancelled"
+)
+
+// subscriptionClaims are the claims set from a Subscription. They are
+// protected from per-login claims while a provider is configured.
+var subscriptionClaims = []string{"plan", "subscription_status", "entitlements", "trial_ends_at"}
+
+// Subscription is the billing state of a user.
+type Subscription struct {
+	Plan         string
+	Status       string
+	Entitlements []string
+	// TrialEndsAt is set for trials and becomes the trial_ends_at claim.
+	TrialEndsAt time.Time
+	// Claims are added to the token unless they conflict with stored claims.
+	Claims map[string]interface{}
+}
+
+// SubscriptionProvider returns the subscription of a user, nil if the user
+// has none. It is called on every login, refresh and Tokens().Issue, so
+// implementations backed by a billing API should cache.
+type SubscriptionProvider interface {
+	Subscription(ctx context.Context, user *models.User) (*Subscription, error)
+}
+
+// SubscriptionProviderFunc adapts a function to a SubscriptionProvider.
+type SubscriptionProviderFunc func(ctx context.Context, user *models.User) (*Subscription, error)
+
+// Subscription calls f.
+func (f SubscriptionProviderFunc) Subscription(ctx context.Context, user *models.User) (*Subscription, error) {
+	return f(ctx, user)
+}
+
+// SubscriptionConfig adds the plan, subscription_status, entitlements and
+// trial_ends_at claims to issued tokens.
+type SubscriptionConfig struct {
+	// Provider looks up subscriptions. Nil disables subscription claims.
+	Provider SubscriptionProvider
+	// BlockedStatuses are refused logins, refreshes and issued tokens with
+	// SUBSCRIPTION_INACTIVE, e.g. {SubscriptionCancelled}.
+	BlockedStatuses []string
+	// FailClosed refuses tokens when the provider fails. By default they
+	// are issued without subscription claims and the error is logged.
+	FailClosed bool
+}
+
+// subscriptionPolicy applies a SubscriptionConfig. A nil policy adds no
+// claims and blocks nobody.
+type subscriptionPolicy struct {
+	config SubscriptionConfig
+	logger *Logger
+}
+
+func newSubscriptionPolicy(config SubscriptionConfig, logger *Logger) *subscriptionPolicy {
+	if config.Provider == nil {
+		return nil
+	}
+	return &subscriptionPolicy{config: config, logger: logger}
+}
+
+// apply adds the subscription claims of user to claims, or returns
+// SUBSCRIPTION_INACTIVE if the subscription status is blocked.
+func (p *subscriptionPolicy) apply(ctx context.Context, user *models.User, claims map[string]interface{}) error {
+	if p == nil {
+		return nil
+	}
+	subscription, err := p.config.Provider.Subscription(ctx, user)
+	if err != nil {
+		p.logger.Warn("Subscription lookup failed", map[string]interface{}{
+			"user_id":     user.ID,
+			"fail_closed": p.config.FailClosed,
+			"error":       err,
+		})
+		if p.config.FailClosed {
+			return WrapError(err, ErrCodeInternalError, "Subscription lookup failed")
+		}
+		return nil
+	}
+	if subscription == nil {
+		return nil
+	}
+	if slices.Contains(p.config.BlockedStatuses, subscription.Status) {
+		return ErrSubscriptionInactive(subscription.Status)
+	}
+
+	if subscription.Plan != "" {
+		claims["plan"] = subscription.Plan
+	}
+	if subscription.Status != "" {
+		claims["subscription_status"] = subscription.Status
+	}
+	if len(subscription.Entitlements) > 0 {
+		claims["entitlements"] = append([]string(nil), subscription.Entitlements...)
+	}
+	if !subscription.TrialEndsAt.IsZero() {
+		claims["trial_ends_at"] = subscription.TrialEndsAt.Unix()
+	}
+	for k, v := range subscription.Claims {
+		if _, exists := claims[k]; !exists {
+			claims[k] = v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+func TestSubscriptionClaims(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("alice", "alice-password")
+	trialEnd := ta.Clock.Now().Add(14 * 24 * time.Hour)
+	subscription := &Subscription{
+		Plan:         "pro",
+		Status:       SubscriptionTrialing,
+		Entitlements: []string{"export", "sso"},
+		TrialEndsAt:  trialEnd,
+		Claims:       map[string]interface{}{"seats": 5, "username": "mallory"},
+	}
+	var lookupErr error
+	ta.subscriptions = newSubscriptionPolicy(SubscriptionConfig{
+		Provider: SubscriptionProviderFunc(func(ctx context.Context, user *models.User) (*Subscription, error) {
+			return subscription, lookupErr
+		}),
+		BlockedStatuses: []string{SubscriptionCancelled},
+	}, ta.logger)
+	ta.claims = newClaimPolicy(ClaimsConfig{Protected: subscriptionClaims}, ta.logger)
+
+	result, err := ta.Login("alice", "alice-password", map[string]interface{}{"plan": "enterprise"})
+	if err != nil {
+		t.Fatalf("Login failed: %v", err)
+	}
+	claims, _ := ta.ValidateAccessToken(result.AccessToken)
+	if claims["plan"] != "pro" || claims["subscription_status"] != SubscriptionTrialing || claims["trial_ends_at"] != float64(trialEnd.Unix()) {
+		t.Errorf("Expected the subscription claims, got %v", claims)
+	}
+	if entitlements, _ := claims["entitlements"].([]interface{}); len(entitlements) != 2 {
+		t.Errorf("Expected 2 entitlements, got %v", claims["entitlements"])
+	}
+	if claims["seats"] != float64(5) || claims["username"] != "alice" {
+		t.Errorf("Expected extra claims not to replace stored ones, got %v", claims)
+	}
+
+	// Refreshes pick up plan changes
+	subscription = &Subscription{Plan: "team", Status: SubscriptionActive}
+	refreshed, err := ta.Tokens().Refresh(result.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+	claims, _ = ta.ValidateAccessToken(refreshed.AccessToken)
+	if claims["plan"] != "team" {
+		t.Errorf("Expected the new plan after refresh, got %v", claims["plan"])
+	}
+
+	// Cancelled subscriptions are blocked
+	subscription = &Subscription{Plan: "team", Status: SubscriptionCancelled}
+	if _, err := ta.Login("alice", "alice-password", nil); !errors.Is(err, ErrSubscriptionInactive("")) {
+		t.Errorf("Expected SUBSCRIPTION_INACTIVE, got %v", err)
+	}
+	if _, err := ta.Tokens().Refresh(refreshed.RefreshToken); !errors.Is(err, ErrSubscriptionInactive("")) {
+		t.Errorf("Expected SUBSCRIPTION_INACTIVE on refresh, got %v", err)
+	}
+
+	// Provider failures fail open unless configured otherwise
+	lookupErr = errors.New("billing unavailable")
+	result, err = ta.Login("alice", "alice-password", nil)
+	if err != nil {
+		t.Fatalf("Expected login without subscription claims, got %v", err)
+	}
+	if claims, _ := ta.ValidateAccessToken(result.AccessToken); claims["plan"] != nil {
+		t.Errorf("Expected no plan claim, got %v", claims["plan"])
+	}
+	ta.subscriptions.config.FailClosed = true
+	if _, err := ta.Login("alice", "alice-password", nil); err == nil {
+		t.Error("Expected a failing provider to block logins when failing closed")
+	}
+}
@@ -0,0 +1,20 @@
+package auth
+
+import "github.com/pragneshbagary/go-auth/pkg/storage"
+
+// isolateTenant enables row-level security on base and returns its storage
+// for tenant, see AuthConfig.Tenant.
+func isolateTenant(base storage.EnhancedStorage, tenant string) (storage.EnhancedStorage, error) {
+	tenantStorage, ok := base.(storage.TenantStorage)
+	if !ok {
+		return nil, NewAuthError(ErrCodeInvalidConfig, "Tenant isolation requires a storage with row-level security, such as PostgreSQL")
+	}
+	if err := tenantStorage.EnableRowLevelSecurity(); err != nil {
+		return nil, WrapDatabaseError(err)
+	}
+	scoped, ok := tenantStorage.ForTenant(tenant).(storage.EnhancedStorage)
+	if !ok {
+		return nil, NewAuthError(ErrCodeInvalidConfig, "Tenant storage does not implement the full storage interface")
+	}
+	return scoped, nil
+}
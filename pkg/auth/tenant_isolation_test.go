@@ -0,0 +1,14 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestTenantIsolationRequiresRowLevelSecurity(t *testing.T) {
+	_, err := NewWithConfig(&AuthConfig{JWTSecret: "secret", Tenant: "acme"})
+	var authErr *AuthError
+	if !errors.As(err, &authErr) || authErr.Code != ErrCodeInvalidConfig {
+		t.Errorf("Expected tenant isolation without PostgreSQL to be rejected, got %v", err)
+	}
+}
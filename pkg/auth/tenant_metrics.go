@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// DefaultTenantLimit is the default number of tenants tracked separately in
+// Metrics.Tenants.
+const DefaultTenantLimit = 100
+
+// OtherTenant labels the metrics of tenants beyond the tenant limit.
+const OtherTenant = "_other"
+
+// tenantKey is the context key of the tenant label.
+type tenantKey struct{}
+
+// WithTenant returns a context labelled with tenant, a customer or
+// application ID. Login, Register and the WithMetrics decorator record
+// their metrics per tenant, see Metrics.Tenants, and events carry the label
+// in a "tenant" field.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// tenantFrom returns the tenant label stored in ctx, if any.
+func tenantFrom(ctx context.Context) string {
+	tenant, _ := ctx.Value(tenantKey{}).(string)
+	return tenant
+}
+
+// TenantMetrics holds the metrics of one tenant
+type TenantMetrics struct {
+	RegistrationAttempts int64 `json:"registration_attempts"`
+	RegistrationFailures int64 `json:"registration_failures"`
+	QuotaRejections      int64 `json:"quota_rejections"`
+	LoginAttempts        int64 `json:"login_attempts"`
+	LoginFailures        int64 `json:"login_failures"`
+	APICalls             int64 `json:"api_calls"`
+	APIErrors            int64 `json:"api_errors"`
+}
+
+// LoginFailureRate returns the fraction of failed logins, zero without logins
+func (t TenantMetrics) LoginFailureRate() float64 {
+	if t.LoginAttempts == 0 {
+		return 0
+	}
+	return float64(t.LoginFailures) / float64(t.LoginAttempts)
+}
+
+// SetTenantLimit bounds the number of tenants tracked separately. Tenants
+// seen after the limit is reached are recorded under OtherTenant, so that
+// arbitrary labels cannot grow the metrics without bound. Zero or less
+// restores DefaultTenantLimit.
+func (mc *MetricsCollector) SetTenantLimit(limit int) {
+	mc.metrics.mu.Lock()
+	defer mc.metrics.mu.Unlock()
+
+	mc.tenantLimit = limit
+}
+
+// RecordTenantLogin records a login attempt of tenant
+func (mc *MetricsCollector) RecordTenantLogin(tenant string, success bool) {
+	mc.recordTenant(tenant, func(t *TenantMetrics) {
+		t.LoginAttempts++
+		if !success {
+			t.LoginFailures++
+		}
+	})
+}
+
+// RecordTenantRegistration records a registration attempt of tenant
+func (mc *MetricsCollector) RecordTenantRegistration(tenant string, success bool) {
+	mc.recordTenant(tenant, func(t *TenantMetrics) {
+		t.RegistrationAttempts++
+		if !success {
+			t.RegistrationFailures++
+		}
+	})
+}
+
+// RecordTenantAPICall records a call of a decorated API operation by tenant
+func (mc *MetricsCollector) RecordTenantAPICall(tenant string, failed bool) {
+	mc.recordTenant(tenant, func(t *TenantMetrics) {
+		t.APICalls++
+		if failed {
+			t.APIErrors++
+		}
+	})
+}
+
+// recordTenant updates the metrics of tenant. Unlabelled calls are only
+// counted in the global metrics.
+func (mc *MetricsCollector) recordTenant(tenant string, update func(t *TenantMetrics)) {
+	if mc == nil || tenant == "" {
+		return
+	}
+	mc.metrics.mu.Lock()
+	defer mc.metrics.mu.Unlock()
+
+	if mc.metrics.Tenants == nil {
+		mc.metrics.Tenants = make(map[string]TenantMetrics)
+	}
+	limit := mc.tenantLimit
+	if limit <= 0 {
+		limit = DefaultTenantLimit
+	}
+	if _, ok := mc.metrics.Tenants[tenant]; !ok && len(mc.metrics.Tenants) >= limit {
+		tenant = OtherTenant
+	}
+	metrics := mc.metrics.Tenants[tenant]
+	update(&metrics)
+	mc.metrics.Tenants[tenant] = metrics
+	mc.metrics.LastActivity = time.Now()
+}
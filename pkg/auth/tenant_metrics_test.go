@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestTenantMetrics_Login(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("alice", TestPassword)
+
+	acme := WithTenant(context.Background(), "acme")
+	globex := WithTenant(context.Background(), "globex")
+	ta.LoginContext(acme, "alice", TestPassword, nil)
+	ta.LoginContext(acme, "alice", "wrong-password", nil)
+	ta.LoginContext(globex, "alice", "wrong-password", nil)
+	ta.LoginContext(context.Background(), "alice", TestPassword, nil)
+
+	metrics := ta.GetMetrics()
+	if metrics.LoginAttempts != 4 {
+		t.Errorf("Expected 4 logins in total, got %d", metrics.LoginAttempts)
+	}
+	if len(metrics.Tenants) != 2 {
+		t.Fatalf("Expected 2 tenants, got %v", metrics.Tenants)
+	}
+	if rate := metrics.Tenants["acme"].LoginFailureRate(); rate != 0.5 {
+		t.Errorf("Expected acme failure rate 0.5, got %v", rate)
+	}
+	if rate := metrics.Tenants["globex"].LoginFailureRate(); rate != 1 {
+		t.Errorf("Expected globex failure rate 1, got %v", rate)
+	}
+}
+
+func TestTenantMetrics_Limit(t *testing.T) {
+	mc := NewMetricsCollector()
+	mc.SetTenantLimit(2)
+
+	for _, tenant := range []string{"a", "b", "c", "d", "a"} {
+		mc.RecordTenantLogin(tenant, false)
+	}
+	mc.RecordTenantRegistration("", true)
+
+	tenants := mc.GetMetrics().Tenants
+	if len(tenants) != 3 {
+		t.Fatalf("Expected 2 tenants and the overflow, got %v", tenants)
+	}
+	if tenants["a"].LoginAttempts != 2 || tenants[OtherTenant].LoginAttempts != 2 {
+		t.Errorf("Unexpected tenant metrics: %v", tenants)
+	}
+}
+
+func TestTenantMetrics_Events(t *testing.T) {
+	sink := &flakySink{}
+	eventLogger := NewAuthEventLogger(NewLogger(LogLevelError, nil))
+	eventLogger.sinks = []*eventDispatcher{newEventDispatcher(EventSinkConfig{
+		Sink:         sink,
+		RetryBackoff: time.Millisecond,
+	}, eventLogger.logger)}
+
+	eventLogger.forTenant("acme").LogLogin("user-1", "alice", "", "", true, time.Millisecond, nil)
+	eventLogger.LogLogin("user-1", "alice", "", "", true, time.Millisecond, nil)
+	eventLogger.close()
+
+	events := sink.delivered()
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %v", events)
+	}
+	if events[0].Data["tenant"] != "acme" {
+		t.Errorf("Expected the tenant label, got %v", events[0].Data)
+	}
+	if _, ok := events[1].Data["tenant"]; ok {
+		t.Errorf("Expected no tenant label, got %v", events[1].Data)
+	}
+}
@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/internal/storage/memory"
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+// TestPassword is the password given to users created by TestAuth.SeedUsers.
+const TestPassword = "test-password-123"
+
+// TestAuth is an Auth instance tuned for fast, deterministic tests.
+// It uses in-memory storage, weak password hashing, and a frozen clock.
+// It mirrors authtest.TestAuth, which the tests of this package cannot
+// import, and exposes the unexported fields of Auth to them.
+type TestAuth struct {
+	*Auth
+
+	// Clock controls the time seen by token issuance and validation.
+	Clock *FrozenClock
+
+	// Mail captures the emails sent through Emails().
+	Mail *capturedEmails
+
+	t         testing.TB
+	passwords map[string]string
+}
+
+// NewTestAuth creates a TestAuth for use in tests. The clock is frozen at the
+// time of the call (truncated to the second) and only moves via Clock.Advance or Clock.Set.
+func NewTestAuth(t testing.TB) *TestAuth {
+	t.Helper()
+
+	clock := NewFrozenClock(time.Now().UTC().Truncate(time.Second))
+	mail := &capturedEmails{}
+	a, err := newAuthWithStorage(memory.NewInMemoryStorage(), &AuthConfig{
+		JWTSecret:          "go-auth-test-secret",
+		JWTRefreshSecret:   "go-auth-test-refresh-secret",
+		JWTIssuer:          "go-auth-test",
+		AppName:            "go-auth-test",
+		LogLevel:           "error",
+		Clock:              clock,
+		PasswordHashParams: TestHashParams,
+		Email:              EmailConfig{BaseURL: "https://go-auth.test", Sender: mail},
+	})
+	if err != nil {
+		t.Fatalf("failed to create test auth: %v", err)
+	}
+
+	return &TestAuth{
+		Auth:      a,
+		Clock:     clock,
+		Mail:      mail,
+		t:         t,
+		passwords: make(map[string]string),
+	}
+}
+
+// SeedUser registers a user with the given password and optional roles.
+// Roles are stored in the user's metadata under "roles" and are added as a
+// "roles" claim by LoginAs. It fails the test on error.
+func (ta *TestAuth) SeedUser(username, password string, roles ...string) *models.User {
+	ta.t.Helper()
+
+	user, err := ta.Register(RegisterRequest{
+		Username: username,
+		Email:    fmt.Sprintf("%s@example.test", username),
+		Password: password,
+	})
+	if err != nil {
+		ta.t.Fatalf("failed to seed user %q: %v", username, err)
+	}
+
+	if len(roles) > 0 {
+		metadata := map[string]interface{}{"roles": roles}
+		if err := ta.Users().Update(user.ID, UserUpdate{Metadata: metadata}); err != nil {
+			ta.t.Fatalf("failed to seed roles for user %q: %v", username, err)
+		}
+		user.Metadata = metadata
+	}
+
+	ta.passwords[username] = password
+	return user
+}
+
+// SeedUsers registers one user per username, all with TestPassword.
+func (ta *TestAuth) SeedUsers(usernames ...string) []*models.User {
+	ta.t.Helper()
+
+	users := make([]*models.User, len(usernames))
+	for i, username := range usernames {
+		users[i] = ta.SeedUser(username, TestPassword)
+	}
+	return users
+}
+
+// LoginAs logs in a seeded user and returns its tokens. Seeded roles are
+// included as a "roles" claim. It fails the test on error.
+func (ta *TestAuth) LoginAs(username string) *LoginResult {
+	ta.t.Helper()
+
+	password, ok := ta.passwords[username]
+	if !ok {
+		ta.t.Fatalf("user %q was not seeded", username)
+	}
+
+	var claims map[string]interface{}
+	if user, err := ta.storage.GetUserByUsername(username); err == nil {
+		if roles, ok := user.Metadata["roles"]; ok {
+			claims = map[string]interface{}{"roles": roles}
+		}
+	}
+
+	result, err := ta.Login(username, password, claims)
+	if err != nil {
+		ta.t.Fatalf("failed to log in as %q: %v", username, err)
+	}
+	return result
+}
+
+// LastResetTokenFor returns the reset token of the last password reset
+// email sent to email. It fails the test if there is none.
+func (ta *TestAuth) LastResetTokenFor(email string) string {
+	ta.t.Helper()
+
+	token, ok := ta.Mail.LastResetTokenFor(email)
+	if !ok {
+		ta.t.Fatalf("no password reset email was sent to %q", email)
+	}
+	return token
+}
+
+// LastLinkFor returns the link of the last email of kind sent to email,
+// e.g. EmailVerification. It fails the test if there is none.
+func (ta *TestAuth) LastLinkFor(email, kind string) string {
+	ta.t.Helper()
+
+	message, ok := ta.Mail.Last(email, kind)
+	if !ok {
+		ta.t.Fatalf("no %s email was sent to %q", kind, email)
+	}
+	return message.Link
+}
+
+// capturedEmails is an EmailSender that records messages instead of
+// sending them, like authtest.CapturedEmails.
+type capturedEmails struct {
+	mu       sync.Mutex
+	messages []EmailMessage
+}
+
+// SendEmail records message.
+func (c *capturedEmails) SendEmail(ctx context.Context, message *EmailMessage) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = append(c.messages, *message)
+	return nil
+}
+
+// Messages returns the recorded messages, oldest first.
+func (c *capturedEmails) Messages() []EmailMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]EmailMessage(nil), c.messages...)
+}
+
+// Last returns the last message of kind sent to email. An empty kind
+// matches any message.
+func (c *capturedEmails) Last(email, kind string) (EmailMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := len(c.messages) - 1; i >= 0; i-- {
+		message := c.messages[i]
+		if message.To == email && (kind == "" || message.Kind == kind) {
+			return message, true
+		}
+	}
+	return EmailMessage{}, false
+}
+
+// LastResetTokenFor returns the reset token of the last password reset
+// email sent to email, for Users().ResetPassword.
+func (c *capturedEmails) LastResetTokenFor(email string) (string, bool) {
+	message, ok := c.Last(email, EmailPasswordReset)
+	if !ok {
+		return "", false
+	}
+	link, err := url.Parse(message.Link)
+	if err != nil {
+		return "", false
+	}
+	token := link.Query().Get(ActionTokenParam)
+	return token, token != ""
+}
+
+// Clear drops the recorded messages.
+func (c *capturedEmails) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.messages = nil
+}
+
+func TestFrozenClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFrozenClock(start)
+
+	if !clock.Now().Equal(start) {
+		t.Fatalf("Expected %v, got %v", start, clock.Now())
+	}
+
+	clock.Advance(time.Hour)
+	if !clock.Now().Equal(start.Add(time.Hour)) {
+		t.Fatalf("Expected clock to advance by one hour, got %v", clock.Now())
+	}
+
+	clock.Set(start)
+	if !clock.Now().Equal(start) {
+		t.Fatalf("Expected clock to be reset to %v, got %v", start, clock.Now())
+	}
+}
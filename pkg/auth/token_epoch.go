@@ -0,0 +1,255 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pragneshbagary/go-auth/internal/jwtutils"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// epochClaim is the claim carrying the token epoch a token was issued in.
+const epochClaim = "epoch"
+
+// EpochStore persists the global token epoch shared by all replicas.
+type EpochStore interface {
+	// Epoch returns the current epoch, 0 if it was never advanced.
+	Epoch() (int64, error)
+	// Advance moves to the next epoch and returns it.
+	Advance() (int64, error)
+}
+
+// TokenEpochConfig configures the global token epoch behind
+// Auth.InvalidateAllTokens.
+type TokenEpochConfig struct {
+	// Store persists the epoch. Defaults to a store kept in the token
+	// blacklist of the Auth storage, which needs no schema changes.
+	Store EpochStore
+	// RefreshInterval is how often a replica reloads the epoch, bounding
+	// how long it accepts tokens invalidated elsewhere. Defaults to 1 second.
+	RefreshInterval time.Duration
+}
+
+// storageEpochStore keeps the epoch as markers in the token blacklist:
+// epoch n has been reached once the marker for n is blacklisted.
+type storageEpochStore struct {
+	storage storage.EnhancedStorage
+
+	mu    sync.Mutex
+	known int64
+}
+
+// NewStorageEpochStore creates an EpochStore backed by the token blacklist of s.
+func NewStorageEpochStore(s storage.EnhancedStorage) EpochStore {
+	return &storageEpochStore{storage: s}
+}
+
+// epochMarker is the blacklist entry marking epoch n.
+func epochMarker(n int64) string {
+	return "go-auth-epoch:" + strconv.FormatInt(n, 10)
+}
+
+func (s *storageEpochStore) Epoch() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// load probes for markers past the last known epoch. The caller must hold s.mu.
+func (s *storageEpochStore) load() (int64, error) {
+	for {
+		reached, err := s.storage.IsTokenBlacklisted(epochMarker(s.known + 1))
+		if err != nil {
+			return s.known, err
+		}
+		if !reached {
+			return s.known, nil
+		}
+		s.known++
+	}
+}
+
+func (s *storageEpochStore) Advance() (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, err := s.load()
+	if err != nil {
+		return 0, err
+	}
+	// Markers must outlive any token, so they never expire in practice
+	next := current + 1
+	if err := s.storage.BlacklistToken(epochMarker(next), time.Now().AddDate(100, 0, 0)); err != nil {
+		return 0, err
+	}
+	s.known = next
+	return next, nil
+}
+
+// epochTokenManager stamps issued tokens with the current epoch and rejects
+// tokens from earlier epochs.
+type epochTokenManager struct {
+	jwtutils.TokenManager
+	store    EpochStore
+	interval time.Duration
+	clock    Clock
+	logger   *Logger
+	metrics  *MetricsCollector
+
+	mu       sync.Mutex
+	epoch    int64
+	loadedAt time.Time
+}
+
+// newEpochTokenManager wraps inner with the global token epoch.
+func newEpochTokenManager(inner jwtutils.TokenManager, config TokenEpochConfig, clock Clock, logger *Logger, metrics *MetricsCollector) *epochTokenManager {
+	if config.RefreshInterval <= 0 {
+		config.RefreshInterval = time.Second
+	}
+	return &epochTokenManager{
+		TokenManager: inner,
+		store:        config.Store,
+		interval:     config.RefreshInterval,
+		clock:        clock,
+		logger:       logger,
+		metrics:      metrics,
+	}
+}
+
+// current returns the epoch, reloading it once per refresh interval. On
+// store errors the last known epoch stays in effect.
+func (m *epochTokenManager) current() int64 {
+	now := nowFrom(m.clock)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.loadedAt.IsZero() && now.Sub(m.loadedAt) < m.interval {
+		return m.epoch
+	}
+	m.loadedAt = now
+	epoch, err := m.store.Epoch()
+	if err != nil {
+		m.logger.Error("Failed to load token epoch", map[string]interface{}{
+			"epoch": m.epoch,
+			"error": err,
+		})
+		return m.epoch
+	}
+	if epoch > m.epoch {
+		m.epoch = epoch
+	}
+	return m.epoch
+}
+
+// advance moves every replica to the next epoch.
+func (m *epochTokenManager) advance() (int64, error) {
+	epoch, err := m.store.Advance()
+	if err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if epoch > m.epoch {
+		m.epoch = epoch
+	}
+	m.loadedAt = nowFrom(m.clock)
+	return m.epoch, nil
+}
+
+// withEpoch returns opts with the epoch claim added.
+func (m *epochTokenManager) withEpoch(opts jwtutils.IssueOptions) jwtutils.IssueOptions {
+	claims := make(map[string]any, len(opts.Claims)+1)
+	for k, v := range opts.Claims {
+		claims[k] = v
+	}
+	claims[epochClaim] = m.current()
+	opts.Claims = claims
+	return opts
+}
+
+// check rejects claims from an earlier epoch. Tokens without the claim
+// predate the epoch and count as epoch 0.
+func (m *epochTokenManager) check(claims jwt.MapClaims) error {
+	var epoch int64
+	if value, ok := claims[epochClaim].(float64); ok {
+		epoch = int64(value)
+	}
+	if current := m.current(); epoch < current {
+		m.metrics.RecordTokenEpochRejection()
+		return fmt.Errorf("token from epoch %d was invalidated by epoch %d", epoch, current)
+	}
+	return nil
+}
+
+func (m *epochTokenManager) GenerateAccessToken(userID string, customClaims map[string]any) (string, error) {
+	return m.GenerateAccessTokenWithOptions(userID, customClaims, jwtutils.IssueOptions{})
+}
+
+func (m *epochTokenManager) GenerateAccessTokenWithOptions(userID string, customClaims map[string]any, opts jwtutils.IssueOptions) (string, error) {
+	return m.TokenManager.GenerateAccessTokenWithOptions(userID, customClaims, m.withEpoch(opts))
+}
+
+func (m *epochTokenManager) GenerateRefreshToken(userID string) (string, error) {
+	return m.GenerateRefreshTokenWithOptions(userID, jwtutils.IssueOptions{})
+}
+
+func (m *epochTokenManager) GenerateRefreshTokenWithOptions(userID string, opts jwtutils.IssueOptions) (string, error) {
+	return m.TokenManager.GenerateRefreshTokenWithOptions(userID, m.withEpoch(opts))
+}
+
+func (m *epochTokenManager) ValidateAccessToken(accessToken string) (jwt.MapClaims, error) {
+	claims, err := m.TokenManager.ValidateAccessToken(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.check(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (m *epochTokenManager) ValidateRefreshToken(refreshToken string) (jwt.MapClaims, error) {
+	claims, err := m.TokenManager.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.check(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// RefreshAccessToken validates the refresh token against the epoch and
+// issues an access token in the current epoch.
+func (m *epochTokenManager) RefreshAccessToken(refreshToken string) (string, error) {
+	claims, err := m.ValidateRefreshToken(refreshToken)
+	if err != nil {
+		return "", fmt.Errorf("could not validate refresh token: %w", err)
+	}
+	if tokenType, _ := claims["token_type"].(string); tokenType != "refresh" {
+		return "", errors.New("token is not a valid refresh token")
+	}
+	userID, ok := claims["sub"].(string)
+	if !ok {
+		return "", errors.New("invalid user ID in refresh token claims")
+	}
+	return m.GenerateAccessToken(userID, nil)
+}
+
+// InvalidateAllTokens is an emergency kill switch: it advances the global
+// token epoch so that every outstanding access and refresh token is
+// rejected. Other replicas follow within TokenEpochConfig.RefreshInterval.
+// Users have to log in again.
+func (a *Auth) InvalidateAllTokens() error {
+	epoch, err := a.epoch.advance()
+	a.eventLogger.LogTokenEpoch(epoch, err == nil, err)
+	if err != nil {
+		a.metricsCollector.RecordDatabaseError()
+		return WrapDatabaseError(err)
+	}
+	a.metricsCollector.RecordTokenEpochAdvance()
+	a.validations.clear()
+	return nil
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInvalidateAllTokens(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("alice", "alice-password")
+	before := ta.LoginAs("alice")
+
+	if err := ta.InvalidateAllTokens(); err != nil {
+		t.Fatalf("Failed to invalidate tokens: %v", err)
+	}
+
+	if _, err := ta.Tokens().Validate(before.AccessToken); err == nil {
+		t.Error("Expected access token from before the invalidation to be rejected")
+	}
+	if _, err := ta.Tokens().Refresh(before.RefreshToken); err == nil {
+		t.Error("Expected refresh token from before the invalidation to be rejected")
+	}
+	if rejections := ta.metricsCollector.GetMetrics().TokenEpochRejections; rejections != 2 {
+		t.Errorf("Expected 2 epoch rejections, got %d", rejections)
+	}
+
+	after := ta.LoginAs("alice")
+	if _, err := ta.Tokens().Validate(after.AccessToken); err != nil {
+		t.Errorf("Expected new access token to be valid: %v", err)
+	}
+	if _, err := ta.Tokens().Refresh(after.RefreshToken); err != nil {
+		t.Errorf("Expected new refresh token to be valid: %v", err)
+	}
+}
+
+func TestInvalidateAllTokensAcrossReplicas(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("bob", "bob-password")
+	result := ta.LoginAs("bob")
+
+	replica, err := newAuthWithStorage(ta.storage, &AuthConfig{
+		JWTSecret:        ta.config.JWTSecret,
+		JWTRefreshSecret: ta.config.JWTRefreshSecret,
+		JWTIssuer:        ta.config.JWTIssuer,
+		LogLevel:         "error",
+		Clock:            ta.Clock,
+		TokenEpoch:       TokenEpochConfig{RefreshInterval: time.Minute},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create replica: %v", err)
+	}
+	if _, err := replica.Tokens().Validate(result.AccessToken); err != nil {
+		t.Fatalf("Expected replica to accept the token: %v", err)
+	}
+
+	if err := ta.InvalidateAllTokens(); err != nil {
+		t.Fatalf("Failed to invalidate tokens: %v", err)
+	}
+	ta.Clock.Advance(2 * time.Minute)
+	if _, err := replica.Tokens().Validate(result.AccessToken); err == nil {
+		t.Error("Expected replica to reject the token once it reloaded the epoch")
+	}
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"github.com/pragneshbagary/go-auth/internal/jwtutils"
+)
+
+// TokenSizeConfig limits the encoded size of access tokens, e.g. to stay
+// below the request header limits of a CDN or proxy.
+type TokenSizeConfig struct {
+	// MaxAccessTokenSize is the maximum length in bytes of an encoded access
+	// token. Zero disables the limit.
+	MaxAccessTokenSize int
+
+	// TrimClaims lists claims that are dropped, in order, from an access
+	// token exceeding MaxAccessTokenSize until it fits. Tokens that still
+	// exceed the limit, or any oversized token without TrimClaims, fail
+	// with TOKEN_TOO_LARGE.
+	TrimClaims []string
+}
+
+// sizeLimitedTokenManager wraps a TokenManager, enforces the size limit of
+// access tokens and records their sizes.
+type sizeLimitedTokenManager struct {
+	jwtutils.TokenManager
+	config  TokenSizeConfig
+	logger  *Logger
+	metrics *MetricsCollector
+}
+
+func newSizeLimitedTokenManager(inner jwtutils.TokenManager, config TokenSizeConfig, logger *Logger, metrics *MetricsCollector) *sizeLimitedTokenManager {
+	return &sizeLimitedTokenManager{TokenManager: inner, config: config, logger: logger, metrics: metrics}
+}
+
+// fits reports whether an encoded token is within the limit.
+func (m *sizeLimitedTokenManager) fits(token string) bool {
+	return m.config.MaxAccessTokenSize <= 0 || len(token) <= m.config.MaxAccessTokenSize
+}
+
+func (m *sizeLimitedTokenManager) GenerateAccessToken(userID string, customClaims map[string]any) (string, error) {
+	return m.GenerateAccessTokenWithOptions(userID, customClaims, jwtutils.IssueOptions{})
+}
+
+func (m *sizeLimitedTokenManager) GenerateAccessTokenWithOptions(userID string, customClaims map[string]any, opts jwtutils.IssueOptions) (string, error) {
+	token, err := m.TokenManager.GenerateAccessTokenWithOptions(userID, customClaims, opts)
+	if err != nil {
+		return "", err
+	}
+	size := len(token)
+
+	var trimmed []string
+	if !m.fits(token) && len(m.config.TrimClaims) > 0 {
+		claims := make(map[string]any, len(customClaims))
+		for k, v := range customClaims {
+			claims[k] = v
+		}
+		for _, name := range m.config.TrimClaims {
+			if _, ok := claims[name]; !ok {
+				continue
+			}
+			delete(claims, name)
+			trimmed = append(trimmed, name)
+			if token, err = m.TokenManager.GenerateAccessTokenWithOptions(userID, claims, opts); err != nil {
+				return "", err
+			}
+			if m.fits(token) {
+				break
+			}
+		}
+	}
+
+	if !m.fits(token) {
+		m.metrics.RecordAccessTokenSize(len(token), false, true)
+		m.logger.Error("Access token exceeds size limit", map[string]interface{}{
+			"user_id": userID,
+			"size":    len(token),
+			"limit":   m.config.MaxAccessTokenSize,
+		})
+		return "", ErrTokenTooLarge(len(token), m.config.MaxAccessTokenSize)
+	}
+	if len(trimmed) > 0 {
+		m.logger.Warn("Trimmed claims from oversized access token", map[string]interface{}{
+			"user_id": userID,
+			"claims":  trimmed,
+			"size":    size,
+			"limit":   m.config.MaxAccessTokenSize,
+		})
+	}
+	m.metrics.RecordAccessTokenSize(len(token), len(trimmed) > 0, false)
+	return token, nil
+}
+
+// RefreshAccessToken issues an access token from a refresh token. It has no
+// custom claims to trim, so an oversized token is rejected.
+func (m *sizeLimitedTokenManager) RefreshAccessToken(refreshToken string) (string, error) {
+	token, err := m.TokenManager.RefreshAccessToken(refreshToken)
+	if err != nil {
+		return "", err
+	}
+	if !m.fits(token) {
+		m.metrics.RecordAccessTokenSize(len(token), false, true)
+		return "", ErrTokenTooLarge(len(token), m.config.MaxAccessTokenSize)
+	}
+	m.metrics.RecordAccessTokenSize(len(token), false, false)
+	return token, nil
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pragneshbagary/go-auth/internal/storage/memory"
+)
+
+func newSizeLimitedAuth(t *testing.T, config TokenSizeConfig) *Auth {
+	t.Helper()
+	a, err := newAuthWithStorage(memory.NewInMemoryStorage(), &AuthConfig{
+		JWTSecret:          "go-auth-test-secret",
+		JWTRefreshSecret:   "go-auth-test-refresh-secret",
+		LogLevel:           "error",
+		PasswordHashParams: TestHashParams,
+		TokenSize:          config,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create auth: %v", err)
+	}
+	t.Cleanup(func() { a.Close() })
+	if _, err := a.Register(RegisterRequest{Username: "alice", Email: "alice@example.com", Password: "alice-password"}); err != nil {
+		t.Fatalf("Failed to register: %v", err)
+	}
+	return a
+}
+
+func TestAccessTokenSizeLimit(t *testing.T) {
+	a := newSizeLimitedAuth(t, TokenSizeConfig{MaxAccessTokenSize: 1024})
+	large := map[string]interface{}{"profile": strings.Repeat("x", 2000)}
+
+	_, err := a.Login("alice", "alice-password", large)
+	if authErr, ok := err.(*AuthError); !ok || authErr.Code != ErrCodeTokenTooLarge {
+		t.Fatalf("Expected TOKEN_TOO_LARGE, got %v", err)
+	}
+	if _, err := a.Login("alice", "alice-password", nil); err != nil {
+		t.Fatalf("Expected a small token to be issued, got %v", err)
+	}
+
+	sizes := a.metricsCollector.GetMetrics().AccessTokenSizes
+	if sizes.Rejected != 1 || sizes.Count != 1 || sizes.MaxBytes > 1024 {
+		t.Errorf("Unexpected token size metrics: %+v", sizes)
+	}
+}
+
+func TestAccessTokenSizeTrimsClaims(t *testing.T) {
+	a := newSizeLimitedAuth(t, TokenSizeConfig{MaxAccessTokenSize: 1024, TrimClaims: []string{"groups", "profile"}})
+
+	result, err := a.Login("alice", "alice-password", map[string]interface{}{
+		"profile": strings.Repeat("x", 2000),
+		"role":    "viewer",
+	})
+	if err != nil {
+		t.Fatalf("Expected the token to be trimmed, got %v", err)
+	}
+	if len(result.AccessToken) > 1024 {
+		t.Errorf("Expected a token of at most 1024 bytes, got %d", len(result.AccessToken))
+	}
+	claims, err := a.ValidateAccessToken(result.AccessToken)
+	if err != nil {
+		t.Fatalf("Failed to validate access token: %v", err)
+	}
+	if _, ok := claims["profile"]; ok || claims["role"] != "viewer" {
+		t.Errorf("Expected only the profile claim to be trimmed, got %v", claims)
+	}
+
+	sizes := a.metricsCollector.GetMetrics().AccessTokenSizes
+	if sizes.Count != 1 || sizes.Trimmed != 1 || sizes.Rejected != 0 {
+		t.Errorf("Unexpected token size metrics: %+v", sizes)
+	}
+	if sizes.Buckets[len(TokenSizeBuckets)] != 0 || sizes.AverageBytes() != len(result.AccessToken) {
+		t.Errorf("Expected the trimmed size to be recorded, got %+v", sizes)
+	}
+}
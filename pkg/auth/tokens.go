@@ -1,7 +1,9 @@
 package auth
 
 import (
+	"context"
 	"fmt"
+	"maps"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -16,12 +18,32 @@ type Tokens struct {
 	storage          storage.EnhancedStorage
 	eventLogger      *AuthEventLogger
 	metricsCollector *MetricsCollector
+	maintenance      *maintenanceSwitch
+	clock            Clock
+	actionKey        []byte
+	grants           GrantStore
+	assertions       *assertionGrants
+	delegationKey    []byte
+	epoch            *epochTokenManager
+	refreshLimiter   *refreshLimiter
+	trustedIssuers   *trustedIssuers
+	sessions         *sessionTracker
+	revocations      *revocationBroadcaster
+	watcher          *RevocationWatcher
+	claims           *claimPolicy
+	subscriptions    *subscriptionPolicy
+	pending          *pendingTokens
+	dual             *compatibilityIssuer
 }
 
 // RefreshResult represents the result of a token refresh operation.
 type RefreshResult struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type,omitempty"`
+	// CompatibilityToken is the access token in the legacy format, set
+	// with AuthConfig.DualIssuance.
+	CompatibilityToken string `json:"compatibility_token,omitempty"`
 }
 
 // SessionInfo represents information about an active session.
@@ -44,9 +66,16 @@ type ValidationResult struct {
 // Refresh validates a refresh token and generates new access and refresh tokens.
 // This implements automatic token rotation for enhanced security.
 func (t *Tokens) Refresh(refreshToken string) (*RefreshResult, error) {
+	return t.refresh(refreshToken, nil)
+}
+
+// refresh implements Refresh, adding extraClaims to the new access token.
+// They carry the binding of RefreshDPoP and RefreshMTLS, not caller claims,
+// so the claim policy does not apply to them.
+func (t *Tokens) refresh(refreshToken string, extraClaims map[string]interface{}) (*RefreshResult, error) {
 	start := time.Now()
 	var userID string
-	var success bool
+	var success, reused bool
 	var err error
 
 	defer func() {
@@ -55,10 +84,18 @@ func (t *Tokens) Refresh(refreshToken string) (*RefreshResult, error) {
 			t.eventLogger.LogTokenRefresh(userID, "", "", success, duration, err)
 		}
 		if t.metricsCollector != nil {
-			t.metricsCollector.RecordTokenRefresh(success, duration)
+			if reused {
+				t.metricsCollector.RecordRefreshChurn()
+			} else {
+				t.metricsCollector.RecordTokenRefresh(success, duration)
+			}
 		}
 	}()
 
+	if err = t.maintenance.check(maintenanceRefresh); err != nil {
+		return nil, err
+	}
+
 	// First, validate the refresh token
 	claims, validateErr := t.jwtManager.ValidateRefreshToken(refreshToken)
 	if validateErr != nil {
@@ -66,22 +103,44 @@ func (t *Tokens) Refresh(refreshToken string) (*RefreshResult, error) {
 		return nil, err
 	}
 
-	// Extract token ID and check if it's blacklisted
-	tokenID, ok := claims["jti"].(string)
+	// Refresh tokens must carry a token ID
+	_, ok := claims["jti"].(string)
 	if !ok {
 		err = NewAuthErrorWithDetails(ErrCodeInvalidToken, 
 			"Refresh token missing token ID", "Token must contain a valid 'jti' claim")
 		return nil, err
 	}
 
-	// Check if the token is blacklisted
-	blacklisted, blacklistErr := t.storage.IsTokenBlacklisted(tokenID)
-	if blacklistErr != nil {
-		err = WrapDatabaseError(blacklistErr)
+	// Certificate-bound refresh tokens are only refreshed by their holder
+	if err = checkRefreshBinding(claims, extraClaims); err != nil {
 		return nil, err
 	}
-	if blacklisted {
-		err = ErrTokenRevoked()
+
+	// Clients refreshing too soon get the pair of the previous rotation of
+	// their session, once the session and the user pass the checks below.
+	// Sender-constrained refreshes always rotate.
+	var recent *RefreshResult
+	if extraClaims == nil {
+		recent = t.recentRefresh(refreshToken)
+	}
+
+	// Check if the token was revoked or already rotated. The token rotated
+	// into a recent pair may still ask for that pair.
+	if recent == nil {
+		revoked, revokedErr := t.refreshRevoked(claims)
+		if revokedErr != nil {
+			err = WrapDatabaseError(revokedErr)
+			return nil, err
+		}
+		if revoked {
+			err = ErrTokenRevoked()
+			return nil, err
+		}
+	}
+
+	// Refreshing counts as session activity, unless the session idled out
+	if sessionErr := t.sessions.touch(claims); sessionErr != nil {
+		err = sessionErr
 		return nil, err
 	}
 
@@ -103,42 +162,73 @@ func (t *Tokens) Refresh(refreshToken string) (*RefreshResult, error) {
 		err = ErrUserInactive()
 		return nil, err
 	}
+	if err = checkAccountExpiry(user, t.clock); err != nil {
+		return nil, err
+	}
+	if err = checkLoggedOut(user.Metadata, claims); err != nil {
+		return nil, err
+	}
+
+	if recent != nil {
+		success, reused = true, true
+		return recent, nil
+	}
+	if extraClaims == nil && t.refreshLimiter.userLimited(userID) {
+		err = ErrRateLimitExceeded()
+		return nil, err
+	}
 
 	// Generate new access token with user claims
-	userClaims := map[string]interface{}{
-		"username": user.Username,
-		"email":    user.Email,
-		"user_id":  user.ID,
+	userClaims := t.claims.stored(user)
+	if err = t.subscriptions.apply(context.Background(), user, userClaims); err != nil {
+		return nil, err
 	}
+	maps.Copy(userClaims, extraClaims)
 
-	newAccessToken, accessErr := t.jwtManager.GenerateAccessToken(userID, userClaims)
+	// The new pair stays in the session of the old one
+	sessionOptions := sessionIssueOptions(sessionID(claims))
+	newAccessToken, accessErr := t.jwtManager.GenerateAccessTokenWithOptions(userID, userClaims, sessionOptions)
 	if accessErr != nil {
 		err = WrapError(accessErr, ErrCodeInternalError, "Failed to generate new access token")
 		return nil, err
 	}
 
-	// Generate new refresh token (token rotation)
-	newRefreshToken, refreshErr := t.jwtManager.GenerateRefreshToken(userID)
+	// Generate new refresh token (token rotation), the next generation of
+	// the family of the old one
+	refreshOptions := sessionOptions
+	if family, ok := familyOf(claims); ok {
+		refreshOptions = family.next(sessionOptions)
+	}
+	refreshOptions = bindRefresh(refreshOptions, userClaims["cnf"])
+	newRefreshToken, refreshErr := t.jwtManager.GenerateRefreshTokenWithOptions(userID, refreshOptions)
 	if refreshErr != nil {
 		err = WrapError(refreshErr, ErrCodeInternalError, "Failed to generate new refresh token")
 		return nil, err
 	}
 
-	// Blacklist the old refresh token to prevent reuse
-	if exp, ok := claims["exp"].(float64); ok {
-		expiresAt := time.Unix(int64(exp), 0)
-		if blacklistErr := t.storage.BlacklistToken(tokenID, expiresAt); blacklistErr != nil {
-			// Log the error but don't fail the refresh operation
-			// The new tokens are still valid
-			fmt.Printf("Warning: failed to blacklist old refresh token: %v\n", blacklistErr)
-		}
+	compatibilityToken, compatibilityErr := t.dual.issue(userID, userClaims, sessionOptions)
+	if compatibilityErr != nil {
+		err = WrapError(compatibilityErr, ErrCodeInternalError, "Failed to generate compatibility token")
+		return nil, err
+	}
+
+	// Retire the old refresh token to prevent reuse
+	if retireErr := t.retireRefreshToken(claims); retireErr != nil {
+		// Log the error but don't fail the refresh operation
+		// The new tokens are still valid
+		fmt.Printf("Warning: failed to blacklist old refresh token: %v\n", retireErr)
 	}
 
 	success = true
-	return &RefreshResult{
-		AccessToken:  newAccessToken,
-		RefreshToken: newRefreshToken,
-	}, nil
+	result := &RefreshResult{
+		AccessToken:        newAccessToken,
+		RefreshToken:       newRefreshToken,
+		CompatibilityToken: compatibilityToken,
+	}
+	if extraClaims == nil {
+		t.recordRefresh(refreshToken, userID, result)
+	}
+	return result, nil
 }
 
 // Revoke blacklists a specific token, preventing its future use.
@@ -173,33 +263,79 @@ func (t *Tokens) Revoke(tokenString string) error {
 	if err := t.storage.BlacklistToken(tokenID, expiresAt); err != nil {
 		return WrapDatabaseError(err)
 	}
+	t.revocations.publishToken(tokenID)
 
 	return nil
 }
 
-// RevokeAll blacklists all tokens for a specific user.
+// RevokeAll logs a user out everywhere.
 // This is useful for scenarios like password changes or account compromise.
+// The access and refresh tokens issued to the user so far are rejected from
+// then on. With session tracking (AuthConfig.Sessions) all sessions of the
+// user are ended too; with a revocation bus (AuthConfig.Revocation) other
+// instances drop what they cached about the user immediately.
 func (t *Tokens) RevokeAll(userID string) error {
-	// Note: This is a simplified implementation. In a production system,
-	// you might want to track active tokens per user in the database.
-	// For now, we'll implement this by updating the user's UpdatedAt timestamp
-	// and checking it during token validation.
-	
-	// Update user's UpdatedAt timestamp to invalidate all existing tokens
-	updates := storage.UserUpdates{}
-	if err := t.storage.UpdateUser(userID, updates); err != nil {
+	user, err := t.storage.GetUserByID(userID)
+	if err != nil {
+		return ErrUserNotFound()
+	}
+	metadata := withoutMetadataKey(user.Metadata, loggedOutMetadataKey)
+	metadata[loggedOutMetadataKey] = nowFrom(t.clock).UTC().Format(time.RFC3339)
+	if err := t.storage.UpdateUser(userID, storage.UserUpdates{Metadata: metadata}); err != nil {
 		return WrapDatabaseError(err)
 	}
 
+	if t.sessions != nil {
+		sessions, err := t.sessions.store.ListSessions(userID)
+		if err != nil {
+			return WrapDatabaseError(err)
+		}
+		t.sessions.forgetUser(userID)
+		for _, session := range sessions {
+			if err := t.sessions.end(session.ID); err != nil {
+				return WrapDatabaseError(err)
+			}
+		}
+	}
+	t.revocations.publish(RevocationLogoutAll, userID, "")
+	t.watcher.notify(RevocationEvent{Type: RevocationLogoutAll, UserID: userID})
+
+	return nil
+}
+
+// loggedOutMetadataKey is the user metadata key holding when RevokeAll last
+// logged the user out.
+const loggedOutMetadataKey = "logged_out_at"
+
+// checkLoggedOut rejects token claims issued before the user with metadata
+// was last logged out by RevokeAll. Token times have a resolution of one
+// second, so tokens issued in the second of the logout are rejected too.
+func checkLoggedOut(metadata map[string]interface{}, claims jwt.MapClaims) error {
+	value, _ := metadata[loggedOutMetadataKey].(string)
+	loggedOutAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil
+	}
+	if iat, _ := claims["iat"].(float64); int64(iat) <= loggedOutAt.Unix() {
+		return ErrTokenRevoked()
+	}
 	return nil
 }
 
 // Validate checks if a token is valid and returns the associated user.
 // This method checks both token validity and blacklist status.
 func (t *Tokens) Validate(tokenString string) (*models.User, error) {
+	if err := t.maintenance.check(maintenanceValidate); err != nil {
+		return nil, err
+	}
+
 	// Try to parse as access token first
 	claims, err := t.jwtManager.ValidateAccessToken(tokenString)
 	if err != nil {
+		// Fall back to the issuers trusted during a migration
+		if user, trustedErr := t.validateTrusted(tokenString); user != nil || trustedErr != nil {
+			return user, trustedErr
+		}
 		return nil, ErrInvalidToken()
 	}
 
@@ -218,6 +354,10 @@ func (t *Tokens) Validate(tokenString string) (*models.User, error) {
 		return nil, ErrTokenRevoked()
 	}
 
+	if err := t.sessions.touch(claims); err != nil {
+		return nil, err
+	}
+
 	// Extract user ID and fetch user
 	userID, ok := claims["sub"].(string)
 	if !ok {
@@ -233,6 +373,12 @@ func (t *Tokens) Validate(tokenString string) (*models.User, error) {
 	if !user.IsActive {
 		return nil, ErrUserInactive()
 	}
+	if err := checkAccountExpiry(user, t.clock); err != nil {
+		return nil, err
+	}
+	if err := checkLoggedOut(user.Metadata, claims); err != nil {
+		return nil, err
+	}
 
 	return user, nil
 }
@@ -312,11 +458,31 @@ func (t *Tokens) CleanupExpired() error {
 }
 
 // ListActiveSessions returns information about active sessions for a user.
-// Note: This is a simplified implementation. In a production system,
-// you might want to track active sessions more explicitly.
+// Sessions are only tracked if AuthConfig.Sessions enables them; otherwise
+// the list is empty. See ListSessions for the full session records.
 func (t *Tokens) ListActiveSessions(userID string) ([]*SessionInfo, error) {
-	// This is a placeholder implementation since we don't currently track
-	// active sessions in the database. In a full implementation, you would
-	// store session information and query it here.
-	return []*SessionInfo{}, nil
+	if t.sessions == nil {
+		return []*SessionInfo{}, nil
+	}
+	sessions, err := t.ListSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := nowFrom(t.clock)
+	infos := []*SessionInfo{}
+	for _, session := range sessions {
+		expiresAt := session.LastActivityAt.Add(t.sessions.maxIdle)
+		if !now.Before(expiresAt) {
+			continue
+		}
+		infos = append(infos, &SessionInfo{
+			TokenID:   session.ID,
+			UserID:    session.UserID,
+			IssuedAt:  session.CreatedAt,
+			ExpiresAt: expiresAt,
+			TokenType: "session",
+		})
+	}
+	return infos, nil
 }
\ No newline at end of file
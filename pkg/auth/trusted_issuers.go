@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+// TrustedIssuer is an additional issuer whose access tokens Tokens.Validate
+// accepts, e.g. a legacy auth service during a migration.
+type TrustedIssuer struct {
+	// Issuer is the "iss" claim of the issuer's tokens.
+	Issuer string
+	// Key verifies the tokens: a []byte HMAC secret or an *rsa.PublicKey,
+	// *ecdsa.PublicKey or ed25519.PublicKey.
+	Key interface{}
+	// Algorithms are the accepted "alg" values. Defaults to HS256, RS256,
+	// ES256 or EdDSA depending on the key type.
+	Algorithms []string
+	// Audience restricts the accepted "aud" values; no check when empty.
+	Audience []string
+	// Until ends the migration window; tokens are rejected after it.
+	// Zero means no end.
+	Until time.Time
+	// Claims maps the issuer's claims into a models.User.
+	Claims IssuerClaimMapping
+}
+
+// IssuerClaimMapping names the claims of a trusted issuer's tokens that hold
+// the user's fields. A name starting with "/" is a path into nested
+// objects, e.g. "/realm_access/roles". KeycloakIssuerClaims and
+// Auth0IssuerClaims map the conventions of those providers.
+type IssuerClaimMapping struct {
+	// UserID defaults to "sub".
+	UserID string
+	// Username defaults to "username".
+	Username string
+	// Email defaults to "email".
+	Email string
+	// Metadata copies claims into user metadata, keyed by metadata key.
+	Metadata map[string]string
+	// LocalUser resolves the token to the stored user with the mapped
+	// email or username instead of building the user from claims. Tokens
+	// of users unknown to storage are then rejected.
+	LocalUser bool
+}
+
+// trustedIssuers verifies tokens of additional issuers. A nil
+// *trustedIssuers trusts no one.
+type trustedIssuers struct {
+	issuers map[string]*TrustedIssuer
+	clock   Clock
+	leeway  time.Duration
+}
+
+// newTrustedIssuers validates the configured issuers and fills in defaults.
+func newTrustedIssuers(configs []TrustedIssuer, clock Clock, leeway time.Duration) (*trustedIssuers, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+
+	issuers := make(map[string]*TrustedIssuer, len(configs))
+	for _, config := range configs {
+		if config.Issuer == "" {
+			return nil, errors.New("trusted issuer requires an issuer")
+		}
+		if _, ok := issuers[config.Issuer]; ok {
+			return nil, fmt.Errorf("trusted issuer %q is configured twice", config.Issuer)
+		}
+		if len(config.Algorithms) == 0 {
+			alg, err := defaultAlgorithm(config.Key)
+			if err != nil {
+				return nil, fmt.Errorf("trusted issuer %q: %w", config.Issuer, err)
+			}
+			config.Algorithms = []string{alg}
+		}
+		if config.Claims.UserID == "" {
+			config.Claims.UserID = "sub"
+		}
+		if config.Claims.Username == "" {
+			config.Claims.Username = "username"
+		}
+		if config.Claims.Email == "" {
+			config.Claims.Email = "email"
+		}
+		issuer := config
+		issuers[config.Issuer] = &issuer
+	}
+	return &trustedIssuers{issuers: issuers, clock: clock, leeway: leeway}, nil
+}
+
+// defaultAlgorithm returns the signing algorithm for key.
+func defaultAlgorithm(key interface{}) (string, error) {
+	switch key := key.(type) {
+	case []byte:
+		if len(key) == 0 {
+			return "", errors.New("empty HMAC key")
+		}
+		return HS256, nil
+	case *rsa.PublicKey:
+		return "RS256", nil
+	case *ecdsa.PublicKey:
+		return "ES256", nil
+	case ed25519.PublicKey:
+		return "EdDSA", nil
+	default:
+		return "", fmt.Errorf("unsupported key type %T", key)
+	}
+}
+
+// verify parses tokenString if it was issued by a trusted issuer. It returns
+// nil without an error when the issuer is not trusted.
+func (ti *trustedIssuers) verify(tokenString string) (*TrustedIssuer, jwt.MapClaims, error) {
+	if ti == nil {
+		return nil, nil, nil
+	}
+
+	unverified := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(tokenString, unverified); err != nil {
+		return nil, nil, nil
+	}
+	iss, _ := unverified.GetIssuer()
+	issuer, ok := ti.issuers[iss]
+	if !ok {
+		return nil, nil, nil
+	}
+
+	now := nowFrom(ti.clock)
+	if !issuer.Until.IsZero() && now.After(issuer.Until) {
+		return nil, nil, fmt.Errorf("trusted issuer %q is no longer accepted", iss)
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(issuer.Algorithms),
+		jwt.WithIssuer(issuer.Issuer),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+		jwt.WithLeeway(ti.leeway),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+	}
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(*jwt.Token) (interface{}, error) {
+		return issuer.Key, nil
+	}, options...)
+	if err != nil || !token.Valid {
+		return nil, nil, fmt.Errorf("trusted issuer %q: %w", iss, err)
+	}
+	if len(issuer.Audience) > 0 && !audienceMatches(claims, issuer.Audience) {
+		return nil, nil, fmt.Errorf("trusted issuer %q: unexpected audience", iss)
+	}
+	return issuer, claims, nil
+}
+
+// audienceMatches reports whether the token's audience contains one of accepted.
+func audienceMatches(claims jwt.MapClaims, accepted []string) bool {
+	audience, err := claims.GetAudience()
+	if err != nil {
+		return false
+	}
+	for _, aud := range audience {
+		for _, want := range accepted {
+			if aud == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// user maps the claims of a token from issuer into a user.
+func (m IssuerClaimMapping) user(claims jwt.MapClaims) *models.User {
+	user := &models.User{IsActive: true}
+	user.ID = claimString(claims, m.UserID)
+	user.Username = claimString(claims, m.Username)
+	user.Email = claimString(claims, m.Email)
+	for key, claim := range m.Metadata {
+		value, ok := claimValue(claims, claim)
+		if !ok {
+			continue
+		}
+		if user.Metadata == nil {
+			user.Metadata = make(map[string]interface{})
+		}
+		user.Metadata[key] = value
+	}
+	return user
+}
+
+// claimString returns the string claim named name, which may be a path.
+func claimString(claims jwt.MapClaims, name string) string {
+	value, _ := claimValue(claims, name)
+	s, _ := value.(string)
+	return s
+}
+
+// validateTrusted validates a token of a trusted issuer. It returns a nil
+// user without an error when the token is not from a trusted issuer.
+func (t *Tokens) validateTrusted(tokenString string) (*models.User, error) {
+	issuer, claims, err := t.trustedIssuers.verify(tokenString)
+	if err != nil {
+		return nil, ErrInvalidToken()
+	}
+	if issuer == nil {
+		return nil, nil
+	}
+
+	if tokenID, _ := claims["jti"].(string); tokenID != "" {
+		blacklisted, err := t.storage.IsTokenBlacklisted(tokenID)
+		if err != nil {
+			return nil, WrapDatabaseError(err)
+		}
+		if blacklisted {
+			return nil, ErrTokenRevoked()
+		}
+	}
+
+	mapped := issuer.Claims.user(claims)
+	if !issuer.Claims.LocalUser {
+		if mapped.ID == "" {
+			return nil, NewAuthErrorWithDetails(ErrCodeInvalidToken,
+				"Token missing user ID", fmt.Sprintf("Token must contain a valid '%s' claim", issuer.Claims.UserID))
+		}
+		return mapped, nil
+	}
+
+	var user *models.User
+	err = ErrUserNotFound()
+	if mapped.Email != "" {
+		user, err = t.storage.GetUserByEmail(mapped.Email)
+	}
+	if err != nil && mapped.Username != "" {
+		user, err = t.storage.GetUserByUsername(mapped.Username)
+	}
+	if err != nil {
+		return nil, ErrUserNotFound()
+	}
+	if !user.IsActive {
+		return nil, ErrUserInactive()
+	}
+	return user, nil
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func legacyToken(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign legacy token: %v", err)
+	}
+	return token
+}
+
+func TestValidateTrustedIssuer(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	ta := NewTestAuth(t)
+	now := ta.Clock.Now()
+	ta.trustedIssuers, err = newTrustedIssuers([]TrustedIssuer{{
+		Issuer: "legacy-auth",
+		Key:    &key.PublicKey,
+		Until:  now.Add(24 * time.Hour),
+		Claims: IssuerClaimMapping{
+			Username: "login",
+			Email:    "mail",
+			Metadata: map[string]string{"roles": "groups"},
+		},
+	}}, ta.Clock, 0)
+	if err != nil {
+		t.Fatalf("Failed to configure trusted issuer: %v", err)
+	}
+
+	claims := jwt.MapClaims{
+		"iss":    "legacy-auth",
+		"sub":    "legacy-42",
+		"login":  "carol",
+		"mail":   "carol@example.test",
+		"groups": []interface{}{"admin"},
+		"iat":    now.Unix(),
+		"exp":    now.Add(time.Hour).Unix(),
+	}
+	user, err := ta.Tokens().Validate(legacyToken(t, key, claims))
+	if err != nil {
+		t.Fatalf("Expected legacy token to validate: %v", err)
+	}
+	if user.ID != "legacy-42" || user.Username != "carol" || user.Email != "carol@example.test" {
+		t.Errorf("Expected claims to be mapped into the user, got %+v", user)
+	}
+	if roles := stringList(user.Metadata["roles"]); len(roles) != 1 || roles[0] != "admin" {
+		t.Errorf("Expected mapped roles, got %v", user.Metadata)
+	}
+
+	// Our own tokens still validate
+	ta.SeedUser("alice", "alice-password")
+	if _, err := ta.Tokens().Validate(ta.LoginAs("alice").AccessToken); err != nil {
+		t.Errorf("Expected own token to validate: %v", err)
+	}
+
+	// A token signed with another key is rejected
+	other, _ := rsa.GenerateKey(rand.Reader, 2048)
+	if _, err := ta.Tokens().Validate(legacyToken(t, other, claims)); err == nil {
+		t.Error("Expected token with a foreign signature to be rejected")
+	}
+
+	// Untrusted issuers are rejected
+	claims["iss"] = "someone-else"
+	if _, err := ta.Tokens().Validate(legacyToken(t, key, claims)); err == nil {
+		t.Error("Expected token from an untrusted issuer to be rejected")
+	}
+
+	// The migration window ends
+	claims["iss"] = "legacy-auth"
+	claims["exp"] = now.Add(48 * time.Hour).Unix()
+	token := legacyToken(t, key, claims)
+	ta.Clock.Advance(25 * time.Hour)
+	if _, err := ta.Tokens().Validate(token); err == nil {
+		t.Error("Expected legacy token to be rejected after the migration window")
+	}
+}
+
+func TestValidateTrustedIssuerLocalUser(t *testing.T) {
+	secret := []byte("legacy-shared-secret")
+	ta := NewTestAuth(t)
+	var err error
+	ta.trustedIssuers, err = newTrustedIssuers([]TrustedIssuer{{
+		Issuer: "legacy-auth",
+		Key:    secret,
+		Claims: IssuerClaimMapping{LocalUser: true},
+	}}, ta.Clock, 0)
+	if err != nil {
+		t.Fatalf("Failed to configure trusted issuer: %v", err)
+	}
+	alice := ta.SeedUser("alice", "alice-password")
+
+	sign := func(email string) string {
+		now := ta.Clock.Now()
+		token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"iss":   "legacy-auth",
+			"sub":   "legacy-1",
+			"email": email,
+			"exp":   now.Add(time.Hour).Unix(),
+		}).SignedString(secret)
+		if err != nil {
+			t.Fatalf("Failed to sign legacy token: %v", err)
+		}
+		return token
+	}
+
+	user, err := ta.Tokens().Validate(sign(alice.Email))
+	if err != nil {
+		t.Fatalf("Expected legacy token to resolve to the local user: %v", err)
+	}
+	if user.ID != alice.ID {
+		t.Errorf("Expected local user %s, got %s", alice.ID, user.ID)
+	}
+	if _, err := ta.Tokens().Validate(sign("nobody@example.test")); err == nil {
+		t.Error("Expected unknown local user to be rejected")
+	}
+}
+
+func TestNewTrustedIssuersInvalid(t *testing.T) {
+	if _, err := newTrustedIssuers([]TrustedIssuer{{Issuer: "legacy"}}, nil, 0); err == nil {
+		t.Error("Expected missing key to be rejected")
+	}
+	if _, err := newTrustedIssuers([]TrustedIssuer{{Key: []byte("k")}}, nil, 0); err == nil {
+		t.Error("Expected missing issuer to be rejected")
+	}
+	duplicate := TrustedIssuer{Issuer: "legacy", Key: []byte("k")}
+	if _, err := newTrustedIssuers([]TrustedIssuer{duplicate, duplicate}, nil, 0); err == nil {
+		t.Error("Expected duplicate issuer to be rejected")
+	}
+}
@@ -0,0 +1,44 @@
+package auth
+
+import "github.com/pragneshbagary/go-auth/pkg/storage"
+
+// Uniqueness scopes for AuthConfig.Uniqueness.
+const (
+	UniqueGlobal    = "global"
+	UniquePerTenant = "tenant"
+	UniquePerApp    = "app"
+)
+
+// scopeUniqueness sets up the unique indexes of config.Uniqueness on base
+// and returns the tenant its users are isolated by: config.Tenant per
+// tenant, the application name per application.
+func scopeUniqueness(base storage.EnhancedStorage, config *AuthConfig) (string, error) {
+	scope, tenant := storage.UniquePerTenant, config.Tenant
+	switch config.Uniqueness {
+	case UniqueGlobal:
+		scope = storage.UniqueGlobal
+	case UniquePerTenant:
+		if config.Tenant == "" {
+			return "", NewAuthError(ErrCodeInvalidConfig, "Per-tenant uniqueness requires Tenant")
+		}
+	case UniquePerApp:
+		if config.Tenant != "" {
+			return "", NewAuthError(ErrCodeInvalidConfig, "Per-app uniqueness cannot be combined with Tenant")
+		}
+		if config.AppName == "" {
+			return "", NewAuthError(ErrCodeInvalidConfig, "Per-app uniqueness requires AppName")
+		}
+		tenant = config.AppName
+	default:
+		return "", NewAuthError(ErrCodeInvalidConfig, "Unsupported uniqueness scope: "+config.Uniqueness)
+	}
+
+	unique, ok := base.(storage.UniquenessStorage)
+	if !ok {
+		return "", NewAuthError(ErrCodeInvalidConfig, "Uniqueness scopes require a SQL storage")
+	}
+	if err := unique.SetUniquenessScope(scope); err != nil {
+		return "", WrapDatabaseError(err)
+	}
+	return tenant, nil
+}
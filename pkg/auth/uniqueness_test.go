@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pragneshbagary/go-auth/internal/storage/memory"
+	"github.com/pragneshbagary/go-auth/internal/storage/sqlite"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+func TestScopeUniqueness(t *testing.T) {
+	db, err := sqlite.NewInMemorySQLiteStorage("")
+	if err != nil {
+		t.Fatalf("NewInMemorySQLiteStorage failed: %v", err)
+	}
+	defer db.Close()
+
+	tenant, err := scopeUniqueness(db, &AuthConfig{Uniqueness: UniqueGlobal, AppName: "app"})
+	if err != nil || tenant != "" {
+		t.Errorf("Expected global uniqueness without tenant, got %q, %v", tenant, err)
+	}
+	for name, config := range map[string]*AuthConfig{
+		"per-tenant without tenant": {Uniqueness: UniquePerTenant},
+		"per-app with tenant":       {Uniqueness: UniquePerApp, AppName: "app", Tenant: "acme"},
+		"per-app without app":       {Uniqueness: UniquePerApp},
+		"unknown scope":             {Uniqueness: "planet"},
+	} {
+		if _, err := scopeUniqueness(db, config); !isCode(err, ErrCodeInvalidConfig) {
+			t.Errorf("%s: expected an invalid configuration, got %v", name, err)
+		}
+	}
+	if _, err := scopeUniqueness(memory.NewInMemoryStorage(), &AuthConfig{Uniqueness: UniqueGlobal}); err == nil {
+		t.Error("Expected the memory storage not to support uniqueness scopes")
+	}
+}
+
+func TestWrapDatabaseErrorDuplicate(t *testing.T) {
+	err := WrapDatabaseError(&storage.DuplicateError{Field: "email"})
+	if !errors.Is(err, ErrUserExists("")) {
+		t.Errorf("Expected USER_EXISTS, got %v", err)
+	}
+}
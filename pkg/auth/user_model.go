@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// UserModel persists an application-defined user struct T that embeds
+// models.User. Exported fields of T tagged `db:"column"` are stored as typed
+// columns of the users table, added on NewUserModel:
+//
+//	type Customer struct {
+//		models.User
+//		Plan      string     `db:"plan"`
+//		Seats     int        `db:"seats"`
+//		TrialEnds *time.Time `db:"trial_ends"` // pointers are nullable
+//	}
+//
+// The storage must implement storage.ExtensionStorage.
+type UserModel[T any] struct {
+	auth   *Auth
+	store  storage.ExtensionStorage
+	user   []int // index of the embedded models.User
+	fields []userModelField
+}
+
+// userModelField maps a struct field to its column.
+type userModelField struct {
+	index  []int
+	column string
+}
+
+// storageWrapper is implemented by storage decorators so that optional
+// storage interfaces of the wrapped storage can be found.
+type storageWrapper interface {
+	unwrapStorage() storage.EnhancedStorage
+}
+
+// extensionStorage returns the ExtensionStorage behind s, if any.
+func extensionStorage(s storage.EnhancedStorage) (storage.ExtensionStorage, bool) {
+	for {
+		if ext, ok := s.(storage.ExtensionStorage); ok {
+			return ext, true
+		}
+		wrapper, ok := s.(storageWrapper)
+		if !ok {
+			return nil, false
+		}
+		s = wrapper.unwrapStorage()
+	}
+}
+
+// baseStorage returns the storage behind the decorators of s.
+func baseStorage(s storage.EnhancedStorage) storage.EnhancedStorage {
+	for {
+		wrapper, ok := s.(storageWrapper)
+		if !ok {
+			return s
+		}
+		s = wrapper.unwrapStorage()
+	}
+}
+
+var (
+	userModelType = reflect.TypeOf(models.User{})
+	timeType      = reflect.TypeOf(time.Time{})
+)
+
+// NewUserModel validates T and adds its columns to the users table.
+func NewUserModel[T any](a *Auth) (*UserModel[T], error) {
+	store, ok := extensionStorage(a.storage)
+	if !ok {
+		return nil, NewAuthErrorWithDetails(ErrCodeInvalidConfig, "Invalid configuration",
+			"The storage does not support custom user columns")
+	}
+
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() != reflect.Struct {
+		return nil, ErrConfigError(fmt.Sprintf("user model %s must be a struct", t))
+	}
+	m := &UserModel[T]{auth: a, store: store}
+	var columns []storage.ExtensionColumn
+	for _, field := range reflect.VisibleFields(t) {
+		if field.Anonymous && field.Type == userModelType && len(field.Index) == 1 {
+			m.user = field.Index
+			continue
+		}
+		column, ok := field.Tag.Lookup("db")
+		if !ok || column == "-" || !field.IsExported() {
+			continue
+		}
+		column = strings.Split(column, ",")[0]
+		if !storage.ValidColumnName(column) {
+			return nil, ErrConfigError(fmt.Sprintf("column %q of field %s", column, field.Name))
+		}
+		columnType, nullable, ok := extensionColumnType(field.Type)
+		if !ok {
+			return nil, ErrConfigError(fmt.Sprintf("type %s of field %s", field.Type, field.Name))
+		}
+		m.fields = append(m.fields, userModelField{index: field.Index, column: column})
+		columns = append(columns, storage.ExtensionColumn{Name: column, Type: columnType, Nullable: nullable})
+	}
+	if m.user == nil {
+		return nil, ErrConfigError(fmt.Sprintf("user model %s must embed models.User", t))
+	}
+
+	if len(columns) > 0 {
+		if err := store.ExtendUserSchema(columns); err != nil {
+			return nil, WrapError(err, ErrCodeMigrationError, "Failed to extend user schema")
+		}
+	}
+	return m, nil
+}
+
+// extensionColumnType maps a Go field type to a column type. Pointer
+// fields map to nullable columns.
+func extensionColumnType(t reflect.Type) (storage.ColumnType, bool, bool) {
+	nullable := false
+	if t.Kind() == reflect.Pointer {
+		t, nullable = t.Elem(), true
+	}
+	if t == timeType {
+		return storage.ColumnTime, nullable, true
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return storage.ColumnText, nullable, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return storage.ColumnInteger, nullable, true
+	case reflect.Float32, reflect.Float64:
+		return storage.ColumnFloat, nullable, true
+	case reflect.Bool:
+		return storage.ColumnBool, nullable, true
+	}
+	return 0, false, false
+}
+
+// columns returns the column names of the model.
+func (m *UserModel[T]) columns() []string {
+	columns := make([]string, len(m.fields))
+	for i, field := range m.fields {
+		columns[i] = field.column
+	}
+	return columns
+}
+
+// Get loads the user with userID including its custom columns.
+func (m *UserModel[T]) Get(userID string) (*T, error) {
+	if userID == "" {
+		return nil, ErrValidationError("user ID")
+	}
+	user, err := m.auth.storage.GetUserByID(userID)
+	if err != nil {
+		return nil, ErrUserNotFound()
+	}
+
+	result := new(T)
+	v := reflect.ValueOf(result).Elem()
+	v.FieldByIndex(m.user).Set(reflect.ValueOf(*user))
+	if len(m.fields) == 0 {
+		return result, nil
+	}
+	dest := make([]interface{}, len(m.fields))
+	for i, field := range m.fields {
+		dest[i] = v.FieldByIndex(field.index).Addr().Interface()
+	}
+	if err := m.store.GetUserExtension(userID, m.columns(), dest); err != nil {
+		if isUserNotFound(err) {
+			return nil, ErrUserNotFound()
+		}
+		return nil, WrapDatabaseError(err)
+	}
+	return result, nil
+}
+
+// Save writes the custom columns of user. The embedded models.User is not
+// written; use Users().Update for its fields.
+func (m *UserModel[T]) Save(user *T) error {
+	v := reflect.ValueOf(user).Elem()
+	userID := v.FieldByIndex(m.user).Interface().(models.User).ID
+	if userID == "" {
+		return ErrValidationError("user ID")
+	}
+	if len(m.fields) == 0 {
+		return nil
+	}
+	values := make([]interface{}, len(m.fields))
+	for i, field := range m.fields {
+		values[i] = v.FieldByIndex(field.index).Interface()
+	}
+	if err := m.store.UpdateUserExtension(userID, m.columns(), values); err != nil {
+		if isUserNotFound(err) {
+			return ErrUserNotFound()
+		}
+		return WrapDatabaseError(err)
+	}
+	return nil
+}
+
+// Register registers a user like Auth.Register and stores the custom
+// columns of user. The embedded models.User is replaced by the registered one.
+func (m *UserModel[T]) Register(ctx context.Context, payload RegisterRequest, user *T) (*T, error) {
+	registered, err := m.auth.RegisterContext(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	reflect.ValueOf(user).Elem().FieldByIndex(m.user).Set(reflect.ValueOf(*registered))
+	if err := m.Save(user); err != nil {
+		// Do not leave a user without its custom columns behind
+		if deleteErr := m.auth.storage.DeleteUser(registered.ID); deleteErr != nil {
+			m.auth.logger.Error("Failed to roll back registration", map[string]interface{}{
+				"user_id": registered.ID,
+				"error":   deleteErr,
+			})
+		}
+		return nil, err
+	}
+	return user, nil
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+type testCustomer struct {
+	models.User
+	Plan      string     `db:"plan"`
+	Seats     int        `db:"seats"`
+	TrialEnds *time.Time `db:"trial_ends"`
+	Scratch   string
+}
+
+func TestUserModel(t *testing.T) {
+	ta := NewTestAuth(t)
+	customers, err := NewUserModel[testCustomer](ta.Auth)
+	if err != nil {
+		t.Fatalf("Failed to create user model: %v", err)
+	}
+
+	trialEnds := ta.Clock.Now().Add(14 * 24 * time.Hour)
+	customer, err := customers.Register(context.Background(), RegisterRequest{
+		Username: "acme",
+		Email:    "admin@acme.test",
+		Password: "acme-password",
+	}, &testCustomer{Plan: "team", Seats: 5, TrialEnds: &trialEnds, Scratch: "not stored"})
+	if err != nil {
+		t.Fatalf("Failed to register customer: %v", err)
+	}
+	if customer.ID == "" || customer.Username != "acme" {
+		t.Fatalf("Expected registered user to be embedded, got %+v", customer.User)
+	}
+
+	loaded, err := customers.Get(customer.ID)
+	if err != nil {
+		t.Fatalf("Failed to load customer: %v", err)
+	}
+	if loaded.Plan != "team" || loaded.Seats != 5 || loaded.TrialEnds == nil || !loaded.TrialEnds.Equal(trialEnds) {
+		t.Errorf("Expected custom columns to round-trip, got %+v", loaded)
+	}
+	if loaded.Scratch != "" {
+		t.Error("Expected untagged fields not to be stored")
+	}
+
+	loaded.Seats = 10
+	loaded.TrialEnds = nil
+	if err := customers.Save(loaded); err != nil {
+		t.Fatalf("Failed to save customer: %v", err)
+	}
+	reloaded, _ := customers.Get(customer.ID)
+	if reloaded.Seats != 10 || reloaded.TrialEnds != nil {
+		t.Errorf("Expected saved columns, got %+v", reloaded)
+	}
+
+	if _, err := customers.Get("missing"); err == nil {
+		t.Error("Expected unknown user to be rejected")
+	}
+}
+
+func TestUserModelRejectsInvalidModels(t *testing.T) {
+	ta := NewTestAuth(t)
+
+	type noUser struct {
+		Plan string `db:"plan"`
+	}
+	if _, err := NewUserModel[noUser](ta.Auth); err == nil {
+		t.Error("Expected model without embedded models.User to be rejected")
+	}
+
+	type badColumn struct {
+		models.User
+		Plan string `db:"plan; DROP TABLE users"`
+	}
+	if _, err := NewUserModel[badColumn](ta.Auth); err == nil {
+		t.Error("Expected invalid column name to be rejected")
+	}
+}
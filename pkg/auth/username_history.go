@@ -0,0 +1,240 @@
+package auth
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// UsernameChange records that a user changed their username.
+type UsernameChange struct {
+	UserID      string    `json:"user_id"`
+	OldUsername string    `json:"old_username"`
+	NewUsername string    `json:"new_username"`
+	ChangedAt   time.Time `json:"changed_at"`
+}
+
+// UsernameHistoryStore persists username changes.
+type UsernameHistoryStore interface {
+	SaveUsernameChange(change UsernameChange) error
+	// LastUsernameRelease returns the most recent change away from username,
+	// nil if it was never changed.
+	LastUsernameRelease(username string) (*UsernameChange, error)
+	// ListUsernameChanges returns the changes of userID, oldest first.
+	ListUsernameChanges(userID string) ([]UsernameChange, error)
+}
+
+// UsernameConfig configures username changes.
+type UsernameConfig struct {
+	// History stores previous usernames. Defaults to an in-memory store.
+	History UsernameHistoryStore
+	// ReuseCooldown is how long a previous username stays reserved for its
+	// former owner. Defaults to 30 days; negative disables the reservation.
+	ReuseCooldown time.Duration
+	// LoginWithPrevious lets users log in with a previous username while it
+	// is reserved for them.
+	LoginWithPrevious bool
+}
+
+// memoryUsernameHistoryStore is an in-memory UsernameHistoryStore.
+type memoryUsernameHistoryStore struct {
+	mu      sync.RWMutex
+	changes []UsernameChange // oldest first
+}
+
+// NewMemoryUsernameHistoryStore creates an in-memory UsernameHistoryStore.
+// History is lost on restart; use a persistent store in production.
+func NewMemoryUsernameHistoryStore() UsernameHistoryStore {
+	return &memoryUsernameHistoryStore{}
+}
+
+func (s *memoryUsernameHistoryStore) SaveUsernameChange(change UsernameChange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.changes = append(s.changes, change)
+	sort.SliceStable(s.changes, func(i, j int) bool {
+		return s.changes[i].ChangedAt.Before(s.changes[j].ChangedAt)
+	})
+	return nil
+}
+
+func (s *memoryUsernameHistoryStore) LastUsernameRelease(username string) (*UsernameChange, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for i := len(s.changes) - 1; i >= 0; i-- {
+		if s.changes[i].OldUsername == username {
+			change := s.changes[i]
+			return &change, nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *memoryUsernameHistoryStore) ListUsernameChanges(userID string) ([]UsernameChange, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var changes []UsernameChange
+	for _, change := range s.changes {
+		if change.UserID == userID {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
+
+// usernamePolicy records username changes and reserves previous usernames.
+// A nil *usernamePolicy reserves nothing.
+type usernamePolicy struct {
+	config UsernameConfig
+	clock  Clock
+}
+
+func newUsernamePolicy(config UsernameConfig, clock Clock) *usernamePolicy {
+	if config.History == nil {
+		config.History = NewMemoryUsernameHistoryStore()
+	}
+	if config.ReuseCooldown == 0 {
+		config.ReuseCooldown = 30 * 24 * time.Hour
+	}
+	return &usernamePolicy{config: config, clock: clock}
+}
+
+// previousOwner returns the ID of the user username is reserved for, or ""
+// if it is not reserved.
+func (p *usernamePolicy) previousOwner(username string) (string, error) {
+	if p == nil || p.config.ReuseCooldown < 0 {
+		return "", nil
+	}
+	change, err := p.config.History.LastUsernameRelease(username)
+	if err != nil || change == nil {
+		return "", err
+	}
+	if !nowFrom(p.clock).Before(change.ChangedAt.Add(p.config.ReuseCooldown)) {
+		return "", nil
+	}
+	return change.UserID, nil
+}
+
+// checkAvailable returns ErrUserExists unless username is free for userID:
+// not used by another user nor reserved for them. userID is empty for new
+// users.
+func (p *usernamePolicy) checkAvailable(store storage.EnhancedStorage, userID, username string) error {
+	if existing, err := store.GetUserByUsername(username); err == nil && existing.ID != userID {
+		return ErrUserExists("username")
+	}
+	owner, err := p.previousOwner(username)
+	if err != nil {
+		return WrapDatabaseError(err)
+	}
+	if owner != "" && owner != userID {
+		return ErrUserExists("username")
+	}
+	return nil
+}
+
+// lookupUser returns the user with username, following previous usernames
+// while they are reserved.
+func (p *usernamePolicy) lookupUser(store storage.EnhancedStorage, username string) (*models.User, error) {
+	if user, err := store.GetUserByUsername(username); err == nil {
+		return user, nil
+	}
+	return p.previousUser(store, username)
+}
+
+// previousUser returns the user a previous username is reserved for.
+func (p *usernamePolicy) previousUser(store storage.EnhancedStorage, username string) (*models.User, error) {
+	owner, err := p.previousOwner(username)
+	if err != nil {
+		return nil, err
+	}
+	if owner == "" {
+		return nil, ErrUserNotFound()
+	}
+	return store.GetUserByID(owner)
+}
+
+// ChangeUsername changes the username of userID. The previous username stays
+// reserved for the user during UsernameConfig.ReuseCooldown, and can be
+// used to log in meanwhile with UsernameConfig.LoginWithPrevious.
+func (u *Users) ChangeUsername(userID, newUsername string) error {
+	var oldUsername string
+	var err error
+	defer func() {
+		u.eventLogger.LogUsernameChange(userID, oldUsername, newUsername, err == nil, err)
+	}()
+
+	newUsername = strings.TrimSpace(newUsername)
+	if userID == "" {
+		err = ErrValidationError("user ID")
+		return err
+	}
+	if newUsername == "" {
+		err = ErrValidationError("username")
+		return err
+	}
+	user, getErr := u.storage.GetUserByID(userID)
+	if getErr != nil {
+		err = ErrUserNotFound()
+		return err
+	}
+	oldUsername = user.Username
+	if newUsername == oldUsername {
+		return nil
+	}
+	if err = u.usernames.checkAvailable(u.storage, userID, newUsername); err != nil {
+		return err
+	}
+
+	if updateErr := u.storage.UpdateUser(userID, storage.UserUpdates{Username: &newUsername}); updateErr != nil {
+		err = WrapDatabaseError(updateErr)
+		return err
+	}
+	u.recordUsernameChange(userID, oldUsername, newUsername)
+	return nil
+}
+
+// recordUsernameChange adds a change to the username history. Failures are
+// logged; the change itself has been stored already.
+func (u *Users) recordUsernameChange(userID, oldUsername, newUsername string) {
+	if u.usernames == nil {
+		return
+	}
+	change := UsernameChange{
+		UserID:      userID,
+		OldUsername: oldUsername,
+		NewUsername: newUsername,
+		ChangedAt:   nowFrom(u.clock),
+	}
+	if err := u.usernames.config.History.SaveUsernameChange(change); err != nil {
+		u.logger.Error("Failed to record username change", map[string]interface{}{
+			"user_id": userID,
+			"error":   err,
+		})
+	}
+}
+
+// UsernameHistory returns the username changes of userID, oldest first.
+func (u *Users) UsernameHistory(userID string) ([]UsernameChange, error) {
+	if u.usernames == nil {
+		return nil, nil
+	}
+	changes, err := u.usernames.config.History.ListUsernameChanges(userID)
+	if err != nil {
+		return nil, WrapDatabaseError(err)
+	}
+	return changes, nil
+}
+
+// ResolveUsername returns the user with username, following a previous
+// username while it is reserved, e.g. to redirect old profile URLs.
+func (u *Users) ResolveUsername(username string) (*models.UserProfile, error) {
+	user, err := u.usernames.lookupUser(u.storage, username)
+	if err != nil {
+		return nil, ErrUserNotFound()
+	}
+	return toProfile(user), nil
+}
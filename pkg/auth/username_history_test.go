@@ -0,0 +1,62 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChangeUsername(t *testing.T) {
+	ta := NewTestAuth(t)
+	alice := ta.SeedUser("alice", "alice-password")
+	bob := ta.SeedUser("bob", "bob-password")
+	users := ta.Users()
+
+	if err := users.ChangeUsername(alice.ID, "bob"); !errors.Is(err, ErrUserExists("")) {
+		t.Errorf("Expected a taken username to be rejected, got %v", err)
+	}
+	if err := users.ChangeUsername(alice.ID, "alicia"); err != nil {
+		t.Fatalf("ChangeUsername failed: %v", err)
+	}
+	if profile, err := users.GetByUsername("alicia"); err != nil || profile.ID != alice.ID {
+		t.Fatalf("Expected the new username to be stored, got %v", err)
+	}
+
+	history, err := users.UsernameHistory(alice.ID)
+	if err != nil || len(history) != 1 || history[0].OldUsername != "alice" || history[0].NewUsername != "alicia" {
+		t.Fatalf("Unexpected history %+v: %v", history, err)
+	}
+
+	// The old username is reserved for alice and redirects to her
+	if profile, err := users.ResolveUsername("alice"); err != nil || profile.Username != "alicia" {
+		t.Errorf("Expected the old username to resolve to alicia, got %+v: %v", profile, err)
+	}
+	if _, err := ta.Register(RegisterRequest{Username: "alice", Password: "password123"}); !errors.Is(err, ErrUserExists("")) {
+		t.Errorf("Expected registration with a reserved username to fail, got %v", err)
+	}
+	reserved := "alice"
+	if err := users.Update(bob.ID, UserUpdate{Username: &reserved}); !errors.Is(err, ErrUserExists("")) {
+		t.Errorf("Expected renaming to a reserved username to fail, got %v", err)
+	}
+	if availability, _ := users.CheckAvailability("alice", ""); availability.Username.Reason != AvailabilityReserved {
+		t.Errorf("Expected the username to be reported as reserved, got %+v", availability.Username)
+	}
+
+	// Logging in with the old username is opt-in
+	if _, err := ta.Login("alice", "alice-password", nil); !errors.Is(err, ErrInvalidCredentials()) {
+		t.Errorf("Expected login with the old username to fail, got %v", err)
+	}
+	ta.usernames.config.LoginWithPrevious = true
+	if _, err := ta.Login("alice", "alice-password", nil); err != nil {
+		t.Errorf("Expected login with the old username to succeed, got %v", err)
+	}
+
+	// Others may take the username after the cooldown
+	ta.Clock.Advance(31 * 24 * time.Hour)
+	if _, err := users.ResolveUsername("alice"); err == nil {
+		t.Error("Expected the reservation to end after the cooldown")
+	}
+	if _, err := ta.Register(RegisterRequest{Username: "alice", Password: "password123"}); err != nil {
+		t.Errorf("Expected the username to be free after the cooldown, got %v", err)
+	}
+}
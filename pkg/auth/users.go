@@ -1,10 +1,13 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/hex"
 	"time"
 
+	"github.com/pragneshbagary/go-auth/internal/storage/memory"
 	"github.com/pragneshbagary/go-auth/pkg/models"
 	"github.com/pragneshbagary/go-auth/pkg/storage"
 )
@@ -14,6 +17,27 @@ type Users struct {
 	storage          storage.EnhancedStorage
 	eventLogger      *AuthEventLogger
 	metricsCollector *MetricsCollector
+	clock            Clock
+	hasher           Hasher
+	hooks            *Hooks
+	logger           *Logger
+	permissions      *PermissionCache
+	consent          ConsentConfig
+	avatars          AvatarConfig
+	visibility       ProfileVisibility
+	usernames        *usernamePolicy
+	pending          *pendingTokens
+	settings         *runtimeSettings
+	sms              *smsResets
+	quotas           *registrationQuotas
+}
+
+// passwordHasher returns the configured Hasher, defaulting to Argon2id.
+func (u *Users) passwordHasher() Hasher {
+	if u.hasher == nil {
+		return NewArgon2Hasher(nil)
+	}
+	return u.hasher
 }
 
 // UserUpdate represents the fields that can be updated for a user.
@@ -21,18 +45,52 @@ type UserUpdate struct {
 	Email    *string
 	Username *string
 	Metadata map[string]interface{}
+	// DisplayName, Locale (a BCP 47 tag such as "en-US") and Timezone (an
+	// IANA name such as "Europe/Berlin") are profile basics; an empty string
+	// clears them.
+	DisplayName *string
+	Locale      *string
+	Timezone    *string
 }
 
 // ResetToken represents a password reset token with expiration.
 type ResetToken struct {
-	Token     string
-	UserID    string
+	Token  string
+	UserID string
+	// Channel is ResetChannelEmail or ResetChannelSMS.
+	Channel   string
+	CreatedAt time.Time
 	ExpiresAt time.Time
 }
 
-// passwordResetTokens is an in-memory store for reset tokens
-// In a production system, this should be stored in the database
-var passwordResetTokens = make(map[string]*ResetToken)
+// ResetRequest selects the user and the channel of a password reset.
+type ResetRequest struct {
+	// Channel is ResetChannelEmail, the default, or ResetChannelSMS.
+	Channel string
+	// Email is the address of the user, for ResetChannelEmail.
+	Email string
+	// Phone is the verified number of the user, for ResetChannelSMS.
+	Phone string
+}
+
+// fallbackResetTokens keeps the reset tokens of storages that do not
+// implement storage.ResetTokenStorage in memory, where they are lost on
+// restart and only redeemable on the instance that created them.
+var fallbackResetTokens storage.ResetTokenStorage = memory.NewInMemoryStorage()
+
+// resetTokenStorage returns where the reset tokens of s are kept.
+func resetTokenStorage(s storage.EnhancedStorage) storage.ResetTokenStorage {
+	if tokens, ok := baseStorage(s).(storage.ResetTokenStorage); ok {
+		return tokens
+	}
+	return fallbackResetTokens
+}
+
+// resetTokenHash returns the hash a reset token or code is stored as.
+func resetTokenHash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
 
 // Update modifies user profile information.
 // It allows updating email, username, and metadata fields.
@@ -42,19 +100,24 @@ func (u *Users) Update(userID string, updates UserUpdate) error {
 	}
 
 	// Validate that the user exists
-	_, err := u.storage.GetUserByID(userID)
+	user, err := u.storage.GetUserByID(userID)
 	if err != nil {
 		return ErrUserNotFound()
 	}
 
-	// Check for username conflicts if username is being updated
-	if updates.Username != nil && *updates.Username != "" {
-		existingUser, err := u.storage.GetUserByUsername(*updates.Username)
-		if err == nil && existingUser.ID != userID {
-			return ErrUserExists("username")
+	// Check for username conflicts if username is being updated; previous
+	// usernames of other users stay reserved
+	renamed := updates.Username != nil && *updates.Username != "" && *updates.Username != user.Username
+	if renamed {
+		if err := u.usernames.checkAvailable(u.storage, userID, *updates.Username); err != nil {
+			return err
 		}
 	}
 
+	if err := validateProfileBasics(updates); err != nil {
+		return err
+	}
+
 	// Check for email conflicts if email is being updated
 	if updates.Email != nil && *updates.Email != "" {
 		existingUser, err := u.storage.GetUserByEmail(*updates.Email)
@@ -65,20 +128,41 @@ func (u *Users) Update(userID string, updates UserUpdate) error {
 
 	// Convert to storage format
 	storageUpdates := storage.UserUpdates{
-		Email:    updates.Email,
-		Username: updates.Username,
-		Metadata: updates.Metadata,
+		Email:       updates.Email,
+		Username:    updates.Username,
+		Metadata:    updates.Metadata,
+		DisplayName: updates.DisplayName,
+		Locale:      updates.Locale,
+		Timezone:    updates.Timezone,
 	}
 
 	if err := u.storage.UpdateUser(userID, storageUpdates); err != nil {
 		return WrapDatabaseError(err)
 	}
-	
+	if renamed {
+		u.recordUsernameChange(userID, user.Username, *updates.Username)
+	}
+
+	// Roles live in metadata, so drop cached permissions of all tenants
+	if updates.Metadata != nil && u.permissions != nil {
+		if err := u.permissions.Invalidate(userID, ""); err != nil {
+			u.logger.Warn("Failed to publish permission invalidation", map[string]interface{}{
+				"user_id": userID,
+				"error":   err,
+			})
+		}
+	}
+
 	return nil
 }
 
 // ChangePassword securely changes a user's password after validating the old password.
 func (u *Users) ChangePassword(userID, oldPassword, newPassword string) error {
+	return u.ChangePasswordContext(context.Background(), userID, oldPassword, newPassword)
+}
+
+// ChangePasswordContext is like ChangePassword but passes ctx to registered hooks.
+func (u *Users) ChangePasswordContext(ctx context.Context, userID, oldPassword, newPassword string) error {
 	if userID == "" {
 		return ErrValidationError("user ID")
 	}
@@ -90,8 +174,8 @@ func (u *Users) ChangePassword(userID, oldPassword, newPassword string) error {
 	}
 
 	// Basic password strength validation
-	if len(newPassword) < 8 {
-		return ErrWeakPassword("Password must be at least 8 characters long")
+	if minLength := u.settings.minPasswordLength(); len(newPassword) < minLength {
+		return ErrPasswordTooShort(minLength)
 	}
 
 	// Get the user to verify the old password
@@ -101,7 +185,7 @@ func (u *Users) ChangePassword(userID, oldPassword, newPassword string) error {
 	}
 
 	// Verify the old password
-	match, err := CheckPasswordHash(oldPassword, user.PasswordHash)
+	match, err := verifyPassword(u.passwordHasher(), oldPassword, user.PasswordHash)
 	if err != nil {
 		return WrapError(err, ErrCodeInternalError, "Failed to verify old password")
 	}
@@ -109,8 +193,12 @@ func (u *Users) ChangePassword(userID, oldPassword, newPassword string) error {
 		return NewAuthError(ErrCodePasswordMismatch, "Old password is incorrect")
 	}
 
+	if err := u.hooks.runPasswordChange(ctx, false, user); err != nil {
+		return err
+	}
+
 	// Hash the new password
-	newPasswordHash, err := HashPassword(newPassword)
+	newPasswordHash, err := u.passwordHasher().Hash(newPassword)
 	if err != nil {
 		return WrapError(err, ErrCodeInternalError, "Failed to hash new password")
 	}
@@ -119,13 +207,61 @@ func (u *Users) ChangePassword(userID, oldPassword, newPassword string) error {
 	if err := u.storage.UpdatePassword(userID, newPasswordHash); err != nil {
 		return WrapDatabaseError(err)
 	}
-	
+
+	u.runAfterPasswordChange(ctx, userID, user)
+	if u.eventLogger != nil {
+		u.eventLogger.LogPasswordChange(userID, user.Username, "", "", true, nil)
+	}
 	return nil
 }
 
+// runAfterPasswordChange records the password change of userID and runs
+// after-password-change hooks for user, logging any failure. A nil user,
+// loaded only when hooks are registered, runs no hooks.
+func (u *Users) runAfterPasswordChange(ctx context.Context, userID string, user *models.User) {
+	if err := u.recordPasswordChange(userID); err != nil && u.logger != nil {
+		u.logger.Warn("Failed to record password change time", map[string]interface{}{
+			"user_id": userID,
+			"error":   err,
+		})
+	}
+	if user == nil {
+		return
+	}
+	if err := u.hooks.runPasswordChange(ctx, true, user); err != nil && u.logger != nil {
+		u.logger.Warn("After-password-change hook failed", map[string]interface{}{
+			"user_id": user.ID,
+			"error":   err,
+		})
+	}
+}
+
 // CreateResetToken generates a password reset token for the user with the given email.
 // The token expires after 1 hour.
 func (u *Users) CreateResetToken(email string) (*ResetToken, error) {
+	return u.CreateResetTokenContext(context.Background(), ResetRequest{Email: email})
+}
+
+// CreateResetTokenContext creates a password reset token for the user
+// selected by req. Emailed tokens expire after 1 hour and are redeemed with
+// ResetPassword. Over ResetChannelSMS a short numeric code is texted to the
+// verified number of the user, replacing the previous code of the number;
+// codes are valid for SMSResetConfig.TTL and are redeemed with
+// ResetPasswordWithCode. Tokens are persisted by storages implementing
+// storage.ResetTokenStorage.
+func (u *Users) CreateResetTokenContext(ctx context.Context, req ResetRequest) (*ResetToken, error) {
+	switch req.Channel {
+	case "", ResetChannelEmail:
+		return u.createEmailResetToken(req.Email)
+	case ResetChannelSMS:
+		return u.createSMSResetToken(ctx, req.Phone)
+	default:
+		return nil, ErrValidationError("channel")
+	}
+}
+
+// createEmailResetToken creates a reset token for the user with email.
+func (u *Users) createEmailResetToken(email string) (*ResetToken, error) {
 	if email == "" {
 		return nil, ErrValidationError("email")
 	}
@@ -144,20 +280,37 @@ func (u *Users) CreateResetToken(email string) (*ResetToken, error) {
 	token := hex.EncodeToString(tokenBytes)
 
 	// Create reset token with 1 hour expiration
+	now := nowFrom(u.clock)
 	resetToken := &ResetToken{
 		Token:     token,
 		UserID:    user.ID,
-		ExpiresAt: time.Now().Add(1 * time.Hour),
+		Channel:   ResetChannelEmail,
+		CreatedAt: now,
+		ExpiresAt: now.Add(1 * time.Hour),
 	}
 
-	// Store the token (in production, this should be in the database)
-	passwordResetTokens[token] = resetToken
+	// Only the hash of the token is stored
+	if err := resetTokenStorage(u.storage).SaveResetToken(storage.ResetToken{
+		ID:        resetTokenHash(token),
+		UserID:    user.ID,
+		Channel:   ResetChannelEmail,
+		CreatedAt: now,
+		ExpiresAt: resetToken.ExpiresAt,
+	}); err != nil {
+		return nil, WrapDatabaseError(err)
+	}
+	u.metricsCollector.RecordFunnelStep(FunnelPasswordReset, false)
 
 	return resetToken, nil
 }
 
 // ResetPassword resets a user's password using a valid reset token.
 func (u *Users) ResetPassword(token, newPassword string) error {
+	return u.ResetPasswordContext(context.Background(), token, newPassword)
+}
+
+// ResetPasswordContext is like ResetPassword but passes ctx to registered hooks.
+func (u *Users) ResetPasswordContext(ctx context.Context, token, newPassword string) error {
 	if token == "" {
 		return ErrValidationError("reset token")
 	}
@@ -166,38 +319,76 @@ func (u *Users) ResetPassword(token, newPassword string) error {
 	}
 
 	// Basic password strength validation
-	if len(newPassword) < 8 {
-		return ErrWeakPassword("Password must be at least 8 characters long")
+	if minLength := u.settings.minPasswordLength(); len(newPassword) < minLength {
+		return ErrPasswordTooShort(minLength)
 	}
 
 	// Retrieve and validate the reset token
-	resetToken, exists := passwordResetTokens[token]
-	if !exists {
+	tokens := resetTokenStorage(u.storage)
+	id := resetTokenHash(token)
+	resetToken, err := tokens.GetResetToken(id)
+	if err != nil {
+		return WrapDatabaseError(err)
+	}
+	if resetToken == nil || resetToken.Channel != ResetChannelEmail {
 		return NewAuthError(ErrCodeInvalidResetToken, "Invalid or expired reset token")
 	}
 
 	// Check if token has expired
-	if time.Now().After(resetToken.ExpiresAt) {
+	if nowFrom(u.clock).After(resetToken.ExpiresAt) {
 		// Clean up expired token
-		delete(passwordResetTokens, token)
+		tokens.DeleteResetToken(id)
 		return NewAuthError(ErrCodeResetTokenExpired, "Reset token has expired")
 	}
 
+	if err := u.resetPassword(ctx, resetToken.UserID, newPassword); err != nil {
+		return err
+	}
+
+	// Remove the used token
+	if _, err := tokens.DeleteResetToken(id); err != nil {
+		return WrapDatabaseError(err)
+	}
+	return nil
+}
+
+// resetPassword sets the password of userID after its reset token or code
+// was verified.
+func (u *Users) resetPassword(ctx context.Context, userID, newPassword string) error {
+	// Load the user only when hooks need it
+	var user *models.User
+	if u.hooks.hasPasswordChangeHooks() {
+		loaded, err := u.storage.GetUserByID(userID)
+		if err != nil {
+			return ErrUserNotFound()
+		}
+		if err := u.hooks.runPasswordChange(ctx, false, loaded); err != nil {
+			return err
+		}
+		user = loaded
+	}
+
 	// Hash the new password
-	newPasswordHash, err := HashPassword(newPassword)
+	newPasswordHash, err := u.passwordHasher().Hash(newPassword)
 	if err != nil {
 		return WrapError(err, ErrCodeInternalError, "Failed to hash new password")
 	}
 
 	// Update the password in storage
-	err = u.storage.UpdatePassword(resetToken.UserID, newPasswordHash)
+	err = u.storage.UpdatePassword(userID, newPasswordHash)
 	if err != nil {
 		return WrapDatabaseError(err)
 	}
+	u.metricsCollector.RecordFunnelStep(FunnelPasswordReset, true)
 
-	// Remove the used token
-	delete(passwordResetTokens, token)
-
+	u.runAfterPasswordChange(ctx, userID, user)
+	if u.eventLogger != nil {
+		username := ""
+		if user != nil {
+			username = user.Username
+		}
+		u.eventLogger.LogPasswordReset(userID, username, "", "", true, nil)
+	}
 	return nil
 }
 
@@ -212,7 +403,7 @@ func (u *Users) Get(userID string) (*models.UserProfile, error) {
 		return nil, ErrUserNotFound()
 	}
 
-	return user.ToUserProfile(), nil
+	return toProfile(user), nil
 }
 
 // GetByEmail retrieves a user by their email, returning a safe UserProfile without sensitive data.
@@ -226,7 +417,7 @@ func (u *Users) GetByEmail(email string) (*models.UserProfile, error) {
 		return nil, ErrUserNotFound()
 	}
 
-	return user.ToUserProfile(), nil
+	return toProfile(user), nil
 }
 
 // GetByUsername retrieves a user by their username, returning a safe UserProfile without sensitive data.
@@ -240,7 +431,7 @@ func (u *Users) GetByUsername(username string) (*models.UserProfile, error) {
 		return nil, ErrUserNotFound()
 	}
 
-	return user.ToUserProfile(), nil
+	return toProfile(user), nil
 }
 
 // List retrieves a paginated list of users, returning safe UserProfile objects without sensitive data.
@@ -262,7 +453,7 @@ func (u *Users) List(limit, offset int) ([]*models.UserProfile, error) {
 
 	profiles := make([]*models.UserProfile, len(users))
 	for i, user := range users {
-		profiles[i] = user.ToUserProfile()
+		profiles[i] = toProfile(user)
 	}
 
 	return profiles, nil
@@ -275,7 +466,7 @@ func (u *Users) Delete(userID string) error {
 	}
 
 	// Verify that the user exists before attempting deletion
-	_, err := u.storage.GetUserByID(userID)
+	user, err := u.storage.GetUserByID(userID)
 	if err != nil {
 		return ErrUserNotFound()
 	}
@@ -283,6 +474,15 @@ func (u *Users) Delete(userID string) error {
 	if err := u.storage.DeleteUser(userID); err != nil {
 		return WrapDatabaseError(err)
 	}
+
+	// Free the registration quota of the user's tenant
+	tenant, _ := user.Metadata[registrationTenantMetadataKey].(string)
+	if err := u.quotas.release(tenant); err != nil {
+		u.logger.Warn("Failed to release registration quota", map[string]interface{}{
+			"user_id": userID,
+			"error":   err,
+		})
+	}
 	
 	return nil
 }
\ No newline at end of file
@@ -0,0 +1,322 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"maps"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pragneshbagary/go-auth/pkg/models"
+	"github.com/pragneshbagary/go-auth/pkg/storage"
+)
+
+// ValidationCacheConfig enables a cache of the access tokens validated by a
+// Middleware, keyed by their jti, so that repeated requests with the same
+// token skip the signature check, the blacklist lookup and the user lookup.
+// Entries are dropped when the token is revoked, its user changes or logs
+// out everywhere, or InvalidateAllTokens is called. Revocations on other
+// instances are applied immediately with AuthConfig.Revocation, and within
+// TTL otherwise. Session checks still run on every request.
+type ValidationCacheConfig struct {
+	// TTL of an entry. Entries never outlive the token. Zero disables the cache.
+	TTL time.Duration
+	// MaxEntries bounds the cache size. Defaults to 10,000.
+	MaxEntries int
+}
+
+// ValidationCacheStats reports the effectiveness of a validation cache.
+type ValidationCacheStats struct {
+	Hits    int64 `json:"hits"`
+	Misses  int64 `json:"misses"`
+	Entries int   `json:"entries"`
+}
+
+// validationCache caches validated access tokens by jti. A nil
+// *validationCache always validates.
+type validationCache struct {
+	config ValidationCacheConfig
+	clock  Clock
+
+	mu      sync.Mutex
+	entries map[string]*validationCacheEntry // by unverifiedTokenID
+	keys    map[string]string                // jti -> entry key
+	users   map[string]map[string]struct{}   // user ID -> entry keys
+	// generation changes on every invalidation so that a validation racing
+	// a revocation does not cache the revoked token.
+	generation uint64
+	hits       int64
+	misses     int64
+}
+
+// validationCacheEntry is a validated token and its user.
+type validationCacheEntry struct {
+	token     string
+	jti       string
+	user      *models.UserProfile
+	claims    jwt.MapClaims
+	expiresAt time.Time
+}
+
+// newValidationCache returns nil unless a TTL is configured.
+func newValidationCache(config ValidationCacheConfig, clock Clock) *validationCache {
+	if config.TTL <= 0 {
+		return nil
+	}
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = 10000
+	}
+	return &validationCache{
+		config:  config,
+		clock:   clock,
+		entries: make(map[string]*validationCacheEntry),
+		keys:    make(map[string]string),
+		users:   make(map[string]map[string]struct{}),
+	}
+}
+
+// lookup returns the user and claims of token, calling validate on a miss.
+// Callers get copies they may modify.
+// Hits are passed to check, which rejects them with an error.
+func (c *validationCache) lookup(token string, validate func(string) (*models.UserProfile, jwt.MapClaims, error), check func(jwt.MapClaims) error) (*models.UserProfile, jwt.MapClaims, error) {
+	if c == nil {
+		return validate(token)
+	}
+	now := nowFrom(c.clock)
+	key := unverifiedTokenID(token)
+
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok && !now.Before(entry.expiresAt) {
+		c.remove(key)
+	} else if ok && subtle.ConstantTimeCompare([]byte(entry.token), []byte(token)) == 1 {
+		c.hits++
+		c.mu.Unlock()
+		if err := check(entry.claims); err != nil {
+			c.forgetToken(entry.jti)
+			return nil, nil, err
+		}
+		user := *entry.user
+		return &user, maps.Clone(entry.claims), nil
+	}
+	c.misses++
+	generation := c.generation
+	c.mu.Unlock()
+
+	user, claims, err := validate(token)
+	if err != nil {
+		return nil, nil, err
+	}
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return user, claims, nil
+	}
+	expiresAt := now.Add(c.config.TTL)
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil && exp.Before(expiresAt) {
+		expiresAt = exp.Time
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if generation != c.generation || !now.Before(expiresAt) {
+		return user, claims, nil
+	}
+	if len(c.entries) >= c.config.MaxEntries {
+		c.prune(now)
+	}
+	if len(c.entries) < c.config.MaxEntries {
+		c.remove(key)
+		cached := *user
+		c.entries[key] = &validationCacheEntry{token: token, jti: jti, user: &cached, claims: maps.Clone(claims), expiresAt: expiresAt}
+		c.keys[jti] = key
+		if c.users[user.ID] == nil {
+			c.users[user.ID] = make(map[string]struct{})
+		}
+		c.users[user.ID][key] = struct{}{}
+	}
+	return user, claims, nil
+}
+
+// unverifiedTokenID returns the jti of token without verifying it, or the
+// token itself if it is not a JWT, e.g. an encrypted one. Cache hits also
+// require the whole token to match.
+func unverifiedTokenID(token string) string {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return token
+	}
+	if jti, _ := claims["jti"].(string); jti != "" {
+		return jti
+	}
+	return token
+}
+
+// forgetToken drops the entry of the token with jti.
+func (c *validationCache) forgetToken(jti string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	if key, ok := c.keys[jti]; ok {
+		c.remove(key)
+	}
+}
+
+// forgetUser drops the entries of the tokens of userID.
+func (c *validationCache) forgetUser(userID string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for key := range c.users[userID] {
+		c.remove(key)
+	}
+}
+
+// clear drops all entries.
+func (c *validationCache) clear() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.entries = make(map[string]*validationCacheEntry)
+	c.keys = make(map[string]string)
+	c.users = make(map[string]map[string]struct{})
+}
+
+// remove drops the entry with key. The caller must hold c.mu.
+func (c *validationCache) remove(key string) {
+	entry, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	delete(c.entries, key)
+	delete(c.keys, entry.jti)
+	if keys := c.users[entry.user.ID]; keys != nil {
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(c.users, entry.user.ID)
+		}
+	}
+}
+
+// prune drops expired entries. The caller must hold c.mu.
+func (c *validationCache) prune(now time.Time) {
+	for key, entry := range c.entries {
+		if !now.Before(entry.expiresAt) {
+			c.remove(key)
+		}
+	}
+}
+
+// stats returns the cache counters.
+func (c *validationCache) stats() ValidationCacheStats {
+	if c == nil {
+		return ValidationCacheStats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ValidationCacheStats{Hits: c.hits, Misses: c.misses, Entries: len(c.entries)}
+}
+
+// validationCaches holds the validation caches of the middlewares of an
+// Auth instance so that revocations reach all of them.
+type validationCaches struct {
+	mu     sync.RWMutex
+	caches []*validationCache
+}
+
+func (s *validationCaches) add(c *validationCache) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.caches = append(s.caches, c)
+}
+
+// each calls f for every cache.
+func (s *validationCaches) each(f func(*validationCache)) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, c := range s.caches {
+		f(c)
+	}
+}
+
+func (s *validationCaches) forgetToken(jti string) {
+	s.each(func(c *validationCache) { c.forgetToken(jti) })
+}
+
+func (s *validationCaches) forgetUser(userID string) {
+	s.each(func(c *validationCache) { c.forgetUser(userID) })
+}
+
+func (s *validationCaches) clear() {
+	s.each(func(c *validationCache) { c.clear() })
+}
+
+// WithValidationCache returns a copy of m that caches validated access
+// tokens, e.g. a.Middleware().WithValidationCache(config).Gin(). Each call
+// creates a separate cache, so adapters can be configured independently.
+func (m *Middleware) WithValidationCache(config ValidationCacheConfig) *Middleware {
+	cache := newValidationCache(config, m.auth.clock)
+	if cache != nil {
+		m.auth.validations.add(cache)
+	}
+	return &Middleware{auth: m.auth, cache: cache}
+}
+
+// CacheStats returns hit and miss counts of the validation cache of m.
+func (m *Middleware) CacheStats() ValidationCacheStats {
+	return m.cache.stats()
+}
+
+// validationCacheStorage drops cached validations of users that change.
+type validationCacheStorage struct {
+	storage.EnhancedStorage
+	caches *validationCaches
+}
+
+func (s *validationCacheStorage) UpdateUser(userID string, updates storage.UserUpdates) error {
+	if err := s.EnhancedStorage.UpdateUser(userID, updates); err != nil {
+		return err
+	}
+	// Recording a login does not change the cached profile
+	loginOnly := updates.LastLoginAt != nil && updates.Email == nil && updates.Username == nil && updates.Metadata == nil
+	if !loginOnly {
+		s.caches.forgetUser(userID)
+	}
+	return nil
+}
+
+func (s *validationCacheStorage) UpdatePassword(userID string, passwordHash string) error {
+	if err := s.EnhancedStorage.UpdatePassword(userID, passwordHash); err != nil {
+		return err
+	}
+	s.caches.forgetUser(userID)
+	return nil
+}
+
+func (s *validationCacheStorage) DeleteUser(userID string) error {
+	if err := s.EnhancedStorage.DeleteUser(userID); err != nil {
+		return err
+	}
+	s.caches.forgetUser(userID)
+	return nil
+}
+
+func (s *validationCacheStorage) BlacklistToken(tokenID string, expiresAt time.Time) error {
+	if err := s.EnhancedStorage.BlacklistToken(tokenID, expiresAt); err != nil {
+		return err
+	}
+	s.caches.forgetToken(tokenID)
+	return nil
+}
+
+func (s *validationCacheStorage) unwrapStorage() storage.EnhancedStorage {
+	return s.EnhancedStorage
+}
@@ -0,0 +1,91 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func serveWithToken(handler http.Handler, token string) int {
+	req := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestValidationCache(t *testing.T) {
+	ta := NewTestAuth(t)
+	user := ta.SeedUser("alice", "alice-password")
+	token := ta.LoginAs("alice").AccessToken
+
+	middleware := ta.Middleware().WithValidationCache(ValidationCacheConfig{TTL: time.Hour})
+	handler := middleware.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for i := 0; i < 3; i++ {
+		if code := serveWithToken(handler, token); code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", code)
+		}
+	}
+	if stats := middleware.CacheStats(); stats.Hits != 2 || stats.Misses != 1 || stats.Entries != 1 {
+		t.Errorf("Expected the token to be validated once, got %+v", stats)
+	}
+
+	// Changes to the user drop its tokens
+	if err := ta.Tokens().RevokeAll(user.ID); err != nil {
+		t.Fatalf("Failed to revoke all: %v", err)
+	}
+	if stats := middleware.CacheStats(); stats.Entries != 0 {
+		t.Errorf("Expected the entries of the user to be dropped, got %+v", stats)
+	}
+	serveWithToken(handler, token)
+
+	// Revoked tokens are rejected at once
+	if err := ta.Tokens().Revoke(token); err != nil {
+		t.Fatalf("Failed to revoke: %v", err)
+	}
+	if code := serveWithToken(handler, token); code != http.StatusUnauthorized {
+		t.Errorf("Expected the revoked token to be rejected, got %d", code)
+	}
+}
+
+func TestValidationCacheRespectsExpiry(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("alice", "alice-password")
+	token := ta.LoginAs("alice").AccessToken
+
+	middleware := ta.Middleware().WithValidationCache(ValidationCacheConfig{TTL: time.Hour})
+	handler := middleware.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	if code := serveWithToken(handler, token); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+
+	// The entry expires with the token, not after the TTL
+	ta.Clock.Advance(ta.config.AccessTokenTTL + time.Second)
+	if code := serveWithToken(handler, token); code != http.StatusUnauthorized {
+		t.Errorf("Expected the expired token to be rejected, got %d", code)
+	}
+	if stats := middleware.CacheStats(); stats.Hits != 0 || stats.Misses != 2 {
+		t.Errorf("Expected the expired entry to miss, got %+v", stats)
+	}
+}
+
+func TestValidationCacheRejectsForgedToken(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.SeedUser("alice", "alice-password")
+	token := ta.LoginAs("alice").AccessToken
+
+	middleware := ta.Middleware().WithValidationCache(ValidationCacheConfig{TTL: time.Hour})
+	handler := middleware.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serveWithToken(handler, token)
+
+	// A token with the same jti but another signature is not a hit
+	forged := token[:len(token)-2] + "xx"
+	if code := serveWithToken(handler, forged); code != http.StatusUnauthorized {
+		t.Errorf("Expected the forged token to be rejected, got %d", code)
+	}
+	if stats := middleware.CacheStats(); stats.Hits != 0 {
+		t.Errorf("Expected no cache hit, got %+v", stats)
+	}
+}
@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+// Profile fields that ProfileVisibility can hide.
+const (
+	ProfileFieldEmail       = "email"
+	ProfileFieldMetadata    = "metadata"
+	ProfileFieldLastLoginAt = "last_login_at"
+	ProfileFieldCreatedAt   = "created_at"
+	ProfileFieldUpdatedAt   = "updated_at"
+)
+
+// ProfileVisibility controls which UserProfile fields callers see. Masking
+// applies to callers other than the user themself and privileged roles.
+type ProfileVisibility struct {
+	// HiddenFields are cleared, e.g. ProfileFieldEmail.
+	HiddenFields []string
+	// RedactMetadata lists glob patterns (see path.Match) of metadata keys to
+	// remove, matched case-insensitively at any nesting level, e.g. "ssn"
+	// or "*credit_card*".
+	RedactMetadata []string
+	// PrivilegedRoles see every field. Defaults to "admin".
+	PrivilegedRoles []string
+}
+
+// validate checks the field names and patterns.
+func (v ProfileVisibility) validate() error {
+	for _, field := range v.HiddenFields {
+		switch field {
+		case ProfileFieldEmail, ProfileFieldMetadata, ProfileFieldLastLoginAt, ProfileFieldCreatedAt, ProfileFieldUpdatedAt:
+		default:
+			return fmt.Errorf("unknown profile field %q", field)
+		}
+	}
+	for _, pattern := range v.RedactMetadata {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid metadata pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// Viewer identifies the caller a profile is serialized for. The zero
+// Viewer is an anonymous caller.
+type Viewer struct {
+	UserID string
+	Roles  []string
+}
+
+// ViewerFromClaims returns the viewer identified by the "sub" and "roles"
+// claims of an access token.
+func ViewerFromClaims(claims jwt.MapClaims) Viewer {
+	userID, _ := claims["sub"].(string)
+	return Viewer{UserID: userID, Roles: stringList(claims["roles"])}
+}
+
+// ViewerFromContext returns the viewer authenticated by Protect or Optional,
+// or an anonymous viewer.
+func ViewerFromContext(ctx context.Context) Viewer {
+	claims, ok := GetClaimsFromContext(ctx)
+	if !ok {
+		return Viewer{}
+	}
+	return ViewerFromClaims(claims)
+}
+
+// privileged reports whether viewer sees every field of the profile of userID.
+func (v ProfileVisibility) privileged(viewer Viewer, userID string) bool {
+	if viewer.UserID != "" && viewer.UserID == userID {
+		return true
+	}
+	privileged := v.PrivilegedRoles
+	if len(privileged) == 0 {
+		privileged = []string{"admin"}
+	}
+	for _, role := range viewer.Roles {
+		for _, p := range privileged {
+			if role == p {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Mask returns the profile as viewer may see it. The original is not modified.
+func (v ProfileVisibility) Mask(profile *models.UserProfile, viewer Viewer) *models.UserProfile {
+	if profile == nil || v.privileged(viewer, profile.ID) {
+		return profile
+	}
+	masked := *profile
+	for _, field := range v.HiddenFields {
+		switch field {
+		case ProfileFieldEmail:
+			masked.Email = ""
+		case ProfileFieldMetadata:
+			masked.Metadata = nil
+		case ProfileFieldLastLoginAt:
+			masked.LastLoginAt = nil
+		case ProfileFieldCreatedAt:
+			masked.CreatedAt = time.Time{}
+		case ProfileFieldUpdatedAt:
+			masked.UpdatedAt = time.Time{}
+		}
+	}
+	if len(v.RedactMetadata) > 0 && masked.Metadata != nil {
+		masked.Metadata = v.redact(masked.Metadata)
+	}
+	return &masked
+}
+
+// redact returns a copy of metadata without keys matching RedactMetadata.
+func (v ProfileVisibility) redact(metadata map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		if v.redacted(key) {
+			continue
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			value = v.redact(nested)
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// redacted reports whether key matches a RedactMetadata pattern.
+func (v ProfileVisibility) redacted(key string) bool {
+	key = strings.ToLower(key)
+	for _, pattern := range v.RedactMetadata {
+		if matched, _ := path.Match(strings.ToLower(pattern), key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// MaskProfile masks profile for the viewer authenticated in ctx, see
+// ViewerFromContext. Use it before writing profiles in HTTP handlers.
+func (a *Auth) MaskProfile(ctx context.Context, profile *models.UserProfile) *models.UserProfile {
+	return a.config.ProfileVisibility.Mask(profile, ViewerFromContext(ctx))
+}
+
+// GetAs retrieves a user's profile masked for viewer.
+func (u *Users) GetAs(viewer Viewer, userID string) (*models.UserProfile, error) {
+	profile, err := u.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	return u.visibility.Mask(profile, viewer), nil
+}
+
+// ListAs retrieves a page of profiles masked for viewer.
+func (u *Users) ListAs(viewer Viewer, limit, offset int) ([]*models.UserProfile, error) {
+	profiles, err := u.List(limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	for i, profile := range profiles {
+		profiles[i] = u.visibility.Mask(profile, viewer)
+	}
+	return profiles, nil
+}
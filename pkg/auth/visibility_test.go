@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestProfileVisibility(t *testing.T) {
+	ta := NewTestAuth(t)
+	ta.config.ProfileVisibility = ProfileVisibility{
+		HiddenFields:   []string{ProfileFieldEmail},
+		RedactMetadata: []string{"ssn", "*credit_card*"},
+	}
+	user := ta.SeedUser("alice", "alice-password")
+	if err := ta.Users().Update(user.ID, UserUpdate{Metadata: map[string]interface{}{
+		"ssn":     "123-45-6789",
+		"billing": map[string]interface{}{"Credit_Card_Number": "4111-1111-1111-1111", "plan": "pro"},
+		"theme":   "dark",
+	}}); err != nil {
+		t.Fatalf("Failed to update metadata: %v", err)
+	}
+
+	masked, err := ta.Users().GetAs(Viewer{UserID: "someone-else"}, user.ID)
+	if err != nil {
+		t.Fatalf("Failed to get profile: %v", err)
+	}
+	if masked.Email != "" {
+		t.Error("Expected email to be hidden from other users")
+	}
+	if _, ok := masked.Metadata["ssn"]; ok {
+		t.Error("Expected ssn to be redacted")
+	}
+	billing, _ := masked.Metadata["billing"].(map[string]interface{})
+	if _, ok := billing["Credit_Card_Number"]; ok || billing["plan"] != "pro" {
+		t.Errorf("Expected nested credit card to be redacted, got %v", billing)
+	}
+	if masked.Metadata["theme"] != "dark" {
+		t.Error("Expected unmatched metadata to be kept")
+	}
+
+	for name, viewer := range map[string]Viewer{
+		"self":  {UserID: user.ID},
+		"admin": ViewerFromClaims(jwt.MapClaims{"sub": "admin-id", "roles": []interface{}{"admin"}}),
+	} {
+		profile, err := ta.Users().GetAs(viewer, user.ID)
+		if err != nil {
+			t.Fatalf("Failed to get profile: %v", err)
+		}
+		if profile.Email == "" || profile.Metadata["ssn"] == nil {
+			t.Errorf("Expected %s to see the full profile", name)
+		}
+	}
+}
+
+func TestProfileVisibilityValidation(t *testing.T) {
+	if err := (ProfileVisibility{HiddenFields: []string{"password_hash"}}).validate(); err == nil {
+		t.Error("Expected unknown field to be rejected")
+	}
+	if err := (ProfileVisibility{RedactMetadata: []string{"[ssn"}}).validate(); err == nil {
+		t.Error("Expected malformed pattern to be rejected")
+	}
+}
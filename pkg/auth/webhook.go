@@ -0,0 +1,365 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookSignatureHeader carries the signature of webhook requests:
+// "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>" keyed by the endpoint secret>".
+const WebhookSignatureHeader = "X-Auth-Signature"
+
+// WebhookEndpoint receives authentication events as CloudEvents 1.0 in
+// structured JSON mode.
+type WebhookEndpoint struct {
+	// ID names the endpoint in dead letters. Defaults to the URL.
+	ID  string
+	URL string
+	// Secret signs the requests, see VerifyWebhookSignature.
+	Secret string
+	// Events lists the event types to send; all when empty.
+	Events []string
+	// Source is the CloudEvents source. Defaults to "go-auth".
+	Source string
+	// MaxAttempts before an event is dead-lettered. Defaults to 8.
+	MaxAttempts int
+	// RetryBackoff is the delay before the first retry; it doubles with
+	// every attempt up to 5 minutes. Defaults to 1 second.
+	RetryBackoff time.Duration
+	// Timeout of a single request. Defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+// WebhookConfig configures the webhook endpoints.
+type WebhookConfig struct {
+	Endpoints []WebhookEndpoint
+	// DeadLetters keeps events that could not be delivered. Defaults to an
+	// in-memory store.
+	DeadLetters DeadLetterStore
+	// Client sends the requests. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+// DeadLetter is an event a webhook endpoint did not accept.
+type DeadLetter struct {
+	ID         string     `json:"id"`
+	EndpointID string     `json:"endpoint_id"`
+	Event      CloudEvent `json:"event"`
+	Attempts   int        `json:"attempts"`
+	LastError  string     `json:"last_error"`
+	FailedAt   time.Time  `json:"failed_at"`
+}
+
+// DeadLetterStore persists undeliverable webhook events.
+type DeadLetterStore interface {
+	SaveDeadLetter(letter *DeadLetter) error
+	// GetDeadLetter returns the letter with id, or an *AuthError if it does
+	// not exist.
+	GetDeadLetter(id string) (*DeadLetter, error)
+	// ListDeadLetters returns up to limit letters, newest first. An empty
+	// endpointID matches all endpoints.
+	ListDeadLetters(endpointID string, limit int) ([]*DeadLetter, error)
+	DeleteDeadLetter(id string) error
+}
+
+// memoryDeadLetterStore is an in-memory DeadLetterStore.
+type memoryDeadLetterStore struct {
+	mu      sync.RWMutex
+	letters map[string]*DeadLetter
+}
+
+// NewMemoryDeadLetterStore creates an in-memory DeadLetterStore. Letters are
+// lost on restart; use a persistent DeadLetterStore in production.
+func NewMemoryDeadLetterStore() DeadLetterStore {
+	return &memoryDeadLetterStore{letters: make(map[string]*DeadLetter)}
+}
+
+func (s *memoryDeadLetterStore) SaveDeadLetter(letter *DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *letter
+	s.letters[letter.ID] = &stored
+	return nil
+}
+
+func (s *memoryDeadLetterStore) GetDeadLetter(id string) (*DeadLetter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	letter, ok := s.letters[id]
+	if !ok {
+		return nil, errDeadLetterNotFound()
+	}
+	result := *letter
+	return &result, nil
+}
+
+func (s *memoryDeadLetterStore) ListDeadLetters(endpointID string, limit int) ([]*DeadLetter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var letters []*DeadLetter
+	for _, letter := range s.letters {
+		if endpointID == "" || letter.EndpointID == endpointID {
+			result := *letter
+			letters = append(letters, &result)
+		}
+	}
+	sort.Slice(letters, func(i, j int) bool {
+		return letters[i].FailedAt.After(letters[j].FailedAt)
+	})
+	if limit > 0 && len(letters) > limit {
+		letters = letters[:limit]
+	}
+	return letters, nil
+}
+
+func (s *memoryDeadLetterStore) DeleteDeadLetter(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.letters, id)
+	return nil
+}
+
+// errDeadLetterNotFound is returned for unknown dead letter IDs.
+func errDeadLetterNotFound() *AuthError {
+	return NewAuthError(ErrCodeUserNotFound, "Dead letter not found")
+}
+
+// SignWebhook returns the WebhookSignatureHeader value for body sent at the
+// given time.
+func SignWebhook(secret string, body []byte, at time.Time) string {
+	timestamp := strconv.FormatInt(at.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + webhookMAC(secret, timestamp, body)
+}
+
+// webhookMAC computes the hex HMAC of a timestamped body.
+func webhookMAC(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature checks a WebhookSignatureHeader value for body.
+// Signatures older than tolerance are rejected to prevent replays; zero
+// disables the check.
+func VerifyWebhookSignature(secret, header string, body []byte, tolerance time.Duration) error {
+	var timestamp, signature string
+	for _, part := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signature = value
+		}
+	}
+	if timestamp == "" || signature == "" {
+		return fmt.Errorf("malformed webhook signature")
+	}
+	if !hmac.Equal([]byte(signature), []byte(webhookMAC(secret, timestamp, body))) {
+		return fmt.Errorf("webhook signature mismatch")
+	}
+	if tolerance > 0 {
+		seconds, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return fmt.Errorf("malformed webhook timestamp")
+		}
+		if age := time.Since(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+			return fmt.Errorf("webhook signature expired")
+		}
+	}
+	return nil
+}
+
+// webhookSink delivers events to one webhook endpoint. Failed deliveries are
+// retried with exponential backoff and dead-lettered after MaxAttempts.
+type webhookSink struct {
+	endpoint    WebhookEndpoint
+	client      *http.Client
+	deadLetters DeadLetterStore
+	clock       Clock
+	logger      *Logger
+}
+
+// newWebhookSink validates endpoint and applies its defaults.
+func newWebhookSink(endpoint WebhookEndpoint, config WebhookConfig, clock Clock, logger *Logger) (*webhookSink, error) {
+	target, err := url.Parse(endpoint.URL)
+	if err != nil || (target.Scheme != "https" && target.Scheme != "http") || target.Host == "" {
+		return nil, ErrConfigError("Webhooks.Endpoints.URL")
+	}
+	if endpoint.Secret == "" {
+		return nil, ErrConfigError("Webhooks.Endpoints.Secret")
+	}
+	if endpoint.ID == "" {
+		endpoint.ID = endpoint.URL
+	}
+	if endpoint.Source == "" {
+		endpoint.Source = "go-auth"
+	}
+	if endpoint.MaxAttempts <= 0 {
+		endpoint.MaxAttempts = 8
+	}
+	if endpoint.RetryBackoff <= 0 {
+		endpoint.RetryBackoff = time.Second
+	}
+	if endpoint.Timeout <= 0 {
+		endpoint.Timeout = 10 * time.Second
+	}
+	client := config.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &webhookSink{
+		endpoint:    endpoint,
+		client:      client,
+		deadLetters: config.DeadLetters,
+		clock:       clock,
+		logger:      logger,
+	}, nil
+}
+
+// Publish delivers event, dead-lettering it once the attempts are used up.
+// It only fails if the dead letter cannot be stored.
+func (s *webhookSink) Publish(ctx context.Context, event AuthEvent) error {
+	return s.deliver(ctx, NewCloudEvent(event, s.endpoint.Source))
+}
+
+// deliver sends envelope with retries.
+func (s *webhookSink) deliver(ctx context.Context, envelope CloudEvent) error {
+	body, err := json.Marshal(envelope)
+	if err != nil {
+		return err
+	}
+
+	backoff := s.endpoint.RetryBackoff
+	attempts := 0
+	var lastErr error
+	for attempts < s.endpoint.MaxAttempts {
+		attempts++
+		var retry bool
+		retry, lastErr = s.send(ctx, body)
+		if lastErr == nil {
+			return nil
+		}
+		s.logger.Warn("Webhook delivery failed", map[string]interface{}{
+			"endpoint": s.endpoint.ID,
+			"event_id": envelope.ID,
+			"attempt":  attempts,
+			"error":    lastErr,
+		})
+		if !retry || attempts == s.endpoint.MaxAttempts {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			lastErr = ctx.Err()
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		backoff = min(2*backoff, 5*time.Minute)
+	}
+
+	letter := &DeadLetter{
+		ID:         uuid.New().String(),
+		EndpointID: s.endpoint.ID,
+		Event:      envelope,
+		Attempts:   attempts,
+		LastError:  lastErr.Error(),
+		FailedAt:   nowFrom(s.clock),
+	}
+	if err := s.deadLetters.SaveDeadLetter(letter); err != nil {
+		return err
+	}
+	s.logger.Error("Webhook event dead-lettered", map[string]interface{}{
+		"endpoint":       s.endpoint.ID,
+		"event_id":       envelope.ID,
+		"dead_letter_id": letter.ID,
+		"attempts":       attempts,
+		"error":          lastErr,
+	})
+	return nil
+}
+
+// send makes one delivery attempt and reports whether a failure is worth
+// retrying: network errors, 408, 429 and 5xx responses are.
+func (s *webhookSink) send(ctx context.Context, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.endpoint.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/cloudevents+json")
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(s.endpoint.Secret, body, nowFrom(s.clock)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests ||
+		resp.StatusCode >= 500
+	return retry, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+}
+
+// ListDeadLetters returns up to limit webhook events that could not be
+// delivered, newest first. An empty endpointID matches all endpoints.
+func (a *Auth) ListDeadLetters(endpointID string, limit int) ([]*DeadLetter, error) {
+	if a.deadLetters == nil {
+		return []*DeadLetter{}, nil
+	}
+	letters, err := a.deadLetters.ListDeadLetters(endpointID, limit)
+	if err != nil {
+		return nil, WrapDatabaseError(err)
+	}
+	return letters, nil
+}
+
+// RedeliverDeadLetter sends a dead-lettered event to its endpoint again and
+// removes the letter. If delivery fails again the event is dead-lettered anew.
+func (a *Auth) RedeliverDeadLetter(ctx context.Context, id string) error {
+	if a.deadLetters == nil {
+		return errDeadLetterNotFound()
+	}
+	letter, err := a.deadLetters.GetDeadLetter(id)
+	if err != nil {
+		if authErr, ok := err.(*AuthError); ok {
+			return authErr
+		}
+		return WrapDatabaseError(err)
+	}
+	sink, ok := a.webhooks[letter.EndpointID]
+	if !ok {
+		return NewAuthErrorWithDetails(ErrCodeInvalidConfig, "Invalid configuration",
+			fmt.Sprintf("Webhook endpoint %s is not configured", letter.EndpointID))
+	}
+	if err := a.deadLetters.DeleteDeadLetter(id); err != nil {
+		return WrapDatabaseError(err)
+	}
+	if err := sink.deliver(ctx, letter.Event); err != nil {
+		return WrapDatabaseError(err)
+	}
+	return nil
+}
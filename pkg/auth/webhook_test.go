@@ -0,0 +1,159 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pragneshbagary/go-auth/internal/storage/memory"
+)
+
+// webhookReceiver accepts requests once failures have been answered with 503.
+func webhookReceiver(t *testing.T, secret string, failures int32) (*httptest.Server, *atomic.Int32, chan CloudEvent) {
+	t.Helper()
+	var calls atomic.Int32
+	received := make(chan CloudEvent, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := VerifyWebhookSignature(secret, r.Header.Get(WebhookSignatureHeader), body, time.Minute); err != nil {
+			t.Errorf("Invalid signature: %v", err)
+		}
+		if r.Header.Get("Content-Type") != "application/cloudevents+json" {
+			t.Errorf("Unexpected content type %q", r.Header.Get("Content-Type"))
+		}
+		if calls.Add(1) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var event CloudEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("Invalid payload: %v", err)
+		}
+		received <- event
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls, received
+}
+
+func newTestWebhookSink(t *testing.T, endpoint WebhookEndpoint, deadLetters DeadLetterStore) *webhookSink {
+	t.Helper()
+	sink, err := newWebhookSink(endpoint, WebhookConfig{DeadLetters: deadLetters}, nil, NewLogger(LogLevelError, nil))
+	if err != nil {
+		t.Fatalf("Failed to create webhook sink: %v", err)
+	}
+	return sink
+}
+
+func TestWebhookRetriesUntilDelivered(t *testing.T) {
+	server, calls, received := webhookReceiver(t, "endpoint-secret", 2)
+	deadLetters := NewMemoryDeadLetterStore()
+	sink := newTestWebhookSink(t, WebhookEndpoint{
+		URL:          server.URL,
+		Secret:       "endpoint-secret",
+		RetryBackoff: time.Millisecond,
+	}, deadLetters)
+
+	event := newAuthEvent(LogLevelInfo, "User logged in", map[string]interface{}{
+		"event":   "user_login",
+		"user_id": "user-1",
+	})
+	if err := sink.Publish(context.Background(), event); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected 3 attempts, got %d", calls.Load())
+	}
+	delivered := <-received
+	if delivered.SpecVersion != "1.0" || delivered.ID != event.ID || delivered.Type != CloudEventTypePrefix+"user_login" {
+		t.Errorf("Unexpected CloudEvent: %+v", delivered)
+	}
+	if letters, _ := deadLetters.ListDeadLetters("", 0); len(letters) != 0 {
+		t.Errorf("Expected no dead letters, got %d", len(letters))
+	}
+}
+
+func TestWebhookDeadLettersAndRedelivers(t *testing.T) {
+	server, calls, received := webhookReceiver(t, "endpoint-secret", 3)
+	a, err := newAuthWithStorage(memory.NewInMemoryStorage(), &AuthConfig{
+		JWTSecret:          "go-auth-test-secret",
+		JWTRefreshSecret:   "go-auth-test-refresh-secret",
+		LogLevel:           "error",
+		PasswordHashParams: TestHashParams,
+		Webhooks: WebhookConfig{Endpoints: []WebhookEndpoint{{
+			ID:           "analytics",
+			URL:          server.URL,
+			Secret:       "endpoint-secret",
+			MaxAttempts:  3,
+			RetryBackoff: time.Millisecond,
+		}}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create auth: %v", err)
+	}
+	defer a.Close()
+
+	event := newAuthEvent(LogLevelInfo, "User logged in", map[string]interface{}{"event": "user_login"})
+	if err := a.webhooks["analytics"].Publish(context.Background(), event); err != nil {
+		t.Fatalf("Failed to publish: %v", err)
+	}
+	letters, err := a.ListDeadLetters("analytics", 10)
+	if err != nil {
+		t.Fatalf("Failed to list dead letters: %v", err)
+	}
+	if len(letters) != 1 || letters[0].Attempts != 3 || letters[0].Event.ID != event.ID {
+		t.Fatalf("Expected the event to be dead-lettered after 3 attempts, got %+v", letters)
+	}
+
+	if err := a.RedeliverDeadLetter(context.Background(), letters[0].ID); err != nil {
+		t.Fatalf("Failed to redeliver: %v", err)
+	}
+	if calls.Load() != 4 || (<-received).ID != event.ID {
+		t.Errorf("Expected the dead letter to be redelivered")
+	}
+	if letters, _ := a.ListDeadLetters("", 0); len(letters) != 0 {
+		t.Errorf("Expected the dead letter to be removed, got %d", len(letters))
+	}
+	err = a.RedeliverDeadLetter(context.Background(), "unknown")
+	if authErr, ok := err.(*AuthError); !ok || authErr.Code != ErrCodeUserNotFound {
+		t.Errorf("Expected not found for an unknown dead letter, got %v", err)
+	}
+}
+
+func TestWebhookDoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+	deadLetters := NewMemoryDeadLetterStore()
+	sink := newTestWebhookSink(t, WebhookEndpoint{URL: server.URL, Secret: "s", RetryBackoff: time.Millisecond}, deadLetters)
+
+	sink.Publish(context.Background(), newAuthEvent(LogLevelInfo, "m", nil))
+	if calls.Load() != 1 {
+		t.Errorf("Expected a single attempt for a 400 response, got %d", calls.Load())
+	}
+	if letters, _ := deadLetters.ListDeadLetters("", 0); len(letters) != 1 {
+		t.Errorf("Expected the event to be dead-lettered")
+	}
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	header := SignWebhook("secret", body, time.Now())
+	if err := VerifyWebhookSignature("secret", header, body, time.Minute); err != nil {
+		t.Errorf("Expected a valid signature: %v", err)
+	}
+	if err := VerifyWebhookSignature("other", header, body, time.Minute); err == nil {
+		t.Error("Expected a signature with another secret to be rejected")
+	}
+	stale := SignWebhook("secret", body, time.Now().Add(-time.Hour))
+	if err := VerifyWebhookSignature("secret", stale, body, time.Minute); err == nil {
+		t.Error("Expected a stale signature to be rejected")
+	}
+}
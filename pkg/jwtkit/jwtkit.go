@@ -0,0 +1,115 @@
+// Package jwtkit issues and validates access and refresh tokens without the
+// rest of go-auth. It is the token layer used by the auth package, with
+// HMAC (HS*), RSA (RS*, PS*) and ECDSA (ES*) signing.
+//
+//	manager, err := jwtkit.NewManager(jwtkit.Config{
+//		AccessSecret:    []byte(os.Getenv("JWT_SECRET")),
+//		RefreshSecret:   []byte(os.Getenv("JWT_REFRESH_SECRET")),
+//		Issuer:          "my-app",
+//		AccessTokenTTL:  15 * time.Minute,
+//		RefreshTokenTTL: 7 * 24 * time.Hour,
+//		SigningMethod:   jwtkit.HS256,
+//	})
+package jwtkit
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pragneshbagary/go-auth/internal/jwtutils"
+)
+
+// Signing methods.
+const (
+	HS256 = "HS256"
+	HS384 = "HS384"
+	HS512 = "HS512"
+	RS256 = "RS256"
+	RS384 = "RS384"
+	RS512 = "RS512"
+	PS256 = "PS256"
+	PS384 = "PS384"
+	PS512 = "PS512"
+	ES256 = "ES256"
+	ES384 = "ES384"
+	ES512 = "ES512"
+)
+
+// TokenManager issues and validates access and refresh tokens.
+type TokenManager = jwtutils.TokenManager
+
+// Config configures a TokenManager. HS* methods sign with AccessSecret and
+// RefreshSecret; the other methods sign both token types with SigningKey and
+// tell them apart by their token_type claim. A manager that only validates
+// tokens of an asymmetric method needs just VerificationKey.
+type Config = jwtutils.JWTConfig
+
+// IssueOptions schedules the validity of an issued token, sets its TTL and
+// adds claims to it.
+type IssueOptions = jwtutils.IssueOptions
+
+// NewManager validates cfg and creates a TokenManager issuing JWTs.
+func NewManager(cfg Config) (TokenManager, error) {
+	if err := Validate(cfg); err != nil {
+		return nil, err
+	}
+	return jwtutils.NewJWTManager(cfg), nil
+}
+
+// Validate checks that cfg has the secrets or keys its signing method needs.
+func Validate(cfg Config) error {
+	if !supported(cfg.SigningMethod) {
+		return fmt.Errorf("jwtkit: unsupported signing method %q", cfg.SigningMethod)
+	}
+	if cfg.AccessTokenTTL < 0 || cfg.RefreshTokenTTL < 0 {
+		return errors.New("jwtkit: token TTLs must not be negative")
+	}
+	switch {
+	case strings.HasPrefix(cfg.SigningMethod, "HS"):
+		if len(cfg.AccessSecret) == 0 {
+			return errors.New("jwtkit: AccessSecret is required for " + cfg.SigningMethod)
+		}
+	case strings.HasPrefix(cfg.SigningMethod, "RS"), strings.HasPrefix(cfg.SigningMethod, "PS"):
+		return checkKeys(cfg, func(key crypto.PublicKey) bool {
+			_, ok := key.(*rsa.PublicKey)
+			return ok
+		})
+	case strings.HasPrefix(cfg.SigningMethod, "ES"):
+		curve := map[string]elliptic.Curve{ES256: elliptic.P256(), ES384: elliptic.P384(), ES512: elliptic.P521()}[cfg.SigningMethod]
+		return checkKeys(cfg, func(key crypto.PublicKey) bool {
+			ecKey, ok := key.(*ecdsa.PublicKey)
+			return ok && ecKey.Curve == curve
+		})
+	}
+	return nil
+}
+
+// supported reports whether method is a supported signing method.
+func supported(method string) bool {
+	for _, m := range jwtutils.SupportedSigningMethods() {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// checkKeys checks that cfg has a signing or verification key and that
+// its keys suit the signing method.
+func checkKeys(cfg Config, suits func(crypto.PublicKey) bool) error {
+	if cfg.SigningKey == nil && cfg.VerificationKey == nil {
+		return errors.New("jwtkit: SigningKey or VerificationKey is required for " + cfg.SigningMethod)
+	}
+	if cfg.SigningKey != nil && !suits(cfg.SigningKey.Public()) {
+		return fmt.Errorf("jwtkit: SigningKey %T does not suit %s", cfg.SigningKey, cfg.SigningMethod)
+	}
+	if cfg.VerificationKey != nil && !suits(cfg.VerificationKey) {
+		return fmt.Errorf("jwtkit: VerificationKey %T does not suit %s", cfg.VerificationKey, cfg.SigningMethod)
+	}
+	return nil
+}
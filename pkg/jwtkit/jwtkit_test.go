@@ -0,0 +1,104 @@
+package jwtkit
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager_SigningMethods(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	configs := map[string]Config{
+		HS256: {AccessSecret: []byte("access-secret"), RefreshSecret: []byte("refresh-secret"), SigningMethod: HS256},
+		RS256: {SigningKey: rsaKey, SigningMethod: RS256},
+		PS256: {SigningKey: rsaKey, SigningMethod: PS256},
+		ES256: {SigningKey: ecKey, SigningMethod: ES256},
+	}
+	for name, cfg := range configs {
+		t.Run(name, func(t *testing.T) {
+			cfg.Issuer = "jwtkit-test"
+			cfg.AccessTokenTTL = time.Minute
+			cfg.RefreshTokenTTL = time.Hour
+			manager, err := NewManager(cfg)
+			require.NoError(t, err)
+
+			accessToken, err := manager.GenerateAccessToken("user-1", map[string]any{"role": "admin"})
+			require.NoError(t, err)
+			claims, err := manager.ValidateAccessToken(accessToken)
+			require.NoError(t, err)
+			assert.Equal(t, "user-1", claims["sub"])
+			assert.Equal(t, "admin", claims["role"])
+
+			refreshToken, err := manager.GenerateRefreshToken("user-1")
+			require.NoError(t, err)
+			_, err = manager.ValidateAccessToken(refreshToken)
+			assert.Error(t, err, "A refresh token must not validate as an access token")
+			_, err = manager.RefreshAccessToken(refreshToken)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestManager_VerificationOnly(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	issuer, err := NewManager(Config{SigningKey: ecKey, SigningMethod: ES384, AccessTokenTTL: time.Minute})
+	require.NoError(t, err)
+	token, err := issuer.GenerateAccessToken("user-1", nil)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&ecKey.PublicKey)
+	require.NoError(t, err)
+	publicKey, err := ParsePublicKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.NoError(t, err)
+
+	verifier, err := NewManager(Config{VerificationKey: publicKey, SigningMethod: ES384})
+	require.NoError(t, err)
+	_, err = verifier.ValidateAccessToken(token)
+	assert.NoError(t, err)
+	_, err = verifier.GenerateAccessToken("user-1", nil)
+	assert.Error(t, err, "A verifier without a signing key cannot issue tokens")
+}
+
+func TestValidate(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	assert.Error(t, Validate(Config{SigningMethod: "none"}))
+	assert.Error(t, Validate(Config{SigningMethod: HS256}), "HS256 needs a secret")
+	assert.Error(t, Validate(Config{SigningMethod: RS256}), "RS256 needs a key")
+	assert.Error(t, Validate(Config{SigningKey: rsaKey, SigningMethod: ES256}), "ES256 needs an ECDSA key")
+	assert.NoError(t, Validate(Config{SigningKey: rsaKey, SigningMethod: RS512}))
+}
+
+func TestParsePrivateKeyPEM(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	pkcs1 := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	key, err := ParsePrivateKeyPEM(pkcs1)
+	require.NoError(t, err)
+	assert.True(t, rsaKey.Equal(key))
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	require.NoError(t, err)
+	key, err = ParsePrivateKeyPEM(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	require.NoError(t, err)
+	assert.True(t, ecKey.Equal(key))
+
+	_, err = ParsePrivateKeyPEM([]byte("not a key"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,65 @@
+package jwtkit
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// ParsePrivateKeyPEM parses a PEM-encoded RSA or ECDSA private key in
+// PKCS #8, PKCS #1 or SEC 1 form, for Config.SigningKey.
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwtkit: no PEM block found")
+	}
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("jwtkit: invalid private key: %w", err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("jwtkit: unsupported private key type %T", key)
+	}
+	return signer, nil
+}
+
+// ParsePublicKeyPEM parses a PEM-encoded RSA or ECDSA public key in PKIX or
+// PKCS #1 form, or the public key of a certificate, for
+// Config.VerificationKey.
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("jwtkit: no PEM block found")
+	}
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		key, err := x509.ParsePKCS1PublicKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwtkit: invalid public key: %w", err)
+		}
+		return key, nil
+	case "CERTIFICATE":
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("jwtkit: invalid certificate: %w", err)
+		}
+		return cert.PublicKey, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("jwtkit: invalid public key: %w", err)
+	}
+	return key, nil
+}
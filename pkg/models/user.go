@@ -1,6 +1,8 @@
 // user.go
 package models
 
+import "time"
+
 // User defines the structure for a user in the system.
 // The password field should always store a hashed password, never plaintext.
 type User struct {
@@ -13,4 +15,13 @@ type User struct {
 	// PasswordHash is the secure, hashed version of the user's password.
 	// The struct tag `json:"-"` ensures it is never exposed in API responses.
 	PasswordHash string `json:"-"`
+	// DisplayName is the name shown to other users, e.g. "Alice Smith".
+	DisplayName string `json:"display_name,omitempty"`
+	// Locale is the preferred language as a BCP 47 tag, e.g. "en-US".
+	Locale string `json:"locale,omitempty"`
+	// Timezone is an IANA time zone name, e.g. "Europe/Berlin".
+	Timezone string `json:"timezone,omitempty"`
+	// ExpiresAt is when the account expires, e.g. for contractors or
+	// trials. Nil accounts never expire.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
@@ -0,0 +1,32 @@
+package storage
+
+import "time"
+
+// BlacklistedToken is an entry of the token blacklist.
+type BlacklistedToken struct {
+	TokenID   string    `json:"token_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// CreatedAt is when the token was blacklisted.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BlacklistFilter selects entries of the token blacklist. Zero fields match
+// all entries.
+type BlacklistFilter struct {
+	// TokenIDPrefix matches the token IDs starting with it.
+	TokenIDPrefix string
+	// CreatedAfter and CreatedBefore bound when tokens were blacklisted.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+	// IncludeExpired includes the entries of expired tokens that were not
+	// cleaned up yet.
+	IncludeExpired bool
+}
+
+// BlacklistListingStorage is implemented by storages that can list the
+// token blacklist for administrators.
+type BlacklistListingStorage interface {
+	// ListBlacklistedTokens returns up to limit entries matching filter,
+	// most recently blacklisted first, skipping the first offset.
+	ListBlacklistedTokens(filter BlacklistFilter, limit, offset int) ([]BlacklistedToken, error)
+}
@@ -0,0 +1,59 @@
+package storage
+
+import "regexp"
+
+// ColumnType is the type of an application-defined user column.
+type ColumnType int
+
+const (
+	ColumnText ColumnType = iota
+	ColumnInteger
+	ColumnFloat
+	ColumnBool
+	ColumnTime
+)
+
+// ExtensionColumn describes a column an application adds to the users table.
+type ExtensionColumn struct {
+	Name string
+	Type ColumnType
+	// Nullable columns default to NULL; others to the zero value of Type.
+	Nullable bool
+}
+
+// ExtensionStorage is implemented by storages that persist application-defined
+// user columns alongside the built-in ones.
+type ExtensionStorage interface {
+	// ExtendUserSchema adds the columns that do not exist yet. Existing
+	// columns are left unchanged.
+	ExtendUserSchema(columns []ExtensionColumn) error
+
+	// GetUserExtension scans the given columns of the user into dest, which
+	// holds one pointer per column. It returns an error if the user is not found.
+	GetUserExtension(userID string, columns []string, dest []interface{}) error
+
+	// UpdateUserExtension sets the given columns of the user to values.
+	UpdateUserExtension(userID string, columns []string, values []interface{}) error
+}
+
+// ProfileColumns are the built-in profile columns of the users table. SQL
+// storages add them to tables created before they existed.
+var ProfileColumns = []ExtensionColumn{
+	{Name: "display_name", Type: ColumnText},
+	{Name: "locale", Type: ColumnText},
+	{Name: "timezone", Type: ColumnText},
+}
+
+// AccountColumns are the built-in account lifecycle columns of the users
+// table. SQL storages add them to tables created before they existed.
+var AccountColumns = []ExtensionColumn{
+	{Name: "expires_at", Type: ColumnTime, Nullable: true},
+}
+
+var columnNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// ValidColumnName reports whether name is safe to use as an extension
+// column: a lowercase SQL identifier.
+func ValidColumnName(name string) bool {
+	return columnNamePattern.MatchString(name)
+}
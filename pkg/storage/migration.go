@@ -0,0 +1,19 @@
+package storage
+
+import "database/sql"
+
+// TxMigrationStorage is implemented by SQL storages that can apply a
+// migration and its record in one transaction, so that application tables
+// migrate together with the go-auth schema.
+type TxMigrationStorage interface {
+	// ApplyMigrationTx runs up and records the migration as applied in the
+	// same transaction. Nothing is committed if up fails.
+	ApplyMigrationTx(version int, description string, up func(tx *sql.Tx) error) error
+
+	// RevertMigrationTx runs down and removes the migration record in the
+	// same transaction. Nothing is committed if down fails.
+	RevertMigrationTx(version int, down func(tx *sql.Tx) error) error
+
+	// DryRunTx runs fn in a transaction that is always rolled back.
+	DryRunTx(fn func(tx *sql.Tx) error) error
+}
@@ -0,0 +1,18 @@
+package storage
+
+import "time"
+
+// RefreshFamilyStorage is implemented by storages that revoke rotated
+// refresh tokens per family. A family is the chain of refresh tokens
+// descending from one login; each rotation increments the generation. One
+// record per family replaces a blacklist entry per rotated token.
+type RefreshFamilyStorage interface {
+	// RevokeRefreshFamily rejects the tokens of the family with a generation
+	// below cutoff until expiresAt. A lower cutoff than the recorded one is
+	// ignored, and expiresAt is only ever extended.
+	RevokeRefreshFamily(familyID string, cutoff int64, expiresAt time.Time) error
+
+	// RefreshFamilyCutoff returns the cutoff of the family, zero if none is
+	// recorded or it expired.
+	RefreshFamilyCutoff(familyID string) (int64, error)
+}
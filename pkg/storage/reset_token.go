@@ -0,0 +1,55 @@
+package storage
+
+import (
+	"time"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+// ResetToken is a stored password reset token or code. Secrets are stored
+// as hashes only.
+type ResetToken struct {
+	// ID is the lookup key: the hash of an emailed token, or of the phone
+	// number of a texted code, which is too short to be a key itself.
+	ID string
+	// CodeHash is the hash of a texted code; empty for emailed tokens.
+	CodeHash string
+	UserID   string
+	// Channel is "email" or "sms".
+	Channel string
+	// Attempts counts the wrong codes entered for the token.
+	Attempts  int
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// ResetTokenStorage is implemented by storages that persist password reset
+// tokens, so that they survive restarts and can be redeemed on any
+// instance. Expired tokens are returned until CleanupExpiredTokens removes
+// them, so that callers can tell them from unknown ones.
+type ResetTokenStorage interface {
+	// SaveResetToken stores token, replacing the token with the same ID.
+	SaveResetToken(token ResetToken) error
+
+	// GetResetToken returns the token with id, or nil if there is none.
+	GetResetToken(id string) (*ResetToken, error)
+
+	// RecordResetTokenAttempt increments the wrong attempts of the token
+	// with id and returns them.
+	RecordResetTokenAttempt(id string) (int, error)
+
+	// DeleteResetToken deletes the token with id and reports whether it
+	// existed, so that concurrent redemptions consume a token once.
+	DeleteResetToken(id string) (bool, error)
+
+	// ListResetTokens returns the tokens of userID.
+	ListResetTokens(userID string) ([]ResetToken, error)
+}
+
+// PhoneStorage is implemented by storages that find users by the "phone"
+// metadata through an index instead of scanning all users.
+type PhoneStorage interface {
+	// GetUsersByPhone returns the users whose "phone" metadata is phone,
+	// verified or not.
+	GetUsersByPhone(phone string) ([]*models.User, error)
+}
@@ -0,0 +1,12 @@
+package storage
+
+// TenantStorage is implemented by storages that isolate the users of
+// tenants in the database, so that even a query missing a tenant condition
+// cannot read or write the users of another tenant.
+type TenantStorage interface {
+	// EnableRowLevelSecurity sets up the isolation. It is idempotent.
+	EnableRowLevelSecurity() error
+
+	// ForTenant returns a storage restricted to the users of tenant.
+	ForTenant(tenant string) Storage
+}
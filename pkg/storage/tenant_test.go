@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/pragneshbagary/go-auth/pkg/models"
+)
+
+// tenantUsers is a TenantStorage keeping the users of each tenant apart.
+type tenantUsers struct {
+	tenant string
+	users  map[string]models.User
+}
+
+func (s *tenantUsers) CreateUser(user models.User) error {
+	s.users[s.tenant+"/"+user.Username] = user
+	return nil
+}
+
+func (s *tenantUsers) GetUserByUsername(username string) (*models.User, error) {
+	user, ok := s.users[s.tenant+"/"+username]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	return &user, nil
+}
+
+func (s *tenantUsers) EnableRowLevelSecurity() error {
+	return nil
+}
+
+func (s *tenantUsers) ForTenant(tenant string) Storage {
+	return &tenantUsers{tenant: tenant, users: s.users}
+}
+
+func TestTenantStorageForTenant(t *testing.T) {
+	var base TenantStorage = &tenantUsers{users: map[string]models.User{}}
+	if err := base.EnableRowLevelSecurity(); err != nil {
+		t.Fatalf("EnableRowLevelSecurity failed: %v", err)
+	}
+
+	acme, globex := base.ForTenant("acme"), base.ForTenant("globex")
+	if err := acme.CreateUser(models.User{ID: "1", Username: "alice"}); err != nil {
+		t.Fatalf("CreateUser failed: %v", err)
+	}
+	if _, err := acme.GetUserByUsername("alice"); err != nil {
+		t.Errorf("Expected the tenant to see its user: %v", err)
+	}
+	if _, err := globex.GetUserByUsername("alice"); err == nil {
+		t.Error("Expected another tenant not to see the user")
+	}
+}
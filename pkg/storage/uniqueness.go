@@ -0,0 +1,33 @@
+package storage
+
+// UniquenessScope is the scope within which usernames and non-empty emails
+// are unique.
+type UniquenessScope int
+
+const (
+	// UniqueGlobal makes them unique across all users.
+	UniqueGlobal UniquenessScope = iota
+	// UniquePerTenant makes them unique among the users of a tenant, see
+	// TenantStorage.
+	UniquePerTenant
+)
+
+// DuplicateError is returned by storages that enforce uniqueness when a
+// user would share a username or email with another user in scope.
+type DuplicateError struct {
+	// Field is "username" or "email".
+	Field string
+}
+
+func (e *DuplicateError) Error() string {
+	return "a user with this " + e.Field + " already exists"
+}
+
+// UniquenessStorage is implemented by storages that enforce the uniqueness
+// of usernames and emails with unique indexes, reporting violations as
+// *DuplicateError.
+type UniquenessStorage interface {
+	// SetUniquenessScope replaces the unique indexes of usernames and emails
+	// with those of scope. It fails if existing users conflict in scope.
+	SetUniquenessScope(scope UniquenessScope) error
+}